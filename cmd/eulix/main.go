@@ -1,15 +1,17 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"eulix/internal/cli"
+	"eulix/internal/crash"
 )
 
 func main() {
-	if err := cli.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	crash.Install()
+	defer crash.Recover()
+
+	if status := cli.Execute(); status != 0 {
+		os.Exit(status)
 	}
 }
@@ -19,6 +19,9 @@ pub enum ChunkType {
     Method,
     File,
     EntryPoint,
+    Doc,
+    Infra,
+    Schema,
     #[serde(other)]
     Other,
 }
@@ -151,9 +154,175 @@ pub fn chunk_knowledge_base(kb: &KnowledgeBase, max_size: usize) -> Vec<Chunk> {
         }
     }
 
+    // Chunk 5: Documentation sections, split where a section doesn't fit
+    for doc in &kb.documents {
+        for section in &doc.sections {
+            let header = format!("Doc: {} ({})\nSection: {}\n\n", doc.path, doc.title, section.heading);
+            let pieces = split_text(&section.text, max_size.min(2000).saturating_sub(header.len()));
+            let multiple = pieces.len() > 1;
+            for (i, piece) in pieces.into_iter().enumerate() {
+                let mut id = format!("doc:{}:{}", doc.path, section.line_start);
+                if multiple {
+                    id.push_str(&format!("#{}", i + 1));
+                }
+                chunks.push(Chunk {
+                    id,
+                    chunk_type: ChunkType::Doc,
+                    content: truncate_content(&format!("{}{}", header, piece), max_size),
+                    metadata: ChunkMetadata {
+                        file_path: Some(doc.path.clone()),
+                        language: Some(doc.kind.clone()),
+                        line_start: Some(section.line_start),
+                        line_end: Some(section.line_end),
+                        name: section.heading.clone(),
+                        complexity: None,
+                    },
+                    tags: vec!["doc".to_string(), doc.kind.clone()],
+                    importance_score: 0.5,
+                });
+            }
+        }
+    }
+
+    // Chunk 6: Services, containers, jobs and resources from deployment config
+    for file in &kb.infra {
+        for svc in &file.services {
+            chunks.push(Chunk {
+                id: format!("infra:{}:{}", file.path, svc.name),
+                chunk_type: ChunkType::Infra,
+                content: truncate_content(&format_infra_service(svc, file), max_size),
+                metadata: ChunkMetadata {
+                    file_path: Some(file.path.clone()),
+                    language: Some(file.kind.clone()),
+                    line_start: Some(svc.line_start),
+                    line_end: Some(svc.line_end),
+                    name: svc.name.clone(),
+                    complexity: None,
+                },
+                tags: vec!["infra".to_string(), file.kind.clone(), svc.kind.clone()],
+                importance_score: 0.5,
+            });
+        }
+    }
+
+    // Chunk 7: One per database table, with its columns and foreign keys
+    for table in &kb.schema.tables {
+        chunks.push(Chunk {
+            id: format!("schema:{}", table.name),
+            chunk_type: ChunkType::Schema,
+            content: truncate_content(&format_table(table), max_size),
+            metadata: ChunkMetadata {
+                file_path: Some(table.source.clone()),
+                language: Some("sql".to_string()),
+                line_start: Some(table.line),
+                line_end: Some(table.line),
+                name: table.name.clone(),
+                complexity: None,
+            },
+            tags: vec!["schema".to_string(), table.origin.clone()],
+            importance_score: 0.6,
+        });
+    }
+
     chunks
 }
 
+fn format_table(table: &crate::kb_loader::Table) -> String {
+    let mut content = String::new();
+
+    content.push_str(&format!("// File: {}:{} ({})\n", table.source, table.line, table.origin));
+    content.push_str(&format!("// Table: {}\n\n", table.name));
+
+    content.push_str("Columns:\n");
+    for column in &table.columns {
+        let mut flags = Vec::new();
+        if column.primary_key {
+            flags.push("primary key");
+        }
+        if !column.nullable {
+            flags.push("not null");
+        }
+        if flags.is_empty() {
+            content.push_str(&format!("  - {} {}\n", column.name, column.data_type));
+        } else {
+            content.push_str(&format!("  - {} {} ({})\n", column.name, column.data_type, flags.join(", ")));
+        }
+    }
+    if !table.foreign_keys.is_empty() {
+        content.push_str("Foreign keys:\n");
+        for fk in &table.foreign_keys {
+            content.push_str(&format!(
+                "  - {} references {}.{}\n",
+                fk.columns.join(", "),
+                fk.ref_table,
+                fk.ref_columns.join(", ")
+            ));
+        }
+    }
+
+    content
+}
+
+fn format_infra_service(svc: &crate::kb_loader::InfraService, file: &crate::kb_loader::InfraFile) -> String {
+    let mut content = String::new();
+
+    content.push_str(&format!("// File: {} ({})\n", file.path, file.kind));
+    content.push_str(&format!("// {}: {}\n", svc.kind, svc.name));
+    content.push_str(&format!("// Lines: {}-{}\n\n", svc.line_start, svc.line_end));
+
+    if let Some(ref image) = svc.image {
+        content.push_str(&format!("Image: {}\n", image));
+    }
+    if let Some(ref command) = svc.command {
+        content.push_str(&format!("Command: {}\n", command));
+    }
+    if !svc.ports.is_empty() {
+        content.push_str(&format!("Ports: {}\n", svc.ports.join(", ")));
+    }
+    if !svc.env.is_empty() {
+        content.push_str("Environment variables:\n");
+        for name in &svc.env {
+            content.push_str(&format!("  - {}\n", name));
+        }
+    }
+    if !svc.depends_on.is_empty() {
+        content.push_str(&format!("Depends on: {}\n", svc.depends_on.join(", ")));
+    }
+
+    content
+}
+
+/// Splits text into pieces of at most max bytes at paragraph breaks, or at
+/// line breaks when a paragraph alone is too long
+fn split_text(text: &str, max: usize) -> Vec<String> {
+    if text.len() <= max || max == 0 {
+        return vec![text.to_string()];
+    }
+
+    let mut pieces = Vec::new();
+    let mut current = String::new();
+    for paragraph in text.split("\n\n") {
+        let (parts, separator): (Vec<&str>, &str) = if paragraph.len() > max {
+            (paragraph.lines().collect(), "\n")
+        } else {
+            (vec![paragraph], "\n\n")
+        };
+        for part in parts {
+            if !current.is_empty() && current.len() + part.len() + separator.len() > max {
+                pieces.push(std::mem::take(&mut current));
+            }
+            if !current.is_empty() {
+                current.push_str(separator);
+            }
+            current.push_str(part);
+        }
+    }
+    if !current.trim().is_empty() {
+        pieces.push(current);
+    }
+    pieces
+}
+
 fn format_function_with_context(
     func: &crate::kb_loader::Function,
     file_path: &str,
@@ -206,6 +375,21 @@ fn format_function_with_context(
         content.push_str("\n");
     }
 
+    // Calls into other languages or services, all of them since they are
+    // what connects this chunk to the rest of the repo
+    let linked: Vec<_> = func.calls.iter().filter(|c| c.link.is_some()).collect();
+    if !linked.is_empty() {
+        content.push_str("Calls across languages:\n");
+        for call in linked {
+            let kind = call.link.as_deref().unwrap_or_default();
+            match call.defined_in {
+                Some(ref file) => content.push_str(&format!("  - {} in {} via {} (line {})\n", call.callee, file, kind, call.line)),
+                None => content.push_str(&format!("  - {} via {} (line {})\n", call.callee, kind, call.line)),
+            }
+        }
+        content.push_str("\n");
+    }
+
     // Called by
     if !func.called_by.is_empty() {
         content.push_str("Called by:\n");
@@ -395,7 +579,11 @@ fn truncate_content(content: &str, max_size: usize) -> String {
         content.to_string()
     } else {
         // Try to truncate at a newline for cleaner cuts
-        let truncate_at = safe_max.saturating_sub(3);
+        let mut truncate_at = safe_max.saturating_sub(3);
+        // Docs are often not ASCII, don't cut a character in half
+        while !content.is_char_boundary(truncate_at) {
+            truncate_at -= 1;
+        }
         if let Some(newline_pos) = content[..truncate_at].rfind('\n') {
             format!("{}...", &content[..newline_pos])
         } else {
@@ -16,6 +16,12 @@ pub struct KnowledgeBase {
     pub entry_points: Vec<EntryPoint>,
     pub external_dependencies: Vec<ExternalDependency>,
     pub patterns: Patterns,
+    #[serde(default)]
+    pub documents: Vec<Document>,
+    #[serde(default)]
+    pub infra: Vec<InfraFile>,
+    #[serde(default)]
+    pub schema: SchemaView,
 }
 
 #[derive(Debug, Serialize, Deserialize, Clone)]
@@ -104,6 +110,8 @@ pub struct FunctionCall {
     #[serde(default)]
     pub is_conditional: bool,
     pub context: String, // "if" | "else" | "loop" | "try" | "unconditional"
+    #[serde(default)]
+    pub link: Option<String>, // "http" | "grpc" | "ffi" for calls across languages
 }
 
 #[derive(Debug, Serialize, Deserialize, Clone)]
@@ -333,6 +341,81 @@ pub struct ExternalDependency {
     pub import_count: usize,
 }
 
+/// A markdown file or notebook split into sections by heading
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct Document {
+    pub path: String,
+    pub kind: String, // "markdown" | "notebook"
+    pub title: String,
+    pub sections: Vec<DocSection>,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct DocSection {
+    pub heading: String,
+    pub line_start: usize, // cell numbers for notebooks
+    pub line_end: usize,
+    pub text: String,
+}
+
+/// Deployment config: Dockerfile, compose, Kubernetes, workflow or Terraform
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct InfraFile {
+    pub path: String,
+    pub kind: String,
+    pub services: Vec<InfraService>,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct InfraService {
+    pub name: String,
+    pub kind: String,
+    pub line_start: usize,
+    pub line_end: usize,
+    pub image: Option<String>,
+    pub command: Option<String>,
+    #[serde(default)]
+    pub ports: Vec<String>,
+    #[serde(default)]
+    pub env: Vec<String>, // names only
+    #[serde(default)]
+    pub depends_on: Vec<String>,
+}
+
+/// Database tables from migrations and ORM models
+#[derive(Debug, Serialize, Deserialize, Clone, Default)]
+pub struct SchemaView {
+    #[serde(default)]
+    pub tables: Vec<Table>,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct Table {
+    pub name: String,
+    pub source: String,
+    pub line: usize,
+    pub origin: String,
+    #[serde(default)]
+    pub columns: Vec<Column>,
+    #[serde(default)]
+    pub foreign_keys: Vec<ForeignKey>,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct Column {
+    pub name: String,
+    pub data_type: String,
+    pub nullable: bool,
+    pub primary_key: bool,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct ForeignKey {
+    pub columns: Vec<String>,
+    pub ref_table: String,
+    pub ref_columns: Vec<String>,
+}
+
 #[derive(Debug, Serialize, Deserialize, Clone)]
 pub struct Patterns {
     #[serde(default)]
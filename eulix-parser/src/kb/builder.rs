@@ -77,6 +77,9 @@ impl KnowledgeBaseBuilder {
             call_graph: CallGraph::default(),
             indices: Indices::default(),
             patterns: PatternInfo::default(),
+            documents: vec![],
+            infra: vec![],
+            schema: Default::default(),
             entry_points,
             external_dependencies,
         })
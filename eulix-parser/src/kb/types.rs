@@ -11,6 +11,83 @@ pub struct KnowledgeBase {
     pub entry_points: Vec<EntryPoint>,
     pub external_dependencies: Vec<ExternalDependency>,
     pub patterns: PatternInfo,
+    #[serde(default)]
+    pub documents: Vec<Document>,
+    #[serde(default)]
+    pub infra: Vec<InfraFile>,
+    #[serde(default)]
+    pub schema: SchemaView,
+}
+
+/// Database tables from migrations and ORM models
+#[derive(Debug, Serialize, Deserialize, Clone, Default)]
+pub struct SchemaView {
+    pub tables: Vec<Table>,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct Table {
+    pub name: String,
+    pub source: String, // file that created or declared it
+    pub line: usize,
+    pub origin: String, // "sql", "sqlalchemy", "django", "gorm"
+    pub columns: Vec<Column>,
+    pub foreign_keys: Vec<ForeignKey>,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct Column {
+    pub name: String,
+    pub data_type: String,
+    pub nullable: bool,
+    pub primary_key: bool,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct ForeignKey {
+    pub columns: Vec<String>,
+    pub ref_table: String,
+    pub ref_columns: Vec<String>,
+}
+
+/// A Dockerfile, compose file, Kubernetes manifest, workflow or Terraform file
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct InfraFile {
+    pub path: String,
+    pub kind: String, // "dockerfile", "compose", "kubernetes", "workflow", "terraform"
+    pub services: Vec<InfraService>,
+}
+
+/// A service, container, job, build stage or resource. env holds variable
+/// names only, values are often secrets.
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct InfraService {
+    pub name: String,
+    pub kind: String,
+    pub line_start: usize,
+    pub line_end: usize,
+    pub image: Option<String>,
+    pub command: Option<String>,
+    pub ports: Vec<String>,
+    pub env: Vec<String>,
+    pub depends_on: Vec<String>,
+}
+
+/// A markdown file or notebook, kept as sections rather than parsed code
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct Document {
+    pub path: String,
+    pub kind: String, // "markdown" or "notebook"
+    pub title: String,
+    pub sections: Vec<DocSection>,
+}
+
+#[derive(Debug, Serialize, Deserialize, Clone)]
+pub struct DocSection {
+    pub heading: String,
+    pub line_start: usize, // cell numbers for notebooks
+    pub line_end: usize,
+    pub text: String,
 }
 
 #[derive(Debug, Serialize, Deserialize, Clone)]
@@ -94,6 +171,10 @@ pub struct FunctionCall {
     pub args: Vec<String>,
     pub is_conditional: bool, // Inside if/loop/try block?
     pub context: String, // "if", "else", "loop", "try", "unconditional"
+    /// "http", "grpc" or "ffi" when the call reaches another language or
+    /// service, see parser::links
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub link: Option<String>,
 }
 
 // Caller information (reverse call graph)
@@ -274,6 +355,8 @@ pub struct Indices {
     pub functions_calling: HashMap<String, Vec<String>>, // callee -> [callers]
     pub functions_by_tag: HashMap<String, Vec<String>>,
     pub types_by_name: HashMap<String, Vec<String>>,
+    #[serde(default)]
+    pub globals_by_name: HashMap<String, Vec<String>>, // package level vars and consts
     pub files_by_category: HashMap<String, Vec<String>>,
 }
 
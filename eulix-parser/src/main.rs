@@ -16,7 +16,11 @@ use parser::language::Language;
 use parser::python;
 use parser::go;
 use parser::c;
+use parser::docs;
+use parser::infra;
+use parser::schema;
 use utils::file_walker::FileWalker;
+use utils::path_filter::PathFilter;
 
 #[derive(Debug, Clone)]
 struct ParseStats {
@@ -67,6 +71,97 @@ struct Args {
     /// Path to custom .euignore file (defaults to <root>/.euignore)
     #[arg(long)]
     euignore: Option<String>,
+
+    /// Only parse paths matching this glob (repeatable, relative to root)
+    #[arg(long = "include", value_name = "GLOB")]
+    include: Vec<String>,
+
+    /// Skip paths matching this glob (repeatable, relative to root)
+    #[arg(long = "exclude", value_name = "GLOB")]
+    exclude: Vec<String>,
+
+    /// Skip paths matching the globs in this file, one per line
+    #[arg(long = "exclude-from", value_name = "FILE")]
+    exclude_from: Option<String>,
+
+    /// Don't index markdown files and notebooks
+    #[arg(long)]
+    no_docs: bool,
+
+    /// Don't index Dockerfiles, compose, Kubernetes, workflow and Terraform files
+    #[arg(long)]
+    no_infra: bool,
+
+    /// Don't build the schema view from .sql migrations and ORM models
+    #[arg(long)]
+    no_schema: bool,
+
+    /// Also write parse statistics as JSON to this file
+    #[arg(long)]
+    stats: Option<String>,
+}
+
+/// Written by --stats so callers get the numbers without scraping stdout
+#[derive(Debug, serde::Serialize)]
+struct StatsReport {
+    parsed: usize,
+    skipped: usize,
+    failed: Vec<FailedFile>,
+    total_files: usize,
+    total_loc: usize,
+    functions: usize,
+    classes: usize,
+    methods: usize,
+    languages: Vec<String>,
+    documents: usize,
+    infra_files: usize,
+    tables: usize,
+    graph_nodes: usize,
+    graph_edges: usize,
+    parse_seconds: f64,
+    total_seconds: f64,
+}
+
+#[derive(Debug, serde::Serialize)]
+struct FailedFile {
+    file: String,
+    error: String,
+}
+
+fn write_stats(
+    path: &str,
+    kb: &KnowledgeBase,
+    stats: &ParseStats,
+    parse_seconds: f64,
+    total_seconds: f64,
+) -> Result<(), Box<dyn std::error::Error>> {
+    let report = StatsReport {
+        parsed: stats.parsed.len(),
+        skipped: stats.skipped.len(),
+        failed: stats
+            .failed
+            .iter()
+            .map(|(file, error)| FailedFile {
+                file: file.clone(),
+                error: error.clone(),
+            })
+            .collect(),
+        total_files: kb.metadata.total_files,
+        total_loc: kb.metadata.total_loc,
+        functions: kb.metadata.total_functions,
+        classes: kb.metadata.total_classes,
+        methods: kb.metadata.total_methods,
+        languages: kb.metadata.languages.clone(),
+        documents: kb.documents.len(),
+        infra_files: kb.infra.len(),
+        tables: kb.schema.tables.len(),
+        graph_nodes: kb.call_graph.nodes.len(),
+        graph_edges: kb.call_graph.edges.len(),
+        parse_seconds,
+        total_seconds,
+    };
+    fs::write(path, serde_json::to_string_pretty(&report)?)?;
+    Ok(())
 }
 
 fn main() -> Result<(), Box<dyn std::error::Error>> {
@@ -93,6 +188,12 @@ fn main() -> Result<(), Box<dyn std::error::Error>> {
         if let Some(ref ignore) = args.euignore {
             println!("[x] Ignore File:     {}", ignore);
         }
+        if !args.include.is_empty() {
+            println!("Include:         {}", args.include.join(", "));
+        }
+        if !args.exclude.is_empty() {
+            println!("Exclude:         {}", args.exclude.join(", "));
+        }
         println!();
         println!("{}", "═".repeat(64));
     }
@@ -103,12 +204,23 @@ fn main() -> Result<(), Box<dyn std::error::Error>> {
         println!("{}", "─".repeat(64));
     }
     let parse_start = Instant::now();
-    let (mut kb, stats) = parse_directory(&args.root, &args.languages, args.euignore.as_deref(), args.verbose)?;
+    let mut exclude = args.exclude.clone();
+    if let Some(ref path) = args.exclude_from {
+        exclude.extend(
+            fs::read_to_string(path)?
+                .lines()
+                .filter(|line| !line.trim().is_empty() && !line.starts_with('#'))
+                .map(String::from),
+        );
+    }
+    let filter = PathFilter::new(&args.include, &exclude)?;
+    let (mut kb, stats) = parse_directory(&args.root, &args.languages, args.euignore.as_deref(), &filter, !args.no_docs, !args.no_infra, !args.no_schema, args.verbose)?;
+    let parse_seconds = parse_start.elapsed().as_secs_f64();
 
     if args.verbose {
         println!("\n{}", "─".repeat(64));
         println!("Parsing Complete!");
-        println!("     Time:         {:.2}s", parse_start.elapsed().as_secs_f64());
+        println!("     Time:         {:.2}s", parse_seconds);
         println!("     Parsed:       {} files", stats.parsed.len());
         println!("     Skipped:      {} files", stats.skipped.len());
         println!("     Failed:       {} files", stats.failed.len());
@@ -202,6 +314,15 @@ fn main() -> Result<(), Box<dyn std::error::Error>> {
             println!("   ✓ {}_summary.json ({:.2} KB)", base_name, size as f64 / 1024.0);
         }
 
+        // Write schema.json
+        let schema_path = output_dir.join(format!("{}_schema.json", base_name));
+        let schema_json = serde_json::to_string_pretty(&kb.schema)?;
+        fs::write(&schema_path, schema_json)?;
+        if args.verbose {
+            let size = fs::metadata(&schema_path)?.len();
+            println!("   ✓ {}_schema.json ({:.2} KB)", base_name, size as f64 / 1024.0);
+        }
+
         // Write call_graph.json
         let callgraph_path = output_dir.join(format!("{}_call_graph.json", base_name));
         let callgraph_json = serde_json::to_string_pretty(&kb.call_graph)?;
@@ -254,6 +375,13 @@ fn main() -> Result<(), Box<dyn std::error::Error>> {
         }
     }
 
+    // The KB is written by now, stats are only for the caller's summary
+    if let Some(ref stats_path) = args.stats {
+        if let Err(e) = write_stats(stats_path, &kb, &stats, parse_seconds, start_time.elapsed().as_secs_f64()) {
+            eprintln!("   ⚠ Failed to write stats to {}: {}", stats_path, e);
+        }
+    }
+
     Ok(())
 }
 
@@ -302,6 +430,10 @@ fn parse_directory(
     dir: &str,
     languages: &str,
     euignore_path: Option<&str>,
+    filter: &PathFilter,
+    with_docs: bool,
+    with_infra: bool,
+    with_schema: bool,
     verbose: bool,
 ) -> Result<(KnowledgeBase, ParseStats), Box<dyn std::error::Error>> {
     let path = PathBuf::from(dir);
@@ -323,7 +455,18 @@ fn parse_directory(
     }
 
     // Collect all source files based on language filter
-    let files = collect_source_files(&path, languages, verbose)?;
+    let mut files = collect_source_files(&path, languages, verbose)?;
+    let mut excluded = Vec::new();
+    if !filter.is_empty() {
+        files.retain(|file| {
+            let relative = file.strip_prefix(&path).unwrap_or(file);
+            let allowed = filter.allows(relative);
+            if !allowed {
+                excluded.push(relative.to_string_lossy().to_string());
+            }
+            allowed
+        });
+    }
 
     if verbose {
         println!("    Discovered {} source files", files.len());
@@ -363,7 +506,24 @@ fn parse_directory(
         })
         .collect();
 
-    let final_stats = Arc::try_unwrap(stats).unwrap().into_inner().unwrap();
+    let mut final_stats = Arc::try_unwrap(stats).unwrap().into_inner().unwrap();
+    final_stats.skipped = excluded;
+
+    let documents = if with_docs {
+        parse_documents(&path, filter, &mut final_stats, verbose)
+    } else {
+        vec![]
+    };
+    let infra = if with_infra {
+        parse_infra(&path, filter, &mut final_stats, verbose)
+    } else {
+        vec![]
+    };
+    let schema = if with_schema {
+        parse_schema(&path, &files, filter, &mut final_stats, verbose)
+    } else {
+        SchemaView::default()
+    };
 
     // Build knowledge base structure
     let mut structure = HashMap::new();
@@ -414,11 +574,137 @@ fn parse_directory(
         entry_points: vec![],
         external_dependencies: vec![],
         patterns: PatternInfo::default(),
+        documents,
+        infra,
+        schema,
     };
 
     Ok((kb, final_stats))
 }
 
+/// Markdown files and notebooks, filtered like source files
+fn parse_documents(
+    root: &Path,
+    filter: &PathFilter,
+    stats: &mut ParseStats,
+    verbose: bool,
+) -> Vec<Document> {
+    let walker = FileWalker::new(root.to_path_buf());
+    let files = match walker.walk_files(docs::is_doc) {
+        Ok(files) => files,
+        Err(e) => {
+            if verbose {
+                eprintln!("        Failed to collect docs: {}", e);
+            }
+            return vec![];
+        }
+    };
+
+    let mut documents = Vec::new();
+    for file in files {
+        let relative = file.strip_prefix(root).unwrap_or(&file);
+        let relative_path = relative.to_string_lossy().to_string();
+        if !filter.allows(relative) {
+            stats.skipped.push(relative_path);
+            continue;
+        }
+        match docs::parse_file(&file, &relative_path) {
+            Ok(doc) => {
+                if verbose {
+                    println!("   ✓ Doc:     {} ({} sections)", relative_path, doc.sections.len());
+                }
+                stats.parsed.push(relative_path);
+                documents.push(doc);
+            }
+            Err(e) => stats.failed.push((relative_path, e.to_string())),
+        }
+    }
+    documents.sort_by(|a, b| a.path.cmp(&b.path));
+    documents
+}
+
+/// Deployment config, filtered like source files. Workflows live in the
+/// hidden .github directory the walker skips, so it is read directly.
+fn parse_infra(
+    root: &Path,
+    filter: &PathFilter,
+    stats: &mut ParseStats,
+    verbose: bool,
+) -> Vec<InfraFile> {
+    let walker = FileWalker::new(root.to_path_buf());
+    let mut files = match walker.walk_files(infra::is_candidate) {
+        Ok(files) => files,
+        Err(e) => {
+            if verbose {
+                eprintln!("        Failed to collect infra files: {}", e);
+            }
+            return vec![];
+        }
+    };
+    if let Ok(entries) = fs::read_dir(root.join(".github").join("workflows")) {
+        files.extend(entries.filter_map(|e| e.ok()).map(|e| e.path()).filter(|p| infra::is_candidate(p)));
+    }
+
+    let mut found = Vec::new();
+    for file in files {
+        let relative = file.strip_prefix(root).unwrap_or(&file);
+        let relative_path = relative.to_string_lossy().to_string();
+        if !filter.allows(relative) {
+            stats.skipped.push(relative_path);
+            continue;
+        }
+        match infra::parse_file(&file, &relative_path) {
+            Ok(Some(parsed)) => {
+                if verbose {
+                    println!("   ✓ Infra:   {} ({}, {} services)", relative_path, parsed.kind, parsed.services.len());
+                }
+                stats.parsed.push(relative_path);
+                found.push(parsed);
+            }
+            Ok(None) => {}
+            Err(e) => stats.failed.push((relative_path, e.to_string())),
+        }
+    }
+    found.sort_by(|a, b| a.path.cmp(&b.path));
+    found
+}
+
+/// Tables from .sql files and from ORM models in the source files already
+/// collected
+fn parse_schema(
+    root: &Path,
+    source_files: &[PathBuf],
+    filter: &PathFilter,
+    stats: &mut ParseStats,
+    verbose: bool,
+) -> SchemaView {
+    let walker = FileWalker::new(root.to_path_buf());
+    let mut sql_files = match walker.walk_files(schema::is_sql) {
+        Ok(files) => files,
+        Err(e) => {
+            if verbose {
+                eprintln!("        Failed to collect sql files: {}", e);
+            }
+            vec![]
+        }
+    };
+    sql_files.retain(|file| {
+        let relative = file.strip_prefix(root).unwrap_or(file);
+        let allowed = filter.allows(relative);
+        if !allowed {
+            stats.skipped.push(relative.to_string_lossy().to_string());
+        }
+        allowed
+    });
+
+    let (view, failed) = schema::extract(root, &sql_files, source_files);
+    if verbose && !view.tables.is_empty() {
+        println!("   ✓ Schema:  {} tables from {} sql files", view.tables.len(), sql_files.len());
+    }
+    stats.failed.extend(failed);
+    view
+}
+
 #[allow(dead_code)]
 fn collect_source_files(
     root: &Path,
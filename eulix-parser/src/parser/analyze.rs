@@ -1,4 +1,5 @@
 use crate::kb::types::*;
+use crate::parser::links;
 use rayon::prelude::*;
 use serde::{Deserialize, Serialize};
 use std::collections::{HashMap, HashSet};
@@ -38,6 +39,21 @@ impl Analyzer {
             Self::resolve_call_locations(&mut kb);
         }
 
+        // Find entry points (lightweight), before linking since routes are
+        // what HTTP clients link to
+        if verbose { println!("   → Finding entry points..."); }
+        kb.entry_points = Self::find_entry_points(&kb);
+
+        // Link calls across languages, after call locations so the links
+        // aren't overwritten by name
+        if !is_large {
+            if verbose { println!("   → Linking calls across languages..."); }
+            let counts = links::link(&mut kb);
+            if verbose && counts.total() > 0 {
+                println!("     {} HTTP, {} gRPC, {} FFI links", counts.http, counts.grpc, counts.ffi);
+            }
+        }
+
         // Build indices (always do this, it's useful)
         if verbose { println!("   → Generating indices..."); }
         kb.indices = Self::generate_indices(&kb);
@@ -46,10 +62,6 @@ impl Analyzer {
         if verbose { println!("   → Detecting patterns..."); }
         kb.patterns = Self::detect_patterns(&kb);
 
-        // Find entry points (lightweight)
-        if verbose { println!("   → Finding entry points..."); }
-        kb.entry_points = Self::find_entry_points(&kb);
-
         // Analyze external dependencies (lightweight)
         if verbose { println!("   → Analyzing dependencies..."); }
         kb.external_dependencies = Self::analyze_external_deps(&kb);
@@ -280,6 +292,7 @@ impl Analyzer {
                 let mut local_fn_by_tag: Vec<(String, String)> = Vec::new();
                 let mut local_fn_calling: Vec<(String, String)> = Vec::new();
                 let mut local_types: Vec<(String, String)> = Vec::new();
+                let mut local_globals: Vec<(String, String)> = Vec::new();
 
                 for (filepath, filedata) in chunk.iter() {
                     // Index functions by name
@@ -307,21 +320,34 @@ impl Analyzer {
                             format!("{}:{}", filepath, class.line_start),
                         ));
 
-                        // Index methods
+                        // Index methods, by name and as Type.Method so a
+                        // method of one type can be told from another's
                         for method in &class.methods {
                             local_fn_by_name.push((
                                 method.name.clone(),
                                 format!("{}:{}", filepath, method.line_start),
                             ));
+                            local_fn_by_name.push((
+                                format!("{}.{}", class.name, method.name),
+                                format!("{}:{}", filepath, method.line_start),
+                            ));
 
                             for tag in &method.tags {
                                 local_fn_by_tag.push((tag.clone(), method.id.clone()));
                             }
                         }
                     }
+
+                    // Index package level variables and constants
+                    for var in &filedata.global_vars {
+                        local_globals.push((
+                            var.name.clone(),
+                            format!("{}:{}", filepath, var.line),
+                        ));
+                    }
                 }
 
-                (local_fn_by_name, local_fn_by_tag, local_fn_calling, local_types)
+                (local_fn_by_name, local_fn_by_tag, local_fn_calling, local_types, local_globals)
             })
             .collect();
 
@@ -330,8 +356,9 @@ impl Analyzer {
         let mut functions_by_tag: HashMap<String, Vec<String>> = HashMap::new();
         let mut functions_calling: HashMap<String, Vec<String>> = HashMap::new();
         let mut types_by_name: HashMap<String, Vec<String>> = HashMap::new();
+        let mut globals_by_name: HashMap<String, Vec<String>> = HashMap::new();
 
-        for (fn_by_name, fn_by_tag, fn_calling, types) in all_indices {
+        for (fn_by_name, fn_by_tag, fn_calling, types, globals) in all_indices {
             for (k, v) in fn_by_name {
                 functions_by_name.entry(k).or_insert_with(Vec::new).push(v);
             }
@@ -344,6 +371,9 @@ impl Analyzer {
             for (k, v) in types {
                 types_by_name.entry(k).or_insert_with(Vec::new).push(v);
             }
+            for (k, v) in globals {
+                globals_by_name.entry(k).or_insert_with(Vec::new).push(v);
+            }
         }
 
         Indices {
@@ -351,6 +381,7 @@ impl Analyzer {
             functions_calling,
             functions_by_tag,
             types_by_name,
+            globals_by_name,
             files_by_category: HashMap::new(),
         }
     }
@@ -414,7 +445,8 @@ impl Analyzer {
 
     fn extract_route_path(decorator: &str) -> Option<String> {
         // Extract path from decorators like @app.route("/api/login")
-        let re = regex::Regex::new(r#"['"]([/\w-]+)['"]"#).ok()?;
+        // Keep parameters like <int:id> and {id}
+        let re = regex::Regex::new(r#"['"](/[^'"\s]*)['"]"#).ok()?;
         re.captures(decorator)
             .and_then(|caps| caps.get(1))
             .map(|m| m.as_str().to_string())
@@ -424,17 +456,22 @@ impl Analyzer {
         let mut methods = Vec::new();
         let dec_lower = decorator.to_lowercase();
 
-        if dec_lower.contains("get") {
-            methods.push("GET".to_string());
-        }
-        if dec_lower.contains("post") {
-            methods.push("POST".to_string());
-        }
-        if dec_lower.contains("put") {
-            methods.push("PUT".to_string());
-        }
-        if dec_lower.contains("delete") {
-            methods.push("DELETE".to_string());
+        // @app.route("/x", methods=["GET", "POST"])
+        if let Some(at) = dec_lower.find("methods") {
+            let list = &dec_lower[at..];
+            let list = &list[..list.find(']').unwrap_or(list.len())];
+            for method in ["get", "post", "put", "patch", "delete", "head", "options"] {
+                if list.contains(&format!("'{}'", method)) || list.contains(&format!("\"{}\"", method)) {
+                    methods.push(method.to_uppercase());
+                }
+            }
+        } else {
+            // @app.get("/x"), @router.post("/x")
+            for method in ["get", "post", "put", "patch", "delete", "head", "options"] {
+                if dec_lower.contains(&format!(".{}(", method)) {
+                    methods.push(method.to_uppercase());
+                }
+            }
         }
 
         if methods.is_empty() {
@@ -300,6 +300,7 @@ impl CParser {
                             args,
                             is_conditional: context != "unconditional",
                             context: context.to_string(),
+                            link: None,
                         });
                     }
                 }
@@ -0,0 +1,236 @@
+// parser/src/parser/docs.rs
+//! Markdown files and Jupyter notebooks, split into sections by heading so
+//! design docs, ADRs and READMEs can be searched alongside the code.
+
+use crate::kb::types::{DocSection, Document};
+use std::fs;
+use std::path::Path;
+
+/// Whether a file is documentation this parser reads
+pub fn is_doc(path: &Path) -> bool {
+    matches!(
+        path.extension().and_then(|ext| ext.to_str()),
+        Some("md") | Some("markdown") | Some("ipynb")
+    )
+}
+
+pub fn parse_file(path: &Path, relative_path: &str) -> Result<Document, Box<dyn std::error::Error>> {
+    let content = fs::read_to_string(path)?;
+    let stem = path
+        .file_stem()
+        .and_then(|s| s.to_str())
+        .unwrap_or(relative_path)
+        .to_string();
+
+    if path.extension().and_then(|ext| ext.to_str()) == Some("ipynb") {
+        return parse_notebook(&content, relative_path, stem);
+    }
+
+    let lines: Vec<&str> = content.lines().collect();
+    let sections = split_sections(&lines, &stem);
+    Ok(Document {
+        path: relative_path.to_string(),
+        kind: "markdown".to_string(),
+        title: title_of(&sections, stem),
+        sections,
+    })
+}
+
+/// Notebooks become markdown with code cells fenced. Section lines are cell
+/// numbers, a notebook has no meaningful line numbers.
+fn parse_notebook(content: &str, relative_path: &str, stem: String) -> Result<Document, Box<dyn std::error::Error>> {
+    let notebook: serde_json::Value = serde_json::from_str(content)?;
+    let language = notebook["metadata"]["kernelspec"]["language"]
+        .as_str()
+        .or_else(|| notebook["metadata"]["language_info"]["name"].as_str())
+        .unwrap_or("python")
+        .to_string();
+
+    let mut lines: Vec<String> = Vec::new();
+    let mut cell_of_line: Vec<usize> = Vec::new();
+    let cells = notebook["cells"].as_array().cloned().unwrap_or_default();
+    for (i, cell) in cells.iter().enumerate() {
+        let source = cell_source(&cell["source"]);
+        if source.trim().is_empty() {
+            continue;
+        }
+        let mut push = |line: String| {
+            lines.push(line);
+            cell_of_line.push(i + 1);
+        };
+        match cell["cell_type"].as_str() {
+            Some("markdown") => source.lines().for_each(|l| push(l.to_string())),
+            Some("code") => {
+                push(format!("```{}", language));
+                source.lines().for_each(|l| push(l.to_string()));
+                push("```".to_string());
+            }
+            _ => continue,
+        }
+        push(String::new());
+    }
+
+    let borrowed: Vec<&str> = lines.iter().map(|l| l.as_str()).collect();
+    let mut sections = split_sections(&borrowed, &stem);
+    for section in &mut sections {
+        section.line_start = cell_of_line[section.line_start - 1];
+        section.line_end = cell_of_line[section.line_end - 1];
+    }
+
+    Ok(Document {
+        path: relative_path.to_string(),
+        kind: "notebook".to_string(),
+        title: title_of(&sections, stem),
+        sections,
+    })
+}
+
+/// A cell's source is a string or a list of lines
+fn cell_source(source: &serde_json::Value) -> String {
+    match source {
+        serde_json::Value::String(s) => s.clone(),
+        serde_json::Value::Array(parts) => parts.iter().filter_map(|p| p.as_str()).collect(),
+        _ => String::new(),
+    }
+}
+
+/// Splits at ATX headings outside code fences. Text before the first
+/// heading is a section named after the file.
+fn split_sections(lines: &[&str], stem: &str) -> Vec<DocSection> {
+    let mut sections = Vec::new();
+    let mut heading = stem.to_string();
+    let mut start = 1;
+    let mut text: Vec<&str> = Vec::new();
+    let mut in_fence = false;
+
+    for (i, line) in lines.iter().enumerate() {
+        let trimmed = line.trim_start();
+        if trimmed.starts_with("```") || trimmed.starts_with("~~~") {
+            in_fence = !in_fence;
+        }
+        if !in_fence {
+            if let Some(title) = heading_text(trimmed) {
+                push_section(&mut sections, &heading, start, i, &text);
+                heading = title;
+                start = i + 1;
+                text.clear();
+            }
+        }
+        text.push(line);
+    }
+    push_section(&mut sections, &heading, start, lines.len(), &text);
+    sections
+}
+
+fn push_section(sections: &mut Vec<DocSection>, heading: &str, start: usize, end: usize, text: &[&str]) {
+    let joined = text.join("\n");
+    if joined.trim().is_empty() || end < start {
+        return;
+    }
+    sections.push(DocSection {
+        heading: heading.to_string(),
+        line_start: start,
+        line_end: end,
+        text: joined.trim_end().to_string(),
+    });
+}
+
+/// "## Retries" is the heading "Retries"
+fn heading_text(line: &str) -> Option<String> {
+    let level = line.chars().take_while(|&c| c == '#').count();
+    if level == 0 || level > 6 || !line[level..].starts_with(' ') {
+        return None;
+    }
+    let title = line[level..].trim().trim_end_matches('#').trim();
+    if title.is_empty() {
+        None
+    } else {
+        Some(title.to_string())
+    }
+}
+
+/// The first top level heading, or the file name
+fn title_of(sections: &[DocSection], stem: String) -> String {
+    sections
+        .iter()
+        .find(|s| s.text.trim_start().starts_with("# "))
+        .map(|s| s.heading.clone())
+        .unwrap_or(stem)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_markdown_sections() {
+        let content = "Intro text\n\n# Orders\nHow orders work\n\n## Retries ##\nBackoff\n```sh\n# not a heading\n```\n";
+        let lines: Vec<&str> = content.lines().collect();
+        let sections = split_sections(&lines, "README");
+
+        let headings: Vec<&str> = sections.iter().map(|s| s.heading.as_str()).collect();
+        assert_eq!(headings, vec!["README", "Orders", "Retries"]);
+        assert_eq!((sections[1].line_start, sections[1].line_end), (3, 5));
+        assert_eq!((sections[2].line_start, sections[2].line_end), (6, 10));
+        assert!(sections[2].text.contains("# not a heading"));
+        assert_eq!(title_of(&sections, "README".to_string()), "Orders");
+    }
+
+    #[test]
+    fn test_heading_text() {
+        assert_eq!(heading_text("## Retries"), Some("Retries".to_string()));
+        assert_eq!(heading_text("# Title #"), Some("Title".to_string()));
+        assert_eq!(heading_text("#hashtag"), None);
+        assert_eq!(heading_text("####### Too deep"), None);
+        assert_eq!(heading_text("#   "), None);
+        assert_eq!(heading_text("plain text"), None);
+    }
+
+    #[test]
+    fn test_title_falls_back_to_file_name() {
+        let lines = vec!["no headings here"];
+        let sections = split_sections(&lines, "notes");
+        assert_eq!(sections.len(), 1);
+        assert_eq!(title_of(&sections, "notes".to_string()), "notes");
+        assert!(split_sections(&[], "empty").is_empty());
+    }
+
+    #[test]
+    fn test_notebook_cells() {
+        let content = r###"{
+            "metadata": {"kernelspec": {"language": "python"}},
+            "cells": [
+                {"cell_type": "markdown", "source": ["# Training\n", "Loads the data"]},
+                {"cell_type": "code", "source": "df = load()\n"},
+                {"cell_type": "code", "source": []},
+                {"cell_type": "markdown", "source": "## Evaluation\nScores"}
+            ]
+        }"###;
+        let doc = parse_notebook(content, "nb/train.ipynb", "train".to_string()).unwrap();
+
+        assert_eq!(doc.kind, "notebook");
+        assert_eq!(doc.title, "Training");
+        assert_eq!(doc.sections.len(), 2);
+        assert!(doc.sections[0].text.contains("```python\ndf = load()\n```"));
+        assert_eq!((doc.sections[0].line_start, doc.sections[0].line_end), (1, 2));
+        assert_eq!(doc.sections[1].heading, "Evaluation");
+        assert_eq!(doc.sections[1].line_start, 4);
+    }
+
+    #[test]
+    fn test_malformed_notebook() {
+        assert!(parse_notebook("{\"cells\": [", "bad.ipynb", "bad".to_string()).is_err());
+
+        let doc = parse_notebook("{\"cells\": \"none\"}", "odd.ipynb", "odd".to_string()).unwrap();
+        assert!(doc.sections.is_empty());
+        assert_eq!(doc.title, "odd");
+    }
+
+    #[test]
+    fn test_is_doc() {
+        assert!(is_doc(Path::new("docs/adr/0001.md")));
+        assert!(is_doc(Path::new("analysis.ipynb")));
+        assert!(!is_doc(Path::new("main.go")));
+        assert!(!is_doc(Path::new("Makefile")));
+    }
+}
@@ -286,6 +286,7 @@ impl GoParser {
                             args,
                             is_conditional: context != "unconditional",
                             context: context.to_string(),
+                            link: None,
                         });
                     }
                 }
@@ -590,10 +591,17 @@ impl GoParser {
             vec![]
         };
 
+        // Embedded types are the closest Go has to bases
+        let bases = node
+            .child_by_field_name("type")
+            .filter(|t| t.kind() == "struct_type")
+            .map(|t| self.extract_embedded_types(&t))
+            .unwrap_or_default();
+
         Some(Class {
             id: format!("struct_{}", name),
             name,
-            bases: vec![],
+            bases,
             docstring,
             line_start,
             line_end,
@@ -603,10 +611,36 @@ impl GoParser {
         })
     }
 
+    /// The { ... } of a struct type, which the grammar doesn't name as a field
+    fn field_list<'a>(&self, struct_node: &Node<'a>) -> Option<Node<'a>> {
+        let mut cursor = struct_node.walk();
+        let list = struct_node
+            .children(&mut cursor)
+            .find(|child| child.kind() == "field_declaration_list");
+        list
+    }
+
+    fn extract_embedded_types(&self, struct_node: &Node) -> Vec<String> {
+        let mut embedded = Vec::new();
+
+        if let Some(body) = self.field_list(struct_node) {
+            let mut cursor = body.walk();
+            for child in body.children(&mut cursor) {
+                if child.kind() == "field_declaration" && child.child_by_field_name("name").is_none() {
+                    if let Some(type_node) = child.child_by_field_name("type") {
+                        embedded.push(self.get_node_text(&type_node).trim_start_matches('*').to_string());
+                    }
+                }
+            }
+        }
+
+        embedded
+    }
+
     fn extract_struct_fields(&self, struct_node: &Node) -> Vec<Attribute> {
         let mut fields = Vec::new();
 
-        if let Some(body) = struct_node.child_by_field_name("body") {
+        if let Some(body) = self.field_list(struct_node) {
             let mut cursor = body.walk();
             for child in body.children(&mut cursor) {
                 if child.kind() == "field_declaration" {
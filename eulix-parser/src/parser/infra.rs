@@ -0,0 +1,465 @@
+// parser/src/parser/infra.rs
+//! Dockerfiles, compose files, Kubernetes manifests, GitHub workflows and
+//! Terraform, reduced to what deployment questions need: services, images,
+//! ports, env var names and commands. YAML is read line by line by
+//! indentation, which covers how these files are written in practice without
+//! a YAML dependency. Env var values are never kept, they are often secrets.
+
+use crate::kb::types::{InfraFile, InfraService};
+use std::fs;
+use std::path::Path;
+
+/// Whether a file may be infrastructure config, the content decides
+pub fn is_candidate(path: &Path) -> bool {
+    let name = path.file_name().and_then(|n| n.to_str()).unwrap_or("");
+    name.starts_with("Dockerfile")
+        || name.ends_with(".dockerfile")
+        || matches!(
+            path.extension().and_then(|ext| ext.to_str()),
+            Some("yml") | Some("yaml") | Some("tf")
+        )
+}
+
+/// None when the file turns out not to be infrastructure config
+pub fn parse_file(path: &Path, relative_path: &str) -> Result<Option<InfraFile>, Box<dyn std::error::Error>> {
+    let content = fs::read_to_string(path)?;
+    let name = path.file_name().and_then(|n| n.to_str()).unwrap_or("");
+    let default_name = path
+        .parent()
+        .and_then(|p| p.file_name())
+        .and_then(|n| n.to_str())
+        .unwrap_or("app");
+
+    let (kind, services) = if name.starts_with("Dockerfile") || name.ends_with(".dockerfile") {
+        ("dockerfile", parse_dockerfile(&content, default_name))
+    } else if relative_path.ends_with(".tf") {
+        ("terraform", parse_terraform(&content))
+    } else {
+        match yaml_kind(&content, relative_path) {
+            Some(kind) => (kind, parse_yaml(&content, kind)),
+            None => return Ok(None),
+        }
+    };
+
+    if services.is_empty() {
+        return Ok(None);
+    }
+    Ok(Some(InfraFile {
+        path: relative_path.to_string(),
+        kind: kind.to_string(),
+        services,
+    }))
+}
+
+fn service(name: &str, kind: &str, line: usize) -> InfraService {
+    InfraService {
+        name: name.to_string(),
+        kind: kind.to_string(),
+        line_start: line,
+        line_end: line,
+        image: None,
+        command: None,
+        ports: vec![],
+        env: vec![],
+        depends_on: vec![],
+    }
+}
+
+fn push_unique(list: &mut Vec<String>, value: &str) {
+    let value = unquote(value);
+    if !value.is_empty() && !list.iter().any(|v| v == value) {
+        list.push(value.to_string());
+    }
+}
+
+fn unquote(s: &str) -> &str {
+    s.trim().trim_matches(|c| c == '"' || c == '\'')
+}
+
+/// Env var names from "A=1 B=2", "A 1" (ENV) or "A" (ARG)
+fn env_names(args: &str, list: &mut Vec<String>) {
+    if !args.contains('=') {
+        if let Some(name) = args.split_whitespace().next() {
+            push_unique(list, name);
+        }
+        return;
+    }
+    for part in args.split_whitespace() {
+        if let Some((name, _)) = part.split_once('=') {
+            push_unique(list, name);
+        }
+    }
+}
+
+fn parse_dockerfile(content: &str, default_name: &str) -> Vec<InfraService> {
+    let mut services: Vec<InfraService> = Vec::new();
+    let mut pending = String::new();
+    let mut start = 0;
+
+    for (i, raw) in content.lines().enumerate() {
+        let line = raw.trim();
+        if pending.is_empty() {
+            if line.is_empty() || line.starts_with('#') {
+                continue;
+            }
+            start = i + 1;
+        }
+        // Instructions continue on the next line after a backslash
+        if let Some(continued) = line.strip_suffix('\\') {
+            pending.push_str(continued);
+            pending.push(' ');
+            continue;
+        }
+        pending.push_str(line);
+        let instruction = std::mem::take(&mut pending);
+
+        let (op, args) = instruction.split_once(char::is_whitespace).unwrap_or((&instruction, ""));
+        let args = args.trim();
+        match op.to_uppercase().as_str() {
+            "FROM" => {
+                let words: Vec<&str> = args.split_whitespace().filter(|w| !w.starts_with("--")).collect();
+                let name = match words.as_slice() {
+                    [_, as_, name] if as_.eq_ignore_ascii_case("as") => *name,
+                    _ => default_name,
+                };
+                let mut stage = service(name, "stage", start);
+                stage.image = words.first().map(|w| w.to_string());
+                services.push(stage);
+            }
+            op => {
+                let Some(stage) = services.last_mut() else { continue };
+                stage.line_end = i + 1;
+                match op {
+                    "EXPOSE" => args.split_whitespace().for_each(|p| push_unique(&mut stage.ports, p)),
+                    "ENV" | "ARG" => env_names(args, &mut stage.env),
+                    "CMD" | "ENTRYPOINT" => stage.command = Some(args.to_string()),
+                    _ => {}
+                }
+            }
+        }
+    }
+    services
+}
+
+/// One YAML line, list items keep the indent of their dash
+struct Line<'a> {
+    no: usize,
+    indent: usize,
+    list: bool,
+    key: Option<&'a str>,
+    value: &'a str,
+}
+
+fn yaml_lines(content: &str) -> Vec<Line<'_>> {
+    let mut lines = Vec::new();
+    for (i, raw) in content.lines().enumerate() {
+        let trimmed = raw.trim_start();
+        if trimmed.is_empty() || trimmed.starts_with('#') {
+            continue;
+        }
+        let indent = raw.len() - trimmed.len();
+        let mut text = match trimmed.find(" #") {
+            Some(pos) => trimmed[..pos].trim_end(),
+            None => trimmed.trim_end(),
+        };
+        let list = text == "-" || text.starts_with("- ");
+        if list {
+            text = text[1..].trim_start();
+        }
+        let (key, value) = match text.split_once(": ") {
+            Some((k, v)) if !k.contains(' ') || k.starts_with('"') => (Some(unquote(k)), v.trim()),
+            _ => match text.strip_suffix(':') {
+                Some(k) if !k.contains(' ') => (Some(unquote(k)), ""),
+                _ => (None, text),
+            },
+        };
+        lines.push(Line { no: i + 1, indent, list, key, value });
+    }
+    lines
+}
+
+/// compose, kubernetes or workflow, from the top level keys
+fn yaml_kind(content: &str, relative_path: &str) -> Option<&'static str> {
+    let top: Vec<&str> = yaml_lines(content)
+        .into_iter()
+        .filter(|l| l.indent == 0 && !l.list)
+        .filter_map(|l| l.key)
+        .collect();
+    if top.contains(&"services") {
+        Some("compose")
+    } else if top.contains(&"apiVersion") && top.contains(&"kind") {
+        Some("kubernetes")
+    } else if top.contains(&"jobs") && (top.contains(&"on") || relative_path.contains(".github/workflows")) {
+        Some("workflow")
+    } else {
+        None
+    }
+}
+
+/// A mapping key whose children follow, and the indent of its first child
+struct Frame<'a> {
+    indent: usize,
+    key: &'a str,
+    child_indent: Option<usize>,
+}
+
+fn parse_yaml(content: &str, kind: &str) -> Vec<InfraService> {
+    let mut services: Vec<InfraService> = Vec::new();
+    let mut stack: Vec<Frame> = Vec::new();
+    let mut current: Option<usize> = None; // index into services
+    let mut object_kind = "";
+    let mut object_name = String::new();
+
+    for line in yaml_lines(content) {
+        if kind == "kubernetes" && line.indent == 0 && line.value == "---" {
+            stack.clear();
+            current = None;
+            continue;
+        }
+        // Leave the keys this line isn't under. A list item at a key's own
+        // indent is still its child, as in "ports:\n- 80:80".
+        while let Some(f) = stack.last() {
+            let item_of_key = line.list && f.indent == line.indent && f.child_indent.map_or(true, |c| c == line.indent);
+            if f.indent < line.indent || item_of_key {
+                break;
+            }
+            stack.pop();
+        }
+        if let Some(parent) = stack.last_mut() {
+            parent.child_indent.get_or_insert(line.indent);
+        }
+        if line.indent == 0 {
+            current = None;
+        }
+        let parent = stack.last().map(|f| f.key).unwrap_or("");
+        let at_child = stack.last().map_or(false, |f| f.child_indent == Some(line.indent));
+        let depth = stack.len();
+
+        // A new service
+        match (kind, line.key) {
+            ("compose", Some(name)) | ("workflow", Some(name))
+                if depth == 1 && !line.list && (parent == "services" || parent == "jobs") =>
+            {
+                let kind_name = if kind == "compose" { "service" } else { "job" };
+                services.push(service(name, kind_name, line.no));
+                current = Some(services.len() - 1);
+            }
+            ("kubernetes", Some("kind")) if depth == 0 => object_kind = line.value,
+            ("kubernetes", Some("name")) if depth == 1 && parent == "metadata" => {
+                object_name = unquote(line.value).to_string()
+            }
+            ("kubernetes", Some("name")) if line.list && (parent == "containers" || parent == "initContainers") => {
+                let name = format!("{}/{}", object_name, unquote(line.value));
+                let label = if object_kind.is_empty() { "container" } else { object_kind };
+                services.push(service(&name, &label.to_lowercase(), line.no));
+                current = Some(services.len() - 1);
+            }
+            _ => {}
+        }
+
+        if let Some(i) = current {
+            let svc = &mut services[i];
+            svc.line_end = line.no;
+            match (line.key, parent) {
+                (Some("image"), _) => svc.image = Some(unquote(line.value).to_string()),
+                (Some("container"), _) if !line.value.is_empty() => svc.image = Some(unquote(line.value).to_string()),
+                (Some("command"), _) | (Some("entrypoint"), _) | (Some("run"), "steps") if !line.value.is_empty() => {
+                    svc.command.get_or_insert(unquote(line.value).to_string());
+                }
+                (Some("containerPort"), _) | (Some("published"), "ports") => push_unique(&mut svc.ports, line.value),
+                (Some("name"), "env") if line.list && at_child => push_unique(&mut svc.env, line.value),
+                (Some(name), "environment") | (Some(name), "env") if !line.list && at_child => {
+                    push_unique(&mut svc.env, name)
+                }
+                (Some(name), "depends_on") | (Some(name), "needs") if at_child && line.value.is_empty() => {
+                    push_unique(&mut svc.depends_on, name)
+                }
+                (None, "ports") if line.list => push_unique(&mut svc.ports, line.value),
+                (None, "environment") if line.list => {
+                    let name = unquote(line.value).split('=').next().unwrap_or("");
+                    push_unique(&mut svc.env, name)
+                }
+                (None, "depends_on") | (None, "needs") if line.list => push_unique(&mut svc.depends_on, line.value),
+                (Some("needs"), _) | (Some("depends_on"), _) if !line.value.is_empty() => {
+                    inline_list(line.value).for_each(|v| push_unique(&mut svc.depends_on, v))
+                }
+                (Some("ports"), _) if line.value.starts_with('[') => {
+                    inline_list(line.value).for_each(|v| push_unique(&mut svc.ports, v))
+                }
+                _ => {}
+            }
+        }
+
+        if let (Some(key), "") = (line.key, line.value) {
+            stack.push(Frame { indent: line.indent + if line.list { 2 } else { 0 }, key, child_indent: None });
+        }
+    }
+    services
+}
+
+/// "[a, b]" or a single "a"
+fn inline_list(value: &str) -> impl Iterator<Item = &str> {
+    value.trim_matches(|c| c == '[' || c == ']').split(',').map(str::trim)
+}
+
+/// resource and module blocks become services, variables are collected as
+/// the env a deployment needs
+fn parse_terraform(content: &str) -> Vec<InfraService> {
+    let mut services: Vec<InfraService> = Vec::new();
+    let mut variables = service("variables", "variables", 0);
+    let mut depth = 0i32;
+    let mut current: Option<usize> = None;
+
+    for (i, raw) in content.lines().enumerate() {
+        let line = raw.trim();
+        if line.starts_with('#') || line.starts_with("//") {
+            continue;
+        }
+        if depth == 0 {
+            let words: Vec<&str> = line.split_whitespace().map(unquote).collect();
+            match words.as_slice() {
+                ["resource", kind, name, ..] | ["data", kind, name, ..] => {
+                    services.push(service(&format!("{}.{}", kind, name), "resource", i + 1));
+                    current = Some(services.len() - 1);
+                }
+                ["module", name, ..] => {
+                    services.push(service(&format!("module.{}", name), "module", i + 1));
+                    current = Some(services.len() - 1);
+                }
+                ["variable", name, ..] => {
+                    if variables.line_start == 0 {
+                        variables.line_start = i + 1;
+                    }
+                    variables.line_end = i + 1;
+                    push_unique(&mut variables.env, name);
+                }
+                _ => {}
+            }
+        } else if depth == 1 {
+            if let (Some(i_svc), Some((key, value))) = (current, line.split_once('=')) {
+                let svc = &mut services[i_svc];
+                match key.trim() {
+                    "image" | "ami" | "source" => svc.image = Some(unquote(value).to_string()),
+                    "port" | "container_port" | "from_port" => push_unique(&mut svc.ports, value),
+                    _ => {}
+                }
+            }
+        }
+        depth += line.matches('{').count() as i32 - line.matches('}').count() as i32;
+        if let Some(i_svc) = current {
+            services[i_svc].line_end = i + 1;
+            if depth <= 0 {
+                current = None;
+            }
+        }
+    }
+
+    if !variables.env.is_empty() {
+        services.push(variables);
+    }
+    services
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn named<'a>(services: &'a [InfraService], name: &str) -> &'a InfraService {
+        services.iter().find(|s| s.name == name).expect(name)
+    }
+
+    #[test]
+    fn test_dockerfile_stages() {
+        let content = "FROM golang:1.23 AS build\nARG VERSION\nRUN go build \\\n  -o /app .\n\n# runtime\nFROM --platform=linux/amd64 alpine:3.20\nENV PORT=8080 DB_PASSWORD=secret\nEXPOSE 8080 9090\nCMD [\"/app\", \"serve\"]\n";
+        let services = parse_dockerfile(content, "api");
+
+        assert_eq!(services.len(), 2);
+        let build = named(&services, "build");
+        assert_eq!(build.image.as_deref(), Some("golang:1.23"));
+        assert_eq!(build.env, vec!["VERSION"]);
+        assert_eq!((build.line_start, build.line_end), (1, 4));
+
+        let runtime = named(&services, "api");
+        assert_eq!(runtime.image.as_deref(), Some("alpine:3.20"));
+        assert_eq!(runtime.env, vec!["PORT", "DB_PASSWORD"]);
+        assert_eq!(runtime.ports, vec!["8080", "9090"]);
+        assert_eq!(runtime.command.as_deref(), Some("[\"/app\", \"serve\"]"));
+        assert!(!format!("{:?}", services).contains("secret"));
+    }
+
+    #[test]
+    fn test_compose_services() {
+        let content = "services:\n  api:\n    image: \"shop/api:latest\"\n    ports:\n    - \"8080:8080\"\n    environment:\n      DATABASE_URL: postgres://u:p@db/shop\n    depends_on:\n      - db\n  db:\n    image: postgres:16\n    environment:\n      - POSTGRES_PASSWORD=secret\nvolumes:\n  data:\n";
+        assert_eq!(yaml_kind(content, "docker-compose.yml"), Some("compose"));
+        let services = parse_yaml(content, "compose");
+
+        assert_eq!(services.len(), 2);
+        let api = named(&services, "api");
+        assert_eq!(api.kind, "service");
+        assert_eq!(api.image.as_deref(), Some("shop/api:latest"));
+        assert_eq!(api.ports, vec!["8080:8080"]);
+        assert_eq!(api.env, vec!["DATABASE_URL"]);
+        assert_eq!(api.depends_on, vec!["db"]);
+
+        let db = named(&services, "db");
+        assert_eq!(db.env, vec!["POSTGRES_PASSWORD"]);
+        assert!(!format!("{:?}", services).contains("secret"));
+    }
+
+    #[test]
+    fn test_kubernetes_containers() {
+        let content = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\nspec:\n  template:\n    spec:\n      containers:\n      - name: server\n        image: shop/api:1.2\n        ports:\n        - containerPort: 8080\n        env:\n        - name: LOG_LEVEL\n          value: debug\n";
+        assert_eq!(yaml_kind(content, "k8s/api.yaml"), Some("kubernetes"));
+        let services = parse_yaml(content, "kubernetes");
+
+        assert_eq!(services.len(), 1);
+        let server = named(&services, "api/server");
+        assert_eq!(server.kind, "deployment");
+        assert_eq!(server.image.as_deref(), Some("shop/api:1.2"));
+        assert_eq!(server.ports, vec!["8080"]);
+        assert_eq!(server.env, vec!["LOG_LEVEL"]);
+    }
+
+    #[test]
+    fn test_workflow_jobs() {
+        let content = "name: ci\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n      - run: go test ./...\n  deploy:\n    needs: [test]\n    runs-on: ubuntu-latest\n";
+        assert_eq!(yaml_kind(content, ".github/workflows/ci.yml"), Some("workflow"));
+        let services = parse_yaml(content, "workflow");
+
+        assert_eq!(services.len(), 2);
+        assert_eq!(named(&services, "test").command.as_deref(), Some("go test ./..."));
+        assert_eq!(named(&services, "deploy").depends_on, vec!["test"]);
+    }
+
+    #[test]
+    fn test_terraform_blocks() {
+        let content = "variable \"region\" {}\n\nresource \"aws_instance\" \"web\" {\n  ami = \"ami-123\"\n  tags = {\n    port = 1\n  }\n}\n\nmodule \"vpc\" {\n  source = \"./vpc\"\n}\n";
+        let services = parse_terraform(content);
+
+        let web = named(&services, "aws_instance.web");
+        assert_eq!(web.image.as_deref(), Some("ami-123"));
+        assert!(web.ports.is_empty());
+        assert_eq!((web.line_start, web.line_end), (3, 8));
+        assert_eq!(named(&services, "module.vpc").image.as_deref(), Some("./vpc"));
+        assert_eq!(named(&services, "variables").env, vec!["region"]);
+    }
+
+    #[test]
+    fn test_not_infrastructure() {
+        assert_eq!(yaml_kind("name: lint\nrules:\n  - a\n", "config.yml"), None);
+        assert_eq!(yaml_kind("", "empty.yaml"), None);
+        assert_eq!(yaml_kind(": : :\n\t- [\n", "broken.yaml"), None);
+        assert!(parse_dockerfile("RUN echo no base\n\\\n", "app").is_empty());
+        assert!(parse_terraform("resource {\n}}}\n").is_empty());
+    }
+
+    #[test]
+    fn test_is_candidate() {
+        assert!(is_candidate(Path::new("Dockerfile")));
+        assert!(is_candidate(Path::new("Dockerfile.prod")));
+        assert!(is_candidate(Path::new("build/api.dockerfile")));
+        assert!(is_candidate(Path::new("deploy/main.tf")));
+        assert!(is_candidate(Path::new("compose.yaml")));
+        assert!(!is_candidate(Path::new("main.go")));
+    }
+}
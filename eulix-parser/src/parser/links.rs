@@ -0,0 +1,678 @@
+// parser/src/parser/links.rs
+//! Calls that cross from one part of a polyglot repo to another without a
+//! direct call the parsers can see: an HTTP client hitting a route, a gRPC
+//! stub reaching the service implementation, Go or Python calling into C.
+//! Found by heuristics after parsing, each link is marked on the call with
+//! how it crosses and added to the call graph with that edge type.
+
+use crate::kb::types::*;
+use std::collections::{HashMap, HashSet};
+
+#[derive(Debug, Default)]
+pub struct LinkCounts {
+    pub http: usize,
+    pub grpc: usize,
+    pub ffi: usize,
+}
+
+impl LinkCounts {
+    pub fn total(&self) -> usize {
+        self.http + self.grpc + self.ffi
+    }
+}
+
+/// Call names of HTTP clients across requests, httpx, net/http and fetch
+const HTTP_CALLS: &[&str] = &[
+    "get", "post", "put", "patch", "delete", "head", "request", "fetch", "urlopen",
+    "Get", "Post", "PostForm", "Head", "NewRequest", "NewRequestWithContext",
+];
+
+const HTTP_METHODS: &[&str] = &["GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"];
+
+/// Builtins a C file may define too, never linked over FFI
+const BUILTINS: &[&str] = &[
+    "len", "append", "make", "new", "cap", "copy", "close", "delete", "panic", "recover",
+    "print", "println", "open", "range", "min", "max", "abs", "sum", "format", "str", "int",
+];
+
+/// A function or method in kb.structure
+#[derive(Debug, Clone)]
+struct Site {
+    file: String,
+    class: Option<usize>,
+    func: usize,
+}
+
+#[derive(Debug, Clone)]
+struct Target {
+    name: String,
+    id: String,
+    file: String,
+}
+
+struct Route {
+    segments: Vec<String>,
+    methods: Vec<String>,
+    target: Target,
+}
+
+struct Link {
+    kind: &'static str,
+    caller: Site,
+    caller_id: String,
+    call: Option<usize>, // the existing call, None adds one
+    line: usize,
+    conditional: bool,
+    context: String,
+    arg: Option<String>,
+    target: Target,
+}
+
+pub fn link(kb: &mut KnowledgeBase) -> LinkCounts {
+    let links = find_links(kb);
+    let mut counts = LinkCounts::default();
+
+    for l in links {
+        match l.kind {
+            "http" => counts.http += 1,
+            "grpc" => counts.grpc += 1,
+            _ => counts.ffi += 1,
+        }
+
+        let caller_file = l.caller.file.clone();
+        if let Some(func) = function_mut(kb, &l.caller) {
+            match l.call {
+                Some(i) => {
+                    func.calls[i].link = Some(l.kind.to_string());
+                    func.calls[i].defined_in = Some(l.target.file.clone());
+                }
+                None => func.calls.push(FunctionCall {
+                    callee: l.target.name.clone(),
+                    defined_in: Some(l.target.file.clone()),
+                    line: l.line,
+                    args: l.arg.into_iter().collect(),
+                    is_conditional: l.conditional,
+                    context: l.context.clone(),
+                    link: Some(l.kind.to_string()),
+                }),
+            }
+        }
+
+        if let Some(target) = find_by_id(kb, &l.target.file, &l.target.id) {
+            if !target.called_by.iter().any(|c| c.function == l.caller_id && c.line == l.line) {
+                target.called_by.push(CallerInfo {
+                    function: l.caller_id.clone(),
+                    file: caller_file,
+                    line: l.line,
+                });
+            }
+        }
+
+        kb.call_graph.edges.push(CallGraphEdge {
+            from: l.caller_id,
+            to: l.target.id,
+            edge_type: l.kind.to_string(),
+            conditional: l.conditional,
+            call_site_line: l.line,
+        });
+    }
+
+    counts
+}
+
+fn find_links(kb: &KnowledgeBase) -> Vec<Link> {
+    let routes = routes(kb);
+    let grpc_impls = grpc_impls(kb);
+    let (c_functions, defined_by_language) = c_functions(kb);
+
+    let mut links = Vec::new();
+    for (path, file) in &kb.structure {
+        let uses_grpc = imports_any(file, |m| m.contains("grpc") || m.ends_with("_pb2") || m.ends_with("pb"));
+        let uses_ffi = file.language != "c"
+            && imports_any(file, |m| m == "C" || m == "ctypes" || m == "cffi" || m.starts_with("ctypes."));
+        let own_names = defined_by_language.get(&file.language);
+
+        for (site, func) in functions(path, file) {
+            for (i, call) in func.calls.iter().enumerate() {
+                let base = Link {
+                    kind: "",
+                    caller: site.clone(),
+                    caller_id: func.id.clone(),
+                    call: Some(i),
+                    line: call.line,
+                    conditional: call.is_conditional,
+                    context: call.context.clone(),
+                    arg: None,
+                    target: Target { name: String::new(), id: String::new(), file: String::new() },
+                };
+
+                if HTTP_CALLS.contains(&call.callee.as_str()) {
+                    if let Some((arg, route)) = match_route(&routes, &call.callee, &call.args) {
+                        if route.target.id != func.id {
+                            links.push(Link { kind: "http", call: None, arg: Some(arg), target: route.target.clone(), ..base });
+                        }
+                        continue;
+                    }
+                }
+
+                if uses_grpc {
+                    if let Some(target) = grpc_impls.get(&call.callee) {
+                        if &target.file != path {
+                            links.push(Link { kind: "grpc", target: target.clone(), ..base });
+                            continue;
+                        }
+                    }
+                }
+
+                if uses_ffi && ffi_candidate(&file.language, &call.callee)
+                    && !own_names.map_or(false, |names| names.contains(&call.callee))
+                {
+                    if let Some(target) = c_functions.get(&call.callee) {
+                        links.push(Link { kind: "ffi", target: target.clone(), ..base });
+                    }
+                }
+            }
+        }
+    }
+    links
+}
+
+fn functions<'a>(path: &'a str, file: &'a FileData) -> impl Iterator<Item = (Site, &'a Function)> + 'a {
+    let top = file.functions.iter().enumerate().map(move |(i, f)| {
+        (Site { file: path.to_string(), class: None, func: i }, f)
+    });
+    let methods = file.classes.iter().enumerate().flat_map(move |(c, class)| {
+        class.methods.iter().enumerate().map(move |(i, f)| {
+            (Site { file: path.to_string(), class: Some(c), func: i }, f)
+        })
+    });
+    top.chain(methods)
+}
+
+fn function_mut<'a>(kb: &'a mut KnowledgeBase, site: &Site) -> Option<&'a mut Function> {
+    let file = kb.structure.get_mut(&site.file)?;
+    match site.class {
+        Some(c) => file.classes.get_mut(c)?.methods.get_mut(site.func),
+        None => file.functions.get_mut(site.func),
+    }
+}
+
+fn find_by_id<'a>(kb: &'a mut KnowledgeBase, file: &str, id: &str) -> Option<&'a mut Function> {
+    let file = kb.structure.get_mut(file)?;
+    if let Some(i) = file.functions.iter().position(|f| f.id == id) {
+        return file.functions.get_mut(i);
+    }
+    file.classes
+        .iter_mut()
+        .flat_map(|c| c.methods.iter_mut())
+        .find(|f| f.id == id)
+}
+
+fn imports_any(file: &FileData, matches: impl Fn(&str) -> bool) -> bool {
+    file.imports
+        .iter()
+        .any(|i| matches(i.module.trim_matches('"')))
+}
+
+// HTTP
+
+fn routes(kb: &KnowledgeBase) -> Vec<Route> {
+    let mut routes = Vec::new();
+    for ep in &kb.entry_points {
+        let (Some(path), true) = (ep.path.as_ref(), ep.entry_type == "api_endpoint") else { continue };
+        let Some(file) = kb.structure.get(&ep.file) else { continue };
+        let Some((_, handler)) = functions(&ep.file, file).find(|(_, f)| f.name == ep.function && f.line_start == ep.line)
+            .or_else(|| functions(&ep.file, file).find(|(_, f)| f.name == ep.function)) else { continue };
+
+        routes.push(Route {
+            segments: segments(path),
+            methods: ep.methods.clone().unwrap_or_default(),
+            target: Target { name: handler.name.clone(), id: handler.id.clone(), file: ep.file.clone() },
+        });
+    }
+    routes
+}
+
+/// Path segments with parameters as "*": <int:id>, {id}, :id, ${id}, %s
+fn segments(path: &str) -> Vec<String> {
+    path.split('/')
+        .filter(|s| !s.is_empty())
+        .map(|s| {
+            if s.contains('{') || s.contains('<') || s.starts_with(':') || s.contains('%') {
+                "*".to_string()
+            } else {
+                s.to_string()
+            }
+        })
+        .collect()
+}
+
+/// The URL path in a call argument, from a string literal, f-string or
+/// template. A literal ending in / followed by concatenation gets a
+/// parameter segment.
+fn url_path(arg: &str) -> Option<String> {
+    let start = arg.find(|c| c == '"' || c == '\'' || c == '`')?;
+    let quote = arg[start..].chars().next()?;
+    let end = arg[start + 1..].find(quote)? + start + 1;
+    let mut literal = &arg[start + 1..end];
+
+    // A leading base URL variable, `${base}/users` or f"{BASE}/users"
+    if literal.starts_with("${") || literal.starts_with('{') {
+        literal = &literal[literal.find('}')? + 1..];
+    }
+
+    let mut path = match literal.find("://") {
+        Some(scheme) => {
+            let rest = &literal[scheme + 3..];
+            rest.find('/').map(|i| rest[i..].to_string()).unwrap_or_else(|| "/".to_string())
+        }
+        None if literal.starts_with('/') => literal.to_string(),
+        None => return None,
+    };
+    if let Some(i) = path.find(|c| c == '?' || c == '#') {
+        path.truncate(i);
+    }
+    let rest = arg[end + 1..].trim_start();
+    if path.ends_with('/') && (rest.starts_with('+') || rest.starts_with('%') || rest.starts_with(".format")) {
+        path.push_str("{param}");
+    }
+    Some(path)
+}
+
+/// The route a client call's URL argument hits, preferring the one with the
+/// most literal segments in common. Clients often add a prefix like /api the
+/// route doesn't have, so a route may match the end of the path.
+fn match_route<'a>(routes: &'a [Route], callee: &str, args: &[String]) -> Option<(String, &'a Route)> {
+    let method = match callee.to_lowercase().as_str() {
+        m @ ("get" | "post" | "put" | "patch" | "delete" | "head") => Some(m.to_uppercase()),
+        "postform" => Some("POST".to_string()),
+        // request("POST", url), NewRequest("POST", url, body)
+        _ => args.iter().find_map(|a| {
+            let a = a.trim_matches(|c| c == '"' || c == '\'').to_uppercase();
+            HTTP_METHODS.contains(&a.as_str()).then_some(a)
+        }),
+    };
+
+    let (arg, path) = args.iter().find_map(|a| url_path(a).map(|p| (a.clone(), p)))?;
+    let called = segments(&path);
+
+    let mut best: Option<(usize, &Route)> = None;
+    for route in routes {
+        if let Some(ref m) = method {
+            if !route.methods.is_empty() && !route.methods.contains(m) {
+                continue;
+            }
+        }
+        if route.segments.is_empty() || route.segments.len() > called.len() {
+            continue;
+        }
+        let tail = &called[called.len() - route.segments.len()..];
+        let mut literal = 0;
+        let matches = tail.iter().zip(&route.segments).all(|(a, b)| {
+            if a == "*" || b == "*" {
+                true
+            } else {
+                literal += (a == b) as usize;
+                a == b
+            }
+        });
+        // A route of only parameters would match any URL
+        if !matches || literal == 0 {
+            continue;
+        }
+        // An exact length match beats a prefixed one
+        let score = literal * 2 + (route.segments.len() == called.len()) as usize;
+        if best.map_or(true, |(s, _)| score > s) {
+            best = Some((score, route));
+        }
+    }
+    best.map(|(_, route)| (arg, route))
+}
+
+// gRPC
+
+/// RPC method names on service implementations: Python classes based on a
+/// generated *Servicer, Go structs embedding Unimplemented*Server. The
+/// generated code itself is skipped, its methods are stubs.
+fn grpc_impls(kb: &KnowledgeBase) -> HashMap<String, Target> {
+    let mut impls = HashMap::new();
+    for (path, file) in &kb.structure {
+        if path.ends_with("_pb2_grpc.py") || path.ends_with(".pb.go") {
+            continue;
+        }
+        for class in &file.classes {
+            let is_impl = class.bases.iter().any(|b| {
+                let b = b.trim_start_matches('*');
+                let name = b.rsplit('.').next().unwrap_or(b);
+                name.ends_with("Servicer") || (name.starts_with("Unimplemented") && name.ends_with("Server"))
+            });
+            if !is_impl {
+                continue;
+            }
+            for method in &class.methods {
+                if method.name.starts_with(|c: char| c.is_uppercase()) {
+                    impls.insert(method.name.clone(), Target {
+                        name: method.name.clone(),
+                        id: method.id.clone(),
+                        file: path.clone(),
+                    });
+                }
+            }
+        }
+    }
+    impls
+}
+
+// FFI
+
+/// Calls only keep the last name, so C.hash and fmt.Println look alike from
+/// Go. Exported Go names start upper case and C functions rarely do.
+fn ffi_candidate(language: &str, callee: &str) -> bool {
+    if BUILTINS.contains(&callee) {
+        return false;
+    }
+    language != "go" || !callee.starts_with(|c: char| c.is_uppercase())
+}
+
+/// C functions by name, and every function name defined per language so a
+/// Python or Go function isn't mistaken for the C one it shares a name with
+fn c_functions(kb: &KnowledgeBase) -> (HashMap<String, Target>, HashMap<String, HashSet<String>>) {
+    let mut c = HashMap::new();
+    let mut by_language: HashMap<String, HashSet<String>> = HashMap::new();
+    for (path, file) in &kb.structure {
+        for (_, func) in functions(path, file) {
+            by_language.entry(file.language.clone()).or_default().insert(func.name.clone());
+            if file.language == "c" {
+                c.insert(func.name.clone(), Target {
+                    name: func.name.clone(),
+                    id: func.id.clone(),
+                    file: path.clone(),
+                });
+            }
+        }
+    }
+    (c, by_language)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn call(callee: &str, line: usize, args: &[&str]) -> FunctionCall {
+        FunctionCall {
+            callee: callee.to_string(),
+            defined_in: None,
+            line,
+            args: args.iter().map(|a| a.to_string()).collect(),
+            is_conditional: false,
+            context: "unconditional".to_string(),
+            link: None,
+        }
+    }
+
+    fn function(id: &str, name: &str, line: usize, calls: Vec<FunctionCall>) -> Function {
+        Function {
+            id: id.to_string(),
+            name: name.to_string(),
+            signature: String::new(),
+            params: vec![],
+            return_type: String::new(),
+            docstring: String::new(),
+            line_start: line,
+            line_end: line + 5,
+            calls,
+            called_by: vec![],
+            variables: vec![],
+            control_flow: ControlFlow::default(),
+            exceptions: ExceptionInfo::default(),
+            complexity: 1,
+            is_async: false,
+            decorators: vec![],
+            tags: vec![],
+            importance_score: 0.0,
+        }
+    }
+
+    fn file(language: &str, imports: &[&str], functions: Vec<Function>, classes: Vec<Class>) -> FileData {
+        FileData {
+            language: language.to_string(),
+            loc: 10,
+            imports: imports
+                .iter()
+                .map(|m| Import { module: m.to_string(), items: vec![], import_type: "external".to_string() })
+                .collect(),
+            functions,
+            classes,
+            global_vars: vec![],
+            todos: vec![],
+            security_notes: vec![],
+        }
+    }
+
+    fn class(name: &str, bases: &[&str], methods: Vec<Function>) -> Class {
+        Class {
+            id: name.to_string(),
+            name: name.to_string(),
+            bases: bases.iter().map(|b| b.to_string()).collect(),
+            docstring: String::new(),
+            line_start: 1,
+            line_end: 50,
+            methods,
+            attributes: vec![],
+            decorators: vec![],
+        }
+    }
+
+    fn endpoint(path: &str, function: &str, file: &str, line: usize, methods: &[&str]) -> EntryPoint {
+        EntryPoint {
+            entry_type: "api_endpoint".to_string(),
+            path: Some(path.to_string()),
+            function: function.to_string(),
+            handler: function.to_string(),
+            file: file.to_string(),
+            line,
+            methods: Some(methods.iter().map(|m| m.to_string()).collect()),
+        }
+    }
+
+    fn knowledge_base(files: Vec<(&str, FileData)>, entry_points: Vec<EntryPoint>) -> KnowledgeBase {
+        KnowledgeBase {
+            metadata: Metadata {
+                project_name: "shop".to_string(),
+                version: "test".to_string(),
+                parsed_at: String::new(),
+                languages: vec![],
+                total_files: files.len(),
+                total_loc: 0,
+                total_functions: 0,
+                total_classes: 0,
+                total_methods: 0,
+            },
+            structure: files.into_iter().map(|(p, f)| (p.to_string(), f)).collect(),
+            call_graph: CallGraph::default(),
+            dependency_graph: DependencyGraph::default(),
+            indices: Indices::default(),
+            entry_points,
+            external_dependencies: vec![],
+            patterns: PatternInfo::default(),
+            documents: vec![],
+            infra: vec![],
+            schema: SchemaView::default(),
+        }
+    }
+
+    fn edges(kb: &KnowledgeBase, edge_type: &str) -> Vec<(String, String)> {
+        kb.call_graph
+            .edges
+            .iter()
+            .filter(|e| e.edge_type == edge_type)
+            .map(|e| (e.from.clone(), e.to.clone()))
+            .collect()
+    }
+
+    #[test]
+    fn test_url_path() {
+        assert_eq!(url_path("\"/api/users\""), Some("/api/users".to_string()));
+        assert_eq!(url_path("f\"{BASE}/orders/{order_id}?full=1\""), Some("/orders/{order_id}".to_string()));
+        assert_eq!(url_path("`${base}/items`"), Some("/items".to_string()));
+        assert_eq!(url_path("'https://shop.example.com/api/carts'"), Some("/api/carts".to_string()));
+        assert_eq!(url_path("\"http://localhost:8080\""), Some("/".to_string()));
+        assert_eq!(url_path("\"/users/\" + user_id"), Some("/users/{param}".to_string()));
+        assert_eq!(url_path("url"), None);
+        assert_eq!(url_path("\"relative/path\""), None);
+        assert_eq!(url_path("\"/never closed"), None);
+    }
+
+    #[test]
+    fn test_segments() {
+        assert_eq!(segments("/users/<int:id>/orders"), vec!["users", "*", "orders"]);
+        assert_eq!(segments("/users/{id}"), vec!["users", "*"]);
+        assert_eq!(segments("/users/:id/"), vec!["users", "*"]);
+        assert!(segments("/").is_empty());
+    }
+
+    #[test]
+    fn test_http_links() {
+        let server = file(
+            "python",
+            &["flask"],
+            vec![
+                function("api.py:get_user", "get_user", 10, vec![]),
+                function("api.py:anything", "anything", 20, vec![]),
+            ],
+            vec![],
+        );
+        let client = file(
+            "javascript",
+            &[],
+            vec![function(
+                "web.js:loadUser",
+                "loadUser",
+                3,
+                vec![
+                    call("fetch", 4, &["`${base}/api/users/${id}`"]),
+                    call("post", 5, &["\"/users/1\""]),
+                    call("get", 6, &["config.url"]),
+                ],
+            )],
+            vec![],
+        );
+        let mut kb = knowledge_base(
+            vec![("api.py", server), ("web.js", client)],
+            vec![
+                endpoint("/users/<int:id>", "get_user", "api.py", 10, &["GET"]),
+                endpoint("/<path>", "anything", "api.py", 20, &[]),
+            ],
+        );
+
+        let counts = link(&mut kb);
+        assert_eq!((counts.http, counts.grpc, counts.ffi), (1, 0, 0));
+        assert_eq!(edges(&kb, "http"), vec![("web.js:loadUser".to_string(), "api.py:get_user".to_string())]);
+
+        let handler = &kb.structure["api.py"].functions[0];
+        assert_eq!(handler.called_by.len(), 1);
+        assert_eq!(handler.called_by[0].file, "web.js");
+        let added = kb.structure["web.js"].functions[0].calls.last().unwrap();
+        assert_eq!((added.callee.as_str(), added.link.as_deref()), ("get_user", Some("http")));
+    }
+
+    #[test]
+    fn test_match_route_methods() {
+        let target = |name: &str| Target { name: name.to_string(), id: name.to_string(), file: "api.go".to_string() };
+        let routes = vec![
+            Route { segments: segments("/orders"), methods: vec!["GET".to_string()], target: target("listOrders") },
+            Route { segments: segments("/orders"), methods: vec!["POST".to_string()], target: target("createOrder") },
+        ];
+
+        let args = vec!["\"POST\"".to_string(), "\"/v1/orders\"".to_string(), "body".to_string()];
+        let (arg, route) = match_route(&routes, "NewRequest", &args).unwrap();
+        assert_eq!(arg, "\"/v1/orders\"");
+        assert_eq!(route.target.name, "createOrder");
+
+        assert!(match_route(&routes, "delete", &["\"/orders\"".to_string()]).is_none());
+        assert!(match_route(&routes, "get", &[]).is_none());
+    }
+
+    #[test]
+    fn test_grpc_links() {
+        let service = file(
+            "python",
+            &["grpc"],
+            vec![],
+            vec![class("OrderService", &["orders_pb2_grpc.OrderServiceServicer"], vec![
+                function("service.py:OrderService.PlaceOrder", "PlaceOrder", 12, vec![]),
+            ])],
+        );
+        let generated = file(
+            "python",
+            &["grpc"],
+            vec![],
+            vec![class("OrderServiceServicer", &["object"], vec![
+                function("orders_pb2_grpc.py:OrderServiceServicer.PlaceOrder", "PlaceOrder", 5, vec![]),
+            ])],
+        );
+        let client = file(
+            "go",
+            &["\"google.golang.org/grpc\""],
+            vec![function("client.go:checkout", "checkout", 8, vec![call("PlaceOrder", 9, &["ctx", "req"])])],
+            vec![],
+        );
+        let mut kb = knowledge_base(
+            vec![("service.py", service), ("orders_pb2_grpc.py", generated), ("client.go", client)],
+            vec![],
+        );
+
+        let counts = link(&mut kb);
+        assert_eq!(counts.grpc, 1);
+        assert_eq!(
+            edges(&kb, "grpc"),
+            vec![("client.go:checkout".to_string(), "service.py:OrderService.PlaceOrder".to_string())]
+        );
+        let linked = &kb.structure["client.go"].functions[0].calls[0];
+        assert_eq!(linked.link.as_deref(), Some("grpc"));
+        assert_eq!(linked.defined_in.as_deref(), Some("service.py"));
+    }
+
+    #[test]
+    fn test_ffi_links() {
+        let native = file(
+            "c",
+            &[],
+            vec![
+                function("hash.c:fast_hash", "fast_hash", 3, vec![]),
+                function("hash.c:len", "len", 20, vec![]),
+                function("hash.c:shared", "shared", 30, vec![]),
+            ],
+            vec![],
+        );
+        let caller = file(
+            "go",
+            &["\"C\""],
+            vec![
+                function(
+                    "hash.go:Sum",
+                    "Sum",
+                    5,
+                    vec![call("fast_hash", 6, &[]), call("len", 7, &[]), call("shared", 8, &[])],
+                ),
+                function("hash.go:shared", "shared", 15, vec![]),
+            ],
+            vec![],
+        );
+        let plain = file("python", &["os"], vec![function("util.py:run", "run", 1, vec![call("fast_hash", 2, &[])])], vec![]);
+        let mut kb = knowledge_base(vec![("hash.c", native), ("hash.go", caller), ("util.py", plain)], vec![]);
+
+        let counts = link(&mut kb);
+        assert_eq!(counts.total(), 1);
+        assert_eq!(edges(&kb, "ffi"), vec![("hash.go:Sum".to_string(), "hash.c:fast_hash".to_string())]);
+        assert!(kb.structure["util.py"].functions[0].calls[0].link.is_none());
+    }
+
+    #[test]
+    fn test_ffi_candidate() {
+        assert!(ffi_candidate("go", "fast_hash"));
+        assert!(!ffi_candidate("go", "Println"));
+        assert!(!ffi_candidate("python", "len"));
+        assert!(ffi_candidate("python", "Compress"));
+    }
+}
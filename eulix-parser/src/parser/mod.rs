@@ -2,5 +2,9 @@
 pub mod python;
 pub mod go;
 pub mod c;
+pub mod docs;
+pub mod infra;
+pub mod schema;
 pub mod language;
 pub mod analyze;
+pub mod links;
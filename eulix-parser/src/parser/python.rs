@@ -368,6 +368,7 @@ impl PythonParser {
                                 args,
                                 is_conditional: context != "unconditional",
                                 context: context.to_string(),
+                                link: None,
                             });
                         }
                     }
@@ -0,0 +1,661 @@
+// parser/src/parser/schema.rs
+//! Database tables from .sql migrations and ORM models. Migrations are
+//! replayed in path order, which is how migration tools name them, so the
+//! result is the schema after the last one. Models cover SQLAlchemy, Django
+//! and GORM, read from the source text rather than the syntax tree since
+//! only a few patterns matter.
+
+use crate::kb::types::{Column, ForeignKey, SchemaView, Table};
+use std::fs;
+use std::path::{Path, PathBuf};
+
+pub fn is_sql(path: &Path) -> bool {
+    path.extension().and_then(|ext| ext.to_str()) == Some("sql")
+}
+
+/// Down migrations undo the up ones, replaying them would drop everything
+fn is_down_migration(relative: &str) -> bool {
+    let name = relative.rsplit('/').next().unwrap_or(relative).to_lowercase();
+    name == "down.sql" || name.ends_with(".down.sql") || name.ends_with("_down.sql")
+}
+
+/// Builds the schema from sql files and the source files already found.
+/// Returns the files that couldn't be read with the error.
+pub fn extract(root: &Path, sql_files: &[PathBuf], source_files: &[PathBuf]) -> (SchemaView, Vec<(String, String)>) {
+    let mut schema = SchemaView::default();
+    let mut failed = Vec::new();
+    let relative = |p: &Path| p.strip_prefix(root).unwrap_or(p).to_string_lossy().replace('\\', "/");
+
+    let mut sql_files: Vec<&PathBuf> = sql_files.iter().collect();
+    sql_files.sort();
+    for file in sql_files {
+        let rel = relative(file);
+        if is_down_migration(&rel) {
+            continue;
+        }
+        match fs::read_to_string(file) {
+            Ok(content) => apply_sql(&mut schema, &content, &rel),
+            Err(e) => failed.push((rel, e.to_string())),
+        }
+    }
+
+    for file in source_files {
+        let ext = file.extension().and_then(|e| e.to_str()).unwrap_or("");
+        if ext != "py" && ext != "go" {
+            continue;
+        }
+        let Ok(content) = fs::read_to_string(file) else { continue };
+        let rel = relative(file);
+        if ext == "py" && (content.contains("__tablename__") || content.contains("models.Model")) {
+            python_models(&mut schema, &content, &rel);
+        } else if ext == "go" && content.contains("gorm:\"") {
+            gorm_models(&mut schema, &content, &rel);
+        }
+    }
+
+    schema.tables.sort_by(|a, b| a.name.cmp(&b.name));
+    (schema, failed)
+}
+
+fn table<'a>(schema: &'a mut SchemaView, name: &str, source: &str, line: usize, origin: &str) -> &'a mut Table {
+    if let Some(i) = schema.tables.iter().position(|t| t.name == name) {
+        return &mut schema.tables[i];
+    }
+    schema.tables.push(Table {
+        name: name.to_string(),
+        source: source.to_string(),
+        line,
+        origin: origin.to_string(),
+        columns: vec![],
+        foreign_keys: vec![],
+    });
+    schema.tables.last_mut().unwrap()
+}
+
+/// "public"."users" and `users` are both users
+fn ident(s: &str) -> String {
+    let s = s.trim().trim_end_matches(|c| c == ',' || c == ';');
+    let last = s.rsplit('.').next().unwrap_or(s);
+    last.trim_matches(|c| c == '"' || c == '`' || c == '[' || c == ']' || c == '\'')
+        .to_lowercase()
+}
+
+fn idents(list: &str) -> Vec<String> {
+    list.trim_matches(|c| c == '(' || c == ')')
+        .split(',')
+        .map(ident)
+        .filter(|s| !s.is_empty())
+        .collect()
+}
+
+// SQL
+
+/// Statements with the line they start on, comments removed. Everything after
+/// a goose or dbmate down marker is dropped.
+fn statements(content: &str) -> Vec<(usize, String)> {
+    let mut out = Vec::new();
+    let mut current = String::new();
+    let mut start = 0;
+    let mut in_block = false;
+
+    for (i, raw) in content.lines().enumerate() {
+        let mut line = raw;
+        if line.contains("+goose Down") || line.contains("migrate:down") {
+            break;
+        }
+        if in_block {
+            match line.find("*/") {
+                Some(end) => {
+                    in_block = false;
+                    line = &line[end + 2..];
+                }
+                None => continue,
+            }
+        }
+        let mut text = String::new();
+        let mut rest = line;
+        loop {
+            let dash = rest.find("--");
+            let block = rest.find("/*");
+            match (dash, block) {
+                (Some(d), b) if b.map_or(true, |b| d < b) => {
+                    text.push_str(&rest[..d]);
+                    break;
+                }
+                (_, Some(b)) => {
+                    text.push_str(&rest[..b]);
+                    match rest[b + 2..].find("*/") {
+                        Some(end) => rest = &rest[b + 2 + end + 2..],
+                        None => {
+                            in_block = true;
+                            break;
+                        }
+                    }
+                }
+                _ => {
+                    text.push_str(rest);
+                    break;
+                }
+            }
+        }
+
+        for part in text.split_inclusive(';') {
+            if current.trim().is_empty() {
+                start = i + 1;
+            }
+            current.push_str(part.trim_end_matches(';'));
+            current.push(' ');
+            if part.ends_with(';') {
+                out.push((start, std::mem::take(&mut current)));
+            }
+        }
+    }
+    if !current.trim().is_empty() {
+        out.push((start, current));
+    }
+    out
+}
+
+/// Splits at commas outside parentheses
+fn split_top(s: &str) -> Vec<String> {
+    let mut parts = Vec::new();
+    let mut depth = 0;
+    let mut current = String::new();
+    for c in s.chars() {
+        match c {
+            '(' => depth += 1,
+            ')' => depth -= 1,
+            ',' if depth == 0 => {
+                parts.push(std::mem::take(&mut current));
+                continue;
+            }
+            _ => {}
+        }
+        current.push(c);
+    }
+    if !current.trim().is_empty() {
+        parts.push(current);
+    }
+    parts.into_iter().map(|p| p.trim().to_string()).collect()
+}
+
+fn words(s: &str) -> Vec<&str> {
+    s.split_whitespace().collect()
+}
+
+fn upper(words: &[&str], i: usize) -> String {
+    words.get(i).map(|w| w.to_uppercase()).unwrap_or_default()
+}
+
+/// The text after the first "(" up to its matching ")"
+fn parenthesized(s: &str) -> Option<&str> {
+    let open = s.find('(')?;
+    let mut depth = 0;
+    for (i, c) in s[open..].char_indices() {
+        match c {
+            '(' => depth += 1,
+            ')' => {
+                depth -= 1;
+                if depth == 0 {
+                    return Some(&s[open + 1..open + i]);
+                }
+            }
+            _ => {}
+        }
+    }
+    None
+}
+
+/// "REFERENCES users (id)" anywhere in s
+fn references(s: &str) -> Option<(String, Vec<String>)> {
+    let at = s.to_uppercase().find("REFERENCES ")?;
+    let rest = &s[at + "REFERENCES ".len()..];
+    let table_end = rest.find(|c: char| c == '(' || c.is_whitespace()).unwrap_or(rest.len());
+    let table = ident(&rest[..table_end]);
+    let columns = parenthesized(rest).map(idents).unwrap_or_else(|| vec!["id".to_string()]);
+    Some((table, columns))
+}
+
+fn apply_sql(schema: &mut SchemaView, content: &str, source: &str) {
+    for (line, statement) in statements(content) {
+        let w = words(&statement);
+        let head = upper(&w, 0);
+        if head == "CREATE" {
+            // CREATE [TEMP] [UNLOGGED] TABLE [IF NOT EXISTS] name
+            let Some(at) = w.iter().position(|x| x.eq_ignore_ascii_case("table")) else { continue };
+            let mut name_at = at + 1;
+            if upper(&w, name_at) == "IF" {
+                name_at += 3;
+            }
+            let Some(name) = w.get(name_at) else { continue };
+            let name = ident(name.split('(').next().unwrap_or(name));
+            let Some(body) = parenthesized(&statement) else { continue };
+            let t = table(schema, &name, source, line, "sql");
+            for def in split_top(body) {
+                apply_definition(t, &def);
+            }
+        } else if head == "ALTER" && upper(&w, 1) == "TABLE" {
+            let mut name_at = 2;
+            if upper(&w, 2) == "IF" {
+                name_at += 2;
+            }
+            if upper(&w, name_at) == "ONLY" {
+                name_at += 1;
+            }
+            let Some(name) = w.get(name_at) else { continue };
+            let name = ident(name);
+            let prefix_len = statement.find(w[name_at]).unwrap_or(0) + w[name_at].len();
+            for action in split_top(&statement[prefix_len..]) {
+                alter(schema, &name, &action, source, line);
+            }
+        } else if head == "DROP" && upper(&w, 1) == "TABLE" {
+            let from = if upper(&w, 2) == "IF" { 4 } else { 2 };
+            let names: Vec<String> = w.iter().skip(from).take_while(|x| !x.eq_ignore_ascii_case("cascade")).map(|x| ident(x)).collect();
+            schema.tables.retain(|t| !names.contains(&t.name));
+        }
+    }
+}
+
+/// A column or table constraint inside CREATE TABLE or after ADD
+fn apply_definition(t: &mut Table, def: &str) {
+    let mut w = words(def);
+    if upper(&w, 0) == "CONSTRAINT" {
+        w.drain(..2.min(w.len()));
+    }
+    let def_rest = w.join(" ");
+    match upper(&w, 0).as_str() {
+        "PRIMARY" => {
+            let cols = parenthesized(&def_rest).map(idents).unwrap_or_default();
+            for c in t.columns.iter_mut().filter(|c| cols.contains(&c.name)) {
+                c.primary_key = true;
+                c.nullable = false;
+            }
+        }
+        "FOREIGN" => {
+            let cols = parenthesized(&def_rest).map(idents).unwrap_or_default();
+            if let Some((ref_table, ref_columns)) = references(&def_rest) {
+                t.foreign_keys.push(ForeignKey { columns: cols, ref_table, ref_columns });
+            }
+        }
+        "UNIQUE" | "INDEX" | "KEY" | "CHECK" | "EXCLUDE" | "" => {}
+        _ => {
+            let name = ident(w[0]);
+            let data_type = w.get(1).map(|s| s.trim_end_matches(',').to_lowercase()).unwrap_or_default();
+            let upper_def = def_rest.to_uppercase();
+            let column = Column {
+                name: name.clone(),
+                data_type,
+                nullable: !upper_def.contains("NOT NULL") && !upper_def.contains("PRIMARY KEY"),
+                primary_key: upper_def.contains("PRIMARY KEY"),
+            };
+            t.columns.retain(|c| c.name != name);
+            t.columns.push(column);
+            if let Some((ref_table, ref_columns)) = references(&def_rest) {
+                t.foreign_keys.push(ForeignKey { columns: vec![name], ref_table, ref_columns });
+            }
+        }
+    }
+}
+
+fn alter(schema: &mut SchemaView, name: &str, action: &str, source: &str, line: usize) {
+    let w = words(action);
+    match (upper(&w, 0).as_str(), upper(&w, 1).as_str()) {
+        ("ADD", "COLUMN") => {
+            let skip = if upper(&w, 2) == "IF" { 5 } else { 2 };
+            let def = w[skip.min(w.len())..].join(" ");
+            apply_definition(table(schema, name, source, line, "sql"), &def);
+        }
+        ("ADD", _) => apply_definition(table(schema, name, source, line, "sql"), &w[1..].join(" ")),
+        ("DROP", "COLUMN") | ("DROP", _) if upper(&w, 1) != "CONSTRAINT" => {
+            let mut at = if upper(&w, 1) == "COLUMN" { 2 } else { 1 };
+            if upper(&w, at) == "IF" {
+                at += 2;
+            }
+            if let (Some(col), Some(t)) = (w.get(at), schema.tables.iter_mut().find(|t| t.name == name)) {
+                let col = ident(col);
+                t.columns.retain(|c| c.name != col);
+                t.foreign_keys.retain(|fk| !fk.columns.contains(&col));
+            }
+        }
+        ("RENAME", "TO") => {
+            if let (Some(new), Some(t)) = (w.get(2), schema.tables.iter_mut().find(|t| t.name == name)) {
+                t.name = ident(new);
+            }
+        }
+        ("RENAME", _) => {
+            let at = if upper(&w, 1) == "COLUMN" { 2 } else { 1 };
+            if let (Some(old), Some(new), Some(t)) = (w.get(at), w.get(at + 2), schema.tables.iter_mut().find(|t| t.name == name)) {
+                let (old, new) = (ident(old), ident(new));
+                for c in t.columns.iter_mut().filter(|c| c.name == old) {
+                    c.name = new.clone();
+                }
+            }
+        }
+        _ => {}
+    }
+}
+
+// ORM models
+
+/// The quoted argument after `key` in line, e.g. ForeignKey("users.id")
+fn quoted_after(line: &str, key: &str) -> Option<String> {
+    let rest = &line[line.find(key)? + key.len()..];
+    let start = rest.find(|c| c == '"' || c == '\'')?;
+    let quote = rest[start..].chars().next()?;
+    let end = rest[start + 1..].find(quote)?;
+    Some(rest[start + 1..start + 1 + end].to_string())
+}
+
+fn indent_of(line: &str) -> usize {
+    line.len() - line.trim_start().len()
+}
+
+/// SQLAlchemy classes with __tablename__ and Django models
+fn python_models(schema: &mut SchemaView, content: &str, source: &str) {
+    let lines: Vec<&str> = content.lines().collect();
+    let mut i = 0;
+    while i < lines.len() {
+        let line = lines[i].trim_start();
+        let Some(rest) = line.strip_prefix("class ") else {
+            i += 1;
+            continue;
+        };
+        let class_name = rest.split(|c| c == '(' || c == ':').next().unwrap_or("").trim().to_string();
+        let django = rest.contains("models.Model");
+        let class_indent = indent_of(lines[i]);
+        let start = i + 1;
+
+        let mut body = Vec::new();
+        i += 1;
+        while i < lines.len() && (lines[i].trim().is_empty() || indent_of(lines[i]) > class_indent) {
+            body.push((i + 1, lines[i].trim()));
+            i += 1;
+        }
+
+        let table_name = body
+            .iter()
+            .find_map(|(_, l)| {
+                if l.starts_with("__tablename__") || l.starts_with("db_table") {
+                    quoted_after(l, "=")
+                } else {
+                    None
+                }
+            })
+            .or_else(|| django.then(|| class_name.to_lowercase()));
+        let Some(table_name) = table_name else { continue };
+
+        let origin = if django { "django" } else { "sqlalchemy" };
+        let t = table(schema, &table_name, source, start, origin);
+        if django && !t.columns.iter().any(|c| c.name == "id") {
+            t.columns.push(Column { name: "id".into(), data_type: "autofield".into(), nullable: false, primary_key: true });
+        }
+        for (_, l) in body {
+            let Some((declared, value)) = l.split_once('=') else { continue };
+            let field = declared.split(':').next().unwrap_or("").trim();
+            // SQLAlchemy 2.0 declares nullability in the annotation, Mapped[Optional[str]]
+            let annotation = declared.split_once(':').map_or("", |(_, a)| a);
+            let value = value.trim();
+            if field.is_empty() || field.starts_with('_') || field.contains(' ') {
+                continue;
+            }
+            let is_column = value.contains("Column(") || value.contains("mapped_column(");
+            let is_django_field = django && value.starts_with("models.") && value.contains("Field(")
+                || django && (value.starts_with("models.ForeignKey(") || value.starts_with("models.OneToOneField("));
+            if !is_column && !is_django_field {
+                continue;
+            }
+
+            let mut name = field.to_string();
+            let data_type = value
+                .split(|c| c == '(' || c == ',')
+                .nth(if value.starts_with("models.") { 0 } else { 1 })
+                .unwrap_or("")
+                .trim()
+                .trim_start_matches("models.")
+                .trim_start_matches("db.")
+                .to_lowercase();
+
+            if let Some(target) = quoted_after(value, "ForeignKey(") {
+                // SQLAlchemy: ForeignKey("users.id")
+                if let Some((ref_table, ref_col)) = target.rsplit_once('.').filter(|_| !django) {
+                    t.foreign_keys.push(ForeignKey { columns: vec![name.clone()], ref_table: ident(ref_table), ref_columns: vec![ident(ref_col)] });
+                } else {
+                    name = format!("{}_id", field);
+                    let model = target.rsplit('.').next().unwrap_or(&target).to_lowercase();
+                    t.foreign_keys.push(ForeignKey { columns: vec![name.clone()], ref_table: model, ref_columns: vec!["id".into()] });
+                }
+            } else if django && (value.starts_with("models.ForeignKey(") || value.starts_with("models.OneToOneField(")) {
+                // Django: models.ForeignKey(User, ...)
+                let model = value.split('(').nth(1).unwrap_or("").split(',').next().unwrap_or("").trim().to_lowercase();
+                name = format!("{}_id", field);
+                t.foreign_keys.push(ForeignKey { columns: vec![name.clone()], ref_table: model, ref_columns: vec!["id".into()] });
+            }
+
+            t.columns.retain(|c| c.name != name);
+            t.columns.push(Column {
+                name,
+                data_type,
+                nullable: value.contains("nullable=True")
+                    || value.contains("null=True")
+                    || annotation.contains("Optional[")
+                    || annotation.contains("| None"),
+                primary_key: value.contains("primary_key=True"),
+            });
+        }
+    }
+}
+
+/// "OrderItem" is order_item
+fn snake(name: &str) -> String {
+    let mut out = String::new();
+    for (i, c) in name.chars().enumerate() {
+        if c.is_uppercase() {
+            if i > 0 && !name[..i].ends_with(|p: char| p.is_uppercase()) {
+                out.push('_');
+            }
+            out.extend(c.to_lowercase());
+        } else {
+            out.push(c);
+        }
+    }
+    out
+}
+
+/// GORM names tables after the struct, snake case and plural
+fn gorm_table(name: &str) -> String {
+    let s = snake(name);
+    if s.ends_with('y') && !s.ends_with("ey") {
+        format!("{}ies", &s[..s.len() - 1])
+    } else if s.ends_with('s') {
+        format!("{}es", s)
+    } else {
+        format!("{}s", s)
+    }
+}
+
+/// Go structs with gorm tags
+fn gorm_models(schema: &mut SchemaView, content: &str, source: &str) {
+    let lines: Vec<&str> = content.lines().collect();
+    let mut i = 0;
+    while i < lines.len() {
+        let line = lines[i].trim();
+        let struct_name = line
+            .strip_prefix("type ")
+            .and_then(|r| r.strip_suffix("struct {"))
+            .map(|r| r.trim().to_string());
+        let Some(struct_name) = struct_name else {
+            i += 1;
+            continue;
+        };
+        let start = i + 1;
+        let mut fields = Vec::new();
+        i += 1;
+        while i < lines.len() && lines[i].trim() != "}" {
+            fields.push(lines[i].trim());
+            i += 1;
+        }
+        if !fields.iter().any(|f| f.contains("gorm:\"") || f.starts_with("gorm.Model")) {
+            continue;
+        }
+
+        let t = table(schema, &gorm_table(&struct_name), source, start, "gorm");
+        for field in fields {
+            if field.starts_with("gorm.Model") {
+                for (name, ty) in [("id", "uint"), ("created_at", "time.Time"), ("updated_at", "time.Time"), ("deleted_at", "gorm.DeletedAt")] {
+                    t.columns.push(Column { name: name.into(), data_type: ty.into(), nullable: name == "deleted_at", primary_key: name == "id" });
+                }
+                continue;
+            }
+            let w = words(field);
+            if w.len() < 2 || w[0].starts_with("//") || !w[0].chars().next().map_or(false, |c| c.is_uppercase()) {
+                continue;
+            }
+            let tag = quoted_after(field, "gorm:").unwrap_or_default();
+            if tag.contains('-') && tag.split(';').any(|p| p == "-") {
+                continue;
+            }
+            let ty = w[1];
+            // Slices and other structs are relations, not columns
+            if ty.starts_with("[]") || (ty.trim_start_matches('*').chars().next().map_or(false, |c| c.is_uppercase()) && !ty.contains('.')) {
+                continue;
+            }
+            let name = tag
+                .split(';')
+                .find_map(|p| p.strip_prefix("column:"))
+                .map(String::from)
+                .unwrap_or_else(|| snake(w[0]));
+            if let Some(model) = w[0].strip_suffix("ID").filter(|m| !m.is_empty()) {
+                t.foreign_keys.push(ForeignKey { columns: vec![name.clone()], ref_table: gorm_table(model), ref_columns: vec!["id".into()] });
+            }
+            t.columns.push(Column {
+                name: name.clone(),
+                data_type: ty.to_string(),
+                nullable: ty.starts_with('*') || ty.starts_with("sql.Null"),
+                primary_key: tag.contains("primaryKey") || tag.contains("primary_key") || name == "id",
+            });
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn find<'a>(schema: &'a SchemaView, name: &str) -> &'a Table {
+        schema.tables.iter().find(|t| t.name == name).expect(name)
+    }
+
+    fn column<'a>(t: &'a Table, name: &str) -> &'a Column {
+        t.columns.iter().find(|c| c.name == name).expect(name)
+    }
+
+    #[test]
+    fn test_sql_migrations_replay() {
+        let mut schema = SchemaView::default();
+        apply_sql(
+            &mut schema,
+            "-- users\nCREATE TABLE IF NOT EXISTS \"public\".\"users\" (\n  id SERIAL PRIMARY KEY,\n  email TEXT NOT NULL, /* unique */\n  nickname TEXT\n);\nCREATE TABLE orders (\n  id BIGINT,\n  user_id INT REFERENCES users (id),\n  total NUMERIC(10, 2),\n  PRIMARY KEY (id)\n);",
+            "migrations/001_init.sql",
+        );
+        apply_sql(
+            &mut schema,
+            "ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login TIMESTAMP;\nALTER TABLE users DROP COLUMN nickname;\nALTER TABLE orders RENAME COLUMN total TO amount;\nCREATE TABLE audit (id INT);\nDROP TABLE IF EXISTS audit CASCADE;",
+            "migrations/002_more.sql",
+        );
+
+        assert_eq!(schema.tables.len(), 2);
+        let users = find(&schema, "users");
+        assert_eq!((users.source.as_str(), users.line, users.origin.as_str()), ("migrations/001_init.sql", 2, "sql"));
+        let names: Vec<&str> = users.columns.iter().map(|c| c.name.as_str()).collect();
+        assert_eq!(names, vec!["id", "email", "last_login"]);
+        assert!(column(users, "id").primary_key);
+        assert!(!column(users, "email").nullable);
+        assert!(column(users, "last_login").nullable);
+
+        let orders = find(&schema, "orders");
+        assert!(column(orders, "id").primary_key);
+        assert!(column(orders, "amount").data_type.starts_with("numeric"));
+        assert_eq!(orders.foreign_keys.len(), 1);
+        assert_eq!(orders.foreign_keys[0].columns, vec!["user_id"]);
+        assert_eq!(orders.foreign_keys[0].ref_table, "users");
+        assert_eq!(orders.foreign_keys[0].ref_columns, vec!["id"]);
+    }
+
+    #[test]
+    fn test_down_migrations() {
+        assert!(is_down_migration("db/0002_orders.down.sql"));
+        assert!(is_down_migration("db/0002/down.sql"));
+        assert!(!is_down_migration("db/0002_orders.up.sql"));
+
+        let mut schema = SchemaView::default();
+        apply_sql(&mut schema, "-- +goose Up\nCREATE TABLE items (id INT);\n-- +goose Down\nDROP TABLE items;", "db/items.sql");
+        assert_eq!(schema.tables.len(), 1);
+    }
+
+    #[test]
+    fn test_malformed_sql() {
+        let mut schema = SchemaView::default();
+        apply_sql(&mut schema, "CREATE TABLE\n;CREATE TABLE broken (id INT\n;ALTER TABLE;DROP TABLE;/* never closed", "bad.sql");
+        apply_sql(&mut schema, "", "empty.sql");
+        assert!(schema.tables.is_empty());
+
+        assert_eq!(statements("SELECT 1; -- done\n/* a\nb */ SELECT 2").len(), 2);
+        assert!(statements("/* only a comment").is_empty());
+    }
+
+    #[test]
+    fn test_sqlalchemy_and_django_models() {
+        let mut schema = SchemaView::default();
+        python_models(
+            &mut schema,
+            "class Order(Base):\n    __tablename__ = \"orders\"\n    id = Column(Integer, primary_key=True)\n    user_id = Column(Integer, ForeignKey(\"users.id\"))\n    note: Mapped[Optional[str]] = mapped_column(String)\n\nclass Helper:\n    x = 1\n",
+            "app/models.py",
+        );
+        python_models(
+            &mut schema,
+            "class Customer(models.Model):\n    name = models.CharField(max_length=80)\n    account = models.ForeignKey(Account, on_delete=models.CASCADE, null=True)\n",
+            "shop/models.py",
+        );
+
+        assert_eq!(schema.tables.len(), 2);
+        let orders = find(&schema, "orders");
+        assert_eq!(orders.origin, "sqlalchemy");
+        assert!(column(orders, "id").primary_key);
+        assert!(column(orders, "note").nullable);
+        assert_eq!(orders.foreign_keys[0].ref_table, "users");
+
+        let customer = find(&schema, "customer");
+        assert_eq!(customer.origin, "django");
+        assert!(column(customer, "id").primary_key);
+        assert_eq!(column(customer, "name").data_type, "charfield");
+        assert!(column(customer, "account_id").nullable);
+        assert_eq!(customer.foreign_keys[0].ref_table, "account");
+    }
+
+    #[test]
+    fn test_gorm_models() {
+        let mut schema = SchemaView::default();
+        gorm_models(
+            &mut schema,
+            "type OrderItem struct {\n\tgorm.Model\n\tOrderID uint\n\tSKU string `gorm:\"column:sku_code\"`\n\tNote *string\n\tOrder Order\n\tTags []Tag\n\tCache string `gorm:\"-\"`\n}\n\ntype plain struct {\n\tName string\n}\n",
+            "models/order.go",
+        );
+
+        assert_eq!(schema.tables.len(), 1);
+        let items = find(&schema, "order_items");
+        let names: Vec<&str> = items.columns.iter().map(|c| c.name.as_str()).collect();
+        assert_eq!(names, vec!["id", "created_at", "updated_at", "deleted_at", "order_id", "sku_code", "note"]);
+        assert!(column(items, "note").nullable);
+        assert_eq!(items.foreign_keys[0].ref_table, "orders");
+    }
+
+    #[test]
+    fn test_table_names() {
+        assert_eq!(snake("OrderItem"), "order_item");
+        assert_eq!(snake("HTTPLog"), "httplog");
+        assert_eq!(gorm_table("Category"), "categories");
+        assert_eq!(gorm_table("Key"), "keys");
+        assert_eq!(gorm_table("Address"), "addresses");
+        assert_eq!(ident("`shop`.`Users`,"), "users");
+    }
+}
@@ -1,3 +1,4 @@
 // src/utils/mod.rs
 pub mod file_walker;
 pub mod ignore;
+pub mod path_filter;
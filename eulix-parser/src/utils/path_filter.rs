@@ -0,0 +1,55 @@
+use glob::{MatchOptions, Pattern};
+use std::path::Path;
+
+/// Limits parsing to paths matching --include globs and drops paths matching
+/// --exclude globs, on top of .euignore. A glob matches a file when it
+/// matches the file's path relative to the root or one of its parent
+/// directories, so `backend` and `backend/**` both take everything under
+/// backend/.
+pub struct PathFilter {
+    include: Vec<Pattern>,
+    exclude: Vec<Pattern>,
+}
+
+const OPTIONS: MatchOptions = MatchOptions {
+    case_sensitive: true,
+    require_literal_separator: true,
+    require_literal_leading_dot: false,
+};
+
+impl PathFilter {
+    pub fn new(include: &[String], exclude: &[String]) -> Result<Self, String> {
+        Ok(Self {
+            include: compile(include)?,
+            exclude: compile(exclude)?,
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.include.is_empty() && self.exclude.is_empty()
+    }
+
+    /// Whether a path relative to the root should be parsed
+    pub fn allows(&self, relative: &Path) -> bool {
+        if !self.include.is_empty() && !matches_any(&self.include, relative) {
+            return false;
+        }
+        !matches_any(&self.exclude, relative)
+    }
+}
+
+fn compile(globs: &[String]) -> Result<Vec<Pattern>, String> {
+    globs
+        .iter()
+        .map(|g| g.trim().trim_start_matches("./").trim_end_matches('/'))
+        .filter(|g| !g.is_empty())
+        .map(|g| Pattern::new(g).map_err(|e| format!("invalid glob '{}': {}", g, e)))
+        .collect()
+}
+
+fn matches_any(patterns: &[Pattern], relative: &Path) -> bool {
+    relative.ancestors().any(|candidate| {
+        !candidate.as_os_str().is_empty()
+            && patterns.iter().any(|p| p.matches_path_with(candidate, OPTIONS))
+    })
+}
@@ -0,0 +1,241 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eulix/internal/centrality"
+	"eulix/internal/checksum"
+	"eulix/internal/child"
+	"eulix/internal/chunkstore"
+	"eulix/internal/config"
+	"eulix/internal/lock"
+	"eulix/internal/owners"
+	"eulix/internal/schema"
+)
+
+// Options controls where the parser and embedder output goes
+type Options struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Wait for another writer to release .eulix/lock instead of failing fast
+	Wait time.Duration
+
+	// Context stops the run when cancelled, e.g. on Ctrl+C. The parser and
+	// embedder are terminated and the staged files removed, the current KB
+	// is left as it was. nil never cancels.
+	Context context.Context
+}
+
+// ErrInterrupted is returned when Options.Context was cancelled mid-run
+var ErrInterrupted = errors.New("analyze interrupted, the previous knowledge base was kept")
+
+// Run parses the project, generates embeddings and saves the checksum,
+// the same pipeline as `eulix analyze`
+func Run(projectPath string, cfg *config.Config, opts Options) (*checksum.Checksum, error) {
+	out := opts.Stdout
+	if out == nil {
+		out = io.Discard
+	}
+	errOut := opts.Stderr
+	if errOut == nil {
+		errOut = io.Discard
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	limits := child.Limits{
+		Timeout:  time.Duration(cfg.Tools.TimeoutMinutes) * time.Minute,
+		MemoryMB: cfg.Tools.MemoryLimitMB,
+	}
+
+	eulixDir := filepath.Join(projectPath, ".eulix")
+	if err := os.MkdirAll(eulixDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", eulixDir, err)
+	}
+
+	var l *lock.Lock
+	var err error
+	if opts.Wait > 0 {
+		l, err = lock.AcquireWait(eulixDir, "analyze", opts.Wait)
+	} else {
+		l, err = lock.Acquire(eulixDir, "analyze")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+
+	// Everything is built in a staging dir and only swapped into .eulix once
+	// the whole pipeline succeeds, so a failed run keeps the previous KB usable
+	stagingDir := filepath.Join(eulixDir, stagingDirName)
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, fmt.Errorf("failed to clear staging dir: %w", err)
+	}
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging dir: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	// Calculate checksum
+	detector := checksum.HashHound(projectPath)
+	currentChecksum, err := detector.Calculate()
+	if err != nil {
+		return nil, fmt.Errorf("checksum calculation failed: %w", err)
+	}
+
+	// Runs parser
+	fmt.Fprintln(out, "Parsing codebase...")
+	reportSkipped(out, currentChecksum.Skipped)
+	kbPath := filepath.Join(stagingDir, "kb.json")
+
+	statsPath := filepath.Join(stagingDir, parserStatsName)
+
+	parserArgs := []string{
+		"--root", projectPath,
+		"-o", kbPath,
+		"--threads", fmt.Sprintf("%d", cfg.Parser.Threads),
+	}
+	if len(cfg.Parser.Languages) > 0 {
+		parserArgs = append(parserArgs, "--languages", strings.Join(cfg.Parser.Languages, ","))
+	}
+	help := parserHelpOf(cfg.Tools.ParserPath())
+	if len(cfg.Parser.Include)+len(cfg.Parser.Exclude) > 0 {
+		if !help.has("--include") {
+			return nil, fmt.Errorf("[parser] include and exclude need a newer eulix_parser, run 'eulix install-tools'")
+		}
+		for _, glob := range cfg.Parser.Include {
+			parserArgs = append(parserArgs, "--include", glob)
+		}
+		for _, glob := range cfg.Parser.Exclude {
+			parserArgs = append(parserArgs, "--exclude", glob)
+		}
+	}
+	skipListPath := filepath.Join(stagingDir, skipListName)
+	if len(currentChecksum.Skipped) > 0 {
+		if help.has("--exclude-from") {
+			if err := writeSkipList(skipListPath, currentChecksum.Skipped); err != nil {
+				return nil, fmt.Errorf("failed to write skipped files: %w", err)
+			}
+			parserArgs = append(parserArgs, "--exclude-from", skipListPath)
+		} else {
+			fmt.Fprintln(out, "   ⚠ eulix_parser is too old to skip them and will parse them anyway, run 'eulix install-tools'")
+		}
+	}
+	if cfg.Parser.SkipDocs && help.has("--no-docs") {
+		parserArgs = append(parserArgs, "--no-docs")
+	}
+	if cfg.Parser.SkipInfra && help.has("--no-infra") {
+		parserArgs = append(parserArgs, "--no-infra")
+	}
+	withStats := help.has("--stats")
+	if withStats {
+		parserArgs = append(parserArgs, "--stats", statsPath)
+	}
+	parserCmd := exec.Command(cfg.Tools.ParserPath(), parserArgs...)
+	parserCmd.Stdout = out
+	parserCmd.Stderr = errOut
+
+	if err := child.Run(ctx, parserCmd, limits); err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrInterrupted
+		}
+		return nil, fmt.Errorf("parser failed: %w", err)
+	}
+	fmt.Fprintln(out, "✓ Parser completed")
+	if withStats {
+		// Only for the summary, a parser that didn't write it still built the KB
+		if stats, err := readParserStats(statsPath); err == nil {
+			stats.report(out)
+		} else {
+			fmt.Fprintf(out, "   ⚠ Parser summary unavailable: %v\n", err)
+		}
+		os.Remove(statsPath)
+	}
+	os.Remove(skipListPath)
+
+	// Written even when empty so a removed CODEOWNERS clears the old rules,
+	// a broken one only loses ownership
+	if codeowners, err := owners.Parse(projectPath); err != nil {
+		fmt.Fprintf(out, "   ⚠ CODEOWNERS ignored: %v\n", err)
+	} else {
+		if err := codeowners.Write(stagingDir); err != nil {
+			return nil, fmt.Errorf("failed to write ownership rules: %w", err)
+		}
+		if !codeowners.Empty() {
+			fmt.Fprintf(out, "   ✓ %d ownership rules from CODEOWNERS\n", len(codeowners.Rules))
+		}
+	}
+	// Only boosts retrieval and feeds eulix central, not worth failing over
+	if ranked, err := centrality.Annotate(stagingDir); err != nil {
+		fmt.Fprintf(out, "   ⚠ Centrality not computed: %v\n", err)
+	} else if ranked > 0 {
+		fmt.Fprintf(out, "   ✓ Ranked %d functions by centrality\n", ranked)
+	}
+	fmt.Fprintln(out)
+
+	// Generate embeddings
+	fmt.Fprintln(out, "Generating embeddings...")
+
+	embedCmd := exec.Command(cfg.Tools.EmbedPath(),
+		"-k", kbPath,
+		"-o", stagingDir,
+		"-m", cfg.Embeddings.Model,
+	)
+	embedCmd.Stdout = out
+	embedCmd.Stderr = errOut
+
+	if err := child.Run(ctx, embedCmd, limits); err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrInterrupted
+		}
+		return nil, fmt.Errorf("embedding generation failed: %w", err)
+	}
+	fmt.Fprintln(out, "   ✓ Embeddings completed")
+	fmt.Fprintln(out)
+
+	// eulix_embed writes chunks and vectors matched by position, store them by ID instead
+	if err := chunkstore.Split(stagingDir); err != nil {
+		return nil, fmt.Errorf("failed to build chunk store: %w", err)
+	}
+
+	// Parser and embedder output is the current schema, mark it as such
+	if err := schema.Stamp(stagingDir, schema.Version); err != nil {
+		return nil, err
+	}
+
+	// Last point to stop, past here the new KB is installed as a whole
+	if ctx.Err() != nil {
+		return nil, ErrInterrupted
+	}
+
+	if err := swapIn(stagingDir, eulixDir); err != nil {
+		return nil, fmt.Errorf("failed to install new knowledge base: %w", err)
+	}
+	if err := chunkstore.RemoveLegacy(eulixDir); err != nil {
+		return nil, fmt.Errorf("failed to remove old embeddings files: %w", err)
+	}
+
+	// Save checksum
+	fmt.Fprintln(out, "Saving checksum...")
+	if err := detector.Save(currentChecksum); err != nil {
+		return nil, fmt.Errorf("failed to save checksum: %w", err)
+	}
+	fmt.Fprintln(out, "   ✓ Checksum saved")
+	// History only feeds eulix digest, losing a record isn't worth failing
+	if err := detector.Record(currentChecksum); err != nil {
+		fmt.Fprintf(out, "   ⚠ History not recorded: %v\n", err)
+	}
+	fmt.Fprintln(out)
+
+	return currentChecksum, nil
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// parserStatsName is the file eulix_parser writes its numbers to with
+// --stats, next to kb.json in the staging dir
+const parserStatsName = "parse_stats.json"
+
+// maxFailedShown is how many files that failed to parse are listed
+const maxFailedShown = 5
+
+// ParserStats is what eulix_parser --stats writes. Reading it replaces
+// picking numbers out of the parser's output, which is passed through as it
+// is printed instead of being kept around.
+type ParserStats struct {
+	Parsed       int          `json:"parsed"`
+	Skipped      int          `json:"skipped"`
+	Failed       []FailedFile `json:"failed"`
+	TotalFiles   int          `json:"total_files"`
+	TotalLOC     int          `json:"total_loc"`
+	Functions    int          `json:"functions"`
+	Classes      int          `json:"classes"`
+	Methods      int          `json:"methods"`
+	Languages    []string     `json:"languages"`
+	Documents    int          `json:"documents"`
+	InfraFiles   int          `json:"infra_files"`
+	Tables       int          `json:"tables"`
+	GraphNodes   int          `json:"graph_nodes"`
+	GraphEdges   int          `json:"graph_edges"`
+	ParseSeconds float64      `json:"parse_seconds"`
+	TotalSeconds float64      `json:"total_seconds"`
+}
+
+// FailedFile is a file the parser couldn't parse
+type FailedFile struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// parserHelp is the parser's --help output. Parsers reject flags they don't
+// know, so newer flags are only passed when it lists them.
+type parserHelp []byte
+
+func parserHelpOf(path string) parserHelp {
+	help, _ := exec.Command(path, "--help").Output()
+	return help
+}
+
+func (h parserHelp) has(flag string) bool {
+	return bytes.Contains(h, []byte(flag))
+}
+
+func readParserStats(path string) (*ParserStats, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var stats ParserStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("failed to read parser stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// report prints the numbers worth knowing before embedding starts, files
+// that failed to parse especially since they are missing from the KB
+func (s *ParserStats) report(w io.Writer) {
+	fmt.Fprintf(w, "   %d files parsed, %d skipped, %d LOC, %d functions, %d classes, %d docs, %d infra files, %d tables\n",
+		s.Parsed, s.Skipped, s.TotalLOC, s.Functions, s.Classes, s.Documents, s.InfraFiles, s.Tables)
+	if len(s.Failed) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "   ⚠ %d files failed to parse and are left out:\n", len(s.Failed))
+	for i, f := range s.Failed {
+		if i == maxFailedShown {
+			fmt.Fprintf(w, "     ... and %d more\n", len(s.Failed)-maxFailedShown)
+			break
+		}
+		fmt.Fprintf(w, "     %s: %s\n", f.File, f.Error)
+	}
+}
@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"eulix/internal/checksum"
+)
+
+// skipListName is the file of paths the checksum left out, handed to the
+// parser with --exclude-from so both skip the same files
+const skipListName = "skipped.txt"
+
+// writeSkipList writes the skipped paths as globs that only match
+// themselves
+func writeSkipList(path string, skipped []checksum.Skipped) error {
+	var sb strings.Builder
+	for _, s := range skipped {
+		sb.WriteString(escapeGlob(filepath.ToSlash(s.Path)))
+		sb.WriteByte('\n')
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// escapeGlob puts glob metacharacters in brackets, [*] matches a literal *
+func escapeGlob(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		switch r {
+		case '*', '?', '[', ']':
+			sb.WriteByte('[')
+			sb.WriteRune(r)
+			sb.WriteByte(']')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// reportSkipped prints how many files were skipped and why, with the first
+// few of them
+func reportSkipped(w io.Writer, skipped []checksum.Skipped) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	byReason := make(map[string][]string)
+	for _, s := range skipped {
+		// "too large, 2048 KB" counts as too large
+		reason, _, _ := strings.Cut(s.Reason, ",")
+		byReason[reason] = append(byReason[reason], s.Path)
+	}
+	reasons := make([]string, 0, len(byReason))
+	for reason := range byReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return len(byReason[reasons[i]]) > len(byReason[reasons[j]])
+	})
+
+	counts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		counts[i] = fmt.Sprintf("%d %s", len(byReason[reason]), reason)
+	}
+	fmt.Fprintf(w, "   Skipping %d files: %s\n", len(skipped), strings.Join(counts, ", "))
+
+	shown := skipped
+	if len(shown) > maxFailedShown {
+		shown = shown[:maxFailedShown]
+	}
+	for _, s := range shown {
+		fmt.Fprintf(w, "     %s: %s\n", s.Path, s.Reason)
+	}
+	if len(skipped) > len(shown) {
+		fmt.Fprintf(w, "     ... and %d more, see .eulix/checksum.json\n", len(skipped)-len(shown))
+	}
+	fmt.Fprintln(w, "   Change [parser] max_file_kb or skip_generated in eulix.toml to include them")
+}
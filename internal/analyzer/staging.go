@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	stagingDirName  = ".staging"
+	previousDirName = ".previous"
+)
+
+// swapIn moves every staged artifact into eulixDir. Files being replaced are
+// parked in .previous first and put back if any move fails, so eulixDir ends
+// up with either the complete old set or the complete new one.
+func swapIn(stagingDir, eulixDir string) error {
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		return err
+	}
+
+	previousDir := filepath.Join(eulixDir, previousDirName)
+	if err := os.RemoveAll(previousDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(previousDir, 0755); err != nil {
+		return err
+	}
+
+	var parked, installed []string
+	rollback := func() {
+		for _, name := range installed {
+			os.Remove(filepath.Join(eulixDir, name))
+		}
+		for _, name := range parked {
+			os.Rename(filepath.Join(previousDir, name), filepath.Join(eulixDir, name))
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		target := filepath.Join(eulixDir, name)
+
+		if _, err := os.Stat(target); err == nil {
+			if err := os.Rename(target, filepath.Join(previousDir, name)); err != nil {
+				rollback()
+				return fmt.Errorf("failed to move old %s aside: %w", name, err)
+			}
+			parked = append(parked, name)
+		}
+
+		if err := os.Rename(filepath.Join(stagingDir, name), target); err != nil {
+			rollback()
+			return fmt.Errorf("failed to install %s: %w", name, err)
+		}
+		installed = append(installed, name)
+	}
+
+	return os.RemoveAll(previousDir)
+}
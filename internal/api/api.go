@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"eulix/internal/fault"
+	"eulix/internal/query"
+)
+
+// The parser records routes it recognizes as api_endpoint entry points, with
+// the handler function they call. Endpoints lines those up with the handler's
+// signature and docstring so they can be listed or turned into an OpenAPI
+// skeleton without asking the LLM.
+
+// Endpoint is one method on one route
+type Endpoint struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`  // OpenAPI form, /users/{id}
+	Route      string   `json:"route"` // as written in the code, /users/<int:id>
+	Handler    string   `json:"handler"`
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	LineEnd    int      `json:"line_end,omitempty"`
+	Signature  string   `json:"signature,omitempty"`
+	Docstring  string   `json:"docstring,omitempty"`
+	Parameters []string `json:"parameters,omitempty"` // path parameters
+}
+
+// Source is where the handler is, file:line
+func (e Endpoint) Source() string {
+	return fmt.Sprintf("%s:%d", e.File, e.Line)
+}
+
+var (
+	// Flask <int:id> or <id>
+	anglePattern = regexp.MustCompile(`<(?:[a-z_]+:)?([A-Za-z_][A-Za-z0-9_]*)>`)
+	// Express and gin :id
+	colonPattern = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+	// FastAPI and OpenAPI {id}, {path:path}
+	bracePattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(?::[^}]*)?\}`)
+)
+
+// Endpoints reads the routes from kb.json in eulixDir, sorted by path and
+// method. Entry points without a route, like a Flask app itself, are left
+// out.
+func Endpoints(eulixDir string) ([]Endpoint, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w, run 'eulix analyze' first: %w", fault.ErrKBMissing, err)
+	}
+
+	var kb query.KnowledgeBase
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return nil, fmt.Errorf("failed to parse kb.json: %w", err)
+	}
+
+	var endpoints []Endpoint
+	seen := make(map[string]bool)
+	for _, ep := range kb.EntryPoints {
+		if ep.EntryType != "api_endpoint" || ep.Path == "" {
+			continue
+		}
+		path, params := openAPIPath(ep.Path)
+		handler := findHandler(kb.Structure[ep.File], ep.Function, ep.Line)
+
+		methods := ep.Methods
+		if len(methods) == 0 {
+			methods = []string{"GET"}
+		}
+		for _, method := range methods {
+			method = strings.ToUpper(method)
+			key := method + " " + path
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			e := Endpoint{
+				Method:     method,
+				Path:       path,
+				Route:      ep.Path,
+				Handler:    ep.Function,
+				File:       ep.File,
+				Line:       ep.Line,
+				Parameters: params,
+			}
+			if handler != nil {
+				e.LineEnd = handler.LineEnd
+				e.Signature = handler.Signature
+				e.Docstring = handler.Docstring
+			}
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return methodOrder(endpoints[i].Method) < methodOrder(endpoints[j].Method)
+	})
+	return endpoints, nil
+}
+
+// openAPIPath rewrites route parameters as {name} and lists them
+func openAPIPath(route string) (string, []string) {
+	var params []string
+	collect := func(pattern *regexp.Regexp, s string) string {
+		return pattern.ReplaceAllStringFunc(s, func(m string) string {
+			name := pattern.FindStringSubmatch(m)[1]
+			if !slices.Contains(params, name) {
+				params = append(params, name)
+			}
+			return "{" + name + "}"
+		})
+	}
+
+	// Braces first so the colon in {path:path} isn't read as a parameter
+	path := collect(anglePattern, route)
+	path = collect(bracePattern, path)
+	path = collect(colonPattern, path)
+	return path, params
+}
+
+// findHandler is the function or method named name, the one defined at line
+// when there are several
+func findHandler(file query.FileStructure, name string, line int) *query.KBFunction {
+	var found *query.KBFunction
+	check := func(fn *query.KBFunction) {
+		if fn.Name != name {
+			return
+		}
+		if found == nil || fn.LineStart == line {
+			found = fn
+		}
+	}
+	for i := range file.Functions {
+		check(&file.Functions[i])
+	}
+	for i := range file.Classes {
+		for j := range file.Classes[i].Methods {
+			check(&file.Classes[i].Methods[j])
+		}
+	}
+	return found
+}
+
+func methodOrder(method string) int {
+	for i, m := range []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"} {
+		if m == method {
+			return i
+		}
+	}
+	return 99
+}
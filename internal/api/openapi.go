@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"eulix/internal/llm"
+)
+
+// Document is the part of OpenAPI 3 a skeleton needs
+type Document struct {
+	OpenAPI string                          `json:"openapi"`
+	Info    Info                            `json:"info"`
+	Paths   map[string]map[string]Operation `json:"paths"` // path -> lowercase method
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	Source      string              `json:"x-eulix-source"`
+}
+
+type Parameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type Response struct {
+	Description string `json:"description"`
+}
+
+const (
+	// describeBatch is how many handlers go in one prompt
+	describeBatch = 8
+	// maxHandlerLines keeps one long handler from filling the prompt
+	maxHandlerLines = 60
+)
+
+var describeLinePattern = regexp.MustCompile(`^\s*[-*]?\s*(\d+)\s*\|\s*([^|]+?)\s*\|\s*(.*)$`)
+
+// OpenAPI builds a skeleton from endpoints. Summaries come from docstrings.
+// With a client the LLM describes each handler from its code in projectDir,
+// onBatch is told how many are done after each prompt. Without one the
+// docstrings are all there is.
+func OpenAPI(title string, endpoints []Endpoint, projectDir string, client *llm.Client, onBatch func(done, total int)) (*Document, error) {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       title,
+			Version:     "1.0.0",
+			Description: "Generated by eulix from the handlers found in the code, review before publishing.",
+		},
+		Paths: make(map[string]map[string]Operation),
+	}
+
+	descriptions := make(map[int][2]string)
+	if client != nil {
+		for start := 0; start < len(endpoints); start += describeBatch {
+			end := min(start+describeBatch, len(endpoints))
+			response, err := client.Complete(describePrompt(endpoints[start:end], start, projectDir))
+			if err != nil {
+				return nil, fmt.Errorf("LLM description failed: %w", err)
+			}
+			for i, d := range parseDescriptions(response) {
+				if i >= start && i < end {
+					descriptions[i] = d
+				}
+			}
+			if onBatch != nil {
+				onBatch(end, len(endpoints))
+			}
+		}
+	}
+
+	for i, e := range endpoints {
+		op := Operation{
+			OperationID: operationID(e),
+			Summary:     firstLine(e.Docstring),
+			Responses:   map[string]Response{"200": {Description: "Successful response"}},
+			Source:      e.Source(),
+		}
+		if text := strings.TrimSpace(e.Docstring); text != op.Summary {
+			op.Description = text
+		}
+		if d, ok := descriptions[i]; ok {
+			op.Summary, op.Description = d[0], d[1]
+		}
+		for _, name := range e.Parameters {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   map[string]string{"type": paramType(e.Route, name)},
+			})
+		}
+
+		if doc.Paths[e.Path] == nil {
+			doc.Paths[e.Path] = make(map[string]Operation)
+		}
+		doc.Paths[e.Path][strings.ToLower(e.Method)] = op
+	}
+	return doc, nil
+}
+
+func describePrompt(endpoints []Endpoint, offset int, projectDir string) string {
+	var sb strings.Builder
+	sb.WriteString(`Describe each HTTP endpoint below for an OpenAPI document, using only its handler code.
+
+Reply with one line per endpoint in exactly this format:
+number|short summary|one or two sentence description
+
+The summary is under 10 words. Describe what the endpoint does for a caller, mention request fields and error cases only when the code shows them.
+`)
+	for i, e := range endpoints {
+		fmt.Fprintf(&sb, "\n%d. %s %s, handler %s in %s\n", offset+i+1, e.Method, e.Route, e.Handler, e.Source())
+		if code := handlerCode(projectDir, e); code != "" {
+			fmt.Fprintf(&sb, "```\n%s\n```\n", code)
+		} else if e.Signature != "" {
+			fmt.Fprintf(&sb, "signature: %s\n", e.Signature)
+		}
+	}
+	return sb.String()
+}
+
+// parseDescriptions maps the endpoint index, zero based, to summary and
+// description
+func parseDescriptions(response string) map[int][2]string {
+	out := make(map[int][2]string)
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		m := describeLinePattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 {
+			continue
+		}
+		out[n-1] = [2]string{strings.TrimSpace(m[2]), strings.TrimSpace(m[3])}
+	}
+	return out
+}
+
+// handlerCode is the handler's source, cut at maxHandlerLines
+func handlerCode(projectDir string, e Endpoint) string {
+	data, err := os.ReadFile(filepath.Join(projectDir, e.File))
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(data), "\n")
+	start, end := e.Line-1, e.LineEnd
+	if start < 0 || start >= len(lines) {
+		return ""
+	}
+	if end <= start || end > len(lines) {
+		end = min(start+maxHandlerLines, len(lines))
+	}
+	if end-start > maxHandlerLines {
+		end = start + maxHandlerLines
+		return strings.Join(lines[start:end], "\n") + "\n..."
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// operationID is the handler name, with the method added for anything but
+// GET so a handler serving several methods gets distinct IDs
+func operationID(e Endpoint) string {
+	if e.Method == "GET" {
+		return e.Handler
+	}
+	return e.Handler + "_" + strings.ToLower(e.Method)
+}
+
+// paramType reads Flask converters, everything else is a string
+func paramType(route, name string) string {
+	switch {
+	case strings.Contains(route, "<int:"+name+">"):
+		return "integer"
+	case strings.Contains(route, "<float:"+name+">"):
+		return "number"
+	}
+	return "string"
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
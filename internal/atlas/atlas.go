@@ -0,0 +1,443 @@
+package atlas
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eulix/internal/chunkstore"
+	"eulix/internal/fault"
+	"eulix/internal/llm"
+	"eulix/internal/query"
+)
+
+// A module map groups files by what they are about rather than where they
+// sit in the tree. Files are linked to the files whose chunks embed closest
+// to theirs and to the files they call, and the groups are the communities of
+// that graph. Where the groups cut across directories is the architecture
+// the directory layout doesn't show.
+
+const (
+	// neighbours is how many most similar files each file is linked to
+	neighbours = 5
+	// minSimilarity drops links between files that are only vaguely alike
+	minSimilarity = 0.3
+	// callWeight is a call link's weight, about the strongest similarity so
+	// both count
+	callWeight = 1.0
+	// maxClusters keeps the map readable, the smallest clusters are merged
+	// into their closest neighbour past it
+	maxClusters = 15
+	// maxClusterSymbols is how many symbols a cluster shows and sends to
+	// the LLM for naming
+	maxClusterSymbols = 8
+)
+
+// Map is the clusters and the calls between them
+type Map struct {
+	Project  string    `json:"project"`
+	Clusters []Cluster `json:"clusters"`
+	Links    []Link    `json:"links"`
+	// Unclustered files have no vectors and no calls to place them by
+	Unclustered []string `json:"unclustered,omitempty"`
+}
+
+type Cluster struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Files       []string `json:"files"`
+	Symbols     []string `json:"symbols"` // the most called ones first
+}
+
+// Link is calls from the files of one cluster into another
+type Link struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Calls int `json:"calls"`
+}
+
+var nameLinePattern = regexp.MustCompile(`^\s*[-*]?\s*(\d+)\s*\|\s*([^|]+?)\s*(?:\|\s*(.*))?$`)
+
+// Build clusters the files in the KB in eulixDir. Without vectors.bin the
+// files are clustered by calls alone.
+func Build(project, eulixDir string) (*Map, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w, run 'eulix analyze' first: %w", fault.ErrKBMissing, err)
+	}
+	var kb query.KnowledgeBase
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return nil, fmt.Errorf("failed to parse kb.json: %w", err)
+	}
+
+	var files []string
+	for file := range kb.Structure {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	index := make(map[string]int, len(files))
+	for i, file := range files {
+		index[file] = i
+	}
+
+	g := newGraph(len(files))
+	calls := make(map[[2]int]int) // caller file, callee file -> calls
+	for file, structure := range kb.Structure {
+		to := index[file]
+		for _, fn := range allFunctions(structure) {
+			for _, caller := range fn.CalledBy {
+				from, ok := index[caller.File]
+				if !ok || from == to {
+					continue
+				}
+				calls[[2]int{from, to}]++
+			}
+		}
+	}
+	for pair := range calls {
+		g.add(pair[0], pair[1], callWeight)
+	}
+
+	if vectors := fileVectors(eulixDir, index); vectors != nil {
+		linkSimilar(g, vectors)
+	}
+
+	community := g.communities()
+	m := &Map{Project: project}
+	members := make(map[int][]int)
+	for node, c := range community {
+		if g.degree[node] == 0 {
+			m.Unclustered = append(m.Unclustered, files[node])
+			continue
+		}
+		members[c] = append(members[c], node)
+	}
+	groups := make([][]int, 0, len(members))
+	for _, nodes := range members {
+		groups = append(groups, nodes)
+	}
+	groups = mergeSmallest(g, groups, maxClusters)
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i]) != len(groups[j]) {
+			return len(groups[i]) > len(groups[j])
+		}
+		return groups[i][0] < groups[j][0]
+	})
+
+	clusterOf := make(map[int]int)
+	for i, nodes := range groups {
+		c := Cluster{ID: i + 1}
+		for _, node := range nodes {
+			c.Files = append(c.Files, files[node])
+			clusterOf[node] = c.ID
+		}
+		sort.Strings(c.Files)
+		c.Symbols = topSymbols(&kb, c.Files)
+		c.Name = commonDir(c.Files)
+		m.Clusters = append(m.Clusters, c)
+	}
+
+	links := make(map[[2]int]int)
+	for pair, n := range calls {
+		from, to := clusterOf[pair[0]], clusterOf[pair[1]]
+		if from != 0 && to != 0 && from != to {
+			links[[2]int{from, to}] += n
+		}
+	}
+	for pair, n := range links {
+		m.Links = append(m.Links, Link{From: pair[0], To: pair[1], Calls: n})
+	}
+	sort.Slice(m.Links, func(i, j int) bool {
+		if m.Links[i].Calls != m.Links[j].Calls {
+			return m.Links[i].Calls > m.Links[j].Calls
+		}
+		if m.Links[i].From != m.Links[j].From {
+			return m.Links[i].From < m.Links[j].From
+		}
+		return m.Links[i].To < m.Links[j].To
+	})
+	return m, nil
+}
+
+func allFunctions(s query.FileStructure) []query.KBFunction {
+	functions := append([]query.KBFunction(nil), s.Functions...)
+	for _, class := range s.Classes {
+		functions = append(functions, class.Methods...)
+	}
+	return functions
+}
+
+// fileVectors averages the chunk vectors of each file, normalized so a dot
+// product is the cosine. nil when there are no vectors.
+func fileVectors(eulixDir string, index map[string]int) [][]float32 {
+	store, err := chunkstore.Load(eulixDir)
+	if err != nil {
+		return nil
+	}
+	vectors, err := chunkstore.LoadVectors(eulixDir)
+	if err != nil {
+		return nil
+	}
+
+	sums := make([][]float32, len(index))
+	found := false
+	for id, chunk := range store.Chunks {
+		i, ok := index[chunk.Metadata.FilePath]
+		vec := vectors.ByID[id]
+		if !ok || len(vec) == 0 {
+			continue
+		}
+		if sums[i] == nil {
+			sums[i] = make([]float32, len(vec))
+		}
+		if len(sums[i]) != len(vec) {
+			continue
+		}
+		for d, v := range vec {
+			sums[i][d] += v
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+
+	for _, sum := range sums {
+		var norm float64
+		for _, v := range sum {
+			norm += float64(v) * float64(v)
+		}
+		if norm == 0 {
+			continue
+		}
+		scale := float32(1 / math.Sqrt(norm))
+		for d := range sum {
+			sum[d] *= scale
+		}
+	}
+	return sums
+}
+
+// linkSimilar links each file to its most similar files
+func linkSimilar(g *graph, vectors [][]float32) {
+	type scored struct {
+		node  int
+		score float64
+	}
+	for i, a := range vectors {
+		if a == nil {
+			continue
+		}
+		var best []scored
+		for j, b := range vectors {
+			if j == i || b == nil || len(b) != len(a) {
+				continue
+			}
+			var dot float64
+			for d := range a {
+				dot += float64(a[d]) * float64(b[d])
+			}
+			if dot < minSimilarity {
+				continue
+			}
+			best = append(best, scored{j, dot})
+		}
+		sort.Slice(best, func(x, y int) bool {
+			if best[x].score != best[y].score {
+				return best[x].score > best[y].score
+			}
+			return best[x].node < best[y].node
+		})
+		for _, s := range best[:min(neighbours, len(best))] {
+			g.add(i, s.node, s.score)
+		}
+	}
+}
+
+// topSymbols are the functions and types in files, most called first
+func topSymbols(kb *query.KnowledgeBase, files []string) []string {
+	type symbol struct {
+		name  string
+		calls int
+	}
+	var symbols []symbol
+	for _, file := range files {
+		structure := kb.Structure[file]
+		for _, fn := range structure.Functions {
+			symbols = append(symbols, symbol{fn.Name, len(fn.CalledBy)})
+		}
+		for _, class := range structure.Classes {
+			calls := 0
+			for _, m := range class.Methods {
+				calls += len(m.CalledBy)
+			}
+			symbols = append(symbols, symbol{class.Name, calls})
+		}
+	}
+	sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].calls > symbols[j].calls })
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range symbols {
+		if len(names) == maxClusterSymbols {
+			break
+		}
+		if !seen[s.name] {
+			seen[s.name] = true
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+// commonDir is the deepest directory holding all of files, or the one
+// holding most of them, a name until the LLM gives a better one
+func commonDir(files []string) string {
+	prefix := path.Dir(files[0])
+	for _, file := range files[1:] {
+		for prefix != "." && file != prefix && !strings.HasPrefix(file, prefix+"/") {
+			prefix = path.Dir(prefix)
+		}
+	}
+	if prefix != "." {
+		return prefix + "/"
+	}
+
+	counts := make(map[string]int)
+	best := ""
+	for _, file := range files {
+		dir := path.Dir(file)
+		counts[dir]++
+		if counts[dir] > counts[best] || (counts[dir] == counts[best] && dir < best) {
+			best = dir
+		}
+	}
+	if best == "." {
+		return "(root)"
+	}
+	return best + "/ and others"
+}
+
+// Name has the LLM name every cluster and say what it does in one line, from
+// its files and symbols
+func (m *Map) Name(client *llm.Client) error {
+	if len(m.Clusters) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`Below are groups of files from one codebase, grouped by what their code is
+about and what calls what. Name each group after what it does (two to four
+words, like "Query routing" or "Embedding pipeline") and describe it in one
+sentence.
+
+Reply with one line per group, exactly: number|name|description
+
+`)
+	for _, c := range m.Clusters {
+		fmt.Fprintf(&sb, "%d. files: %s\n", c.ID, strings.Join(limit(c.Files, 12), ", "))
+		if len(c.Symbols) > 0 {
+			fmt.Fprintf(&sb, "   symbols: %s\n", strings.Join(c.Symbols, ", "))
+		}
+	}
+
+	reply, err := client.Complete(sb.String())
+	if err != nil {
+		return fmt.Errorf("LLM query failed: %w", err)
+	}
+	for _, line := range strings.Split(reply, "\n") {
+		match := nameLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		id, _ := strconv.Atoi(match[1])
+		if id < 1 || id > len(m.Clusters) || match[2] == "" {
+			continue
+		}
+		m.Clusters[id-1].Name = match[2]
+		m.Clusters[id-1].Description = strings.TrimSpace(match[3])
+	}
+	return nil
+}
+
+func limit(list []string, n int) []string {
+	if len(list) <= n {
+		return list
+	}
+	return append(list[:n:n], fmt.Sprintf("and %d more", len(list)-n))
+}
+
+// Mermaid renders the clusters as a flowchart, edges are calls
+func (m *Map) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	for _, c := range m.Clusters {
+		fmt.Fprintf(&sb, "  c%d[\"%s<br/>%d files\"]\n", c.ID, mermaidText(c.Name), len(c.Files))
+	}
+	for _, l := range m.Links {
+		fmt.Fprintf(&sb, "  c%d -->|%d| c%d\n", l.From, l.Calls, l.To)
+	}
+	return sb.String()
+}
+
+func mermaidText(s string) string {
+	return strings.NewReplacer(`"`, "'", "[", "(", "]", ")").Replace(s)
+}
+
+// Markdown renders the map with the Mermaid chart on top and a section per
+// cluster
+func (m *Map) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Module map of %s\n\n", m.Project)
+	fmt.Fprintf(&sb, "```mermaid\n%s```\n", m.Mermaid())
+
+	names := make(map[int]string)
+	for _, c := range m.Clusters {
+		names[c.ID] = c.Name
+	}
+	for _, c := range m.Clusters {
+		fmt.Fprintf(&sb, "\n## %d. %s\n\n", c.ID, c.Name)
+		if c.Description != "" {
+			fmt.Fprintf(&sb, "%s\n\n", c.Description)
+		}
+		if len(c.Symbols) > 0 {
+			fmt.Fprintf(&sb, "Key symbols: `%s`\n\n", strings.Join(c.Symbols, "`, `"))
+		}
+		linked := false
+		for _, l := range m.Links {
+			if l.From == c.ID {
+				fmt.Fprintf(&sb, "- calls %s (%d calls)\n", names[l.To], l.Calls)
+				linked = true
+			}
+		}
+		for _, l := range m.Links {
+			if l.To == c.ID {
+				fmt.Fprintf(&sb, "- called from %s (%d calls)\n", names[l.From], l.Calls)
+				linked = true
+			}
+		}
+		if linked {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("Files:\n\n")
+		for _, file := range c.Files {
+			fmt.Fprintf(&sb, "- `%s`\n", file)
+		}
+	}
+
+	if len(m.Unclustered) > 0 {
+		sb.WriteString("\n## Unclustered\n\nNo vectors or calls to place these by.\n\n")
+		for _, file := range m.Unclustered {
+			fmt.Fprintf(&sb, "- `%s`\n", file)
+		}
+	}
+	return sb.String()
+}
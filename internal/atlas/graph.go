@@ -0,0 +1,183 @@
+package atlas
+
+import "sort"
+
+// graph is an undirected weighted graph of files
+type graph struct {
+	edges  []map[int]float64
+	degree []float64 // sum of edge weights per node
+	total  float64   // sum of degrees, twice the edge weight
+}
+
+func newGraph(n int) *graph {
+	g := &graph{edges: make([]map[int]float64, n), degree: make([]float64, n)}
+	for i := range g.edges {
+		g.edges[i] = make(map[int]float64)
+	}
+	return g
+}
+
+func (g *graph) add(a, b int, w float64) {
+	if a == b || w <= 0 {
+		return
+	}
+	g.edges[a][b] += w
+	g.edges[b][a] += w
+	g.degree[a] += w
+	g.degree[b] += w
+	g.total += 2 * w
+}
+
+// communities finds the communities with the Louvain method: nodes move to
+// the neighbouring community that raises modularity most, then communities
+// become the nodes of a smaller graph and it repeats until nothing moves.
+// The result maps each node to a community id.
+func (g *graph) communities() []int {
+	community := make([]int, len(g.edges))
+	for i := range community {
+		community[i] = i
+	}
+	if g.total == 0 {
+		return community
+	}
+
+	level := g
+	for {
+		moved := level.localMoves()
+		ids := renumber(moved)
+		for node, c := range community {
+			community[node] = ids[moved[c]]
+		}
+		if len(ids) == len(level.edges) {
+			return community
+		}
+		level = level.collapse(moved, ids)
+	}
+}
+
+// localMoves is one Louvain phase, each node's community after it
+func (g *graph) localMoves() []int {
+	n := len(g.edges)
+	community := make([]int, n)
+	tot := make([]float64, n) // degree sum per community
+	for i := range community {
+		community[i] = i
+		tot[i] = g.degree[i]
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < n; i++ {
+			own := community[i]
+			links := make(map[int]float64)
+			for j, w := range g.edges[i] {
+				if j != i {
+					links[community[j]] += w
+				}
+			}
+			tot[own] -= g.degree[i]
+
+			// The gain of joining c is links[c] - tot[c]*k_i/m, staying is
+			// the baseline; ties go to the lower id so runs are repeatable
+			best, bestGain := own, links[own]-tot[own]*g.degree[i]/g.total
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+			for _, c := range candidates {
+				gain := links[c] - tot[c]*g.degree[i]/g.total
+				if gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+
+			tot[best] += g.degree[i]
+			if best != own {
+				community[i] = best
+				changed = true
+			}
+		}
+	}
+	return community
+}
+
+// renumber maps the community ids in use to 0..n-1 in order of first use
+func renumber(community []int) map[int]int {
+	ids := make(map[int]int)
+	for _, c := range community {
+		if _, ok := ids[c]; !ok {
+			ids[c] = len(ids)
+		}
+	}
+	return ids
+}
+
+// collapse makes a graph with a node per community, edges inside a
+// community become a self loop that keeps its weight in the degree
+func (g *graph) collapse(community []int, ids map[int]int) *graph {
+	c := newGraph(len(ids))
+	for i, edges := range g.edges {
+		for j, w := range edges {
+			a, b := ids[community[i]], ids[community[j]]
+			if a == b {
+				c.degree[a] += w
+				c.total += w
+				continue
+			}
+			c.edges[a][b] += w
+			c.degree[a] += w
+			c.total += w
+		}
+	}
+	return c
+}
+
+// mergeSmallest folds the smallest groups into the group they have the
+// most edge weight to until at most max are left. A group with no edges to
+// another is kept.
+func mergeSmallest(g *graph, groups [][]int, max int) [][]int {
+	for len(groups) > max {
+		sort.Slice(groups, func(i, j int) bool {
+			if len(groups[i]) != len(groups[j]) {
+				return len(groups[i]) < len(groups[j])
+			}
+			return groups[i][0] < groups[j][0]
+		})
+
+		merged := false
+		for s := 0; s < len(groups) && !merged; s++ {
+			in := make(map[int]bool)
+			for _, node := range groups[s] {
+				in[node] = true
+			}
+			best, bestWeight := -1, 0.0
+			for t := range groups {
+				if t == s {
+					continue
+				}
+				weight := 0.0
+				for _, node := range groups[t] {
+					for j, w := range g.edges[node] {
+						if in[j] {
+							weight += w
+						}
+					}
+				}
+				if weight > bestWeight {
+					best, bestWeight = t, weight
+				}
+			}
+			if best >= 0 {
+				groups[best] = append(groups[best], groups[s]...)
+				sort.Ints(groups[best])
+				groups = append(groups[:s], groups[s+1:]...)
+				merged = true
+			}
+		}
+		if !merged {
+			break
+		}
+	}
+	return groups
+}
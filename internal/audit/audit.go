@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every prompt that leaves the machine is recorded as one JSON line in
+// audit.jsonl: when, where to, how big, which files and the sha256 of what
+// was sent, never the prompt itself. The log is only ever appended to,
+// rotation renames it and starts a new one.
+
+// Current is the log being written, rotated logs are audit-<time>.jsonl
+const Current = "audit.jsonl"
+
+// Entry is one outbound prompt
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model"`
+	Endpoint string    `json:"endpoint,omitempty"`
+	Bytes    int       `json:"bytes"`
+	SHA256   string    `json:"sha256"`
+	Files    []string  `json:"files,omitempty"`
+}
+
+// Log appends entries to the current log of a directory
+type Log struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Scribe writes the audit log in dir, the directory is created on the first
+// entry
+func Scribe(dir string) *Log {
+	return &Log{dir: dir}
+}
+
+// Record appends an entry. Each entry is written with a single write on a
+// file opened for appending, so concurrent eulix processes don't interleave.
+func (l *Log) Record(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(l.dir, Current), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Read returns the entries of one log file, oldest first
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return entries, fmt.Errorf("%s line %d: %w", filepath.Base(path), n, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Files lists the logs in dir, rotated ones oldest first and the current
+// one last
+func Files(dir string) ([]string, error) {
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	// The timestamp in the name sorts chronologically
+	sort.Strings(rotated)
+
+	current := filepath.Join(dir, Current)
+	if _, err := os.Stat(current); err == nil {
+		rotated = append(rotated, current)
+	}
+	return rotated, nil
+}
+
+// Rotate moves the current log aside as audit-<time>.jsonl and returns its
+// new path, "" when there was nothing to rotate
+func Rotate(dir string, now time.Time) (string, error) {
+	current := filepath.Join(dir, Current)
+	info, err := os.Stat(current)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Size() == 0 {
+		return "", nil
+	}
+
+	rotated := filepath.Join(dir, fmt.Sprintf("audit-%s.jsonl", now.UTC().Format("20060102T150405Z")))
+	if _, err := os.Stat(rotated); err == nil {
+		return "", fmt.Errorf("%s already exists", filepath.Base(rotated))
+	}
+	if err := os.Rename(current, rotated); err != nil {
+		return "", err
+	}
+	return rotated, nil
+}
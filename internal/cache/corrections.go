@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A correction is a note from the user that a cached answer is wrong, like
+// "this function was removed in v2". It is shown with the answer whenever
+// the question is asked again, and with any other question that got the
+// same answer, until the next analyze changes the checksum it was made
+// against. Corrections are local and SQL only, like ratings.
+
+// ErrNoCorrections is returned when correcting needs a SQL cache that isn't on
+var ErrNoCorrections = errors.New("corrections need the SQL cache, set cache.sql.enabled")
+
+// Correction is the user's note on a cached answer
+type Correction struct {
+	QueryHash string    `json:"query_hash"`
+	Query     string    `json:"query"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (m *Manager) initCorrectionsSchema() error {
+	_, err := m.sqlDB.Exec(`
+	CREATE TABLE IF NOT EXISTS corrections (
+		query_hash TEXT PRIMARY KEY,
+		query TEXT NOT NULL,
+		correction TEXT NOT NULL,
+		response_hash TEXT NOT NULL,
+		checksum_hash TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_corrections_response ON corrections(response_hash);
+	`)
+	return err
+}
+
+// Correct attaches a correction to the cached answer with queryHash, an
+// empty text removes it
+func (m *Manager) Correct(queryHash, text string) error {
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return ErrNoCorrections
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		_, err := m.sqlDB.Exec("DELETE FROM corrections WHERE query_hash = ?", queryHash)
+		return err
+	}
+
+	entries, err := m.ListAll()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.QueryHash != queryHash {
+			continue
+		}
+		_, err := m.sqlDB.Exec(`
+			INSERT OR REPLACE INTO corrections
+			(query_hash, query, correction, response_hash, checksum_hash, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, entry.QueryHash, entry.Query, text, responseHash(entry.Response), entry.ChecksumHash, time.Now())
+		return err
+	}
+	return fmt.Errorf("no cached answer with hash %s", queryHash)
+}
+
+// CorrectionFor returns the correction for query, or for response when
+// another question got the same answer. Corrections made against another
+// checksum no longer apply.
+func (m *Manager) CorrectionFor(query, response, currentChecksumHash string) (*Correction, bool) {
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return nil, false
+	}
+	var c Correction
+	err := m.sqlDB.QueryRow(`
+		SELECT query_hash, query, correction, created_at
+		FROM corrections
+		WHERE checksum_hash = ? AND (query_hash = ? OR response_hash = ?)
+		ORDER BY query_hash = ? DESC, created_at DESC
+		LIMIT 1
+	`, currentChecksumHash, m.hashQuery(query), responseHash(response), m.hashQuery(query)).Scan(
+		&c.QueryHash, &c.Query, &c.Text, &c.CreatedAt)
+	if err != nil {
+		return nil, false
+	}
+	return &c, true
+}
+
+// Corrections returns every correction by query hash, for the history view
+func (m *Manager) Corrections() map[string]Correction {
+	corrections := make(map[string]Correction)
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return corrections
+	}
+	rows, err := m.sqlDB.Query("SELECT query_hash, query, correction, created_at FROM corrections")
+	if err != nil {
+		return corrections
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c Correction
+		if rows.Scan(&c.QueryHash, &c.Query, &c.Text, &c.CreatedAt) == nil {
+			corrections[c.QueryHash] = c
+		}
+	}
+	return corrections
+}
+
+// dropStaleCorrections forgets corrections made against another checksum,
+// re-analysis is what they were waiting for
+func (m *Manager) dropStaleCorrections(currentChecksumHash string) error {
+	_, err := m.sqlDB.Exec("DELETE FROM corrections WHERE checksum_hash != ?", currentChecksumHash)
+	return err
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A pinned answer is copied to the favorites table, so it outlives its
+// cache entry: TTLs, cache clean, invalidation on code changes and delete
+// never touch it. Favorites are personal and SQL only, a shared Redis
+// keeps no favorites.
+
+// ErrNoFavorites is returned when pinning needs a SQL cache that isn't on
+var ErrNoFavorites = errors.New("pinned answers need the SQL cache, set cache.sql.enabled")
+
+// Favorite is a pinned answer
+type Favorite struct {
+	CacheEntry
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+func (m *Manager) initFavoritesSchema() error {
+	_, err := m.sqlDB.Exec(`
+	CREATE TABLE IF NOT EXISTS favorites (
+		query_hash TEXT PRIMARY KEY,
+		query TEXT NOT NULL,
+		response TEXT NOT NULL,
+		checksum_hash TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		sources TEXT NOT NULL DEFAULT '',
+		notes TEXT NOT NULL DEFAULT '',
+		pinned_at DATETIME NOT NULL
+	);
+	`)
+	return err
+}
+
+func (m *Manager) favoritesEnabled() bool {
+	return m.config.Cache.SQL.Enabled && m.sqlDB != nil
+}
+
+// Pin copies the cached answer with queryHash to the favorites, pinning it
+// again refreshes the copy
+func (m *Manager) Pin(queryHash string) (*Favorite, error) {
+	if !m.favoritesEnabled() {
+		return nil, ErrNoFavorites
+	}
+	entries, err := m.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.QueryHash != queryHash {
+			continue
+		}
+		fav := &Favorite{CacheEntry: entry, PinnedAt: time.Now()}
+		_, err := m.sqlDB.Exec(`
+			INSERT OR REPLACE INTO favorites
+			(query_hash, query, response, checksum_hash, created_at, sources, notes, pinned_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, entry.QueryHash, entry.Query, entry.Response, entry.ChecksumHash, entry.CreatedAt,
+			encodeSources(entry.Sources), entry.Notes, fav.PinnedAt)
+		if err != nil {
+			return nil, err
+		}
+		return fav, nil
+	}
+	return nil, fmt.Errorf("no cached answer with hash %s", queryHash)
+}
+
+// Unpin removes queryHash from the favorites, its cache entry stays
+func (m *Manager) Unpin(queryHash string) error {
+	if !m.favoritesEnabled() {
+		return ErrNoFavorites
+	}
+	res, err := m.sqlDB.Exec("DELETE FROM favorites WHERE query_hash = ?", queryHash)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s isn't pinned", queryHash)
+	}
+	return nil
+}
+
+// Favorites lists the pinned answers, most recently pinned first. A
+// non-empty search keeps those whose question, answer or notes contain it.
+func (m *Manager) Favorites(search string) ([]Favorite, error) {
+	if !m.favoritesEnabled() {
+		return nil, nil
+	}
+	rows, err := m.sqlDB.Query(`
+		SELECT query_hash, query, response, checksum_hash, created_at, sources, notes, pinned_at
+		FROM favorites
+		ORDER BY pinned_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	search = strings.ToLower(search)
+	var favorites []Favorite
+	for rows.Next() {
+		var fav Favorite
+		var sources string
+		if err := rows.Scan(&fav.QueryHash, &fav.Query, &fav.Response, &fav.ChecksumHash,
+			&fav.CreatedAt, &sources, &fav.Notes, &fav.PinnedAt); err != nil {
+			continue
+		}
+		fav.Sources = decodeSources(sources)
+		if search != "" && !strings.Contains(strings.ToLower(fav.Query+"\n"+fav.Response+"\n"+fav.Notes), search) {
+			continue
+		}
+		favorites = append(favorites, fav)
+	}
+	return favorites, rows.Err()
+}
+
+// Pinned is the set of pinned hashes
+func (m *Manager) Pinned() map[string]bool {
+	pinned := make(map[string]bool)
+	if !m.favoritesEnabled() {
+		return pinned
+	}
+	rows, err := m.sqlDB.Query("SELECT query_hash FROM favorites")
+	if err != nil {
+		return pinned
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hash string
+		if rows.Scan(&hash) == nil {
+			pinned[hash] = true
+		}
+	}
+	return pinned
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"sort"
+)
+
+// The history view shows a question once however often it was asked and
+// however it was phrased, as far as normalizeQuery can tell: the current
+// answer on top, older ones under it.
+
+// HistoryGroup is every cached answer to one question
+type HistoryGroup struct {
+	Key     string       // the normalized question
+	Entries []CacheEntry // most recent first, the first is the one shown
+	Older   []CacheEntry // answers from before the code changed, see Previous
+	Asked   int          // times asked, from the query log, at least len(Entries)
+}
+
+// Latest is the most recent answer in the group
+func (g HistoryGroup) Latest() CacheEntry {
+	return g.Entries[0]
+}
+
+// History groups every cache entry by question, most recently answered
+// first
+func (m *Manager) History() ([]HistoryGroup, error) {
+	entries, err := m.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	older, err := m.listPrevious()
+	if err != nil {
+		return nil, err
+	}
+	asked, err := m.askCounts()
+	if err != nil {
+		return nil, err
+	}
+	return groupHistory(entries, older, asked), nil
+}
+
+// groupHistory groups entries, already most recent first, and files each
+// older answer under the group of its question
+func groupHistory(entries, older []CacheEntry, asked map[string]int) []HistoryGroup {
+	var groups []HistoryGroup
+	index := make(map[string]int)
+	for _, entry := range entries {
+		key := normalizeQuery(entry.Query)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, HistoryGroup{Key: key})
+		}
+		groups[i].Entries = append(groups[i].Entries, entry)
+	}
+
+	sort.SliceStable(older, func(i, j int) bool {
+		return older[i].CreatedAt.After(older[j].CreatedAt)
+	})
+	for _, entry := range older {
+		if i, ok := index[normalizeQuery(entry.Query)]; ok {
+			groups[i].Older = append(groups[i].Older, entry)
+		}
+	}
+
+	for i := range groups {
+		groups[i].Asked = max(asked[groups[i].Key], len(groups[i].Entries))
+	}
+	return groups
+}
+
+// listPrevious returns every answer kept in previous_answers, SQL only
+func (m *Manager) listPrevious() ([]CacheEntry, error) {
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return nil, nil
+	}
+	rows, err := m.sqlDB.Query(`
+		SELECT query_hash, query, response, checksum_hash, created_at, expires_at, sources
+		FROM previous_answers
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []CacheEntry
+	for rows.Next() {
+		var entry CacheEntry
+		var sources string
+		if err := rows.Scan(&entry.QueryHash, &entry.Query, &entry.Response, &entry.ChecksumHash,
+			&entry.CreatedAt, &entry.ExpiresAt, &sources); err != nil {
+			continue
+		}
+		entry.Sources = decodeSources(sources)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// askCounts is how often each normalized question was asked, SQL only like
+// the query log
+func (m *Manager) askCounts() (map[string]int, error) {
+	counts := make(map[string]int)
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return counts, nil
+	}
+	rows, err := m.sqlDB.Query("SELECT query, COUNT(*) FROM query_log GROUP BY query")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var query string
+		var n int
+		if err := rows.Scan(&query, &n); err != nil {
+			continue
+		}
+		counts[normalizeQuery(query)] += n
+	}
+	return counts, rows.Err()
+}
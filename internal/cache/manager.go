@@ -8,9 +8,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
+	"unicode"
 
 	"eulix/internal/config"
+	"eulix/internal/version"
 
 	"github.com/redis/go-redis/v9"
 	_ "github.com/mattn/go-sqlite3"
@@ -21,6 +24,8 @@ type Manager struct {
 	redisClient *redis.Client
 	sqlDB       *sql.DB
 	ctx         context.Context
+	user        string // author of new entries
+	kbCurrent   bool   // KB matches the working tree, see SetKBCurrent
 }
 
 type CacheEntry struct {
@@ -30,16 +35,28 @@ type CacheEntry struct {
 	ChecksumHash   string    `json:"checksum_hash"`
 	CreatedAt      time.Time `json:"created_at"`
 	ExpiresAt      time.Time `json:"expires_at"`
+	Sources        []string  `json:"sources,omitempty"`     // files the answer was built from
+	Fingerprint    string    `json:"fingerprint,omitempty"` // of the KB's chunks of Sources, "" without any
+	User           string    `json:"user,omitempty"`        // who asked, matters on a shared Redis
+	Model          string    `json:"model,omitempty"`
+	Version        string    `json:"eulix_version,omitempty"`
+	Rating         int       `json:"rating,omitempty"` // 1-5 from the history view, 0 when unrated
+	Notes          string    `json:"notes,omitempty"`
 }
 
 func CacheController(cfg *config.Config) (*Manager, error) {
 	m := &Manager{
 		config: cfg,
 		ctx:    context.Background(),
+		user:   resolveUser(cfg.Cache.Redis.User),
 	}
 
 	// Initialize Redis if enabled
 	if cfg.Cache.Redis.Enabled {
+		if err := validateAccess(cfg.Cache.Redis.Access); err != nil {
+			return nil, err
+		}
+
 		opt, err := redis.ParseURL(cfg.Cache.Redis.URL)
 		if err != nil {
 			return nil, fmt.Errorf("invalid redis URL: %w", err)
@@ -55,7 +72,7 @@ func CacheController(cfg *config.Config) (*Manager, error) {
 
 	// Initialize SQL if enabled
 	if cfg.Cache.SQL.Enabled {
-		dbPath := ".eulix/cache.db"
+		dbPath := config.DefaultCacheDSN
 		if cfg.Cache.SQL.DSN != "" {
 			dbPath = cfg.Cache.SQL.DSN
 		}
@@ -92,68 +109,136 @@ func (m *Manager) initSQLSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_created_at ON cache_entries(created_at);
 	`
 
-	_, err := m.sqlDB.Exec(schema)
+	if _, err := m.sqlDB.Exec(schema); err != nil {
+		return err
+	}
+
+	// Caches created before sources were recorded lack the column
+	if err := m.addColumnIfMissing("cache_entries", "sources", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := m.addColumnIfMissing("cache_entries", "fingerprint", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	for _, column := range []string{"user", "model", "eulix_version"} {
+		if err := m.addColumnIfMissing("cache_entries", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+
+	if err := m.initRatingSchema(); err != nil {
+		return err
+	}
+
+	if err := m.initPreviousSchema(); err != nil {
+		return err
+	}
+
+	if err := m.initFavoritesSchema(); err != nil {
+		return err
+	}
+
+	if err := m.initCorrectionsSchema(); err != nil {
+		return err
+	}
+
+	return m.initQueryLogSchema()
+}
+
+func (m *Manager) addColumnIfMissing(table, column, definition string) error {
+	rows, err := m.sqlDB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+
+	_, err = m.sqlDB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
 // Get retrieves a cached response if it exists and the checksum matches
 func (m *Manager) Get(query string, currentChecksumHash string) (string, bool, error) {
+	entry, found, err := m.Lookup(query, currentChecksumHash)
+	if !found {
+		return "", false, err
+	}
+	return entry.Response, true, nil
+}
+
+// Lookup is Get returning the whole entry, for callers that also check the
+// context fingerprint. An answer rated low is never a hit, see Rate.
+func (m *Manager) Lookup(query string, currentChecksumHash string) (*CacheEntry, bool, error) {
 	queryHash := m.hashQuery(query)
 
 	// Try Redis first (if enabled)
 	if m.config.Cache.Redis.Enabled && m.redisClient != nil {
-		if response, found, err := m.getFromRedis(queryHash, currentChecksumHash); err == nil && found {
-			return response, true, nil
+		if entry, found, err := m.getFromRedis(queryHash, currentChecksumHash); err == nil && found && !m.rejected(entry) {
+			return entry, true, nil
 		}
 	}
 
 	// Try SQL (if enabled)
 	if m.config.Cache.SQL.Enabled && m.sqlDB != nil {
-		if response, found, err := m.getFromSQL(queryHash, currentChecksumHash); err == nil && found {
-			return response, true, nil
+		if entry, found, err := m.getFromSQL(queryHash, currentChecksumHash); err == nil && found && !m.rejected(entry) {
+			return entry, true, nil
 		}
 	}
 
-	return "", false, nil
+	return nil, false, nil
 }
 
-func (m *Manager) getFromRedis(queryHash, currentChecksumHash string) (string, bool, error) {
-	key := fmt.Sprintf("eulix:query:%s", queryHash)
+func (m *Manager) getFromRedis(queryHash, currentChecksumHash string) (*CacheEntry, bool, error) {
+	key := m.redisKey(queryHash, currentChecksumHash)
 
 	data, err := m.redisClient.Get(m.ctx, key).Result()
 	if err == redis.Nil {
-		return "", false, nil
+		return nil, false, nil
 	}
 	if err != nil {
-		return "", false, err
+		return nil, false, err
 	}
 
 	var entry CacheEntry
 	if err := json.Unmarshal([]byte(data), &entry); err != nil {
-		return "", false, err
+		return nil, false, err
 	}
 
 	// Verify checksum matches
 	if entry.ChecksumHash != currentChecksumHash {
-		// Checksum mismatch - invalidate cache
-		m.redisClient.Del(m.ctx, key)
-		return "", false, nil
+		// Checksum mismatch - keep it as the previous answer for diffing
+		if m.redisWritable() {
+			m.redisClient.Rename(m.ctx, key, previousKey(queryHash))
+		}
+		return nil, false, nil
 	}
 
-	// Check expiration
+	// Check expiration, Redis drops the key itself at the TTL
 	if time.Now().After(entry.ExpiresAt) {
-		m.redisClient.Del(m.ctx, key)
-		return "", false, nil
+		return nil, false, nil
 	}
 
-	return entry.Response, true, nil
+	return &entry, true, nil
 }
 
-func (m *Manager) getFromSQL(queryHash, currentChecksumHash string) (string, bool, error) {
+func (m *Manager) getFromSQL(queryHash, currentChecksumHash string) (*CacheEntry, bool, error) {
 	var entry CacheEntry
+	var sources string
 
 	query := `
-		SELECT query_hash, query, response, checksum_hash, created_at, expires_at
+		SELECT query_hash, query, response, checksum_hash, created_at, expires_at, sources, fingerprint,
+			user, model, eulix_version, rating, notes
 		FROM cache_entries
 		WHERE query_hash = ? AND checksum_hash = ?
 	`
@@ -165,40 +250,56 @@ func (m *Manager) getFromSQL(queryHash, currentChecksumHash string) (string, boo
 		&entry.ChecksumHash,
 		&entry.CreatedAt,
 		&entry.ExpiresAt,
+		&sources,
+		&entry.Fingerprint,
+		&entry.User,
+		&entry.Model,
+		&entry.Version,
+		&entry.Rating,
+		&entry.Notes,
 	)
 
 	if err == sql.ErrNoRows {
-		return "", false, nil
+		return nil, false, nil
 	}
 	if err != nil {
-		return "", false, err
+		return nil, false, err
 	}
 
 	// Check expiration
 	if time.Now().After(entry.ExpiresAt) {
 		// Delete expired entry
 		m.sqlDB.Exec("DELETE FROM cache_entries WHERE query_hash = ?", queryHash)
-		return "", false, nil
+		return nil, false, nil
 	}
 
-	return entry.Response, true, nil
+	entry.Sources = decodeSources(sources)
+	return &entry, true, nil
 }
 
-// Set stores a response in cache with the current checksum
-func (m *Manager) Set(query, response, checksumHash string) error {
+// Set stores a response in cache with the current checksum and the
+// fingerprint of the context it was built from. queryType picks its
+// lifetime, see ttl; "" gets the backend's own.
+func (m *Manager) Set(query, response, checksumHash string, sources []string, fingerprint, queryType string) error {
 	queryHash := m.hashQuery(query)
+	now := time.Now()
 
 	entry := CacheEntry{
 		QueryHash:    queryHash,
 		Query:        query,
 		Response:     response,
 		ChecksumHash: checksumHash,
-		CreatedAt:    time.Now(),
-		ExpiresAt:    time.Now().Add(m.getTTL()),
+		CreatedAt:    now,
+		Sources:      sources,
+		Fingerprint:  fingerprint,
+		User:         m.user,
+		Model:        m.config.LLM.Model,
+		Version:      version.Version,
 	}
 
 	// Save to Redis
-	if m.config.Cache.Redis.Enabled && m.redisClient != nil {
+	if m.config.Cache.Redis.Enabled && m.redisClient != nil && m.redisWritable() {
+		entry.ExpiresAt = now.Add(m.ttl(queryType, m.config.Cache.Redis.TTLHours))
 		if err := m.saveToRedis(&entry); err != nil {
 			return fmt.Errorf("redis save failed: %w", err)
 		}
@@ -206,12 +307,14 @@ func (m *Manager) Set(query, response, checksumHash string) error {
 
 	// Save to SQL
 	if m.config.Cache.SQL.Enabled && m.sqlDB != nil {
+		entry.ExpiresAt = now.Add(m.ttl(queryType, m.config.Cache.SQL.TTLHours))
 		if err := m.saveToSQL(&entry); err != nil {
 			return fmt.Errorf("sql save failed: %w", err)
 		}
 	}
 
-	return nil
+	// The new answer has been shown against the previous one, if any
+	return m.dropPrevious(queryHash)
 }
 
 func (m *Manager) saveToRedis(entry *CacheEntry) error {
@@ -220,17 +323,23 @@ func (m *Manager) saveToRedis(entry *CacheEntry) error {
 		return err
 	}
 
-	key := fmt.Sprintf("eulix:query:%s", entry.QueryHash)
+	key := m.redisKey(entry.QueryHash, entry.ChecksumHash)
 	ttl := time.Until(entry.ExpiresAt)
 
+	// First answer wins on a shared Redis, so entries don't flip between
+	// whoever asked last
+	if m.shared() {
+		return m.redisClient.SetNX(m.ctx, key, data, ttl).Err()
+	}
 	return m.redisClient.Set(m.ctx, key, data, ttl).Err()
 }
 
 func (m *Manager) saveToSQL(entry *CacheEntry) error {
 	query := `
 		INSERT OR REPLACE INTO cache_entries
-		(query_hash, query, response, checksum_hash, created_at, expires_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		(query_hash, query, response, checksum_hash, created_at, expires_at, sources, fingerprint,
+		 user, model, eulix_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := m.sqlDB.Exec(
@@ -241,15 +350,21 @@ func (m *Manager) saveToSQL(entry *CacheEntry) error {
 		entry.ChecksumHash,
 		entry.CreatedAt,
 		entry.ExpiresAt,
+		encodeSources(entry.Sources),
+		entry.Fingerprint,
+		entry.User,
+		entry.Model,
+		entry.Version,
 	)
 
 	return err
 }
 
-// Delete removes a specific cache entry from both backends
+// Delete removes a specific cache entry from both backends. A shared Redis
+// is left alone, its entries are the team's.
 func (m *Manager) Delete(queryHash string) error {
 	// Delete from Redis
-	if m.config.Cache.Redis.Enabled && m.redisClient != nil {
+	if m.config.Cache.Redis.Enabled && m.redisClient != nil && !m.shared() && !m.readOnly() {
 		key := fmt.Sprintf("eulix:query:%s", queryHash)
 		if err := m.redisClient.Del(m.ctx, key).Err(); err != nil {
 			return fmt.Errorf("redis delete failed: %w", err)
@@ -274,7 +389,8 @@ func (m *Manager) ListAll() ([]CacheEntry, error) {
 	// Get from SQL (primary source of truth)
 	if m.config.Cache.SQL.Enabled && m.sqlDB != nil {
 		rows, err := m.sqlDB.Query(`
-			SELECT query_hash, query, response, checksum_hash, created_at, expires_at
+			SELECT query_hash, query, response, checksum_hash, created_at, expires_at, sources,
+				user, model, eulix_version, rating, notes
 			FROM cache_entries
 			ORDER BY created_at DESC
 		`)
@@ -285,6 +401,7 @@ func (m *Manager) ListAll() ([]CacheEntry, error) {
 
 		for rows.Next() {
 			var entry CacheEntry
+			var sources string
 			err := rows.Scan(
 				&entry.QueryHash,
 				&entry.Query,
@@ -292,10 +409,17 @@ func (m *Manager) ListAll() ([]CacheEntry, error) {
 				&entry.ChecksumHash,
 				&entry.CreatedAt,
 				&entry.ExpiresAt,
+				&sources,
+				&entry.User,
+				&entry.Model,
+				&entry.Version,
+				&entry.Rating,
+				&entry.Notes,
 			)
 			if err != nil {
 				continue
 			}
+			entry.Sources = decodeSources(sources)
 			entries = append(entries, entry)
 		}
 	}
@@ -329,10 +453,18 @@ func (m *Manager) ListAll() ([]CacheEntry, error) {
 	return entries, nil
 }
 
-// InvalidateByChecksum removes all cache entries with a different checksum
+// InvalidateByChecksum removes all cache entries with a different checksum.
+// They are kept as previous answers so a re-asked question can show what
+// changed; an empty checksum clears everything, previous answers included.
 func (m *Manager) InvalidateByChecksum(currentChecksumHash string) error {
 	// Invalidate in SQL
 	if m.config.Cache.SQL.Enabled && m.sqlDB != nil {
+		if err := m.retireStale(currentChecksumHash); err != nil {
+			return err
+		}
+		if err := m.dropStaleCorrections(currentChecksumHash); err != nil {
+			return err
+		}
 		_, err := m.sqlDB.Exec(
 			"DELETE FROM cache_entries WHERE checksum_hash != ?",
 			currentChecksumHash,
@@ -383,6 +515,7 @@ func (m *Manager) GetStats() (map[string]interface{}, error) {
 			stats["redis_connected"] = true
 			stats["redis_info"] = info
 		}
+		stats["redis_shared"] = m.shared()
 	}
 
 	return stats, nil
@@ -405,20 +538,68 @@ func (m *Manager) Close() error {
 	return nil
 }
 
+// hashQuery keys a question by its normalized form, the entry keeps the
+// text as asked
 func (m *Manager) hashQuery(query string) string {
 	h := sha256.New()
-	h.Write([]byte(query))
+	h.Write([]byte(normalizeQuery(query)))
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-func (m *Manager) getTTL() time.Duration {
-	// Default 24 hours
-	ttl := 24 * time.Hour
+// normalizeQuery collapses a question's whitespace, drops trailing
+// punctuation and lowercases its first word when that is just capitalized,
+// so "Where is main?" and "where is  main" are answered once. Other words
+// keep their case, "Config" and "config" can be different things.
+func normalizeQuery(query string) string {
+	words := strings.Fields(query)
+	if len(words) > 0 && capitalized(words[0]) {
+		words[0] = strings.ToLower(words[0])
+	}
+	return strings.TrimRightFunc(strings.Join(words, " "), func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
 
-	// Use Redis TTL if enabled
-	if m.config.Cache.Redis.Enabled && m.config.Cache.Redis.TTLHours > 0 {
-		ttl = time.Duration(m.config.Cache.Redis.TTLHours) * time.Hour
+// capitalized reports whether word is a plain word with only its first
+// letter upper case, "Where" and "What's" but not "ParseConfig" or "HTTP"
+func capitalized(word string) bool {
+	for i, r := range strings.TrimRightFunc(word, unicode.IsPunct) {
+		if !unicode.IsLetter(r) && r != '\'' || i > 0 && unicode.IsUpper(r) {
+			return false
+		}
 	}
+	return true
+}
+
+// defaultTTL is for a backend without ttl_hours
+const defaultTTL = 24 * time.Hour
+
+// typeTTL are the built-in lifetimes by query type. Where things are and
+// who calls them only change with the code, which the checksum already
+// catches; a debugging answer is about the moment.
+var typeTTL = map[string]time.Duration{
+	"location":   7 * 24 * time.Hour,
+	"usage":      7 * 24 * time.Hour,
+	"dependency": 7 * 24 * time.Hour,
+	"debug":      time.Hour,
+}
 
-	return ttl
+// ttl is how long an answer of queryType lives in a backend set to hours.
+// [cache.types.<type>] wins, then the backend's ttl_hours, which the
+// built-in lifetime for the type can only shorten. Without ttl_hours the
+// built-in lifetime applies as it is.
+func (m *Manager) ttl(queryType string, hours int) time.Duration {
+	for name, t := range m.config.Cache.Types {
+		if t.TTLHours > 0 && strings.EqualFold(name, queryType) {
+			return time.Duration(t.TTLHours * float64(time.Hour))
+		}
+	}
+	backend := defaultTTL
+	if hours > 0 {
+		backend = time.Duration(hours) * time.Hour
+	}
+	if ttl, ok := typeTTL[strings.ToLower(queryType)]; ok && (hours <= 0 || ttl < backend) {
+		return ttl
+	}
+	return backend
 }
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"eulix/internal/config"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	cases := []struct {
+		query, want string
+	}{
+		{"Where is main?", "where is main"},
+		{"where is  main", "where is main"},
+		{"  What's in\tcache.go?!  ", "what's in cache.go"},
+		{"who calls ParseConfig", "who calls ParseConfig"},
+		{"who calls parseConfig?", "who calls parseConfig"},
+		{"Where is Config", "where is Config"},
+		{"where is config", "where is config"},
+		{"HTTP handlers", "HTTP handlers"},
+		{"ParseConfig callers", "ParseConfig callers"},
+		{"what does MAX_RETRIES do...", "what does MAX_RETRIES do"},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := normalizeQuery(tc.query); got != tc.want {
+			t.Errorf("normalizeQuery(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestTTL(t *testing.T) {
+	m := &Manager{config: &config.Config{}}
+	m.config.Cache.Types = map[string]config.CacheTypeConfig{"usage": {TTLHours: 2}}
+	cases := []struct {
+		queryType string
+		hours     int
+		want      time.Duration
+	}{
+		{"location", 24, 24 * time.Hour}, // ttl_hours caps the built-in week
+		{"location", 0, 7 * 24 * time.Hour},
+		{"debug", 24, time.Hour},
+		{"Debug", 0, time.Hour},
+		{"usage", 24, 2 * time.Hour}, // [cache.types.usage]
+		{"architecture", 6, 6 * time.Hour},
+		{"architecture", 0, defaultTTL},
+	}
+	for _, tc := range cases {
+		if got := m.ttl(tc.queryType, tc.hours); got != tc.want {
+			t.Errorf("ttl(%q, %d) = %s, want %s", tc.queryType, tc.hours, got, tc.want)
+		}
+	}
+}
+
+func newSQLManager(t *testing.T) *Manager {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Cache.SQL.Enabled = true
+	cfg.Cache.SQL.DSN = filepath.Join(t.TempDir(), "cache.db")
+	m, err := CacheController(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestPrunePrevious(t *testing.T) {
+	m := newSQLManager(t)
+	for _, q := range []string{"where is main", "who calls Start", "what does Serve do"} {
+		if err := m.Set(q, "answer to "+q, "old", nil, "", "location"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.InvalidateByChecksum("new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.prunePrevious(2); err != nil {
+		t.Fatal(err)
+	}
+
+	previous, err := m.listPrevious()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previous) != 2 {
+		t.Fatalf("%d previous answers kept, want 2", len(previous))
+	}
+	if _, found := m.Previous("where is main", "new"); found {
+		t.Error("the oldest previous answer was kept")
+	}
+	if _, found := m.Previous("what does Serve do", "new"); !found {
+		t.Error("the newest previous answer was dropped")
+	}
+}
+
+func TestPruneQueryLog(t *testing.T) {
+	m := newSQLManager(t)
+	var last int64
+	for _, q := range []string{"where is main", "who calls Start", "what does Serve do", "where is Config"} {
+		row, err := m.LogQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = row
+	}
+	if err := m.pruneQueryLog(last, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	recent, err := m.RecentQueries(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"where is Config", "what does Serve do"}; !reflect.DeepEqual(recent, want) {
+		t.Errorf("RecentQueries = %q, want %q", recent, want)
+	}
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Previous answers are cache entries whose checksum went stale. They are kept
+// until the question is asked again, so the new answer can be compared
+// against the old one. Questions that never come back would pile up with
+// every analyze, so only the newest maxPrevious are kept.
+
+// maxPrevious is how many previous answers SQL keeps
+const maxPrevious = 1000
+
+func (m *Manager) initPreviousSchema() error {
+	_, err := m.sqlDB.Exec(`
+	CREATE TABLE IF NOT EXISTS previous_answers (
+		query_hash TEXT PRIMARY KEY,
+		query TEXT NOT NULL,
+		response TEXT NOT NULL,
+		checksum_hash TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		sources TEXT NOT NULL DEFAULT ''
+	);
+	`)
+	return err
+}
+
+func previousKey(queryHash string) string {
+	return fmt.Sprintf("eulix:previous:%s", queryHash)
+}
+
+// Previous returns the answer cached for query against an older checksum,
+// either retired by InvalidateByChecksum or still sitting in the cache
+func (m *Manager) Previous(query, currentChecksumHash string) (*CacheEntry, bool) {
+	queryHash := m.hashQuery(query)
+
+	if m.config.Cache.SQL.Enabled && m.sqlDB != nil {
+		if entry, err := m.scanPrevious(`
+			SELECT query_hash, query, response, checksum_hash, created_at, expires_at, sources
+			FROM previous_answers
+			WHERE query_hash = ?
+		`, queryHash); err == nil {
+			return entry, true
+		}
+
+		if entry, err := m.scanPrevious(`
+			SELECT query_hash, query, response, checksum_hash, created_at, expires_at, sources
+			FROM cache_entries
+			WHERE query_hash = ? AND checksum_hash != ?
+		`, queryHash, currentChecksumHash); err == nil {
+			return entry, true
+		}
+	}
+
+	if m.config.Cache.Redis.Enabled && m.redisClient != nil && !m.shared() {
+		for _, key := range []string{previousKey(queryHash), fmt.Sprintf("eulix:query:%s", queryHash)} {
+			data, err := m.redisClient.Get(m.ctx, key).Result()
+			if err != nil {
+				continue
+			}
+			var entry CacheEntry
+			if err := json.Unmarshal([]byte(data), &entry); err != nil {
+				continue
+			}
+			if entry.ChecksumHash != currentChecksumHash {
+				return &entry, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func (m *Manager) scanPrevious(query string, args ...interface{}) (*CacheEntry, error) {
+	var entry CacheEntry
+	var sources string
+
+	err := m.sqlDB.QueryRow(query, args...).Scan(
+		&entry.QueryHash,
+		&entry.Query,
+		&entry.Response,
+		&entry.ChecksumHash,
+		&entry.CreatedAt,
+		&entry.ExpiresAt,
+		&sources,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Sources = decodeSources(sources)
+	return &entry, nil
+}
+
+// retireStale moves entries with a different checksum to previous_answers,
+// or clears previous_answers when the whole cache is being cleared
+func (m *Manager) retireStale(currentChecksumHash string) error {
+	if currentChecksumHash == "" {
+		_, err := m.sqlDB.Exec("DELETE FROM previous_answers")
+		return err
+	}
+
+	_, err := m.sqlDB.Exec(`
+		INSERT OR REPLACE INTO previous_answers
+		(query_hash, query, response, checksum_hash, created_at, expires_at, sources)
+		SELECT query_hash, query, response, checksum_hash, created_at, expires_at, sources
+		FROM cache_entries
+		WHERE checksum_hash != ?
+	`, currentChecksumHash)
+	if err != nil {
+		return err
+	}
+	return m.prunePrevious(maxPrevious)
+}
+
+// prunePrevious drops all but the keep most recently cached previous answers
+func (m *Manager) prunePrevious(keep int) error {
+	_, err := m.sqlDB.Exec(`
+		DELETE FROM previous_answers
+		WHERE rowid NOT IN (
+			SELECT rowid FROM previous_answers
+			ORDER BY created_at DESC, rowid DESC
+			LIMIT ?
+		)
+	`, keep)
+	return err
+}
+
+func (m *Manager) dropPrevious(queryHash string) error {
+	if m.config.Cache.Redis.Enabled && m.redisClient != nil && !m.shared() && !m.readOnly() {
+		if err := m.redisClient.Del(m.ctx, previousKey(queryHash)).Err(); err != nil {
+			return fmt.Errorf("redis delete failed: %w", err)
+		}
+	}
+
+	if m.config.Cache.SQL.Enabled && m.sqlDB != nil {
+		if _, err := m.sqlDB.Exec("DELETE FROM previous_answers WHERE query_hash = ?", queryHash); err != nil {
+			return fmt.Errorf("sql delete failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func encodeSources(sources []string) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(sources)
+	return string(data)
+}
+
+func decodeSources(data string) []string {
+	var sources []string
+	if data != "" {
+		json.Unmarshal([]byte(data), &sources)
+	}
+	return sources
+}
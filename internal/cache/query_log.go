@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// The query log keeps every question asked, unlike cache_entries which only
+// has one row per question and loses it when the code changes. It backs
+// history recall and autocomplete in chat. SQL only; with just Redis there is
+// no history. Only the last maxQueryLog questions are kept, older ones go as
+// new ones are logged.
+
+// maxQueryLog is how many asked questions the log keeps
+const maxQueryLog = 10000
+
+// QueryCount is a question and how often it was asked
+type QueryCount struct {
+	Query     string
+	Count     int
+	LastAsked time.Time
+}
+
+func (m *Manager) initQueryLogSchema() error {
+	_, err := m.sqlDB.Exec(`
+	CREATE TABLE IF NOT EXISTS query_log (
+		query TEXT NOT NULL,
+		asked_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_query_log_asked_at ON query_log(asked_at);
+	`)
+	if err != nil {
+		return err
+	}
+	// NULL for questions logged before hits were recorded, so they don't
+	// count as misses
+	return m.addColumnIfMissing("query_log", "cached", "INTEGER")
+}
+
+// LogQuery records that query was asked and returns its row for MarkHit,
+// 0 when nothing was logged
+func (m *Manager) LogQuery(query string) (int64, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return 0, nil
+	}
+
+	res, err := m.sqlDB.Exec("INSERT INTO query_log (query, asked_at, cached) VALUES (?, ?, 0)", query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	row, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return row, m.pruneQueryLog(row, maxQueryLog)
+}
+
+// pruneQueryLog keeps the last keep questions, row being the newest
+func (m *Manager) pruneQueryLog(row, keep int64) error {
+	if row <= keep {
+		return nil
+	}
+	_, err := m.sqlDB.Exec("DELETE FROM query_log WHERE rowid <= ?", row-keep)
+	return err
+}
+
+// MarkHit records that the question LogQuery logged as row was answered
+// from the cache
+func (m *Manager) MarkHit(row int64) error {
+	if row == 0 || !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return nil
+	}
+	_, err := m.sqlDB.Exec("UPDATE query_log SET cached = 1 WHERE rowid = ?", row)
+	return err
+}
+
+// Usage is how much the cache holds and how often it answered
+type Usage struct {
+	Entries int `json:"entries"`
+	Valid   int `json:"valid"` // not expired
+	Asked   int `json:"asked"` // questions logged since hits were recorded
+	Hits    int `json:"hits"`
+}
+
+// HitRate is the share of questions answered from the cache, 0 when none
+// were asked
+func (u Usage) HitRate() float64 {
+	if u.Asked == 0 {
+		return 0
+	}
+	return float64(u.Hits) / float64(u.Asked)
+}
+
+// Usage counts entries and hits, SQL only like the query log
+func (m *Manager) Usage() (Usage, error) {
+	var u Usage
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return u, nil
+	}
+	err := m.sqlDB.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM cache_entries),
+			(SELECT COUNT(*) FROM cache_entries WHERE expires_at > ?),
+			(SELECT COUNT(*) FROM query_log WHERE cached IS NOT NULL),
+			(SELECT COUNT(*) FROM query_log WHERE cached = 1)
+	`, time.Now()).Scan(&u.Entries, &u.Valid, &u.Asked, &u.Hits)
+	return u, err
+}
+
+// RecentQueries returns distinct questions, most recently asked first
+func (m *Manager) RecentQueries(limit int) ([]string, error) {
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return nil, nil
+	}
+
+	rows, err := m.sqlDB.Query(`
+		SELECT query FROM query_log
+		GROUP BY query
+		ORDER BY MAX(asked_at) DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []string
+	for rows.Next() {
+		var query string
+		if err := rows.Scan(&query); err != nil {
+			continue
+		}
+		queries = append(queries, query)
+	}
+	return queries, rows.Err()
+}
+
+// FrequentQueries returns the most asked questions, ties go to the most recent
+func (m *Manager) FrequentQueries(limit int) ([]QueryCount, error) {
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return nil, nil
+	}
+
+	rows, err := m.sqlDB.Query(`
+		SELECT query, COUNT(*) AS asked, MAX(asked_at) AS last
+		FROM query_log
+		GROUP BY query
+		ORDER BY asked DESC, last DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []QueryCount
+	for rows.Next() {
+		var qc QueryCount
+		var last string
+		if err := rows.Scan(&qc.Query, &qc.Count, &last); err != nil {
+			continue
+		}
+		qc.LastAsked = parseSQLiteTime(last)
+		counts = append(counts, qc)
+	}
+	return counts, rows.Err()
+}
+
+// parseSQLiteTime reads a timestamp that went through an aggregate, which
+// go-sqlite3 returns as text instead of time.Time
+func parseSQLiteTime(s string) time.Time {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02T15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
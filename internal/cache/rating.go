@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Ratings are 1 to 5, given from the history view. An answer rated
+// LowRating or below is a negative example: it isn't returned as a cache
+// hit again, for its own question or any other that cached the same text,
+// so the next ask gets a fresh answer. Like deletes, ratings stay local and
+// never touch a shared Redis.
+
+const (
+	MinRating = 1
+	MaxRating = 5
+	// LowRating and below keep an answer from being a cache hit
+	LowRating = 2
+)
+
+func (m *Manager) initRatingSchema() error {
+	if err := m.addColumnIfMissing("cache_entries", "rating", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := m.addColumnIfMissing("cache_entries", "notes", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	_, err := m.sqlDB.Exec(`
+	CREATE TABLE IF NOT EXISTS low_rated_answers (
+		response_hash TEXT PRIMARY KEY,
+		query TEXT NOT NULL,
+		rating INTEGER NOT NULL,
+		notes TEXT NOT NULL DEFAULT '',
+		rated_at DATETIME NOT NULL
+	);
+	`)
+	return err
+}
+
+// Rate records a rating and notes for the entry with queryHash, a rating
+// of 0 clears it
+func (m *Manager) Rate(queryHash string, rating int, notes string) error {
+	if rating != 0 && (rating < MinRating || rating > MaxRating) {
+		return fmt.Errorf("rating must be %d to %d, got %d", MinRating, MaxRating, rating)
+	}
+
+	if m.config.Cache.SQL.Enabled && m.sqlDB != nil {
+		if err := m.rateSQL(queryHash, rating, notes); err != nil {
+			return fmt.Errorf("sql rate failed: %w", err)
+		}
+	}
+
+	if m.config.Cache.Redis.Enabled && m.redisClient != nil && !m.shared() && !m.readOnly() {
+		if err := m.rateRedis(queryHash, rating, notes); err != nil {
+			return fmt.Errorf("redis rate failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) rateSQL(queryHash string, rating int, notes string) error {
+	var query, response string
+	err := m.sqlDB.QueryRow("SELECT query, response FROM cache_entries WHERE query_hash = ?", queryHash).Scan(&query, &response)
+	if err == sql.ErrNoRows {
+		// Only in Redis
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.sqlDB.Exec("UPDATE cache_entries SET rating = ?, notes = ? WHERE query_hash = ?", rating, notes, queryHash); err != nil {
+		return err
+	}
+	// A pinned copy keeps the notes in step
+	if _, err := m.sqlDB.Exec("UPDATE favorites SET notes = ? WHERE query_hash = ?", notes, queryHash); err != nil {
+		return err
+	}
+
+	if lowRated(rating) {
+		_, err = m.sqlDB.Exec(`
+			INSERT OR REPLACE INTO low_rated_answers (response_hash, query, rating, notes, rated_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, responseHash(response), query, rating, notes, time.Now())
+	} else {
+		_, err = m.sqlDB.Exec("DELETE FROM low_rated_answers WHERE response_hash = ?", responseHash(response))
+	}
+	return err
+}
+
+func (m *Manager) rateRedis(queryHash string, rating int, notes string) error {
+	key := m.redisKey(queryHash, "")
+	data, err := m.redisClient.Get(m.ctx, key).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return err
+	}
+	entry.Rating = rating
+	entry.Notes = notes
+	updated, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return m.redisClient.Set(m.ctx, key, updated, redis.KeepTTL).Err()
+}
+
+// rejected reports whether entry was rated low, itself or as the same
+// answer to another question
+func (m *Manager) rejected(entry *CacheEntry) bool {
+	if lowRated(entry.Rating) {
+		return true
+	}
+	if !m.config.Cache.SQL.Enabled || m.sqlDB == nil {
+		return false
+	}
+	var n int
+	m.sqlDB.QueryRow("SELECT COUNT(*) FROM low_rated_answers WHERE response_hash = ?", responseHash(entry.Response)).Scan(&n)
+	return n > 0
+}
+
+func lowRated(rating int) bool {
+	return rating >= MinRating && rating <= LowRating
+}
+
+func responseHash(response string) string {
+	sum := sha256.Sum256([]byte(response))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+)
+
+// A shared Redis serves a team working on different branches and KBs, so
+// its entries follow three rules:
+//   - keys carry the checksum, an answer for one codebase never replaces or
+//     retires the answer for another; old ones just expire
+//   - the first answer for a question and checksum is kept, later writers
+//     don't overwrite it
+//   - answers are only written from a KB that matches its working tree, a
+//     stale KB can answer for its owner but not for everyone with that tree
+// Deletes and previous answers stay local, they are about your history.
+
+const (
+	AccessReadWrite = "read-write"
+	AccessReadOnly  = "read-only"
+)
+
+// SetKBCurrent tells the manager whether the KB was analyzed from the
+// working tree as it is now. Until then nothing is written to a shared Redis.
+func (m *Manager) SetKBCurrent(current bool) {
+	m.kbCurrent = current
+}
+
+func (m *Manager) shared() bool {
+	return m.config.Cache.Redis.Shared
+}
+
+func (m *Manager) readOnly() bool {
+	return m.config.Cache.Redis.Access == AccessReadOnly
+}
+
+// redisWritable reports whether Set may write to Redis
+func (m *Manager) redisWritable() bool {
+	if m.readOnly() {
+		return false
+	}
+	return !m.shared() || m.kbCurrent
+}
+
+// redisKey is the entry key for a question, per checksum when shared
+func (m *Manager) redisKey(queryHash, checksumHash string) string {
+	if m.shared() {
+		return fmt.Sprintf("eulix:query:%s:%s", queryHash, checksumHash)
+	}
+	return fmt.Sprintf("eulix:query:%s", queryHash)
+}
+
+// resolveUser names the author of new entries
+func resolveUser(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+func validateAccess(access string) error {
+	switch access {
+	case "", AccessReadWrite, AccessReadOnly:
+		return nil
+	}
+	return fmt.Errorf("invalid cache.redis access %q, use %q or %q", access, AccessReadWrite, AccessReadOnly)
+}
@@ -0,0 +1,175 @@
+package centrality
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Centrality is PageRank over the call graph, calls pointing from caller to
+// callee: a function scores high when many functions call it, or when a
+// few important ones do. Analyze stores it in kb_index.json under Key,
+// scaled so the most central function is 1.
+
+// Key is the kb_index.json field holding the scores
+const Key = "centrality"
+
+const (
+	damping    = 0.85
+	iterations = 100
+	tolerance  = 1e-9
+)
+
+// callGraph is the part of kb_call_graph.json Rank needs
+type callGraph struct {
+	Functions map[string]struct {
+		Calls    []string `json:"calls"`
+		CalledBy []string `json:"called_by"`
+	} `json:"functions"`
+}
+
+// Rank scores every function in calls, which maps a caller to its callees.
+// Callees that aren't keys are calls out of the project and ignored.
+func Rank(calls map[string][]string) map[string]float64 {
+	names := make([]string, 0, len(calls))
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	n := len(names)
+	if n == 0 {
+		return map[string]float64{}
+	}
+	index := make(map[string]int, n)
+	for i, name := range names {
+		index[name] = i
+	}
+
+	out := make([][]int, n)
+	for i, name := range names {
+		seen := make(map[int]bool)
+		for _, callee := range calls[name] {
+			if j, ok := index[callee]; ok && j != i && !seen[j] {
+				seen[j] = true
+				out[i] = append(out[i], j)
+			}
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for iter := 0; iter < iterations; iter++ {
+		// Functions that call nothing spread their rank over everything,
+		// otherwise it would leak out of the graph
+		dangling := 0.0
+		for i := range rank {
+			if len(out[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, callees := range out {
+			share := damping * rank[i] / float64(len(callees))
+			for _, j := range callees {
+				next[j] += share
+			}
+		}
+
+		delta := 0.0
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < tolerance {
+			break
+		}
+	}
+
+	top := 0.0
+	for _, r := range rank {
+		top = math.Max(top, r)
+	}
+	scores := make(map[string]float64, n)
+	for i, name := range names {
+		scores[name] = math.Round(rank[i]/top*1e4) / 1e4
+	}
+	return scores
+}
+
+// FromCallGraph ranks the functions in eulixDir's kb_call_graph.json, none
+// without one
+func FromCallGraph(eulixDir string) (map[string]float64, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb_call_graph.json"))
+	if os.IsNotExist(err) {
+		return map[string]float64{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var graph callGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return nil, fmt.Errorf("failed to parse kb_call_graph.json: %w", err)
+	}
+
+	calls := make(map[string][]string, len(graph.Functions))
+	for name, fn := range graph.Functions {
+		calls[name] = append(calls[name], fn.Calls...)
+		// called_by fills in calls the caller's side missed
+		for _, caller := range fn.CalledBy {
+			if _, ok := graph.Functions[caller]; ok {
+				calls[caller] = append(calls[caller], name)
+			}
+		}
+	}
+	return Rank(calls), nil
+}
+
+// Annotate ranks the functions in eulixDir's kb_call_graph.json and writes
+// the scores into its kb_index.json, keeping everything else there. It
+// returns how many functions were ranked, 0 without a call graph.
+func Annotate(eulixDir string) (int, error) {
+	scores, err := FromCallGraph(eulixDir)
+	if err != nil || len(scores) == 0 {
+		return 0, err
+	}
+
+	indexPath := filepath.Join(eulixDir, "kb_index.json")
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return 0, err
+	}
+	var index map[string]json.RawMessage
+	if err := json.Unmarshal(data, &index); err != nil {
+		return 0, fmt.Errorf("failed to parse kb_index.json: %w", err)
+	}
+	if index[Key], err = json.Marshal(scores); err != nil {
+		return 0, err
+	}
+	// schema_version has to stay the first key, see internal/schema
+	version, stamped := index["schema_version"]
+	delete(index, "schema_version")
+	if data, err = json.MarshalIndent(index, "", "  "); err != nil {
+		return 0, err
+	}
+	if stamped {
+		data = append([]byte(`{"schema_version": `+string(version)+","), data[1:]...)
+	}
+
+	tmp := indexPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, indexPath); err != nil {
+		return 0, err
+	}
+	return len(scores), nil
+}
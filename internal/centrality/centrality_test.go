@@ -0,0 +1,97 @@
+package centrality
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRankOrder(t *testing.T) {
+	// log is called by everyone, util by a single caller that is itself
+	// only called by main
+	calls := map[string][]string{
+		"main":    {"handler", "util"},
+		"handler": {"log"},
+		"worker":  {"log"},
+		"util":    {"log", "fmt.Println"},
+		"log":     nil,
+	}
+
+	scores := Rank(calls)
+	if len(scores) != len(calls) {
+		t.Fatalf("ranked %d functions, want %d: %v", len(scores), len(calls), scores)
+	}
+	if scores["log"] != 1 {
+		t.Errorf("log = %v, want 1 as the most central", scores["log"])
+	}
+	order := []string{"log", "util", "worker"}
+	for i := 1; i < len(order); i++ {
+		if scores[order[i-1]] <= scores[order[i]] {
+			t.Errorf("%s = %v, want above %s = %v", order[i-1], scores[order[i-1]], order[i], scores[order[i]])
+		}
+	}
+	if scores["util"] != scores["handler"] || scores["main"] != scores["worker"] {
+		t.Errorf("functions in the same position scored differently: %v", scores)
+	}
+	if _, ok := scores["fmt.Println"]; ok {
+		t.Error("a call out of the project was ranked")
+	}
+}
+
+func TestRankIgnoresRecursion(t *testing.T) {
+	plain := Rank(map[string][]string{"a": {"b"}, "b": nil})
+	recursive := Rank(map[string][]string{"a": {"a", "b", "b"}, "b": nil})
+	if plain["a"] != recursive["a"] || plain["b"] != recursive["b"] {
+		t.Errorf("self calls and repeats changed the ranking: %v vs %v", plain, recursive)
+	}
+	if len(Rank(nil)) != 0 {
+		t.Error("an empty graph ranked something")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	eulixDir := t.TempDir()
+	// Only log's called_by records the call from worker
+	graph := `{"functions": {
+		"worker": {"calls": [], "called_by": []},
+		"handler": {"calls": ["log"], "called_by": []},
+		"log": {"calls": [], "called_by": ["handler", "worker"]}
+	}}`
+	index := `{"schema_version": 2, "functions_by_name": {}}`
+	for name, content := range map[string]string{"kb_call_graph.json": graph, "kb_index.json": index} {
+		if err := os.WriteFile(filepath.Join(eulixDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := Annotate(eulixDir)
+	if err != nil || n != 3 {
+		t.Fatalf("Annotate = %d, %v, want 3 functions", n, err)
+	}
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb_index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"schema_version": 2,`) {
+		t.Errorf("schema_version isn't the first key:\n%s", data)
+	}
+	var annotated struct {
+		Centrality      map[string]float64 `json:"centrality"`
+		FunctionsByName map[string]any     `json:"functions_by_name"`
+	}
+	if err := json.Unmarshal(data, &annotated); err != nil {
+		t.Fatal(err)
+	}
+	if annotated.FunctionsByName == nil {
+		t.Error("existing index fields were dropped")
+	}
+	if c := annotated.Centrality; c["log"] != 1 || c["worker"] != c["handler"] || c["worker"] >= c["log"] {
+		t.Errorf("centrality = %v, want log first and both callers level", c)
+	}
+
+	if n, err := Annotate(t.TempDir()); n != 0 || err != nil {
+		t.Errorf("Annotate without a call graph = %d, %v, want nothing done", n, err)
+	}
+}
@@ -0,0 +1,188 @@
+package changes
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"eulix/internal/llm"
+	"eulix/internal/query"
+)
+
+// Commit is one commit in a changelog range
+type Commit struct {
+	Hash    string
+	Subject string
+	Author  string
+	Files   []string
+}
+
+// ModuleChanges is what a range changed in one module
+type ModuleChanges struct {
+	Module  string
+	Commits []Commit
+	Symbols []Symbol
+	Added   int
+	Removed int
+}
+
+// maxModuleCommits keeps one busy module from filling the prompt
+const maxModuleCommits = 60
+
+// Collect groups the commits and changes in rng, like v1.2..v1.3, by module,
+// busiest module first. A commit touching several modules is listed in each.
+func Collect(rng string, kb *query.KnowledgeBase) ([]ModuleChanges, error) {
+	commits, err := gitLog(rng)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := RangeDiff(rng)
+	if err != nil {
+		return nil, err
+	}
+	files := ParseDiff(diff)
+
+	byModule := make(map[string]*ModuleChanges)
+	module := func(name string) *ModuleChanges {
+		if byModule[name] == nil {
+			byModule[name] = &ModuleChanges{Module: name}
+		}
+		return byModule[name]
+	}
+
+	for _, c := range commits {
+		seen := make(map[string]bool)
+		for _, file := range c.Files {
+			name := Module(file)
+			if !seen[name] {
+				seen[name] = true
+				m := module(name)
+				m.Commits = append(m.Commits, c)
+			}
+		}
+	}
+	for _, f := range files {
+		m := module(Module(f.Path))
+		m.Added += f.Added
+		m.Removed += f.Removed
+	}
+	for _, s := range ChangedSymbols(kb, files) {
+		m := module(Module(s.File))
+		m.Symbols = append(m.Symbols, s)
+	}
+
+	var modules []ModuleChanges
+	for _, m := range byModule {
+		if len(m.Commits) > 0 {
+			modules = append(modules, *m)
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if len(modules[i].Commits) != len(modules[j].Commits) {
+			return len(modules[i].Commits) > len(modules[j].Commits)
+		}
+		return modules[i].Module < modules[j].Module
+	})
+	return modules, nil
+}
+
+// Changelog writes markdown with a section per module. With a client the
+// LLM summarizes each module's commits, onModule is told how many are done
+// after each. Without one the sections list the commit subjects.
+func Changelog(client *llm.Client, rng string, modules []ModuleChanges, onModule func(done, total int)) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Changes in %s\n", rng)
+
+	for i, m := range modules {
+		fmt.Fprintf(&sb, "\n## %s\n\n", m.Module)
+
+		if client == nil {
+			for _, c := range m.Commits {
+				fmt.Fprintf(&sb, "- %s (%s)\n", c.Subject, c.Hash)
+			}
+			continue
+		}
+
+		response, err := client.Complete(buildChangelogPrompt(m))
+		if err != nil {
+			return "", fmt.Errorf("LLM summary of %s failed: %w", m.Module, err)
+		}
+		bullets := 0
+		for _, line := range strings.Split(strings.TrimSpace(response), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+				sb.WriteString("- " + strings.TrimSpace(line[2:]) + "\n")
+				bullets++
+			}
+		}
+		// Nothing usable came back, the subjects are better than an empty section
+		if bullets == 0 {
+			for _, c := range m.Commits {
+				fmt.Fprintf(&sb, "- %s (%s)\n", c.Subject, c.Hash)
+			}
+		}
+		if onModule != nil {
+			onModule(i+1, len(modules))
+		}
+	}
+	return sb.String(), nil
+}
+
+func buildChangelogPrompt(m ModuleChanges) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `Summarize the changes to %s for a changelog read by users of the project.
+
+Reply with a markdown bullet list only, one bullet per user visible change, most important first. Merge commits that belong together, leave out pure refactors, test and CI changes unless nothing else changed. Each bullet is one sentence.
+
+%d commits, +%d -%d lines:
+`, m.Module, len(m.Commits), m.Added, m.Removed)
+
+	for i, c := range m.Commits {
+		if i == maxModuleCommits {
+			fmt.Fprintf(&sb, "  ... and %d more\n", len(m.Commits)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "  %s %s\n", c.Hash, c.Subject)
+	}
+
+	if len(m.Symbols) > 0 {
+		sb.WriteString("\nChanged symbols:\n")
+		for _, s := range m.Symbols {
+			fmt.Fprintf(&sb, "  %s %s", s.Kind, s.Name)
+			if doc := firstLine(s.Docstring); doc != "" {
+				fmt.Fprintf(&sb, ": %s", doc)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// gitLog lists the commits selected by args, like a range or --since, with
+// the files each touched. Merges are left out since their changes are in
+// the commits they merge.
+func gitLog(args ...string) ([]Commit, error) {
+	cmd := append([]string{"log", "--no-merges", "--name-only", "--format=%x1e%h%x1f%s%x1f%an"}, args...)
+	out, err := exec.Command("git", cmd...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s failed: %w", strings.Join(args, " "), err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		header := strings.Split(lines[0], "\x1f")
+		if len(header) != 3 {
+			continue
+		}
+		c := Commit{Hash: header[0], Subject: header[1], Author: header[2]}
+		for _, file := range lines[1:] {
+			if file = strings.TrimSpace(file); file != "" {
+				c.Files = append(c.Files, file)
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
@@ -0,0 +1,94 @@
+package changes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"eulix/internal/llm"
+	"eulix/internal/query"
+)
+
+// maxDiffBytes keeps prompts within what small local models handle
+const maxDiffBytes = 40000
+
+var subjectPattern = regexp.MustCompile(`^(feat|fix|refactor|perf|docs|test|build|ci|chore|style|revert)(\([^)]+\))?!?: \S`)
+
+// CommitMessage asks the LLM for a conventional commit message for diff,
+// with the symbols it changes from kb, which may be nil
+func CommitMessage(client *llm.Client, diff string, kb *query.KnowledgeBase) (string, error) {
+	files := ParseDiff(diff)
+	if len(files) == 0 {
+		return "", fmt.Errorf("nothing is staged, git add the changes first")
+	}
+	symbols := ChangedSymbols(kb, files)
+
+	if len(diff) > maxDiffBytes {
+		diff = diff[:maxDiffBytes] + "\n... (diff truncated)\n"
+	}
+
+	response, err := client.Complete(buildCommitPrompt(files, symbols, diff))
+	if err != nil {
+		return "", fmt.Errorf("LLM commit message failed: %w", err)
+	}
+
+	message, ok := cleanMessage(response)
+	if !ok {
+		return "", fmt.Errorf("the LLM didn't reply with a conventional commit message:\n%s", strings.TrimSpace(response))
+	}
+	return message, nil
+}
+
+func buildCommitPrompt(files []FileChange, symbols []Symbol, diff string) string {
+	scopes := make(map[string]bool)
+	var scopeList []string
+	for _, f := range files {
+		if m := Module(f.Path); !scopes[m] {
+			scopes[m] = true
+			scopeList = append(scopeList, m)
+		}
+	}
+
+	return fmt.Sprintf(`Write a git commit message for the staged changes below, following Conventional Commits.
+
+The first line is type(scope): subject
+- type is one of feat, fix, refactor, perf, docs, test, build, ci, chore, style, revert
+- scope is a short name for the part of the code changed, the modules touched are: %s
+- subject is imperative, lower case, no period, under 72 characters
+- add ! after the scope only when a changed signature with callers breaks them
+
+Then a blank line and a body of a few lines wrapped at 72 characters saying what changed and why, from the code, not a list of files. Leave the body out for trivial changes.
+For a breaking change end with a BREAKING CHANGE: footer.
+
+Reply with the commit message only.
+
+%s
+DIFF:
+%s`, strings.Join(scopeList, ", "), describe(files, symbols), diff)
+}
+
+// cleanMessage drops fences and preambles around the message, false when
+// no line looks like a conventional commit subject
+func cleanMessage(response string) (string, bool) {
+	lines := strings.Split(strings.TrimSpace(response), "\n")
+	start := -1
+	for i, line := range lines {
+		if subjectPattern.MatchString(strings.TrimSpace(line)) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+
+	var kept []string
+	for _, line := range lines[start:] {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			break
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+	kept[0] = strings.TrimSpace(kept[0])
+	return strings.TrimSpace(strings.Join(kept, "\n")), true
+}
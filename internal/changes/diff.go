@@ -0,0 +1,256 @@
+package changes
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eulix/internal/query"
+)
+
+// Commit messages and changelogs are written by the LLM from a diff. The
+// diff alone shows lines, the KB adds which functions and types those lines
+// belong to, what they are documented to do and how many callers a changed
+// signature has, which is what makes a message say more than "update
+// router.go".
+
+// FileChange is one file in a diff
+type FileChange struct {
+	Path    string
+	OldPath string // before a rename, else ""
+	Status  string // added, deleted, renamed or modified
+	Added   int
+	Removed int
+	Hunks   []Hunk
+}
+
+// Hunk is a range of changed lines in the new version of the file, context
+// lines left out. A deletion is the line after it, with Count 0.
+type Hunk struct {
+	Start int
+	Count int
+}
+
+// Symbol is a function, method or type a diff touches
+type Symbol struct {
+	Name      string
+	Kind      string // function, method or type
+	File      string
+	Line      int
+	Signature string
+	Docstring string
+	Callers   int
+}
+
+var hunkPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// StagedDiff is what `git commit` would commit
+func StagedDiff() (string, error) {
+	out, err := exec.Command("git", "diff", "--cached", "--unified=3", "-M").Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff --cached failed: %w", err)
+	}
+	return string(out), nil
+}
+
+// RangeDiff is the diff of a from..to range
+func RangeDiff(rng string) (string, error) {
+	out, err := exec.Command("git", "diff", "--unified=3", "-M", rng).Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff %s failed: %w", rng, err)
+	}
+	return string(out), nil
+}
+
+// ParseDiff reads the files and hunks of a unified git diff
+func ParseDiff(diff string) []FileChange {
+	var files []FileChange
+	var current *FileChange
+	inHunk := false
+	newLine := 0 // line in the new file the next diff line is at
+	changed := func(line, count int) {
+		if n := len(current.Hunks); n > 0 {
+			last := &current.Hunks[n-1]
+			if line <= last.Start+last.Count {
+				last.Count = max(last.Count, line+count-last.Start)
+				return
+			}
+		}
+		current.Hunks = append(current.Hunks, Hunk{Start: line, Count: count})
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, FileChange{Status: "modified"})
+			current = &files[len(files)-1]
+			inHunk = false
+			// a/x b/x, overwritten by the +++ and rename lines when present
+			if i := strings.LastIndex(line, " b/"); i >= 0 {
+				current.Path = line[i+3:]
+			}
+		case current == nil:
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+			if m := hunkPattern.FindStringSubmatch(line); m != nil {
+				newLine, _ = strconv.Atoi(m[1])
+				// A pure deletion's header names the line before it
+				if m[2] == "0" {
+					newLine++
+				}
+			}
+		case inHunk && strings.HasPrefix(line, "+"):
+			current.Added++
+			changed(newLine, 1)
+			newLine++
+		case inHunk && strings.HasPrefix(line, "-"):
+			current.Removed++
+			changed(newLine, 0)
+		case inHunk && strings.HasPrefix(line, " "):
+			newLine++
+		case inHunk:
+		case strings.HasPrefix(line, "new file mode"):
+			current.Status = "added"
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.Status = "deleted"
+		case strings.HasPrefix(line, "rename from "):
+			current.Status = "renamed"
+			current.OldPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			current.Path = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "+++ b/"):
+			current.Path = strings.TrimPrefix(line, "+++ b/")
+		case strings.HasPrefix(line, "--- a/") && current.Status == "deleted":
+			current.Path = strings.TrimPrefix(line, "--- a/")
+		}
+	}
+	return files
+}
+
+// ChangedSymbols finds the KB symbols whose lines overlap a hunk. Lines are
+// matched against the analyzed tree, so a KB older than the diff can miss
+// or misplace symbols. kb may be nil.
+func ChangedSymbols(kb *query.KnowledgeBase, files []FileChange) []Symbol {
+	if kb == nil {
+		return nil
+	}
+
+	var symbols []Symbol
+	for _, f := range files {
+		if f.Status == "deleted" {
+			continue
+		}
+		structure, ok := kb.Structure[f.Path]
+		if !ok {
+			continue
+		}
+		touched := func(start, end int) bool {
+			for _, h := range f.Hunks {
+				if h.Start <= end && h.Start+max(h.Count, 1)-1 >= start {
+					return true
+				}
+			}
+			return false
+		}
+		function := func(fn query.KBFunction, kind string) Symbol {
+			return Symbol{
+				Name:      fn.Name,
+				Kind:      kind,
+				File:      f.Path,
+				Line:      fn.LineStart,
+				Signature: fn.Signature,
+				Docstring: fn.Docstring,
+				Callers:   len(fn.CalledBy),
+			}
+		}
+
+		for _, fn := range structure.Functions {
+			if touched(fn.LineStart, fn.LineEnd) {
+				symbols = append(symbols, function(fn, "function"))
+			}
+		}
+		for _, class := range structure.Classes {
+			methods := 0
+			for _, m := range class.Methods {
+				if touched(m.LineStart, m.LineEnd) {
+					symbols = append(symbols, function(m, "method"))
+					methods++
+				}
+			}
+			// A type is only listed for changes outside its methods
+			if methods == 0 && touched(class.LineStart, class.LineEnd) {
+				symbols = append(symbols, Symbol{
+					Name:      class.Name,
+					Kind:      "type",
+					File:      f.Path,
+					Line:      class.LineStart,
+					Docstring: class.Docstring,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		if symbols[i].File != symbols[j].File {
+			return symbols[i].File < symbols[j].File
+		}
+		return symbols[i].Line < symbols[j].Line
+	})
+	return symbols
+}
+
+// Module groups a file with its neighbours: its directory, cut to two
+// levels so a deep tree doesn't make a module per folder
+func Module(file string) string {
+	dir := path.Dir(file)
+	if dir == "." {
+		return "(root)"
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, "/")
+}
+
+// describe lists changed files and symbols for a prompt
+func describe(files []FileChange, symbols []Symbol) string {
+	var sb strings.Builder
+	sb.WriteString("Changed files:\n")
+	for _, f := range files {
+		name := f.Path
+		if f.OldPath != "" {
+			name = f.OldPath + " -> " + f.Path
+		}
+		fmt.Fprintf(&sb, "  %s (%s, +%d -%d)\n", name, f.Status, f.Added, f.Removed)
+	}
+
+	if len(symbols) > 0 {
+		sb.WriteString("\nChanged symbols:\n")
+		for _, s := range symbols {
+			fmt.Fprintf(&sb, "  %s %s in %s:%d", s.Kind, s.Name, s.File, s.Line)
+			if s.Callers > 0 {
+				fmt.Fprintf(&sb, ", %d callers", s.Callers)
+			}
+			sb.WriteString("\n")
+			if s.Signature != "" {
+				fmt.Fprintf(&sb, "    signature: %s\n", s.Signature)
+			}
+			if doc := firstLine(s.Docstring); doc != "" {
+				fmt.Fprintf(&sb, "    doc: %s\n", doc)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
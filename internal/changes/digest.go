@@ -0,0 +1,334 @@
+package changes
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"eulix/internal/checksum"
+	"eulix/internal/llm"
+)
+
+// Digest is what changed in the project over a period, written to be posted
+// to a team channel. Files and symbols come from the analyze history, commits
+// from git.
+type Digest struct {
+	Since      time.Time             `json:"since"`
+	Label      string                `json:"label"` // the --since it was asked for
+	Commits    []Commit              `json:"commits"`
+	Files      []FileDelta           `json:"files"`
+	Added      []checksum.SymbolStat `json:"added"`
+	Removed    []checksum.SymbolStat `json:"removed"`
+	Complexity []ComplexityDelta     `json:"complexity"`
+	Narrative  string                `json:"narrative,omitempty"`
+	Notes      []string              `json:"notes,omitempty"` // gaps in the history
+}
+
+// FileDelta is a file added, modified or removed over the period
+type FileDelta struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// ComplexityDelta is a symbol whose complexity changed over the period
+type ComplexityDelta struct {
+	Symbol checksum.SymbolStat `json:"symbol"`
+	Before int                 `json:"before"`
+}
+
+const (
+	maxDigestSymbols    = 30 // per list in the markdown, JSON has them all
+	maxComplexityDeltas = 15
+)
+
+// ParseSince reads --since as an age like 24h, 3d or 2w, or a git ref. It
+// returns the start of the period and the git log arguments selecting its
+// commits.
+func ParseSince(since string) (time.Time, []string, error) {
+	if age, ok := parseAge(since); ok {
+		start := time.Now().Add(-age)
+		return start, []string{"--since=" + start.Format(time.RFC3339)}, nil
+	}
+
+	out, err := exec.Command("git", "log", "-1", "--format=%cI", since, "--").Output()
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("%q is neither an age like 24h or 7d nor a git ref", since)
+	}
+	start, err := time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("unexpected commit date for %s: %w", since, err)
+	}
+	return start, []string{since + "..HEAD"}, nil
+}
+
+// parseAge is time.ParseDuration plus days and weeks
+func parseAge(s string) (time.Duration, bool) {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, true
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) && n > 0 {
+			return time.Duration(n) * unit, true
+		}
+	}
+	return 0, false
+}
+
+// BuildDigest compares the last analyze with the one in effect at the start
+// of the period. Without history before then it starts from the oldest
+// record, and without any the files come from the commits.
+func BuildDigest(label string, start time.Time, logArgs []string, history []checksum.Record) *Digest {
+	d := &Digest{Since: start, Label: label}
+
+	commits, err := gitLog(logArgs...)
+	if err != nil {
+		d.Notes = append(d.Notes, "Not a git repository or git failed, no commits listed")
+	}
+	d.Commits = commits
+
+	if len(history) == 0 {
+		d.Notes = append(d.Notes, "No analyze history yet, symbols aren't compared. It starts with the next 'eulix analyze'.")
+		d.Files = commitFiles(commits)
+		return d
+	}
+
+	baseline := history[0]
+	for _, r := range history {
+		if !r.Time.After(start) {
+			baseline = r
+		}
+	}
+	current := history[len(history)-1]
+	if baseline.Time.After(start) {
+		d.Notes = append(d.Notes, fmt.Sprintf("History starts at %s, files and symbols are compared from there", baseline.Time.Local().Format("2006-01-02 15:04")))
+	}
+	if current.Time.Equal(baseline.Time) {
+		d.Notes = append(d.Notes, "No analyze since the start of the period, run 'eulix analyze' to compare files and symbols")
+		d.Files = commitFiles(commits)
+		return d
+	}
+
+	d.Files = fileDeltas(baseline.FileHashes, current.FileHashes)
+	d.Added, d.Removed, d.Complexity = symbolDeltas(baseline.Symbols, current.Symbols)
+	return d
+}
+
+func fileDeltas(before, after map[string]string) []FileDelta {
+	var files []FileDelta
+	for path, hash := range after {
+		if old, ok := before[path]; !ok {
+			files = append(files, FileDelta{Path: path, Status: "added"})
+		} else if old != hash {
+			files = append(files, FileDelta{Path: path, Status: "modified"})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			files = append(files, FileDelta{Path: path, Status: "removed"})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// commitFiles is the files the commits touched, when there's no history to
+// tell added from modified
+func commitFiles(commits []Commit) []FileDelta {
+	seen := make(map[string]bool)
+	var files []FileDelta
+	for _, c := range commits {
+		for _, f := range c.Files {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, FileDelta{Path: f, Status: "changed"})
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// symbolDeltas lists symbols only in after, only in before, and the biggest
+// complexity changes. A renamed symbol shows as removed and added.
+func symbolDeltas(before, after []checksum.SymbolStat) (added, removed []checksum.SymbolStat, complexity []ComplexityDelta) {
+	old := make(map[string]checksum.SymbolStat, len(before))
+	for _, s := range before {
+		old[s.Key()] = s
+	}
+	current := make(map[string]bool, len(after))
+	for _, s := range after {
+		current[s.Key()] = true
+		prev, ok := old[s.Key()]
+		switch {
+		case !ok:
+			added = append(added, s)
+		// 0 is a parser that doesn't measure complexity, not a change
+		case s.Complexity != prev.Complexity && s.Complexity > 0 && prev.Complexity > 0:
+			complexity = append(complexity, ComplexityDelta{Symbol: s, Before: prev.Complexity})
+		}
+	}
+	for _, s := range before {
+		if !current[s.Key()] {
+			removed = append(removed, s)
+		}
+	}
+
+	sort.SliceStable(complexity, func(i, j int) bool {
+		return abs(complexity[i].Symbol.Complexity-complexity[i].Before) > abs(complexity[j].Symbol.Complexity-complexity[j].Before)
+	})
+	if len(complexity) > maxComplexityDeltas {
+		complexity = complexity[:maxComplexityDeltas]
+	}
+	return added, removed, complexity
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Narrate has the LLM write a short summary of the digest for people who
+// didn't follow the commits
+func (d *Digest) Narrate(client *llm.Client) error {
+	if len(d.Commits) == 0 && len(d.Files) == 0 {
+		return nil
+	}
+	reply, err := client.Complete(buildDigestPrompt(d))
+	if err != nil {
+		return fmt.Errorf("LLM query failed: %w", err)
+	}
+	d.Narrative = strings.TrimSpace(reply)
+	return nil
+}
+
+func buildDigestPrompt(d *Digest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `Write a short digest of what changed in this codebase since %s, for the team
+channel. One paragraph of three to five sentences, then up to five bullets
+for changes others should know about: new APIs, removed code, functions
+that got much more complex. Plain text and "- " bullets, no headings. Don't
+list every commit, group them by what they were for.
+
+`, d.Since.Local().Format("2006-01-02 15:04"))
+
+	sb.WriteString("Commits:\n")
+	for i, c := range d.Commits {
+		if i == maxModuleCommits {
+			fmt.Fprintf(&sb, "  and %d more\n", len(d.Commits)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "  %s %s (%s)\n", c.Hash, c.Subject, c.Author)
+	}
+
+	counts := make(map[string]int)
+	for _, f := range d.Files {
+		counts[f.Status]++
+	}
+	fmt.Fprintf(&sb, "\nFiles: %d added, %d modified, %d removed, %d changed\n", counts["added"], counts["modified"], counts["removed"], counts["changed"])
+
+	writeSymbols := func(title string, symbols []checksum.SymbolStat) {
+		if len(symbols) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n%s:\n", title)
+		for i, s := range symbols {
+			if i == maxDigestSymbols {
+				fmt.Fprintf(&sb, "  and %d more\n", len(symbols)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "  %s %s in %s\n", s.Kind, s.Name, s.File)
+		}
+	}
+	writeSymbols("Added symbols", d.Added)
+	writeSymbols("Removed symbols", d.Removed)
+
+	if len(d.Complexity) > 0 {
+		sb.WriteString("\nComplexity changes:\n")
+		for _, c := range d.Complexity {
+			fmt.Fprintf(&sb, "  %s in %s: %d -> %d\n", c.Symbol.Name, c.Symbol.File, c.Before, c.Symbol.Complexity)
+		}
+	}
+	return sb.String()
+}
+
+// Markdown renders the digest, narrative first
+func (d *Digest) Markdown() string {
+	var sb strings.Builder
+	period := "since " + d.Label
+	if _, ok := parseAge(d.Label); ok {
+		period = "for the last " + d.Label
+	}
+	fmt.Fprintf(&sb, "# Digest %s (%s)\n\n", period, d.Since.Local().Format("2006-01-02 15:04"))
+	if d.Narrative != "" {
+		fmt.Fprintf(&sb, "%s\n\n", d.Narrative)
+	}
+	for _, note := range d.Notes {
+		fmt.Fprintf(&sb, "> %s\n", note)
+	}
+	if len(d.Notes) > 0 {
+		sb.WriteString("\n")
+	}
+
+	authors := make(map[string]bool)
+	for _, c := range d.Commits {
+		authors[c.Author] = true
+	}
+	fmt.Fprintf(&sb, "## Commits\n\n%d commits by %d authors\n", len(d.Commits), len(authors))
+	if len(d.Commits) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, c := range d.Commits {
+		fmt.Fprintf(&sb, "- `%s` %s (%s)\n", c.Hash, c.Subject, c.Author)
+	}
+
+	counts := make(map[string]int)
+	for _, f := range d.Files {
+		counts[f.Status]++
+	}
+	sb.WriteString("\n## Files\n\n")
+	if counts["changed"] > 0 {
+		fmt.Fprintf(&sb, "%d changed\n", counts["changed"])
+	} else {
+		fmt.Fprintf(&sb, "%d added, %d modified, %d removed\n", counts["added"], counts["modified"], counts["removed"])
+	}
+	if len(d.Files) > 0 {
+		sb.WriteString("\n")
+	}
+	for i, f := range d.Files {
+		if i == maxDigestSymbols {
+			fmt.Fprintf(&sb, "- and %d more\n", len(d.Files)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "- `%s` %s\n", f.Path, f.Status)
+	}
+
+	writeSymbols := func(title string, symbols []checksum.SymbolStat) {
+		if len(symbols) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n", title)
+		for i, s := range symbols {
+			if i == maxDigestSymbols {
+				fmt.Fprintf(&sb, "- and %d more\n", len(symbols)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "- `%s` %s, %s:%d\n", s.Name, s.Kind, s.File, s.Line)
+		}
+	}
+	writeSymbols("Added symbols", d.Added)
+	writeSymbols("Removed symbols", d.Removed)
+
+	if len(d.Complexity) > 0 {
+		sb.WriteString("\n## Complexity\n\n")
+		for _, c := range d.Complexity {
+			fmt.Fprintf(&sb, "- `%s` %s:%d, %d → %d (%+d)\n", c.Symbol.Name, c.Symbol.File, c.Symbol.Line,
+				c.Before, c.Symbol.Complexity, c.Symbol.Complexity-c.Before)
+		}
+	}
+	return sb.String()
+}
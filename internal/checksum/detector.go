@@ -8,8 +8,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
+
+	"eulix/internal/version"
 )
 
 type Checksum struct {
@@ -20,16 +23,19 @@ type Checksum struct {
 	FileHashes      map[string]string `json:"file_hashes"`
 	LastAnalyzed    time.Time         `json:"last_analyzed"`
 	AnalysisVersion string            `json:"analysis_version"`
+	Skipped         []Skipped         `json:"skipped,omitempty"`
 }
 
 type Detector struct {
 	projectPath   string
 	ignorePatterns []string
+	guard         guard
 }
 
 func HashHound(projectPath string) *Detector {
 	d := &Detector{projectPath: projectPath}
 	d.loadIgnorePatterns()
+	d.loadGuard()
 	return d
 }
 
@@ -102,8 +108,58 @@ func (d *Detector) Calculate() (*Checksum, error) {
 	fileHashes := make(map[string]string)
 	totalLines := 0
 	totalFiles := 0
+	var skipped []Skipped
+
+	err := d.walkSourceFiles(func(path, relPath string, info os.FileInfo) {
+		if reason := d.guard.skipReason(path, info); reason != "" {
+			skipped = append(skipped, Skipped{Path: relPath, Reason: reason})
+			return
+		}
+
+		// Calculate file hash
+		hash, lines, err := hashFile(path)
+		if err != nil {
+			return // Skip files we can't read
+		}
+
+		fileHashes[relPath] = hash
+		totalLines += lines
+		totalFiles++
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate project hash, in path order so the same tree always hashes
+	// the same, on any machine
+	paths := make([]string, 0, len(fileHashes))
+	for path := range fileHashes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		h.Write([]byte(fileHashes[path]))
+	}
+	projectHash := hex.EncodeToString(h.Sum(nil))
+
+	return &Checksum{
+		ProjectPath:     d.projectPath,
+		TotalFiles:      totalFiles,
+		TotalLines:      totalLines,
+		Hash:            projectHash,
+		FileHashes:      fileHashes,
+		LastAnalyzed:    time.Now(),
+		AnalysisVersion: version.Version,
+		Skipped:         skipped,
+	}, nil
+}
 
-	err := filepath.Walk(d.projectPath, func(path string, info os.FileInfo, err error) error {
+// walkSourceFiles calls fn for every source file Calculate would hash
+func (d *Detector) walkSourceFiles(fn func(path, relPath string, info os.FileInfo)) error {
+	return filepath.Walk(d.projectPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -121,46 +177,60 @@ func (d *Detector) Calculate() (*Checksum, error) {
 			return nil
 		}
 
-		// Skip non-source files
+		// Skip non-source files, docs and deployment config are indexed
+		// unless [parser] skip_docs or skip_infra, migrations and CODEOWNERS
+		// always are
 		ext := filepath.Ext(path)
-		if !isSourceFile(ext) {
+		if !isSourceFile(ext) && ext != ".sql" && filepath.Base(path) != "CODEOWNERS" && (d.guard.skipDocs || !isDocFile(ext)) && (d.guard.skipInfra || !isInfraFile(path)) {
 			return nil
 		}
 
-		// Calculate file hash
-		hash, lines, err := hashFile(path)
-		if err != nil {
-			return nil // Skip files we can't read
-		}
-
 		relPath, _ := filepath.Rel(d.projectPath, path)
-		fileHashes[relPath] = hash
-		totalLines += lines
-		totalFiles++
-
+		fn(path, relPath, info)
 		return nil
 	})
+}
 
+// StaleFiles counts files added, removed or modified since the last analyze.
+// It only compares mtimes against the stored checksum, so it is cheap enough
+// to run before every query.
+func (d *Detector) StaleFiles() (int, error) {
+	stored, err := d.Load()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	// Calculate project hash
-	h := sha256.New()
-	for _, hash := range fileHashes {
-		h.Write([]byte(hash))
+	stale := 0
+	seen := make(map[string]bool, len(stored.FileHashes))
+	skipped := make(map[string]bool, len(stored.Skipped))
+	for _, s := range stored.Skipped {
+		skipped[s.Path] = true
 	}
-	projectHash := hex.EncodeToString(h.Sum(nil))
 
-	return &Checksum{
-		ProjectPath:     d.projectPath,
-		TotalFiles:      totalFiles,
-		TotalLines:      totalLines,
-		Hash:            projectHash,
-		FileHashes:      fileHashes,
-		LastAnalyzed:    time.Now(),
-		AnalysisVersion: "0.5.3",
-	}, nil
+	err = d.walkSourceFiles(func(path, relPath string, info os.FileInfo) {
+		seen[relPath] = true
+		_, known := stored.FileHashes[relPath]
+		modified := info.ModTime().After(stored.LastAnalyzed)
+		switch {
+		case known && !modified, skipped[relPath] && !modified:
+			return
+		case !known && d.guard.skipReason(path, info) != "":
+			// analyze would skip it too, only new files are read
+			return
+		}
+		stale++
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for file := range stored.FileHashes {
+		if !seen[file] {
+			stale++
+		}
+	}
+
+	return stale, nil
 }
 
 func (d *Detector) Save(checksum *Checksum) error {
@@ -258,6 +328,25 @@ func hashFile(path string) (string, int, error) {
 	return hex.EncodeToString(h.Sum(nil)), lines, nil
 }
 
+// isDocFile matches the markdown files and notebooks the parser indexes
+func isDocFile(ext string) bool {
+	switch ext {
+	case ".md", ".markdown", ".ipynb":
+		return true
+	}
+	return false
+}
+
+// isInfraFile matches files the parser may read as deployment config
+func isInfraFile(path string) bool {
+	name := filepath.Base(path)
+	switch filepath.Ext(name) {
+	case ".yml", ".yaml", ".tf", ".dockerfile":
+		return true
+	}
+	return strings.HasPrefix(name, "Dockerfile")
+}
+
 func isSourceFile(ext string) bool {
 	sourceExts := map[string]bool{
 		".go":   true,
@@ -0,0 +1,127 @@
+package checksum
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/config"
+)
+
+// Bundles, minified assets and dumps with a source extension turn into
+// thousands of useless chunks. Files over [parser] max_file_kb or that look
+// generated are left out of the checksum, and analyze has the parser skip
+// them too, see Checksum.Skipped.
+
+// sniffBytes is how much of a file is read to tell what it is
+const sniffBytes = 64 * 1024
+
+// A line this long with lines this long on average is minified code
+const (
+	minifiedLine    = 1000
+	minifiedAverage = 300
+)
+
+// generatedMarkers in the first lines mark a file as generated by a tool
+var generatedMarkers = [][]byte{
+	[]byte("code generated"),
+	[]byte("do not edit"),
+	[]byte("@generated"),
+	[]byte("auto-generated"),
+	[]byte("autogenerated"),
+}
+
+// Skipped is a source file left out of the KB and why
+type Skipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"` // too large, binary, minified or generated
+}
+
+type guard struct {
+	maxBytes      int64 // 0 means no limit
+	skipGenerated bool
+	skipDocs      bool
+	skipInfra     bool
+}
+
+// loadGuard reads the limits from the project's eulix.toml
+func (d *Detector) loadGuard() {
+	cfg, err := config.LoadFile(filepath.Join(d.projectPath, "eulix.toml"))
+	if err != nil {
+		return
+	}
+	d.guard = guard{
+		maxBytes:      int64(cfg.Parser.MaxFileKB) * 1024,
+		skipGenerated: cfg.Parser.SkipGenerated,
+		skipDocs:      cfg.Parser.SkipDocs,
+		skipInfra:     cfg.Parser.SkipInfra,
+	}
+}
+
+// skipReason says why a file is left out, "" when it isn't
+func (g guard) skipReason(path string, info os.FileInfo) string {
+	// Only a notebook's cell sources are indexed, its outputs are often large
+	// images on one line. Markdown paragraphs are often one long line.
+	ext := filepath.Ext(path)
+	notebook := ext == ".ipynb"
+
+	if g.maxBytes > 0 && info.Size() > g.maxBytes && !notebook {
+		return fmt.Sprintf("too large, %d KB", info.Size()/1024)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sample := make([]byte, sniffBytes)
+	n, _ := io.ReadFull(f, sample)
+	sample = sample[:n]
+
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return "binary"
+	}
+	if !g.skipGenerated || notebook {
+		return ""
+	}
+	if !isDocFile(ext) && isMinified(sample) {
+		return "minified"
+	}
+	if isGenerated(sample) {
+		return "generated"
+	}
+	return ""
+}
+
+func isMinified(sample []byte) bool {
+	lines := bytes.Split(sample, []byte("\n"))
+	longest := 0
+	for _, line := range lines {
+		if len(line) > longest {
+			longest = len(line)
+		}
+	}
+	return longest > minifiedLine && len(sample)/len(lines) > minifiedAverage
+}
+
+// isGenerated looks for a generator's header in the first few lines
+func isGenerated(sample []byte) bool {
+	head := sample
+	for i, lines := 0, 0; i < len(sample); i++ {
+		if sample[i] == '\n' {
+			if lines++; lines == 5 {
+				head = sample[:i]
+				break
+			}
+		}
+	}
+	head = bytes.ToLower(head)
+	for _, marker := range generatedMarkers {
+		if bytes.Contains(head, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package checksum
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Every analyze leaves a record of the files and symbols it saw in
+// .eulix/history, so what changed between two points in time can be worked
+// out without keeping whole knowledge bases around.
+
+const (
+	historyDirName = "history"
+	maxHistory     = 30 // records kept, oldest removed first
+)
+
+// Record is the state of the project at one analyze
+type Record struct {
+	Time       time.Time         `json:"time"`
+	Commit     string            `json:"commit,omitempty"` // git HEAD, "" outside a repo
+	Hash       string            `json:"hash"`
+	FileHashes map[string]string `json:"file_hashes"`
+	Symbols    []SymbolStat      `json:"symbols"`
+}
+
+// SymbolStat is a function, method or type as a record keeps it. Methods
+// are named Type.method.
+type SymbolStat struct {
+	File       string `json:"file"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"` // function, method or type
+	Line       int    `json:"line"`
+	Lines      int    `json:"lines"`
+	Complexity int    `json:"complexity,omitempty"`
+}
+
+// Key identifies a symbol across records
+func (s SymbolStat) Key() string {
+	return s.File + ":" + s.Name
+}
+
+// kb.json as far as records need it
+type historyKB struct {
+	Structure map[string]struct {
+		Functions []historyFunction `json:"functions"`
+		Classes   []struct {
+			Name      string            `json:"name"`
+			LineStart int               `json:"line_start"`
+			LineEnd   int               `json:"line_end"`
+			Methods   []historyFunction `json:"methods"`
+		} `json:"classes"`
+	} `json:"structure"`
+}
+
+type historyFunction struct {
+	Name       string `json:"name"`
+	LineStart  int    `json:"line_start"`
+	LineEnd    int    `json:"line_end"`
+	Complexity int    `json:"complexity"`
+}
+
+// Record saves checksum and the symbols in the current kb.json as a history
+// record, dropping the oldest past maxHistory
+func (d *Detector) Record(checksum *Checksum) error {
+	eulixDir := filepath.Join(d.projectPath, ".eulix")
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return err
+	}
+	var kb historyKB
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return fmt.Errorf("failed to parse kb.json: %w", err)
+	}
+
+	record := Record{
+		Time:       checksum.LastAnalyzed,
+		Hash:       checksum.Hash,
+		FileHashes: checksum.FileHashes,
+	}
+	if out, err := exec.Command("git", "-C", d.projectPath, "rev-parse", "HEAD").Output(); err == nil {
+		record.Commit = strings.TrimSpace(string(out))
+	}
+	function := func(file, name, kind string, fn historyFunction) SymbolStat {
+		return SymbolStat{
+			File:       file,
+			Name:       name,
+			Kind:       kind,
+			Line:       fn.LineStart,
+			Lines:      fn.LineEnd - fn.LineStart + 1,
+			Complexity: fn.Complexity,
+		}
+	}
+	for file, structure := range kb.Structure {
+		for _, fn := range structure.Functions {
+			record.Symbols = append(record.Symbols, function(file, fn.Name, "function", fn))
+		}
+		for _, class := range structure.Classes {
+			record.Symbols = append(record.Symbols, SymbolStat{
+				File:  file,
+				Name:  class.Name,
+				Kind:  "type",
+				Line:  class.LineStart,
+				Lines: class.LineEnd - class.LineStart + 1,
+			})
+			for _, m := range class.Methods {
+				record.Symbols = append(record.Symbols, function(file, class.Name+"."+m.Name, "method", m))
+			}
+		}
+	}
+	sort.Slice(record.Symbols, func(i, j int) bool {
+		if record.Symbols[i].File != record.Symbols[j].File {
+			return record.Symbols[i].File < record.Symbols[j].File
+		}
+		return record.Symbols[i].Line < record.Symbols[j].Line
+	})
+
+	dir := filepath.Join(eulixDir, historyDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err = json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	name := record.Time.UTC().Format("20060102-150405") + ".json"
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return err
+	}
+
+	names, err := historyFiles(dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > maxHistory {
+		os.Remove(filepath.Join(dir, names[0]))
+		names = names[1:]
+	}
+	return nil
+}
+
+// History reads the saved records, oldest first. No history yet is an
+// empty list.
+func (d *Detector) History() ([]Record, error) {
+	dir := filepath.Join(d.projectPath, ".eulix", historyDirName)
+	names, err := historyFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to parse history/%s: %w", name, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// historyFiles lists the records in dir oldest first, their names sort by time
+func historyFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,144 @@
+package child
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"eulix/internal/fault"
+)
+
+// The parser and embedder are separate binaries. Run starts them in their
+// own process group and keeps track of them, so Ctrl+C or a SIGTERM stops
+// them together with anything they spawned instead of leaving them running
+// after eulix has exited. On Linux they also get SIGTERM when eulix dies
+// without a chance to clean up, elsewhere Reap finds them on the next run.
+
+// gracePeriod is how long a child gets to exit after SIGTERM before it is
+// killed
+const gracePeriod = 5 * time.Second
+
+// memoryCheckInterval is how often a child's memory is compared to its limit
+const memoryCheckInterval = 500 * time.Millisecond
+
+// Limits bound a child, zero means no limit
+type Limits struct {
+	Timeout  time.Duration
+	MemoryMB int // resident memory, only enforced on Linux
+}
+
+var (
+	mu      sync.Mutex
+	running = make(map[*exec.Cmd]chan struct{}) // cmd -> closed to stop it
+)
+
+// Run starts cmd and waits for it like cmd.Run. Cancelling ctx or calling
+// StopAll terminates it, Run then returns ctx's error or context.Canceled.
+// Going over a limit terminates it with an error saying which.
+func Run(ctx context.Context, cmd *exec.Cmd, limits Limits) error {
+	setGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %w", fault.ErrBinaryMissing, err)
+		}
+		return err
+	}
+	name := filepath.Base(cmd.Path)
+
+	stop := make(chan struct{})
+	mu.Lock()
+	running[cmd] = stop
+	mu.Unlock()
+	record(cmd)
+	defer func() {
+		mu.Lock()
+		delete(running, cmd)
+		mu.Unlock()
+		forget(cmd)
+	}()
+
+	var waitErr error
+	exited := make(chan struct{})
+	go func() {
+		waitErr = cmd.Wait()
+		close(exited)
+	}()
+
+	var timeout <-chan time.Time
+	if limits.Timeout > 0 {
+		timer := time.NewTimer(limits.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var overMemory <-chan int
+	if limits.MemoryMB > 0 {
+		overMemory = watchMemory(cmd.Process.Pid, limits.MemoryMB, exited)
+	}
+
+	var reason error
+	select {
+	case <-exited:
+		return waitErr
+	case <-ctx.Done():
+		reason = ctx.Err()
+	case <-stop:
+		reason = context.Canceled
+	case <-timeout:
+		reason = fmt.Errorf("%s was stopped after running for %s", name, limits.Timeout)
+	case mb := <-overMemory:
+		reason = fmt.Errorf("%s was stopped using %d MB, over the %d MB limit", name, mb, limits.MemoryMB)
+	}
+
+	terminate(cmd)
+	select {
+	case <-exited:
+	case <-time.After(gracePeriod):
+		kill(cmd)
+		<-exited
+	}
+	return reason
+}
+
+// watchMemory sends the resident memory in MB once it goes over limitMB
+func watchMemory(pid, limitMB int, exited <-chan struct{}) <-chan int {
+	over := make(chan int, 1)
+	go func() {
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exited:
+				return
+			case <-ticker.C:
+				mb, ok := residentMB(pid)
+				if !ok {
+					return
+				}
+				if mb > limitMB {
+					over <- mb
+					return
+				}
+			}
+		}
+	}()
+	return over
+}
+
+// StopAll terminates every child still running, call it before exiting.
+// The signal is sent before StopAll returns, so exiting right after doesn't
+// leave children behind.
+func StopAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	for cmd, stop := range running {
+		terminate(cmd)
+		close(stop)
+		delete(running, cmd)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package child
+
+import "syscall"
+
+// setParentDeathSignal is Linux only, elsewhere Reap cleans up after a
+// killed eulix on its next run
+func setParentDeathSignal(attr *syscall.SysProcAttr) {}
+
+// residentMB isn't implemented outside Linux, memory limits aren't enforced
+func residentMB(pid int) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package child
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setGroup is a no-op, Windows has no process groups to signal
+func setGroup(cmd *exec.Cmd) {}
+
+// terminate kills right away, there is no SIGTERM to send
+func terminate(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+func kill(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+func alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	// FindProcess opens a handle on Windows and fails for dead pids
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+func groupAlive(pgid int) bool {
+	return false
+}
+
+// stopGroup kills the process, without groups its children are out of reach
+func stopGroup(pid int) {
+	if p, err := os.FindProcess(pid); err == nil {
+		p.Kill()
+		p.Release()
+	}
+}
+
+// commandOf isn't available, so Reap can't tell a left behind child from a
+// reused pid and leaves it alone
+func commandOf(pid int) string {
+	return ""
+}
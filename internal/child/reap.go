@@ -0,0 +1,105 @@
+package child
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Every running child has a record in ~/.eulix/run until it exits. A record
+// whose eulix is gone means the child was left behind by a run that was
+// killed, Reap stops it.
+
+type entry struct {
+	PID     int       `json:"pid"`
+	Parent  int       `json:"parent"`
+	Binary  string    `json:"binary"`
+	Started time.Time `json:"started"`
+}
+
+func registryDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".eulix", "run")
+}
+
+func record(cmd *exec.Cmd) {
+	dir := registryDir()
+	if dir == "" || os.MkdirAll(dir, 0755) != nil {
+		return
+	}
+	data, err := json.Marshal(entry{
+		PID:     cmd.Process.Pid,
+		Parent:  os.Getpid(),
+		Binary:  filepath.Base(cmd.Path),
+		Started: time.Now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(dir, strconv.Itoa(cmd.Process.Pid)+".json"), data, 0644)
+}
+
+func forget(cmd *exec.Cmd) {
+	if dir := registryDir(); dir != "" {
+		os.Remove(filepath.Join(dir, strconv.Itoa(cmd.Process.Pid)+".json"))
+	}
+}
+
+// Reap stops children left running by eulix processes that no longer exist
+// and returns the names of those it stopped. A pid that now belongs to a
+// different program is left alone.
+func Reap() []string {
+	dir := registryDir()
+	if dir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil
+	}
+
+	var stopped []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var e entry
+		if json.Unmarshal(data, &e) != nil || e.PID <= 0 {
+			os.Remove(file)
+			continue
+		}
+		if alive(e.Parent) {
+			// Still being looked after
+			continue
+		}
+
+		// A zombie or an unreadable process has no command line, its group
+		// is checked below
+		command := ""
+		if alive(e.PID) {
+			command = commandOf(e.PID)
+		}
+		if command != "" {
+			if !strings.Contains(command, e.Binary) {
+				os.Remove(file)
+				continue
+			}
+			stopGroup(e.PID)
+			stopped = append(stopped, e.Binary)
+		} else if groupAlive(e.PID) {
+			// The child is gone but what it started is still running
+			stopGroup(e.PID)
+			stopped = append(stopped, e.Binary+" workers")
+		}
+		os.Remove(file)
+	}
+	return stopped
+}
@@ -0,0 +1,104 @@
+package chunkstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LegacyFiles are what eulix_embed writes: embeddings.json carries every
+// vector inline, and embeddings.bin holds the same vectors again, matched to
+// chunks only by position
+var LegacyFiles = []string{"embeddings.json", "embeddings.bin"}
+
+type legacyIndex struct {
+	Model      string        `json:"model"`
+	Dimension  int           `json:"dimension"`
+	Embeddings []legacyChunk `json:"embeddings"`
+}
+
+type legacyChunk struct {
+	ID        string    `json:"id"`
+	ChunkType string    `json:"chunk_type"`
+	Content   string    `json:"content"`
+	Embedding []float32 `json:"embedding"`
+	Metadata  Metadata  `json:"metadata"`
+}
+
+// HasLegacy reports whether eulixDir still has embeddings.json to split
+func HasLegacy(eulixDir string) bool {
+	_, err := os.Stat(filepath.Join(eulixDir, LegacyFiles[0]))
+	return err == nil
+}
+
+// Split turns embeddings.json into chunks.json and vectors.bin, then removes
+// the legacy files. IDs that eulix_embed produced more than once get the file
+// and line appended so each chunk keeps its own vector.
+func Split(eulixDir string) error {
+	data, err := os.ReadFile(filepath.Join(eulixDir, LegacyFiles[0]))
+	if err != nil {
+		return err
+	}
+
+	var legacy legacyIndex
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", LegacyFiles[0], err)
+	}
+
+	seen := make(map[string]int, len(legacy.Embeddings))
+	for _, c := range legacy.Embeddings {
+		seen[c.ID]++
+	}
+
+	store := &Store{
+		Model:     legacy.Model,
+		Dimension: legacy.Dimension,
+		Chunks:    make(map[string]*Chunk, len(legacy.Embeddings)),
+	}
+	vectors := &Vectors{
+		Dimension: legacy.Dimension,
+		ByID:      make(map[string][]float32, len(legacy.Embeddings)),
+	}
+
+	for _, c := range legacy.Embeddings {
+		id := c.ID
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s@%s:%d", c.ID, c.Metadata.FilePath, c.Metadata.LineStart)
+		}
+		if _, dup := store.Chunks[id]; dup {
+			return fmt.Errorf("duplicate chunk %s in %s", id, LegacyFiles[0])
+		}
+
+		store.Chunks[id] = &Chunk{
+			ID:        id,
+			ChunkType: c.ChunkType,
+			Content:   c.Content,
+			Metadata:  c.Metadata,
+		}
+
+		// A chunk without a vector stays searchable by keyword
+		if len(c.Embedding) > 0 {
+			vectors.ByID[id] = c.Embedding
+		}
+	}
+
+	if err := vectors.Save(eulixDir); err != nil {
+		return fmt.Errorf("failed to write %s: %w", VectorsFile, err)
+	}
+	if err := store.Save(eulixDir); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ChunksFile, err)
+	}
+
+	return RemoveLegacy(eulixDir)
+}
+
+// RemoveLegacy deletes embeddings.json and embeddings.bin from eulixDir
+func RemoveLegacy(eulixDir string) error {
+	for _, name := range LegacyFiles {
+		if err := os.Remove(filepath.Join(eulixDir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
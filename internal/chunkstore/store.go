@@ -0,0 +1,93 @@
+// Package chunkstore keeps chunk metadata and vectors in separate files that
+// both key on the chunk ID, so nothing depends on two files agreeing on order.
+//
+//	chunks.json  chunk content and metadata, keyed by ID
+//	vectors.bin  version(4) + count(8) + dim(4), then per vector
+//	             idLen(4) + id + dim float32s
+package chunkstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	ChunksFile  = "chunks.json"
+	VectorsFile = "vectors.bin"
+)
+
+type Metadata struct {
+	FilePath   string `json:"file_path"`
+	Language   string `json:"language"`
+	LineStart  int    `json:"line_start"`
+	LineEnd    int    `json:"line_end"`
+	Name       string `json:"name"`
+	Complexity int    `json:"complexity"`
+}
+
+type Chunk struct {
+	ID        string   `json:"-"` // the key in Store.Chunks
+	ChunkType string   `json:"chunk_type"`
+	Content   string   `json:"content"`
+	Metadata  Metadata `json:"metadata"`
+}
+
+type Store struct {
+	Model     string            `json:"model"`
+	Dimension int               `json:"dimension"`
+	Chunks    map[string]*Chunk `json:"chunks"`
+}
+
+// Load reads chunks.json from eulixDir
+func Load(eulixDir string) (*Store, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, ChunksFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var store Store
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ChunksFile, err)
+	}
+
+	for id, chunk := range store.Chunks {
+		chunk.ID = id
+	}
+	return &store, nil
+}
+
+// Save writes chunks.json to eulixDir
+func (s *Store) Save(eulixDir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(eulixDir, ChunksFile), data)
+}
+
+// Sorted returns the chunks ordered by ID, a stable order for callers that
+// want a slice
+func (s *Store) Sorted() []*Chunk {
+	ids := make([]string, 0, len(s.Chunks))
+	for id := range s.Chunks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	chunks := make([]*Chunk, len(ids))
+	for i, id := range ids {
+		chunks[i] = s.Chunks[id]
+	}
+	return chunks
+}
+
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
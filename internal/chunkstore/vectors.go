@@ -0,0 +1,99 @@
+package chunkstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Matches ContextIndex::save_binary in eulix_embed
+const vectorsVersion = uint32(1)
+
+// Vectors maps chunk IDs to their embedding
+type Vectors struct {
+	Dimension int
+	ByID      map[string][]float32
+}
+
+// LoadVectors reads vectors.bin from eulixDir
+func LoadVectors(eulixDir string) (*Vectors, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, VectorsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 16 { // Version(4)+Count(8)+Dim(4)
+		return nil, fmt.Errorf("invalid %s: too short (%d bytes)", VectorsFile, len(data))
+	}
+
+	version := binary.LittleEndian.Uint32(data[0:4])
+	if version != vectorsVersion {
+		return nil, fmt.Errorf("%s version mismatch: expected %d, got %d", VectorsFile, vectorsVersion, version)
+	}
+
+	count := binary.LittleEndian.Uint64(data[4:12])
+	dim := int(binary.LittleEndian.Uint32(data[12:16]))
+	offset := 16
+
+	v := &Vectors{Dimension: dim, ByID: make(map[string][]float32, count)}
+
+	for i := uint64(0); i < count; i++ {
+		if offset+4 > len(data) {
+			return nil, fmt.Errorf("unexpected EOF reading ID length at index %d", i)
+		}
+		idLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
+		offset += 4
+
+		if offset+idLen+dim*4 > len(data) {
+			return nil, fmt.Errorf("unexpected EOF reading vector at index %d", i)
+		}
+		id := string(data[offset : offset+idLen])
+		offset += idLen
+
+		vec := make([]float32, dim)
+		for j := range vec {
+			vec[j] = math.Float32frombits(binary.LittleEndian.Uint32(data[offset : offset+4]))
+			offset += 4
+		}
+		v.ByID[id] = vec
+	}
+
+	return v, nil
+}
+
+// Save writes vectors.bin to eulixDir, sorted by ID so the same KB always
+// produces the same bytes
+func (v *Vectors) Save(eulixDir string) error {
+	ids := make([]string, 0, len(v.ByID))
+	for id, vec := range v.ByID {
+		if len(vec) != v.Dimension {
+			return fmt.Errorf("vector %s has dimension %d, expected %d", id, len(vec), v.Dimension)
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint32(header[0:4], vectorsVersion)
+	binary.LittleEndian.PutUint64(header[4:12], uint64(len(ids)))
+	binary.LittleEndian.PutUint32(header[12:16], uint32(v.Dimension))
+	buf.Write(header)
+
+	word := make([]byte, 4)
+	for _, id := range ids {
+		binary.LittleEndian.PutUint32(word, uint32(len(id)))
+		buf.Write(word)
+		buf.WriteString(id)
+		for _, f := range v.ByID[id] {
+			binary.LittleEndian.PutUint32(word, math.Float32bits(f))
+			buf.Write(word)
+		}
+	}
+
+	return writeFile(filepath.Join(eulixDir, VectorsFile), buf.Bytes())
+}
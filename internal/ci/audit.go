@@ -0,0 +1,137 @@
+package ci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type auditRule struct {
+	Rule
+	pattern *regexp.Regexp
+	message string
+	secret  bool // the line holds the secret, findings only point at it
+}
+
+var securityTags = []string{"security"}
+
+var auditRules = []auditRule{
+	{
+		Rule:    Rule{ID: "EULIX-SEC001", Name: "command-execution", Description: "Shell or process execution, check that arguments are not user controlled", Severity: SeverityWarning, Tags: securityTags},
+		pattern: regexp.MustCompile(`exec\.Command(Context)?\(|subprocess\.(call|run|Popen|check_output)\(|os\.system\(|child_process|Runtime\.getRuntime\(\)\.exec`),
+		message: "process execution, make sure arguments are not user controlled",
+	},
+	{
+		Rule:    Rule{ID: "EULIX-SEC002", Name: "dynamic-eval", Description: "Dynamic code evaluation", Severity: SeverityError, Tags: securityTags},
+		pattern: regexp.MustCompile(`(^|[^\w.])eval\(|new Function\(`),
+		message: "dynamic code evaluation",
+	},
+	{
+		Rule:    Rule{ID: "EULIX-SEC003", Name: "weak-hash", Description: "MD5 or SHA1 used, both are broken for security purposes", Severity: SeverityWarning, Tags: securityTags},
+		pattern: regexp.MustCompile(`"crypto/(md5|sha1)"|hashlib\.(md5|sha1)\(|MessageDigest\.getInstance\("(MD5|SHA-?1)"\)`),
+		message: "weak hash algorithm, fine for checksums but not for security",
+	},
+	{
+		Rule:    Rule{ID: "EULIX-SEC004", Name: "tls-verification-disabled", Description: "TLS certificate verification is turned off", Severity: SeverityError, Tags: securityTags},
+		pattern: regexp.MustCompile(`InsecureSkipVerify:\s*true|verify\s*=\s*False|rejectUnauthorized:\s*false`),
+		message: "TLS certificate verification disabled",
+	},
+	{
+		Rule:    Rule{ID: "EULIX-SEC005", Name: "hardcoded-secret", Description: "Credential-looking string literal in source", Severity: SeverityError, Tags: securityTags},
+		pattern: regexp.MustCompile(`(?i)(api_?key|secret|passw(or)?d|token)\w*\s*(:=|=|:)\s*["'][^"'\s]{8,}["']`),
+		message: "possible hardcoded credential",
+		secret:  true,
+	},
+	{
+		Rule:    Rule{ID: "EULIX-SEC006", Name: "sql-string-building", Description: "SQL built with string formatting or concatenation", Severity: SeverityWarning, Tags: securityTags},
+		pattern: regexp.MustCompile(`(?i)(sprintf|format)\(\s*["'](select|insert|update|delete)\s|["'](select|insert|update|delete)\s[^"']*["']\s*\+`),
+		message: "SQL built from strings, use parameterized queries",
+	},
+}
+
+// Audit scans every file in the KB for risky patterns
+func Audit(projectRoot, eulixDir string) (*Report, error) {
+	kb, err := loadKnowledgeBase(eulixDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Check: "audit"}
+	for _, r := range auditRules {
+		report.Rules = append(report.Rules, r.Rule)
+	}
+
+	for path := range kb.Structure {
+		findings, err := auditFile(projectRoot, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// File removed since the last analyze, nothing to scan
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", path, err)
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	return report, nil
+}
+
+func auditFile(projectRoot, path string) ([]Finding, error) {
+	f, err := os.Open(filepath.Join(projectRoot, path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var findings []Finding
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		for _, r := range auditRules {
+			if !r.pattern.MatchString(line) {
+				continue
+			}
+			// Reports are uploaded and kept by CI, a secret must not end up in one
+			message := r.message
+			if !r.secret {
+				message = fmt.Sprintf("%s: %s", r.message, truncate(trimmed, 80))
+			}
+			findings = append(findings, Finding{
+				RuleID:   r.ID,
+				Severity: r.Severity,
+				File:     path,
+				Line:     lineNum,
+				Message:  message,
+			})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// truncate shortens s to n runes, ending in "..." when it was cut
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,79 @@
+package ci
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// auditProject writes files under a temp project whose KB lists kbFiles
+func auditProject(t *testing.T, files map[string]string, kbFiles ...string) (root, eulixDir string) {
+	t.Helper()
+	root = t.TempDir()
+	eulixDir = filepath.Join(root, ".eulix")
+	if err := os.MkdirAll(eulixDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var structure []string
+	for _, f := range kbFiles {
+		structure = append(structure, `"`+f+`": {}`)
+	}
+	kb := `{"structure": {` + strings.Join(structure, ", ") + `}}`
+	if err := os.WriteFile(filepath.Join(eulixDir, "kb.json"), []byte(kb), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root, eulixDir
+}
+
+func TestAuditKeepsSecretsOut(t *testing.T) {
+	root, eulixDir := auditProject(t, map[string]string{
+		"config.go": "package config\n\nvar apiKey = \"sk-live-9f8e7d6c5b4a\"\n\nfunc run() { exec.Command(\"ls\") }\n",
+	}, "config.go", "removed.go")
+
+	report, err := Audit(root, eulixDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := make(map[string]Finding)
+	for _, f := range report.Findings {
+		rules[f.RuleID] = f
+	}
+	secret, ok := rules["EULIX-SEC005"]
+	if !ok {
+		t.Fatalf("hardcoded secret not found: %+v", report.Findings)
+	}
+	if strings.Contains(secret.Message, "sk-live") || secret.Line != 3 {
+		t.Errorf("secret finding = %+v, want line 3 without the literal", secret)
+	}
+	if exec := rules["EULIX-SEC001"]; !strings.Contains(exec.Message, `exec.Command("ls")`) {
+		t.Errorf("other findings should quote their line: %q", exec.Message)
+	}
+}
+
+func TestAuditReportsUnreadableFiles(t *testing.T) {
+	root, eulixDir := auditProject(t, map[string]string{"pkg/dir.go/x.go": "package x\n"}, "pkg/dir.go")
+	if _, err := Audit(root, eulixDir); err == nil || !strings.Contains(err.Error(), "pkg/dir.go") {
+		t.Errorf("Audit over a file that can't be read = %v, want its error", err)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	s := strings.Repeat("é", 100)
+	got := truncate(s, 80)
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != 80 || !strings.HasSuffix(got, "...") {
+		t.Errorf("truncate = %q, want 77 runes and ...", got)
+	}
+	if truncate("short", 80) != "short" {
+		t.Error("a short line was changed")
+	}
+}
@@ -0,0 +1,104 @@
+package ci
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var deadCodeRules = []Rule{
+	{
+		ID:          "EULIX-DEAD001",
+		Name:        "unused-function",
+		Description: "Function is never called from anywhere in the analyzed codebase",
+		Severity:    SeverityWarning,
+		Tags:        []string{"maintainability"},
+	},
+}
+
+// DeadCodeOptions tunes how aggressive the dead code check is
+type DeadCodeOptions struct {
+	// Exported functions may be used by other modules, skip them unless asked
+	IncludeExported bool
+}
+
+// DeadCode reports functions that nothing in the KB calls. Methods are skipped
+// since they can satisfy interfaces without a direct call.
+func DeadCode(eulixDir string, opts DeadCodeOptions) (*Report, error) {
+	kb, err := loadKnowledgeBase(eulixDir)
+	if err != nil {
+		return nil, err
+	}
+
+	called := make(map[string]bool)
+	for _, file := range kb.Structure {
+		for _, fn := range file.Functions {
+			for _, call := range fn.Calls {
+				called[call.Callee] = true
+			}
+		}
+		for _, class := range file.Classes {
+			for _, method := range class.Methods {
+				for _, call := range method.Calls {
+					called[call.Callee] = true
+				}
+			}
+		}
+	}
+
+	entry := make(map[string]bool)
+	for _, ep := range kb.EntryPoints {
+		entry[ep.Function] = true
+	}
+
+	report := &Report{Check: "deadcode", Rules: deadCodeRules}
+	for path, file := range kb.Structure {
+		for _, fn := range file.Functions {
+			if called[fn.Name] || entry[fn.Name] || isImplicitlyUsed(fn.Name) {
+				continue
+			}
+			if !opts.IncludeExported && isExported(fn.Name, file.Language) {
+				continue
+			}
+
+			report.Findings = append(report.Findings, Finding{
+				RuleID:   "EULIX-DEAD001",
+				Severity: SeverityWarning,
+				File:     path,
+				Line:     fn.LineStart,
+				Message:  fmt.Sprintf("function %s is never called", fn.Name),
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// isImplicitlyUsed covers names the runtime or test tooling calls for us
+func isImplicitlyUsed(name string) bool {
+	switch name {
+	case "main", "init", "setup", "teardown", "setUp", "tearDown":
+		return true
+	}
+
+	for _, prefix := range []string{"Test", "Benchmark", "Example", "Fuzz", "test_", "__"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExported(name, language string) bool {
+	if name == "" {
+		return false
+	}
+
+	switch language {
+	case "go":
+		return unicode.IsUpper([]rune(name)[0])
+	case "python":
+		return !strings.HasPrefix(name, "_")
+	}
+	return true
+}
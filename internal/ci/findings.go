@@ -0,0 +1,126 @@
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Exit codes for ci subcommands, stable so workflows can branch on them
+const (
+	ExitOK       = 0
+	ExitFindings = 1
+	ExitError    = 2
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityNote:
+		return 1
+	}
+	return 0
+}
+
+// ParseSeverity accepts error, warning, note and never (fail on nothing)
+func ParseSeverity(s string) (Severity, error) {
+	switch Severity(s) {
+	case SeverityError, SeverityWarning, SeverityNote:
+		return Severity(s), nil
+	case "never":
+		return "", nil
+	}
+	return "", fmt.Errorf("unknown severity %q (use error, warning, note or never)", s)
+}
+
+// Rule describes a check that can produce findings
+type Rule struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Severity    Severity `json:"severity"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+type Finding struct {
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Message  string   `json:"message"`
+}
+
+// Report is the output of one ci check
+type Report struct {
+	Check    string    `json:"check"`
+	Rules    []Rule    `json:"rules"`
+	Findings []Finding `json:"findings"`
+}
+
+func (r *Report) sortFindings() {
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// ExitCode is ExitFindings if any finding is at or above failOn.
+// An empty failOn never fails.
+func (r *Report) ExitCode(failOn Severity) int {
+	if failOn == "" {
+		return ExitOK
+	}
+	for _, f := range r.Findings {
+		if f.Severity.rank() >= failOn.rank() {
+			return ExitFindings
+		}
+	}
+	return ExitOK
+}
+
+// Write renders the report as text, json or sarif
+func (r *Report) Write(w io.Writer, format string) error {
+	r.sortFindings()
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "sarif":
+		return r.writeSARIF(w)
+	case "text", "":
+		return r.writeText(w)
+	}
+	return fmt.Errorf("unknown format %q (use text, json or sarif)", format)
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	if len(r.Findings) == 0 {
+		_, err := fmt.Fprintf(w, "%s: no findings\n", r.Check)
+		return err
+	}
+
+	for _, f := range r.Findings {
+		if _, err := fmt.Fprintf(w, "%s:%d: %s [%s] %s\n", f.File, f.Line, f.Severity, f.RuleID, f.Message); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%s: %d finding(s)\n", r.Check, len(r.Findings))
+	return err
+}
@@ -0,0 +1,24 @@
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/fault"
+	"eulix/internal/query"
+)
+
+func loadKnowledgeBase(eulixDir string) (*query.KnowledgeBase, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w, run 'eulix analyze' first: %w", fault.ErrKBMissing, err)
+	}
+
+	var kb query.KnowledgeBase
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return nil, fmt.Errorf("failed to parse kb.json: %w", err)
+	}
+	return &kb, nil
+}
@@ -0,0 +1,76 @@
+package ci
+
+import "fmt"
+
+var refactorRules = []Rule{
+	{
+		ID:          "EULIX-REF001",
+		Name:        "high-complexity",
+		Description: "Cyclomatic complexity above the configured limit",
+		Severity:    SeverityWarning,
+		Tags:        []string{"maintainability"},
+	},
+	{
+		ID:          "EULIX-REF002",
+		Name:        "long-function",
+		Description: "Function body longer than the configured limit",
+		Severity:    SeverityNote,
+		Tags:        []string{"maintainability"},
+	},
+}
+
+// RefactorOptions holds the limits for refactoring candidates
+type RefactorOptions struct {
+	MaxComplexity int
+	MaxLines      int
+}
+
+// Refactor reports refactoring candidates from the complexity and size the
+// parser recorded in the KB
+func Refactor(eulixDir string, opts RefactorOptions) (*Report, error) {
+	kb, err := loadKnowledgeBase(eulixDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Check: "refactor", Rules: refactorRules}
+	for path, file := range kb.Structure {
+		for _, fn := range file.Functions {
+			report.Findings = append(report.Findings, refactorFindings(path, fn.Name, fn.LineStart, fn.LineEnd, fn.Complexity, opts)...)
+		}
+		for _, class := range file.Classes {
+			for _, m := range class.Methods {
+				name := class.Name + "." + m.Name
+				report.Findings = append(report.Findings, refactorFindings(path, name, m.LineStart, m.LineEnd, m.Complexity, opts)...)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func refactorFindings(path, name string, start, end, complexity int, opts RefactorOptions) []Finding {
+	var findings []Finding
+
+	if opts.MaxComplexity > 0 && complexity > opts.MaxComplexity {
+		findings = append(findings, Finding{
+			RuleID:   "EULIX-REF001",
+			Severity: SeverityWarning,
+			File:     path,
+			Line:     start,
+			Message:  fmt.Sprintf("%s has complexity %d (limit %d), consider splitting it", name, complexity, opts.MaxComplexity),
+		})
+	}
+
+	if lines := end - start + 1; opts.MaxLines > 0 && lines > opts.MaxLines {
+		findings = append(findings, Finding{
+			RuleID:   "EULIX-REF002",
+			Severity: SeverityNote,
+			File:     path,
+			Line:     start,
+			Message:  fmt.Sprintf("%s is %d lines long (limit %d)", name, lines, opts.MaxLines),
+		})
+	}
+
+	return findings
+}
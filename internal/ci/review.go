@@ -0,0 +1,117 @@
+package ci
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"eulix/internal/llm"
+)
+
+var reviewRules = []Rule{
+	{
+		ID:          "EULIX-REVIEW",
+		Name:        "llm-review",
+		Description: "Issue raised by the LLM while reviewing the diff",
+		Severity:    SeverityWarning,
+		Tags:        []string{"correctness"},
+	},
+}
+
+// Keep prompts within what small local models handle
+const maxDiffBytes = 60000
+
+var reviewLinePattern = regexp.MustCompile(`(?i)^\s*[-*]?\s*(error|warning|note)\s*\|\s*([^|:]+):(\d+)\s*\|\s*(.+)$`)
+
+// Review asks the LLM to review the diff against base and parses its findings
+func Review(client *llm.Client, base string) (*Report, error) {
+	diff, err := gitDiff(base)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Check: "review", Rules: reviewRules}
+	if strings.TrimSpace(diff) == "" {
+		return report, nil
+	}
+
+	if len(diff) > maxDiffBytes {
+		diff = diff[:maxDiffBytes] + "\n... (diff truncated)\n"
+	}
+
+	response, err := client.Complete(buildReviewPrompt(diff))
+	if err != nil {
+		return nil, fmt.Errorf("LLM review failed: %w", err)
+	}
+
+	changed := changedFiles(diff)
+	report.Findings = parseReview(response, changed)
+	return report, nil
+}
+
+func buildReviewPrompt(diff string) string {
+	return fmt.Sprintf(`You are reviewing a pull request. Only report real problems: bugs, security issues, data races, missing error handling, broken edge cases. Do not report style nits.
+
+Report each problem on its own line in exactly this format:
+severity|path/to/file:line|short description
+
+severity is one of error, warning, note. Use the line number in the new version of the file.
+If there are no problems, reply with NONE.
+
+DIFF:
+%s`, diff)
+}
+
+func parseReview(response string, changed map[string]bool) []Finding {
+	var findings []Finding
+
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		m := reviewLinePattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		file := strings.TrimSpace(m[2])
+		if !changed[file] {
+			// Models sometimes invent files, only keep what is in the diff
+			continue
+		}
+
+		line, _ := strconv.Atoi(m[3])
+		findings = append(findings, Finding{
+			RuleID:   "EULIX-REVIEW",
+			Severity: Severity(strings.ToLower(m[1])),
+			File:     file,
+			Line:     line,
+			Message:  strings.TrimSpace(m[4]),
+		})
+	}
+
+	return findings
+}
+
+func gitDiff(base string) (string, error) {
+	out, err := exec.Command("git", "diff", "--unified=3", base+"...HEAD").Output()
+	if err != nil {
+		// Shallow clones may not have a merge base, fall back to a plain diff
+		out, err = exec.Command("git", "diff", "--unified=3", base, "HEAD").Output()
+		if err != nil {
+			return "", fmt.Errorf("git diff against %s failed: %w", base, err)
+		}
+	}
+	return string(out), nil
+}
+
+func changedFiles(diff string) map[string]bool {
+	files := make(map[string]bool)
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++ b/") {
+			files[strings.TrimPrefix(line, "+++ b/")] = true
+		}
+	}
+	return files
+}
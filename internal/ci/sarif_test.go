@@ -0,0 +1,104 @@
+package ci
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	report := &Report{
+		Check: "audit",
+		Rules: []Rule{
+			{ID: "EULIX-SEC001", Name: "command-exec", Description: "Shell command built at runtime", Severity: SeverityWarning, Tags: []string{"security"}},
+			{ID: "EULIX-SEC005", Name: "hardcoded-secret", Description: "Secret in source", Severity: SeverityError, Tags: []string{"security"}},
+		},
+		Findings: []Finding{
+			{RuleID: "EULIX-SEC005", Severity: SeverityError, File: "internal/config.go", Line: 3, Message: "Secret in source"},
+			{RuleID: "EULIX-SEC001", Severity: SeverityWarning, File: "run.go", Line: 0, Message: "Shell command built at runtime"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, "sarif"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoded generically so the field names are checked, not our structs
+	var log struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID                   string `json:"id"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+						Properties struct {
+							SecuritySeverity string `json:"security-severity"`
+						} `json:"properties"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []sarifResultDoc `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("SARIF isn't valid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || log.Schema == "" || len(log.Runs) != 1 {
+		t.Fatalf("version %q, schema %q, %d runs; want one 2.1.0 run", log.Version, log.Schema, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "eulix" || len(run.Tool.Driver.Rules) != 2 {
+		t.Fatalf("driver = %+v, want eulix with 2 rules", run.Tool.Driver)
+	}
+	if rule := run.Tool.Driver.Rules[1]; rule.ID != "EULIX-SEC005" || rule.DefaultConfiguration.Level != "error" || rule.Properties.SecuritySeverity != "8.0" {
+		t.Errorf("rule = %+v, want EULIX-SEC005 at error with security-severity 8.0", rule)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("%d results, want 2", len(run.Results))
+	}
+
+	// Findings are sorted by file, run.go comes second
+	result := run.Results[0]
+	if result.RuleID != "EULIX-SEC005" || result.RuleIndex != 1 || result.Level != "error" || result.Message.Text == "" {
+		t.Errorf("result = %+v, want EULIX-SEC005 (rule 1) at error with a message", result)
+	}
+	if len(result.Locations) != 1 {
+		t.Fatalf("%d locations, want 1", len(result.Locations))
+	}
+	loc := result.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "internal/config.go" || loc.ArtifactLocation.URIBaseID != "%SRCROOT%" || loc.Region.StartLine != 3 {
+		t.Errorf("location = %+v, want internal/config.go under %%SRCROOT%% at line 3", loc)
+	}
+
+	// SARIF lines start at 1, a finding without one is put on the first
+	if locs := run.Results[1].Locations; len(locs) != 1 || locs[0].PhysicalLocation.Region.StartLine != 1 {
+		t.Errorf("finding without a line: locations = %+v, want startLine 1", locs)
+	}
+}
+
+// sarifResultDoc is a SARIF result as code scanning reads it
+type sarifResultDoc struct {
+	RuleID    string `json:"ruleId"`
+	RuleIndex int    `json:"ruleIndex"`
+	Level     string `json:"level"`
+	Message   struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI       string `json:"uri"`
+				URIBaseID string `json:"uriBaseId"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	} `json:"locations"`
+}
@@ -1,17 +1,19 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"eulix/internal/checksum"
+	"eulix/internal/analyzer"
 	"eulix/internal/config"
+	"eulix/internal/hooks"
 )
 
-func analyzeProject(projectPath string) error {
+func analyzeProject(projectPath string, wait time.Duration) error {
 	startTime := time.Now()
 
 	// Load config
@@ -20,64 +22,30 @@ func analyzeProject(projectPath string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	eulixDir := filepath.Join(projectPath, ".eulix")
-
-	// Calculate checksum
-	// fmt.Println("Calculating checksum...")
-	detector := checksum.HashHound(projectPath)
-	currentChecksum, err := detector.Calculate()
+	// Ctrl+C stops the parser and embedder and cleans up the staged files
+	// instead of killing eulix with them still running
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	currentChecksum, err := analyzer.Run(projectPath, cfg, analyzer.Options{
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+		Wait:    wait,
+		Context: ctx,
+	})
 	if err != nil {
-		return fmt.Errorf("checksum calculation failed: %w", err)
-	}
-	// fmt.Printf("   Found: %d files\n", currentChecksum.TotalFiles)
-	// fmt.Println()
-
-	// Runs parser
-	fmt.Println("Parsing codebase...")
-	kbPath := filepath.Join(eulixDir, "kb.json")
-
-	parserCmd := exec.Command("eulix_parser",
-		"--root", projectPath,
-		"-o", kbPath,
-		"--threads", fmt.Sprintf("%d", cfg.Parser.Threads),
-	)
-	parserCmd.Stdout = os.Stdout
-	parserCmd.Stderr = os.Stderr
-
-	if err := parserCmd.Run(); err != nil {
-		return fmt.Errorf("parser failed: %w", err)
+		return err
 	}
-	fmt.Println("✓ Parser completed")
-	fmt.Println()
-
-
-	// Generate embeddings
-	fmt.Println("Generating embeddings...")
-	embeddingsPath := filepath.Join(eulixDir)
 
-	embedCmd := exec.Command("eulix_embed",
-		"-k", kbPath,
-		"-o", embeddingsPath,
-		"-m", cfg.Embeddings.Model,
-	)
-	embedCmd.Stdout = os.Stdout
-	embedCmd.Stderr = os.Stderr
+	duration := time.Since(startTime)
 
-	if err := embedCmd.Run(); err != nil {
-		return fmt.Errorf("embedding generation failed: %w", err)
-	}
-	fmt.Println("   ✓ Embeddings completed")
-	fmt.Println()
+	hooks.Bellboy(cfg.Hooks, cfg.LLM.Privacy.LocalOnly).AfterAnalyze(projectPath, map[string]interface{}{
+		"files":            currentChecksum.TotalFiles,
+		"lines":            currentChecksum.TotalLines,
+		"checksum":         currentChecksum.Hash,
+		"duration_seconds": duration.Seconds(),
+	})
 
-	// Step 6: Save checksum
-	fmt.Println("Saving checksum...")
-	if err := detector.Save(currentChecksum); err != nil {
-		return fmt.Errorf("failed to save checksum: %w", err)
-	}
-	fmt.Println("   ✓ Checksum saved")
-	fmt.Println()
-
-	duration := time.Since(startTime)
 	fmt.Printf("Took %s\n", duration.Round(time.Second))
 	// fmt.Println("═══════════════════════════════════════")
 	fmt.Println()
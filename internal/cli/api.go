@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/api"
+	"eulix/internal/config"
+	"eulix/internal/llm"
+)
+
+func listAPI(asJSON bool) error {
+	endpoints, err := api.Endpoints(".eulix")
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if endpoints == nil {
+			endpoints = []api.Endpoint{}
+		}
+		data, err := json.MarshalIndent(endpoints, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(endpoints) == 0 {
+		fmt.Println("No HTTP endpoints found in the knowledge base.")
+		fmt.Println("Routes are detected from Flask and FastAPI style decorators.")
+		return nil
+	}
+
+	fmt.Printf("%d endpoints\n\n", len(endpoints))
+	for _, e := range endpoints {
+		fmt.Printf("%-7s %-40s %-28s %s\n", e.Method, e.Path, e.Handler, e.Source())
+	}
+	return nil
+}
+
+// writeOpenAPI writes the skeleton as JSON to output, stdout when it is ""
+// or "-". Progress goes to stderr so stdout stays valid JSON.
+func writeOpenAPI(output string, noLLM bool) error {
+	endpoints, err := api.Endpoints(".eulix")
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return fmt.Errorf("no HTTP endpoints found in the knowledge base")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	var client *llm.Client
+	if !noLLM {
+		if client, err = llm.MouthClient(cfg); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Describing %d endpoints with %s...\n", len(endpoints), cfg.LLM.Model)
+	}
+
+	title := "API"
+	if wd, err := os.Getwd(); err == nil {
+		title = filepath.Base(wd)
+	}
+
+	doc, err := api.OpenAPI(title, endpoints, ".", client, func(done, total int) {
+		fmt.Fprintf(os.Stderr, "  %d/%d\n", done, total)
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if output == "" || output == "-" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(output, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d paths)\n", output, len(doc.Paths))
+	return nil
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eulix/internal/cache"
+	"eulix/internal/checksum"
+	"eulix/internal/config"
+	"eulix/internal/daemon"
+	"eulix/internal/fault"
+	"eulix/internal/llm"
+	"eulix/internal/query"
+	"eulix/internal/schema"
+)
+
+// buildRouter sets up the same query pipeline chat uses for the project at root,
+// without any prompts. The returned cleanup func closes the router and cache.
+func buildRouter(root string, cfg *config.Config) (*query.Router, func(), error) {
+	eulixDir := filepath.Join(root, ".eulix")
+
+	if err := waitForWriters(eulixDir); err != nil {
+		return nil, nil, err
+	}
+
+	kbPath := filepath.Join(eulixDir, "kb.json")
+	if _, err := os.Stat(kbPath); os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("%w. Run 'eulix analyze' first", fault.ErrKBMissing)
+	}
+
+	if err := schema.Check(eulixDir); err != nil {
+		return nil, nil, err
+	}
+
+	if missing := checkEmbeddingsFiles(eulixDir); len(missing) > 0 {
+		return nil, nil, fmt.Errorf("%w, missing required files:\n%s\nRun 'eulix analyze' to generate them", fault.ErrKBMissing, strings.Join(missing, "\n"))
+	}
+
+	detector := checksum.HashHound(root)
+	stored, err := detector.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w, no checksum found. Run 'eulix analyze' first", fault.ErrKBMissing)
+	}
+
+	current, err := detector.Calculate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+
+	changePercent := detector.CompareChecksums(stored, current)
+	if changePercent > 0.10 {
+		fmt.Fprintf(os.Stderr, "Codebase changed %.1f%% since last analyze, answers may be stale\n", changePercent*100)
+	}
+
+	var cacheManager *cache.Manager
+	if cfg.Cache.Redis.Enabled || cfg.Cache.SQL.Enabled {
+		cacheManager, err = cache.CacheController(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cache initialization failed: %v (continuing without cache)\n", err)
+			cacheManager = nil
+		} else {
+			// Only a KB built from exactly these files answers for a shared cache
+			cacheManager.SetKBCurrent(changePercent == 0)
+			if changePercent > 0 {
+				cacheManager.InvalidateByChecksum(current.Hash)
+			}
+		}
+	}
+
+	llmClient, err := llm.MouthClient(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize LLM: %w", err)
+	}
+
+	router, err := query.QueryTrafficController(eulixDir, cfg, llmClient, cacheManager)
+	if err != nil {
+		if cacheManager != nil {
+			cacheManager.Close()
+		}
+		return nil, nil, fmt.Errorf("failed to initialize query router: %w", err)
+	}
+	router.SetCurrentChecksum(current.Hash)
+
+	cleanup := func() {
+		router.Close()
+		if cacheManager != nil {
+			cacheManager.Close()
+		}
+	}
+
+	return router, cleanup, nil
+}
+
+// staleWarning returns a one line note when files changed since the last
+// analyze, or "" when the KB is current
+func staleWarning(root string) string {
+	stale, err := checksum.HashHound(root).StaleFiles()
+	if err != nil || stale == 0 {
+		return ""
+	}
+	return fmt.Sprintf("KB is %d files stale, run 'eulix analyze' to refresh", stale)
+}
+
+// askOptions are the ask flags that change how a question is answered
+type askOptions struct {
+	Verbose bool // report search capabilities and agent tool calls on stderr
+	Agent   bool // let the model look things up over several rounds
+	Steps   int  // tool call budget in agent mode, 0 for the default
+
+	OverBudget bool // send even if the question goes over [llm.budget]
+	Confirm    bool // ask on the terminal before going over [llm.budget]
+	Full       bool // skip the index-only answer for plain lookups
+
+	MaxResponse int // answer length in tokens, 0 for [retrieval] max_response
+}
+
+// askQuestion answers a single question, going through the daemon when one is running.
+// Plain where-is and who-calls lookups are answered from the index first.
+func askQuestion(question string, opts askOptions) (*query.Answer, error) {
+	eulixDir := ".eulix"
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !opts.Agent && !opts.Full {
+		if answer, err := query.QuickAnswer(eulixDir, cfg, question); err == nil && answer != nil {
+			if opts.Verbose {
+				fmt.Fprintf(os.Stderr, "Answered from the index, no LLM\n\n")
+			}
+			return answer, nil
+		}
+	}
+
+	if client, err := daemon.Dial(eulixDir); err == nil {
+		if opts.Verbose {
+			if status, err := client.Status(); err == nil {
+				fmt.Fprintf(os.Stderr, "Using daemon (pid %d)\nSearch: %s\n\n", status.PID, status.Capabilities)
+			}
+		}
+		client.SetMaxResponse(opts.MaxResponse)
+		return confirmBudget(opts, client.WaiveBudget, func() (*query.Answer, error) {
+			if opts.Agent {
+				answer, err := client.Agent(question, opts.Steps)
+				printAgentSteps(answer, opts.Verbose)
+				return answer, err
+			}
+			return client.Answer(question)
+		})
+	}
+
+	router, cleanup, err := buildRouter(".", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Search: %s\n\n", router.Capabilities())
+	}
+	router.SetMaxResponse(opts.MaxResponse)
+
+	answer, err := confirmBudget(opts, router.WaiveBudget, func() (*query.Answer, error) {
+		if opts.Agent {
+			answer, err := router.Agent(question, opts.Steps)
+			printAgentSteps(answer, opts.Verbose)
+			return answer, err
+		}
+		return router.Answer(question)
+	})
+	for _, warning := range router.Warnings() {
+		fmt.Fprintf(os.Stderr, "[!] %s\n", warning)
+	}
+	return answer, err
+}
+
+// confirmBudget runs ask, and when the question goes over [llm.budget] asks
+// whether to send it anyway
+func confirmBudget(opts askOptions, waive func(), ask func() (*query.Answer, error)) (*query.Answer, error) {
+	if opts.OverBudget {
+		waive()
+	}
+
+	answer, err := ask()
+	var budget *query.BudgetError
+	if errors.As(err, &budget) && opts.Confirm && promptConfirm(fmt.Sprintf("This %v. Send anyway?", budget)) {
+		waive()
+		return ask()
+	}
+	return answer, err
+}
+
+// printAgentSteps lists the tool calls an agent answer was built from
+func printAgentSteps(answer *query.Answer, verbose bool) {
+	if !verbose || answer == nil {
+		return
+	}
+	for i, step := range answer.Steps {
+		mark := "✓"
+		if !step.Found {
+			mark = "✗"
+		}
+		fmt.Fprintf(os.Stderr, "%d. %s %s(%s)\n", i+1, mark, step.Tool, step.Arg)
+	}
+	if len(answer.Steps) > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// explainQuestion turns eulix explain's arguments into a question the
+// classifier sends to the file handler
+func explainQuestion(target string, question []string) (string, error) {
+	ref, err := query.ParseFileRef(target)
+	if err != nil {
+		return "", err
+	}
+	if len(question) == 0 {
+		return "explain " + ref.String(), nil
+	}
+	return fmt.Sprintf("explain %s, %s", ref, strings.Join(question, " ")), nil
+}
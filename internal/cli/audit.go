@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"eulix/internal/audit"
+	"eulix/internal/config"
+)
+
+func auditDir() (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	if cfg.LLM.Privacy.AuditDir == "" {
+		return filepath.Join(".eulix", "audit"), nil
+	}
+	return cfg.LLM.Privacy.AuditDir, nil
+}
+
+// showAuditLog prints the last limit entries, from the current log or with
+// all from rotated logs too. limit <= 0 prints everything.
+func showAuditLog(limit int, all, verbose bool) error {
+	dir, err := auditDir()
+	if err != nil {
+		return err
+	}
+
+	files := []string{filepath.Join(dir, audit.Current)}
+	if all {
+		if files, err = audit.Files(dir); err != nil {
+			return err
+		}
+	}
+
+	var entries []audit.Entry
+	for _, file := range files {
+		read, err := audit.Read(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		entries = append(entries, read...)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No prompts sent to hosted providers are recorded in %s\n", dir)
+		return nil
+	}
+
+	total := 0
+	for _, e := range entries {
+		total += e.Bytes
+	}
+	fmt.Printf("%d prompts, %.1f KB sent\n\n", len(entries), float64(total)/1024)
+
+	if limit > 0 && len(entries) > limit {
+		fmt.Printf("(showing the last %d, use -n 0 for all)\n\n", limit)
+		entries = entries[len(entries)-limit:]
+	}
+
+	for _, e := range entries {
+		target := e.Provider + "/" + e.Model
+		if e.Endpoint != "" {
+			target += " @ " + e.Endpoint
+		}
+		fmt.Printf("%s  %-32s %8d bytes  %d files  sha256:%s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), target, e.Bytes, len(e.Files), shortHash(e.SHA256))
+		if verbose {
+			for _, file := range e.Files {
+				fmt.Printf("    %s\n", file)
+			}
+		}
+	}
+	return nil
+}
+
+func rotateAuditLog() error {
+	dir, err := auditDir()
+	if err != nil {
+		return err
+	}
+
+	rotated, err := audit.Rotate(dir, time.Now())
+	if err != nil {
+		return err
+	}
+	if rotated == "" {
+		fmt.Println("Audit log is empty, nothing to rotate")
+		return nil
+	}
+	fmt.Printf("Rotated audit log to %s\n", rotated)
+	return nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+	"eulix/internal/query"
+
+	"github.com/BurntSushi/toml"
+)
+
+// batchOptions are the ask flags for --batch
+type batchOptions struct {
+	askOptions
+	Delay  time.Duration // least time between the starts of two questions
+	JSON   bool          // JSON report instead of markdown
+	Output string        // report file, stdout when "" or "-"
+}
+
+// batchQuestion is one question of a batch file. Scope keeps retrieval to
+// files under those paths.
+type batchQuestion struct {
+	Text  string   `toml:"text" json:"question"`
+	Scope []string `toml:"scope" json:"scope,omitempty"`
+}
+
+type batchResult struct {
+	batchQuestion
+	Answer  *query.Answer `json:"answer,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Code    string        `json:"code,omitempty"` // of the error, see fault.Code
+	Seconds float64       `json:"seconds"`
+}
+
+// readBatch reads questions from a .toml file of [[question]] tables, or
+// any other file as one question per line with blank lines and # comments
+// skipped
+func readBatch(path string) ([]batchQuestion, error) {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		var file struct {
+			Scope    []string        `toml:"scope"` // default for questions without one
+			Question []batchQuestion `toml:"question"`
+		}
+		meta, err := toml.DecodeFile(path, &file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if keys := meta.Undecoded(); len(keys) > 0 {
+			return nil, fmt.Errorf("%s: unknown key %s", path, keys[0])
+		}
+		for i, q := range file.Question {
+			if strings.TrimSpace(q.Text) == "" {
+				return nil, fmt.Errorf("%s: question %d has no text", path, i+1)
+			}
+			if q.Scope == nil {
+				file.Question[i].Scope = file.Scope
+			}
+		}
+		return file.Question, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var questions []batchQuestion
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		questions = append(questions, batchQuestion{Text: line})
+	}
+	return questions, scanner.Err()
+}
+
+// runBatch answers every question in path with one router, so the chunks,
+// embeddings and call graph are loaded once, and writes a report. Ctrl-C
+// stops after the question in flight and still writes what was answered.
+func runBatch(path string, opts batchOptions) error {
+	questions, err := readBatch(path)
+	if err != nil {
+		return err
+	}
+	if len(questions) == 0 {
+		return fmt.Errorf("no questions in %s", path)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	router, cleanup, err := buildRouter(".", cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := router.Warm(); err != nil {
+		return err
+	}
+	router.SetMaxResponse(opts.MaxResponse)
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Search: %s\n\n", router.Capabilities())
+	}
+
+	var stopped atomic.Bool
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		if _, ok := <-sigs; ok {
+			stopped.Store(true)
+			fmt.Fprintln(os.Stderr, "\nStopping after this question...")
+		}
+	}()
+
+	// Lookups go first and skip the delay, they don't call the LLM. The
+	// report keeps the file's order.
+	classes := make([]*query.Classification, len(questions))
+	for i, q := range questions {
+		classes[i] = router.Classify(q.Text)
+	}
+	answered := make([]*batchResult, len(questions))
+	var last time.Time
+	for n, i := range query.Schedule(classes) {
+		q := questions[i]
+		if stopped.Load() {
+			break
+		}
+		if wait := opts.Delay - time.Since(last); !last.IsZero() && wait > 0 && (classes[i].NeedsContext || opts.Agent) {
+			time.Sleep(wait)
+		}
+		last = time.Now()
+
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", n+1, len(questions), q.Text)
+		result := batchResult{batchQuestion: q}
+		if err := router.SetScope(q.Scope); err != nil {
+			return err
+		}
+		answer, err := confirmBudget(opts.askOptions, router.WaiveBudget, func() (*query.Answer, error) {
+			if opts.Agent {
+				answer, err := router.Agent(q.Text, opts.Steps)
+				printAgentSteps(answer, opts.Verbose)
+				return answer, err
+			}
+			return router.Answer(q.Text)
+		})
+		result.Seconds = time.Since(last).Seconds()
+		if err != nil {
+			result.Error = err.Error()
+			result.Code = fault.Code(err)
+			fmt.Fprintf(os.Stderr, "  failed: %v\n", err)
+		} else {
+			result.Answer = answer
+		}
+		answered[i] = &result
+	}
+	var results []batchResult
+	for _, result := range answered {
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+	for _, warning := range router.Warnings() {
+		fmt.Fprintf(os.Stderr, "[!] %s\n", warning)
+	}
+
+	text := batchMarkdown(path, results)
+	if opts.JSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(data) + "\n"
+	}
+	if opts.Output == "" || opts.Output == "-" {
+		fmt.Print(text)
+	} else {
+		if err := os.WriteFile(opts.Output, []byte(text), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s (%d of %d questions)\n", opts.Output, len(results), len(questions))
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	if len(results) > 0 && failed == len(results) {
+		return errors.New("every question failed")
+	}
+	return nil
+}
+
+// batchMarkdown renders a section per question
+func batchMarkdown(path string, results []batchResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Answers to %s\n", filepath.Base(path))
+	for i, r := range results {
+		fmt.Fprintf(&sb, "\n## %d. %s\n\n", i+1, r.Text)
+		if len(r.Scope) > 0 {
+			fmt.Fprintf(&sb, "Scope: `%s`\n\n", strings.Join(r.Scope, "`, `"))
+		}
+		if r.Error != "" {
+			fmt.Fprintf(&sb, "**Failed:** %s\n", r.Error)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s\n", strings.TrimSpace(r.Answer.String()))
+		if len(r.Answer.Sources) > 0 {
+			fmt.Fprintf(&sb, "\nSources: `%s`\n", strings.Join(r.Answer.Sources, "`, `"))
+		}
+	}
+	return sb.String()
+}
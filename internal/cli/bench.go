@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+	"eulix/internal/query"
+
+	"github.com/BurntSushi/toml"
+)
+
+// benchOptions are the flags of 'eulix bench prompts'
+type benchOptions struct {
+	Model  string // overrides [llm] model for the run
+	JSON   bool
+	Output string // report file, stdout when "" or "-"
+}
+
+// benchCase is one [[case]] of a prompt suite. A symbol must appear in the
+// answer, a file must be named in it, by its path or any tail of it.
+type benchCase struct {
+	Name        string   `toml:"name" json:"name,omitempty"`
+	Query       string   `toml:"query" json:"query"`
+	MustMention []string `toml:"must_mention" json:"must_mention,omitempty"`
+	MustCite    []string `toml:"must_cite" json:"must_cite,omitempty"`
+}
+
+// benchAssertion is the outcome of one must_mention or must_cite
+type benchAssertion struct {
+	Kind   string `json:"kind"` // "mention" or "cite"
+	Want   string `json:"want"`
+	Passed bool   `json:"passed"`
+	// InContext tells a failed cite apart: the file was retrieved but the
+	// answer didn't name it, or retrieval never found it
+	InContext bool `json:"in_context,omitempty"`
+}
+
+type benchResult struct {
+	benchCase
+	Assertions []benchAssertion `json:"assertions"`
+	Answer     string           `json:"answer,omitempty"`
+	Sources    []string         `json:"sources,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Code       string           `json:"code,omitempty"` // of the error, see fault.Code
+	Seconds    float64          `json:"seconds"`
+}
+
+func (r benchResult) passed() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, a := range r.Assertions {
+		if !a.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// benchReport is what 'eulix bench prompts' prints
+type benchReport struct {
+	Suite    string        `json:"suite"`
+	Provider string        `json:"provider"`
+	Model    string        `json:"model"`
+	Passed   int           `json:"passed"` // assertions
+	Total    int           `json:"total"`
+	Cases    []benchResult `json:"cases"`
+}
+
+// readBenchSuite reads a .toml file of [[case]] tables
+func readBenchSuite(path string) ([]benchCase, error) {
+	var file struct {
+		Case []benchCase `toml:"case"`
+	}
+	meta, err := toml.DecodeFile(path, &file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if keys := meta.Undecoded(); len(keys) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %s", path, keys[0])
+	}
+	if len(file.Case) == 0 {
+		return nil, fmt.Errorf("no [[case]] in %s", path)
+	}
+	for i, c := range file.Case {
+		if strings.TrimSpace(c.Query) == "" {
+			return nil, fmt.Errorf("%s: case %d has no query", path, i+1)
+		}
+		if len(c.MustMention)+len(c.MustCite) == 0 {
+			return nil, fmt.Errorf("%s: case %d asserts nothing, add must_mention or must_cite", path, i+1)
+		}
+	}
+	return file.Case, nil
+}
+
+// runBenchPrompts answers every case of the suite through the full pipeline
+// with caching off, so every answer is the model's own, and reports the
+// assertions. Failed assertions exit 1, a suite that can't run exits 2.
+func runBenchPrompts(path string, opts benchOptions) int {
+	cases, err := readBenchSuite(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eulix bench: %v\n", err)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eulix bench: failed to load config: %v\n", err)
+		return 2
+	}
+	if opts.Model != "" {
+		cfg.LLM.Model = opts.Model
+	}
+	cfg.Cache.Redis.Enabled = false
+	cfg.Cache.SQL.Enabled = false
+	cfg.LLM.ResponseCache.Enabled = false
+
+	router, cleanup, err := buildRouter(".", cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eulix bench: %v\n", err)
+		return 2
+	}
+	defer cleanup()
+
+	report := benchReport{Suite: path, Provider: cfg.LLM.Provider, Model: cfg.LLM.Model}
+	for i, c := range cases {
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", i+1, len(cases), c.Query)
+		start := time.Now()
+		answer, err := router.Answer(c.Query)
+		result := benchResult{benchCase: c, Seconds: time.Since(start).Seconds()}
+		if err != nil {
+			result.Error = err.Error()
+			result.Code = fault.Code(err)
+		} else {
+			result.Answer = answer.Text
+			result.Sources = answer.Sources
+			result.Assertions = checkBenchCase(c, answer)
+		}
+		for _, a := range result.Assertions {
+			report.Total++
+			if a.Passed {
+				report.Passed++
+			}
+		}
+		report.Cases = append(report.Cases, result)
+	}
+
+	text := benchText(report)
+	if opts.JSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eulix bench: %v\n", err)
+			return 2
+		}
+		text = string(data) + "\n"
+	}
+	if opts.Output == "" || opts.Output == "-" {
+		fmt.Print(text)
+	} else if err := os.WriteFile(opts.Output, []byte(text), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "eulix bench: %v\n", err)
+		return 2
+	} else {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", opts.Output)
+	}
+
+	for _, r := range report.Cases {
+		if !r.passed() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkBenchCase runs the assertions of c against an answer
+func checkBenchCase(c benchCase, answer *query.Answer) []benchAssertion {
+	var out []benchAssertion
+	for _, symbol := range c.MustMention {
+		out = append(out, benchAssertion{Kind: "mention", Want: symbol, Passed: strings.Contains(answer.Text, symbol)})
+	}
+	for _, file := range c.MustCite {
+		want := filepath.ToSlash(file)
+		a := benchAssertion{Kind: "cite", Want: file, Passed: citesFile(answer.Text, want)}
+		for _, source := range answer.Sources {
+			if source == want || strings.HasSuffix(source, "/"+want) {
+				a.InContext = true
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// citesFile reports whether text names file, or the tail of its path down
+// to the base name, like orders/orders.go for internal/orders/orders.go
+func citesFile(text, file string) bool {
+	parts := strings.Split(file, "/")
+	for i := range parts {
+		if strings.Contains(text, strings.Join(parts[i:], "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// benchText renders a line per assertion under each case
+func benchText(report benchReport) string {
+	var sb strings.Builder
+	failed := 0
+	for _, r := range report.Cases {
+		mark := "PASS"
+		if !r.passed() {
+			mark = "FAIL"
+			failed++
+		}
+		name := r.Name
+		if name == "" {
+			name = r.Query
+		}
+		fmt.Fprintf(&sb, "%s  %s (%.1fs)\n", mark, name, r.Seconds)
+		if r.Error != "" {
+			fmt.Fprintf(&sb, "      error: %s\n", r.Error)
+		}
+		for _, a := range r.Assertions {
+			status := "ok  "
+			if !a.Passed {
+				status = "miss"
+			}
+			note := ""
+			if a.Kind == "cite" && !a.Passed {
+				note = " (not in the context)"
+				if a.InContext {
+					note = " (in the context, not cited)"
+				}
+			}
+			fmt.Fprintf(&sb, "      %s must %s %s%s\n", status, a.Kind, a.Want, note)
+		}
+	}
+	fmt.Fprintf(&sb, "\n%d of %d assertions passed, %d of %d cases failed with %s %s\n",
+		report.Passed, report.Total, failed, len(report.Cases), report.Provider, report.Model)
+	return sb.String()
+}
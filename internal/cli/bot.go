@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/config"
+	"eulix/internal/integrations"
+	"eulix/internal/integrations/discord"
+	"eulix/internal/integrations/slack"
+)
+
+// runBot serves slash commands for the given platform until the process is killed
+func runBot(platform string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var cleanups []func()
+	defer func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}()
+
+	open := func(repo string) (integrations.Querier, error) {
+		repoCfg, err := repoConfig(repo, cfg)
+		if err != nil {
+			return nil, err
+		}
+		router, cleanup, err := buildRouter(repo, repoCfg)
+		if err != nil {
+			return nil, err
+		}
+		cleanups = append(cleanups, cleanup)
+		return router, nil
+	}
+
+	var handler http.Handler
+	var listen string
+
+	switch platform {
+	case "slack":
+		sc := cfg.Integrations.Slack
+		if !sc.Enabled {
+			return fmt.Errorf("slack integration is disabled, set [integrations.slack] enabled = true in eulix.toml")
+		}
+		if sc.SigningSecret == "" {
+			return fmt.Errorf("slack signing secret missing, set signing_secret or SLACK_SIGNING_SECRET")
+		}
+		bridge := integrations.Switchboard(sc.Channels, sc.DefaultRepo, open)
+		handler = slack.Messenger(sc.SigningSecret, bridge)
+		listen = sc.Listen
+
+	case "discord":
+		dc := cfg.Integrations.Discord
+		if !dc.Enabled {
+			return fmt.Errorf("discord integration is disabled, set [integrations.discord] enabled = true in eulix.toml")
+		}
+		bridge := integrations.Switchboard(dc.Channels, dc.DefaultRepo, open)
+		bot, err := discord.Messenger(dc.PublicKey, bridge)
+		if err != nil {
+			return err
+		}
+		handler = bot
+		listen = dc.Listen
+
+	default:
+		return fmt.Errorf("unknown platform %q (use slack or discord)", platform)
+	}
+
+	if listen == "" {
+		listen = ":3000"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/eulix", handler)
+
+	fmt.Printf("%s bot listening on %s/eulix\n", platform, listen)
+	return http.ListenAndServe(listen, mux)
+}
+
+// repoConfig is the config a channel's repo is answered with: its own
+// eulix.toml when it has one, else the bot's. Either way the cache, audit
+// log and cassettes are the repo's own, so repos never share answers.
+func repoConfig(repo string, cfg *config.Config) (*config.Config, error) {
+	repoCfg := *cfg
+	path := filepath.Join(repo, "eulix.toml")
+	if _, err := os.Stat(path); err == nil {
+		loaded, err := config.LoadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config for %s: %w", repo, err)
+		}
+		repoCfg = *loaded
+	}
+	repoCfg.InRepo(repo)
+	return &repoCfg, nil
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"eulix/internal/config"
+)
+
+func TestRepoConfig(t *testing.T) {
+	bot := &config.Config{}
+	bot.LLM.Model = "bot-model"
+
+	shop, blog := t.TempDir(), t.TempDir()
+	toml := "[llm]\nmodel = \"shop-model\"\n[cache.sql]\nenabled = true\ndsn = \"answers.db\"\n"
+	if err := os.WriteFile(filepath.Join(shop, "eulix.toml"), []byte(toml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	shopCfg, err := repoConfig(shop, bot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blogCfg, err := repoConfig(blog, bot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shopCfg.LLM.Model != "shop-model" || blogCfg.LLM.Model != "bot-model" {
+		t.Errorf("models %q and %q, want the repo's own and the bot's", shopCfg.LLM.Model, blogCfg.LLM.Model)
+	}
+	if want := filepath.Join(shop, "answers.db"); shopCfg.Cache.SQL.DSN != want {
+		t.Errorf("shop cache at %s, want %s", shopCfg.Cache.SQL.DSN, want)
+	}
+	if want := filepath.Join(blog, config.DefaultCacheDSN); blogCfg.Cache.SQL.DSN != want {
+		t.Errorf("blog cache at %s, want %s", blogCfg.Cache.SQL.DSN, want)
+	}
+	if want := filepath.Join(blog, config.DefaultAuditDir); blogCfg.LLM.Privacy.AuditDir != want {
+		t.Errorf("blog audit log in %s, want %s", blogCfg.LLM.Privacy.AuditDir, want)
+	}
+	if bot.Cache.SQL.DSN != "" || bot.LLM.Privacy.AuditDir != "" {
+		t.Errorf("the bot's own config was changed: %+v", bot.Cache.SQL)
+	}
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"eulix/internal/query"
+)
+
+// printCentral lists the top most central functions in the call graph
+func printCentral(top int, asJSON bool) error {
+	ranked, err := query.Central(".eulix", top)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(ranked, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	width := len("Function")
+	for _, f := range ranked {
+		width = max(width, len(f.Name))
+	}
+	fmt.Printf("  #  %-*s  Score   Callers  Callees  Location\n", width, "Function")
+	for i, f := range ranked {
+		fmt.Printf("%3d  %-*s  %.3f  %7d  %7d  %s\n", i+1, width, f.Name, f.Score, f.Callers, f.Callees, f.Location)
+	}
+	return nil
+}
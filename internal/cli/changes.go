@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eulix/internal/changes"
+	"eulix/internal/checksum"
+	"eulix/internal/config"
+	"eulix/internal/llm"
+	"eulix/internal/query"
+)
+
+// writeCommitMessage prints a message for the staged changes, so it can be
+// used as git commit -m "$(eulix commitmsg)". Progress goes to stderr.
+func writeCommitMessage() error {
+	diff, err := changes.StagedDiff()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	client, err := llm.MouthClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Writing commit message with %s...\n", cfg.LLM.Model)
+	message, err := changes.CommitMessage(client, diff, changedSymbolsKB())
+	if err != nil {
+		return err
+	}
+	fmt.Println(message)
+	return nil
+}
+
+// writeChangelog writes markdown for rng to output, stdout when it is "" or
+// "-". A single ref means from there to HEAD.
+func writeChangelog(rng, output string, noLLM bool) error {
+	if !strings.Contains(rng, "..") {
+		rng += "..HEAD"
+	}
+
+	modules, err := changes.Collect(rng, changedSymbolsKB())
+	if err != nil {
+		return err
+	}
+	if len(modules) == 0 {
+		return fmt.Errorf("no commits in %s", rng)
+	}
+
+	var client *llm.Client
+	if !noLLM {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if client, err = llm.MouthClient(cfg); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Summarizing %d modules with %s...\n", len(modules), cfg.LLM.Model)
+	}
+
+	text, err := changes.Changelog(client, rng, modules, func(done, total int) {
+		fmt.Fprintf(os.Stderr, "  %d/%d\n", done, total)
+	})
+	if err != nil {
+		return err
+	}
+
+	if output == "" || output == "-" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(output, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d modules)\n", output, len(modules))
+	return nil
+}
+
+// writeDigest writes what changed since an age or ref to output, stdout
+// when it is "" or "-"
+func writeDigest(since, format, output string, noLLM bool) error {
+	if format != "markdown" && format != "json" {
+		return fmt.Errorf("unknown format %q, use markdown or json", format)
+	}
+	start, logArgs, err := changes.ParseSince(since)
+	if err != nil {
+		return err
+	}
+	history, err := checksum.HashHound(".").History()
+	if err != nil {
+		return err
+	}
+
+	digest := changes.BuildDigest(since, start, logArgs, history)
+	if !noLLM {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		client, err := llm.MouthClient(cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Writing digest with %s...\n", cfg.LLM.Model)
+		if err := digest.Narrate(client); err != nil {
+			return err
+		}
+	}
+
+	text := digest.Markdown()
+	if format == "json" {
+		data, err := json.MarshalIndent(digest, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(data) + "\n"
+	}
+	if output == "" || output == "-" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(output, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d commits, %d files)\n", output, len(digest.Commits), len(digest.Files))
+	return nil
+}
+
+// changedSymbolsKB is kb.json when there is one. Without it messages are
+// written from the diff alone, which is worth a note but not failing.
+func changedSymbolsKB() *query.KnowledgeBase {
+	data, err := os.ReadFile(filepath.Join(".eulix", "kb.json"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "No knowledge base, using the diff only. Run 'eulix analyze' for symbol context.")
+		return nil
+	}
+	var kb query.KnowledgeBase
+	if err := json.Unmarshal(data, &kb); err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring kb.json: %v\n", err)
+		return nil
+	}
+	return &kb
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,9 +9,14 @@ import (
 
 	"eulix/internal/cache"
 	"eulix/internal/checksum"
+	"eulix/internal/child"
 	"eulix/internal/config"
+	"eulix/internal/crash"
+	"eulix/internal/daemon"
+	"eulix/internal/fault"
 	"eulix/internal/llm"
 	"eulix/internal/query"
+	"eulix/internal/schema"
 	"eulix/internal/tui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -37,15 +43,15 @@ func promptConfirm(question string) bool {
 	return response == "y" || response == "yes"
 }
 
-// checkEmbeddingsFiles verifies all required files exist
+// checkEmbeddingsFiles verifies all required files exist. chunks.json,
+// vectors.bin and kb_call_graph.json are optional, the router reports what
+// it can't do without them.
 func checkEmbeddingsFiles(eulixDir string) []string {
 	var missing []string
 
 	requiredFiles := map[string]string{
-		"kb.json":            "Knowledge base",
-		"kb_index.json":      "KB index",
-		"kb_call_graph.json": "Call graph",
-		"embeddings.bin":     "Embeddings (binary)",
+		"kb.json":       "Knowledge base",
+		"kb_index.json": "KB index",
 	}
 
 	for file, desc := range requiredFiles {
@@ -67,9 +73,18 @@ func startChat() error {
 
 	// Check KB files
 	eulixDir := ".eulix"
+	if err := waitForWriters(eulixDir); err != nil {
+		return err
+	}
+
 	kbPath := filepath.Join(eulixDir, "kb.json")
 	if _, err := os.Stat(kbPath); os.IsNotExist(err) {
-		return fmt.Errorf("knowledge base not found. Run 'eulix analyze' first")
+		return fmt.Errorf("%w. Run 'eulix analyze' first", fault.ErrKBMissing)
+	}
+
+	// An old layout is missing files on purpose, point at migrate before listing them
+	if err := schema.Check(eulixDir); err != nil {
+		return err
 	}
 
 	// Check for all required files
@@ -90,6 +105,14 @@ func startChat() error {
 		return fmt.Errorf("missing required files")
 	}
 
+	// A running daemon already holds the KB warm, skip the local cold start
+	if client, err := daemon.Dial(eulixDir); err == nil {
+		fmt.Println("Using running daemon for queries")
+		fmt.Println()
+		model := tui.MainModel(nil, cfg, nil).WithDaemon(client)
+		return runChat(model, eulixDir, func() { client.Interrupt() })
+	}
+
 	// Validate checksum
 	detector := checksum.HashHound(".")
 	stored, err := detector.Load()
@@ -134,6 +157,9 @@ func startChat() error {
 		} else {
 			defer cacheManager.Close()
 
+			// Only a KB built from exactly these files answers for a shared cache
+			cacheManager.SetKBCurrent(changePercent == 0)
+
 			// Clean expired entries on startup
 			if err := cacheManager.CleanExpired(); err != nil {
 				fmt.Printf("Failed to clean expired cache: %v\n", err)
@@ -182,14 +208,34 @@ func startChat() error {
 	fmt.Println("Starting chat interface...")
 	fmt.Println()
 
-	model := tui.MainModel(router, cfg, cacheManager)
+	return runChat(tui.MainModel(router, cfg, cacheManager), eulixDir, router.Interrupt)
+}
+
+// runChat runs the chat TUI until it quits, interrupt stops whatever answer
+// is still in flight
+func runChat(model tui.Model, eulixDir string, interrupt func()) error {
 	p := tea.NewProgram(
-		model,
+		crash.Guard(model),
 		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
+		tea.WithoutCatchPanics(),
 	)
 
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+
+	// Quitting mid-answer stops the answer and anything it started before
+	// the cache and router are closed
+	interrupt()
+	child.StopAll()
+	if chat, ok := crash.Unwrap(final).(tui.Model); ok {
+		if path, err := chat.SaveTranscript(eulixDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save transcript: %v\n", err)
+		} else if path != "" {
+			fmt.Printf("Conversation saved to %s\n", path)
+		}
+	}
+
+	// Ctrl+C outside raw mode or a SIGINT ends the chat like /quit
+	if err != nil && !errors.Is(err, tea.ErrInterrupted) {
 		return fmt.Errorf("TUI error: %w", err)
 	}
 
@@ -208,7 +254,7 @@ func printSystemDiagnostics(eulixDir string) {
 	}
 
 	// Check embeddings file size
-	embPath := filepath.Join(eulixDir, "embeddings.bin")
+	embPath := filepath.Join(eulixDir, "vectors.bin")
 	if info, err := os.Stat(embPath); err == nil {
 		sizeMB := float64(info.Size()) / (1024 * 1024)
 		fmt.Printf("Embeddings file: %.2f MB\n", sizeMB)
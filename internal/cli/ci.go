@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"eulix/internal/ci"
+	"eulix/internal/config"
+	"eulix/internal/llm"
+
+	"github.com/spf13/cobra"
+)
+
+// runCICheck runs one ci check and returns the process exit code.
+// Nothing here prompts or assumes a TTY.
+func runCICheck(cmd *cobra.Command, check string) int {
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	failOnFlag, _ := cmd.Flags().GetString("fail-on")
+
+	failOn, err := ci.ParseSeverity(failOnFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eulix ci: %v\n", err)
+		return ci.ExitError
+	}
+
+	eulixDir := ".eulix"
+
+	var report *ci.Report
+	switch check {
+	case "deadcode":
+		includeExported, _ := cmd.Flags().GetBool("include-exported")
+		report, err = ci.DeadCode(eulixDir, ci.DeadCodeOptions{IncludeExported: includeExported})
+
+	case "audit":
+		report, err = ci.Audit(".", eulixDir)
+
+	case "refactor":
+		maxComplexity, _ := cmd.Flags().GetInt("max-complexity")
+		maxLines, _ := cmd.Flags().GetInt("max-lines")
+		report, err = ci.Refactor(eulixDir, ci.RefactorOptions{MaxComplexity: maxComplexity, MaxLines: maxLines})
+
+	case "review":
+		base, _ := cmd.Flags().GetString("base")
+		if base == "" {
+			base = defaultReviewBase()
+		}
+
+		var cfg *config.Config
+		cfg, err = config.Load()
+		if err == nil {
+			var client *llm.Client
+			client, err = llm.MouthClient(cfg)
+			if err == nil {
+				report, err = ci.Review(client, base)
+			}
+		}
+
+	default:
+		err = fmt.Errorf("unknown check %q", check)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eulix ci %s: %v\n", check, err)
+		return ci.ExitError
+	}
+
+	out := os.Stdout
+	if output != "" && output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eulix ci: failed to create %s: %v\n", output, err)
+			return ci.ExitError
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := report.Write(out, format); err != nil {
+		fmt.Fprintf(os.Stderr, "eulix ci: %v\n", err)
+		return ci.ExitError
+	}
+
+	return report.ExitCode(failOn)
+}
+
+// defaultReviewBase picks the PR base branch on GitHub Actions, the previous
+// commit anywhere else
+func defaultReviewBase() string {
+	if ref := os.Getenv("GITHUB_BASE_REF"); ref != "" {
+		return "origin/" + ref
+	}
+	return "HEAD~1"
+}
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,69 +10,163 @@ import (
 	"time"
 
 	"eulix/internal/cache"
+	"eulix/internal/child"
 	"eulix/internal/config"
+	"eulix/internal/crash"
+	"eulix/internal/fault"
 	"eulix/internal/fixers"
+	"eulix/internal/installer"
+	"eulix/internal/lock"
+	"eulix/internal/query"
+	"eulix/internal/telemetry"
 	"eulix/internal/tui"
+	"eulix/internal/version"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "eulix",
-	Short: "Eulix - AI-powered code assistant",
-	Long:  `Eulix is an intelligent CLI tool for understanding and querying your codebase.`,
-	CompletionOptions: cobra.CompletionOptions{
-		DisableDefaultCmd: true,
+	Use:     "eulix",
+	Short:   "Eulix - AI-powered code assistant",
+	Long: `Eulix is an intelligent CLI tool for understanding and querying your codebase.
+
+Exit codes:
+  0   success
+  1   failed
+  2   invalid config
+  3   not initialized, or the knowledge base is missing or out of date
+  4   the LLM provider is unavailable
+  5   eulix_parser or eulix_embed not found
+  64  bad flags or arguments
+  70  eulix crashed
+
+eulix ci and eulix bench have their own, see their help. With --json or
+--format json a failure is written to stderr as
+{"error": ..., "code": ..., "exit_code": ...}, --quiet writes nothing.`,
+	Version: version.Version,
+	// Errors go through reportFailure, in the format the command asked for
+	SilenceErrors: true,
+	// --local-only and --cassette go through the environment so every
+	// config.Load sees them, including the daemon and hooks started from here
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if completing(cmd) {
+			return
+		}
+		setFailureFormat(cmd)
+		// Flags and arguments parsed fine, a failure from here on isn't
+		// a usage problem
+		cmd.SilenceUsage = true
+		if localOnly, _ := cmd.Flags().GetBool("local-only"); localOnly {
+			os.Setenv("EULIX_LOCAL_ONLY", "1")
+		}
+		if cassette, _ := cmd.Flags().GetString("cassette"); cassette != "" {
+			os.Setenv("EULIX_CASSETTE", cassette)
+		}
+		telemetry.Start(cmd.CommandPath())
+
+		// A killed eulix can leave eulix_embed or eulix_parser behind
+		if stopped := child.Reap(); len(stopped) > 0 {
+			fmt.Fprintf(os.Stderr, "Stopped what an earlier eulix left running: %s\n", strings.Join(stopped, ", "))
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		telemetry.Finish("")
+		if cmd.Name() != "upgrade" && !completing(cmd) && failureFormat == failureText && term.IsTerminal(os.Stderr.Fd()) {
+			if notice := installer.UpdateNotice(version.Version); notice != "" {
+				fmt.Fprintf(os.Stderr, "\n%s\n", notice)
+			}
+		}
 	},
 }
 
 var analyzeCmd = &cobra.Command{
 	Use:   "analyze",
 	Short: "Analyze codebase and generate knowledge base",
+	Example: `  eulix analyze
+  # Wait for an analyze started elsewhere instead of failing
+  eulix analyze --wait 5m`,
 	Args:  cobra.NoArgs,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := analyzeProject("."); err != nil {
-			fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
-			os.Exit(1)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wait, _ := cmd.Flags().GetDuration("wait")
+		if err := analyzeProject(".", wait); err != nil {
+			return failed("Analysis failed", err)
 		}
+		return nil
 	},
 }
 
 var configCmd = &cobra.Command{
-	Use:   "config",
-	Short: "Manage eulix configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Configuration management coming soon!")
+	Use:               "config [key]",
+	Short:             "Show the effective configuration, or one key of it",
+	Long:              "Show eulix.toml with defaults and environment overrides applied. Keys are dotted TOML paths like llm.model, credentials are masked.",
+	Example: `  eulix config
+  eulix config llm.model
+  eulix config cache.sql.driver`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeConfigKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := ""
+		if len(args) > 0 {
+			key = args[0]
+		}
+		if err := showConfig(key); err != nil {
+			return failed("Config failed", err)
+		}
+		return nil
 	},
 }
 
 var glaDOSCmd = &cobra.Command{
 	Use:   "glados [directory]",
 	Short: "Checks for errors in knowledge base and embeddings size",
+	Example: `  eulix glados
+  eulix glados path/to/.eulix`,
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		eulixDir := ".eulix"
 		if len(args) > 0 {
 			eulixDir = args[0]
 		}
 
 		if err := fixers.GLaDOS(eulixDir); err != nil {
-			fmt.Fprintf(os.Stderr, "holy [moooo]... Even Doctor failed\n")
-			os.Exit(1)
+			return failed("holy [moooo]... Even Doctor failed", err)
 		}
+		return nil
+	},
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show a dashboard of the knowledge base",
+	Long:  "Sum up the knowledge base: code size per language, chunk types, embedding coverage, index sizes and the cache hit rate. For finding problems use 'eulix glados'.",
+	Example: `  eulix stats
+  eulix stats --json | jq .languages`,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if err := showStats(asJSON); err != nil {
+			return failed("Stats failed", err)
+		}
+		return nil
 	},
 }
 
 var aspirineCmd = &cobra.Command{
 	Use:   "aspirine [directory]",
-	Short: "tries to fix embedings.bin and kb MEANT TO BE USED IN TEST",
-	Long:  "Tries to fixes corrupted or mismatched embeddings by rebuilding the binary file from JSON",
+	Short: "tries to fix vectors.bin and kb MEANT TO BE USED IN TEST",
+	Long:  "Tries to fix mismatched embeddings by splitting an old embeddings.json and dropping vectors whose chunk is gone",
+	Example: `  eulix aspirine
+  eulix aspirine --no-backup --force`,
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		eulixDir := ".eulix"
 		if len(args) > 0 {
 			eulixDir = args[0]
@@ -84,36 +180,947 @@ var aspirineCmd = &cobra.Command{
 			Force:    force,
 		}
 
+		l, err := lock.Acquire(eulixDir, "aspirine")
+		if err != nil {
+			return failed("Failed to rebuild embeddings", err)
+		}
+		defer l.Release()
+
 		if err := fixers.Aspirine(eulixDir, opts); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to rebuild embeddings: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to rebuild embeddings", err)
 		}
+		return nil
 	},
 }
 
 var chatCmd = &cobra.Command{
 	Use:   "chat",
 	Short: "Start interactive chat interface",
+	Example: `  eulix chat`,
 	Args:  cobra.NoArgs,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := startChat(); err != nil {
-			fmt.Fprintf(os.Stderr, "Chat failed: %v\n", err)
-			os.Exit(1)
+			return failed("Chat failed", err)
+		}
+		return nil
+	},
+}
+
+var askCmd = &cobra.Command{
+	Use:   "ask <question>",
+	Short: "Ask a single question about the codebase",
+	Long: `Answer one question and exit. Uses the daemon if one is running, otherwise loads the knowledge base in-process.
+
+Where-is and who-calls questions naming one known symbol are answered from the index without loading the rest or calling the LLM, --full skips that.
+
+With --agent the model looks up symbols, source and callers itself over several rounds instead of getting one context window, slower but better on large codebases.
+
+With --batch questions come from a file, one per line, and the answers are written as a report. A .toml file can give each question a scope, the paths retrieval keeps to:
+
+  scope = ["internal"]      # default for every question
+
+  [[question]]
+  text = "How are answers cached?"
+  scope = ["internal/cache"]`,
+	Example: `  eulix ask "where is ParseConfig defined"
+  eulix ask "who calls CreateOrder" --json
+  eulix ask --max-response 4000 "walk me through the request lifecycle"
+  eulix ask --agent --steps 12 "how does a request reach the database"
+  eulix ask --batch questions.toml -o report.json`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if batch, _ := cmd.Flags().GetString("batch"); batch != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		agent, _ := cmd.Flags().GetBool("agent")
+		steps, _ := cmd.Flags().GetInt("steps")
+		overBudget, _ := cmd.Flags().GetBool("over-budget")
+		full, _ := cmd.Flags().GetBool("full")
+		maxResponse, _ := cmd.Flags().GetInt("max-response")
+		if maxResponse < 0 {
+			return failed("Query failed", fmt.Errorf("--max-response must be positive, got %d", maxResponse))
+		}
+
+		if batch, _ := cmd.Flags().GetString("batch"); batch != "" {
+			opts := batchOptions{
+				askOptions: askOptions{Verbose: verbose, Agent: agent, Steps: steps, OverBudget: overBudget, MaxResponse: maxResponse},
+				JSON:       asJSON,
+			}
+			opts.Delay, _ = cmd.Flags().GetDuration("delay")
+			opts.Output, _ = cmd.Flags().GetString("output")
+			if err := runBatch(batch, opts); err != nil {
+				return failed("Batch failed", err)
+			}
+			return nil
+		}
+
+		answer, err := askQuestion(strings.Join(args, " "), askOptions{
+			Verbose:    verbose,
+			Agent:      agent,
+			Steps:      steps,
+			OverBudget: overBudget,
+			Confirm:    !asJSON,
+			Full:       full,
+
+			MaxResponse: maxResponse,
+		})
+		if err != nil {
+			return failed("Query failed", err)
+		}
+
+		if asJSON {
+			out, _ := json.MarshalIndent(answer, "", "  ")
+			fmt.Println(string(out))
+		} else {
+			fmt.Println(answer)
+		}
+
+		if warning := staleWarning("."); warning != "" {
+			fmt.Fprintf(os.Stderr, "\n%s\n", warning)
+		}
+		return nil
+	},
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <file[:start-end]> [question]",
+	Short: "Explain a file, or a range of its lines",
+	Long: `Explain a whole file, or just the lines given, from the chunks covering them rather than from search.
+
+Lines are given as file.go:120-180, file.go:120 or file.go#L120-L180 as in a review link. A file name alone is enough when only one file in the knowledge base has it. Anything after the file is asked about those lines instead of a general explanation.`,
+	Example: `  eulix explain internal/cache/manager.go
+  eulix explain internal/cache/manager.go:120-180
+  eulix explain manager.go#L120-L180 "why is the lock released early"`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeOwnerTarget,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		question, err := explainQuestion(args[0], args[1:])
+		if err != nil {
+			return failed("Explain failed", err)
+		}
+
+		answer, err := askQuestion(question, askOptions{Confirm: !asJSON})
+		if err != nil {
+			return failed("Explain failed", err)
+		}
+		if asJSON {
+			out, _ := json.MarshalIndent(answer, "", "  ")
+			fmt.Println(string(out))
+		} else {
+			fmt.Println(answer)
+		}
+		return nil
+	},
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep the knowledge base warm in a background process",
+	Long:  "Load the KB, call graph and embeddings once and serve ask/chat queries over a unix socket in .eulix",
+	Example: `  eulix daemon
+  eulix daemon --status
+  eulix daemon --stop`,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stop, _ := cmd.Flags().GetBool("stop")
+		status, _ := cmd.Flags().GetBool("status")
+
+		var err error
+		switch {
+		case stop:
+			err = daemonStop()
+		case status:
+			err = daemonStatus()
+		default:
+			err = runDaemon()
+		}
+
+		if err != nil {
+			return failed("Daemon failed", err)
+		}
+		return nil
+	},
+}
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a JSON-RPC server on stdin/stdout for editor plugins",
+	Long: `Speak LSP style JSON-RPC (Content-Length framed) on stdin/stdout.
+
+Methods:
+  eulix/explainSymbol  {"symbol": "..."}
+  eulix/findUsages     {"symbol": "..."}
+  eulix/askSelection   {"file": "...", "startLine": 1, "endLine": 10, "text": "...", "question": "..."}
+
+All of them return {"answer": "..."}. Uses the daemon if one is running.`,
+	Example: `  eulix lsp`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := runEditorServer(); err != nil {
+			return failed("LSP server failed", err)
+		}
+		return nil
+	},
+}
+
+var botCmd = &cobra.Command{
+	Use:       "bot <slack|discord>",
+	Short:     "Answer /eulix slash commands from Slack or Discord",
+	Long:      "Serve the /eulix slash command endpoint for Slack or Discord. Channels can be mapped to other analyzed repos under [integrations] in eulix.toml.",
+	Example: `  eulix bot slack
+  eulix bot discord`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"slack", "discord"},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := runBot(args[0]); err != nil {
+			return failed("Bot failed", err)
+		}
+		return nil
+	},
+}
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Run checks for CI pipelines",
+	Long: `Non-interactive checks for CI. LLM settings can come from the environment
+(EULIX_LLM_PROVIDER, EULIX_LLM_MODEL, EULIX_LLM_BASE_URL, EULIX_API_KEY).
+
+Exit codes:
+  0  no findings at or above --fail-on
+  1  findings at or above --fail-on
+  2  the check itself failed`,
+	Example: `  eulix ci review --base origin/main
+  eulix ci audit --format sarif -o audit.sarif
+  eulix ci deadcode --fail-on never`,
+}
+
+var ciReviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "LLM review of the diff against a base ref",
+	Example: `  eulix ci review
+  eulix ci review --base origin/main --format json`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exitWith(runCICheck(cmd, "review"))
+	},
+}
+
+var ciDeadcodeCmd = &cobra.Command{
+	Use:   "deadcode",
+	Short: "Report functions that are never called",
+	Example: `  eulix ci deadcode
+  eulix ci deadcode --include-exported --fail-on warning`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exitWith(runCICheck(cmd, "deadcode"))
+	},
+}
+
+var ciAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Scan the analyzed files for risky security patterns",
+	Example: `  eulix ci audit
+  eulix ci audit --format sarif -o audit.sarif`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exitWith(runCICheck(cmd, "audit"))
+	},
+}
+
+var ciRefactorCmd = &cobra.Command{
+	Use:   "refactor",
+	Short: "Report overly complex or long functions",
+	Example: `  eulix ci refactor
+  eulix ci refactor --max-complexity 10 --max-lines 60`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exitWith(runCICheck(cmd, "refactor"))
+	},
+}
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "List HTTP endpoints and generate an OpenAPI skeleton",
+	Long: `Routes come from the entry points found by analyze, currently Flask and
+FastAPI style decorators, matched up with their handler functions.`,
+	Example: `  eulix api list
+  eulix api openapi -o openapi.json`,
+}
+
+var apiListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the HTTP endpoints with their handlers",
+	Example: `  eulix api list
+  eulix api list --json`,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if err := listAPI(asJSON); err != nil {
+			return failed("Listing endpoints failed", err)
 		}
+		return nil
+	},
+}
+
+var apiOpenAPICmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Write an OpenAPI 3 skeleton, described by the LLM from handler code",
+	Example: `  eulix api openapi -o openapi.json
+  eulix api openapi --no-llm`,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		noLLM, _ := cmd.Flags().GetBool("no-llm")
+		if err := writeOpenAPI(output, noLLM); err != nil {
+			return failed("OpenAPI generation failed", err)
+		}
+		return nil
+	},
+}
+
+var ownerCmd = &cobra.Command{
+	Use:   "owner <file|symbol>",
+	Short: "Show who owns a file or the files defining a function or type",
+	ValidArgsFunction: completeOwnerTarget,
+	Long: `Owners come from CODEOWNERS, read by analyze, and from .eulix/owners.toml,
+whose [[rule]] entries (path, owners) apply after CODEOWNERS.`,
+	Example: `  eulix owner internal/cache/cache.go
+  eulix owner CreateOrder`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := showOwner(args[0]); err != nil {
+			return failed("Owner lookup failed", err)
+		}
+		return nil
+	},
+}
+
+var commitMsgCmd = &cobra.Command{
+	Use:   "commitmsg",
+	Short: "Write a conventional commit message for the staged changes",
+	Long: `The LLM writes the message from the staged diff and the functions and types
+it changes, from the knowledge base when there is one. Use it as
+  git commit -e -m "$(eulix commitmsg)"`,
+	Example: `  git add -p && eulix commitmsg
+  git commit -e -m "$(eulix commitmsg)"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := writeCommitMessage(); err != nil {
+			return failed("Commit message failed", err)
+		}
+		return nil
+	},
+}
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog <from..to>",
+	Short: "Summarize the commits in a range as a changelog grouped by module",
+	Long: `A single ref like v1.2 means v1.2..HEAD. Each module, a directory up to two
+levels deep, gets a section summarized by the LLM from its commits and the
+symbols they changed.`,
+	Example: `  eulix changelog v1.2..v1.3
+  eulix changelog v1.2 -o CHANGELOG-next.md
+  eulix changelog HEAD~20..HEAD --no-llm`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		noLLM, _ := cmd.Flags().GetBool("no-llm")
+		if err := writeChangelog(args[0], output, noLLM); err != nil {
+			return failed("Changelog failed", err)
+		}
+		return nil
+	},
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Break a change into ordered tasks",
+	Example: `  eulix plan refactor "split the cache package by backend"`,
+}
+
+var planRefactorCmd = &cobra.Command{
+	Use:   "refactor <goal>",
+	Short: "Plan a refactoring as tasks with affected files and risks",
+	Long: `The LLM orders the tasks. Affected files come from where the symbols named in
+the goal are defined and called, so callers aren't forgotten. The JSON
+format is meant for turning tasks into tracker tickets.`,
+	Example: `  eulix plan refactor "move config loading out of the CLI"
+  eulix plan refactor "replace the redis client" --format json -o plan.json`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		if err := writeRefactorPlan(strings.Join(args, " "), format, output); err != nil {
+			return failed("Planning failed", err)
+		}
+		return nil
+	},
+}
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Summarize what changed in the codebase, for the team channel",
+	Long: `List the commits, files, added and removed symbols and complexity changes
+since an age (24h, 3d, 2w) or a git ref, with a short LLM-written summary on
+top. Files and symbols are compared between analyze runs, each 'eulix analyze'
+keeps a record in .eulix/history.`,
+	Example: `  eulix digest
+  eulix digest --since 7d -o weekly.md
+  eulix digest --since v1.2 --format json --no-llm`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, _ := cmd.Flags().GetString("since")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		noLLM, _ := cmd.Flags().GetBool("no-llm")
+		if err := writeDigest(since, format, output, noLLM); err != nil {
+			return failed("Digest failed", err)
+		}
+		return nil
+	},
+}
+
+var mapCmd = &cobra.Command{
+	Use:   "map",
+	Short: "Group the code into modules by what it does",
+	Long: `Cluster files by how alike their embeddings are and by the calls between
+them, then have the LLM name each cluster. Where clusters cut across
+directories is the architecture the layout doesn't show. Output is markdown
+with a Mermaid chart, the chart alone or JSON.`,
+	Example: `  eulix map
+  eulix map --format mermaid -o modules.mmd`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		noLLM, _ := cmd.Flags().GetBool("no-llm")
+		if err := writeModuleMap(format, output, noLLM); err != nil {
+			return failed("Map failed", err)
+		}
+		return nil
+	},
+}
+
+var vizCmd = &cobra.Command{
+	Use:   "viz",
+	Short: "Export visualizations of the knowledge base",
+	Example: `  eulix viz embeddings -o embeddings.html`,
+}
+
+var vizEmbeddingsCmd = &cobra.Command{
+	Use:   "embeddings",
+	Short: "Plot the chunk embeddings in 2D",
+	Long: `Project every chunk vector onto its first two principal components and
+write them as a scatter plot, coloured by directory with the chunk under the
+pointer named. Related code should sit together, if it doesn't the
+embeddings model is worth a look.`,
+	Example: `  eulix viz embeddings -o embeddings.html
+  eulix viz embeddings --format csv -o embeddings.csv`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		if err := writeEmbeddingsPlot(format, output); err != nil {
+			return failed("Export failed", err)
+		}
+		return nil
+	},
+}
+
+var depsCmd = &cobra.Command{
+	Use:   "deps <symbol>",
+	Short: "Show what a function calls and what calls it",
+	ValidArgsFunction: completeSymbols,
+	Long: `Follow the call graph from a function, down to what it calls and up to
+what calls it, and print it as a tree. A function reached twice is expanded
+once. With --include-types methods are named with their type and a type
+follows all of its methods. [dependencies] in eulix.toml sets the defaults.`,
+	Example: `  eulix deps CreateOrder
+  eulix deps ParseConfig --direction dependents --depth 5
+  eulix deps Server --include-types --format dot | dot -Tsvg > server.svg`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return failed("Failed to load config", err)
+		}
+		deps := cfg.Dependencies
+		if cmd.Flags().Changed("depth") {
+			deps.Depth, _ = cmd.Flags().GetInt("depth")
+		}
+		if cmd.Flags().Changed("direction") {
+			deps.Direction, _ = cmd.Flags().GetString("direction")
+		}
+		if cmd.Flags().Changed("include-types") {
+			deps.IncludeTypes, _ = cmd.Flags().GetBool("include-types")
+		}
+		format := deps.Format
+		if format == "" || cmd.Flags().Changed("format") {
+			format, _ = cmd.Flags().GetString("format")
+		}
+		output, _ := cmd.Flags().GetString("output")
+
+		opts, err := query.DependencyDefaults(deps)
+		if err != nil {
+			return failed("Deps failed", err)
+		}
+		if err := writeDependencyTree(args[0], opts, format, output); err != nil {
+			return failed("Deps failed", err)
+		}
+		return nil
+	},
+}
+
+var cyclesCmd = &cobra.Command{
+	Use:   "cycles",
+	Short: "Find circular calls and dependencies",
+	Long: `Report the strongly connected components of the call graph and the
+dependency graph, groups where every member reaches every other, with the
+shortest loop through each as a place to start cutting. A function calling
+itself isn't reported.`,
+	Example: `  eulix cycles
+  eulix cycles --graph deps
+  eulix cycles --json`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		graph, _ := cmd.Flags().GetString("graph")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if err := printCycles(graph, asJSON); err != nil {
+			return failed("Cycles failed", err)
+		}
+		return nil
+	},
+}
+
+var usesCmd = &cobra.Command{
+	Use:   "uses <module>",
+	Short: "List the files importing a module and where they call it",
+	Long: `List every file importing a module, from the imports analyze recorded,
+with the lines in each that go through it. The module can be its full path,
+a prefix of it, or a bare name matching one element of the path, so redis
+finds github.com/redis/go-redis/v9.`,
+	Example: `  eulix uses github.com/redis/go-redis
+  eulix uses numpy --calls 20
+  eulix uses golang.org/x/sync --json`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		calls, _ := cmd.Flags().GetInt("calls")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if err := printUses(args[0], calls, asJSON); err != nil {
+			return failed("Uses failed", err)
+		}
+		return nil
+	},
+}
+
+var centralCmd = &cobra.Command{
+	Use:   "central",
+	Short: "Rank functions by how central they are to the call graph",
+	Long: `Rank functions by PageRank over the call graph: a function scores high
+when many functions call it, or a few central ones do. Scores are scaled so
+the top one is 1, analyze stores them and retrieval favours central code.`,
+	Example: `  eulix central --top 20
+  eulix central -n 0 --json`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		top, _ := cmd.Flags().GetInt("top")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if err := printCentral(top, asJSON); err != nil {
+			return failed("Central failed", err)
+		}
+		return nil
+	},
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Replace this eulix with the latest release",
+	Long: `Download eulix for this platform from GitHub releases, verify it against the
+release checksums and that it runs, then put it in place of the running
+executable. eulix checks for a new release at most once a day and says so
+after a command, set EULIX_NO_UPDATE_CHECK=1 to turn that off.`,
+	Example: `  eulix upgrade
+  eulix upgrade --check
+  eulix upgrade --version v0.4.0 --force`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		release, _ := cmd.Flags().GetString("version")
+		baseURL, _ := cmd.Flags().GetString("base-url")
+		check, _ := cmd.Flags().GetBool("check")
+		force, _ := cmd.Flags().GetBool("force")
+		if err := runUpgrade(release, baseURL, check, force); err != nil {
+			return failed("Upgrade failed", err)
+		}
+		return nil
+	},
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark answer quality",
+	Example: `  eulix bench prompts suite.toml`,
+}
+
+var benchPromptsCmd = &cobra.Command{
+	Use:   "prompts <suite.toml>",
+	Short: "Check answers to a suite of questions against what they must mention",
+	Long: `Answers every [[case]] of a suite through the full pipeline, with caching
+off, and checks the answer:
+
+  [[case]]
+  name = "order saving"
+  query = "how does CreateOrder save an order"
+  must_mention = ["saveOrder", "validateOrder"]
+  must_cite = ["internal/orders/orders.go"]
+
+A cited file may be named by any tail of its path. --model picks the model,
+EULIX_LLM_PROVIDER the provider, and --cassette replay reruns a recorded
+suite without calling the model.
+
+Exit codes:
+  0  every assertion passed
+  1  an assertion failed or a question couldn't be answered
+  2  the suite couldn't run`,
+	Example: `  eulix bench prompts suite.toml
+  eulix bench prompts suite.toml --model llama3.1:8b --json -o report.json`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts benchOptions
+		opts.Model, _ = cmd.Flags().GetString("model")
+		opts.JSON, _ = cmd.Flags().GetBool("json")
+		opts.Output, _ = cmd.Flags().GetString("output")
+		return exitWith(runBenchPrompts(args[0], opts))
+	},
+}
+
+var tourCmd = &cobra.Command{
+	Use:   "tour",
+	Short: "Generate a guided reading path through the codebase",
+	Long: `Entry points first, then the modules most of the code calls into, then the
+types named in the most signatures. Each stop gets a short LLM summary and
+file:line pointers, --tui walks through them with the code on screen.`,
+	Example: `  eulix tour
+  eulix tour --tui
+  eulix tour --no-llm -o TOUR.md`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts tourOptions
+		opts.TUI, _ = cmd.Flags().GetBool("tui")
+		opts.NoLLM, _ = cmd.Flags().GetBool("no-llm")
+		opts.Format, _ = cmd.Flags().GetString("format")
+		opts.Output, _ = cmd.Flags().GetString("output")
+		if err := runTour(opts); err != nil {
+			return failed("Tour failed", err)
+		}
+		return nil
+	},
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade an old knowledge base in .eulix to the current schema",
+	Example: `  eulix migrate`,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := migrateKnowledgeBase(".eulix"); err != nil {
+			return failed("Migration failed", err)
+		}
+		return nil
+	},
+}
+
+var installToolsCmd = &cobra.Command{
+	Use:   "install-tools",
+	Short: "Download eulix_parser and eulix_embed for this platform",
+	Long:  "Download the companion binaries from GitHub releases, verify their checksums and install them to ~/.eulix/bin",
+	Example: `  eulix install-tools
+  eulix install-tools --version v0.4.0 --dir /usr/local/bin`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, _ := cmd.Flags().GetString("version")
+		dir, _ := cmd.Flags().GetString("dir")
+		baseURL, _ := cmd.Flags().GetString("base-url")
+
+		if err := installTools(version, dir, baseURL); err != nil {
+			return failed("Install failed", err)
+		}
+		return nil
+	},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that binaries, LLM, cache and knowledge base are all working",
+	Example: `  eulix doctor`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems, err := runDoctor()
+		if err != nil {
+			return failed("Error", err)
+		}
+		if problems > 0 {
+			return failed("", errChecksFailed)
+		}
+		return nil
 	},
 }
 
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize eulix in current directory",
-	Run: func(cmd *cobra.Command, args []string) {
+	Example: `  eulix init && eulix analyze`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := initializeProject(); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to initialize: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to initialize", err)
+		}
+		return nil
+	},
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect what was sent to hosted LLM providers",
+	Long: `Every prompt sent to a hosted provider is recorded in .eulix/audit with its
+provider, model, size, files and sha256. The code itself is not stored.`,
+	Example: `  eulix audit log
+  eulix audit log -n 5 --verbose`,
+}
+
+var auditLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show or rotate the audit log of outbound prompts",
+	Example: `  eulix audit log
+  eulix audit log --all -n 0
+  eulix audit log --rotate`,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		all, _ := cmd.Flags().GetBool("all")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		rotate, _ := cmd.Flags().GetBool("rotate")
+
+		var err error
+		if rotate {
+			err = rotateAuditLog()
+		} else {
+			err = showAuditLog(limit, all, verbose)
 		}
+		if err != nil {
+			return failed("Audit log failed", err)
+		}
+		return nil
+	},
+}
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry [on|off|status]",
+	Short: "Opt in or out of anonymous usage metrics",
+	Long: `Telemetry is off unless you turn it on. When on, eulix counts which commands
+run, how long they take in coarse buckets and what kind of error they fail
+with, and sends the counts once a day. Arguments, paths, questions, error
+messages and code are never collected. status prints exactly what would be
+sent. DO_NOT_TRACK=1 or EULIX_TELEMETRY=off turn it off regardless.`,
+	Example: `  eulix telemetry status
+  eulix telemetry off`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off", "status"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action := "status"
+		if len(args) == 1 {
+			action = args[0]
+		}
+		if err := runTelemetry(action); err != nil {
+			return failed("Telemetry failed", err)
+		}
+		return nil
+	},
+}
+
+// Snapshot command group
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore the knowledge base",
+	Long: `Snapshots archive the KB, chunks, vectors and checksum in .eulix/snapshots,
+to roll back after a bad analyze or to copy a prebuilt index to another machine.`,
+	Example: `  eulix snapshot create before-refactor
+  eulix snapshot restore before-refactor`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Snapshot the current knowledge base",
+	Example: `  eulix snapshot create
+  eulix snapshot create before-refactor --force`,
+	Args:  cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		force, _ := cmd.Flags().GetBool("force")
+
+		if err := createSnapshot(".eulix", name, force); err != nil {
+			return failed("Snapshot failed", err)
+		}
+		return nil
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots",
+	Example: `  eulix snapshot list`,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := listSnapshots(".eulix"); err != nil {
+			return failed("Failed to list snapshots", err)
+		}
+		return nil
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name|file.tar.gz>",
+	Short: "Replace the knowledge base with a snapshot",
+	Example: `  eulix snapshot restore before-refactor
+  eulix snapshot restore kb-backup.tar.gz --force`,
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+
+		if err := restoreSnapshot(".eulix", args[0], force); err != nil {
+			return failed("Restore failed", err)
+		}
+		return nil
+	},
+}
+
+var pushIndexCmd = &cobra.Command{
+	Use:   "push-index [s3://bucket/path|gs://bucket/path|file:///dir]",
+	Short: "Upload the knowledge base for others to pull",
+	Long: `Upload the knowledge base to object storage, typically from CI, so developers
+can pull a prebuilt index instead of analyzing a large repository themselves.
+The location defaults to [index] remote in eulix.toml.`,
+	Example: `  eulix push-index s3://team-bucket/eulix/myrepo
+  eulix push-index file:///mnt/shared/eulix`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		location := ""
+		if len(args) == 1 {
+			location = args[0]
+		}
+
+		if err := pushIndex(".eulix", location); err != nil {
+			return failed("Push failed", err)
+		}
+		return nil
+	},
+}
+
+var pullIndexCmd = &cobra.Command{
+	Use:   "pull-index [s3://bucket/path|gs://bucket/path|file:///dir]",
+	Short: "Download a prebuilt knowledge base",
+	Long: `Download a knowledge base uploaded with push-index, verify its sha256 and
+install it, then report how many of your files differ from it.
+The location defaults to [index] remote in eulix.toml.`,
+	Example: `  eulix pull-index s3://team-bucket/eulix/myrepo
+  eulix pull-index gs://team-bucket/eulix/myrepo --force`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		location := ""
+		if len(args) == 1 {
+			location = args[0]
+		}
+		force, _ := cmd.Flags().GetBool("force")
+
+		if err := pullIndex(".eulix", location, force); err != nil {
+			return failed("Pull failed", err)
+		}
+		return nil
 	},
 }
 
@@ -123,32 +1130,34 @@ var cacheCmd = &cobra.Command{
 	Use:   "cache",
 	Short: "Manage cache entries",
 	Long:  `View, manage, and interact with cached query responses`,
+	Example: `  eulix cache stats
+  eulix cache clean`,
 }
 
 var cacheListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all cache entries",
 	Long:  "Display all cached queries and their metadata",
+	Example: `  eulix cache list
+  eulix cache list -v`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		mgr, err := initCacheManager()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to initialize cache", err)
 		}
 		defer mgr.Close()
 
 		entries, err := mgr.ListAll()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to list cache entries: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to list cache entries", err)
 		}
 
 		if len(entries) == 0 {
 			fmt.Println("No cache entries found.")
-			return
+			return nil
 		}
 
 		verbose, _ := cmd.Flags().GetBool("verbose")
@@ -169,9 +1178,19 @@ var cacheListCmd = &cobra.Command{
 				fmt.Printf("    Query: %s\n", truncateString(entry.Query, 80))
 				fmt.Printf("    Response: %s\n", truncateString(entry.Response, 100))
 				fmt.Printf("    Checksum: %s\n", entry.ChecksumHash[:12])
+				if entry.User != "" {
+					fmt.Printf("    By: %s (%s, eulix %s)\n", entry.User, entry.Model, entry.Version)
+				}
+				if entry.Rating > 0 {
+					fmt.Printf("    Rating: %d/%d\n", entry.Rating, cache.MaxRating)
+				}
+				if entry.Notes != "" {
+					fmt.Printf("    Notes: %s\n", truncateString(entry.Notes, 100))
+				}
 			}
 			fmt.Println()
 		}
+		return nil
 	},
 }
 
@@ -179,21 +1198,20 @@ var cacheStatsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show cache statistics",
 	Long:  "Display statistics about cache usage and storage",
+	Example: `  eulix cache stats`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		mgr, err := initCacheManager()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to initialize cache", err)
 		}
 		defer mgr.Close()
 
 		stats, err := mgr.GetStats()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to get cache stats: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to get cache stats", err)
 		}
 
 		fmt.Println("Cache Statistics:")
@@ -207,6 +1225,7 @@ var cacheStatsCmd = &cobra.Command{
 		if connected, ok := stats["redis_connected"].(bool); ok && connected {
 			fmt.Println("Redis: Connected")
 		}
+		return nil
 	},
 }
 
@@ -214,10 +1233,12 @@ var cacheClearCmd = &cobra.Command{
 	Use:   "clear",
 	Short: "Clear all cache entries",
 	Long:  "Remove all cached queries and responses",
+	Example: `  eulix cache clear
+  eulix cache clear -f`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		force, _ := cmd.Flags().GetBool("force")
 
 		if !force {
@@ -226,21 +1247,19 @@ var cacheClearCmd = &cobra.Command{
 			fmt.Scanln(&response)
 			if strings.ToLower(response) != "y" {
 				fmt.Println("Operation cancelled.")
-				return
+				return nil
 			}
 		}
 
 		mgr, err := initCacheManager()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to initialize cache", err)
 		}
 		defer mgr.Close()
 
 		entries, err := mgr.ListAll()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to list entries: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to list entries", err)
 		}
 
 		deleted := 0
@@ -253,33 +1272,36 @@ var cacheClearCmd = &cobra.Command{
 		}
 
 		fmt.Printf("Successfully cleared %d cache entries.\n", deleted)
+		return nil
 	},
 }
 
 var cacheDeleteCmd = &cobra.Command{
 	Use:   "delete <query-hash>",
 	Short: "Delete a specific cache entry",
+	ValidArgsFunction: completeCacheHashes,
 	Long:  "Remove a cache entry by its query hash",
+	Example: `  # Tab completes the hashes in the cache
+  eulix cache delete <TAB>`,
 	Args:  cobra.ExactArgs(1),
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		queryHash := args[0]
 
 		mgr, err := initCacheManager()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to initialize cache", err)
 		}
 		defer mgr.Close()
 
 		if err := mgr.Delete(queryHash); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to delete entry: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to delete entry", err)
 		}
 
 		fmt.Printf("Successfully deleted cache entry: %s\n", queryHash)
+		return nil
 	},
 }
 
@@ -287,23 +1309,23 @@ var cacheCleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Remove expired cache entries",
 	Long:  "Clean up cache by removing all expired entries",
+	Example: `  eulix cache clean`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		mgr, err := initCacheManager()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to initialize cache", err)
 		}
 		defer mgr.Close()
 
 		if err := mgr.CleanExpired(); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to clean expired entries: %v\n", err)
-			os.Exit(1)
+			return failed("Failed to clean expired entries", err)
 		}
 
 		fmt.Println("Successfully cleaned expired cache entries.")
+		return nil
 	},
 }
 
@@ -324,46 +1346,133 @@ var historyCmd = &cobra.Command{
 	Use:   "history",
 	Short: "View query history interactively",
 	Long:  "Launch an interactive TUI to browse your cached query history",
+	Example: `  eulix history
+  eulix history --no-tui | less`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInitialized()
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		useTUI, _ := cmd.Flags().GetBool("tui")
 		noTUI, _ := cmd.Flags().GetBool("no-tui")
 
 		// Default to TUI unless --no-tui is specified
 		if !noTUI || useTUI {
-			runHistoryTUI(cmd)
-		} else {
-			runHistoryCommand(cmd)
+			return runHistoryTUI(cmd)
+		}
+		return runHistoryCommand(cmd)
+	},
+}
+
+var historyPinCmd = &cobra.Command{
+	Use:               "pin <query-hash>",
+	Short:             "Pin an answer to your notes",
+	Long:              "Copy a cached answer to your notes, where it stays when the cache expires, is cleaned or is invalidated by code changes. Needs the SQL cache.",
+	Example: `  eulix history pin <TAB>
+  eulix notes`,
+	ValidArgsFunction: completeCacheHashes,
+	Args:              cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := pinAnswer(args[0]); err != nil {
+			return failed("Pin failed", err)
+		}
+		return nil
+	},
+}
+
+var historyUnpinCmd = &cobra.Command{
+	Use:               "unpin <query-hash>",
+	Short:             "Remove an answer from your notes",
+	Example:           `  eulix history unpin <TAB>`,
+	ValidArgsFunction: completePinnedHashes,
+	Args:              cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := unpinAnswer(args[0]); err != nil {
+			return failed("Unpin failed", err)
+		}
+		return nil
+	},
+}
+
+var historyCorrectCmd = &cobra.Command{
+	Use:   "correct <query-hash> <correction>",
+	Short: "Attach a correction to a cached answer",
+	Long:  "Note that a cached answer is wrong. The correction is shown with the answer whenever the question, or another with the same answer, is asked again, and a fresh answer is told about it. It lapses at the next analyze. Needs the SQL cache.",
+	Example: `  eulix history correct <TAB> "parseConfig was removed in v2, see loadConfig"
+  eulix history correct --clear <hash>`,
+	ValidArgsFunction: completeCacheHashes,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if clear, _ := cmd.Flags().GetBool("clear"); clear {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.MinimumNArgs(2)(cmd, args)
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := correctAnswer(args[0], strings.Join(args[1:], " ")); err != nil {
+			return failed("Correction failed", err)
 		}
+		return nil
 	},
 }
 
-func runHistoryCommand(cmd *cobra.Command) {
+var notesCmd = &cobra.Command{
+	Use:   "notes [search]",
+	Short: "Show your pinned answers",
+	Long:  "Show the answers pinned from history with p or eulix history pin, newest first, as a personal knowledge base about the repo. A search keeps the notes whose question, answer or notes contain it.",
+	Example: `  eulix notes
+  eulix notes auth
+  eulix notes --markdown > NOTES.md`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkInitialized()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		search := ""
+		if len(args) == 1 {
+			search = args[0]
+		}
+		markdown, _ := cmd.Flags().GetBool("markdown")
+		if err := showNotes(search, markdown); err != nil {
+			return failed("Notes failed", err)
+		}
+		return nil
+	},
+}
+
+func runHistoryCommand(cmd *cobra.Command) error {
 	mgr, err := initCacheManager()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
-		os.Exit(1)
+		return failed("Failed to initialize cache", err)
 	}
 	defer mgr.Close()
 
-	entries, err := mgr.ListAll()
+	groups, err := mgr.History()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load history: %v\n", err)
-		os.Exit(1)
+		return failed("Failed to load history", err)
 	}
 
-	if len(entries) == 0 {
+	if len(groups) == 0 {
 		fmt.Println("No history found. Your question history is empty.")
-		return
+		return nil
 	}
 
-	fmt.Printf("Query History (%d entries):\n", len(entries))
+	fmt.Printf("Query History (%d questions):\n", len(groups))
 	fmt.Println(strings.Repeat("=", 80))
 
-	for i, entry := range entries {
+	for i, group := range groups {
+		entry := group.Latest()
 		fmt.Printf("\n[Entry %d]\n", i+1)
+		if group.Asked > 1 {
+			fmt.Printf("Asked: %d times\n", group.Asked)
+		}
 		fmt.Printf("Hash: %s\n", entry.QueryHash)
 		fmt.Printf("Created: %s\n", entry.CreatedAt.Format("2006-01-02 15:04:05"))
 		fmt.Printf("Expires: %s\n", entry.ExpiresAt.Format("2006-01-02 15:04:05"))
@@ -377,47 +1486,91 @@ func runHistoryCommand(cmd *cobra.Command) {
 
 		fmt.Printf("\nQuery:\n%s\n", wrapText(entry.Query, 76))
 		fmt.Printf("\nResponse:\n%s\n", wrapText(entry.Response, 76))
+
+		if older := len(group.Entries) - 1 + len(group.Older); older > 0 {
+			fmt.Printf("\nOlder answers (%d):\n", older)
+			for _, e := range group.Entries[1:] {
+				fmt.Printf("  %s  %s\n", e.CreatedAt.Format("2006-01-02 15:04"), e.QueryHash)
+			}
+			for _, e := range group.Older {
+				fmt.Printf("  %s  %s  (before the code changed)\n", e.CreatedAt.Format("2006-01-02 15:04"), e.QueryHash)
+			}
+		}
 		fmt.Println(strings.Repeat("-", 80))
 	}
+	return nil
 }
 
 // TUI implementation for history
-func runHistoryTUI(cmd *cobra.Command) {
+func runHistoryTUI(cmd *cobra.Command) error {
 	mgr, err := initCacheManager()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
-		os.Exit(1)
+		return failed("Failed to initialize cache", err)
 	}
 	defer mgr.Close()
 
-	entries, err := mgr.ListAll()
+	groups, err := mgr.History()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load history: %v\n", err)
-		os.Exit(1)
+		return failed("Failed to load history", err)
 	}
 
-	if len(entries) == 0 {
+	if len(groups) == 0 {
 		fmt.Println("No history found. Your question history is empty.")
-		return
+		return nil
 	}
 
 	// Launch the TUI
-	model := tui.HistoryView(entries, mgr)
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	model := tui.HistoryView(groups, mgr)
+	if cfg, err := config.Load(); err == nil {
+		model = model.WithKeys(tui.Locksmith(cfg.TUI.Keys))
+	}
+	p := tea.NewProgram(crash.Guard(model), tea.WithAltScreen(), tea.WithoutCatchPanics())
 
 	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
-		os.Exit(1)
+		return failed("Error running TUI", err)
 	}
+	return nil
 }
 
-func Execute() error {
-	return rootCmd.Execute()
+// Execute runs the command line and returns the exit status. A failed
+// command is reported here, once, after its deferred cleanup has run.
+func Execute() int {
+	err := rootCmd.Execute()
+	child.StopAll()
+	if err == nil {
+		return fault.ExitOK
+	}
+
+	var status exitStatus
+	if errors.As(err, &status) {
+		telemetry.Finish("exit_status")
+		return int(status)
+	}
+
+	code := reportFailure(err)
+	if code == fault.ExitUsage {
+		telemetry.Finish("usage")
+	} else {
+		telemetry.Finish(errorCategory(err))
+	}
+	return code
 }
 
 func init() {
+	rootCmd.PersistentFlags().Bool("local-only", false, "Refuse to send code to a model that isn't running on this machine")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Print nothing when a command fails, only set the exit status")
+	rootCmd.PersistentFlags().String("cassette", "", "Record model and embedding answers to .eulix/cassettes, or replay them: record, replay or off")
+
+	// Analyze flags
+	analyzeCmd.Flags().Duration("wait", 0, "Wait up to this long for a running analyze to finish instead of failing")
+
+	// Install tools flags
+	installToolsCmd.Flags().String("version", "latest", "Release tag to install")
+	installToolsCmd.Flags().String("dir", "", "Install directory (default ~/.eulix/bin)")
+	installToolsCmd.Flags().String("base-url", "", "Releases base URL, for mirrors")
+
 	// Aspirine flags
-	aspirineCmd.Flags().Bool("no-backup", false, "Don't backup existing embeddings.bin")
+	aspirineCmd.Flags().Bool("no-backup", false, "Don't backup existing vectors.bin")
 	aspirineCmd.Flags().Bool("force", false, "Force rebuild even if validations fail")
 
 	// Cache list flags
@@ -426,9 +1579,115 @@ func init() {
 	// Cache clear flags
 	cacheClearCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
 
+	// Snapshot flags
+	snapshotCreateCmd.Flags().BoolP("force", "f", false, "Replace a snapshot with the same name")
+	snapshotRestoreCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+
+	pullIndexCmd.Flags().BoolP("force", "f", false, "Replace an existing knowledge base without asking")
+
+	// Ask flags
+	askCmd.Flags().BoolP("verbose", "v", false, "Show which search capabilities the knowledge base has, and agent tool calls")
+	askCmd.Flags().Bool("json", false, "Print the answer and its confidence score as JSON")
+	askCmd.Flags().Bool("agent", false, "Let the model search symbols, read source and follow callers over several rounds")
+	askCmd.Flags().Int("steps", query.DefaultAgentSteps, "Maximum tool calls in agent mode")
+	askCmd.Flags().Bool("over-budget", false, "Send the question even if it goes over the [llm.budget] limits")
+	askCmd.Flags().Int("max-response", 0, "Most tokens the answer may run to, also kept free of context (default per question type, see [retrieval] max_response)")
+	askCmd.Flags().Bool("full", false, "Load the whole pipeline even for where-is and who-calls lookups the index answers alone")
+	askCmd.Flags().String("batch", "", "Answer every question in a file (.txt one per line, or .toml with scopes)")
+	askCmd.Flags().Duration("delay", time.Second, "With --batch, the least time between two questions that call the LLM")
+	askCmd.Flags().StringP("output", "o", "", "With --batch, write the report to a file instead of stdout")
+
+	// Explain flags
+	explainCmd.Flags().Bool("json", false, "Print the answer and its confidence score as JSON")
+
+	// Daemon flags
+	daemonCmd.Flags().Bool("stop", false, "Stop the running daemon")
+	daemonCmd.Flags().Bool("status", false, "Show daemon status")
+
 	// History command flags
 	historyCmd.Flags().Bool("tui", false, "Force interactive TUI mode (default)")
 	historyCmd.Flags().Bool("no-tui", false, "Use text output instead of TUI")
+	historyCmd.AddCommand(historyPinCmd)
+	historyCmd.AddCommand(historyUnpinCmd)
+	historyCorrectCmd.Flags().Bool("clear", false, "Remove the correction")
+	historyCmd.AddCommand(historyCorrectCmd)
+	notesCmd.Flags().Bool("markdown", false, "Print the notes as a Markdown document")
+
+	// CI flags
+	ciCmd.PersistentFlags().String("format", "text", "Output format: text, json or sarif")
+	ciCmd.PersistentFlags().StringP("output", "o", "", "Write the report to a file instead of stdout")
+	ciCmd.PersistentFlags().String("fail-on", "error", "Exit 1 on findings at or above: error, warning, note or never")
+	ciReviewCmd.Flags().String("base", "", "Base ref to diff against (default: origin/$GITHUB_BASE_REF or HEAD~1)")
+	ciDeadcodeCmd.Flags().Bool("include-exported", false, "Also report exported functions")
+	ciRefactorCmd.Flags().Int("max-complexity", 15, "Complexity above which a function is reported")
+	ciRefactorCmd.Flags().Int("max-lines", 100, "Length above which a function is reported")
+
+	ciCmd.AddCommand(ciReviewCmd)
+	ciCmd.AddCommand(ciDeadcodeCmd)
+	ciCmd.AddCommand(ciAuditCmd)
+	ciCmd.AddCommand(ciRefactorCmd)
+
+	// API flags
+	apiListCmd.Flags().Bool("json", false, "Print the endpoints as JSON")
+	apiOpenAPICmd.Flags().StringP("output", "o", "", "Write the document to a file instead of stdout")
+	apiOpenAPICmd.Flags().Bool("no-llm", false, "Use docstrings only, don't send handler code to the LLM")
+
+	changelogCmd.Flags().StringP("output", "o", "", "Write the changelog to a file instead of stdout")
+
+	planRefactorCmd.Flags().String("format", "markdown", "Output format: markdown or json")
+	planRefactorCmd.Flags().StringP("output", "o", "", "Write the plan to a file instead of stdout")
+	planCmd.AddCommand(planRefactorCmd)
+
+	changelogCmd.Flags().Bool("no-llm", false, "List commit subjects instead of summarizing them")
+	apiCmd.AddCommand(apiListCmd)
+	apiCmd.AddCommand(apiOpenAPICmd)
+
+	statsCmd.Flags().Bool("json", false, "Print the stats as JSON")
+
+	mapCmd.Flags().String("format", "markdown", "Output format: markdown, mermaid or json")
+	mapCmd.Flags().StringP("output", "o", "", "Write the map to a file instead of stdout")
+	mapCmd.Flags().Bool("no-llm", false, "Name clusters after their directories")
+
+	vizEmbeddingsCmd.Flags().String("format", "html", "Output format: html or csv")
+	vizEmbeddingsCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+	vizCmd.AddCommand(vizEmbeddingsCmd)
+
+	digestCmd.Flags().String("since", "24h", "Age like 24h, 3d or 2w, or a git ref")
+	digestCmd.Flags().String("format", "markdown", "Output format: markdown or json")
+	digestCmd.Flags().StringP("output", "o", "", "Write the digest to a file instead of stdout")
+	digestCmd.Flags().Bool("no-llm", false, "Leave out the written summary")
+
+	depsCmd.Flags().Int("depth", 3, "Levels of calls to follow, up to 10")
+	depsCmd.Flags().String("direction", "both", "dependencies, dependents or both")
+	depsCmd.Flags().Bool("include-types", false, "Name methods with their type and follow a type's methods")
+	depsCmd.Flags().String("format", "tree", "Output format: tree, dot or json")
+	depsCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+
+	cyclesCmd.Flags().String("graph", "all", "Graph to check: calls, deps or all")
+	cyclesCmd.Flags().Bool("json", false, "Print the cycles as JSON")
+	usesCmd.Flags().Int("calls", 10, "Call sites to show per file")
+	usesCmd.Flags().Bool("json", false, "Print the files and every call site as JSON")
+
+	centralCmd.Flags().IntP("top", "n", 20, "Show the top n functions, 0 for all")
+	centralCmd.Flags().Bool("json", false, "Print the ranking as JSON")
+
+	upgradeCmd.Flags().String("version", "", "Release tag to install instead of the latest")
+	upgradeCmd.Flags().String("base-url", "", "Releases URL to download from, for mirrors")
+	upgradeCmd.Flags().Bool("check", false, "Only say whether a newer release is out")
+	upgradeCmd.Flags().Bool("force", false, "Install even if it isn't newer than this eulix")
+
+	genDocsCmd.Flags().String("man", "", "Directory to write man pages to")
+	genDocsCmd.Flags().String("markdown", "", "Directory to write the Markdown reference to")
+
+	benchPromptsCmd.Flags().String("model", "", "Model to answer with instead of [llm] model")
+	benchPromptsCmd.Flags().Bool("json", false, "Print the report as JSON")
+	benchPromptsCmd.Flags().StringP("output", "o", "", "Write the report to a file instead of stdout")
+	benchCmd.AddCommand(benchPromptsCmd)
+
+	tourCmd.Flags().Bool("tui", false, "Walk through the stops in the terminal UI")
+	tourCmd.Flags().Bool("no-llm", false, "Skip the summaries, stops and pointers only")
+	tourCmd.Flags().String("format", "markdown", "Output format: markdown or json")
+	tourCmd.Flags().StringP("output", "o", "", "Write the tour to a file instead of stdout")
 
 	// Add cache subcommands
 	cacheCmd.AddCommand(cacheListCmd)
@@ -437,6 +1696,16 @@ func init() {
 	cacheCmd.AddCommand(cacheDeleteCmd)
 	cacheCmd.AddCommand(cacheCleanCmd)
 
+	auditLogCmd.Flags().IntP("limit", "n", 20, "Show the last n prompts, 0 for all")
+	auditLogCmd.Flags().Bool("all", false, "Include rotated logs")
+	auditLogCmd.Flags().BoolP("verbose", "v", false, "List the files included in each prompt")
+	auditLogCmd.Flags().Bool("rotate", false, "Start a new log, keeping the current one as audit-<time>.jsonl")
+	auditCmd.AddCommand(auditLogCmd)
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+
 	// Disable default help command
 	rootCmd.SetHelpCommand(&cobra.Command{
 		Use:    "no-help",
@@ -447,36 +1716,63 @@ func init() {
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(analyzeCmd)
 	rootCmd.AddCommand(chatCmd)
+	rootCmd.AddCommand(askCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(daemonCmd)
+	rootCmd.AddCommand(lspCmd)
+	rootCmd.AddCommand(botCmd)
+	rootCmd.AddCommand(ciCmd)
+	rootCmd.AddCommand(apiCmd)
+	rootCmd.AddCommand(ownerCmd)
+	rootCmd.AddCommand(commitMsgCmd)
+	rootCmd.AddCommand(changelogCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(tourCmd)
+	rootCmd.AddCommand(digestCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(mapCmd)
+	rootCmd.AddCommand(vizCmd)
+	rootCmd.AddCommand(depsCmd)
+	rootCmd.AddCommand(cyclesCmd)
+	rootCmd.AddCommand(usesCmd)
+	rootCmd.AddCommand(centralCmd)
+	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(installToolsCmd)
+	rootCmd.AddCommand(upgradeCmd)
+	rootCmd.AddCommand(genDocsCmd)
+	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(glaDOSCmd)
 	rootCmd.AddCommand(aspirineCmd)
 	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(pushIndexCmd)
+	rootCmd.AddCommand(pullIndexCmd)
 	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(notesCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(telemetryCmd)
 }
 
 // Helper functions
 
+// checkInitialized fails with fault.ErrNotInitialized unless the current
+// directory has .eulix, .euignore and eulix.toml
 func checkInitialized() error {
 	eulixDir := ".eulix"
 	if _, err := os.Stat(eulixDir); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "\nEulix not initialized in this directory\n\n")
-		fmt.Fprintf(os.Stderr, "Please run: eulix init\n\n")
-		os.Exit(1)
+		return fmt.Errorf("%w, please run: eulix init", fault.ErrNotInitialized)
 	}
 
 	euignorePath := ".euignore"
 	if _, err := os.Stat(euignorePath); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "\n.euignore file missing\n\n")
-		fmt.Fprintf(os.Stderr, "Please run: eulix init, or create a .euignore file similar to .gitignore\n\n")
-		os.Exit(1)
+		return fmt.Errorf("%w, .euignore is missing: run eulix init, or create a .euignore file similar to .gitignore", fault.ErrNotInitialized)
 	}
 
 	configPath := "eulix.toml"
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "\neulix.toml configuration file missing\n\n")
-		fmt.Fprintf(os.Stderr, "This file is required for eulix to run properly.\n")
-		fmt.Fprintf(os.Stderr, "Please run: eulix init\n\n")
-		os.Exit(1)
+		return fmt.Errorf("%w, eulix.toml is missing: run eulix init", fault.ErrNotInitialized)
 	}
 
 	return nil
@@ -502,6 +1798,22 @@ func hasKnowledgeBase() bool {
 	return err == nil
 }
 
+// waitForWriters holds off readers while analyze (or another writer) is
+// replacing files in .eulix
+func waitForWriters(eulixDir string) error {
+	holder, err := lock.Read(eulixDir)
+	if err != nil || holder == nil {
+		return err
+	}
+	if holder.Corrupt {
+		// An unreadable lock doesn't go away by waiting
+		return &lock.HeldError{Info: *holder}
+	}
+
+	fmt.Fprintf(os.Stderr, "Waiting for %s (pid %d) to finish...\n", holder.Operation, holder.PID)
+	return lock.WaitForRelease(eulixDir, 30*time.Minute)
+}
+
 // initCacheManager initializes and returns a cache manager
 func initCacheManager() (*cache.Manager, error) {
 	cfg, err := config.Load()
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"eulix/internal/config"
+	"eulix/internal/query"
+
+	"github.com/spf13/cobra"
+)
+
+// completing reports whether cmd is the shell asking for completions, which
+// runs on every TAB and mustn't count for telemetry or print notices
+func completing(cmd *cobra.Command) bool {
+	return cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// completeSymbols offers the functions and types in kb_index.json, with
+// where the first one is defined
+func completeSymbols(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	data, err := os.ReadFile(filepath.Join(".eulix", "kb_index.json"))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var index query.KBIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	add := func(byName map[string][]string, kind string) {
+		for name, locations := range byName {
+			if seen[name] || !strings.HasPrefix(name, toComplete) {
+				continue
+			}
+			seen[name] = true
+			desc := kind
+			if len(locations) > 0 {
+				desc += " " + locations[0]
+			}
+			if len(locations) > 1 {
+				desc += fmt.Sprintf(" (+%d more)", len(locations)-1)
+			}
+			out = append(out, name+"\t"+desc)
+		}
+	}
+	add(index.FunctionsByName, "func")
+	add(index.TypesByName, "type")
+	sort.Strings(out)
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOwnerTarget offers files for anything that looks like a path,
+// symbols otherwise
+func completeOwnerTarget(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 && strings.ContainsAny(toComplete, "/.") {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completeSymbols(cmd, args, toComplete)
+}
+
+// completeCacheHashes offers the cached answers by hash, with their question
+func completeCacheHashes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	mgr, err := initCacheManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer mgr.Close()
+	entries, err := mgr.ListAll()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var out []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.QueryHash, toComplete) {
+			out = append(out, entry.QueryHash+"\t"+truncateString(entry.Query, 60))
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePinnedHashes offers the pinned answers by hash, with their question
+func completePinnedHashes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	mgr, err := initCacheManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer mgr.Close()
+	favorites, err := mgr.Favorites("")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var out []string
+	for _, fav := range favorites {
+		if strings.HasPrefix(fav.QueryHash, toComplete) {
+			out = append(out, fav.QueryHash+"\t"+truncateString(fav.Query, 60))
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigKeys offers the dotted keys of eulix.toml
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []string
+	for _, key := range config.Keys() {
+		if strings.HasPrefix(key, toComplete) {
+			out = append(out, key)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"eulix/internal/config"
+)
+
+// showConfig prints the value of key in the effective config, every key
+// when it is "". Credentials are masked.
+func showConfig(key string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	keys := []string{key}
+	if key == "" {
+		keys = config.Keys()
+	}
+	for _, k := range keys {
+		value, err := cfg.Get(k)
+		if err != nil {
+			return err
+		}
+		text := fmt.Sprint(value)
+		switch {
+		case config.Secret(k) && text != "":
+			text = "********"
+		case !isScalar(value):
+			data, _ := json.Marshal(value)
+			text = string(data)
+		}
+		if key == "" {
+			fmt.Printf("%s = %s\n", k, text)
+		} else {
+			fmt.Println(text)
+		}
+	}
+	return nil
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case string, bool, int, int64, float64:
+		return true
+	}
+	return false
+}
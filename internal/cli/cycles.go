@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"eulix/internal/query"
+)
+
+// graphTitles are the headings printCycles uses
+var graphTitles = map[string]string{
+	query.CycleGraphCalls: "Call graph",
+	query.CycleGraphDeps:  "Dependency graph",
+}
+
+// printCycles reports the cycles in graph, "all" for both
+func printCycles(graph string, asJSON bool) error {
+	graphs := []string{graph}
+	if graph == "all" {
+		graphs = []string{query.CycleGraphCalls, query.CycleGraphDeps}
+	}
+	report, err := query.FindCycles(".eulix", graphs)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for i, g := range report {
+		if i > 0 {
+			fmt.Println()
+		}
+		unit := "functions"
+		if g.Graph == query.CycleGraphDeps {
+			unit = "modules"
+		}
+		switch {
+		case g.Nodes == 0:
+			fmt.Printf("%s: empty, nothing to check\n", graphTitles[g.Graph])
+			continue
+		case len(g.Cycles) == 0:
+			fmt.Printf("%s: no cycles in %d %s\n", graphTitles[g.Graph], g.Nodes, unit)
+			continue
+		}
+		fmt.Printf("%s: %d cycles in %d %s\n", graphTitles[g.Graph], len(g.Cycles), g.Nodes, unit)
+		for n, c := range g.Cycles {
+			fmt.Printf("\n  %d. %d %s, %d edges between them\n", n+1, len(c.Members), unit, c.Edges)
+			fmt.Printf("     %s\n", strings.Join(c.Members, ", "))
+			if len(c.Loop) > 0 {
+				fmt.Printf("     e.g. %s\n", strings.Join(c.Loop, " → "))
+			}
+		}
+	}
+	return nil
+}
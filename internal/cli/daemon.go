@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"eulix/internal/config"
+	"eulix/internal/daemon"
+)
+
+// runDaemon warms up the router once and serves queries until stopped
+func runDaemon() error {
+	eulixDir := ".eulix"
+
+	if client, err := daemon.Dial(eulixDir); err == nil {
+		if status, err := client.Status(); err == nil {
+			return fmt.Errorf("daemon already running (pid %d)", status.PID)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	startTime := time.Now()
+	fmt.Println("Loading knowledge base...")
+
+	router, cleanup, err := buildRouter(".", cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	fmt.Println("Loading embeddings...")
+	if err := router.Warm(); err != nil {
+		return fmt.Errorf("failed to warm up: %w", err)
+	}
+
+	for _, warning := range router.Warnings() {
+		fmt.Fprintf(os.Stderr, "[!] %s\n", warning)
+	}
+
+	d := daemon.NightWatch(eulixDir, router)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		d.Shutdown()
+	}()
+
+	fmt.Printf("Ready in %s, listening on %s\n", time.Since(startTime).Round(time.Millisecond), daemon.SocketPath(eulixDir))
+	fmt.Println("Press Ctrl+C or run 'eulix daemon --stop' to stop")
+
+	if err := d.Serve(); err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon stopped")
+	return nil
+}
+
+func daemonStatus() error {
+	client, err := daemon.Dial(".eulix")
+	if err != nil {
+		fmt.Println("Daemon is not running")
+		return nil
+	}
+
+	status, err := client.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon is running")
+	fmt.Printf("  PID:     %d\n", status.PID)
+	fmt.Printf("  Uptime:  %s\n", time.Since(status.StartedAt).Round(time.Second))
+	fmt.Printf("  Queries: %d\n", status.Served)
+	fmt.Printf("  Search:  %s\n", status.Capabilities)
+	for _, warning := range status.Warnings {
+		fmt.Printf("  [!] %s\n", warning)
+	}
+	return nil
+}
+
+func daemonStop() error {
+	client, err := daemon.Dial(".eulix")
+	if err != nil {
+		fmt.Println("Daemon is not running")
+		return nil
+	}
+
+	if err := client.Stop(); err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon stopped")
+	return nil
+}
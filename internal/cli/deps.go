@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"eulix/internal/query"
+)
+
+// writeDependencyTree follows the calls to and from symbol and writes the
+// tree to output, stdout when it is "" or "-"
+func writeDependencyTree(symbol string, opts query.DependencyOptions, format, output string) error {
+	if format != "tree" && format != "dot" && format != "json" {
+		return fmt.Errorf("unknown format %q, use tree, dot or json", format)
+	}
+
+	graph, err := query.LoadCallGraph(".eulix")
+	if err != nil {
+		return err
+	}
+	tree, err := graph.Dependencies(symbol, opts)
+	if err != nil {
+		return err
+	}
+
+	var text string
+	switch format {
+	case "dot":
+		text = tree.DOT()
+	case "json":
+		data, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(data) + "\n"
+	default:
+		text = tree.Tree()
+	}
+
+	if output == "" || output == "-" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(output, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", output)
+	return nil
+}
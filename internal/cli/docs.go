@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"eulix/internal/version"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Man pages and the Markdown reference are written the way cobra/doc lays
+// them out, one file per command, without pulling go-md2man into the build
+
+var genDocsCmd = &cobra.Command{
+	Use:    "gen-docs",
+	Short:  "Write man pages and a Markdown command reference",
+	Long:   "Write a man page and a Markdown page for every command. Run by make docs. SOURCE_DATE_EPOCH, when set, dates the man pages so builds are reproducible.",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Example: `  eulix gen-docs --man build/man --markdown build/docs
+  man -l build/man/eulix-ask.1`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manDir, _ := cmd.Flags().GetString("man")
+		mdDir, _ := cmd.Flags().GetString("markdown")
+		if manDir == "" && mdDir == "" {
+			return fmt.Errorf("nothing to write, give --man, --markdown or both")
+		}
+
+		root := cmd.Root()
+		if manDir != "" {
+			if err := writeManPages(root, manDir, docsDate()); err != nil {
+				return failed("Man pages failed", err)
+			}
+			fmt.Printf("Man pages written to %s\n", manDir)
+		}
+		if mdDir != "" {
+			if err := writeMarkdown(root, mdDir); err != nil {
+				return failed("Markdown reference failed", err)
+			}
+			fmt.Printf("Markdown reference written to %s\n", mdDir)
+		}
+		return nil
+	},
+}
+
+// docsDate is SOURCE_DATE_EPOCH when it's set, otherwise now
+func docsDate() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Now()
+}
+
+// documented lists cmd and every command under it that help would show
+func documented(cmd *cobra.Command) []*cobra.Command {
+	out := []*cobra.Command{cmd}
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() || sub.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		out = append(out, documented(sub)...)
+	}
+	return out
+}
+
+// docsName is the command path joined by sep, eulix-cache-list for a man page
+func docsName(cmd *cobra.Command, sep string) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", sep)
+}
+
+// seeAlso is the parent and the subcommands of cmd
+func seeAlso(cmd *cobra.Command) []*cobra.Command {
+	var out []*cobra.Command
+	if cmd.HasParent() {
+		out = append(out, cmd.Parent())
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+func writeManPages(root *cobra.Command, dir string, date time.Time) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, cmd := range documented(root) {
+		path := filepath.Join(dir, docsName(cmd, "-")+".1")
+		if err := os.WriteFile(path, manPage(cmd, date), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manPage renders cmd as a section 1 man page in roff
+func manPage(cmd *cobra.Command, date time.Time) []byte {
+	var b bytes.Buffer
+	name := docsName(cmd, "-")
+
+	fmt.Fprintf(&b, ".TH %q \"1\" %q \"eulix %s\" \"Eulix Manual\"\n",
+		strings.ToUpper(name), date.Format("Jan 2006"), version.Version)
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", name, roffEscape(cmd.Short))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n", roffEscape(cmd.UseLine()))
+
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", roffText(description))
+
+	manFlags(&b, "OPTIONS", cmd.NonInheritedFlags())
+	manFlags(&b, "OPTIONS INHERITED FROM PARENT COMMANDS", cmd.InheritedFlags())
+
+	if cmd.Example != "" {
+		fmt.Fprintf(&b, ".SH EXAMPLES\n.PP\n.RS\n.nf\n%s\n.fi\n.RE\n", roffEscape(cmd.Example))
+	}
+
+	if related := seeAlso(cmd); len(related) > 0 {
+		refs := make([]string, len(related))
+		for i, c := range related {
+			refs[i] = fmt.Sprintf("\\fB%s\\fP(1)", docsName(c, "-"))
+		}
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", strings.Join(refs, ", "))
+	}
+	return b.Bytes()
+}
+
+func manFlags(b *bytes.Buffer, heading string, flags *pflag.FlagSet) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	fmt.Fprintf(b, ".SH %s\n", heading)
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		b.WriteString(".TP\n")
+		if f.Shorthand != "" {
+			fmt.Fprintf(b, "\\fB\\-%s\\fP, ", f.Shorthand)
+		}
+		fmt.Fprintf(b, "\\fB\\-\\-%s\\fP", roffEscape(f.Name))
+		if f.Value.Type() != "bool" {
+			fmt.Fprintf(b, " \\fI%s\\fP", f.Value.Type())
+		}
+		b.WriteString("\n" + roffEscape(f.Usage))
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "[]" {
+			fmt.Fprintf(b, " (default %s)", roffEscape(f.DefValue))
+		}
+		b.WriteString("\n")
+	})
+}
+
+// roffText escapes text and keeps its paragraphs and indented blocks
+func roffText(text string) string {
+	var out []string
+	for _, para := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if strings.HasPrefix(para, " ") {
+			out = append(out, ".PP\n.RS\n.nf\n"+roffEscape(para)+"\n.fi\n.RE")
+		} else {
+			out = append(out, ".PP\n"+roffEscape(para))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// roffEscape keeps backslashes, dashes and lines starting with a dot or a
+// quote from being read as roff
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func writeMarkdown(root *cobra.Command, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, cmd := range documented(root) {
+		path := filepath.Join(dir, docsName(cmd, "_")+".md")
+		if err := os.WriteFile(path, markdownPage(cmd), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownPage renders cmd the way cobra/doc's GenMarkdown does, so the
+// pages link to each other by file name
+func markdownPage(cmd *cobra.Command) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "## %s\n\n%s\n\n", cmd.CommandPath(), cmd.Short)
+	if cmd.Long != "" {
+		fmt.Fprintf(&b, "### Synopsis\n\n%s\n\n", cmd.Long)
+	}
+	if cmd.Runnable() {
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", cmd.UseLine())
+	}
+	if cmd.Example != "" {
+		fmt.Fprintf(&b, "### Examples\n\n```\n%s\n```\n\n", cmd.Example)
+	}
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options inherited from parent commands\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+	if related := seeAlso(cmd); len(related) > 0 {
+		b.WriteString("### SEE ALSO\n\n")
+		for _, c := range related {
+			fmt.Fprintf(&b, "* [%s](%s.md)\t - %s\n", c.CommandPath(), docsName(c, "_"), c.Short)
+		}
+	}
+	return b.Bytes()
+}
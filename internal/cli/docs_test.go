@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEveryCommandHasExamples(t *testing.T) {
+	for _, cmd := range documented(rootCmd)[1:] {
+		if cmd.Example == "" {
+			t.Errorf("%s has no Example", cmd.CommandPath())
+		}
+	}
+}
+
+func TestGenDocs(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeManPages(rootCmd, filepath.Join(dir, "man"), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMarkdown(rootCmd, filepath.Join(dir, "md")); err != nil {
+		t.Fatal(err)
+	}
+
+	man, err := os.ReadFile(filepath.Join(dir, "man", "eulix-cache-list.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`.TH "EULIX-CACHE-LIST" "1" "Jan 2026"`, `\fB\-\-verbose\fP`, ".SH EXAMPLES", `\fBeulix-cache\fP(1)`} {
+		if !strings.Contains(string(man), want) {
+			t.Errorf("man page is missing %q:\n%s", want, man)
+		}
+	}
+
+	md, err := os.ReadFile(filepath.Join(dir, "md", "eulix_cache.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(md), "[eulix cache list](eulix_cache_list.md)") {
+		t.Errorf("markdown doesn't link its subcommands:\n%s", md)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "md", "eulix_gen-docs.md")); err == nil {
+		t.Error("hidden gen-docs was documented")
+	}
+}
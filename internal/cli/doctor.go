@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eulix/internal/cache"
+	"eulix/internal/config"
+	"eulix/internal/fixers"
+	"eulix/internal/llm"
+	"eulix/internal/schema"
+)
+
+// doctorCheck is the outcome of one toolchain check, with a fix to print when it fails
+type doctorCheck struct {
+	name   string
+	ok     bool
+	detail string
+	fix    string
+}
+
+// runDoctor checks everything eulix depends on and prints a fix for each failure.
+// It returns the number of failed checks.
+func runDoctor() (int, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Println("Eulix doctor")
+	fmt.Println("================================")
+
+	var checks []doctorCheck
+	checks = append(checks, checkBinary("eulix_parser", cfg.Tools.ParserPath()))
+	checks = append(checks, checkBinary("eulix_embed", cfg.Tools.EmbedPath()))
+	checks = append(checks, checkLLM(cfg))
+	checks = append(checks, checkCache(cfg)...)
+	checks = append(checks, checkKnowledgeBase(".eulix"))
+
+	failed := 0
+	for _, c := range checks {
+		mark := "✓"
+		if !c.ok {
+			mark = "✗"
+			failed++
+		}
+		fmt.Printf("%s %-14s %s\n", mark, c.name, c.detail)
+		if !c.ok && c.fix != "" {
+			fmt.Printf("    fix: %s\n", c.fix)
+		}
+	}
+
+	// GLaDOS gives the detailed artifact report, only worth it when there is a KB
+	if _, err := os.Stat(".eulix/kb.json"); err == nil {
+		fmt.Println()
+		if err := fixers.GLaDOS(".eulix"); err != nil {
+			failed++
+		}
+	}
+
+	fmt.Println()
+	if failed == 0 {
+		fmt.Println("All checks passed")
+	} else {
+		fmt.Printf("%d check(s) failed\n", failed)
+	}
+
+	return failed, nil
+}
+
+func checkBinary(name, path string) doctorCheck {
+	c := doctorCheck{name: name, fix: "run 'eulix install-tools' or set [tools] in eulix.toml"}
+
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		c.detail = fmt.Sprintf("not found (%s)", path)
+		return c
+	}
+
+	cmd := exec.Command(resolved, "--help")
+	if err := cmd.Run(); err != nil {
+		c.detail = fmt.Sprintf("%s does not run: %v", resolved, err)
+		c.fix = "reinstall with 'eulix install-tools', the binary may be for another platform"
+		return c
+	}
+
+	c.ok = true
+	c.detail = resolved
+	return c
+}
+
+func checkLLM(cfg *config.Config) doctorCheck {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	if strings.EqualFold(cfg.LLM.Provider, llm.ProviderMock) {
+		return doctorCheck{name: "llm", ok: true, detail: "mock provider, answers are canned"}
+	}
+	if err := llm.CheckProvider(cfg.LLM); err != nil {
+		return doctorCheck{name: "llm", detail: err.Error(), fix: "set [llm] provider to anthropic, ollama or mock in eulix.toml"}
+	}
+	if cfg.LLM.Local {
+		return checkOllama(client, cfg)
+	}
+
+	return checkAPIKey(client, "Anthropic", "https://api.anthropic.com/v1/models", cfg.LLM.APIKey, func(req *http.Request) {
+		req.Header.Set("x-api-key", cfg.LLM.APIKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}, "ANTHROPIC_API_KEY")
+}
+
+func checkOllama(client *http.Client, cfg *config.Config) doctorCheck {
+	c := doctorCheck{name: "ollama"}
+
+	baseURL := cfg.LLM.BaseURL
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+
+	resp, err := client.Get(strings.TrimRight(baseURL, "/") + "/api/tags")
+	if err != nil {
+		c.detail = fmt.Sprintf("%s unreachable", baseURL)
+		c.fix = "start Ollama with 'ollama serve' or fix llm.baseURL in eulix.toml"
+		return c
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		c.detail = fmt.Sprintf("unexpected response from %s", baseURL)
+		c.fix = "check that llm.baseURL points at an Ollama server"
+		return c
+	}
+
+	for _, m := range tags.Models {
+		if m.Name == cfg.LLM.Model || strings.TrimSuffix(m.Name, ":latest") == cfg.LLM.Model {
+			c.ok = true
+			c.detail = fmt.Sprintf("%s has %s", baseURL, cfg.LLM.Model)
+			return c
+		}
+	}
+
+	c.detail = fmt.Sprintf("model %s not pulled", cfg.LLM.Model)
+	c.fix = fmt.Sprintf("run 'ollama pull %s'", cfg.LLM.Model)
+	return c
+}
+
+func checkAPIKey(client *http.Client, provider, url, key string, auth func(*http.Request), envVar string) doctorCheck {
+	c := doctorCheck{name: strings.ToLower(provider)}
+
+	if key == "" {
+		c.detail = "no API key"
+		c.fix = fmt.Sprintf("set %s or llm.api_key in eulix.toml", envVar)
+		return c
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		c.detail = err.Error()
+		return c
+	}
+	auth(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		c.detail = fmt.Sprintf("%s API unreachable: %v", provider, err)
+		c.fix = "check your network connection or proxy settings"
+		return c
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		c.detail = fmt.Sprintf("%s rejected the API key", provider)
+		c.fix = fmt.Sprintf("create a new key and set %s", envVar)
+	case resp.StatusCode != http.StatusOK:
+		c.detail = fmt.Sprintf("%s API returned %d", provider, resp.StatusCode)
+		c.fix = "try again later, the API may be having issues"
+	default:
+		c.ok = true
+		c.detail = "API key valid"
+	}
+	return c
+}
+
+func checkCache(cfg *config.Config) []doctorCheck {
+	var checks []doctorCheck
+
+	if cfg.Cache.Redis.Enabled {
+		only := *cfg
+		only.Cache.SQL.Enabled = false
+		checks = append(checks, checkCacheBackend("redis", &only,
+			fmt.Sprintf("start Redis or fix cache.redis in eulix.toml (%s)", cfg.Cache.Redis.URL)))
+	}
+	if cfg.Cache.SQL.Enabled {
+		only := *cfg
+		only.Cache.Redis.Enabled = false
+		checks = append(checks, checkCacheBackend("sqlite", &only,
+			"check that the cache.sql path is writable, or run 'eulix cache clear'"))
+	}
+
+	return checks
+}
+
+func checkCacheBackend(name string, cfg *config.Config, fix string) doctorCheck {
+	manager, err := cache.CacheController(cfg)
+	if err != nil {
+		return doctorCheck{name: name, detail: err.Error(), fix: fix}
+	}
+	manager.Close()
+	return doctorCheck{name: name, ok: true, detail: "reachable"}
+}
+
+func checkKnowledgeBase(eulixDir string) doctorCheck {
+	c := doctorCheck{name: "knowledge base", fix: "run 'eulix analyze'"}
+
+	if _, err := os.Stat(filepath.Join(eulixDir, "kb.json")); err != nil {
+		c.detail = "not built yet"
+		return c
+	}
+
+	if err := schema.Check(eulixDir); err != nil {
+		c.detail = err.Error()
+		c.fix = "run 'eulix migrate' or 'eulix analyze'"
+		return c
+	}
+
+	if missing := checkEmbeddingsFiles(eulixDir); len(missing) > 0 {
+		c.detail = fmt.Sprintf("missing files:\n%s", strings.Join(missing, "\n"))
+		return c
+	}
+
+	c.ok = true
+	c.detail = "artifacts present, schema current"
+	return c
+}
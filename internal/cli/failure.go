@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"eulix/internal/fault"
+
+	"github.com/spf13/cobra"
+)
+
+// Failure formats, see setFailureFormat
+const (
+	failureText  = "text"
+	failureJSON  = "json"
+	failureQuiet = "quiet"
+)
+
+// failureFormat is how Execute reports a failed command
+var failureFormat = failureText
+
+// errChecksFailed is doctor finding a problem, the checks say which
+var errChecksFailed = errors.New("some checks failed")
+
+// commandError is a command failing at what it was asked to do, as opposed
+// to being called wrong. Msg says what failed.
+type commandError struct {
+	Msg string
+	Err error
+}
+
+func (e *commandError) Error() string {
+	if e.Msg == "" {
+		return e.Err.Error()
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *commandError) Unwrap() error { return e.Err }
+
+// failed is what a command's RunE returns when it fails
+func failed(msg string, err error) error {
+	return &commandError{Msg: msg, Err: err}
+}
+
+// exitStatus is a status a command picked itself after reporting why,
+// like eulix ci
+type exitStatus int
+
+func (s exitStatus) Error() string { return fmt.Sprintf("exit status %d", int(s)) }
+
+// exitWith is what RunE returns to end with status, nil for zero
+func exitWith(status int) error {
+	if status == 0 {
+		return nil
+	}
+	return exitStatus(status)
+}
+
+// failureReport is what a failure looks like with --json or --format json
+type failureReport struct {
+	Error    string `json:"error"`
+	Code     string `json:"code"` // see fault.Code, "error" for anything else
+	ExitCode int    `json:"exit_code"`
+}
+
+// setFailureFormat picks how the command being run reports errors: nothing
+// with --quiet, JSON when its output is JSON
+func setFailureFormat(cmd *cobra.Command) {
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	format, _ := cmd.Flags().GetString("format")
+	switch {
+	case quiet:
+		failureFormat = failureQuiet
+	case asJSON || format == "json":
+		failureFormat = failureJSON
+	}
+}
+
+// reportFailure writes err to stderr in the failure format and returns the
+// exit status for it, see fault.ExitCode
+func reportFailure(err error) int {
+	status := fault.ExitCode(err)
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) && fault.Code(err) == "" {
+		status = fault.ExitUsage
+	}
+
+	switch failureFormat {
+	case failureQuiet:
+	case failureJSON:
+		code := fault.Code(err)
+		if code == "" {
+			code = "error"
+		}
+		data, _ := json.Marshal(failureReport{Error: err.Error(), Code: code, ExitCode: status})
+		fmt.Fprintln(os.Stderr, string(data))
+	default:
+		if errors.As(err, &cmdErr) && cmdErr.Msg != "" {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+	return status
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"eulix/internal/fault"
+)
+
+func TestCheckInitialized(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := checkInitialized(); !errors.Is(err, fault.ErrNotInitialized) {
+		t.Fatalf("empty directory: err = %v, want ErrNotInitialized", err)
+	}
+	os.Mkdir(".eulix", 0755)
+	os.WriteFile(".euignore", nil, 0644)
+	if err := checkInitialized(); !errors.Is(err, fault.ErrNotInitialized) {
+		t.Fatalf("without eulix.toml: err = %v, want ErrNotInitialized", err)
+	}
+	os.WriteFile("eulix.toml", nil, 0644)
+	if err := checkInitialized(); err != nil {
+		t.Fatalf("initialized directory: %v", err)
+	}
+}
+
+func TestReportFailureStatus(t *testing.T) {
+	failureFormat = failureQuiet
+	defer func() { failureFormat = failureText }()
+
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{failed("Query failed", errors.New("boom")), fault.ExitFailure},
+		{failed("Query failed", fmt.Errorf("%w: dial tcp", fault.ErrProviderUnavailable)), fault.ExitProvider},
+		{fmt.Errorf("%w, please run: eulix init", fault.ErrNotInitialized), fault.ExitKB},
+		{errors.New("unknown flag: --bogus"), fault.ExitUsage},
+	}
+	for _, c := range cases {
+		if got := reportFailure(c.err); got != c.want {
+			t.Errorf("reportFailure(%v) = %d, want %d", c.err, got, c.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"eulix/internal/cache"
 	"eulix/internal/checksum"
 	"eulix/internal/config"
+	"eulix/internal/crash"
 	"eulix/internal/tui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -59,6 +60,19 @@ func CacheStats() error {
 			fmt.Printf("  Status: Disconnected\n")
 		}
 		fmt.Printf("  TTL: %d hours\n", cfg.Cache.Redis.TTLHours)
+		if cfg.Cache.Redis.Shared {
+			access := cfg.Cache.Redis.Access
+			if access == "" {
+				access = cache.AccessReadWrite
+			}
+			fmt.Printf("  Shared: yes (%s)\n", access)
+			detector := checksum.HashHound(".")
+			stored, err := detector.Load()
+			current, cerr := detector.Calculate()
+			if access != cache.AccessReadOnly && (err != nil || cerr != nil || detector.CompareChecksums(stored, current) > 0) {
+				fmt.Printf("  Writes: paused until 'eulix analyze', the KB is behind your files\n")
+			}
+		}
 	}
 
 	// Show current checksum
@@ -169,7 +183,7 @@ func CacheTest() error {
 	testResponse := "This is a test response"
 
 	fmt.Print("  Writing test entry... ")
-	if err := cacheManager.Set(testQuery, testResponse, current.Hash); err != nil {
+	if err := cacheManager.Set(testQuery, testResponse, current.Hash, nil, "", ""); err != nil {
 		fmt.Printf("❌ Failed: %v\n", err)
 		return err
 	}
@@ -279,20 +293,19 @@ func CacheView() error {
 	}
 	defer cacheManager.Close()
 
-	// Get all entries
-	entries, err := cacheManager.ListAll()
+	groups, err := cacheManager.History()
 	if err != nil {
 		return fmt.Errorf("failed to list cache entries: %w", err)
 	}
 
-	if len(entries) == 0 {
+	if len(groups) == 0 {
 		fmt.Println("📭 No cache entries found")
 		return nil
 	}
 
 	// Launch TUI
-	model := tui.HistoryView(entries, cacheManager)
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	model := tui.HistoryView(groups, cacheManager).WithKeys(tui.Locksmith(cfg.TUI.Keys))
+	p := tea.NewProgram(crash.Guard(model), tea.WithAltScreen(), tea.WithoutCatchPanics())
 
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("TUI error: %w", err)
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eulix/internal/checksum"
+	"eulix/internal/config"
+	"eulix/internal/lock"
+	"eulix/internal/remote"
+	"eulix/internal/schema"
+	"eulix/internal/snapshot"
+)
+
+// indexObject is the snapshot archive in the remote store, with its sha256
+// next to it in sha256sum format
+const indexObject = "eulix-index.tar.gz"
+
+// indexStore opens location, or [index] remote when it is empty
+func indexStore(location string) (remote.Store, error) {
+	if location == "" {
+		if cfg, err := config.Load(); err == nil {
+			location = cfg.Index.Remote
+		}
+	}
+	if location == "" {
+		return nil, fmt.Errorf("no index location, pass one or set [index] remote in eulix.toml")
+	}
+	return remote.Courier(location)
+}
+
+func pushIndex(eulixDir, location string) error {
+	store, err := indexStore(location)
+	if err != nil {
+		return err
+	}
+
+	l, err := lock.Acquire(eulixDir, "push-index")
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	tmp, err := os.MkdirTemp("", "eulix-index-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	fmt.Println("Packing knowledge base...")
+	info, err := snapshot.Write(eulixDir, "index", filepath.Join(tmp, indexObject))
+	if err != nil {
+		return err
+	}
+	sum, err := fileSHA256(info.Path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(info.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Printf("Uploading %.2f MB to %s...\n", float64(info.Size)/(1024*1024), store)
+	if err := store.Put(indexObject, f, info.Size); err != nil {
+		return err
+	}
+
+	// The sum goes last, a pull racing this push sees a mismatch and fails
+	// instead of restoring a half updated index
+	line := fmt.Sprintf("%s  %s\n", sum, indexObject)
+	if err := store.Put(indexObject+".sha256", strings.NewReader(line), int64(len(line))); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pushed index (%d files, schema v%d, sha256 %s)\n", len(info.Files), info.SchemaVersion, sum[:12])
+	return nil
+}
+
+func pullIndex(eulixDir, location string, force bool) error {
+	store, err := indexStore(location)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(eulixDir, "kb.json")); err == nil && !force {
+		if !promptConfirm("Replace the knowledge base in " + eulixDir + " with the one from " + store.String() + "?") {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	want, err := remoteSum(store)
+	if err != nil {
+		return err
+	}
+
+	l, err := lock.Acquire(eulixDir, "pull-index")
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	fmt.Printf("Downloading index from %s...\n", store)
+	archive, err := downloadIndex(store, eulixDir, want)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+
+	info, err := snapshot.Restore(eulixDir, archive)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored index from %s (%d files, schema v%d)\n", info.CreatedAt.Format("2006-01-02 15:04"), len(info.Files), info.SchemaVersion)
+
+	if err := schema.Check(eulixDir); err != nil {
+		fmt.Printf("Note: %v\n", err)
+	}
+	return checkPulledIndex()
+}
+
+// remoteSum reads the sha256 pushed next to the archive
+func remoteSum(store remote.Store) (string, error) {
+	rc, err := store.Get(indexObject + ".sha256")
+	if errors.Is(err, remote.ErrNotFound) {
+		return "", fmt.Errorf("no index at %s, push one with 'eulix push-index'", store)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	line, err := bufio.NewReader(io.LimitReader(rc, 1024)).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("%s/%s.sha256 is not a sha256 sum", store, indexObject)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// downloadIndex fetches the archive into eulixDir and returns its path, only
+// if its sha256 matches want
+func downloadIndex(store remote.Store, eulixDir, want string) (string, error) {
+	rc, err := store.Get(indexObject)
+	if errors.Is(err, remote.ErrNotFound) {
+		return "", fmt.Errorf("%s has a checksum but no %s, the push may have failed", store, indexObject)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp(eulixDir, ".pull-*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), rc); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("checksum mismatch: expected %s, got %s (a push may be in progress, try again)", want, got)
+	}
+	return tmp.Name(), nil
+}
+
+// checkPulledIndex compares the pulled checksum.json with the files here.
+// File mtimes are from this checkout, not the machine that analyzed, so the
+// analyze time is moved to now when every file matches; otherwise each file
+// would count as stale.
+func checkPulledIndex() error {
+	detector := checksum.HashHound(".")
+	stored, err := detector.Load()
+	if err != nil {
+		fmt.Println("Note: the index has no checksum.json, it can't be checked against your files")
+		return nil
+	}
+
+	fmt.Println("Checking the index against your files...")
+	current, err := detector.Calculate()
+	if err != nil {
+		return fmt.Errorf("failed to hash source files: %w", err)
+	}
+
+	differ := 0
+	for file, hash := range current.FileHashes {
+		if stored.FileHashes[file] != hash {
+			differ++
+		}
+	}
+	for file := range stored.FileHashes {
+		if _, ok := current.FileHashes[file]; !ok {
+			differ++
+		}
+	}
+
+	if differ > 0 {
+		fmt.Printf("%d files differ from the index, run 'eulix analyze' to bring it up to date\n", differ)
+		return nil
+	}
+
+	stored.LastAnalyzed = time.Now()
+	stored.ProjectPath = current.ProjectPath
+	if err := detector.Save(stored); err != nil {
+		return err
+	}
+	fmt.Printf("Index matches all %d files\n", len(current.FileHashes))
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -40,6 +40,18 @@ path = "."
 
 [parser]
 threads = 4
+# Skip files over this size (0 means no limit), and minified or generated
+# files like bundles and protobuf output
+max_file_kb = 1024
+skip_generated = true
+# READMEs, docs/ and notebooks are indexed too, and so are Dockerfiles,
+# compose, Kubernetes, workflow and Terraform files. Set to leave them out.
+skip_docs = false
+skip_infra = false
+# Index only part of the repo, globs are relative to the project root
+# include = ["backend"]
+# exclude = ["backend/migrations", "**/*_generated.go"]
+# languages = ["go", "python"]
 
 [embeddings]
 model = "BAAI/bge-small-en-v1.5"
@@ -53,6 +65,17 @@ model = "llama3.2:3b"
 max_tokens = 8192
 temperature = 0.7
 baseURL = "http://localhost:11434"
+# On large projects, strategy = "map_reduce" answers architecture and
+# understanding questions from several windows of context: summary_model
+# (a smaller, cheaper model) summarizes each for the question, then model
+# answers over the summaries. One extra call per window, up to map_groups.
+strategy = "direct"
+# summary_model = "llama3.2:1b"
+# map_groups = 4
+# Questions in other languages are translated to English for search and
+# answered in their language, by translate_model if set.
+translate = "auto"
+# translate_model = "llama3.2:1b"
 
 # To use Anthropic Claude instead, change to:
 # local = false
@@ -60,20 +83,152 @@ baseURL = "http://localhost:11434"
 # model = "claude-3-5-sonnet-20241022"
 # api_key = ""  # or set ANTHROPIC_API_KEY environment variable
 
+# Reuse the response when the exact same prompt is sent again (retries,
+# repeated review passes). Kept in memory, separate from [cache] below.
+[llm.response_cache]
+enabled = false
+max_entries = 256
+ttl_minutes = 60
+
+# Per-query limits, 0 means none. A query over max_prompt_tokens or max_cost
+# asks for confirmation first, or with on_exceed = "reduce" drops the least
+# relevant context until it fits. max_seconds stops the answer and keeps what
+# was generated. Cost is estimated from the prices, in dollars per million
+# tokens, with max_tokens as the worst case output.
+[llm.budget]
+max_prompt_tokens = 0
+max_cost = 0.0
+max_seconds = 0
+input_price = 0.0
+output_price = 0.0
+on_exceed = "confirm"
+
+# What hosted providers get to see. Secrets (API keys, passwords, tokens,
+# private keys, .env values) are masked before sending; add your own regexes
+# to patterns. anonymize_identifiers swaps your function and type names for
+# aliases and maps them back in the answer. local_only refuses to send code
+# to anything but a model on this machine, like --local-only.
+[llm.privacy]
+redact = true
+patterns = []
+anonymize_identifiers = false
+local_only = false
+# Record provider, model, size, files and sha256 of every prompt sent to a
+# hosted provider, the code itself is not stored. See eulix audit log.
+audit = true
+audit_dir = ".eulix/audit"
+
 [cache]
 [cache.redis]
 enabled = false
 url = "redis://localhost:6379"
 ttl_hours = 6
+# Set shared when several people point at this Redis. Answers are then kept
+# per codebase checksum, nobody's entries get replaced, and answers are only
+# shared from a KB that is up to date with its files.
+shared = false
+access = "read-write"  # or "read-only" to use the team's answers without adding yours
+user = ""              # name on the entries you write, default $USER
 
 [cache.sql]
 enabled = true
 driver = "sqlite"
 dsn = ".eulix/history.db"
+ttl_hours = 24
+
+# How long answers of one query type live, in both backends. Built in:
+# location, usage and dependency answers keep 7 days, debug answers 1 hour,
+# neither longer than the backend's ttl_hours.
+# [cache.types.location]
+# ttl_hours = 168
+# [cache.types.debug]
+# ttl_hours = 0.5
 
 [checksum]
 change_threshold = 0.10
 force_reanalyze_threshold = 0.30
+
+# Prebuilt KB for 'eulix push-index' / 'eulix pull-index', e.g. from CI.
+# s3:// uses AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_REGION
+# (AWS_ENDPOINT_URL for MinIO or R2), gs:// uses GOOGLE_OAUTH_ACCESS_TOKEN
+# or your gcloud login.
+[index]
+remote = ""
+
+# Where eulix_parser and eulix_embed live. Empty means ~/.eulix/bin, then PATH.
+# 'eulix install-tools' fills this in.
+[tools]
+bin_dir = ""
+# Stop eulix_parser or eulix_embed when a run of analyze takes longer or
+# uses more memory (Linux only) than this. 0 means no limit.
+timeout_minutes = 120
+memory_limit_mb = 0
+
+# Chat bots, run with: eulix bot slack / eulix bot discord
+[integrations.slack]
+enabled = false
+listen = ":3000"
+signing_secret = ""  # or set SLACK_SIGNING_SECRET environment variable
+default_repo = "."
+
+# Map channels to other analyzed repos
+# [integrations.slack.channels.C0123456789]
+# repo = "/srv/repos/backend"
+# source_url = "https://github.com/acme/backend/blob/main"
+
+[integrations.discord]
+enabled = false
+listen = ":3001"
+public_key = ""  # or set DISCORD_PUBLIC_KEY environment variable
+default_repo = "."
+
+[tui]
+# Capture the mouse in chat: the wheel scrolls and clicking a message selects
+# it for copy. Off leaves the mouse to the terminal for text selection.
+# /mouse toggles it during a session.
+mouse = false
+
+# Chat and history keys, in bubbletea key names. Leave an action out to keep
+# its default, set it to [] to unbind it.
+[tui.keys]
+# quit = ["ctrl+c", "esc"]
+# back = ["esc", "b"]            # leave the history detail view
+# up = ["up", "k"]               # history list
+# down = ["down", "j"]
+# scroll_up = ["ctrl+up"]        # chat and history detail
+# scroll_down = ["ctrl+down"]
+# page_up = ["pgup"]
+# page_down = ["pgdown"]
+# open = ["enter"]
+# delete = ["d", "delete"]
+# history = ["ctrl+o"]
+# copy = ["ctrl+y"]              # last answer, or the open history entry
+# note = ["n"]                   # notes on the open history entry
+# group = ["tab"]                # show a question's older answers in history
+# mark = ["space"]               # mark history entries for a bulk delete or export
+# mark_all = ["a"]
+# export = ["x"]                 # marked entries to .eulix/exports as Markdown
+# expired = ["e"]                # list only expired history entries
+# pin = ["p"]                    # pin a history entry to eulix notes
+# correct = ["c"]                # attach a correction to the open history entry
+# reanalyze = ["ctrl+r"]
+# interrupt = ["esc"]            # stop an answer, keeping what was generated
+# context_panel = ["f2"]         # sources and confidence behind the last answer
+# stats_panel = ["f3"]
+# panel_grow = ["shift+left"]    # widen the side panels
+# panel_shrink = ["shift+right"]
+
+# Hooks run on pre-query, post-answer and post-analyze. Commands get the event
+# as JSON on stdin, urls get it POSTed. Printing/returning {"query": ...} or
+# {"answer": ...} rewrites the query or answer.
+# [[hooks]]
+# event = "post-answer"
+# command = "./scripts/log-answer.sh"
+# timeout_seconds = 10
+#
+# [[hooks]]
+# event = "post-analyze"
+# url = "https://hooks.example.com/eulix"
 `
 		if err := os.WriteFile(configPath, []byte(defaultConfig), 0644); err != nil {
 			return fmt.Errorf("failed to create config: %w", err)
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"eulix/internal/config"
+	"eulix/internal/installer"
+)
+
+func installTools(version, dir, baseURL string) error {
+	if dir == "" {
+		def, err := config.DefaultToolsDir()
+		if err != nil {
+			return fmt.Errorf("failed to find home directory: %w", err)
+		}
+		dir = def
+	}
+
+	installed, err := installer.Install(installer.Options{
+		Version: version,
+		BaseURL: baseURL,
+		Dir:     dir,
+		Out:     os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("Installed %d tools to %s\n", len(installed), dir)
+
+	// Point the project config at the new binaries when there is one
+	if _, err := os.Stat("eulix.toml"); err == nil {
+		if err := config.SetToolsBinDir("eulix.toml", dir); err != nil {
+			return fmt.Errorf("installed, but failed to update eulix.toml: %w", err)
+		}
+		fmt.Println("Updated [tools] bin_dir in eulix.toml")
+	}
+
+	return nil
+}
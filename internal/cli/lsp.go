@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"eulix/internal/config"
+	"eulix/internal/daemon"
+	"eulix/internal/editor"
+)
+
+// runEditorServer serves editor requests over stdin/stdout. Stdout belongs to
+// the protocol, so everything else goes to stderr.
+func runEditorServer() error {
+	eulixDir := ".eulix"
+
+	var querier editor.Querier
+	if client, err := daemon.Dial(eulixDir); err == nil {
+		fmt.Fprintln(os.Stderr, "eulix lsp: using running daemon")
+		querier = client
+	} else {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		router, cleanup, err := buildRouter(".", cfg)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		querier = router
+	}
+
+	server := editor.Diplomat(querier, os.Stdin, os.Stdout)
+	return server.Serve()
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/atlas"
+	"eulix/internal/config"
+	"eulix/internal/llm"
+)
+
+// writeModuleMap clusters the KB, names the clusters unless noLLM and
+// writes the map to output, stdout when it is "" or "-"
+func writeModuleMap(format, output string, noLLM bool) error {
+	if format != "markdown" && format != "mermaid" && format != "json" {
+		return fmt.Errorf("unknown format %q, use markdown, mermaid or json", format)
+	}
+
+	project := "this project"
+	if wd, err := os.Getwd(); err == nil {
+		project = filepath.Base(wd)
+	}
+	m, err := atlas.Build(project, ".eulix")
+	if err != nil {
+		return err
+	}
+	if len(m.Clusters) == 0 {
+		return fmt.Errorf("nothing to cluster, the knowledge base has no vectors or calls between files")
+	}
+
+	if !noLLM {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		client, err := llm.MouthClient(cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Naming %d clusters with %s...\n", len(m.Clusters), cfg.LLM.Model)
+		if err := m.Name(client); err != nil {
+			return err
+		}
+	}
+
+	var text string
+	switch format {
+	case "mermaid":
+		text = m.Mermaid()
+	case "json":
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(data) + "\n"
+	default:
+		text = m.Markdown()
+	}
+
+	if output == "" || output == "-" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(output, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d clusters)\n", output, len(m.Clusters))
+	return nil
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"eulix/internal/lock"
+	"eulix/internal/schema"
+)
+
+func migrateKnowledgeBase(eulixDir string) error {
+	l, err := lock.Acquire(eulixDir, "migrate")
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	from, err := schema.Migrate(eulixDir, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if from == schema.Version {
+		fmt.Printf("Knowledge base is already at schema v%d\n", schema.Version)
+		return nil
+	}
+
+	fmt.Printf("Migrated knowledge base from v%d to v%d\n", from, schema.Version)
+	return nil
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func pinAnswer(queryHash string) error {
+	mgr, err := initCacheManager()
+	if err != nil {
+		return err
+	}
+	defer mgr.Close()
+
+	fav, err := mgr.Pin(queryHash)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pinned: %s\n", truncateString(fav.Query, 70))
+	return nil
+}
+
+func unpinAnswer(queryHash string) error {
+	mgr, err := initCacheManager()
+	if err != nil {
+		return err
+	}
+	defer mgr.Close()
+
+	if err := mgr.Unpin(queryHash); err != nil {
+		return err
+	}
+	fmt.Printf("Unpinned %s\n", queryHash)
+	return nil
+}
+
+// correctAnswer attaches a correction to queryHash, an empty one removes it
+func correctAnswer(queryHash, correction string) error {
+	mgr, err := initCacheManager()
+	if err != nil {
+		return err
+	}
+	defer mgr.Close()
+
+	if err := mgr.Correct(queryHash, correction); err != nil {
+		return err
+	}
+	if strings.TrimSpace(correction) == "" {
+		fmt.Printf("Correction removed from %s\n", queryHash)
+	} else {
+		fmt.Println("Correction saved, it is shown with this answer until the next analyze")
+	}
+	return nil
+}
+
+// showNotes prints the pinned answers matching search, as text or as a
+// Markdown document
+func showNotes(search string, markdown bool) error {
+	mgr, err := initCacheManager()
+	if err != nil {
+		return err
+	}
+	defer mgr.Close()
+
+	favorites, err := mgr.Favorites(search)
+	if err != nil {
+		return err
+	}
+	if len(favorites) == 0 {
+		if search != "" {
+			fmt.Printf("No notes match %q.\n", search)
+		} else {
+			fmt.Println("No notes yet. Pin an answer with p in eulix history or with eulix history pin <hash>.")
+		}
+		return nil
+	}
+
+	if markdown {
+		fmt.Printf("# eulix notes, %s\n", time.Now().Format("2006-01-02"))
+		for _, fav := range favorites {
+			fmt.Printf("\n## %s\n\n", strings.TrimSpace(fav.Query))
+			fmt.Printf("_Answered %s, pinned %s_\n\n", fav.CreatedAt.Format("2006-01-02"), fav.PinnedAt.Format("2006-01-02"))
+			fmt.Println(strings.TrimSpace(fav.Response))
+			if fav.Notes != "" {
+				fmt.Printf("\n> Notes: %s\n", fav.Notes)
+			}
+			if len(fav.Sources) > 0 {
+				fmt.Printf("\nSources: %s\n", strings.Join(fav.Sources, ", "))
+			}
+		}
+		return nil
+	}
+
+	fmt.Printf("Notes (%d pinned):\n", len(favorites))
+	fmt.Println(strings.Repeat("=", 80))
+	for _, fav := range favorites {
+		fmt.Printf("\nQuery:\n%s\n", wrapText(fav.Query, 76))
+		fmt.Printf("Pinned: %s • Hash: %s\n", fav.PinnedAt.Format("2006-01-02 15:04"), fav.QueryHash)
+		if fav.Notes != "" {
+			fmt.Printf("\nNotes:\n%s\n", wrapText(fav.Notes, 76))
+		}
+		fmt.Printf("\nResponse:\n%s\n", wrapText(fav.Response, 76))
+		if len(fav.Sources) > 0 {
+			fmt.Printf("\nSources: %s\n", strings.Join(fav.Sources, ", "))
+		}
+		fmt.Println(strings.Repeat("-", 80))
+	}
+	return nil
+}
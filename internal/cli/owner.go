@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eulix/internal/owners"
+	"eulix/internal/query"
+)
+
+// showOwner prints who owns target, a file or a function or type name from
+// the KB index
+func showOwner(target string) error {
+	m, err := owners.Load(".eulix")
+	if err != nil {
+		return err
+	}
+	if m.Empty() {
+		return fmt.Errorf("no ownership rules, add a CODEOWNERS file and run 'eulix analyze', or write .eulix/owners.toml")
+	}
+
+	locations, err := ownerTargets(target)
+	if err != nil {
+		return err
+	}
+
+	for _, loc := range locations {
+		file := loc
+		if i := strings.LastIndex(loc, ":"); i > 0 {
+			file = loc[:i]
+		}
+		fmt.Println(loc)
+		rule := m.Of(file)
+		switch {
+		case rule == nil:
+			fmt.Println("  no owner")
+		case len(rule.Owners) == 0:
+			fmt.Printf("  no owner (%s %s)\n", rule.Source, rule.Pattern)
+		default:
+			fmt.Printf("  owned by %s (%s %s)\n", strings.Join(rule.Owners, ", "), rule.Source, rule.Pattern)
+		}
+	}
+	return nil
+}
+
+// ownerTargets is target itself when it is a file in the project, else
+// where the symbol is defined as file:line
+func ownerTargets(target string) ([]string, error) {
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return nil, err
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(wd, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("%s is outside the project", target)
+		}
+		return []string{filepath.ToSlash(rel)}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(".eulix", "kb_index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a file and the knowledge base isn't built, run 'eulix analyze' first", target)
+	}
+	var index query.KBIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse kb_index.json: %w", err)
+	}
+
+	locations := append(index.FunctionsByName[target], index.TypesByName[target]...)
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("%s is neither a file nor a function or type in the knowledge base", target)
+	}
+	return locations, nil
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"eulix/internal/config"
+)
+
+// writeRefactorPlan plans goal and writes it as markdown or json to output,
+// stdout when it is "" or "-"
+func writeRefactorPlan(goal, format, output string) error {
+	if format != "markdown" && format != "json" {
+		return fmt.Errorf("unknown format %q, use markdown or json", format)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	router, cleanup, err := buildRouter(".", cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	fmt.Fprintf(os.Stderr, "Planning with %s...\n", cfg.LLM.Model)
+	plan, err := router.PlanRefactor(goal)
+	if err != nil {
+		return err
+	}
+
+	text := plan.Markdown()
+	if format == "json" {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(data) + "\n"
+	}
+
+	if output == "" || output == "-" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(output, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d tasks)\n", output, len(plan.Tasks))
+	return nil
+}
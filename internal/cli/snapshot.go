@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"eulix/internal/lock"
+	"eulix/internal/schema"
+	"eulix/internal/snapshot"
+)
+
+func createSnapshot(eulixDir, name string, overwrite bool) error {
+	if name == "" {
+		name = snapshot.DefaultName()
+	}
+
+	// An analyze writing the KB halfway through would leave a mixed snapshot
+	l, err := lock.Acquire(eulixDir, "snapshot")
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	info, err := snapshot.Create(eulixDir, name, overwrite)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created snapshot %s (%d files, %.2f MB)\n", info.Name, len(info.Files), float64(info.Size)/(1024*1024))
+	fmt.Printf("  %s\n", info.Path)
+	return nil
+}
+
+func listSnapshots(eulixDir string) error {
+	snapshots, err := snapshot.List(eulixDir)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots found. Create one with: eulix snapshot create [name]")
+		return nil
+	}
+
+	fmt.Printf("Found %d snapshots:\n\n", len(snapshots))
+	for _, s := range snapshots {
+		fmt.Printf("%-24s %s  schema v%d  %d files  %.2f MB\n",
+			s.Name, s.CreatedAt.Format("2006-01-02 15:04"), s.SchemaVersion, len(s.Files), float64(s.Size)/(1024*1024))
+	}
+	return nil
+}
+
+func restoreSnapshot(eulixDir, nameOrPath string, force bool) error {
+	path, err := snapshot.Resolve(eulixDir, nameOrPath)
+	if err != nil {
+		return err
+	}
+	info, err := snapshot.Read(path)
+	if err != nil {
+		return err
+	}
+
+	if !force && !promptConfirm(fmt.Sprintf("Replace the knowledge base in %s with snapshot %s from %s?",
+		eulixDir, info.Name, info.CreatedAt.Format("2006-01-02 15:04"))) {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
+
+	l, err := lock.Acquire(eulixDir, "snapshot restore")
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	if _, err := snapshot.Restore(eulixDir, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored snapshot %s (%d files) from %s\n", info.Name, len(info.Files), filepath.Base(path))
+	if err := schema.Check(eulixDir); err != nil {
+		fmt.Printf("Note: %v\n", err)
+	}
+	return nil
+}
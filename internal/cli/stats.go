@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"eulix/internal/config"
+	"eulix/internal/fixers"
+)
+
+// showStats prints the KB dashboard. Cache figures need the SQL cache, the
+// query log only lives there.
+func showStats(asJSON bool) error {
+	stats, err := fixers.Census(".eulix")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Cache.SQL.Enabled {
+		if mgr, err := initCacheManager(); err != nil {
+			fmt.Fprintf(os.Stderr, "Cache figures left out: %v\n", err)
+		} else {
+			defer mgr.Close()
+			if usage, err := mgr.Usage(); err != nil {
+				fmt.Fprintf(os.Stderr, "Cache figures left out: %v\n", err)
+			} else {
+				stats.Cache = &usage
+			}
+		}
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	fmt.Print(stats.Table())
+	return nil
+}
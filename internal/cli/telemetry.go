@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"eulix/internal/llm"
+	"eulix/internal/lock"
+	"eulix/internal/remote"
+	"eulix/internal/telemetry"
+)
+
+func runTelemetry(action string) error {
+	switch action {
+	case "on":
+		if _, err := telemetry.SetEnabled(true); err != nil {
+			return err
+		}
+		fmt.Println("Telemetry is on, thanks! Only command counts, coarse timings and error kinds are sent.")
+		fmt.Println("See what is collected with: eulix telemetry status")
+		if telemetry.Disabled() {
+			fmt.Println("Note: DO_NOT_TRACK or EULIX_TELEMETRY=off is set, nothing is collected while it is.")
+		}
+		return nil
+	case "off":
+		if _, err := telemetry.SetEnabled(false); err != nil {
+			return err
+		}
+		fmt.Println("Telemetry is off, unsent counts were deleted.")
+		return nil
+	case "status":
+		return telemetryStatus()
+	default:
+		return fmt.Errorf("unknown action %q, use on, off or status", action)
+	}
+}
+
+func telemetryStatus() error {
+	settings, err := telemetry.Load()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case telemetry.Disabled():
+		fmt.Println("Telemetry: off (DO_NOT_TRACK or EULIX_TELEMETRY=off is set)")
+	case settings.Enabled:
+		fmt.Println("Telemetry: on")
+	default:
+		fmt.Println("Telemetry: off")
+		fmt.Println("Turn it on with: eulix telemetry on")
+		return nil
+	}
+	fmt.Printf("Endpoint:  %s\n", telemetry.Endpoint())
+
+	report, err := telemetry.Pending(settings)
+	if err != nil {
+		return err
+	}
+	if len(report.Commands) == 0 {
+		fmt.Println("\nNothing collected since the last report.")
+		return nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nNext report:\n%s\n", data)
+	return nil
+}
+
+// errorCategory names the kind of a command's error for telemetry, eulix's
+// own errors first
+func errorCategory(err error) string {
+	var held *lock.HeldError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, errChecksFailed):
+		return "doctor_checks"
+	case errors.As(err, &held):
+		return "locked"
+	case errors.Is(err, llm.ErrLocalOnly):
+		return "local_only"
+	case errors.Is(err, llm.ErrInterrupted):
+		return "interrupted"
+	case errors.Is(err, remote.ErrNotFound):
+		return "not_found"
+	default:
+		return telemetry.Category(err)
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/config"
+	"eulix/internal/crash"
+	"eulix/internal/llm"
+	"eulix/internal/tour"
+	"eulix/internal/tui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tourOptions are the eulix tour flags
+type tourOptions struct {
+	TUI    bool
+	NoLLM  bool
+	Format string // markdown or json
+	Output string // file, stdout when "" or "-"
+}
+
+// runTour builds the tour, summarizes it unless NoLLM, then walks through it
+// in the TUI or writes it out
+func runTour(opts tourOptions) error {
+	if opts.Format != "markdown" && opts.Format != "json" {
+		return fmt.Errorf("unknown format %q, use markdown or json", opts.Format)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	project := "this project"
+	if wd, err := os.Getwd(); err == nil {
+		project = filepath.Base(wd)
+	}
+	t, err := tour.Build(project, ".eulix")
+	if err != nil {
+		return err
+	}
+	if len(t.Stops) == 0 {
+		return fmt.Errorf("nothing to show, the knowledge base has no entry points, functions or types")
+	}
+
+	if !opts.NoLLM {
+		client, err := llm.MouthClient(cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Summarizing %d stops with %s...\n", len(t.Stops), cfg.LLM.Model)
+		err = t.Describe(client, ".", func(done, total int) {
+			fmt.Fprintf(os.Stderr, "  %d/%d\n", done, total)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.TUI {
+		model := tui.TourGuide(t, ".").WithKeys(tui.Locksmith(cfg.TUI.Keys))
+		p := tea.NewProgram(crash.Guard(model), tea.WithAltScreen(), tea.WithoutCatchPanics())
+		if _, err := p.Run(); err != nil {
+			return fmt.Errorf("TUI error: %w", err)
+		}
+		return nil
+	}
+
+	text := t.Markdown()
+	if opts.Format == "json" {
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(data) + "\n"
+	}
+	if opts.Output == "" || opts.Output == "-" {
+		fmt.Print(text)
+		return nil
+	}
+	if err := os.WriteFile(opts.Output, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d stops)\n", opts.Output, len(t.Stops))
+	return nil
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"eulix/internal/installer"
+	"eulix/internal/version"
+)
+
+// runUpgrade installs the release asked for, the latest when "", over the
+// running eulix. With check it only says whether there is a newer one.
+func runUpgrade(release, baseURL string, check, force bool) error {
+	if release == "" {
+		latest, err := installer.LatestVersion(baseURL, time.Minute)
+		if err != nil {
+			return err
+		}
+		release = latest
+	}
+
+	if !force && !installer.Newer(release, version.Version) {
+		fmt.Printf("eulix %s is up to date (latest is %s)\n", version.Version, release)
+		return nil
+	}
+	if check {
+		fmt.Printf("eulix %s is available, you have %s. Run 'eulix upgrade' to install it.\n", release, version.Version)
+		return nil
+	}
+
+	installed, err := installer.Upgrade(installer.UpgradeOptions{
+		Version: release,
+		BaseURL: baseURL,
+		Out:     os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nUpgraded eulix %s -> %s\n", version.Version, installed)
+	fmt.Println("eulix_parser and eulix_embed are separate, update them with: eulix install-tools")
+	return nil
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"eulix/internal/query"
+)
+
+// printUses reports the files importing module, with at most calls call
+// sites for each in the text report
+func printUses(module string, calls int, asJSON bool) error {
+	uses, err := query.FindUses(".eulix", module)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(uses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(uses.Files) == 0 {
+		fmt.Printf("No file imports %s. Run 'eulix analyze' if the import is new.\n", module)
+		return nil
+	}
+	fmt.Println(uses.Summary(calls))
+	return nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/viz"
+)
+
+// writeEmbeddingsPlot projects the chunk vectors to 2D and writes them as an
+// HTML page or CSV to output, stdout when it is "" or "-"
+func writeEmbeddingsPlot(format, output string) error {
+	if format != "html" && format != "csv" {
+		return fmt.Errorf("unknown format %q, use html or csv", format)
+	}
+
+	points, err := viz.Points(".eulix")
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" && output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	buf := bufio.NewWriter(w)
+
+	if format == "csv" {
+		err = viz.WriteCSV(buf, points)
+	} else {
+		project := "this project"
+		if wd, err := os.Getwd(); err == nil {
+			project = filepath.Base(wd)
+		}
+		err = viz.WriteHTML(buf, project, points)
+	}
+	if err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	if output != "" && output != "-" {
+		fmt.Fprintf(os.Stderr, "Wrote %s (%d chunks)\n", output, len(points))
+	}
+	return nil
+}
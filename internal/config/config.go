@@ -3,6 +3,8 @@ package config
 import (
 	// "fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 
 	"github.com/BurntSushi/toml"
 )
@@ -14,6 +16,14 @@ type Config struct {
 	LLM        LLMConfig        `toml:"llm"`
 	Cache      CacheConfig      `toml:"cache"`
 	Checksum   ChecksumConfig   `toml:"checksum"`
+	Integrations IntegrationsConfig `toml:"integrations"`
+	Hooks      []HookConfig     `toml:"hooks"`
+	Tools      ToolsConfig      `toml:"tools"`
+	Index      IndexConfig      `toml:"index"`
+	TUI        TUIConfig        `toml:"tui"`
+	Retrieval  RetrievalConfig  `toml:"retrieval"`
+	Dependencies DependenciesConfig `toml:"dependencies"`
+	Cassettes  CassettesConfig  `toml:"cassettes"`
 }
 
 type ProjectConfig struct {
@@ -22,6 +32,27 @@ type ProjectConfig struct {
 
 type ParserConfig struct {
 	Threads int `toml:"threads"`
+
+	// Globs relative to the project root, a glob also takes everything under
+	// a directory it matches. Include limits parsing to matching paths,
+	// exclude drops them, both on top of .euignore.
+	Include []string `toml:"include"`
+	Exclude []string `toml:"exclude"`
+
+	// Languages to parse, e.g. ["go", "python"], empty parses all
+	Languages []string `toml:"languages"`
+
+	// Files over MaxFileKB are skipped, 0 means no limit. SkipGenerated
+	// also skips minified files and files with a "Code generated" style
+	// header.
+	MaxFileKB     int  `toml:"max_file_kb"`
+	SkipGenerated bool `toml:"skip_generated"`
+
+	// Markdown files and notebooks are indexed as doc chunks unless SkipDocs.
+	// Dockerfiles, compose, Kubernetes, workflow and Terraform files are
+	// indexed as infra chunks unless SkipInfra.
+	SkipDocs  bool `toml:"skip_docs"`
+	SkipInfra bool `toml:"skip_infra"`
 }
 
 type EmbeddingsConfig struct {
@@ -38,23 +69,227 @@ type LLMConfig struct {
 	MaxTokens   int     `toml:"max_tokens"`
 	Temperature float64 `toml:"temperature"`
 	BaseURL     string `toml:"baseURL"`
+
+	// How architecture and understanding questions are answered: "direct"
+	// (default) from one context window, "map_reduce" by summarizing up to
+	// map_groups windows of context with summary_model and answering over
+	// the summaries, for projects too large for one window
+	Strategy     string `toml:"strategy"`
+	SummaryModel string `toml:"summary_model"` // same provider, default model
+	MapGroups    int    `toml:"map_groups"`    // default 4
+
+	// Questions that aren't in English are translated by translate_model
+	// before they are classified and answered in their own language:
+	// "auto" (default) or "off" to search them as written
+	Translate      string `toml:"translate"`
+	TranslateModel string `toml:"translate_model"` // same provider, default model
+
+	ResponseCache ResponseCacheConfig `toml:"response_cache"`
+	Budget        BudgetConfig        `toml:"budget"`
+	Privacy       PrivacyConfig       `toml:"privacy"`
+	Mock          MockConfig          `toml:"mock"`
+}
+
+// MockConfig shapes the answers of provider = "mock", a stand-in model for
+// tests and demos that never leaves the machine. A cassette recording of
+// the prompt wins, then the responses in turn, then the template.
+type MockConfig struct {
+	Responses []string `toml:"responses"` // answered in turn, the last one repeats
+	Template  string   `toml:"template"`  // Go text/template over .Question, .Files and .Prompt
+	Cassette  string   `toml:"cassette"`  // JSON file of recorded prompts and responses to replay
+}
+
+// PrivacyConfig controls what code leaves the machine. Redaction and
+// anonymization only apply to hosted providers, a local model sees the code
+// as is.
+type PrivacyConfig struct {
+	Redact               bool     `toml:"redact"`                // mask secrets like API keys and passwords
+	Patterns             []string `toml:"patterns"`              // extra regexes to mask, only group 1 if the regex has one
+	AnonymizeIdentifiers bool     `toml:"anonymize_identifiers"` // replace KB symbol names with aliases
+	LocalOnly            bool     `toml:"local_only"`            // refuse any provider that isn't on this machine
+	Audit                bool     `toml:"audit"`                 // log every prompt sent to a hosted provider
+	AuditDir             string   `toml:"audit_dir"`             // where the audit log is kept
+}
+
+// BudgetConfig limits what a single query may send and cost. Zero means no
+// limit.
+type BudgetConfig struct {
+	MaxPromptTokens int     `toml:"max_prompt_tokens"`
+	MaxCost         float64 `toml:"max_cost"`     // dollars, estimated from the prices below
+	MaxSeconds      int     `toml:"max_seconds"`  // wall time, the answer so far is kept
+	InputPrice      float64 `toml:"input_price"`  // dollars per million prompt tokens
+	OutputPrice     float64 `toml:"output_price"` // dollars per million generated tokens
+	OnExceed        string  `toml:"on_exceed"`    // "confirm" (default) or "reduce" the context
+}
+
+// ResponseCacheConfig controls the in-memory cache of raw LLM responses,
+// separate from the query cache below
+type ResponseCacheConfig struct {
+	Enabled    bool `toml:"enabled"`
+	MaxEntries int  `toml:"max_entries"`
+	TTLMinutes int  `toml:"ttl_minutes"`
+}
+
+// RetrievalConfig tunes how much context a question gets. The top level
+// applies to every question, [retrieval.types.<type>] to one query type,
+// like location or architecture, on top of eulix's own tuning for it.
+type RetrievalConfig struct {
+	RetrievalParams
+	Types map[string]RetrievalParams `toml:"types"`
+
+	// Questions the classifier ranks less urgent, like locations, get less
+	// of the budget unless IgnorePriority
+	IgnorePriority bool `toml:"ignore_priority"`
+
+	// Tokens of max_tokens left unused for estimates that come out low,
+	// default 200
+	SafetyBuffer int `toml:"safety_buffer"`
+
+	// What happens to relevant chunks over the budget: "outline" (default)
+	// strips comments and then cuts them to signature and docs, "strip"
+	// only strips comments, "off" drops them
+	Compress string `toml:"compress"`
+
+	Importance ImportanceWeights `toml:"importance"`
+}
+
+// ImportanceWeights weigh what makes a chunk worth including, each factor
+// scores 0-1. All zero keeps the defaults, otherwise the weights are used as
+// given and a missing one counts 0.
+type ImportanceWeights struct {
+	Type       float64 `toml:"type"`       // functions and classes over files and docs, default 0.4
+	Complexity float64 `toml:"complexity"` // default 0.2
+	Centrality float64 `toml:"centrality"` // PageRank over the call graph, default 0.25
+	Recency    float64 `toml:"recency"`    // recently modified files, default 0.1
+	Docs       float64 `toml:"docs"`       // has a doc comment, default 0.05
+
+	// How far importance moves a search score, default 0.1 for up to 5%
+	// either way, negative leaves scores alone
+	Influence float64 `toml:"influence"`
+}
+
+// RetrievalParams are the retrieval knobs, zero keeps the default
+type RetrievalParams struct {
+	TopK           int     `toml:"top_k"`            // candidates kept from search, default 100
+	Similarity     float64 `toml:"similarity"`       // least cosine similarity for a semantic match, default 0.5
+	BudgetShare    float64 `toml:"budget_share"`     // part of the free tokens spent on context, default 0.85
+	MaxChunkTokens int     `toml:"max_chunk_tokens"` // larger chunks are left out, 0 for no limit
+	MaxResponse    int     `toml:"max_response"`     // tokens kept for the answer and the most the model writes, default 2000
+}
+
+// DependenciesConfig shapes dependency answers and 'eulix deps'. Zero
+// values keep the defaults.
+type DependenciesConfig struct {
+	Depth        int    `toml:"depth"`         // levels of calls to follow, default 3
+	Direction    string `toml:"direction"`     // "dependencies", "dependents" or "both" (default)
+	IncludeTypes bool   `toml:"include_types"` // name methods with their type, a type follows its methods
+	Format       string `toml:"format"`        // "tree" (default) or "dot"
+}
+
+// Cassette modes, see CassettesConfig
+const (
+	CassetteOff    = "off"
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// CassettesConfig records what the model and eulix_embed answer, to play
+// it back later instead of asking them again. Record keeps the latest
+// answer to every prompt and query, replay fails on anything not recorded.
+type CassettesConfig struct {
+	Mode string `toml:"mode"` // "off" (default), "record" or "replay"
+	Dir  string `toml:"dir"`  // default .eulix/cassettes
+	Name string `toml:"name"` // cassette to use, default "default"
+}
+
+// Path is the cassette file for kind, "llm" or "embed"
+func (c CassettesConfig) Path(kind string) string {
+	dir, name := c.Dir, c.Name
+	if dir == "" {
+		dir = DefaultCassetteDir
+	}
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(dir, name+"."+kind+".json")
+}
+
+// Recording reports whether a cassette is recorded or replayed
+func (c CassettesConfig) Recording() bool {
+	return c.Mode != "" && c.Mode != CassetteOff
+}
+
+// TUIConfig holds chat and history view settings
+type TUIConfig struct {
+	Mouse bool       `toml:"mouse"` // capture the mouse in chat, /mouse toggles it
+	Keys  KeysConfig `toml:"keys"`
+}
+
+// KeysConfig maps TUI actions to keys, using bubbletea key names like
+// "ctrl+c", "pgup" or "q". An action left out keeps its default; an empty
+// list unbinds it.
+type KeysConfig struct {
+	Quit       []string `toml:"quit"`
+	Back       []string `toml:"back"`
+	Up         []string `toml:"up"`
+	Down       []string `toml:"down"`
+	ScrollUp   []string `toml:"scroll_up"`
+	ScrollDown []string `toml:"scroll_down"`
+	PageUp     []string `toml:"page_up"`
+	PageDown   []string `toml:"page_down"`
+	Open       []string `toml:"open"`
+	Delete     []string `toml:"delete"`
+	History    []string `toml:"history"`
+	Copy       []string `toml:"copy"`
+	Reanalyze  []string `toml:"reanalyze"`
+	Palette    []string `toml:"palette"`
+	Expand     []string `toml:"expand"`
+	Note       []string `toml:"note"`
+	Group      []string `toml:"group"`
+	Mark       []string `toml:"mark"`
+	MarkAll    []string `toml:"mark_all"`
+	Export     []string `toml:"export"`
+	Expired    []string `toml:"expired"`
+	Pin        []string `toml:"pin"`
+	Correct    []string `toml:"correct"`
+
+	Interrupt    []string `toml:"interrupt"`
+	ContextPanel []string `toml:"context_panel"`
+	StatsPanel   []string `toml:"stats_panel"`
+	PanelGrow    []string `toml:"panel_grow"`
+	PanelShrink  []string `toml:"panel_shrink"`
 }
 
 type CacheConfig struct {
 	Redis RedisConfig `toml:"redis"`
 	SQL   SQLConfig   `toml:"sql"`
+	// Types overrides how long answers of one query type live, in both
+	// backends: [cache.types.<type>] like location or debug
+	Types map[string]CacheTypeConfig `toml:"types"`
+}
+
+// CacheTypeConfig is the cache lifetime of one query type, zero keeps the
+// default for it
+type CacheTypeConfig struct {
+	TTLHours float64 `toml:"ttl_hours"`
 }
 
 type RedisConfig struct {
 	Enabled  bool   `toml:"enabled"`
 	URL      string `toml:"url"`
 	TTLHours int    `toml:"ttl_hours"`
+	// Shared is a Redis used by a whole team. Entries are kept per checksum
+	// and only written from a KB that matches its working tree.
+	Shared bool   `toml:"shared"`
+	Access string `toml:"access"` // "read-write" (default) or "read-only"
+	User   string `toml:"user"`   // shown on entries you write, default $USER
 }
 
 type SQLConfig struct {
-	Enabled bool   `toml:"enabled"`
-	Driver  string `toml:"driver"`
-	DSN     string `toml:"dsn"`
+	Enabled  bool   `toml:"enabled"`
+	Driver   string `toml:"driver"`
+	DSN      string `toml:"dsn"`
+	TTLHours int    `toml:"ttl_hours"`
 }
 
 type ChecksumConfig struct {
@@ -62,13 +297,146 @@ type ChecksumConfig struct {
 	ForceReanalyzeThreshold float64 `toml:"force_reanalyze_threshold"`
 }
 
+// ToolsConfig says where the eulix_parser and eulix_embed binaries live.
+// Empty values fall back to ~/.eulix/bin and then PATH.
+type ToolsConfig struct {
+	BinDir string `toml:"bin_dir"`
+	Parser string `toml:"parser"`
+	Embed  string `toml:"embed"`
+
+	// Limits for each eulix_parser and eulix_embed run of analyze, 0 means
+	// no limit. Memory is resident memory and only enforced on Linux.
+	TimeoutMinutes int `toml:"timeout_minutes"`
+	MemoryLimitMB  int `toml:"memory_limit_mb"`
+}
+
+// IndexConfig is where push-index and pull-index find the prebuilt KB when
+// no location is given
+type IndexConfig struct {
+	Remote string `toml:"remote"` // s3://bucket/path, gs://bucket/path or file:///dir
+}
+
+// HookConfig runs a shell command or POSTs JSON to a url on an event
+// (pre-query, post-answer, post-analyze)
+type HookConfig struct {
+	Event          string `toml:"event"`
+	Command        string `toml:"command"`
+	URL            string `toml:"url"`
+	TimeoutSeconds int    `toml:"timeout_seconds"`
+}
+
+type IntegrationsConfig struct {
+	Slack   SlackConfig   `toml:"slack"`
+	Discord DiscordConfig `toml:"discord"`
+}
+
+type SlackConfig struct {
+	Enabled       bool                      `toml:"enabled"`
+	Listen        string                    `toml:"listen"`
+	SigningSecret string                    `toml:"signing_secret"`
+	DefaultRepo   string                    `toml:"default_repo"`
+	Channels      map[string]ChannelMapping `toml:"channels"`
+}
+
+type DiscordConfig struct {
+	Enabled     bool                      `toml:"enabled"`
+	Listen      string                    `toml:"listen"`
+	PublicKey   string                    `toml:"public_key"`
+	DefaultRepo string                    `toml:"default_repo"`
+	Channels    map[string]ChannelMapping `toml:"channels"`
+}
+
+// ChannelMapping points a chat channel at an analyzed repo on disk
+type ChannelMapping struct {
+	Repo      string `toml:"repo"`
+	SourceURL string `toml:"source_url"`
+}
+
+// ParserPath resolves the eulix_parser binary to run
+func (t ToolsConfig) ParserPath() string {
+	return t.resolve(t.Parser, "eulix_parser")
+}
+
+// EmbedPath resolves the eulix_embed binary to run
+func (t ToolsConfig) EmbedPath() string {
+	return t.resolve(t.Embed, "eulix_embed")
+}
+
+func (t ToolsConfig) resolve(explicit, name string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	if t.BinDir != "" {
+		return filepath.Join(t.BinDir, name)
+	}
+
+	if dir, err := DefaultToolsDir(); err == nil {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	// Plain name, exec looks it up in PATH
+	return name
+}
+
+// DefaultToolsDir is where `eulix install-tools` puts binaries
+func DefaultToolsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".eulix", "bin"), nil
+}
+
+// Where a project's cache, audit log and cassettes go when eulix.toml
+// doesn't say, relative to the project
+const (
+	DefaultCacheDSN    = ".eulix/cache.db"
+	DefaultAuditDir    = ".eulix/audit"
+	DefaultCassetteDir = ".eulix/cassettes"
+)
+
+// InRepo makes the paths cfg keeps per project, the SQL cache, the audit
+// log and the cassettes, relative to root instead of the working directory.
+// Paths left empty get their default under root.
+func (cfg *Config) InRepo(root string) {
+	cfg.Cache.SQL.DSN = InRoot(root, cfg.Cache.SQL.DSN, DefaultCacheDSN)
+	cfg.LLM.Privacy.AuditDir = InRoot(root, cfg.LLM.Privacy.AuditDir, DefaultAuditDir)
+	cfg.Cassettes.Dir = InRoot(root, cfg.Cassettes.Dir, DefaultCassetteDir)
+}
+
+// InRoot is path, or def when path is empty, relative to root. Absolute
+// paths are kept as they are.
+func InRoot(root, path, def string) string {
+	if path == "" {
+		path = def
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
 func Load() (*Config, error) {
+	return LoadFile("eulix.toml")
+}
+
+// LoadFile reads config from path, falling back to defaults when it is missing
+func LoadFile(path string) (*Config, error) {
 	var cfg Config
 
 	// Try to read from eulix.toml
-	if _, err := toml.DecodeFile("eulix.toml", &cfg); err != nil {
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
 		// Return default config
-		return defaultConfig(), nil
+		def := defaultConfig()
+		applyEnv(def)
+		return def, nil
 	}
 
 	// Override API key from environment if not set
@@ -76,16 +444,60 @@ func Load() (*Config, error) {
 		cfg.LLM.APIKey = os.Getenv("ANTHROPIC_API_KEY")
 	}
 
+	// Bot secrets usually live in the environment, not in the repo
+	if cfg.Integrations.Slack.SigningSecret == "" {
+		cfg.Integrations.Slack.SigningSecret = os.Getenv("SLACK_SIGNING_SECRET")
+	}
+	if cfg.Integrations.Discord.PublicKey == "" {
+		cfg.Integrations.Discord.PublicKey = os.Getenv("DISCORD_PUBLIC_KEY")
+	}
+
+	applyEnv(&cfg)
 	return &cfg, nil
 }
 
+// LocalOnlyEnv reports whether EULIX_LOCAL_ONLY is set, which --local-only
+// does for the whole process
+func LocalOnlyEnv() bool {
+	v := os.Getenv("EULIX_LOCAL_ONLY")
+	return v != "" && v != "0" && v != "false"
+}
+
+// applyEnv lets CI runners override the LLM setup from secrets without
+// touching eulix.toml
+func applyEnv(cfg *Config) {
+	if v := os.Getenv("EULIX_LLM_PROVIDER"); v != "" {
+		cfg.LLM.Provider = v
+		cfg.LLM.Local = v == "ollama"
+	}
+	if v := os.Getenv("EULIX_LLM_MODEL"); v != "" {
+		cfg.LLM.Model = v
+	}
+	if LocalOnlyEnv() {
+		cfg.LLM.Privacy.LocalOnly = true
+	}
+	if v := os.Getenv("EULIX_CASSETTE"); v != "" {
+		cfg.Cassettes.Mode = v
+	}
+	if v := os.Getenv("EULIX_LLM_BASE_URL"); v != "" {
+		cfg.LLM.BaseURL = v
+	}
+	if v := os.Getenv("EULIX_API_KEY"); v != "" {
+		cfg.LLM.APIKey = v
+	} else if cfg.LLM.APIKey == "" {
+		cfg.LLM.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+}
+
 func defaultConfig() *Config {
 	return &Config{
 		Project: ProjectConfig{
 			Path: ".",
 		},
 		Parser: ParserConfig{
-			Threads: 4,
+			Threads:       4,
+			MaxFileKB:     1024,
+			SkipGenerated: true,
 		},
 		Embeddings: EmbeddingsConfig{
 			Model:     "BAAI/bge-small-en-v1.5",
@@ -99,6 +511,16 @@ func defaultConfig() *Config {
 			MaxTokens:   8192,
 			Temperature: 0.7,
 			BaseURL: "http://localhost:11434",
+			ResponseCache: ResponseCacheConfig{
+				Enabled:    false,
+				MaxEntries: 256,
+				TTLMinutes: 60,
+			},
+			Privacy: PrivacyConfig{
+				Redact:   true,
+				Audit:    true,
+				AuditDir: ".eulix/audit",
+			},
 		},
 		Cache: CacheConfig{
 			Redis: RedisConfig{
@@ -107,14 +529,30 @@ func defaultConfig() *Config {
 				TTLHours: 6,
 			},
 			SQL: SQLConfig{
-				Enabled: true,
-				Driver:  "sqlite",
-				DSN:     ".eulix/history.db",
+				Enabled:  true,
+				Driver:   "sqlite",
+				DSN:      ".eulix/history.db",
+				TTLHours: 24,
 			},
 		},
+		Tools: ToolsConfig{
+			TimeoutMinutes: 120,
+		},
 		Checksum: ChecksumConfig{
 			ChangeThreshold:          0.10,
 			ForceReanalyzeThreshold: 0.30,
 		},
+		Integrations: IntegrationsConfig{
+			Slack: SlackConfig{
+				Enabled:     false,
+				Listen:      ":3000",
+				DefaultRepo: ".",
+			},
+			Discord: DiscordConfig{
+				Enabled:     false,
+				Listen:      ":3001",
+				DefaultRepo: ".",
+			},
+		},
 	}
 }
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Keys lists every setting as its dotted TOML path, like llm.model, in the
+// order the config structs declare them
+func Keys() []string {
+	return keysOf(reflect.TypeOf(Config{}), "")
+}
+
+func keysOf(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		tag := tomlName(t.Field(i))
+		if tag == "" {
+			continue
+		}
+		if ft := t.Field(i).Type; ft.Kind() == reflect.Struct {
+			keys = append(keys, keysOf(ft, prefix+tag+".")...)
+			continue
+		}
+		keys = append(keys, prefix+tag)
+	}
+	return keys
+}
+
+// Get is the value of a dotted key, a table gives its whole struct
+func (c *Config) Get(key string) (any, error) {
+	v := reflect.ValueOf(c).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("unknown config key %q", key)
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if tomlName(v.Type().Field(i)) == part {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown config key %q", key)
+		}
+	}
+	return v.Interface(), nil
+}
+
+// Secret reports whether key holds a credential, not to be printed
+func Secret(key string) bool {
+	last := key[strings.LastIndex(key, ".")+1:]
+	for _, word := range strings.Split(last, "_") {
+		switch word {
+		case "key", "secret", "token", "password":
+			return true
+		}
+	}
+	return false
+}
+
+func tomlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
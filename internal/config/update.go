@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var sectionHeader = regexp.MustCompile(`^\s*\[[^\]]+\]\s*$`)
+
+// SetToolsBinDir points [tools] bin_dir at dir in the given toml file,
+// editing the text in place so comments and layout survive
+func SetToolsBinDir(path, dir string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	entry := fmt.Sprintf("bin_dir = %q", dir)
+	lines := strings.Split(string(data), "\n")
+
+	inTools := false
+	toolsAt := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if sectionHeader.MatchString(line) {
+			inTools = trimmed == "[tools]"
+			if inTools {
+				toolsAt = i
+			}
+			continue
+		}
+		if inTools && strings.HasPrefix(trimmed, "bin_dir") {
+			lines[i] = entry
+			return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+		}
+	}
+
+	if toolsAt >= 0 {
+		lines = append(lines[:toolsAt+1], append([]string{entry}, lines[toolsAt+1:]...)...)
+	} else {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, "", "[tools]", entry, "")
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+}
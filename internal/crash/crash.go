@@ -0,0 +1,206 @@
+package crash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"eulix/internal/child"
+	"eulix/internal/fault"
+	"eulix/internal/version"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+)
+
+// A panic, in a TUI especially, used to leave the terminal in raw mode on
+// the alt screen with the stack trace drawn over and lost. Recover puts the
+// terminal back, writes the panic with the last log lines to
+// .eulix/crash-<time>.log and prints where to find it.
+
+// Dir is where crash logs are written, falling back to the temp dir when it
+// doesn't exist
+var Dir = ".eulix"
+
+// keepLines is how much log output a crash log includes
+const keepLines = 200
+
+var (
+	recent   = &lines{}
+	terminal struct {
+		fd    uintptr
+		state *term.State
+	}
+	reporting sync.Mutex
+)
+
+// Install remembers the terminal state and starts keeping recent log output,
+// call it first thing in main
+func Install() {
+	log.SetOutput(io.MultiWriter(os.Stderr, recent))
+
+	fd := os.Stdin.Fd()
+	if term.IsTerminal(fd) {
+		if state, err := term.GetState(fd); err == nil {
+			terminal.fd = fd
+			terminal.state = state
+		}
+	}
+}
+
+// Recover handles a panic in the goroutine it is deferred in and exits 2.
+// Defer it in main and at the start of long running goroutines.
+func Recover() {
+	if r := recover(); r != nil {
+		report(r, debug.Stack())
+	}
+}
+
+func report(value interface{}, stack []byte) {
+	// Goroutines panicking together report once, the others wait for exit
+	reporting.Lock()
+
+	restoreTerminal()
+	child.StopAll()
+
+	path, err := write(value, stack)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\neulix crashed: %v\n\n%s\n(could not write a crash log: %v)\n", value, stack, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "\neulix crashed: %v\n", value)
+		fmt.Fprintf(os.Stderr, "The stack trace and recent log are in %s\n", path)
+		fmt.Fprintf(os.Stderr, "Please attach it when reporting the bug.\n")
+	}
+	os.Exit(fault.ExitCrash)
+}
+
+// restoreTerminal leaves the alt screen, shows the cursor, turns off mouse
+// and paste reporting, and takes the terminal out of raw mode
+func restoreTerminal() {
+	if term.IsTerminal(os.Stdout.Fd()) {
+		os.Stdout.WriteString("\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l\x1b[?1004l\x1b[?2004l\x1b[?25h\x1b[?1049l")
+	}
+	if terminal.state != nil {
+		term.Restore(terminal.fd, terminal.state)
+	}
+}
+
+func write(value interface{}, stack []byte) (string, error) {
+	dir := Dir
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = os.TempDir()
+	}
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s.log", now.Format("20060102-150405")))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "eulix %s crashed at %s\n", version.Version, now.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "command: %s\n", strings.Join(os.Args, " "))
+	fmt.Fprintf(&sb, "runtime: %s %s/%s\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&sb, "panic: %v\n\n%s\n", value, stack)
+	sb.WriteString("recent log:\n")
+	if logged := recent.String(); logged != "" {
+		sb.WriteString(logged)
+	} else {
+		sb.WriteString("(nothing logged)\n")
+	}
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// lines keeps the last keepLines lines written to it
+type lines struct {
+	mu      sync.Mutex
+	kept    []string
+	partial []byte
+}
+
+func (l *lines) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.kept = append(l.kept, string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+	if over := len(l.kept) - keepLines; over > 0 {
+		l.kept = append(l.kept[:0], l.kept[over:]...)
+	}
+	return len(p), nil
+}
+
+func (l *lines) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.kept) == 0 && len(l.partial) == 0 {
+		return ""
+	}
+	out := strings.Join(l.kept, "\n") + "\n"
+	if len(l.partial) > 0 {
+		out += string(l.partial) + "\n"
+	}
+	return out
+}
+
+// Guard wraps a TUI model so panics in its commands, which Bubble Tea runs
+// in their own goroutines, are recovered too. Run the program with
+// tea.WithoutCatchPanics so Update and View panics reach Recover in main.
+func Guard(model tea.Model) tea.Model {
+	return guarded{model}
+}
+
+// Unwrap returns the model Guard wrapped, for reading the final state
+// p.Run returns
+func Unwrap(model tea.Model) tea.Model {
+	if g, ok := model.(guarded); ok {
+		return g.Model
+	}
+	return model
+}
+
+type guarded struct {
+	tea.Model
+}
+
+func (g guarded) Init() tea.Cmd {
+	return guardCmd(g.Model.Init())
+}
+
+func (g guarded) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := g.Model.Update(msg)
+	return guarded{model}, guardCmd(cmd)
+}
+
+func guardCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer Recover()
+		msg := cmd()
+		// A batch is run as separate commands, guard each
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = guardCmd(batch[i])
+			}
+		}
+		return msg
+	}
+}
@@ -0,0 +1,219 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+	"eulix/internal/query"
+)
+
+// Client talks to a running daemon, one connection per request
+type Client struct {
+	socketPath  string
+	timeout     time.Duration
+	waiveBudget bool // sent with the next question
+	expand      int  // Expand level, also sent with the next question
+
+	disambiguate bool              // see Disambiguate, sent with every question
+	picks        map[string]string // see Pick, sent with the next question
+	maxResponse  int               // see SetMaxResponse, sent with every question
+}
+
+// Dial returns a client if something is listening on the daemon socket
+func Dial(eulixDir string) (*Client, error) {
+	c := &Client{
+		socketPath: SocketPath(eulixDir),
+		timeout:    10 * time.Minute, // LLM calls can be slow
+	}
+
+	conn, err := net.DialTimeout("unix", c.socketPath, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("daemon not running: %w", err)
+	}
+	conn.Close()
+
+	return c, nil
+}
+
+// Query sends a question to the daemon and waits for the answer
+func (c *Client) Query(q string) (string, error) {
+	resp, err := c.send(Request{Op: "query", Query: q})
+	if err != nil {
+		return "", err
+	}
+	return resp.Result, nil
+}
+
+// Answer sends a question to the daemon and returns the answer with its confidence
+func (c *Client) Answer(q string) (*query.Answer, error) {
+	return c.AnswerInSession(q, nil)
+}
+
+// AnswerInSession is Answer for chat, the session carries what was discussed so far
+func (c *Client) AnswerInSession(q string, session *query.Session) (*query.Answer, error) {
+	return c.AnswerStream(q, session, nil, nil)
+}
+
+// AnswerStream is AnswerInSession that calls progress for each stage and text
+// for each piece of the answer as the daemon produces them, either may be
+// nil. Older daemons send neither.
+func (c *Client) AnswerStream(q string, session *query.Session, progress func(query.Progress), text func(string)) (*query.Answer, error) {
+	req := Request{Op: "query", Query: q, Session: session, Progress: progress != nil, Stream: text != nil, OverBudget: c.takeWaiver(), Expand: c.expand,
+		Disambiguate: c.disambiguate, Picks: c.picks, MaxResponse: c.maxResponse}
+	c.expand = 0
+	c.picks = nil
+	resp, err := c.stream(req, progress, text)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Answer == nil {
+		// Older daemon, no confidence to report
+		return &query.Answer{
+			Text:       resp.Result,
+			Confidence: query.Confidence{Level: "unknown", Retrieval: -1, Classifier: -1, Model: -1},
+		}, nil
+	}
+	return resp.Answer, nil
+}
+
+// Agent sends a question to be answered in agent mode with at most steps tool calls
+func (c *Client) Agent(q string, steps int) (*query.Answer, error) {
+	resp, err := c.send(Request{Op: "agent", Query: q, Steps: steps, OverBudget: c.takeWaiver(), MaxResponse: c.maxResponse})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Answer, nil
+}
+
+// WaiveBudget lets the next question go over [llm.budget], after the user
+// confirmed a query.BudgetError
+func (c *Client) WaiveBudget() {
+	c.waiveBudget = true
+}
+
+// Expand makes the next question retrieve wider, see query.Router.Expand
+func (c *Client) Expand(level int) {
+	c.expand = level
+}
+
+// SetMaxResponse caps the answers to the following questions at tokens,
+// see query.Router.SetMaxResponse
+func (c *Client) SetMaxResponse(tokens int) {
+	c.maxResponse = tokens
+}
+
+// Disambiguate makes questions naming a symbol defined in several places
+// fail with a query.AmbiguousError, see query.Router.Disambiguate
+func (c *Client) Disambiguate(on bool) {
+	c.disambiguate = on
+}
+
+// Pick makes the next question about symbol mean its definition at
+// location, see query.Router.Pick
+func (c *Client) Pick(symbol, location string) {
+	if c.picks == nil {
+		c.picks = make(map[string]string)
+	}
+	c.picks[symbol] = location
+}
+
+func (c *Client) takeWaiver() bool {
+	waived := c.waiveBudget
+	c.waiveBudget = false
+	return waived
+}
+
+// Interrupt stops the answer the daemon is generating, the client waiting on
+// it gets what was generated so far
+func (c *Client) Interrupt() error {
+	_, err := c.send(Request{Op: "interrupt"})
+	return err
+}
+
+// Status asks the daemon about itself
+func (c *Client) Status() (*Status, error) {
+	resp, err := c.send(Request{Op: "status"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+// Reload asks the daemon to pick up a new knowledge base
+func (c *Client) Reload() error {
+	_, err := c.send(Request{Op: "reload"})
+	return err
+}
+
+// Stop asks the daemon to shut down
+func (c *Client) Stop() error {
+	_, err := c.send(Request{Op: "shutdown"})
+	return err
+}
+
+func (c *Client) send(req Request) (*Response, error) {
+	return c.stream(req, nil, nil)
+}
+
+// stream sends a request and reads responses until the final one, handing
+// stage events and answer text to the callbacks on the way
+func (c *Client) stream(req Request, progress func(query.Progress), text func(string)) (*Response, error) {
+	conn, err := net.DialTimeout("unix", c.socketPath, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("daemon not reachable: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	// The daemon may have been started without --local-only, it has to
+	// check for this client
+	req.LocalOnly = config.LocalOnlyEnv()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	var resp Response
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		resp = Response{}
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return nil, fmt.Errorf("invalid response: %w", err)
+		}
+		if resp.Progress == nil && resp.Text == "" {
+			break
+		}
+		if resp.Progress != nil && progress != nil {
+			progress(*resp.Progress)
+		}
+		if resp.Text != "" && text != nil {
+			text(resp.Text)
+		}
+	}
+
+	if !resp.OK {
+		if resp.Budget != nil {
+			return nil, resp.Budget
+		}
+		if resp.Ambiguous != nil {
+			return nil, resp.Ambiguous
+		}
+		if sentinel := fault.FromCode(resp.Code); sentinel != nil {
+			return nil, fmt.Errorf("daemon: %w: %s", sentinel, resp.Error)
+		}
+		return nil, fmt.Errorf("daemon: %s", resp.Error)
+	}
+
+	return &resp, nil
+}
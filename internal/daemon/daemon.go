@@ -0,0 +1,382 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"eulix/internal/fault"
+	"eulix/internal/lock"
+	"eulix/internal/query"
+)
+
+// SocketName is the unix socket the daemon listens on inside .eulix
+const SocketName = "daemon.sock"
+
+// Request is a single newline delimited JSON message sent by a client
+type Request struct {
+	Op    string `json:"op"`
+	Query string `json:"query,omitempty"`
+	Steps int    `json:"steps,omitempty"` // tool call budget for agent
+	// Progress asks for stage events, written as extra responses before the answer
+	Progress bool `json:"progress,omitempty"`
+	// Stream asks for the answer text as it is generated, also as extra responses
+	Stream bool `json:"stream,omitempty"`
+	// OverBudget sends the question even if it goes over [llm.budget]
+	OverBudget bool `json:"over_budget,omitempty"`
+	// Expand retrieves wider than usual, see query.Router.Expand
+	Expand int `json:"expand,omitempty"`
+	// Disambiguate refuses questions naming a symbol defined in several
+	// places with the list of them, see query.Router.Disambiguate
+	Disambiguate bool `json:"disambiguate,omitempty"`
+	// Picks are the definitions the user chose, symbol to location
+	Picks map[string]string `json:"picks,omitempty"`
+	// MaxResponse caps the answer in tokens, 0 for the query type's default,
+	// see query.Router.SetMaxResponse
+	MaxResponse int `json:"max_response,omitempty"`
+	// Session is the chat's state, for follow-ups and session memory
+	Session *query.Session `json:"session,omitempty"`
+	// LocalOnly refuses the question if the daemon's model isn't local, the
+	// client was run with --local-only
+	LocalOnly bool `json:"local_only,omitempty"`
+}
+
+// Response is what the daemon writes back for every request
+type Response struct {
+	OK     bool          `json:"ok"`
+	Result string        `json:"result,omitempty"`
+	Answer *query.Answer `json:"answer,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	// Code names the failure, see fault.Code
+	Code   string  `json:"code,omitempty"`
+	Status *Status `json:"status,omitempty"`
+	// Progress is set on the stage events sent before the final response
+	Progress *query.Progress `json:"progress,omitempty"`
+	// Text is a piece of the answer being generated, sent before the final response
+	Text string `json:"text,omitempty"`
+	// Budget is set when a query was refused for going over [llm.budget]
+	Budget *query.BudgetError `json:"budget,omitempty"`
+	// Ambiguous is set when a query was refused to ask which definition it means
+	Ambiguous *query.AmbiguousError `json:"ambiguous,omitempty"`
+}
+
+// Status describes a running daemon
+type Status struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Served    int       `json:"served"`
+	EulixDir  string    `json:"eulix_dir"`
+	Warnings  []string  `json:"warnings,omitempty"`
+
+	Capabilities query.Capabilities `json:"capabilities"`
+}
+
+// Daemon keeps a warm Router in memory and answers queries over a unix socket
+type Daemon struct {
+	eulixDir   string
+	socketPath string
+	router     *query.Router
+	listener   net.Listener
+	startedAt  time.Time
+
+	mu        sync.Mutex // Router is not safe for concurrent use
+	kbModTime time.Time
+	done      chan struct{}
+	once      sync.Once
+
+	// What status reports, under a lock of its own so it answers while a
+	// query holds mu
+	statusMu     sync.Mutex
+	served       int
+	warnings     []string
+	capabilities query.Capabilities
+}
+
+// SocketPath returns the socket location for a given .eulix directory
+func SocketPath(eulixDir string) string {
+	return filepath.Join(eulixDir, SocketName)
+}
+
+// NightWatch creates a daemon that stands guard over an already warmed router
+func NightWatch(eulixDir string, router *query.Router) *Daemon {
+	d := &Daemon{
+		eulixDir:   eulixDir,
+		socketPath: SocketPath(eulixDir),
+		router:     router,
+		done:       make(chan struct{}),
+	}
+	d.publish(false)
+	return d
+}
+
+// Serve listens on the socket and blocks until Shutdown is called
+func (d *Daemon) Serve() error {
+	if _, err := os.Stat(d.socketPath); err == nil {
+		// Another daemon may own the socket, or it is left over from a crash
+		if client, err := Dial(d.eulixDir); err == nil {
+			if _, err := client.Status(); err == nil {
+				return fmt.Errorf("daemon already running on %s", d.socketPath)
+			}
+		}
+		if err := os.Remove(d.socketPath); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", d.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", d.socketPath, err)
+	}
+	d.listener = listener
+	d.startedAt = time.Now()
+	d.kbModTime = d.router.KBModTime()
+	defer os.Remove(d.socketPath)
+
+	go d.watchKB(5 * time.Second)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-d.done:
+				return nil
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("accept failed: %w", err)
+		}
+		go d.handle(conn)
+	}
+}
+
+// Shutdown stops accepting connections and unblocks Serve
+func (d *Daemon) Shutdown() error {
+	var err error
+	d.once.Do(func() {
+		close(d.done)
+		if d.listener != nil {
+			err = d.listener.Close()
+		}
+	})
+	return err
+}
+
+func (d *Daemon) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	encoder := json.NewEncoder(conn)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			encoder.Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+
+		var progress func(query.Progress)
+		if req.Progress {
+			progress = func(p query.Progress) {
+				encoder.Encode(Response{OK: true, Progress: &p})
+			}
+		}
+		var text func(string)
+		if req.Stream {
+			text = func(t string) {
+				encoder.Encode(Response{OK: true, Text: t})
+			}
+		}
+
+		resp := d.dispatch(req, progress, text)
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+
+		if req.Op == "shutdown" {
+			d.Shutdown()
+			return
+		}
+	}
+}
+
+func (d *Daemon) dispatch(req Request, progress func(query.Progress), text func(string)) Response {
+	switch req.Op {
+	case "ping", "status":
+		return Response{OK: true, Status: d.status()}
+
+	case "query":
+		if req.Query == "" {
+			return Response{Error: "empty query"}
+		}
+		if req.LocalOnly {
+			if err := d.router.RequireLocal(); err != nil {
+				return errorResponse(err)
+			}
+		}
+		d.mu.Lock()
+		d.router.OnProgress(progress)
+		d.router.OnText(text)
+		if req.OverBudget {
+			d.router.WaiveBudget()
+		}
+		d.router.Expand(req.Expand)
+		d.router.Disambiguate(req.Disambiguate)
+		d.router.SetMaxResponse(req.MaxResponse)
+		for symbol, location := range req.Picks {
+			d.router.Pick(symbol, location)
+		}
+		answer, err := d.router.AnswerInSession(req.Query, req.Session)
+		d.router.OnProgress(nil)
+		d.router.OnText(nil)
+		d.publish(true)
+		d.mu.Unlock()
+		if err != nil {
+			return errorResponse(err)
+		}
+		return Response{OK: true, Result: answer.String(), Answer: answer}
+
+	case "agent":
+		if req.Query == "" {
+			return Response{Error: "empty query"}
+		}
+		if req.LocalOnly {
+			if err := d.router.RequireLocal(); err != nil {
+				return errorResponse(err)
+			}
+		}
+		d.mu.Lock()
+		d.router.OnProgress(progress)
+		if req.OverBudget {
+			d.router.WaiveBudget()
+		}
+		d.router.SetMaxResponse(req.MaxResponse)
+		answer, err := d.router.Agent(req.Query, req.Steps)
+		d.router.OnProgress(nil)
+		d.publish(true)
+		d.mu.Unlock()
+		if err != nil {
+			return errorResponse(err)
+		}
+		return Response{OK: true, Result: answer.String(), Answer: answer}
+
+	case "interrupt":
+		// Not under mu, that is held by the query being interrupted
+		d.router.Interrupt()
+		return Response{OK: true}
+
+	case "reload":
+		if err := d.Reload(); err != nil {
+			return errorResponse(err)
+		}
+		return Response{OK: true, Status: d.status()}
+
+	case "shutdown":
+		return Response{OK: true}
+
+	default:
+		return Response{Error: fmt.Sprintf("unknown op: %s", req.Op)}
+	}
+}
+
+// errorResponse reports a failed query with its code, keeping the details
+// of a budget refusal so the client can ask the user
+func errorResponse(err error) Response {
+	resp := Response{Error: err.Error(), Code: fault.Code(err)}
+	var budget *query.BudgetError
+	if errors.As(err, &budget) {
+		resp.Budget = budget
+	}
+	var ambiguous *query.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		resp.Ambiguous = ambiguous
+	}
+	return resp
+}
+
+// Reload swaps in a freshly analyzed KB between queries
+func (d *Daemon) Reload() error {
+	if err := lock.WaitForRelease(d.eulixDir, 30*time.Minute); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.router.Reload(); err != nil {
+		return err
+	}
+	d.kbModTime = d.router.KBModTime()
+	d.publish(false)
+	return nil
+}
+
+// watchKB reloads whenever a new analyze lands, until Shutdown
+func (d *Daemon) watchKB(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			changed := d.router.KBModTime().After(d.kbModTime)
+			d.mu.Unlock()
+
+			if changed {
+				if err := d.Reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "daemon: reload failed: %v\n", err)
+					// Don't retry the same broken KB every tick
+					d.mu.Lock()
+					d.kbModTime = d.router.KBModTime()
+					d.mu.Unlock()
+				} else {
+					fmt.Println("Knowledge base changed, reloaded")
+				}
+			}
+		}
+	}
+}
+
+// publish copies what status reports from the router, counting a query
+// when served. Called with mu held, or before the daemon serves.
+func (d *Daemon) publish(served bool) {
+	warnings, capabilities := d.router.Warnings(), d.router.Capabilities()
+
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+	if served {
+		d.served++
+	}
+	d.warnings = warnings
+	d.capabilities = capabilities
+}
+
+func (d *Daemon) status() *Status {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	return &Status{
+		PID:       os.Getpid(),
+		StartedAt: d.startedAt,
+		Served:    d.served,
+		EulixDir:  d.eulixDir,
+		Warnings:  d.warnings,
+
+		Capabilities: d.capabilities,
+	}
+}
@@ -0,0 +1,261 @@
+package editor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"eulix/internal/fault"
+)
+
+// Querier is anything that can answer a question about the codebase,
+// the local router and the daemon client both fit
+type Querier interface {
+	Query(q string) (string, error)
+}
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type rpcErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *rpcErrorData `json:"data,omitempty"`
+}
+
+// rpcErrorData names what failed, see fault.Code
+type rpcErrorData struct {
+	Code string `json:"code"`
+}
+
+// SymbolParams is used by eulix/explainSymbol and eulix/findUsages
+type SymbolParams struct {
+	Symbol string `json:"symbol"`
+	File   string `json:"file,omitempty"`
+}
+
+// SelectionParams is used by eulix/askSelection
+type SelectionParams struct {
+	File      string `json:"file"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Text      string `json:"text"`
+	Question  string `json:"question"`
+}
+
+// AnswerResult is what every eulix/* request returns
+type AnswerResult struct {
+	Answer string `json:"answer"`
+}
+
+// Server speaks LSP style framed JSON-RPC (Content-Length headers) so editor
+// plugins can keep one process around instead of shelling out per request
+type Server struct {
+	querier  Querier
+	in       *bufio.Reader
+	out      io.Writer
+	writeMu  sync.Mutex
+	shutdown bool
+}
+
+func Diplomat(querier Querier, in io.Reader, out io.Writer) *Server {
+	return &Server{
+		querier: querier,
+		in:      bufio.NewReader(in),
+		out:     out,
+	}
+}
+
+// Serve handles requests until the client sends exit or closes stdin
+func (s *Server) Serve() error {
+	for {
+		body, err := s.readMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.writeError(nil, codeParseError, err.Error())
+			continue
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, rpcErr := s.dispatch(&req)
+
+		// Notifications (no id) never get a response
+		if len(req.ID) == 0 {
+			continue
+		}
+
+		if rpcErr != nil {
+			s.write(rpcErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcErr})
+		} else {
+			s.write(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+	}
+}
+
+func (s *Server) dispatch(req *rpcRequest) (interface{}, *rpcError) {
+	if req.Method == "" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "missing method"}
+	}
+
+	if s.shutdown && req.Method != "exit" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"serverInfo": map[string]string{"name": "eulix"},
+			"capabilities": map[string]interface{}{
+				"experimental": map[string]bool{
+					"explainSymbol": true,
+					"findUsages":    true,
+					"askSelection":  true,
+				},
+			},
+		}, nil
+
+	case "initialized":
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "eulix/explainSymbol":
+		var params SymbolParams
+		if err := decodeParams(req.Params, &params); err != nil || params.Symbol == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "symbol is required"}
+		}
+		return s.ask(fmt.Sprintf("Explain what %s does", params.Symbol))
+
+	case "eulix/findUsages":
+		var params SymbolParams
+		if err := decodeParams(req.Params, &params); err != nil || params.Symbol == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "symbol is required"}
+		}
+		return s.ask(fmt.Sprintf("Where is %s used", params.Symbol))
+
+	case "eulix/askSelection":
+		var params SelectionParams
+		if err := decodeParams(req.Params, &params); err != nil || params.Text == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "text is required"}
+		}
+		return s.ask(selectionQuery(params))
+
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "unknown method: " + req.Method}
+	}
+}
+
+func (s *Server) ask(q string) (interface{}, *rpcError) {
+	answer, err := s.querier.Query(q)
+	if err != nil {
+		rpcErr := &rpcError{Code: codeInternalError, Message: err.Error()}
+		if code := fault.Code(err); code != "" {
+			rpcErr.Data = &rpcErrorData{Code: code}
+		}
+		return nil, rpcErr
+	}
+	return AnswerResult{Answer: answer}, nil
+}
+
+func selectionQuery(p SelectionParams) string {
+	question := strings.TrimSpace(p.Question)
+	if question == "" {
+		question = "Explain this code"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(question)
+	if p.File != "" {
+		sb.WriteString(fmt.Sprintf("\n\nSelected code from %s (lines %d-%d):\n", p.File, p.StartLine, p.EndLine))
+	} else {
+		sb.WriteString("\n\nSelected code:\n")
+	}
+	sb.WriteString(p.Text)
+	return sb.String()
+}
+
+func decodeParams(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return fmt.Errorf("missing params")
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func (s *Server) readMessage() ([]byte, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *Server) writeError(id json.RawMessage, code int, msg string) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	s.write(rpcErrorResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: msg}})
+}
+
+func (s *Server) write(resp interface{}) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(data))
+	s.out.Write(data)
+}
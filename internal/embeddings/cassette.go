@@ -0,0 +1,119 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+)
+
+// ErrNotRecorded is returned in replay mode for a query the cassette
+// doesn't have
+var ErrNotRecorded = errors.New("query isn't on the cassette")
+
+// Cassette is a recording of query embeddings, see config.CassettesConfig
+type Cassette struct {
+	Queries []RecordedQuery `json:"queries"`
+}
+
+// RecordedQuery is one query and the embedding eulix_embed gave it
+type RecordedQuery struct {
+	Model     string    `json:"model"`
+	Query     string    `json:"query"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// Recorder embeds queries through a cassette, recording what eulix_embed
+// returns or replaying it without running eulix_embed at all
+type Recorder struct {
+	mu       sync.Mutex
+	mode     string
+	path     string
+	model    string
+	embed    func(string) ([]float32, error)
+	cassette Cassette
+}
+
+// Tapedeck puts a cassette in front of embed, the embedder of model, in the
+// [cassettes] mode of cfg. Without a cassette file replay has nothing
+// recorded, as when eulix_embed failed while recording.
+func Tapedeck(cfg config.CassettesConfig, model string, embed func(string) ([]float32, error)) (*Recorder, error) {
+	if cfg.Mode != config.CassetteRecord && cfg.Mode != config.CassetteReplay {
+		return nil, fmt.Errorf("%w: [cassettes] mode must be off, record or replay, not %q", fault.ErrConfig, cfg.Mode)
+	}
+
+	r := &Recorder{mode: cfg.Mode, path: cfg.Path("embed"), model: model, embed: embed}
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", r.path, err)
+	}
+	return r, nil
+}
+
+// EmbedQueryBinary embeds a query from the cassette when replaying,
+// otherwise with eulix_embed, recording the result
+func (r *Recorder) EmbedQueryBinary(query string) ([]float32, error) {
+	r.mu.Lock()
+	i := r.find(query)
+	r.mu.Unlock()
+
+	if r.mode == config.CassetteReplay {
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s (query %q)", ErrNotRecorded, r.path, query)
+		}
+		return r.cassette.Queries[i].Embedding, nil
+	}
+
+	embedding, err := r.embed(query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recorded := RecordedQuery{Model: r.model, Query: query, Embedding: embedding}
+	if i = r.find(query); i >= 0 {
+		r.cassette.Queries[i] = recorded
+	} else {
+		r.cassette.Queries = append(r.cassette.Queries, recorded)
+	}
+	if err := r.save(); err != nil {
+		return embedding, fmt.Errorf("failed to record to %s: %w", r.path, err)
+	}
+	return embedding, nil
+}
+
+// find is the index of query for the model, -1 when it isn't recorded
+func (r *Recorder) find(query string) int {
+	for i, q := range r.cassette.Queries {
+		if q.Query == query && q.Model == r.model {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Recorder) save() error {
+	data, err := json.Marshal(r.cassette)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
@@ -0,0 +1,42 @@
+package embeddings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"eulix/internal/config"
+)
+
+func TestTapedeckReplaysExactly(t *testing.T) {
+	cfg := config.CassettesConfig{Mode: config.CassetteRecord, Dir: t.TempDir()}
+	want := []float32{0.1, -0.33333334, 1e-7, 0.70710677}
+	live := func(string) ([]float32, error) { return want, nil }
+
+	recording, err := Tapedeck(cfg, "bge", live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recording.EmbedQueryBinary("who calls CreateOrder"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Mode = config.CassetteReplay
+	broken := func(string) ([]float32, error) { return nil, errors.New("eulix_embed must not run") }
+	replaying, err := Tapedeck(cfg, "bge", broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := replaying.EmbedQueryBinary("who calls CreateOrder")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed %v, %v, want %v", got, err, want)
+	}
+	if _, err := replaying.EmbedQueryBinary("where is main"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("unrecorded query: err = %v, want ErrNotRecorded", err)
+	}
+
+	other, _ := Tapedeck(cfg, "another-model", broken)
+	if _, err := other.EmbedQueryBinary("who calls CreateOrder"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("query recorded for another model was replayed: %v", err)
+	}
+}
@@ -2,14 +2,23 @@ package embeddings
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"math"
 	"os/exec"
+	"time"
 	// "unsafe"
+
+	"eulix/internal/child"
+	"eulix/internal/fault"
 )
 
+// queryLimits bounds embedding a single query. The first run downloads the
+// model, so it is generous.
+var queryLimits = child.Limits{Timeout: 10 * time.Minute}
+
 // Embedder wraps the Rust eulix_embed binary for embedding generation
 // This ensures consistent embeddings with the KB generation pipeline
 type Embedder struct {
@@ -85,7 +94,7 @@ func (e *Embedder) EmbedQuery(query string) ([]float32, error) {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := child.Run(context.Background(), cmd, queryLimits); err != nil {
 		return nil, fmt.Errorf("eulix_embed failed: %w\nstderr: %s", err, stderr.String())
 	}
 
@@ -116,7 +125,7 @@ func (e *Embedder) EmbedQueryBinary(query string) ([]float32, error) {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := child.Run(context.Background(), cmd, queryLimits); err != nil {
 		return nil, fmt.Errorf("eulix_embed failed: %w\nstderr: %s", err, stderr.String())
 	}
 
@@ -193,7 +202,7 @@ func findEulixBinary() (string, error) {
 		return string(bytes.TrimSpace(output)), nil
 	}
 
-	return "", fmt.Errorf("eulix_embed binary not found in any common location")
+	return "", fmt.Errorf("%w: eulix_embed isn't in any common location", fault.ErrBinaryMissing)
 }
 
 // CosineSimilarity calculates cosine similarity between two vectors
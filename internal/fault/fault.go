@@ -0,0 +1,96 @@
+// Package fault names the failures callers branch on. Errors wrap one of
+// the sentinels here with %w, Code and ExitCode read it back for JSON
+// output, telemetry and the exit status of eulix.
+package fault
+
+import "errors"
+
+var (
+	// ErrNotInitialized is a directory without .eulix, see eulix init
+	ErrNotInitialized = errors.New("eulix is not initialized in this directory")
+	// ErrKBMissing is a .eulix without the knowledge base, or part of it
+	ErrKBMissing = errors.New("knowledge base not found")
+	// ErrKBStale is a knowledge base this build can't read as is
+	ErrKBStale = errors.New("knowledge base is out of date")
+	// ErrProviderUnavailable is an LLM provider that couldn't be reached or
+	// is overloaded, asking again later may work
+	ErrProviderUnavailable = errors.New("LLM provider unavailable")
+	// ErrBinaryMissing is eulix_parser or eulix_embed not being where it
+	// was looked for, see eulix install-tools
+	ErrBinaryMissing = errors.New("required binary not found")
+	// ErrConfig is a setting in eulix.toml that can't be used
+	ErrConfig = errors.New("invalid config")
+)
+
+// Codes of the sentinels, stable for scripts and JSON
+const (
+	CodeNotInitialized      = "not_initialized"
+	CodeKBMissing           = "kb_missing"
+	CodeKBStale             = "kb_stale"
+	CodeProviderUnavailable = "provider_unavailable"
+	CodeBinaryMissing       = "binary_missing"
+	CodeConfig              = "config"
+)
+
+// Exit statuses of eulix, the same for every command. eulix ci and eulix
+// bench keep their own, documented in their help.
+const (
+	ExitOK       = 0
+	ExitFailure  = 1 // anything without a sentinel
+	ExitConfig   = 2
+	ExitKB       = 3 // not initialized, KB missing or stale
+	ExitProvider = 4
+	ExitBinary   = 5
+	ExitUsage    = 64 // bad flags or arguments, as in sysexits.h
+	ExitCrash    = 70 // a panic, see package crash
+)
+
+var codes = []struct {
+	err  error
+	code string
+	exit int
+}{
+	{ErrNotInitialized, CodeNotInitialized, ExitKB},
+	{ErrKBMissing, CodeKBMissing, ExitKB},
+	{ErrKBStale, CodeKBStale, ExitKB},
+	{ErrProviderUnavailable, CodeProviderUnavailable, ExitProvider},
+	{ErrBinaryMissing, CodeBinaryMissing, ExitBinary},
+	{ErrConfig, CodeConfig, ExitConfig},
+}
+
+// Code is the code of the sentinel err wraps, "" for any other error
+func Code(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// ExitCode is the exit status for err, ExitOK for nil
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			return c.exit
+		}
+	}
+	return ExitFailure
+}
+
+// FromCode is the sentinel of a code, nil for an unknown one. Clients of
+// the daemon use it to get back the error the daemon ran into.
+func FromCode(code string) error {
+	for _, c := range codes {
+		if c.code == code {
+			return c.err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestCodeThroughWrapping(t *testing.T) {
+	err := fmt.Errorf("daemon: %w", fmt.Errorf("%w, run 'eulix analyze' first: %w", ErrKBMissing, fs.ErrNotExist))
+	if got := Code(err); got != CodeKBMissing {
+		t.Errorf("Code = %q, want %q", got, CodeKBMissing)
+	}
+	if got := Code(errors.New("something else")); got != "" {
+		t.Errorf("Code of a plain error = %q", got)
+	}
+}
+
+func TestFromCodeRoundTrip(t *testing.T) {
+	for _, c := range codes {
+		if got := Code(FromCode(c.code)); got != c.code {
+			t.Errorf("FromCode(%q) comes back as %q", c.code, got)
+		}
+	}
+	if FromCode("nonsense") != nil {
+		t.Error("unknown code has a sentinel")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("plain"), ExitFailure},
+		{fmt.Errorf("load: %w", ErrConfig), ExitConfig},
+		{fmt.Errorf("%w, schema v1", ErrKBStale), ExitKB},
+		{fmt.Errorf("%w: dial tcp", ErrProviderUnavailable), ExitProvider},
+		{fmt.Errorf("%w: eulix_embed", ErrBinaryMissing), ExitBinary},
+	}
+	for _, c := range cases {
+		if got := ExitCode(c.err); got != c.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", c.err, got, c.want)
+		}
+	}
+}
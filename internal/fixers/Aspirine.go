@@ -1,12 +1,11 @@
 package fixers
 
 import (
-	"encoding/binary"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"unsafe"
+
+	"eulix/internal/chunkstore"
 )
 
 // AspirineOptions holds configuration for the Aspirine rebuild process
@@ -29,234 +28,149 @@ func Aspirine(eulixDir string, opts AspirineOptions) error {
 		return fmt.Errorf("directory not found: %s", eulixDir)
 	}
 
-	embJsonPath := filepath.Join(eulixDir, "embeddings.json")
-	embBinPath := filepath.Join(eulixDir, "embeddings.bin")
-
-	fmt.Println("🔧 Rebuilding embeddings.bin from embeddings.json")
-	fmt.Println("==================================================\n")
+	fmt.Println("🔧 Rebuilding vectors.bin from the chunk store")
+	fmt.Println("==================================================")
+	fmt.Println()
 
-	// 1. Load embeddings.json
-	fmt.Println("1. Loading embeddings.json...")
-	data, err := os.ReadFile(embJsonPath)
-	if err != nil {
-		fmt.Printf("❌ Failed to read embeddings.json: %v\n", err)
-		fmt.Println("\n💡 Make sure embeddings.json exists in the .eulix directory")
-		return fmt.Errorf("failed to read embeddings.json: %w", err)
+	// Old knowledge bases only have embeddings.json, split it first
+	if chunkstore.HasLegacy(eulixDir) {
+		fmt.Println("0. Found embeddings.json, splitting it into chunks.json and vectors.bin...")
+		if !opts.NoBackup {
+			backupFiles(eulixDir, chunkstore.LegacyFiles)
+		}
+		if err := chunkstore.Split(eulixDir); err != nil {
+			fmt.Printf("❌ Failed to split embeddings.json: %v\n", err)
+			return fmt.Errorf("failed to split embeddings.json: %w", err)
+		}
+		fmt.Println("✅ Split done")
+		fmt.Println()
 	}
 
-	var embFile EmbeddingsFile
-	if err := json.Unmarshal(data, &embFile); err != nil {
-		fmt.Printf("❌ Failed to parse embeddings.json: %v\n", err)
-		fmt.Println("\n💡 The JSON file may be corrupted. Try regenerating it with:")
+	// 1. Load chunks.json
+	fmt.Println("1. Loading chunks.json...")
+	store, err := chunkstore.Load(eulixDir)
+	if err != nil {
+		fmt.Printf("❌ Failed to read chunks.json: %v\n", err)
+		fmt.Println("\n💡 Regenerate it with:")
 		fmt.Println("   eulix analyze")
-		return fmt.Errorf("failed to parse embeddings.json: %w", err)
+		return fmt.Errorf("failed to read chunks.json: %w", err)
 	}
 
-	fmt.Printf("✅ Loaded embeddings metadata\n")
-	fmt.Printf("   Model: %s\n", embFile.Model)
-	fmt.Printf("   Dimension: %d\n", embFile.Dimension)
-	fmt.Printf("   Total Chunks: %d\n", embFile.TotalChunks)
-	fmt.Printf("   Actual Embeddings: %d\n", len(embFile.Embeddings))
+	fmt.Printf("✅ Loaded chunk store\n")
+	fmt.Printf("   Model: %s\n", store.Model)
+	fmt.Printf("   Dimension: %d\n", store.Dimension)
+	fmt.Printf("   Total Chunks: %d\n", len(store.Chunks))
 
-	// 2. Validate embeddings
-	fmt.Println("\n2. Validating embeddings...")
-	if len(embFile.Embeddings) == 0 {
-		fmt.Println("❌ No embeddings found in JSON file")
+	if len(store.Chunks) == 0 {
+		fmt.Println("❌ No chunks found")
 		fmt.Println("\n💡 Regenerate embeddings with:")
 		fmt.Println("   eulix analyze")
-		return fmt.Errorf("no embeddings found")
+		return fmt.Errorf("no chunks found")
 	}
 
-	if embFile.Dimension <= 0 {
-		fmt.Println("❌ Invalid dimension in metadata")
-		return fmt.Errorf("invalid dimension: %d", embFile.Dimension)
+	// 2. Load vectors.bin
+	fmt.Println("\n2. Loading vectors.bin...")
+	vectors, err := chunkstore.LoadVectors(eulixDir)
+	if err != nil {
+		fmt.Printf("❌ Failed to read vectors.bin: %v\n", err)
+		fmt.Println("\n💡 Vectors can't be recovered from chunks.json. Regenerate them with:")
+		fmt.Println("   eulix analyze")
+		return fmt.Errorf("failed to read vectors.bin: %w", err)
 	}
+	fmt.Printf("✅ Loaded %d vectors (%d dimensions)\n", len(vectors.ByID), vectors.Dimension)
 
-	// Check for dimension mismatches (common issue)
-	if embFile.Dimension < 100 {
-		fmt.Printf("⚠️  WARNING: Dimension %d seems suspiciously low\n", embFile.Dimension)
-		fmt.Println("   Most embedding models use 384, 768, or 1536 dimensions")
+	// 3. Validate vectors against chunks
+	fmt.Println("\n3. Matching vectors to chunks by ID...")
+	if vectors.Dimension != store.Dimension {
+		fmt.Printf("⚠️  WARNING: vectors.bin has %d dimensions but chunks.json says %d\n",
+			vectors.Dimension, store.Dimension)
 		if !opts.Force {
 			fmt.Println("   Use --force flag to continue anyway")
-			return fmt.Errorf("dimension too low: %d", embFile.Dimension)
+			return fmt.Errorf("dimension mismatch: %d vs %d", vectors.Dimension, store.Dimension)
 		}
 	}
 
-	// Check total chunks mismatch
-	if embFile.TotalChunks != len(embFile.Embeddings) {
-		fmt.Printf("⚠️  WARNING: Metadata says %d chunks but found %d embeddings\n",
-			embFile.TotalChunks, len(embFile.Embeddings))
-		if !opts.Force {
-			fmt.Println("   Use --force flag to continue anyway")
-			return fmt.Errorf("chunk count mismatch")
+	var orphans []string
+	for id := range vectors.ByID {
+		if _, ok := store.Chunks[id]; !ok {
+			orphans = append(orphans, id)
 		}
 	}
 
-	// Check each embedding
-	invalidCount := 0
-	wrongDimCount := 0
-	var firstInvalid string
-
-	for i, chunk := range embFile.Embeddings {
-		if len(chunk.Embedding) == 0 {
-			if invalidCount == 0 {
-				firstInvalid = chunk.ID
+	missing := 0
+	var firstMissing string
+	for _, chunk := range store.Sorted() {
+		if _, ok := vectors.ByID[chunk.ID]; !ok {
+			if missing == 0 {
+				firstMissing = chunk.ID
 			}
-			invalidCount++
-		} else if len(chunk.Embedding) != embFile.Dimension {
-			if wrongDimCount == 0 {
-				fmt.Printf("⚠️  Chunk %d (%s) has wrong dimension: %d (expected %d)\n",
-					i, chunk.ID, len(chunk.Embedding), embFile.Dimension)
-			}
-			wrongDimCount++
+			missing++
 		}
 	}
 
-	if invalidCount > 0 {
-		fmt.Printf("❌ Found %d embeddings with no vectors (first: %s)\n",
-			invalidCount, firstInvalid)
-		fmt.Println("\n💡 The embeddings file is incomplete. Regenerate it with:")
-		fmt.Println("   eulix analyze")
-		return fmt.Errorf("found %d invalid embeddings", invalidCount)
+	if missing > 0 {
+		fmt.Printf("⚠️  %d chunks have no vector (first: %s), they only match by keyword\n",
+			missing, firstMissing)
+		fmt.Println("   Run 'eulix analyze' to embed them")
 	}
 
-	if wrongDimCount > 0 {
-		fmt.Printf("❌ Found %d embeddings with wrong dimensions\n", wrongDimCount)
-		if !opts.Force {
-			fmt.Println("   Use --force flag to continue anyway")
-			return fmt.Errorf("found %d embeddings with wrong dimensions", wrongDimCount)
-		}
+	if len(orphans) == 0 {
+		fmt.Println("✅ No orphaned vectors, nothing to rebuild")
+		return nil
 	}
+	fmt.Printf("⚠️  %d vectors have no chunk and will be dropped\n", len(orphans))
 
-	fmt.Printf("✅ All %d embeddings are valid (%d dimensions)\n",
-		len(embFile.Embeddings), embFile.Dimension)
-
-	// 3. Backup old embeddings.bin
+	// 4. Backup old vectors.bin
 	if !opts.NoBackup {
-		fmt.Println("\n3. Backing up old embeddings.bin...")
-		if _, err := os.Stat(embBinPath); err == nil {
-			timestamp := fmt.Sprintf("%d", os.Getpid())
-			backupPath := fmt.Sprintf("%s.backup.%s", embBinPath, timestamp)
-			if err := os.Rename(embBinPath, backupPath); err != nil {
-				fmt.Printf("⚠️  Failed to backup: %v\n", err)
-			} else {
-				fmt.Printf("✅ Backed up to: %s\n", backupPath)
-			}
-		} else {
-			fmt.Println("ℹ️  No existing embeddings.bin found (will create new)")
-		}
+		fmt.Println("\n4. Backing up old vectors.bin...")
+		backupFiles(eulixDir, []string{chunkstore.VectorsFile})
 	} else {
-		fmt.Println("\n3. Skipping backup (--no-backup flag set)")
+		fmt.Println("\n4. Skipping backup (--no-backup flag set)")
 	}
 
-	// 4. Write new embeddings.bin
-	fmt.Println("\n4. Writing new embeddings.bin...")
-	file, err := os.Create(embBinPath)
-	if err != nil {
-		fmt.Printf("❌ Failed to create file: %v\n", err)
-		return fmt.Errorf("failed to create file: %w", err)
+	// 5. Write new vectors.bin
+	fmt.Println("\n5. Writing new vectors.bin...")
+	for _, id := range orphans {
+		delete(vectors.ByID, id)
 	}
-	defer file.Close()
-
-	// Write header: num_embeddings (uint32) + dimension (uint32)
-	numEmbeddings := uint32(len(embFile.Embeddings))
-	dimension := uint32(embFile.Dimension)
-
-	headerBuf := make([]byte, 8)
-	binary.LittleEndian.PutUint32(headerBuf[0:4], numEmbeddings)
-	binary.LittleEndian.PutUint32(headerBuf[4:8], dimension)
-
-	if _, err := file.Write(headerBuf); err != nil {
-		fmt.Printf("❌ Failed to write header: %v\n", err)
-		return fmt.Errorf("failed to write header: %w", err)
+	if err := vectors.Save(eulixDir); err != nil {
+		fmt.Printf("❌ Failed to write vectors.bin: %v\n", err)
+		return fmt.Errorf("failed to write vectors.bin: %w", err)
 	}
 
-	fmt.Printf("✅ Wrote header: %d embeddings × %d dimensions\n",
-		numEmbeddings, dimension)
-
-	// Write each embedding vector
-	vectorBuf := make([]byte, 4) // float32 = 4 bytes
-	totalFloats := 0
-
-	fmt.Println("   Writing vectors...")
-	for i, chunk := range embFile.Embeddings {
-		for _, val := range chunk.Embedding {
-			binary.LittleEndian.PutUint32(vectorBuf, floatToUint32(val))
-			if _, err := file.Write(vectorBuf); err != nil {
-				fmt.Printf("❌ Failed to write embedding %d: %v\n", i, err)
-				return fmt.Errorf("failed to write embedding %d: %w", i, err)
-			}
-			totalFloats++
-		}
-
-		// Progress indicator
-		if (i+1)%100 == 0 || i == len(embFile.Embeddings)-1 {
-			percent := float64(i+1) / float64(numEmbeddings) * 100
-			fmt.Printf("   Progress: %d/%d (%.1f%%)\n", i+1, numEmbeddings, percent)
-		}
-	}
-
-	fmt.Printf("✅ Wrote %d total floats (%d embeddings × %d dims)\n",
-		totalFloats, numEmbeddings, dimension)
-
-	// 5. Verify the new file
-	fmt.Println("\n5. Verifying new embeddings.bin...")
-	info, err := os.Stat(embBinPath)
-	if err != nil {
-		fmt.Printf("❌ Failed to stat file: %v\n", err)
-		return fmt.Errorf("failed to stat file: %w", err)
-	}
-
-	expectedSize := 8 + (int(numEmbeddings) * int(dimension) * 4) // header + floats
-	actualSize := int(info.Size())
-
-	fmt.Printf("   Expected size: %d bytes (%.2f MB)\n",
-		expectedSize, float64(expectedSize)/(1024*1024))
-	fmt.Printf("   Actual size: %d bytes (%.2f MB)\n",
-		actualSize, float64(actualSize)/(1024*1024))
-
-	if actualSize != expectedSize {
-		fmt.Printf("❌ Size mismatch! File may be corrupted.\n")
-		fmt.Printf("   Difference: %d bytes\n", actualSize-expectedSize)
-		return fmt.Errorf("size mismatch: expected %d, got %d", expectedSize, actualSize)
-	}
-
-	// Read back and verify header
-	verifyData, err := os.ReadFile(embBinPath)
-	if err != nil {
-		fmt.Printf("❌ Failed to read back file: %v\n", err)
-		return fmt.Errorf("failed to verify file: %w", err)
-	}
-
-	verifyNumEmb := binary.LittleEndian.Uint32(verifyData[0:4])
-	verifyDim := binary.LittleEndian.Uint32(verifyData[4:8])
-
-	if verifyNumEmb != numEmbeddings || verifyDim != dimension {
-		fmt.Printf("❌ Header verification failed!\n")
-		fmt.Printf("   Expected: %d × %d\n", numEmbeddings, dimension)
-		fmt.Printf("   Got: %d × %d\n", verifyNumEmb, verifyDim)
-		return fmt.Errorf("header verification failed")
-	}
-
-	fmt.Printf("✅ Header verified: %d embeddings × %d dimensions\n",
-		verifyNumEmb, verifyDim)
-
 	// 6. Summary
-	sizeMB := float64(actualSize) / (1024 * 1024)
+	vecPath := filepath.Join(eulixDir, chunkstore.VectorsFile)
+	var sizeMB float64
+	if info, err := os.Stat(vecPath); err == nil {
+		sizeMB = float64(info.Size()) / (1024 * 1024)
+	}
 	fmt.Printf("\n════════════════════════════════════════\n")
-	fmt.Printf("✅ Successfully rebuilt embeddings.bin!\n")
+	fmt.Printf("✅ Successfully rebuilt vectors.bin!\n")
 	fmt.Printf("════════════════════════════════════════\n")
-	fmt.Printf("Location:   %s\n", embBinPath)
+	fmt.Printf("Location:   %s\n", vecPath)
 	fmt.Printf("Size:       %.2f MB\n", sizeMB)
-	fmt.Printf("Format:     %d embeddings × %d dimensions\n", numEmbeddings, dimension)
-	fmt.Printf("Model:      %s\n", embFile.Model)
+	fmt.Printf("Format:     %d vectors × %d dimensions\n", len(vectors.ByID), vectors.Dimension)
+	fmt.Printf("Model:      %s\n", store.Model)
 	fmt.Printf("════════════════════════════════════════\n")
-	fmt.Println("\n🎉 Your embeddings.bin is ready! Run 'eulix chat' to use it.")
+	fmt.Println("\n🎉 Your vectors.bin is ready! Run 'eulix chat' to use it.")
 
 	return nil
 }
 
-// Convert float32 to uint32 bits (for binary.LittleEndian.PutUint32)
-func floatToUint32(f float32) uint32 {
-	return *(*uint32)(unsafe.Pointer(&f))
+// backupFiles copies each file that exists to <name>.backup.<pid>
+func backupFiles(eulixDir string, names []string) {
+	for _, name := range names {
+		path := filepath.Join(eulixDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		backupPath := fmt.Sprintf("%s.backup.%d", path, os.Getpid())
+		if err := os.WriteFile(backupPath, data, 0644); err != nil {
+			fmt.Printf("⚠️  Failed to backup %s: %v\n", name, err)
+		} else {
+			fmt.Printf("✅ Backed up to: %s\n", backupPath)
+		}
+	}
 }
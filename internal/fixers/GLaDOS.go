@@ -1,12 +1,13 @@
 package fixers
 
 import (
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"eulix/internal/chunkstore"
 )
 
 // GLaDOS checks for knowledge base outputs and checks for embeddings size and other errors
@@ -23,6 +24,9 @@ func GLaDOS(eulixDir string) error {
 		return fmt.Errorf("directory not found: %s", eulixDir)
 	}
 
+	// Artifacts that failed to load, the report goes on without them
+	var broken []string
+
 	fmt.Println("🔍 KB Diagnostic Tool")
 	fmt.Println("================================")
 	fmt.Printf("Analyzing: %s\n\n", eulixDir)
@@ -33,6 +37,7 @@ func GLaDOS(eulixDir string) error {
 	kb, err := loadKB(kbPath)
 	if err != nil {
 		fmt.Printf("❌ Failed to load kb.json: %v\n", err)
+		broken = append(broken, "kb.json")
 	} else {
 		fmt.Printf("✅ Loaded KB for project: %s\n", kb.Metadata.ProjectName)
 		fmt.Printf("   Languages: %v\n", kb.Metadata.Languages)
@@ -51,31 +56,25 @@ func GLaDOS(eulixDir string) error {
 		fmt.Printf("     - Call graph edges: %d\n", len(kb.CallGraph.Edges))
 	}
 
-	// 2. Check embeddings.json
-	fmt.Println("\n2. Checking embeddings.json...")
-	embJsonPath := filepath.Join(eulixDir, "embeddings.json")
-	embFile, chunks, err := loadEmbeddingsJSON(embJsonPath)
+	// 2. Check chunks.json and vectors.bin
+	fmt.Println("\n2. Checking chunks.json...")
+	store, chunks, vectors, err := loadChunkStore(eulixDir)
 	if err != nil {
-		fmt.Printf("❌ Failed to load embeddings.json: %v\n", err)
+		fmt.Printf("❌ Failed to load chunks.json: %v\n", err)
+		broken = append(broken, "chunks.json")
 	} else {
-		fmt.Printf("✅ Loaded embeddings file\n")
-		fmt.Printf("   Model: %s\n", embFile.Model)
-		fmt.Printf("   Dimension: %d\n", embFile.Dimension)
-		fmt.Printf("   Total chunks: %d\n", embFile.TotalChunks)
-		fmt.Printf("   Actual embeddings: %d\n", len(chunks))
-
-		if embFile.TotalChunks != len(chunks) {
-			fmt.Printf("   ⚠️  WARNING: total_chunks (%d) != actual count (%d)\n",
-				embFile.TotalChunks, len(chunks))
-		}
+		fmt.Printf("✅ Loaded chunk store\n")
+		fmt.Printf("   Model: %s\n", store.Model)
+		fmt.Printf("   Dimension: %d\n", store.Dimension)
+		fmt.Printf("   Total chunks: %d\n", len(chunks))
 
 		// Check if embeddings are present
 		hasVectors := false
 		if len(chunks) > 0 && len(chunks[0].Embedding) > 0 {
 			hasVectors = true
-			fmt.Printf("   ✅ Embeddings contain %d-dimensional vectors\n", len(chunks[0].Embedding))
+			fmt.Printf("   ✅ vectors.bin holds %d-dimensional vectors\n", len(chunks[0].Embedding))
 		} else {
-			fmt.Println("   ⚠️  No embedding vectors found in JSON")
+			fmt.Println("   ⚠️  No embedding vectors found for chunks")
 		}
 
 		// Show sample chunks
@@ -90,7 +89,7 @@ func GLaDOS(eulixDir string) error {
 			fmt.Printf("     Name: %s (complexity: %d)\n",
 				chunk.Metadata.Name, chunk.Metadata.Complexity)
 			fmt.Printf("     Content: %s...\n", truncate(chunk.Content, 80))
-			if hasVectors {
+			if hasVectors && len(chunk.Embedding) > 1 {
 				fmt.Printf("     Vector: [%.3f, %.3f, ...] (%d dims)\n",
 					chunk.Embedding[0], chunk.Embedding[1], len(chunk.Embedding))
 			}
@@ -141,33 +140,46 @@ func GLaDOS(eulixDir string) error {
 		}
 	}
 
-	// 6. Check embeddings.bin
-	fmt.Println("\n6. Checking embeddings.bin...")
-	embBinPath := filepath.Join(eulixDir, "embeddings.bin")
-	numEmb, dim, err := checkEmbeddingsBin(embBinPath)
-	if err != nil {
-		fmt.Printf("❌ Failed to load embeddings.bin: %v\n", err)
+	// 6. Check vectors.bin
+	fmt.Println("\n6. Checking vectors.bin...")
+	if vectors == nil {
+		fmt.Println("❌ Failed to load vectors.bin")
+		broken = append(broken, "vectors.bin")
 	} else {
 		fmt.Printf("✅ Loaded binary embeddings\n")
-		fmt.Printf("   Count: %d embeddings\n", numEmb)
-		fmt.Printf("   Dimension: %d\n", dim)
-
-		// Compare with JSON
-		if len(chunks) > 0 {
-			if numEmb != len(chunks) {
-				fmt.Printf("   ⚠️  WARNING: Binary has %d but JSON has %d embeddings\n",
-					numEmb, len(chunks))
-			} else {
-				fmt.Println("   ✅ Binary count matches JSON count")
+		fmt.Printf("   Count: %d embeddings\n", len(vectors.ByID))
+		fmt.Printf("   Dimension: %d\n", vectors.Dimension)
+
+		// Vectors and chunks are matched by ID, so count both sides of the join
+		orphans := 0
+		for id := range vectors.ByID {
+			if _, ok := store.Chunks[id]; !ok {
+				orphans++
 			}
-
-			if embFile != nil && dim != embFile.Dimension {
-				fmt.Printf("   ⚠️  WARNING: Binary dim (%d) != JSON dim (%d)\n",
-					dim, embFile.Dimension)
-			} else {
-				fmt.Println("   ✅ Dimensions match")
+		}
+		unembedded := 0
+		for _, chunk := range chunks {
+			if len(chunk.Embedding) == 0 {
+				unembedded++
 			}
 		}
+
+		if orphans > 0 {
+			fmt.Printf("   ⚠️  WARNING: %d vectors have no chunk (run 'eulix aspirine' to prune them)\n", orphans)
+		}
+		if unembedded > 0 {
+			fmt.Printf("   ⚠️  WARNING: %d chunks have no vector, they only match by keyword\n", unembedded)
+		}
+		if orphans == 0 && unembedded == 0 {
+			fmt.Println("   ✅ Every chunk has exactly one vector")
+		}
+
+		if vectors.Dimension != store.Dimension {
+			fmt.Printf("   ⚠️  WARNING: Binary dim (%d) != chunk store dim (%d)\n",
+				vectors.Dimension, store.Dimension)
+		} else {
+			fmt.Println("   ✅ Dimensions match")
+		}
 	}
 
 	// 7. Check kb_index.json
@@ -176,6 +188,7 @@ func GLaDOS(eulixDir string) error {
 	funcCount, typeCount, err := checkIndex(indexPath)
 	if err != nil {
 		fmt.Printf("❌ Failed to load kb_index.json: %v\n", err)
+		broken = append(broken, "kb_index.json")
 	} else {
 		fmt.Printf("✅ Loaded index\n")
 		fmt.Printf("   Functions: %d\n", funcCount)
@@ -184,7 +197,7 @@ func GLaDOS(eulixDir string) error {
 
 	// 8. File sizes
 	fmt.Println("\n8. File sizes:")
-	files := []string{"kb.json", "embeddings.json", "embeddings.bin", "kb_index.json", "kb_call_graph.json"}
+	files := []string{"kb.json", "chunks.json", "vectors.bin", "kb_index.json", "kb_call_graph.json"}
 	for _, file := range files {
 		path := filepath.Join(eulixDir, file)
 		if info, err := os.Stat(path); err == nil {
@@ -194,6 +207,10 @@ func GLaDOS(eulixDir string) error {
 			fmt.Printf("   %s: NOT FOUND\n", file)
 		}
 	}
+	if len(broken) > 0 {
+		fmt.Printf("\n❌ Diagnostic complete, %s failed to load\n", strings.Join(broken, ", "))
+		return fmt.Errorf("failed to load %s", strings.Join(broken, ", "))
+	}
 	fmt.Println("\n✅ Diagnostic complete!")
 
 	return nil
@@ -213,34 +230,34 @@ func loadKB(path string) (*KBFile, error) {
 	return &kb, nil
 }
 
-func loadEmbeddingsJSON(path string) (*EmbeddingsFile, []KBChunk, error) {
-	data, err := os.ReadFile(path)
+// loadChunkStore reads chunks.json and vectors.bin and joins them by ID.
+// vectors is nil when vectors.bin could not be read.
+func loadChunkStore(eulixDir string) (*chunkstore.Store, []KBChunk, *chunkstore.Vectors, error) {
+	store, err := chunkstore.Load(eulixDir)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	var embFile EmbeddingsFile
-	if err := json.Unmarshal(data, &embFile); err != nil {
-		return nil, nil, err
-	}
-
-	return &embFile, embFile.Embeddings, nil
-}
-
-func checkEmbeddingsBin(path string) (int, int, error) {
-	data, err := os.ReadFile(path)
+	vectors, err := chunkstore.LoadVectors(eulixDir)
 	if err != nil {
-		return 0, 0, err
+		vectors = nil
 	}
 
-	if len(data) < 8 {
-		return 0, 0, fmt.Errorf("invalid embeddings file: too short")
+	var chunks []KBChunk
+	for _, c := range store.Sorted() {
+		chunk := KBChunk{
+			ID:        c.ID,
+			ChunkType: c.ChunkType,
+			Content:   c.Content,
+			Metadata:  Metadata(c.Metadata),
+		}
+		if vectors != nil {
+			chunk.Embedding = vectors.ByID[c.ID]
+		}
+		chunks = append(chunks, chunk)
 	}
 
-	numEmbeddings := binary.LittleEndian.Uint32(data[0:4])
-	dimension := binary.LittleEndian.Uint32(data[4:8])
-
-	return int(numEmbeddings), int(dimension), nil
+	return store, chunks, vectors, nil
 }
 
 func checkIndex(path string) (int, int, error) {
@@ -0,0 +1,17 @@
+package fixers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGLaDOSReportsBrokenArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kb.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := GLaDOS(dir); err == nil {
+		t.Error("GLaDOS passed a .eulix with a corrupt kb.json and nothing else")
+	}
+}
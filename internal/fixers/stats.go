@@ -0,0 +1,192 @@
+package fixers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"eulix/internal/cache"
+)
+
+// KBStats is what GLaDOS looks at, counted up for a dashboard instead of
+// checked for problems
+type KBStats struct {
+	Project     string          `json:"project"`
+	ParsedAt    string          `json:"parsed_at,omitempty"`
+	Files       int             `json:"files"`
+	LOC         int             `json:"loc"`
+	Functions   int             `json:"functions"`
+	Classes     int             `json:"classes"`
+	Methods     int             `json:"methods"`
+	EntryPoints int             `json:"entry_points"`
+	Languages   []LanguageStats `json:"languages"`
+
+	Chunks     int            `json:"chunks"`
+	ChunkTypes map[string]int `json:"chunk_types"`
+	Embedded   int            `json:"embedded"` // chunks with a vector
+	Model      string         `json:"embedding_model,omitempty"`
+	Dimension  int            `json:"dimension,omitempty"`
+
+	IndexedFunctions int              `json:"indexed_functions"`
+	IndexedTypes     int              `json:"indexed_types"`
+	CallGraphNodes   int              `json:"call_graph_nodes"`
+	CallGraphEdges   int              `json:"call_graph_edges"`
+	Sizes            map[string]int64 `json:"sizes"` // bytes per file in .eulix, missing ones left out
+
+	Cache *cache.Usage `json:"cache,omitempty"` // nil with the cache off
+}
+
+// LanguageStats is the files and lines of one language
+type LanguageStats struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	LOC      int    `json:"loc"`
+}
+
+// statsFiles are the .eulix files whose size is reported
+var statsFiles = []string{"kb.json", "chunks.json", "vectors.bin", "kb_index.json", "kb_call_graph.json", "cache.db"}
+
+// Census counts up the knowledge base in eulixDir. Only a missing kb.json
+// is an error, without chunks the search figures are left at zero.
+func Census(eulixDir string) (*KBStats, error) {
+	kb, err := loadKB(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kb.json: %w", err)
+	}
+
+	s := &KBStats{
+		Project:          kb.Metadata.ProjectName,
+		ParsedAt:         kb.Metadata.ParsedAt,
+		Files:            kb.Metadata.TotalFiles,
+		LOC:              kb.Metadata.TotalLOC,
+		Functions:        kb.Metadata.TotalFunctions,
+		Classes:          kb.Metadata.TotalClasses,
+		Methods:          kb.Metadata.TotalMethods,
+		EntryPoints:      len(kb.EntryPoints),
+		ChunkTypes:       make(map[string]int),
+		IndexedFunctions: len(kb.Indices.FunctionsByName),
+		IndexedTypes:     len(kb.Indices.TypesByName),
+		CallGraphNodes:   len(kb.CallGraph.Nodes),
+		CallGraphEdges:   len(kb.CallGraph.Edges),
+		Sizes:            make(map[string]int64),
+	}
+
+	languages := make(map[string]*LanguageStats)
+	for _, file := range kb.Structure {
+		l, ok := languages[file.Language]
+		if !ok {
+			l = &LanguageStats{Language: file.Language}
+			languages[file.Language] = l
+		}
+		l.Files++
+		l.LOC += file.LOC
+	}
+	for _, l := range languages {
+		s.Languages = append(s.Languages, *l)
+	}
+	sort.Slice(s.Languages, func(i, j int) bool {
+		if s.Languages[i].LOC != s.Languages[j].LOC {
+			return s.Languages[i].LOC > s.Languages[j].LOC
+		}
+		return s.Languages[i].Language < s.Languages[j].Language
+	})
+
+	if store, chunks, _, err := loadChunkStore(eulixDir); err == nil {
+		s.Model = store.Model
+		s.Dimension = store.Dimension
+		s.Chunks = len(chunks)
+		for _, chunk := range chunks {
+			s.ChunkTypes[chunk.ChunkType]++
+			if len(chunk.Embedding) > 0 {
+				s.Embedded++
+			}
+		}
+	}
+
+	for _, file := range statsFiles {
+		if info, err := os.Stat(filepath.Join(eulixDir, file)); err == nil {
+			s.Sizes[file] = info.Size()
+		}
+	}
+	return s, nil
+}
+
+// Table renders the stats as aligned sections for the terminal
+func (s *KBStats) Table() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	section := func(title string) {
+		fmt.Fprintf(w, "\n%s\n", title)
+	}
+
+	fmt.Fprintf(w, "Knowledge base: %s\n", s.Project)
+	if s.ParsedAt != "" {
+		fmt.Fprintf(w, "Analyzed: %s\n", s.ParsedAt)
+	}
+
+	section("Code")
+	fmt.Fprintf(w, "  Files\t%d\n", s.Files)
+	fmt.Fprintf(w, "  Lines\t%d\n", s.LOC)
+	fmt.Fprintf(w, "  Functions\t%d\n", s.Functions)
+	fmt.Fprintf(w, "  Classes\t%d\n", s.Classes)
+	fmt.Fprintf(w, "  Methods\t%d\n", s.Methods)
+	fmt.Fprintf(w, "  Entry points\t%d\n", s.EntryPoints)
+
+	if len(s.Languages) > 0 {
+		section("Languages")
+		for _, l := range s.Languages {
+			fmt.Fprintf(w, "  %s\t%d files\t%d lines\t%s\n", l.Language, l.Files, l.LOC, percent(l.LOC, s.LOC))
+		}
+	}
+
+	section("Search")
+	fmt.Fprintf(w, "  Chunks\t%d\n", s.Chunks)
+	fmt.Fprintf(w, "  Embedded\t%d\t%s\n", s.Embedded, percent(s.Embedded, s.Chunks))
+	if s.Model != "" {
+		fmt.Fprintf(w, "  Model\t%s (%d dims)\n", s.Model, s.Dimension)
+	}
+	types := make([]string, 0, len(s.ChunkTypes))
+	for t := range s.ChunkTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if s.ChunkTypes[types[i]] != s.ChunkTypes[types[j]] {
+			return s.ChunkTypes[types[i]] > s.ChunkTypes[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	for _, t := range types {
+		fmt.Fprintf(w, "  %s chunks\t%d\t%s\n", t, s.ChunkTypes[t], percent(s.ChunkTypes[t], s.Chunks))
+	}
+
+	section("Indexes")
+	fmt.Fprintf(w, "  Functions indexed\t%d\n", s.IndexedFunctions)
+	fmt.Fprintf(w, "  Types indexed\t%d\n", s.IndexedTypes)
+	fmt.Fprintf(w, "  Call graph\t%d nodes, %d edges\n", s.CallGraphNodes, s.CallGraphEdges)
+	for _, file := range statsFiles {
+		if size, ok := s.Sizes[file]; ok {
+			fmt.Fprintf(w, "  %s\t%.2f MB\n", file, float64(size)/(1024*1024))
+		}
+	}
+
+	section("Cache")
+	if s.Cache == nil {
+		fmt.Fprintf(w, "  off\n")
+	} else {
+		fmt.Fprintf(w, "  Entries\t%d (%d not expired)\n", s.Cache.Entries, s.Cache.Valid)
+		fmt.Fprintf(w, "  Hit rate\t%s of %d questions\n", percent(s.Cache.Hits, s.Cache.Asked), s.Cache.Asked)
+	}
+
+	w.Flush()
+	return strings.TrimPrefix(sb.String(), "\n")
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)/float64(total)*100)
+}
@@ -1,13 +1,6 @@
 package fixers
 
 // Types for fixer packages
-type EmbeddingsFile struct {
-	Model       string      `json:"model"`
-	Dimension   int         `json:"dimension"`
-	TotalChunks int         `json:"total_chunks"`
-	Embeddings  []KBChunk   `json:"embeddings"`
-}
-
 type KBChunk struct {
 	ID        string    `json:"id"`
 	ChunkType string    `json:"chunk_type"`
@@ -0,0 +1,187 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"eulix/internal/child"
+	"eulix/internal/config"
+)
+
+// Events hooks can subscribe to
+const (
+	PreQuery    = "pre-query"
+	PostAnswer  = "post-answer"
+	PostAnalyze = "post-analyze"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// outputGrace is how long a command's output is waited for after it exits,
+// something it started in the background may hold stdout open
+const outputGrace = time.Second
+
+// Payload is sent as JSON on stdin (commands) or as the request body (urls)
+type Payload struct {
+	Event    string                 `json:"event"`
+	Query    string                 `json:"query,omitempty"`
+	Answer   string                 `json:"answer,omitempty"`
+	Project  string                 `json:"project,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// reply is what a hook may print or return to rewrite the query or answer.
+// Empty fields leave things untouched.
+type reply struct {
+	Query  string `json:"query"`
+	Answer string `json:"answer"`
+}
+
+// Runner fires the hooks configured in eulix.toml. A nil Runner is a no-op.
+type Runner struct {
+	hooks      []config.HookConfig
+	httpClient *http.Client
+	localOnly  bool // url hooks are skipped, payloads stay on this machine
+}
+
+func Bellboy(hooks []config.HookConfig, localOnly bool) *Runner {
+	if len(hooks) == 0 {
+		return nil
+	}
+	return &Runner{
+		hooks:      hooks,
+		httpClient: &http.Client{},
+		localOnly:  localOnly,
+	}
+}
+
+// BeforeQuery runs pre-query hooks, which may rewrite the query
+func (r *Runner) BeforeQuery(query string) string {
+	if r == nil {
+		return query
+	}
+
+	for _, out := range r.fire(Payload{Event: PreQuery, Query: query}) {
+		if out.Query != "" {
+			query = out.Query
+		}
+	}
+	return query
+}
+
+// AfterAnswer runs post-answer hooks, which may rewrite the answer
+func (r *Runner) AfterAnswer(query, answer string) string {
+	if r == nil {
+		return answer
+	}
+
+	for _, out := range r.fire(Payload{Event: PostAnswer, Query: query, Answer: answer}) {
+		if out.Answer != "" {
+			answer = out.Answer
+		}
+	}
+	return answer
+}
+
+// AfterAnalyze runs post-analyze hooks, output is ignored
+func (r *Runner) AfterAnalyze(project string, metadata map[string]interface{}) {
+	if r == nil {
+		return
+	}
+	r.fire(Payload{Event: PostAnalyze, Project: project, Metadata: metadata})
+}
+
+// fire runs matching hooks in config order. Failures are reported on stderr
+// but never break the query or analyze that triggered them.
+func (r *Runner) fire(payload Payload) []reply {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+
+	var replies []reply
+	for _, h := range r.hooks {
+		if h.Event != payload.Event {
+			continue
+		}
+		if r.localOnly && h.Command == "" {
+			fmt.Fprintf(os.Stderr, "hook %s skipped: local-only mode keeps questions and answers on this machine\n", describe(h))
+			continue
+		}
+
+		out, err := r.run(h, body)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hook %s failed: %v\n", describe(h), err)
+			continue
+		}
+
+		var rep reply
+		if len(bytes.TrimSpace(out)) > 0 && json.Unmarshal(out, &rep) == nil {
+			replies = append(replies, rep)
+		}
+	}
+	return replies
+}
+
+func (r *Runner) run(h config.HookConfig, body []byte) ([]byte, error) {
+	timeout := defaultTimeout
+	if h.TimeoutSeconds > 0 {
+		timeout = time.Duration(h.TimeoutSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch {
+	case h.Command != "":
+		// The command runs in its own process group, a timeout stops it
+		// together with anything it started
+		var out bytes.Buffer
+		cmd := exec.Command("sh", "-c", h.Command)
+		cmd.Stdin = bytes.NewReader(body)
+		cmd.Stdout = &out
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), "EULIX_HOOK_EVENT="+h.Event)
+		cmd.WaitDelay = outputGrace
+		if err := child.Run(ctx, cmd, child.Limits{}); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+
+	case h.URL != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := r.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("%s returned %s", h.URL, resp.Status)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}
+
+	return nil, fmt.Errorf("hook has neither command nor url")
+}
+
+func describe(h config.HookConfig) string {
+	target := h.Command
+	if target == "" {
+		target = h.URL
+	}
+	return strings.TrimSpace(h.Event + " " + target)
+}
@@ -0,0 +1,59 @@
+package hooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"eulix/internal/config"
+)
+
+func TestCommandRewritesQuery(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+	r := Bellboy([]config.HookConfig{
+		{Event: PreQuery, Command: `echo '{"query": "rewritten"}'`},
+	}, false)
+	if got := r.BeforeQuery("original"); got != "rewritten" {
+		t.Errorf("BeforeQuery = %q, want rewritten", got)
+	}
+}
+
+func TestCommandTimeoutStopsChildren(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+	// The background sleep holds stdout open after sh is gone
+	r := Bellboy([]config.HookConfig{
+		{Event: PreQuery, Command: "sleep 30 & sleep 30", TimeoutSeconds: 1},
+	}, false)
+
+	start := time.Now()
+	if got := r.BeforeQuery("question"); got != "question" {
+		t.Errorf("BeforeQuery = %q, want the query untouched", got)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hook ran for %s past its 1s timeout", elapsed)
+	}
+}
+
+func TestLocalOnlySkipsURLHooks(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"answer": "from the hook"}`))
+	}))
+	defer srv.Close()
+	hooks := []config.HookConfig{{Event: PostAnswer, URL: srv.URL}}
+
+	if got := Bellboy(hooks, true).AfterAnswer("question", "answer"); got != "answer" || calls.Load() != 0 {
+		t.Errorf("local-only: answer = %q after %d calls, want the hook skipped", got, calls.Load())
+	}
+	if got := Bellboy(hooks, false).AfterAnswer("question", "answer"); got != "from the hook" || calls.Load() != 1 {
+		t.Errorf("answer = %q after %d calls, want the hook's answer", got, calls.Load())
+	}
+}
@@ -0,0 +1,169 @@
+package installer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the GitHub releases page the binaries are published to
+const DefaultBaseURL = "https://github.com/Nurysso/eulix/releases"
+
+// checksumsAsset lists "<sha256>  <asset>" for every file in a release
+const checksumsAsset = "checksums.txt"
+
+// Tools installed by Install
+var Tools = []string{"eulix_parser", "eulix_embed"}
+
+// Options controls Install
+type Options struct {
+	Version string // release tag, "latest" when empty
+	BaseURL string // DefaultBaseURL when empty, override for mirrors
+	Dir     string // install directory
+	Out     io.Writer
+}
+
+// Install downloads the companion binaries for this OS/arch, checks them
+// against the release checksums and installs them into opts.Dir
+func Install(opts Options) ([]string, error) {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	if opts.Version == "" {
+		opts.Version = "latest"
+	}
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", opts.Dir, err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+
+	fmt.Fprintf(out, "Fetching checksums for %s (%s/%s)...\n", opts.Version, runtime.GOOS, runtime.GOARCH)
+	sums, err := fetchChecksums(client, releaseURL(opts.BaseURL, opts.Version, checksumsAsset))
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []string
+	for _, tool := range Tools {
+		asset := AssetName(tool, runtime.GOOS, runtime.GOARCH)
+		want, ok := sums[asset]
+		if !ok {
+			return installed, fmt.Errorf("release %s has no %s, this platform may not be supported", opts.Version, asset)
+		}
+
+		fmt.Fprintf(out, "Downloading %s...\n", asset)
+		dest := filepath.Join(opts.Dir, binaryName(tool))
+		if err := download(client, releaseURL(opts.BaseURL, opts.Version, asset), dest, want); err != nil {
+			return installed, fmt.Errorf("%s: %w", tool, err)
+		}
+
+		if err := exec.Command(dest, "--help").Run(); err != nil {
+			return installed, fmt.Errorf("%s was installed but does not run: %w", tool, err)
+		}
+
+		fmt.Fprintf(out, "   ✓ %s\n", dest)
+		installed = append(installed, dest)
+	}
+
+	return installed, nil
+}
+
+// AssetName is the release file name for a tool, e.g. eulix_embed-linux-amd64
+func AssetName(tool, goos, goarch string) string {
+	name := fmt.Sprintf("%s-%s-%s", tool, goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func binaryName(tool string) string {
+	if runtime.GOOS == "windows" {
+		return tool + ".exe"
+	}
+	return tool
+}
+
+func releaseURL(base, version, asset string) string {
+	base = strings.TrimSuffix(base, "/")
+	if version == "latest" {
+		return fmt.Sprintf("%s/latest/download/%s", base, asset)
+	}
+	return fmt.Sprintf("%s/download/%s/%s", base, version, asset)
+}
+
+func fetchChecksums(client *http.Client, url string) (map[string]string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch checksums: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch checksums: %s returned %s", url, resp.Status)
+	}
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks binary mode with a leading '*'
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums, scanner.Err()
+}
+
+// download writes url to dest only if its sha256 matches want
+func download(client *http.Client, url, dest, want string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", want, got)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
@@ -0,0 +1,71 @@
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"eulix/internal/config"
+)
+
+// checkEvery is how often UpdateNotice asks for the latest release
+const checkEvery = 24 * time.Hour
+
+// checkTimeout is how long a command's exit waits for the check
+const checkTimeout = 1500 * time.Millisecond
+
+// updateCheck is what the last check found, kept in ~/.eulix/update-check.json
+type updateCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest,omitempty"`
+}
+
+// UpdateNotice says so when the last check found a release newer than
+// current, "" otherwise. A check older than a day is redone first, for at
+// most checkTimeout. EULIX_NO_UPDATE_CHECK and --local-only turn it off.
+func UpdateNotice(current string) string {
+	if v := os.Getenv("EULIX_NO_UPDATE_CHECK"); v != "" && v != "0" && v != "false" {
+		return ""
+	}
+	if config.LocalOnlyEnv() {
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return updateNotice(current, filepath.Join(home, ".eulix", "update-check.json"), DefaultBaseURL)
+}
+
+// updateNotice is UpdateNotice keeping its state in statePath and asking
+// baseURL
+func updateNotice(current, statePath, baseURL string) string {
+	var last updateCheck
+	if data, err := os.ReadFile(statePath); err == nil {
+		json.Unmarshal(data, &last)
+	}
+	if time.Since(last.CheckedAt) > checkEvery {
+		if latest, err := LatestVersion(baseURL, checkTimeout); err == nil {
+			last.Latest = latest
+		}
+		// A failing check waits a day like any other
+		last.CheckedAt = time.Now().UTC()
+		saveUpdateCheck(statePath, last)
+	}
+
+	if last.Latest == "" || !Newer(last.Latest, current) {
+		return ""
+	}
+	return fmt.Sprintf("eulix %s is out (you have %s), run 'eulix upgrade'", last.Latest, current)
+}
+
+func saveUpdateCheck(path string, check updateCheck) {
+	data, err := json.Marshal(check)
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,50 @@
+package installer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateNotice(t *testing.T) {
+	checks := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks++
+		http.Redirect(w, r, "/releases/tag/v0.9.0", http.StatusFound)
+	}))
+	defer srv.Close()
+	statePath := filepath.Join(t.TempDir(), "update-check.json")
+
+	// The first run checks and tells right away
+	if notice := updateNotice("0.8.0", statePath, srv.URL+"/releases"); notice == "" {
+		t.Error("no notice of v0.9.0")
+	}
+	var saved updateCheck
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Latest != "v0.9.0" || time.Since(saved.CheckedAt) > time.Minute {
+		t.Errorf("saved %+v, %v, want v0.9.0 checked now", saved, err)
+	}
+
+	// Within a day it doesn't ask again
+	if notice := updateNotice("0.9.0", statePath, srv.URL+"/releases"); notice != "" || checks != 1 {
+		t.Errorf("up to date: notice %q after %d checks, want none after 1", notice, checks)
+	}
+}
+
+func TestUpdateNoticeLocalOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("EULIX_LOCAL_ONLY", "1")
+	if notice := UpdateNotice("0.0.1"); notice != "" {
+		t.Errorf("notice %q under --local-only", notice)
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".eulix")); err == nil {
+		t.Error("checked for a release under --local-only")
+	}
+}
@@ -0,0 +1,159 @@
+package installer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Binary is the tool name of eulix itself in a release
+const Binary = "eulix"
+
+// UpgradeOptions controls Upgrade
+type UpgradeOptions struct {
+	Version string // release tag to install, the latest one when empty
+	BaseURL string // DefaultBaseURL when empty
+	Out     io.Writer
+}
+
+// LatestVersion is the tag the latest release points to, read from where
+// the releases page redirects .../latest
+func LatestVersion(baseURL string, timeout time.Duration) (string, error) {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	url := strings.TrimSuffix(baseURL, "/") + "/latest"
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to check for a new release: %w", err)
+	}
+	resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	if location == "" || !strings.Contains(location, "/tag/") {
+		return "", fmt.Errorf("failed to check for a new release: %s returned %s without a release tag", url, resp.Status)
+	}
+	return path.Base(location), nil
+}
+
+// Newer reports whether version a is newer than b. Both are dotted numbers
+// with an optional leading v, anything after a '-' or '+' is ignored.
+func Newer(a, b string) bool {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(field)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// Upgrade downloads the eulix of a release for this OS/arch, checks it
+// against the release checksums and that it runs, then puts it in place of
+// the running executable. It returns the version installed.
+func Upgrade(opts UpgradeOptions) (string, error) {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+	if opts.Version == "" {
+		latest, err := LatestVersion(opts.BaseURL, time.Minute)
+		if err != nil {
+			return "", err
+		}
+		opts.Version = latest
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the running eulix: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", fmt.Errorf("failed to find the running eulix: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+	fmt.Fprintf(out, "Fetching checksums for %s (%s/%s)...\n", opts.Version, runtime.GOOS, runtime.GOARCH)
+	sums, err := fetchChecksums(client, releaseURL(opts.BaseURL, opts.Version, checksumsAsset))
+	if err != nil {
+		return "", err
+	}
+	asset := AssetName(Binary, runtime.GOOS, runtime.GOARCH)
+	want, ok := sums[asset]
+	if !ok {
+		return "", fmt.Errorf("release %s has no %s, this platform may not be supported", opts.Version, asset)
+	}
+
+	fmt.Fprintf(out, "Downloading %s...\n", asset)
+	staged := exe + ".new"
+	if err := download(client, releaseURL(opts.BaseURL, opts.Version, asset), staged, want); err != nil {
+		return "", err
+	}
+	defer os.Remove(staged)
+	if err := exec.Command(staged, "--version").Run(); err != nil {
+		return "", fmt.Errorf("the downloaded eulix does not run, kept the current one: %w", err)
+	}
+
+	if err := replace(exe, staged); err != nil {
+		return "", fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	fmt.Fprintf(out, "   ✓ %s\n", exe)
+	return opts.Version, nil
+}
+
+// replace swaps staged in for exe. Windows can't overwrite a running
+// executable but can rename it, the old one is left as .old until the next
+// upgrade.
+func replace(exe, staged string) error {
+	if runtime.GOOS != "windows" {
+		return os.Rename(staged, exe)
+	}
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package installer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"v0.6.0", "0.5.3", true},
+		{"0.5.10", "0.5.9", true},
+		{"0.5.3", "v0.5.3", false},
+		{"0.5", "0.5.1", false},
+		{"1.0.0-rc1", "0.9.9", true},
+		{"0.5.2", "0.5.3", false},
+	}
+	for _, c := range cases {
+		if got := Newer(c.a, c.b); got != c.want {
+			t.Errorf("Newer(%q, %q) = %t, want %t", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/releases/tag/v0.7.1", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	got, err := LatestVersion(srv.URL+"/releases", time.Second)
+	if err != nil || got != "v0.7.1" {
+		t.Errorf("LatestVersion = %q, %v, want v0.7.1", got, err)
+	}
+	if _, err := LatestVersion(srv.URL+"/elsewhere", time.Second); err == nil {
+		t.Error("a page without a redirect to a tag gave a version")
+	}
+}
@@ -0,0 +1,103 @@
+package integrations
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"eulix/internal/config"
+)
+
+// Querier answers questions about one repo, usually a *query.Router
+type Querier interface {
+	Query(q string) (string, error)
+}
+
+// Opener builds a Querier for an analyzed repo on disk
+type Opener func(repo string) (Querier, error)
+
+// Bridge maps chat channels to repos and keeps one router per repo around
+type Bridge struct {
+	channels    map[string]config.ChannelMapping
+	defaultRepo string
+	open        Opener
+
+	mu      sync.Mutex
+	routers map[string]Querier
+
+	// routers are not safe for concurrent queries
+	queryMu sync.Mutex
+}
+
+func Switchboard(channels map[string]config.ChannelMapping, defaultRepo string, open Opener) *Bridge {
+	if defaultRepo == "" {
+		defaultRepo = "."
+	}
+
+	return &Bridge{
+		channels:    channels,
+		defaultRepo: defaultRepo,
+		open:        open,
+		routers:     make(map[string]Querier),
+	}
+}
+
+// Answer routes a question from a channel to the repo mapped to it.
+// The returned mapping carries the source URL used for links, if any.
+func (b *Bridge) Answer(channel, question string) (string, config.ChannelMapping, error) {
+	mapping, ok := b.channels[channel]
+	if !ok || mapping.Repo == "" {
+		mapping.Repo = b.defaultRepo
+	}
+
+	querier, err := b.querierFor(mapping.Repo)
+	if err != nil {
+		return "", mapping, err
+	}
+
+	b.queryMu.Lock()
+	answer, err := querier.Query(question)
+	b.queryMu.Unlock()
+	if err != nil {
+		return "", mapping, err
+	}
+
+	return answer, mapping, nil
+}
+
+func (b *Bridge) querierFor(repo string) (Querier, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q, ok := b.routers[repo]; ok {
+		return q, nil
+	}
+
+	q, err := b.open(repo)
+	if err != nil {
+		return nil, fmt.Errorf("repo %s: %w", repo, err)
+	}
+
+	b.routers[repo] = q
+	return q, nil
+}
+
+// file.go:42 or internal/cli/chat.go:120 style references in answers
+var sourceRefPattern = regexp.MustCompile(`([\w./-]+\.[A-Za-z]+):(\d+)`)
+
+// LinkSources rewrites file:line references into links using format(url, label).
+// Answers are returned unchanged when there is no source URL.
+func LinkSources(answer, sourceURL string, format func(url, label string) string) string {
+	if sourceURL == "" {
+		return answer
+	}
+	base := strings.TrimSuffix(sourceURL, "/")
+
+	return sourceRefPattern.ReplaceAllStringFunc(answer, func(ref string) string {
+		m := sourceRefPattern.FindStringSubmatch(ref)
+		path := strings.TrimPrefix(m[1], "./")
+		url := fmt.Sprintf("%s/%s#L%s", base, path, m[2])
+		return format(url, ref)
+	})
+}
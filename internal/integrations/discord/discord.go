@@ -0,0 +1,201 @@
+package discord
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"eulix/internal/integrations"
+)
+
+const apiBase = "https://discord.com/api/v10"
+
+// Discord caps message content at 2000 characters
+const maxContentLength = 2000
+
+// Interaction and response types we care about
+const (
+	interactionPing               = 1
+	interactionApplicationCommand = 2
+
+	responsePong                   = 1
+	responseChannelMessage         = 4
+	responseDeferredChannelMessage = 5
+)
+
+// Bot answers /eulix slash commands from a Discord interactions endpoint.
+// Commands are deferred and the answer is edited in once the router is done.
+type Bot struct {
+	publicKey  ed25519.PublicKey
+	bridge     *integrations.Bridge
+	httpClient *http.Client
+}
+
+type interaction struct {
+	Type          int    `json:"type"`
+	Token         string `json:"token"`
+	ApplicationID string `json:"application_id"`
+	ChannelID     string `json:"channel_id"`
+	Data          struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+type interactionResponse struct {
+	Type int                      `json:"type"`
+	Data *interactionResponseData `json:"data,omitempty"`
+}
+
+type interactionResponseData struct {
+	Content string `json:"content"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+// Ephemeral message flag
+const flagEphemeral = 1 << 6
+
+func Messenger(publicKeyHex string, bridge *integrations.Bridge) (*Bot, error) {
+	key, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid discord public key")
+	}
+
+	return &Bot{
+		publicKey:  ed25519.PublicKey(key),
+		bridge:     bridge,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// ServeHTTP handles the interactions endpoint
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !b.verify(r.Header, body) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+
+	var in interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+
+	switch in.Type {
+	case interactionPing:
+		writeJSON(w, interactionResponse{Type: responsePong})
+
+	case interactionApplicationCommand:
+		question := questionFrom(&in)
+		if question == "" {
+			writeJSON(w, interactionResponse{
+				Type: responseChannelMessage,
+				Data: &interactionResponseData{Content: "Usage: `/eulix question:<question about the codebase>`", Flags: flagEphemeral},
+			})
+			return
+		}
+
+		go b.answer(&in, question)
+		writeJSON(w, interactionResponse{Type: responseDeferredChannelMessage})
+
+	default:
+		http.Error(w, "unsupported interaction type", http.StatusBadRequest)
+	}
+}
+
+func (b *Bot) answer(in *interaction, question string) {
+	answer, mapping, err := b.bridge.Answer(in.ChannelID, question)
+
+	var content string
+	if err != nil {
+		content = fmt.Sprintf("Query failed: %v", err)
+	} else {
+		answer = integrations.LinkSources(answer, mapping.SourceURL, func(link, label string) string {
+			return fmt.Sprintf("[%s](<%s>)", label, link)
+		})
+		content = fmt.Sprintf("> %s\n\n%s", question, answer)
+	}
+
+	if runes := []rune(content); len(runes) > maxContentLength {
+		content = string(runes[:maxContentLength-3]) + "..."
+	}
+
+	if err := b.editOriginal(in, content); err != nil {
+		log.Printf("discord: failed to post answer: %v", err)
+	}
+}
+
+func (b *Bot) editOriginal(in *interaction, content string) error {
+	data, err := json.Marshal(interactionResponseData{Content: content})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/webhooks/%s/%s/messages/@original", apiBase, in.ApplicationID, in.Token)
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook edit returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (b *Bot) verify(h http.Header, body []byte) bool {
+	sig, err := hex.DecodeString(h.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+
+	msg := append([]byte(h.Get("X-Signature-Timestamp")), body...)
+	return ed25519.Verify(b.publicKey, msg, sig)
+}
+
+// questionFrom takes the "question" option, or the first string option
+func questionFrom(in *interaction) string {
+	for _, opt := range in.Data.Options {
+		var value string
+		if err := json.Unmarshal(opt.Value, &value); err != nil {
+			continue
+		}
+		if opt.Name == "question" || len(in.Data.Options) == 1 {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
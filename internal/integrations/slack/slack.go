@@ -0,0 +1,152 @@
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"eulix/internal/integrations"
+)
+
+// Slack rejects replays older than this
+const maxRequestAge = 5 * time.Minute
+
+// Bot answers /eulix slash commands. Slack wants an ack within 3 seconds,
+// so the answer is posted to the response_url once the router is done.
+type Bot struct {
+	signingSecret string
+	bridge        *integrations.Bridge
+	httpClient    *http.Client
+}
+
+type message struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func Messenger(signingSecret string, bridge *integrations.Bridge) *Bot {
+	return &Bot{
+		signingSecret: signingSecret,
+		bridge:        bridge,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ServeHTTP handles the slash command request
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if err := b.verify(r.Header, body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+
+	question := strings.TrimSpace(form.Get("text"))
+	if question == "" {
+		writeJSON(w, message{ResponseType: "ephemeral", Text: "Usage: `/eulix <question about the codebase>`"})
+		return
+	}
+
+	channel := form.Get("channel_id")
+	responseURL := form.Get("response_url")
+	user := form.Get("user_id")
+
+	go b.answer(channel, user, question, responseURL)
+
+	writeJSON(w, message{ResponseType: "ephemeral", Text: fmt.Sprintf("Looking into: _%s_", question)})
+}
+
+func (b *Bot) answer(channel, user, question, responseURL string) {
+	answer, mapping, err := b.bridge.Answer(channel, question)
+
+	var reply message
+	if err != nil {
+		reply = message{ResponseType: "ephemeral", Text: fmt.Sprintf("Query failed: %v", err)}
+	} else {
+		answer = integrations.LinkSources(answer, mapping.SourceURL, func(link, label string) string {
+			return fmt.Sprintf("<%s|%s>", link, label)
+		})
+		reply = message{
+			ResponseType: "in_channel",
+			Text:         fmt.Sprintf("<@%s> asked: _%s_\n\n%s", user, question, answer),
+		}
+	}
+
+	if err := b.post(responseURL, reply); err != nil {
+		log.Printf("slack: failed to post answer: %v", err)
+	}
+}
+
+func (b *Bot) post(responseURL string, msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.httpClient.Post(responseURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("response_url returned %s", resp.Status)
+	}
+	return nil
+}
+
+// verify checks the v0 request signature described in Slack's docs
+func (b *Bot) verify(h http.Header, body []byte) error {
+	if b.signingSecret == "" {
+		return fmt.Errorf("signing secret not configured")
+	}
+
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing request timestamp")
+	}
+	if age := time.Since(time.Unix(sec, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("stale request")
+	}
+
+	mac := hmac.New(sha256.New, []byte(b.signingSecret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(h.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
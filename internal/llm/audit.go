@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"eulix/internal/audit"
+)
+
+// fileLabel finds the files in a prompt from the way eulix labels them:
+// "File: x (Lines", "--- x (lines" in context windows and agent tool
+// results, "+++ b/x" in the diffs ci review sends
+var fileLabel = regexp.MustCompile(`(?m)^(?:File: (.+?) \(Lines \d|--- (.+?) \(lines \d|\+\+\+ b/(.+)$)`)
+
+// promptFiles lists the files included in a prompt, sorted
+func promptFiles(prompt string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, m := range fileLabel.FindAllStringSubmatch(prompt, -1) {
+		file := m[1] + m[2] + m[3]
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// record writes a prompt about to go to a hosted provider to the audit log.
+// The prompt is hashed as sent, after redaction.
+func (c *Client) record(provider, prompt string) error {
+	if c.audit == nil || !c.hosted() {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(prompt))
+	endpoint := ""
+	if c.config.LLM.Local {
+		endpoint = c.config.LLM.BaseURL
+	}
+	err := c.audit.Record(audit.Entry{
+		Time:     time.Now().UTC(),
+		Provider: provider,
+		Model:    c.modelName(),
+		Endpoint: endpoint,
+		Bytes:    len(prompt),
+		SHA256:   hex.EncodeToString(sum[:]),
+		Files:    promptFiles(prompt),
+	})
+	if err != nil {
+		// Nothing is sent that the log doesn't know about
+		return fmt.Errorf("failed to write the audit log, prompt not sent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Cassette is a recording of prompts and the responses they got, replayed
+// by the mock provider or in [cassettes] replay mode. A prompt is matched
+// exactly, by its SHA-256.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one prompt and its response
+type Interaction struct {
+	Key      string `json:"key"` // sha256 of the prompt
+	Model    string `json:"model,omitempty"`
+	Prompt   string `json:"prompt"`
+	Response string `json:"response"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	for i, in := range c.Interactions {
+		if in.Key == "" {
+			c.Interactions[i].Key = promptKey(in.Prompt)
+		}
+	}
+	return &c, nil
+}
+
+// Find returns the recorded response to prompt, the latest when it was
+// recorded more than once
+func (c *Cassette) Find(prompt string) (string, bool) {
+	key := promptKey(prompt)
+	for i := len(c.Interactions) - 1; i >= 0; i-- {
+		if c.Interactions[i].Key == key {
+			return c.Interactions[i].Response, true
+		}
+	}
+	return "", false
+}
+
+func promptKey(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Put records an interaction, replacing an earlier one for the same prompt
+func (c *Cassette) Put(in Interaction) {
+	in.Key = promptKey(in.Prompt)
+	for i := range c.Interactions {
+		if c.Interactions[i].Key == in.Key {
+			c.Interactions[i] = in
+			return
+		}
+	}
+	c.Interactions = append(c.Interactions, in)
+}
+
+// Save writes the cassette to path, creating its directory
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -1,21 +1,40 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
+	"eulix/internal/audit"
 	"eulix/internal/config"
+	"eulix/internal/fault"
 	"eulix/internal/types"
 )
 
 type Client struct {
 	config     *config.Config
 	httpClient *http.Client
+	responses  *responseCache // nil unless llm.response_cache is enabled
+	privacy    *privacy       // what hosted providers may see, see privacy.go
+	audit      *audit.Log     // nil unless llm.privacy.audit is enabled
+	provider   Provider       // nil sends to Anthropic or Ollama per the config
+	recorder   *recorder      // nil unless [cassettes] records or replays
+	maxTokens  int            // response length, 0 for llm.max_tokens, see SetMaxTokens
+	model      string         // "" for llm.model, see SetModel
 }
 
+// Provider generates the response to a prompt, handing it to onText piece
+// by piece when onText isn't nil. Anthropic and Ollama are built in, tests
+// swap in a fake with SetProvider.
+type Provider func(ctx context.Context, prompt string, onText func(string)) (string, error)
+
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
@@ -27,6 +46,7 @@ type AnthropicRequest struct {
 	Messages    []Message `json:"messages"`
 	MaxTokens   int       `json:"max_tokens"`
 	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
 }
 
 type AnthropicResponse struct {
@@ -36,6 +56,18 @@ type AnthropicResponse struct {
 	} `json:"content"`
 }
 
+// AnthropicEvent is one server-sent event of a streamed response
+type AnthropicEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
 // Ollama API structures
 type OllamaRequest struct {
 	Model       string    `json:"model"`
@@ -58,31 +90,195 @@ type OllamaResponse struct {
 
 // MouthClient cause thats what llm is used for to speak
 func MouthClient(cfg *config.Config) (*Client, error) {
-	return &Client{
+	if err := CheckProvider(cfg.LLM); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
 		config:     cfg,
 		httpClient: &http.Client{},
-	}, nil
+	}
+
+	if rc := cfg.LLM.ResponseCache; rc.Enabled {
+		c.responses = newResponseCache(rc.MaxEntries, time.Duration(rc.TTLMinutes)*time.Minute)
+	}
+
+	privacy, err := newPrivacy(cfg.LLM.Privacy)
+	if err != nil {
+		return nil, err
+	}
+	c.privacy = privacy
+
+	if cfg.LLM.Privacy.Audit {
+		dir := cfg.LLM.Privacy.AuditDir
+		if dir == "" {
+			dir = config.DefaultAuditDir
+		}
+		c.audit = audit.Scribe(dir)
+	}
+
+	if c.mocked() {
+		m, err := newMock(cfg.LLM.Mock)
+		if err != nil {
+			return nil, err
+		}
+		c.provider = m.generate
+	}
+
+	if cfg.Cassettes.Recording() {
+		r, err := newRecorder(cfg)
+		if err != nil {
+			return nil, err
+		}
+		c.recorder = r
+	}
+
+	return c, nil
+}
+
+// CheckProvider fails for an [llm] provider there is no client for. An empty
+// one sends to Anthropic, or to Ollama with local = true.
+func CheckProvider(cfg config.LLMConfig) error {
+	switch strings.ToLower(cfg.Provider) {
+	case "", "anthropic", "ollama", ProviderMock:
+		return nil
+	}
+	return fmt.Errorf("%w: [llm] provider %q is not supported, use anthropic, ollama or mock", fault.ErrConfig, cfg.Provider)
 }
 
-func (c *Client) Query(context *types.ContextWindow, userQuery string) (string, error) {
-	// Build prompt
-	prompt := c.buildPrompt(context, userQuery)
+// SetProvider sends prompts to p instead of the configured provider. The
+// response cache, privacy layer and audit log still apply.
+func (c *Client) SetProvider(p Provider) {
+	c.provider = p
+}
+
+// ErrInterrupted is returned when generation was stopped by cancelling its
+// context, together with the text generated until then
+var ErrInterrupted = errors.New("generation interrupted")
+
+func (c *Client) Query(window *types.ContextWindow, userQuery string) (string, error) {
+	return c.QueryStream(context.Background(), window, userQuery, nil)
+}
+
+// Complete sends a prompt as is, for callers that build their own instructions
+func (c *Client) Complete(prompt string) (string, error) {
+	return c.CompleteStream(context.Background(), prompt, nil)
+}
+
+// SetMaxTokens caps the following responses at n tokens, 0 or anything
+// over llm.max_tokens leaves it at llm.max_tokens
+func (c *Client) SetMaxTokens(n int) {
+	c.maxTokens = n
+}
+
+// SetModel sends the following prompts to model on the configured
+// provider, "" goes back to llm.model
+func (c *Client) SetModel(model string) {
+	c.model = model
+}
+
+func (c *Client) modelName() string {
+	if c.model != "" {
+		return c.model
+	}
+	return c.config.LLM.Model
+}
+
+func (c *Client) responseTokens() int {
+	if c.maxTokens > 0 && c.maxTokens < c.config.LLM.MaxTokens {
+		return c.maxTokens
+	}
+	return c.config.LLM.MaxTokens
+}
+
+// QueryStream is Query that hands the answer to onText piece by piece as it
+// is generated, onText may be nil. Cancelling ctx stops generation.
+func (c *Client) QueryStream(ctx context.Context, window *types.ContextWindow, userQuery string, onText func(string)) (string, error) {
+	// Build prompt, only the code in it is anonymized
+	prompt := c.buildPrompt(c.protectChunks(window), userQuery)
+
+	return c.send(ctx, c.protect(prompt, false), onText)
+}
+
+// CompleteStream is Complete with streaming and cancellation, see QueryStream.
+// A bare prompt has no code block of its own, so it is anonymized whole.
+func (c *Client) CompleteStream(ctx context.Context, prompt string, onText func(string)) (string, error) {
+	return c.send(ctx, c.protect(prompt, true), onText)
+}
+
+// send routes a prompt to the configured provider, answering from the
+// response cache when the exact same prompt was already sent. Interrupted
+// responses are returned as far as they got but never cached. Prompts have
+// passed the privacy layer already, those for hosted providers are written
+// to the audit log.
+func (c *Client) send(ctx context.Context, prompt string, onText func(string)) (string, error) {
+	if err := c.checkLocalOnly(); err != nil {
+		return "", err
+	}
+	onText, flush := c.restoreStream(onText)
+
+	provider := "anthropic"
+	if c.mocked() {
+		provider = ProviderMock
+	} else if c.config.LLM.Local {
+		provider = "ollama"
+	}
+
+	var key string
+	if c.responses != nil {
+		key = responseKey(provider, c.modelName(), prompt)
+		if response, ok := c.responses.get(key); ok {
+			if onText != nil {
+				onText(response)
+				flush()
+			}
+			return response, nil
+		}
+	}
+
+	if err := c.record(provider, prompt); err != nil {
+		return "", err
+	}
+
+	var response string
+	var err error
+	if c.recorder != nil {
+		response, err = c.recorder.play(ctx, prompt, onText, c.generate)
+	} else {
+		response, err = c.generate(ctx, prompt, onText)
+	}
+	flush()
+	response = c.restore(response)
+	if err != nil {
+		return response, err
+	}
+
+	if c.responses != nil {
+		c.responses.put(key, response)
+	}
+	return response, nil
+}
 
-	// Route to appropriate provider
+// generate asks the provider for the response to a prompt
+func (c *Client) generate(ctx context.Context, prompt string, onText func(string)) (string, error) {
+	if c.provider != nil {
+		return c.provider(ctx, prompt, onText)
+	}
 	if c.config.LLM.Local {
-		return c.queryOllama(prompt)
+		return c.queryOllama(ctx, prompt, onText)
 	}
-	return c.queryAnthropic(prompt)
+	return c.queryAnthropic(ctx, prompt, onText)
 }
 
-func (c *Client) queryAnthropic(prompt string) (string, error) {
+func (c *Client) queryAnthropic(ctx context.Context, prompt string, onText func(string)) (string, error) {
 	reqBody := AnthropicRequest{
-		Model: c.config.LLM.Model,
+		Model: c.modelName(),
 		Messages: []Message{
 			{Role: "user", Content: prompt},
 		},
-		MaxTokens:   c.config.LLM.MaxTokens,
+		MaxTokens:   c.responseTokens(),
 		Temperature: c.config.LLM.Temperature,
+		Stream:      onText != nil,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -90,7 +286,7 @@ func (c *Client) queryAnthropic(prompt string) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", err
 	}
@@ -101,18 +297,22 @@ func (c *Client) queryAnthropic(prompt string) (string, error) {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return interrupted(ctx, "", fmt.Errorf("%w: %w", fault.ErrProviderUnavailable, err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Anthropic API error %d: %s", resp.StatusCode, string(body))
+		return "", unavailable(resp.StatusCode, fmt.Errorf("Anthropic API error %d: %s", resp.StatusCode, string(body)))
+	}
+
+	if onText != nil {
+		return readAnthropicStream(ctx, resp.Body, onText)
 	}
 
 	var response AnthropicResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", err
+		return interrupted(ctx, "", err)
 	}
 
 	if len(response.Content) == 0 {
@@ -122,16 +322,16 @@ func (c *Client) queryAnthropic(prompt string) (string, error) {
 	return response.Content[0].Text, nil
 }
 
-func (c *Client) queryOllama(prompt string) (string, error) {
+func (c *Client) queryOllama(ctx context.Context, prompt string, onText func(string)) (string, error) {
 	reqBody := OllamaRequest{
-		Model: c.config.LLM.Model,
+		Model: c.modelName(),
 		Messages: []Message{
 			{Role: "user", Content: prompt},
 		},
-		Stream: false,
+		Stream: onText != nil,
 		Options: &OllamaOptions{
 			Temperature: c.config.LLM.Temperature,
-			NumPredict:  c.config.LLM.MaxTokens,
+			NumPredict:  c.responseTokens(),
 		},
 	}
 
@@ -146,7 +346,7 @@ func (c *Client) queryOllama(prompt string) (string, error) {
 		ollamaURL = c.config.LLM.BaseURL + "/api/chat"  // Changed from /api/generate
 	}
 
-	req, err := http.NewRequest("POST", ollamaURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", ollamaURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", err
 	}
@@ -155,18 +355,25 @@ func (c *Client) queryOllama(prompt string) (string, error) {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to Ollama: %w (make sure Ollama is running)", err)
+		if ctx.Err() != nil {
+			return "", ErrInterrupted
+		}
+		return "", fmt.Errorf("%w, failed to connect to Ollama: %w (make sure Ollama is running)", fault.ErrProviderUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Ollama API error %d: %s", resp.StatusCode, string(body))
+		return "", unavailable(resp.StatusCode, fmt.Errorf("Ollama API error %d: %s", resp.StatusCode, string(body)))
+	}
+
+	if onText != nil {
+		return readOllamaStream(ctx, resp.Body, onText)
 	}
 
 	var response OllamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", err
+		return interrupted(ctx, "", err)
 	}
 
 	if response.Message.Content == "" {
@@ -176,28 +383,115 @@ func (c *Client) queryOllama(prompt string) (string, error) {
 	return response.Message.Content, nil
 }
 
-func (c *Client) buildPrompt(context *types.ContextWindow, userQuery string) string {
+// readOllamaStream reads newline delimited JSON, one message piece per line
+func readOllamaStream(ctx context.Context, body io.Reader, onText func(string)) (string, error) {
+	var text strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var piece OllamaResponse
+		if err := json.Unmarshal(scanner.Bytes(), &piece); err != nil {
+			continue
+		}
+		if piece.Message.Content != "" {
+			text.WriteString(piece.Message.Content)
+			onText(piece.Message.Content)
+		}
+		if piece.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil || ctx.Err() != nil {
+		return interrupted(ctx, text.String(), err)
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("empty response from Ollama")
+	}
+	return text.String(), nil
+}
+
+// readAnthropicStream reads server-sent events, text arrives in
+// content_block_delta events
+func readAnthropicStream(ctx context.Context, body io.Reader, onText func(string)) (string, error) {
+	var text strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event AnthropicEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				text.WriteString(event.Delta.Text)
+				onText(event.Delta.Text)
+			}
+		case "error":
+			return text.String(), fmt.Errorf("Anthropic API error: %s", event.Error.Message)
+		}
+		if event.Type == "message_stop" {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil || ctx.Err() != nil {
+		return interrupted(ctx, text.String(), err)
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("empty response from Anthropic API")
+	}
+	return text.String(), nil
+}
+
+// interrupted reports a failed read as ErrInterrupted when it was caused by
+// cancelling ctx, keeping the partial text
+// unavailable marks an API error as fault.ErrProviderUnavailable when its
+// status says to try again later, rate limited or down
+func unavailable(status int, err error) error {
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return fmt.Errorf("%w: %w", fault.ErrProviderUnavailable, err)
+	}
+	return err
+}
+
+func interrupted(ctx context.Context, partial string, err error) (string, error) {
+	if ctx.Err() != nil {
+		return partial, ErrInterrupted
+	}
+	return partial, err
+}
+
+func (c *Client) buildPrompt(window *types.ContextWindow, userQuery string) string {
 	prompt := "You are analyzing a codebase with the following context:\n\n"
 	prompt += "═══════════════════════════════════════════════════════════════\n\n"
 
-	for i, chunk := range context.Chunks {
+	for i, chunk := range window.Chunks {
 		prompt += fmt.Sprintf("File: %s (Lines %d-%d)\n", chunk.File, chunk.StartLine, chunk.EndLine)
 		prompt += fmt.Sprintf("Relevance: %.2f\n\n", chunk.Importance)
 		prompt += chunk.Content + "\n\n"
 
-		if i < len(context.Chunks)-1 {
+		if i < len(window.Chunks)-1 {
 			prompt += "───────────────────────────────────────────────────────────────\n\n"
 		}
 	}
 
 	prompt += "═══════════════════════════════════════════════════════════════\n\n"
 	prompt += fmt.Sprintf("Context Statistics:\n")
-	prompt += fmt.Sprintf("  • Total chunks: %d\n", len(context.Chunks))
-	prompt += fmt.Sprintf("  • Total tokens: %d\n", context.TotalTokens)
-	prompt += fmt.Sprintf("  • Files covered: %d\n\n", len(context.Sources))
+	prompt += fmt.Sprintf("  • Total chunks: %d\n", len(window.Chunks))
+	prompt += fmt.Sprintf("  • Total tokens: %d\n", window.TotalTokens)
+	prompt += fmt.Sprintf("  • Files covered: %d\n\n", len(window.Sources))
 
 	prompt += fmt.Sprintf("User Question: %s\n\n", userQuery)
 	prompt += "Provide a concise, accurate answer based on the context above."
+	prompt += ConfidenceInstruction
 
 	return prompt
 }
@@ -0,0 +1,25 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+)
+
+func TestUnsupportedProvider(t *testing.T) {
+	for _, provider := range []string{"", "anthropic", "Ollama", ProviderMock} {
+		cfg := &config.Config{}
+		cfg.LLM.Provider = provider
+		if _, err := MouthClient(cfg); err != nil {
+			t.Errorf("MouthClient with provider %q: %v", provider, err)
+		}
+	}
+
+	cfg := &config.Config{}
+	cfg.LLM.Provider = "openai"
+	if _, err := MouthClient(cfg); !errors.Is(err, fault.ErrConfig) {
+		t.Errorf("MouthClient with provider openai = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ConfidenceInstruction asks the model to rate itself on a line SplitConfidence
+// can strip
+const ConfidenceInstruction = "\n\nOn the very last line write CONFIDENCE: high, medium or low, for how well the context supports your answer."
+
+var confidenceLine = regexp.MustCompile(`(?im)^\W*confidence\W*:\W*(high|medium|low)\W*$`)
+
+// SplitConfidence removes the model's CONFIDENCE line from a response and
+// returns it as 0-1, or -1 when the model didn't give one
+func SplitConfidence(response string) (string, float64) {
+	locs := confidenceLine.FindAllStringSubmatchIndex(response, -1)
+	if len(locs) == 0 {
+		return response, -1
+	}
+
+	// Only the last one counts, earlier matches may be quoted code
+	loc := locs[len(locs)-1]
+	level := strings.ToLower(response[loc[2]:loc[3]])
+	answer := strings.TrimRight(response[:loc[0]]+response[loc[1]:], " \t\r\n")
+
+	switch level {
+	case "high":
+		return answer, 0.9
+	case "medium":
+		return answer, 0.6
+	default:
+		return answer, 0.3
+	}
+}
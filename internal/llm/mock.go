@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+)
+
+// ProviderMock is the [llm] provider that answers without a model, see
+// config.MockConfig
+const ProviderMock = "mock"
+
+// defaultMockTemplate names what the answer would have been built from, so
+// a demo or a test can see retrieval at work
+const defaultMockTemplate = `This is a mock answer to "{{.Question}}".
+{{if .Files}}The context came from {{len .Files}} files: {{join .Files ", "}}.{{else}}No files were in the context.{{end}}
+
+CONFIDENCE: medium`
+
+// questionLine finds the question in the prompts eulix builds. Handlers
+// wrap theirs in the client's, so the last one is the user's.
+var questionLine = regexp.MustCompile(`(?im)^(?:user )?question:[ \t]*(.+)$`)
+
+// mock is the mock provider, safe for concurrent prompts
+type mock struct {
+	mu        sync.Mutex
+	responses []string
+	answered  int
+	template  *template.Template
+	cassette  *Cassette
+}
+
+// mockData is what the mock template sees
+type mockData struct {
+	Question string
+	Files    []string
+	Prompt   string
+}
+
+// mocked reports whether the config asks for the mock provider
+func (c *Client) mocked() bool {
+	return strings.EqualFold(c.config.LLM.Provider, ProviderMock)
+}
+
+func newMock(cfg config.MockConfig) (*mock, error) {
+	m := &mock{responses: cfg.Responses}
+
+	text := cfg.Template
+	if text == "" {
+		text = defaultMockTemplate
+	}
+	tmpl, err := template.New("mock").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: [llm.mock] template: %w", fault.ErrConfig, err)
+	}
+	m.template = tmpl
+
+	if cfg.Cassette != "" {
+		if m.cassette, err = LoadCassette(cfg.Cassette); err != nil {
+			return nil, fmt.Errorf("%w: [llm.mock] cassette: %w", fault.ErrConfig, err)
+		}
+	}
+	return m, nil
+}
+
+// generate is the Provider, streaming the answer word by word
+func (m *mock) generate(ctx context.Context, prompt string, onText func(string)) (string, error) {
+	response, err := m.respond(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	if onText != nil {
+		var sent strings.Builder
+		for _, word := range strings.SplitAfter(response, " ") {
+			if ctx.Err() != nil {
+				return interrupted(ctx, sent.String(), ctx.Err())
+			}
+			onText(word)
+			sent.WriteString(word)
+		}
+	}
+	return response, nil
+}
+
+func (m *mock) respond(prompt string) (string, error) {
+	if m.cassette != nil {
+		if response, ok := m.cassette.Find(prompt); ok {
+			return response, nil
+		}
+	}
+
+	m.mu.Lock()
+	if len(m.responses) > 0 {
+		response := m.responses[min(m.answered, len(m.responses)-1)]
+		m.answered++
+		m.mu.Unlock()
+		return response, nil
+	}
+	m.mu.Unlock()
+
+	data := mockData{Prompt: prompt, Files: promptFiles(prompt)}
+	if match := questionLine.FindAllStringSubmatch(prompt, -1); len(match) > 0 {
+		data.Question = strings.TrimSpace(match[len(match)-1][1])
+	}
+	var sb strings.Builder
+	if err := m.template.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("[llm.mock] template: %w", err)
+	}
+	return sb.String(), nil
+}
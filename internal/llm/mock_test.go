@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"eulix/internal/config"
+	"eulix/internal/types"
+)
+
+func mockClient(t *testing.T, mock config.MockConfig) *Client {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.LLM.Provider = ProviderMock
+	cfg.LLM.Mock = mock
+	c, err := MouthClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestMockResponsesInTurn(t *testing.T) {
+	c := mockClient(t, config.MockConfig{Responses: []string{"first", "second"}})
+	for _, want := range []string{"first", "second", "second"} {
+		if got, err := c.Complete("anything"); err != nil || got != want {
+			t.Errorf("Complete = %q, %v, want %q", got, err, want)
+		}
+	}
+}
+
+func TestMockTemplate(t *testing.T) {
+	c := mockClient(t, config.MockConfig{})
+	window := &types.ContextWindow{Chunks: []types.ContextChunk{{File: "internal/orders/orders.go", StartLine: 10, EndLine: 38}}}
+
+	var streamed strings.Builder
+	got, err := c.QueryStream(context.Background(), window, "how are orders saved", func(s string) { streamed.WriteString(s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"how are orders saved"`, "internal/orders/orders.go", "CONFIDENCE: medium"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("mock answer is missing %q:\n%s", want, got)
+		}
+	}
+	if streamed.String() != got {
+		t.Errorf("streamed %q, returned %q", streamed.String(), got)
+	}
+}
+
+func TestMockCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	data, _ := json.Marshal(Cassette{Interactions: []Interaction{{Prompt: "recorded prompt", Response: "recorded answer"}}})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := mockClient(t, config.MockConfig{Cassette: path, Responses: []string{"canned"}})
+
+	if got, _ := c.Complete("recorded prompt"); got != "recorded answer" {
+		t.Errorf("recorded prompt answered %q", got)
+	}
+	if got, _ := c.Complete("new prompt"); got != "canned" {
+		t.Errorf("unrecorded prompt answered %q", got)
+	}
+}
@@ -0,0 +1,291 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+	"eulix/internal/types"
+)
+
+// Prompts for hosted providers go through a privacy layer first: secrets are
+// masked, .env chunks lose their values and, when enabled, symbol names are
+// swapped for aliases that are mapped back in the answer. Local models get
+// the prompt untouched. With local_only nothing is sent off the machine.
+
+// ErrLocalOnly is returned instead of sending a prompt to a hosted provider
+// when [llm.privacy] local_only or --local-only is set
+var ErrLocalOnly = errors.New("local-only mode refuses to send code off this machine")
+
+const redacted = "[REDACTED]"
+
+// secretPatterns mask the whole match, or group 1 when there is one
+var secretPatterns = []*regexp.Regexp{
+	// apiKey := "value", "password": "value"
+	regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passw(?:or)?d|pwd|access[_-]?key|credentials?)[A-Za-z0-9_]*["']?\s*[:=]+\s*["']([^"'\s]{6,})["']`),
+	// password: value and API_TOKEN=value lines in config files, values
+	// that look like code are left alone
+	regexp.MustCompile(`(?im)^\s*[A-Za-z0-9_.-]*(?:api[_-]?key|secret|token|passw(?:or)?d|pwd|access[_-]?key|credentials?)[A-Za-z0-9_.-]*\s*[:=]\s*([^\s"'#()]{6,})\s*$`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bsk-(?:ant-)?[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	// user:password@ in connection strings
+	regexp.MustCompile(`://[^/\s:@]+:([^/\s@]+)@`),
+}
+
+// envValue is the value of a KEY=value line, everything in a .env file is
+// treated as a secret
+var envValue = regexp.MustCompile(`(?m)^\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_]*\s*=\s*(.+)$`)
+
+var word = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// aliasPattern matches what anonymize puts in place of a name
+var aliasPattern = regexp.MustCompile(`\bsym_[0-9]+\b`)
+
+// minIdentifier keeps short names like "id" or "run" readable, aliasing them
+// hides nothing and mangles ordinary words
+const minIdentifier = 4
+
+type privacy struct {
+	redact    bool
+	patterns  []*regexp.Regexp
+	anonymize bool
+	aliases   map[string]string // name -> alias
+	names     map[string]string // alias -> name
+}
+
+func newPrivacy(cfg config.PrivacyConfig) (*privacy, error) {
+	p := &privacy{redact: cfg.Redact, anonymize: cfg.AnonymizeIdentifiers}
+	if p.redact {
+		p.patterns = append(p.patterns, secretPatterns...)
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: [llm.privacy] pattern %q: %w", fault.ErrConfig, pattern, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	return p, nil
+}
+
+// SetIdentifiers gives the names to anonymize, usually every function and
+// type in the KB. Aliases follow sorted order so a name keeps its alias for
+// as long as the KB doesn't change.
+func (c *Client) SetIdentifiers(names []string) {
+	if c == nil || c.privacy == nil || !c.privacy.anonymize {
+		return
+	}
+
+	unique := make(map[string]bool)
+	for _, name := range names {
+		// Qualified names like Type.Method alias each part
+		for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '.' || r == ':' }) {
+			if len(part) >= minIdentifier && word.MatchString(part) && word.FindString(part) == part {
+				unique[part] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(unique))
+	for name := range unique {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	c.privacy.aliases = make(map[string]string, len(sorted))
+	c.privacy.names = make(map[string]string, len(sorted))
+	for i, name := range sorted {
+		alias := fmt.Sprintf("sym_%d", i+1)
+		c.privacy.aliases[name] = alias
+		c.privacy.names[alias] = name
+	}
+}
+
+// hosted reports whether prompts leave the machine: any non-local provider
+// but the mock, or Ollama on another host
+func (c *Client) hosted() bool {
+	if c.mocked() {
+		return false
+	}
+	if !c.config.LLM.Local {
+		return true
+	}
+	base := c.config.LLM.BaseURL
+	if base == "" {
+		return false
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return true
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsLoopback()
+}
+
+// checkLocalOnly refuses hosted providers in local-only mode. Replaying a
+// cassette never reaches the provider.
+func (c *Client) checkLocalOnly() error {
+	if !c.config.LLM.Privacy.LocalOnly || c.recorder.replaying() {
+		return nil
+	}
+	return c.RequireLocal()
+}
+
+// RequireLocal fails with ErrLocalOnly when prompts would leave the machine,
+// whatever the config says. The daemon uses it for clients run with
+// --local-only.
+func (c *Client) RequireLocal() error {
+	if !c.hosted() {
+		return nil
+	}
+	where := "a hosted provider"
+	if c.config.LLM.Local {
+		where = c.config.LLM.BaseURL
+	} else if c.config.LLM.Provider != "" {
+		where = c.config.LLM.Provider
+	}
+	return fmt.Errorf("%w: the model is on %s, use [llm] local = true with a baseURL on localhost", ErrLocalOnly, where)
+}
+
+// protectChunks masks secrets and .env files in a window before it is put in
+// a prompt and aliases the names in its code, the prompt's own words around
+// the code keep their meaning. The window is copied, the caller's chunks are
+// left alone.
+func (c *Client) protectChunks(window *types.ContextWindow) *types.ContextWindow {
+	if c.privacy == nil || !c.hosted() || window == nil {
+		return window
+	}
+
+	copied := *window
+	copied.Chunks = make([]types.ContextChunk, len(window.Chunks))
+	for i, chunk := range window.Chunks {
+		if c.privacy.redact && isEnvFile(chunk.File) {
+			chunk.Content = maskGroups(envValue, chunk.Content)
+		}
+		chunk.Content = c.privacy.alias(c.privacy.mask(chunk.Content))
+		copied.Chunks[i] = chunk
+	}
+	return &copied
+}
+
+func isEnvFile(path string) bool {
+	base := filepath.Base(path)
+	return base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env")
+}
+
+// protect prepares a prompt for a hosted provider, aliasing names all
+// through it when anonymize is set
+func (c *Client) protect(prompt string, anonymize bool) string {
+	if c.privacy == nil || !c.hosted() {
+		return prompt
+	}
+	prompt = c.privacy.mask(prompt)
+	if anonymize {
+		prompt = c.privacy.alias(prompt)
+	}
+	return prompt
+}
+
+// mask redacts secrets, before alias so a renamed key can't hide one
+func (p *privacy) mask(text string) string {
+	for _, re := range p.patterns {
+		text = maskGroups(re, text)
+	}
+	return text
+}
+
+// alias swaps the known symbol names in text for their aliases
+func (p *privacy) alias(text string) string {
+	if !p.anonymize || len(p.aliases) == 0 {
+		return text
+	}
+	return word.ReplaceAllStringFunc(text, func(w string) string {
+		if alias, ok := p.aliases[w]; ok {
+			return alias
+		}
+		return w
+	})
+}
+
+// restore maps aliases in a response back to the real names
+func (c *Client) restore(text string) string {
+	if c.privacy == nil || len(c.privacy.names) == 0 || !c.hosted() {
+		return text
+	}
+	return aliasPattern.ReplaceAllStringFunc(text, func(alias string) string {
+		if name, ok := c.privacy.names[alias]; ok {
+			return name
+		}
+		return alias
+	})
+}
+
+// restoreStream wraps onText so streamed pieces are restored too. A piece
+// can end halfway through an alias, so a trailing word is held back until
+// the next piece shows where it ends; flush sends what is left.
+func (c *Client) restoreStream(onText func(string)) (wrapped func(string), flush func()) {
+	if onText == nil || c.privacy == nil || len(c.privacy.names) == 0 || !c.hosted() {
+		return onText, func() {}
+	}
+
+	var held string
+	wrapped = func(piece string) {
+		text := held + piece
+		cut := len(text)
+		for cut > 0 && isWordByte(text[cut-1]) {
+			cut--
+		}
+		held = text[cut:]
+		if cut > 0 {
+			onText(c.restore(text[:cut]))
+		}
+	}
+	flush = func() {
+		if held != "" {
+			onText(c.restore(held))
+			held = ""
+		}
+	}
+	return wrapped, flush
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// maskGroups replaces group 1 of every match, or the whole match when the
+// pattern has no groups
+func maskGroups(re *regexp.Regexp, text string) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(redacted)
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
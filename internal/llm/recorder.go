@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"eulix/internal/config"
+	"eulix/internal/fault"
+)
+
+// ErrNotRecorded is returned in replay mode for a prompt the cassette
+// doesn't have
+var ErrNotRecorded = errors.New("prompt isn't on the cassette")
+
+// recorder sits between send and the provider in [cassettes] record and
+// replay mode. Recording saves every response as it comes in, so an
+// interrupted session keeps what it got.
+type recorder struct {
+	mu       sync.Mutex
+	mode     string
+	path     string
+	model    string
+	cassette *Cassette
+}
+
+func newRecorder(cfg *config.Config) (*recorder, error) {
+	mode := cfg.Cassettes.Mode
+	if mode != config.CassetteRecord && mode != config.CassetteReplay {
+		return nil, fmt.Errorf("%w: [cassettes] mode must be off, record or replay, not %q", fault.ErrConfig, mode)
+	}
+
+	r := &recorder{mode: mode, path: cfg.Cassettes.Path("llm"), model: cfg.LLM.Model}
+	cassette, err := LoadCassette(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		cassette = &Cassette{}
+	} else if err != nil {
+		return nil, err
+	}
+	r.cassette = cassette
+	return r, nil
+}
+
+// replaying reports whether responses come from the cassette alone
+func (r *recorder) replaying() bool {
+	return r != nil && r.mode == config.CassetteReplay
+}
+
+// play answers from the cassette when replaying, otherwise asks live and
+// records what it answered. Failed and interrupted responses aren't
+// recorded.
+func (r *recorder) play(ctx context.Context, prompt string, onText func(string), live Provider) (string, error) {
+	if r.replaying() {
+		r.mu.Lock()
+		response, ok := r.cassette.Find(prompt)
+		r.mu.Unlock()
+		if !ok {
+			return "", fmt.Errorf("%w: %s (prompt %.12s)", ErrNotRecorded, r.path, promptKey(prompt))
+		}
+		if onText != nil {
+			onText(response)
+		}
+		return response, nil
+	}
+
+	response, err := live(ctx, prompt, onText)
+	if err != nil {
+		return response, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Put(Interaction{Model: r.model, Prompt: prompt, Response: response})
+	if err := r.cassette.Save(r.path); err != nil {
+		return response, fmt.Errorf("failed to record to %s: %w", r.path, err)
+	}
+	return response, nil
+}
@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+
+	"eulix/internal/config"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LLM.Provider = ProviderMock
+	cfg.LLM.Mock.Responses = []string{"live answer"}
+	cfg.Cassettes = config.CassettesConfig{Mode: config.CassetteRecord, Dir: t.TempDir()}
+
+	recording, err := MouthClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := recording.Complete("explain saveOrder"); err != nil || got != "live answer" {
+		t.Fatalf("recording answered %q, %v", got, err)
+	}
+
+	cfg.Cassettes.Mode = config.CassetteReplay
+	cfg.LLM.Mock.Responses = []string{"a different live answer"}
+	replaying, err := MouthClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := replaying.Complete("explain saveOrder"); err != nil || got != "live answer" {
+		t.Errorf("replay answered %q, %v", got, err)
+	}
+	if _, err := replaying.Complete("explain validate"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("unrecorded prompt: err = %v, want ErrNotRecorded", err)
+	}
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// responseCache remembers raw provider responses by (provider, model, prompt hash)
+// so the same prompt sent twice in a session is only billed once. It sits below
+// the query cache and also catches internal calls like retries and review passes.
+type responseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List // front is most recently used
+	entries    map[string]*list.Element
+}
+
+type responseEntry struct {
+	key       string
+	response  string
+	expiresAt time.Time
+}
+
+func newResponseCache(maxEntries int, ttl time.Duration) *responseCache {
+	if maxEntries <= 0 {
+		maxEntries = 256
+	}
+	return &responseCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func responseKey(provider, model, prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return provider + "|" + model + "|" + hex.EncodeToString(sum[:])
+}
+
+func (c *responseCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	entry := el.Value.(*responseEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return "", false
+	}
+
+	c.order.MoveToFront(el)
+	return entry.response, true
+}
+
+func (c *responseCache) put(key, response string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*responseEntry)
+		entry.response = response
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&responseEntry{key: key, response: response, expiresAt: expiresAt})
+
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseEntry).key)
+	}
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"eulix/internal/config"
+)
+
+// countingClient is a mock client with the response cache on, whose
+// provider answers "response N" for its Nth call, or fails while fail is set
+func countingClient(t *testing.T) (c *Client, calls *int, fail *bool) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.LLM.Provider = ProviderMock
+	cfg.LLM.Model = "fake"
+	cfg.LLM.ResponseCache = config.ResponseCacheConfig{Enabled: true, MaxEntries: 8, TTLMinutes: 60}
+	c, err := MouthClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls, fail = new(int), new(bool)
+	c.SetProvider(func(ctx context.Context, prompt string, onText func(string)) (string, error) {
+		*calls++
+		if *fail {
+			return "", errors.New("overloaded")
+		}
+		return fmt.Sprintf("response %d", *calls), nil
+	})
+	return c, calls, fail
+}
+
+func TestResponseCacheSendsOnce(t *testing.T) {
+	c, calls, _ := countingClient(t)
+
+	first, err := c.Complete("explain CreateOrder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed string
+	second, err := c.CompleteStream(context.Background(), "explain CreateOrder", func(s string) { streamed += s })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 1 || second != first || streamed != first {
+		t.Errorf("repeat prompt: %d calls, got %q streamed %q, want %q from 1 call", *calls, second, streamed, first)
+	}
+
+	if _, err := c.Complete("explain saveOrder"); err != nil {
+		t.Fatal(err)
+	}
+	c.SetModel("other")
+	if _, err := c.Complete("explain CreateOrder"); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 {
+		t.Errorf("a new prompt and another model made %d calls in all, want 3", *calls)
+	}
+}
+
+func TestResponseCacheSkipsFailures(t *testing.T) {
+	c, calls, fail := countingClient(t)
+
+	*fail = true
+	if _, err := c.Complete("explain CreateOrder"); err == nil {
+		t.Fatal("the provider's error was lost")
+	}
+	*fail = false
+	got, err := c.Complete("explain CreateOrder")
+	if err != nil || got != "response 2" || *calls != 2 {
+		t.Errorf("after a failure: %q, %v after %d calls, want a fresh response", got, err, *calls)
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResponseCache(2, time.Hour)
+	cache.put("a", "A")
+	cache.put("b", "B")
+	cache.get("a")
+	cache.put("c", "C")
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("b was kept, it was used least recently")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
+
+func TestResponseCacheExpires(t *testing.T) {
+	cache := newResponseCache(2, time.Millisecond)
+	cache.put("a", "A")
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.get("a"); ok {
+		t.Error("an expired response was served")
+	}
+	if cache.order.Len() != 0 {
+		t.Errorf("%d entries left, the expired one wasn't dropped", cache.order.Len())
+	}
+}
@@ -0,0 +1,201 @@
+package lock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const fileName = "lock"
+
+// Info is what gets written to .eulix/lock
+type Info struct {
+	PID       int       `json:"pid"`
+	Operation string    `json:"operation"`
+	StartedAt time.Time `json:"started_at"`
+
+	// Corrupt marks a lock file that couldn't be parsed. Operation then holds
+	// its path and StartedAt when it was written.
+	Corrupt bool `json:"-"`
+}
+
+// HeldError is returned when another live process owns the lock
+type HeldError struct {
+	Info Info
+}
+
+func (e *HeldError) Error() string {
+	if e.Info.Corrupt {
+		return fmt.Sprintf("%s is corrupt (written %s ago), remove it if no eulix command is running",
+			e.Info.Operation, time.Since(e.Info.StartedAt).Round(time.Second))
+	}
+	return fmt.Sprintf("%s is already running (pid %d, started %s ago)",
+		e.Info.Operation, e.Info.PID, time.Since(e.Info.StartedAt).Round(time.Second))
+}
+
+// Lock is an exclusive hold on a .eulix directory for writes
+type Lock struct {
+	path string
+}
+
+func path(eulixDir string) string {
+	return filepath.Join(eulixDir, fileName)
+}
+
+// Acquire takes the lock for op, failing fast with a *HeldError if a live
+// process has it. Locks left behind by dead processes are cleared.
+//
+// The lock is written to a file of its own first and linked into place, so
+// it appears whole or not at all and a reader never sees it half written.
+func Acquire(eulixDir, op string) (*Lock, error) {
+	p := path(eulixDir)
+
+	data, err := json.Marshal(Info{PID: os.Getpid(), Operation: op, StartedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(eulixDir, fileName+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write lock: %w", err)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		err := os.Link(tmp.Name(), p)
+		if err == nil {
+			return &Lock{path: p}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock: %w", err)
+		}
+
+		holder, data, err := readLock(p)
+		if err != nil {
+			return nil, err
+		}
+		if holder != nil {
+			return nil, &HeldError{Info: *holder}
+		}
+		if data != nil {
+			if err := breakStale(p, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("failed to acquire lock %s", p)
+}
+
+// AcquireWait retries Acquire until the lock is free or timeout passes
+func AcquireWait(eulixDir, op string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := Acquire(eulixDir, op)
+		var held *HeldError
+		if err == nil || !errors.As(err, &held) || time.Now().After(deadline) {
+			return l, err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Release removes the lock
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	err := os.Remove(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Read returns the current holder, or nil when the directory is unlocked or
+// the lock's process is gone. A lock that can't be parsed is reported as
+// held and Corrupt, it may be someone else's lock file.
+func Read(eulixDir string) (*Info, error) {
+	holder, _, err := readLock(path(eulixDir))
+	return holder, err
+}
+
+// readLock returns the holder of the lock at p along with its contents. No
+// holder with contents means the lock was left by a dead process.
+func readLock(p string) (*Info, []byte, error) {
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read lock: %w", err)
+	}
+
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil || info.PID <= 0 {
+		held := Info{Operation: p, Corrupt: true}
+		if fi, err := os.Stat(p); err == nil {
+			held.StartedAt = fi.ModTime()
+		}
+		return &held, data, nil
+	}
+	if !processAlive(info.PID) {
+		return nil, data, nil
+	}
+
+	return &info, data, nil
+}
+
+// breakStale clears a lock left by a dead process. It is moved aside before
+// it is removed, so a lock another process took since stale was read is put
+// back instead of deleted.
+func breakStale(p string, stale []byte) error {
+	aside := fmt.Sprintf("%s.%d.stale", p, os.Getpid())
+	if err := os.Rename(p, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to clear stale lock: %w", err)
+	}
+	defer os.Remove(aside)
+
+	data, err := os.ReadFile(aside)
+	if err != nil {
+		return fmt.Errorf("failed to clear stale lock: %w", err)
+	}
+	if !bytes.Equal(data, stale) {
+		if err := os.Link(aside, p); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to restore lock: %w", err)
+		}
+	}
+	return nil
+}
+
+// WaitForRelease blocks readers while a writer holds the lock, up to timeout
+func WaitForRelease(eulixDir string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		holder, err := Read(eulixDir)
+		if err != nil {
+			return err
+		}
+		if holder == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return &HeldError{Info: *holder}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
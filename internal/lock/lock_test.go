@@ -0,0 +1,110 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAcquireRelease(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Acquire(dir, "analyze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err := Read(dir)
+	if err != nil || holder == nil || holder.PID != os.Getpid() || holder.Operation != "analyze" {
+		t.Fatalf("Read = %+v, %v, want this process running analyze", holder, err)
+	}
+	var held *HeldError
+	if _, err := Acquire(dir, "index"); !errors.As(err, &held) {
+		t.Fatalf("second Acquire: err = %v, want HeldError", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("left %d files behind, want none", len(entries))
+	}
+}
+
+func TestUnreadableLockIsKept(t *testing.T) {
+	for _, content := range []string{"", `{"pid":`} {
+		dir := t.TempDir()
+		p := filepath.Join(dir, fileName)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		holder, err := Read(dir)
+		if err != nil || holder == nil {
+			t.Fatalf("%q: Read = %v, %v, want it held", content, holder, err)
+		}
+		var held *HeldError
+		_, err = Acquire(dir, "analyze")
+		if !errors.As(err, &held) {
+			t.Fatalf("%q: Acquire err = %v, want HeldError", content, err)
+		}
+		if !holder.Corrupt || !strings.Contains(err.Error(), "corrupt") {
+			t.Errorf("%q: holder = %+v, err = %v, want it reported as corrupt", content, holder, err)
+		}
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%q: lock file was removed", content)
+		}
+	}
+}
+
+// staleLock names a pid past any the kernel hands out
+const staleLock = `{"pid":1073741824,"operation":"analyze"}`
+
+func TestStaleLockIsBrokenByAcquire(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, fileName)
+	if err := os.WriteFile(p, []byte(staleLock), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	holder, err := Read(dir)
+	if err != nil || holder != nil {
+		t.Fatalf("Read = %+v, %v, want no holder", holder, err)
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Fatalf("Read removed the stale lock: %v", err)
+	}
+
+	l, err := Acquire(dir, "index")
+	if err != nil {
+		t.Fatalf("Acquire over a stale lock: %v", err)
+	}
+	defer l.Release()
+	if holder, _ := Read(dir); holder == nil || holder.PID != os.Getpid() {
+		t.Errorf("Read = %+v, want this process", holder)
+	}
+}
+
+func TestBreakStaleKeepsRetakenLock(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, fileName)
+	live := []byte(`{"pid":1,"operation":"index"}`)
+	if err := os.WriteFile(p, live, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The dead holder was read, then another process took the lock
+	if err := breakStale(p, []byte(staleLock)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil || string(data) != string(live) {
+		t.Fatalf("lock = %q, %v, want the live lock kept", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("left %d files behind, want only the lock", len(entries))
+	}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package lock
+
+import "os"
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// FindProcess opens a handle on Windows and fails for dead pids
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,226 @@
+package owners
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Ownership comes from the project's CODEOWNERS, read by analyze into
+// kb_owners.json, and from .eulix/owners.toml, read on every load so it can
+// be edited without reanalyzing. Both use CODEOWNERS rules: the last pattern
+// matching a file decides its owners, owners.toml rules come after
+// CODEOWNERS ones and so win.
+//
+//	[[rule]]
+//	path = "internal/payments/"
+//	owners = ["@payments-team"]
+
+// FileName is where analyze writes the CODEOWNERS rules in .eulix
+const FileName = "kb_owners.json"
+
+// overridesName is the hand written rules file in .eulix
+const overridesName = "owners.toml"
+
+// codeownersPaths are where GitHub and GitLab look, the first one found is used
+var codeownersPaths = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+}
+
+// Rule is one pattern and its owners, no owners leaves matching files
+// unowned
+type Rule struct {
+	Pattern string   `json:"pattern" toml:"path"`
+	Owners  []string `json:"owners" toml:"owners"`
+	Source  string   `json:"source" toml:"-"` // file:line it came from
+
+	match *regexp.Regexp
+}
+
+// Map holds the rules in the order they apply
+type Map struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Parse reads the CODEOWNERS file in projectDir. A project without one has
+// an empty Map.
+func Parse(projectDir string) (*Map, error) {
+	for _, rel := range codeownersPaths {
+		f, err := os.Open(filepath.Join(projectDir, rel))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return parseCodeowners(f, rel)
+	}
+	return &Map{}, nil
+}
+
+func parseCodeowners(f io.Reader, name string) (*Map, error) {
+	m := &Map{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		// Comments, and GitLab [Section] headers whose rules still apply
+		if line == "" || line[0] == '#' || line[0] == '[' || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		rule := Rule{
+			Pattern: strings.ReplaceAll(fields[0], `\#`, "#"),
+			Owners:  fields[1:],
+			Source:  fmt.Sprintf("%s:%d", name, n),
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Source, err)
+		}
+		m.Rules = append(m.Rules, rule)
+	}
+	return m, scanner.Err()
+}
+
+// Write saves the rules to FileName in dir
+func (m *Map) Write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FileName), data, 0644)
+}
+
+// Load reads the rules analyze saved in eulixDir, then owners.toml. Neither
+// existing is an empty Map, not an error.
+func Load(eulixDir string) (*Map, error) {
+	m := &Map{}
+	data, err := os.ReadFile(filepath.Join(eulixDir, FileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+		}
+	}
+
+	var overrides struct {
+		Rule []Rule `toml:"rule"`
+	}
+	meta, err := toml.DecodeFile(filepath.Join(eulixDir, overridesName), &overrides)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to parse %s: %w", overridesName, err)
+	}
+	for i, rule := range overrides.Rule {
+		rule.Source = fmt.Sprintf(".eulix/%s rule %d", overridesName, i+1)
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("%s: path is required", rule.Source)
+		}
+		m.Rules = append(m.Rules, rule)
+	}
+	if keys := meta.Undecoded(); len(keys) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %s", overridesName, keys[0])
+	}
+
+	for i := range m.Rules {
+		if err := m.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Rules[i].Source, err)
+		}
+	}
+	return m, nil
+}
+
+// Empty is true when there are no rules at all
+func (m *Map) Empty() bool {
+	return m == nil || len(m.Rules) == 0
+}
+
+// Of returns the rule deciding who owns file, a path relative to the
+// project root, or nil when no rule matches
+func (m *Map) Of(file string) *Rule {
+	if m == nil {
+		return nil
+	}
+	file = strings.TrimPrefix(filepath.ToSlash(file), "./")
+	for i := len(m.Rules) - 1; i >= 0; i-- {
+		if m.Rules[i].match.MatchString(file) {
+			return &m.Rules[i]
+		}
+	}
+	return nil
+}
+
+// All is everyone owning one of files, in the order they first appear
+func (m *Map) All(files []string) []string {
+	var all []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		rule := m.Of(file)
+		if rule == nil {
+			continue
+		}
+		for _, owner := range rule.Owners {
+			if !seen[owner] {
+				seen[owner] = true
+				all = append(all, owner)
+			}
+		}
+	}
+	return all
+}
+
+// compile turns a gitignore style pattern into a regexp. A leading slash or
+// one in the middle anchors it to the root, otherwise it matches at any
+// depth. Matching a directory matches everything under it.
+func (r *Rule) compile() error {
+	pattern := r.Pattern
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("(?:^|/)")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(?:/.*)?$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", r.Pattern, err)
+	}
+	r.match = re
+	return nil
+}
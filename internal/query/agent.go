@@ -0,0 +1,258 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eulix/internal/llm"
+)
+
+// DefaultAgentSteps is how many tool calls agent mode gets when none is given
+const DefaultAgentSteps = 8
+
+// maxToolResult keeps one tool result from eating the whole prompt
+const maxToolResult = 4000
+
+// AgentStep is one tool call the model made while answering
+type AgentStep struct {
+	Tool  string `json:"tool"`
+	Arg   string `json:"arg"`
+	Found bool   `json:"found"`
+}
+
+// toolCall matches CALL tool(arg) on a line of its own
+var toolCall = regexp.MustCompile(`(?m)^\s*CALL\s+(\w+)\s*\((.*)\)\s*$`)
+
+const agentInstructions = `You are answering a question about a codebase. You can't see the code yet,
+look it up with these tools, one call per reply:
+
+  CALL search_symbols(<text>)   functions, types and globals whose name contains <text>, with locations
+  CALL get_source(<symbol>)     source of a function or type, or get_source(<file path>) for a file
+  CALL get_callers(<symbol>)    functions that call <symbol>, and what it calls
+
+Reply with exactly one CALL line to use a tool, nothing else. When you have
+enough to answer, reply with ANSWER: followed by the answer. Only state what
+the tool results show, cite files and functions, and say what you couldn't find.
+`
+
+// Agent answers a question by letting the model look things up over several
+// rounds instead of stuffing one context window up front. steps caps the
+// number of tool calls; 0 means DefaultAgentSteps.
+func (r *Router) Agent(query string, steps int) (*Answer, error) {
+	if steps <= 0 {
+		steps = DefaultAgentSteps
+	}
+	if err := r.ensureContextBuilder(); err != nil {
+		return nil, err
+	}
+
+	query = r.hooks.BeforeQuery(query)
+
+	r.signals.quiet = true
+	r.signals.waived, r.budgetWaived = r.budgetWaived, false
+	r.signals.warnings = nil
+	defer r.interruptible()()
+
+	var transcript strings.Builder
+	var taken []AgentStep
+	var response string
+
+	for len(taken) < steps {
+		prompt := fmt.Sprintf("%s\nQUESTION: %s\n\n%sTool calls left: %d\nIf you answer now:%s\n",
+			agentInstructions, query, transcript.String(), steps-len(taken), llm.ConfidenceInstruction)
+
+		reply, err := r.complete(prompt)
+		if errors.Is(err, llm.ErrInterrupted) {
+			return r.agentInterrupted(taken), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("LLM query failed: %w", err)
+		}
+
+		m := toolCall.FindStringSubmatch(reply)
+		if m == nil {
+			response = reply
+			break
+		}
+
+		tool, arg := m[1], strings.Trim(strings.TrimSpace(m[2]), `"'`+"`")
+		r.report(StageTool, fmt.Sprintf("%s(%s)", tool, arg))
+		result, found := r.runTool(tool, arg)
+		taken = append(taken, AgentStep{Tool: tool, Arg: arg, Found: found})
+
+		transcript.WriteString(fmt.Sprintf("CALL %s(%s)\nRESULT:\n%s\n\n", tool, arg, result))
+	}
+
+	if response == "" {
+		// Out of steps, make it answer with what it has
+		prompt := fmt.Sprintf("%s\nQUESTION: %s\n\n%sNo tool calls left. Reply with ANSWER: and your answer.%s\n",
+			agentInstructions, query, transcript.String(), llm.ConfidenceInstruction)
+
+		reply, err := r.complete(prompt)
+		if errors.Is(err, llm.ErrInterrupted) {
+			return r.agentInterrupted(taken), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("LLM query failed: %w", err)
+		}
+		response = reply
+	}
+
+	r.report(StageRendering, "")
+	response = strings.TrimSpace(response)
+	response = strings.TrimSpace(strings.TrimPrefix(response, "ANSWER:"))
+	text, model := llm.SplitConfidence(response)
+
+	return &Answer{
+		Text:       r.hooks.AfterAnswer(query, text),
+		Confidence: scoreConfidence(agentRetrieval(taken), -1, model),
+		Steps:      taken,
+	}, nil
+}
+
+// agentInterrupted is the answer when agent mode is stopped, the tool calls
+// made so far are all there is to show
+func (r *Router) agentInterrupted(taken []AgentStep) *Answer {
+	r.noteDeadline()
+	return &Answer{
+		Text:       fmt.Sprintf("Interrupted after %d tool calls.", len(taken)),
+		Confidence: scoreConfidence(agentRetrieval(taken), -1, -1),
+		Steps:      taken,
+		Truncated:  true,
+		Warnings:   r.signals.warnings,
+	}
+}
+
+// agentRetrieval is the share of tool calls that found something, -1 when
+// the model answered without looking anything up
+func agentRetrieval(steps []AgentStep) float64 {
+	if len(steps) == 0 {
+		return -1
+	}
+	found := 0
+	for _, step := range steps {
+		if step.Found {
+			found++
+		}
+	}
+	return float64(found) / float64(len(steps))
+}
+
+// runTool runs one tool call, the result goes back to the model as text
+func (r *Router) runTool(tool, arg string) (string, bool) {
+	if arg == "" {
+		return "missing argument", false
+	}
+
+	var result string
+	switch tool {
+	case "search_symbols":
+		result = r.searchSymbols(arg)
+	case "get_source":
+		result = r.getSource(arg)
+	case "get_callers":
+		result = r.getCallers(arg)
+	default:
+		return fmt.Sprintf("unknown tool %q, use search_symbols, get_source or get_callers", tool), false
+	}
+
+	if result == "" {
+		return "nothing found for " + arg, false
+	}
+	if len(result) > maxToolResult {
+		result = result[:maxToolResult] + "\n... (truncated)"
+	}
+	return result, true
+}
+
+func (r *Router) searchSymbols(text string) string {
+	const limit = 20
+	needle := strings.ToLower(text)
+
+	var lines []string
+	for kind, index := range map[string]map[string][]string{
+		"func": r.kbIndex.FunctionsByName,
+		"type": r.kbIndex.TypesByName,
+		"var":  r.kbIndex.GlobalsByName,
+	} {
+		for name, locs := range index {
+			if strings.Contains(strings.ToLower(name), needle) {
+				lines = append(lines, fmt.Sprintf("%s %s  %s", kind, name, strings.Join(locs, ", ")))
+			}
+		}
+	}
+
+	sort.Strings(lines)
+	if len(lines) > limit {
+		lines = append(lines[:limit], fmt.Sprintf("... and %d more, search for something narrower", len(lines)-limit))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getSource finds chunks by symbol name, then by index location, then by file
+func (r *Router) getSource(arg string) string {
+	chunks := r.contextBuilder.chunks
+
+	var matched []Chunk
+	for _, chunk := range chunks {
+		if chunk.Name == arg {
+			matched = append(matched, chunk)
+		}
+	}
+
+	if len(matched) == 0 {
+		match, _ := r.kbIndex.Lookup(arg)
+		for _, loc := range match.Locations() {
+			file, line := splitLocation(loc)
+			for _, chunk := range chunks {
+				if chunk.File == file && chunk.StartLine <= line && line <= chunk.EndLine {
+					matched = append(matched, chunk)
+				}
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		for _, chunk := range chunks {
+			if strings.HasSuffix(chunk.File, arg) {
+				matched = append(matched, chunk)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for _, chunk := range matched {
+		sb.WriteString(fmt.Sprintf("--- %s (lines %d-%d) ---\n%s\n", chunk.File, chunk.StartLine, chunk.EndLine, chunk.Content))
+	}
+	return sb.String()
+}
+
+func (r *Router) getCallers(symbol string) string {
+	callers, callees := r.neighbours(symbol)
+
+	var sb strings.Builder
+	if len(callers) > 0 {
+		sb.WriteString(fmt.Sprintf("%s is called by: %s\n", symbol, strings.Join(callers, ", ")))
+	}
+	if len(callees) > 0 {
+		sb.WriteString(fmt.Sprintf("%s calls: %s\n", symbol, strings.Join(callees, ", ")))
+	}
+	return sb.String()
+}
+
+// splitLocation parses the index's file:line locations
+func splitLocation(loc string) (string, int) {
+	i := strings.LastIndex(loc, ":")
+	if i < 0 {
+		return loc, 0
+	}
+	line, err := strconv.Atoi(loc[i+1:])
+	if err != nil {
+		return loc, 0
+	}
+	return loc[:i], line
+}
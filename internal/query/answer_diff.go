@@ -0,0 +1,160 @@
+package query
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"eulix/internal/cache"
+	"eulix/internal/llm"
+)
+
+// AnswerDiff compares a fresh answer with the one cached before the code
+// changed. Lines are prefixed "  ", "- " or "+ " like a unified diff.
+type AnswerDiff struct {
+	PreviousAt     time.Time `json:"previous_at"`
+	Previous       string    `json:"previous"`
+	Lines          []string  `json:"lines"`
+	ChangedSources []string  `json:"changed_sources,omitempty"` // edited since the previous answer
+	AddedSources   []string  `json:"added_sources,omitempty"`
+	RemovedSources []string  `json:"removed_sources,omitempty"`
+}
+
+// diffContext is how many unchanged lines are kept around each change
+const diffContext = 1
+
+func (d *AnswerDiff) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Changed since the answer from %s:\n", d.PreviousAt.Format("2 Jan 15:04")))
+
+	var sources []string
+	for _, s := range d.ChangedSources {
+		sources = append(sources, "* "+s)
+	}
+	for _, s := range d.AddedSources {
+		sources = append(sources, "+ "+s)
+	}
+	for _, s := range d.RemovedSources {
+		sources = append(sources, "- "+s)
+	}
+	if len(sources) > 0 {
+		sb.WriteString("Sources: " + strings.Join(sources, ", ") + "\n")
+	}
+
+	// Only the changes and a little around them
+	keep := make([]bool, len(d.Lines))
+	for i, line := range d.Lines {
+		if strings.HasPrefix(line, "  ") {
+			continue
+		}
+		for j := i - diffContext; j <= i+diffContext; j++ {
+			if j >= 0 && j < len(d.Lines) {
+				keep[j] = true
+			}
+		}
+	}
+
+	skipped := false
+	for i, line := range d.Lines {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("  ...\n")
+			skipped = false
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// diffAnswer compares the previous cached answer with the new one, nil when
+// neither the text nor the sources changed
+func (r *Router) diffAnswer(previous *cache.CacheEntry, text string) *AnswerDiff {
+	before, _ := llm.SplitConfidence(previous.Response)
+
+	d := &AnswerDiff{
+		PreviousAt: previous.CreatedAt,
+		Previous:   before,
+		Lines:      diffLines(strings.Split(before, "\n"), strings.Split(text, "\n")),
+	}
+
+	root := filepath.Dir(r.eulixDir)
+	old := make(map[string]bool)
+	for _, source := range previous.Sources {
+		old[source] = true
+	}
+	current := make(map[string]bool)
+	for _, source := range r.signals.sources {
+		current[source] = true
+		if !old[source] {
+			d.AddedSources = append(d.AddedSources, source)
+		}
+	}
+	for source := range old {
+		info, err := os.Stat(filepath.Join(root, source))
+		switch {
+		case err != nil || !current[source]:
+			d.RemovedSources = append(d.RemovedSources, source)
+		case info.ModTime().After(previous.CreatedAt):
+			d.ChangedSources = append(d.ChangedSources, source)
+		}
+	}
+	sort.Strings(d.AddedSources)
+	sort.Strings(d.RemovedSources)
+	sort.Strings(d.ChangedSources)
+
+	if before == text && len(d.AddedSources)+len(d.RemovedSources)+len(d.ChangedSources) == 0 {
+		return nil
+	}
+	return d
+}
+
+// diffLines is a longest common subsequence line diff, answers are short
+// enough that the quadratic table doesn't matter
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
@@ -0,0 +1,237 @@
+package query
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Search picks chunks best match first, so a file's chunks end up scattered
+// through the context with other files in between. groupByFile puts them
+// back together: one block per file, files in the order of their best
+// match, chunks in line order with a marker where lines are left out. A
+// method gets the header of its type when the type isn't there already and
+// there is room left in the budget for it.
+//
+// Every chunk also starts with a line saying where it sits, "// method of
+// type Manager (internal/cache/manager.go)", so a method read on its own
+// still says whose it is.
+
+// mergeGap is the most lines between two chunks that are joined without a
+// marker, as canMerge does
+const mergeGap = 5
+
+// receiverPattern matches the type of a Go method, Server in
+// "func (s *Server) Start()"
+var receiverPattern = regexp.MustCompile(`(?m)^\s*func\s*\(\s*\w*\s*\*?\s*(\w+)`)
+
+// groupByFile arranges selected, best match first, into one chunk per file.
+// Type headers are added while they fit in what selected leaves of budget.
+func (cb *ContextBuilder) groupByFile(selected []Chunk, budget int) []Chunk {
+	var files []string
+	byFile := make(map[string][]Chunk)
+	room := budget
+	for _, c := range selected {
+		if _, ok := byFile[c.File]; !ok {
+			files = append(files, c.File)
+		}
+		c = cb.scoped(c)
+		byFile[c.File] = append(byFile[c.File], c)
+		room -= c.Tokens + 20 // the header, as assembleContext counts it
+	}
+
+	grouped := make([]Chunk, 0, len(files))
+	for _, file := range files {
+		chunks := cb.withTypeHeaders(byFile[file], &room)
+		sort.SliceStable(chunks, func(i, j int) bool {
+			if chunks[i].StartLine != chunks[j].StartLine {
+				return chunks[i].StartLine < chunks[j].StartLine
+			}
+			return chunks[i].EndLine > chunks[j].EndLine
+		})
+		grouped = append(grouped, joinRegions(chunks))
+	}
+	return grouped
+}
+
+// withTypeHeaders adds the outline of the type each method belongs to,
+// unless the type's chunk is already among chunks or the outline doesn't
+// fit in room, which is reduced by what is added
+func (cb *ContextBuilder) withTypeHeaders(chunks []Chunk, room *int) []Chunk {
+	have := make(map[string]bool)
+	for _, c := range chunks {
+		if c.ChunkType == "class" {
+			have[c.Name] = true
+		}
+	}
+	for _, c := range chunks {
+		if c.ChunkType != "method" {
+			continue
+		}
+		owner, ok := cb.enclosingType(c)
+		if !ok || have[owner.Name] {
+			continue
+		}
+		have[owner.Name] = true
+		outline := outlineChunk(owner.Content, filepath.Ext(owner.File))
+		if outline == "" {
+			continue
+		}
+		header := cb.scoped(compressedChunk(owner, outline, compressedOutline))
+		if header.Tokens > *room {
+			continue
+		}
+		chunks = append(chunks, header)
+		*room -= header.Tokens
+	}
+	return chunks
+}
+
+// enclosingType is the class chunk of method's type in the same file: by
+// its name, Server.Start, or else the one whose lines contain the method
+func (cb *ContextBuilder) enclosingType(method Chunk) (Chunk, bool) {
+	owner := cb.ownerName(method)
+	var enclosing *Chunk
+	for i := range cb.chunks {
+		c := &cb.chunks[i]
+		if c.ChunkType != "class" || c.File != method.File {
+			continue
+		}
+		if owner != "" && c.Name == owner {
+			return *c, true
+		}
+		if c.StartLine <= method.StartLine && c.EndLine >= method.EndLine && (enclosing == nil || c.StartLine > enclosing.StartLine) {
+			enclosing = c
+		}
+	}
+	if enclosing == nil {
+		return Chunk{}, false
+	}
+	return *enclosing, true
+}
+
+// joinRegions makes one chunk of a file's chunks in line order, with a
+// marker for the lines between them and for ones that were shortened
+func joinRegions(chunks []Chunk) Chunk {
+	if len(chunks) == 1 {
+		return chunks[0]
+	}
+
+	joined := chunks[0]
+	joined.Compressed = ""
+	var sb strings.Builder
+	end := 0
+	for i, c := range chunks {
+		switch {
+		case i == 0:
+		case c.StartLine > end+mergeGap+1:
+			fmt.Fprintf(&sb, "\n\n... lines %d-%d not shown ...\n\n", end+1, c.StartLine-1)
+		default:
+			sb.WriteString("\n")
+		}
+		if c.Compressed != "" {
+			fmt.Fprintf(&sb, "(lines %d-%d, %s)\n", c.StartLine, c.EndLine, c.Compressed)
+		}
+		sb.WriteString(c.Content)
+
+		if i > 0 {
+			joined.Tokens += c.Tokens
+			joined.Symbols = appendMissing(joined.Symbols, c.Symbols...)
+			if c.Importance > joined.Importance {
+				joined.Importance = c.Importance
+			}
+		}
+		if c.StartLine < joined.StartLine {
+			joined.StartLine = c.StartLine
+		}
+		if c.EndLine > end {
+			end = c.EndLine
+		}
+	}
+	joined.Content = sb.String()
+	joined.EndLine = end
+	return joined
+}
+
+// ownerName is the type method belongs to, from its name, the KB's classes
+// or a Go receiver, "" when none says
+func (cb *ContextBuilder) ownerName(method Chunk) string {
+	if i := strings.LastIndex(method.Name, "."); i > 0 {
+		return method.Name[:i]
+	}
+	if cb.kbData != nil {
+		for _, class := range cb.kbData.Structure[method.File].Classes {
+			for _, m := range class.Methods {
+				if m.Name == method.Name && m.LineStart >= method.StartLine && m.LineStart <= method.EndLine {
+					return class.Name
+				}
+			}
+		}
+	}
+	if m := receiverPattern.FindStringSubmatch(method.Content); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// scoped starts chunk's content with its scope line, once
+func (cb *ContextBuilder) scoped(chunk Chunk) Chunk {
+	header := cb.scopeHeader(chunk)
+	if strings.HasPrefix(chunk.Content, header+"\n") {
+		return chunk
+	}
+	chunk.Content = header + "\n" + chunk.Content
+	chunk.Tokens += len(header)/4 + 1
+	return chunk
+}
+
+// scopeHeader says what kind of code chunk is and where it sits
+func (cb *ContextBuilder) scopeHeader(chunk Chunk) string {
+	where := cb.packageOf(chunk.File)
+	switch chunk.ChunkType {
+	case "method":
+		if owner := cb.ownerName(chunk); owner != "" {
+			where = "of type " + owner
+		}
+		return scopeLine("method", where, chunk.File)
+	case "function":
+		return scopeLine("function", where, chunk.File)
+	case "class":
+		return scopeLine("type", where, chunk.File)
+	case "global":
+		return scopeLine("package level declaration", where, chunk.File)
+	}
+	return scopeLine("code", where, chunk.File)
+}
+
+func scopeLine(kind, where, file string) string {
+	line := lineComment(filepath.Ext(file)) + " " + kind
+	if where != "" {
+		line += " " + where
+	}
+	return fmt.Sprintf("%s (%s)", line, file)
+}
+
+// packageOf is "in package cache" for a Go file, "in module manager" for a
+// language where files are modules, "" when neither is known
+func (cb *ContextBuilder) packageOf(file string) string {
+	language := ""
+	if cb.kbData != nil {
+		language = cb.kbData.Structure[file].Language
+	}
+	if language == "" {
+		language = strings.TrimPrefix(filepath.Ext(file), ".")
+	}
+	switch language {
+	case "go":
+		if dir := path.Base(path.Dir(file)); dir != "." && dir != "/" {
+			return "in package " + dir
+		}
+	case "python", "py", "javascript", "js", "typescript", "ts":
+		return "in module " + strings.TrimSuffix(path.Base(file), path.Ext(file))
+	}
+	return ""
+}
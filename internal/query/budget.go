@@ -0,0 +1,122 @@
+package query
+
+import (
+	"fmt"
+
+	"eulix/internal/types"
+)
+
+// promptOverhead is the instructions and statistics llm wraps around the
+// context, in tokens
+const promptOverhead = 250
+
+// BudgetError is returned when a query would go over [llm.budget]. Callers can
+// ask the user and send it anyway with WaiveBudget.
+type BudgetError struct {
+	Tokens int     `json:"tokens"`         // estimated prompt tokens
+	Cost   float64 `json:"cost,omitempty"` // estimated dollars, 0 without prices
+	Limit  string  `json:"limit"`          // which limit, like "max_prompt_tokens 8000"
+}
+
+func (e *BudgetError) Error() string {
+	s := fmt.Sprintf("query would send ~%d prompt tokens", e.Tokens)
+	if e.Cost > 0 {
+		s += fmt.Sprintf(" (~$%.4f)", e.Cost)
+	}
+	return s + ", over " + e.Limit
+}
+
+// WaiveBudget lets the next question go over [llm.budget], after the user
+// confirmed a BudgetError
+func (r *Router) WaiveBudget() {
+	r.budgetWaived = true
+}
+
+// SetMaxResponse caps the answers to the following questions at tokens and
+// keeps that much of the budget free for them, 0 goes back to [retrieval]
+// max_response for each question's type
+func (r *Router) SetMaxResponse(tokens int) {
+	r.maxResponse = tokens
+}
+
+// responseTokens is how long the answer to the current question may be
+func (r *Router) responseTokens() int {
+	if r.maxResponse > 0 {
+		return r.maxResponse
+	}
+	return tunedParams(r.config.Retrieval, r.signals.class).maxResponse
+}
+
+// checkBudget is called before every model call, before the window is
+// rendered into the prompt. With on_exceed = "reduce" it drops the least
+// relevant chunks from window until the prompt fits. prompt is what goes
+// with the window and must not contain it, the window is counted by its
+// TotalTokens.
+func (r *Router) checkBudget(window *types.ContextWindow, prompt string) error {
+	if r.signals.waived {
+		return nil
+	}
+	budget := r.config.LLM.Budget
+
+	tokens := len(prompt)/4 + promptOverhead
+	if window != nil {
+		tokens += window.TotalTokens
+	}
+	over := r.overBudget(tokens)
+	if over == nil {
+		return nil
+	}
+	if window == nil || budget.OnExceed != "reduce" {
+		return over
+	}
+
+	// Chunks are ordered by relevance, the tail goes first
+	before := window.TotalTokens
+	for len(window.Chunks) > 1 && over != nil {
+		last := window.Chunks[len(window.Chunks)-1]
+		window.Chunks = window.Chunks[:len(window.Chunks)-1]
+		window.TotalTokens -= chunkTokens(last)
+		tokens -= chunkTokens(last)
+		over = r.overBudget(tokens)
+	}
+	if over != nil {
+		return over
+	}
+
+	window.Sources = chunkSources(window.Chunks)
+	r.signals.sources = window.Sources
+	r.signals.warnings = append(r.signals.warnings,
+		fmt.Sprintf("context reduced from %d to %d tokens to stay within [llm.budget]", before, window.TotalTokens))
+	return nil
+}
+
+func (r *Router) overBudget(tokens int) *BudgetError {
+	budget := r.config.LLM.Budget
+	cost := (float64(tokens)*budget.InputPrice + float64(r.responseTokens())*budget.OutputPrice) / 1e6
+
+	switch {
+	case budget.MaxPromptTokens > 0 && tokens > budget.MaxPromptTokens:
+		return &BudgetError{Tokens: tokens, Cost: cost, Limit: fmt.Sprintf("max_prompt_tokens %d", budget.MaxPromptTokens)}
+	case budget.MaxCost > 0 && cost > budget.MaxCost:
+		return &BudgetError{Tokens: tokens, Cost: cost, Limit: fmt.Sprintf("max_cost $%.4f", budget.MaxCost)}
+	}
+	return nil
+}
+
+// chunkTokens is what a chunk adds to ContextWindow.TotalTokens, counted the
+// way assembleContext does
+func chunkTokens(chunk types.ContextChunk) int {
+	return len(chunk.Content)/4 + 20
+}
+
+func chunkSources(chunks []types.ContextChunk) []string {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, chunk := range chunks {
+		if !seen[chunk.File] {
+			seen[chunk.File] = true
+			sources = append(sources, chunk.File)
+		}
+	}
+	return sources
+}
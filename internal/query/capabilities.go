@@ -0,0 +1,69 @@
+package query
+
+import "strings"
+
+// Capabilities says which search strategies the loaded KB supports. Only
+// kb_index.json is required; everything else degrades to what is left.
+type Capabilities struct {
+	Keyword  bool `json:"keyword"`  // chunks.json loaded, keyword and symbol search over chunks
+	Semantic bool `json:"semantic"` // vectors.bin lines up with chunks, embedding search
+	Graph    bool `json:"graph"`    // kb_call_graph.json loaded, usage and dependency answers
+}
+
+// Available lists the enabled capabilities by name
+func (c Capabilities) Available() []string {
+	var names []string
+	if c.Keyword {
+		names = append(names, "keyword")
+	}
+	if c.Semantic {
+		names = append(names, "semantic")
+	}
+	if c.Graph {
+		names = append(names, "graph")
+	}
+	return names
+}
+
+// Missing lists the disabled capabilities by name
+func (c Capabilities) Missing() []string {
+	var names []string
+	if !c.Keyword {
+		names = append(names, "keyword")
+	}
+	if !c.Semantic {
+		names = append(names, "semantic")
+	}
+	if !c.Graph {
+		names = append(names, "graph")
+	}
+	return names
+}
+
+func (c Capabilities) String() string {
+	s := "index only"
+	if available := c.Available(); len(available) > 0 {
+		s = strings.Join(available, ", ")
+	}
+	if missing := c.Missing(); len(missing) > 0 {
+		s += " (unavailable: " + strings.Join(missing, ", ") + ")"
+	}
+	return s
+}
+
+func (g *CallGraph) loaded() bool {
+	return g != nil && len(g.Functions)+len(g.Types) > 0
+}
+
+// Capabilities reports what the loaded KB can do. It loads the context
+// builder if needed.
+func (r *Router) Capabilities() Capabilities {
+	caps := Capabilities{Graph: r.callGraph.loaded()}
+
+	if err := r.ensureContextBuilder(); err == nil {
+		caps.Keyword = len(r.contextBuilder.chunks) > 0
+		caps.Semantic = r.contextBuilder.hasEmbeddings
+	}
+
+	return caps
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+
+	"eulix/internal/centrality"
+)
+
+// CentralFunction is a function ranked by how central it is to the call
+// graph, see internal/centrality
+type CentralFunction struct {
+	Name     string  `json:"name"`
+	Score    float64 `json:"score"`
+	Location string  `json:"location,omitempty"`
+	Callers  int     `json:"callers"`
+	Callees  int     `json:"callees"`
+}
+
+// Central returns the top most central functions, all of them when top is
+// 0. Indexes analyzed before centrality was stored are ranked on the spot.
+func Central(eulixDir string, top int) ([]CentralFunction, error) {
+	scores := map[string]float64{}
+	if index, err := loadKBIndex(eulixDir); err == nil && len(index.Centrality) > 0 {
+		scores = index.Centrality
+	} else if scores, err = centrality.FromCallGraph(eulixDir); err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("no call graph to rank, run 'eulix analyze' first")
+	}
+
+	graph, err := LoadCallGraph(eulixDir)
+	if err != nil {
+		graph = &CallGraph{}
+	}
+	ranked := make([]CentralFunction, 0, len(scores))
+	for name, score := range scores {
+		fn := graph.Functions[name]
+		ranked = append(ranked, CentralFunction{
+			Name:     name,
+			Score:    score,
+			Location: fn.Location,
+			Callers:  len(dedupe(fn.CalledBy)),
+			Callees:  len(dedupe(fn.Calls)),
+		})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if top > 0 && len(ranked) > top {
+		ranked = ranked[:top]
+	}
+	return ranked, nil
+}
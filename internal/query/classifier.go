@@ -25,6 +25,8 @@ const (
 	QueryTypeDocumentation
 	QueryTypeExample
 	QueryTypeTesting
+	QueryTypeSchema
+	QueryTypeFile
 )
 
 func (qt QueryType) String() string {
@@ -45,6 +47,8 @@ func (qt QueryType) String() string {
 		"Documentation",
 		"Example",
 		"Testing",
+		"Schema",
+		"File",
 	}[qt]
 }
 
@@ -57,6 +61,8 @@ type Classification struct {
 	Priority     int
 	NeedsContext bool
 	Entities     []Entity
+	Files        []FileRef // files and line ranges named in the question, for QueryTypeFile
+	Module       string    // the imported module asked about, for QueryTypeDependency
 }
 
 type Entity struct {
@@ -82,10 +88,15 @@ type Classifier struct {
 	documentationPattern  *regexp.Regexp
 	examplePattern        *regexp.Regexp
 	testingPattern        *regexp.Regexp
+	schemaPattern         *regexp.Regexp
+	tableWordPattern      *regexp.Regexp
+	fileQuestionPattern   *regexp.Regexp
+	moduleUsePattern      *regexp.Regexp
 
 	symbolPattern         *regexp.Regexp
 	validSymbols          map[string]bool
 	validTypes            map[string]bool
+	schema                *DBSchema // kb_schema.json, nil or empty without one
 }
 
 type SymbolIndex struct {
@@ -111,6 +122,10 @@ func QuerySheriff(kbIndexPath string) (*Classifier, error) {
 		documentationPattern:  regexp.MustCompile(`(?i)(document|comment|explain|describe|what\s+does|purpose\s+of|meant\s+to\s+do)`),
 		examplePattern:        regexp.MustCompile(`(?i)(example|how\s+to\s+use|usage\s+example|sample|demonstrate|show\s+me\s+how)`),
 		testingPattern:        regexp.MustCompile(`(?i)(test|unit\s+test|integration\s+test|mock|coverage|test\s+case)`),
+		schemaPattern:         regexp.MustCompile(`(?i)(\b(database|db)\s+(schema|tables?)\b|\bschema\b|foreign\s+keys?|primary\s+keys?)`),
+		tableWordPattern:      regexp.MustCompile(`(?i)(\btables?\b|\bcolumns?\b|\bmigrations?\b)`),
+		fileQuestionPattern:   regexp.MustCompile(`(?i)\b(explain|describe|summari[sz]e|walk\s+(me\s+)?through|go\s+through|overview\s+of|tell\s+me\s+about|what\s+(does|is|'s))\b`),
+		moduleUsePattern:      regexp.MustCompile(`(?i)\b(where|who|which|what|list|show|find)\b.*\b(uses?|using|used|imports?|imported|importing|depends?\s+on)\b`),
 
 		symbolPattern:         regexp.MustCompile(`\b[A-Z][a-z]+(?:[A-Z][a-z]+)*\b|\b[a-z_][a-z0-9_]*\b|\b[A-Z_][A-Z0-9_]+\b`),
 		validSymbols:          make(map[string]bool),
@@ -135,22 +150,62 @@ func (c *Classifier) loadSymbols(path string) error {
 	var kbIndex struct {
 		FunctionsByName map[string][]string `json:"functions_by_name"`
 		TypesByName     map[string][]string `json:"types_by_name"`
+		GlobalsByName   map[string][]string `json:"globals_by_name"`
 	}
 
 	if err := json.Unmarshal(data, &kbIndex); err != nil {
 		return err
 	}
 
-	for funcName := range kbIndex.FunctionsByName {
+	c.addSymbols(kbIndex.FunctionsByName, kbIndex.TypesByName)
+	c.addGlobals(kbIndex.GlobalsByName)
+	return nil
+}
+
+// addSymbols makes the names of functions and types known symbols
+func (c *Classifier) addSymbols(functions, types map[string][]string) {
+	for funcName := range functions {
 		c.validSymbols[funcName] = true
 	}
 
-	for typeName := range kbIndex.TypesByName {
+	for typeName := range types {
 		c.validSymbols[typeName] = true
 		c.validTypes[typeName] = true
 	}
+}
 
-	return nil
+// addGlobals makes the names of package level vars and consts known symbols
+func (c *Classifier) addGlobals(globals map[string][]string) {
+	for name := range globals {
+		c.validSymbols[name] = true
+	}
+}
+
+// schemaQuestion reports whether query is about the database schema. It
+// takes a loaded schema and either a phrase only schemas have, like
+// "foreign key", a column of one of its tables as table.column, or one of
+// its tables named along with "table", "column" or "migration".
+func (c *Classifier) schemaQuestion(query, queryLower string) bool {
+	if !c.schema.loaded() {
+		return false
+	}
+	if c.schemaPattern.MatchString(queryLower) {
+		return true
+	}
+	for _, m := range columnRefPattern.FindAllStringSubmatch(query, -1) {
+		if t := c.schema.table(m[1]); t != nil && t.column(m[2]) != nil {
+			return true
+		}
+	}
+	if !c.tableWordPattern.MatchString(queryLower) {
+		return false
+	}
+	for _, word := range strings.FieldsFunc(queryLower, func(r rune) bool { return r > 127 || !isWordByte(byte(r)) }) {
+		if !c.tableWordPattern.MatchString(word) && c.schema.table(word) != nil {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Classifier) Classify(query string) *Classification {
@@ -202,6 +257,37 @@ func (c *Classifier) level1PatternMatch(query, queryLower string) *Classificatio
 		}
 	}
 
+	// "where do we use github.com/redis/go-redis" is answered from the
+	// imports, not the call graph
+	if module := extractModule(query); module != "" && c.moduleUsePattern.MatchString(queryLower) {
+		return &Classification{
+			Type:         QueryTypeDependency,
+			Confidence:   0.95,
+			Module:       module,
+			Reasoning:    "Level 1: uses of an imported module",
+			NeedsContext: false,
+			Priority:     2,
+		}
+	}
+
+	// "explain internal/cache/manager.go" is about the whole file, it gets
+	// all of it rather than what search finds. A line range like
+	// orders.go:120-180 is always about those lines.
+	if files := extractFiles(query); len(files) > 0 && (c.fileQuestionPattern.MatchString(queryLower) || onlyFiles(query) || hasRange(files)) {
+		reasoning := "Level 1: question about a whole file"
+		if hasRange(files) {
+			reasoning = "Level 1: question about a line range"
+		}
+		return &Classification{
+			Type:         QueryTypeFile,
+			Confidence:   0.95,
+			Files:        files,
+			Reasoning:    reasoning,
+			NeedsContext: true,
+			Priority:     3,
+		}
+	}
+
 	// Example queries
 	if c.examplePattern.MatchString(queryLower) {
 		return &Classification{
@@ -290,6 +376,20 @@ func (c *Classifier) level1PatternMatch(query, queryLower string) *Classificatio
 		}
 	}
 
+	// Schema queries, after the code patterns so "add a column to the
+	// config struct" still gets context, before usage so "which tables
+	// reference users.id" is answered from the schema rather than the call
+	// graph
+	if c.schemaQuestion(query, queryLower) {
+		return &Classification{
+			Type:         QueryTypeSchema,
+			Confidence:   0.95,
+			Reasoning:    "Level 1: schema pattern match",
+			NeedsContext: false,
+			Priority:     2,
+		}
+	}
+
 	// Original patterns
 	if c.locationPattern.MatchString(queryLower) {
 		return &Classification{
@@ -571,15 +671,9 @@ func (c *Classifier) extractEntities(symbols []string) []Entity {
 	return entities
 }
 
+// extractKeywords is the words of the question without stop words, see
+// stopwords.go
 func extractKeywords(queryLower string) []string {
-	stopWords := map[string]bool{
-		"how": true, "does": true, "the": true, "a": true, "an": true,
-		"is": true, "are": true, "what": true, "where": true, "when": true,
-		"can": true, "will": true, "should": true, "would": true, "could": true,
-		"this": true, "that": true, "these": true, "those": true, "of": true,
-		"in": true, "on": true, "at": true, "to": true, "for": true, "with": true,
-	}
-
 	words := strings.FieldsFunc(queryLower, func(r rune) bool {
 		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_'
 	})
@@ -0,0 +1,204 @@
+package query
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"eulix/internal/config"
+	"eulix/internal/types"
+)
+
+// A relevant chunk that doesn't fit the budget is shortened rather than
+// dropped: first without comments and blank lines, then down to its
+// signature and doc comment. Chunks are taken best match first, so the
+// closest ones keep their bodies and the further ones keep at least their
+// shape, and the answer can still name them.
+
+// Values of [retrieval] compress
+const (
+	CompressOff     = "off"     // drop what doesn't fit
+	CompressStrip   = "strip"   // strip comments and blank lines only
+	CompressOutline = "outline" // strip, then fall back to signature and docs (default)
+)
+
+// How a chunk was shortened, shown in its header in the prompt
+const (
+	compressedStripped = "comments stripped"
+	compressedOutline  = "signature and docs only"
+)
+
+// minCompressedTokens is the least room worth trying to fit a chunk into
+const minCompressedTokens = 15
+
+// checkCompression warns about a [retrieval] compress that isn't one of the
+// modes, it falls back to the default
+func checkCompression(cfg config.RetrievalConfig) []string {
+	switch cfg.Compress {
+	case "", CompressOff, CompressStrip, CompressOutline:
+		return nil
+	}
+	return []string{fmt.Sprintf("[retrieval] compress = %q is not a mode, using %s. Use off, strip or outline", cfg.Compress, CompressOutline)}
+}
+
+func (cb *ContextBuilder) compression() string {
+	switch cb.config.Retrieval.Compress {
+	case CompressOff, CompressStrip:
+		return cb.config.Retrieval.Compress
+	}
+	return CompressOutline
+}
+
+// compress shortens chunk to at most room tokens, false when compression is
+// off or even its outline doesn't fit
+func (cb *ContextBuilder) compress(chunk Chunk, room int) (Chunk, bool) {
+	mode := cb.compression()
+	if mode == CompressOff || room < minCompressedTokens || chunk.Compressed != "" {
+		return Chunk{}, false
+	}
+
+	if stripped := stripComments(chunk.Content, filepath.Ext(chunk.File)); len(stripped)/4 <= room {
+		return compressedChunk(chunk, stripped, compressedStripped), true
+	}
+	if mode == CompressStrip {
+		return Chunk{}, false
+	}
+	if outline := outlineChunk(chunk.Content, filepath.Ext(chunk.File)); outline != "" && len(outline)/4 <= room {
+		return compressedChunk(chunk, outline, compressedOutline), true
+	}
+	return Chunk{}, false
+}
+
+func compressedChunk(chunk Chunk, content, how string) Chunk {
+	chunk.Content = content
+	chunk.Tokens = len(content) / 4
+	chunk.Compressed = how
+	return chunk
+}
+
+// lineComment is how a comment line starts in files with ext
+func lineComment(ext string) string {
+	switch ext {
+	case ".py", ".pyi", ".rb", ".sh", ".toml", ".yaml", ".yml":
+		return "#"
+	case ".sql":
+		return "--"
+	}
+	return "//"
+}
+
+// stripComments drops blank lines and lines that are only a comment,
+// including /* */ blocks that start a line. The comments above the code
+// stay, they are the chunk's header and doc comment. Comments after code
+// stay too, the code may be inside a string.
+func stripComments(content, ext string) string {
+	marker := lineComment(ext)
+	inBlock, inCode := false, false
+	var kept []string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case !inCode && (strings.HasPrefix(trimmed, marker) || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "*")):
+			kept = append(kept, strings.TrimRight(line, " \t"))
+			continue
+		case inBlock:
+			if strings.Contains(trimmed, "*/") {
+				inBlock = false
+			}
+			continue
+		case strings.HasPrefix(trimmed, "/*") && marker == "//":
+			inBlock = !strings.Contains(trimmed[2:], "*/")
+			continue
+		case strings.HasPrefix(trimmed, marker):
+			continue
+		}
+		inCode = true
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+	return strings.Join(kept, "\n")
+}
+
+// outlineChunk is the doc comment above the first declaration in content,
+// the declaration up to where its body opens, and a Python docstring, with
+// "..." for the body
+func outlineChunk(content, ext string) string {
+	lines := strings.Split(content, "\n")
+	marker := lineComment(ext)
+
+	var outline []string
+	i := 0
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, marker) || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "@") {
+			if trimmed != "" {
+				outline = append(outline, lines[i])
+			}
+			continue
+		}
+		break
+	}
+	if i == len(lines) {
+		return ""
+	}
+
+	// The signature runs until the body opens, a few lines at most
+	end := i
+	for ; end < len(lines) && end < i+8; end++ {
+		trimmed := strings.TrimSpace(lines[end])
+		if strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, ";") {
+			break
+		}
+	}
+	if end == len(lines) || end == i+8 {
+		end = i
+	}
+	outline = append(outline, lines[i:end+1]...)
+
+	// A Python docstring comes after the signature
+	if next := end + 1; next < len(lines) {
+		trimmed := strings.TrimSpace(lines[next])
+		for _, quote := range []string{`"""`, `'''`} {
+			if !strings.HasPrefix(trimmed, quote) {
+				continue
+			}
+			last := next
+			if !strings.Contains(trimmed[3:], quote) {
+				for last = next + 1; last < len(lines) && !strings.Contains(lines[last], quote); last++ {
+				}
+			}
+			if last < len(lines) {
+				outline = append(outline, lines[next:last+1]...)
+				end = last
+			}
+		}
+	}
+
+	if end+1 < len(lines) {
+		indent := lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+		outline = append(outline, indent+"    ...")
+	}
+	return strings.Join(outline, "\n")
+}
+
+// compressedWarning says how many chunks of window were shortened to fit,
+// "" when none were
+func compressedWarning(window *types.ContextWindow) string {
+	stripped, outlined := 0, 0
+	for _, chunk := range window.Chunks {
+		switch chunk.Compressed {
+		case compressedStripped:
+			stripped++
+		case compressedOutline:
+			outlined++
+		}
+	}
+	switch {
+	case stripped+outlined == 0:
+		return ""
+	case outlined == 0:
+		return fmt.Sprintf("%d chunks had their comments stripped to fit the context budget", stripped)
+	}
+	return fmt.Sprintf("%d chunks were cut to their signatures and %d had comments stripped to fit the context budget", outlined, stripped)
+}
@@ -0,0 +1,235 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"eulix/internal/cache"
+	"eulix/internal/llm"
+	"eulix/internal/types"
+)
+
+// Answer is a response along with how much to trust it
+type Answer struct {
+	Text       string            `json:"answer"`
+	Confidence Confidence        `json:"confidence"`
+	FollowUps  []string          `json:"followups,omitempty"`
+	Steps      []AgentStep       `json:"steps,omitempty"`      // tool calls made in agent mode
+	Entities   []string          `json:"entities,omitempty"`   // symbols the answer was about
+	Diff       *AnswerDiff       `json:"diff,omitempty"`       // against the answer cached before the code changed
+	Correction *cache.Correction `json:"correction,omitempty"` // the user's note that an earlier answer was wrong
+	Sources    []string          `json:"sources,omitempty"`    // files the context was built from
+	Owners     []string          `json:"owners,omitempty"`     // who owns the sources, from CODEOWNERS
+	Truncated  bool              `json:"truncated,omitempty"`  // interrupted, the text is incomplete
+	Warnings   []string          `json:"warnings,omitempty"`   // like context dropped to fit the budget
+	// MissingContext is an answer saying the context lacked what it needed,
+	// asking again after Expand may do better
+	MissingContext bool `json:"missing_context,omitempty"`
+	Expansion      int  `json:"expansion,omitempty"` // the Expand level it was answered at
+}
+
+func (a *Answer) String() string {
+	s := a.Text
+	if a.Correction != nil {
+		s = fmt.Sprintf("[correction, %s] %s\n\n%s", a.Correction.CreatedAt.Format("2 Jan"), a.Correction.Text, s)
+	}
+	if a.Truncated {
+		s += "\n\n[interrupted] The answer is incomplete and was not cached."
+	}
+	for _, warning := range a.Warnings {
+		s += "\n[!] " + warning
+	}
+	if len(a.Owners) > 0 {
+		s += "\n\nOwned by " + strings.Join(a.Owners, ", ")
+	}
+	s += "\n\nConfidence: " + a.Confidence.String()
+	if a.Diff != nil {
+		s += "\n\n" + a.Diff.String()
+	}
+	return s
+}
+
+// Confidence combines the signals behind an answer. Each part is 0-1, or -1
+// when it wasn't available (cache hits skip retrieval, index lookups never
+// ask the model).
+type Confidence struct {
+	Score      float64 `json:"score"`
+	Level      string  `json:"level"`
+	Retrieval  float64 `json:"retrieval"`
+	Classifier float64 `json:"classifier"`
+	Model      float64 `json:"model"`
+}
+
+func (c Confidence) String() string {
+	return fmt.Sprintf("%s (%.0f%%)", c.Level, c.Score*100)
+}
+
+// Retrieval and the model's own rating matter most, the classifier only says
+// whether the question was routed to the right handler
+const (
+	retrievalWeight  = 0.45
+	classifierWeight = 0.15
+	modelWeight      = 0.40
+)
+
+func scoreConfidence(retrieval, classifier, model float64) Confidence {
+	c := Confidence{Retrieval: retrieval, Classifier: classifier, Model: model}
+
+	var sum, weights float64
+	for _, part := range []struct{ value, weight float64 }{
+		{retrieval, retrievalWeight},
+		{classifier, classifierWeight},
+		{model, modelWeight},
+	} {
+		if part.value < 0 {
+			continue
+		}
+		sum += part.value * part.weight
+		weights += part.weight
+	}
+	if weights > 0 {
+		c.Score = sum / weights
+	}
+
+	switch {
+	case weights == 0:
+		c.Level = "unknown"
+	case c.Score >= 0.75:
+		c.Level = "high"
+	case c.Score >= 0.45:
+		c.Level = "medium"
+	default:
+		c.Level = "low"
+	}
+	return c
+}
+
+// answerSignals is what route saw while answering the current query. Router
+// isn't safe for concurrent queries anyway, so it lives on the Router.
+type answerSignals struct {
+	retrieval  float64
+	classifier float64
+	class      *Classification
+	sources    []string
+	resolved   string            // query with symbols from earlier turns, for retrieval
+	memory     string            // session memory block for prompts
+	previous   *cache.CacheEntry // cached answer from before the code changed
+	correction *cache.Correction // the user's correction of this answer
+	ctx        context.Context   // cancelled by Interrupt
+	partial    string            // what the model generated before an interrupt
+	quiet      bool              // don't stream, for agent tool calls
+	waived     bool              // [llm.budget] doesn't apply, the user confirmed
+	warnings   []string          // budget adjustments, shown with the answer
+	expansion  int               // retrieval widened by Expand, cache skipped
+	picks      map[string]string // definitions the user picked, see Pick
+	language   string            // the question's language when not English, see translate.go
+	logged     int64             // the question's row in the query log, for MarkHit
+	// window is the context retrieved for this answer, windowKey what it
+	// was retrieved for
+	window    *types.ContextWindow
+	windowKey string
+}
+
+// Answer runs a query and scores how confident the result is
+func (r *Router) Answer(query string) (*Answer, error) {
+	return r.answer(query, nil)
+}
+
+func (r *Router) answer(query string, session *Session) (*Answer, error) {
+	var logged int64
+	if r.cache != nil {
+		// History for chat recall, losing an entry isn't worth failing the query
+		logged, _ = r.cache.LogQuery(query)
+	}
+
+	query = r.hooks.BeforeQuery(query)
+
+	r.signals = answerSignals{retrieval: -1, classifier: -1, memory: session.Memory(), waived: r.budgetWaived, expansion: r.expansion, picks: r.picks, logged: logged}
+	r.budgetWaived = false
+	r.expansion = 0
+	r.picks = nil
+	defer r.interruptible()()
+	var recent []string
+	if session != nil {
+		recent = session.Recent
+	}
+	response, err := r.route(query, recent)
+	if errors.Is(err, llm.ErrInterrupted) {
+		return r.truncatedAnswer(query), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.report(StageRendering, "")
+
+	text, model := llm.SplitConfidence(response)
+
+	var diff *AnswerDiff
+	if r.signals.previous != nil {
+		diff = r.diffAnswer(r.signals.previous, text)
+	}
+
+	return &Answer{
+		Text:       r.hooks.AfterAnswer(query, text),
+		Confidence: scoreConfidence(r.signals.retrieval, r.signals.classifier, model),
+		FollowUps:  r.followUps(query),
+		Entities:   answerEntities(query, r.signals.class),
+		Diff:       diff,
+		Correction: r.signals.correction,
+		Sources:    r.signals.sources,
+		Owners:     r.owners.All(r.signals.sources),
+		Warnings:   r.signals.warnings,
+
+		MissingContext: missingContext(text),
+		Expansion:      r.signals.expansion,
+	}, nil
+}
+
+// buildContext is BuildContext for handlers, it records retrieval strength
+// for the confidence score and the sources for follow-ups. Follow-up questions
+// retrieve with the symbols they refer back to.
+func (r *Router) buildContext(query string) (*types.ContextWindow, error) {
+	if r.signals.resolved != "" {
+		query = r.signals.resolved
+	}
+	window, err := r.retrieved(query, func() (*types.ContextWindow, error) {
+		r.report(StageRetrieving, "")
+		return r.contextBuilder.BuildContext(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.report(StageRetrieving, retrievedChunks(len(window.Chunks)))
+	r.signals.retrieval = window.Retrieval
+	r.signals.sources = window.Sources
+	if warning := compressedWarning(window); warning != "" {
+		r.signals.warnings = append(r.signals.warnings, warning)
+	}
+	return window, nil
+}
+
+// ask is llmClient.Query for handlers, with the session memory on top and
+// the language to answer in below
+func (r *Router) ask(context *types.ContextWindow, prompt string) (string, error) {
+	prompt = r.signals.memory + prompt + r.answerLanguage()
+	if err := r.checkBudget(context, prompt); err != nil {
+		return "", err
+	}
+	r.report(StageCalling, "")
+	r.llmClient.SetMaxTokens(r.responseTokens())
+	return r.keepPartial(r.llmClient.QueryStream(r.generationContext(), context, prompt, r.stream()))
+}
+
+// complete is llmClient.Complete with the session memory on top and the
+// language to answer in below
+func (r *Router) complete(prompt string) (string, error) {
+	prompt = r.signals.memory + prompt + r.answerLanguage()
+	if err := r.checkBudget(nil, prompt); err != nil {
+		return "", err
+	}
+	r.report(StageCalling, "")
+	r.llmClient.SetMaxTokens(r.responseTokens())
+	return r.keepPartial(r.llmClient.CompleteStream(r.generationContext(), prompt, r.stream()))
+}
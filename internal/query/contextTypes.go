@@ -1,9 +1,13 @@
 package query
 import (
+	"context"
+	"sync"
+
 	"eulix/internal/config"
-	"eulix/internal/embeddings"
 	"eulix/internal/llm"
 	"eulix/internal/cache"
+	"eulix/internal/hooks"
+	"eulix/internal/owners"
 
 )
 
@@ -21,7 +25,21 @@ type Router struct {
 	contextBuilder *ContextBuilder
 	kbIndex        *KBIndex
 	callGraph      *CallGraph
+	graphWarning   string // why a call graph that is there couldn't be loaded
+	dbSchema       *DBSchema
+	owners         *owners.Map
 	currentChecksum string
+	hooks          *hooks.Runner
+	signals        answerSignals
+	onProgress     func(Progress)
+	onText         func(string)
+	mu             sync.Mutex         // guards cancel, Interrupt comes from other goroutines
+	cancel         context.CancelFunc // stops the answer in flight
+	budgetWaived   bool               // the next question may go over [llm.budget]
+	expansion      int                // the next question retrieves wider, see Expand
+	disambiguate   bool               // ask which definition is meant, see Disambiguate
+	picks          map[string]string  // definitions picked for the next question, see Pick
+	maxResponse    int                // answer length in tokens, 0 per query type, see SetMaxResponse
 }
 
 type KBIndex struct {
@@ -29,6 +47,10 @@ type KBIndex struct {
 	FunctionsCalling map[string][]string `json:"functions_calling"`
 	FunctionsByTag   map[string][]string `json:"functions_by_tag"`
 	TypesByName      map[string][]string `json:"types_by_name"`
+	Centrality       map[string]float64  `json:"centrality"` // see internal/centrality, written by analyze
+	GlobalsByName    map[string][]string `json:"globals_by_name"` // package level vars and consts
+
+	folded map[string][]string // lower case name to the names, built by Lookup
 }
 
 type CallGraph struct {
@@ -49,9 +71,38 @@ type TypeNode struct {
 	Methods  []string `json:"methods"`
 }
 
-type centralFunction struct {
-	name  string
-	count int
+// DBSchema is kb_schema.json, the tables the parser found in .sql
+// migrations and ORM models
+type DBSchema struct {
+	Tables []DBTable `json:"tables"`
+}
+
+type DBTable struct {
+	Name        string         `json:"name"`
+	Source      string         `json:"source"`
+	Line        int            `json:"line"`
+	Origin      string         `json:"origin"` // "sql", "sqlalchemy", "django", "gorm"
+	Columns     []DBColumn     `json:"columns"`
+	ForeignKeys []DBForeignKey `json:"foreign_keys"`
+}
+
+type DBColumn struct {
+	Name       string `json:"name"`
+	DataType   string `json:"data_type"`
+	Nullable   bool   `json:"nullable"`
+	PrimaryKey bool   `json:"primary_key"`
+}
+
+type DBForeignKey struct {
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+}
+
+// queryEmbedder embeds a question for semantic search, eulix_embed outside
+// of tests
+type queryEmbedder interface {
+	EmbedQueryBinary(query string) ([]float32, error)
 }
 
 // context_window.go
@@ -59,16 +110,21 @@ type ContextBuilder struct {
 	eulixDir       string
 	config         *config.Config
 	llmClient      *llm.Client
-	queryEmbedder  *embeddings.QueryEmbedder
+	queryEmbedder  queryEmbedder
 	embeddings     [][]float32
 	chunks         []Chunk
 	vectorMap      map[string]int // ID -> Index in embeddings slice
 	callGraph      map[string][]Relationship
 	hasCallGraph   bool
 	hasEmbeddings  bool
-	embData        *EmbeddingsData
+	warnings       []string
 	kbData         *KnowledgeBase
 	hasKB          bool
+	progress       func(Progress)
+	scope          []string // retrieval keeps to files under these, all files when empty
+	expansion      int      // how much wider than usual to retrieve, see Expand
+	params         retrievalParams // for the question being answered, see Tune
+	picks          map[string]string // symbol to the definition the user meant, see Router.Pick
 }
 
 type Chunk struct {
@@ -82,6 +138,7 @@ type Chunk struct {
 	Symbols   []string
 	Name      string
 	Importance float64
+	Compressed string // how the content was shortened to fit, "" for whole, see compress.go
 }
 
 type Relationship struct {
@@ -99,29 +156,6 @@ type ScoredChunk struct {
 	MatchDetails string
 }
 
-type EmbeddingsData struct {
-	Model       string             `json:"model"`
-	Dimension   int                `json:"dimension"`
-	TotalChunks int                `json:"total_chunks"`
-	Embeddings  []EmbeddingChunk   `json:"embeddings"`
-}
-
-type EmbeddingChunk struct {
-	ID       string      `json:"id"`
-	ChunkType string     `json:"chunk_type"`
-	Content  string      `json:"content"`
-	Metadata Metadata    `json:"metadata"`
-}
-
-type Metadata struct {
-	FilePath   string `json:"file_path"`
-	Language   string `json:"language"`
-	LineStart  int    `json:"line_start"`
-	LineEnd    int    `json:"line_end"`
-	Name       string `json:"name"`
-	Complexity int    `json:"complexity"`
-}
-
 type CallGraphData struct {
 	Functions map[string]struct {
 		Calls    []string `json:"calls"`
@@ -153,6 +187,15 @@ type FileStructure struct {
 	Language   string        `json:"language"`
 	Functions  []KBFunction  `json:"functions"`
 	Classes    []KBClass     `json:"classes"`
+	GlobalVars []KBGlobalVar `json:"global_vars"`
+}
+
+// KBGlobalVar is a package level variable or constant
+type KBGlobalVar struct {
+	Name           string `json:"name"`
+	TypeAnnotation string `json:"type_annotation"`
+	Value          string `json:"value"`
+	Line           int    `json:"line"`
 }
 
 type KBFunction struct {
@@ -163,6 +206,14 @@ type KBFunction struct {
 	LineStart   int          `json:"line_start"`
 	LineEnd     int          `json:"line_end"`
 	Calls       []FunctionCall `json:"calls"`
+	CalledBy    []KBCaller   `json:"called_by"`
+	Complexity  int          `json:"complexity"`
+}
+
+type KBCaller struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
 }
 
 type KBClass struct {
@@ -203,7 +254,10 @@ type KBIndices struct {
 }
 
 type EntryPoint struct {
-	Function string `json:"function"`
-	File     string `json:"file"`
-	Line     int    `json:"line"`
+	EntryType string   `json:"entry_type"` // "api_endpoint", "cli_command", "main"
+	Path      string   `json:"path"`       // route or command, may be empty
+	Function  string   `json:"function"`
+	File      string   `json:"file"`
+	Line      int      `json:"line"`
+	Methods   []string `json:"methods"` // HTTP methods of an api_endpoint
 }
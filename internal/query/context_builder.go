@@ -1,7 +1,6 @@
 package query
 
 import (
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -11,19 +10,13 @@ import (
 	"strings"
 	"unicode"
 
+	"eulix/internal/chunkstore"
 	"eulix/internal/config"
 	"eulix/internal/embeddings"
 	"eulix/internal/llm"
 	"eulix/internal/types"
 )
 
-// These are written in bin files generated by eulix_embed
-const (
-	BinaryVersion = uint32(2)
-	MagicBytes    = "EULX"
-	VectorVersion = uint32(1)
-)
-
 func ContextWindowCreator(eulixDir string, cfg *config.Config, llmClient *llm.Client) (*ContextBuilder, error) {
 	cb := &ContextBuilder{
 		eulixDir:   eulixDir,
@@ -31,32 +24,45 @@ func ContextWindowCreator(eulixDir string, cfg *config.Config, llmClient *llm.Cl
 		llmClient:  llmClient,
 		vectorMap:  make(map[string]int),
 	}
+	cb.Tune(nil)
+	cb.warnings = append(cb.warnings, checkRetrievalTypes(cfg.Retrieval)...)
+	cb.warnings = append(cb.warnings, checkCompression(cfg.Retrieval)...)
+	cb.warnings = append(cb.warnings, checkStrategy(cfg.LLM)...)
+	cb.warnings = append(cb.warnings, checkTranslate(cfg.LLM)...)
 
 	// Initialize query embedder
-	eulixBinaryPath := filepath.Join(eulixDir, "..", "eulix_embed")
-	cb.queryEmbedder = embeddings.VectorWeaver(
-		eulixBinaryPath,
+	embedder := embeddings.VectorWeaver(
+		cfg.Tools.EmbedPath(),
 		cfg.Embeddings.Model,
 	)
+	cb.queryEmbedder = embedder
+	if cfg.Cassettes.Recording() {
+		recorder, err := embeddings.Tapedeck(cfg.Cassettes, cfg.Embeddings.Model, embedder.EmbedQueryBinary)
+		if err != nil {
+			return nil, err
+		}
+		cb.queryEmbedder = recorder
+	}
 
-	// Load pre-computed KB embeddings from embeddings.bin
+	// Load chunks from chunks.json. Without them only kb.json lookups are left.
+	complexity, err := cb.loadChunks()
+	if err != nil {
+		cb.warnings = append(cb.warnings, fmt.Sprintf("Keyword and semantic search disabled, only exact symbol lookups work: %v", err))
+	}
+	cb.scoreImportance(complexity)
+
+	// Load pre-computed KB embeddings from vectors.bin, matched to chunks by ID.
+	// Without them search falls back to keyword matching, say so instead of
+	// quietly returning worse results.
 	if err := cb.loadEmbeddings(); err != nil {
 		cb.hasEmbeddings = false
+		if len(cb.chunks) > 0 {
+			cb.warnings = append(cb.warnings, fmt.Sprintf("Semantic search disabled, using keyword search only: %v", err))
+		}
 	} else {
 		cb.hasEmbeddings = true
 	}
 
-	// Load chunks from embeddings.json
-	if err := cb.loadChunks(); err != nil {
-		return nil, fmt.Errorf("failed to load chunks: %w", err)
-	}
-
-	// Load vector map from vectors.bin for fast ID lookups
-	if err := cb.loadVectorMap(); err != nil {
-		// Vector map is optional, continue without it
-		cb.vectorMap = make(map[string]int)
-	}
-
 	// Try to load call graph
 	cb.loadCallGraph()
 	if err := cb.loadKnowledgeBase(); err != nil {
@@ -86,150 +92,87 @@ func (cb *ContextBuilder) loadKnowledgeBase() error {
 }
 
 func (cb *ContextBuilder) loadEmbeddings() error {
-	embPath := filepath.Join(cb.eulixDir, "embeddings.bin")
-	data, err := os.ReadFile(embPath)
+	vectors, err := chunkstore.LoadVectors(cb.eulixDir)
 	if err != nil {
-		return fmt.Errorf("embeddings.bin not found: %w", err)
+		return fmt.Errorf("vectors.bin not loaded: %w", err)
 	}
 
-	if len(data) < 16 { // Magic(4)+Version(4)+Num(4)+Dim(4)
-		return fmt.Errorf("invalid embeddings file: too short (%d bytes)", len(data))
+	if vectors.Dimension != cb.config.Embeddings.Dimension {
+		return fmt.Errorf("dimension mismatch: expected %d, got %d", cb.config.Embeddings.Dimension, vectors.Dimension)
 	}
 
-	// Validate magic bytes
-	magic := string(data[0:4])
-	if magic != MagicBytes {
-		return fmt.Errorf("invalid binary file: wrong magic bytes. Expected %q, got %q", MagicBytes, magic)
+	// Chunks without a vector keep a nil entry and are skipped by vectorSearch
+	var missing []string
+	cb.embeddings = make([][]float32, len(cb.chunks))
+	for i, chunk := range cb.chunks {
+		vec, ok := vectors.ByID[chunk.ID]
+		if !ok {
+			missing = append(missing, chunk.ID)
+			continue
+		}
+		cb.embeddings[i] = vec
 	}
 
-	// Validate version
-	version := binary.LittleEndian.Uint32(data[4:8])
-	if version != BinaryVersion {
-		return fmt.Errorf("version mismatch: expected %d, got %d", BinaryVersion, version)
+	if len(cb.chunks) == 0 {
+		return fmt.Errorf("no chunks loaded to match vectors to")
 	}
-
-	// Parse counts
-	numEmbeddings := binary.LittleEndian.Uint32(data[8:12])
-	dimension := binary.LittleEndian.Uint32(data[12:16])
-
-	if int(dimension) != cb.config.Embeddings.Dimension {
-		return fmt.Errorf("dimension mismatch: expected %d, got %d", cb.config.Embeddings.Dimension, dimension)
+	if len(missing) == len(cb.chunks) {
+		return fmt.Errorf("no chunk in chunks.json has a vector in vectors.bin, run 'eulix analyze'")
 	}
 
-	// Validate file size
-	expectedSize := 16 + (numEmbeddings * dimension * 4)
-	if uint32(len(data)) != expectedSize {
-		return fmt.Errorf("file size mismatch: expected %d bytes, got %d bytes", expectedSize, len(data))
-	}
-
-	cb.embeddings = make([][]float32, numEmbeddings)
-	offset := 16
-
-	for i := 0; i < int(numEmbeddings); i++ {
-		embedding := make([]float32, dimension)
-		for j := 0; j < int(dimension); j++ {
-			if offset+4 > len(data) {
-				return fmt.Errorf("unexpected EOF at embedding %d, dimension %d", i, j)
-			}
-			bits := binary.LittleEndian.Uint32(data[offset : offset+4])
-			embedding[j] = math.Float32frombits(bits)
-			offset += 4
+	if len(missing) > 0 {
+		// A stale vectors.bin can miss thousands, a few ids say which
+		shown := missing
+		if len(shown) > missingShown {
+			shown = shown[:missingShown]
 		}
-		cb.embeddings[i] = embedding
+		list := strings.Join(shown, ", ")
+		if more := len(missing) - len(shown); more > 0 {
+			list += fmt.Sprintf(" and %d more", more)
+		}
+		cb.warnings = append(cb.warnings, fmt.Sprintf(
+			"%d of %d chunks have no vector and are only found by keyword search, run 'eulix analyze' to embed them: %s",
+			len(missing), len(cb.chunks), list))
 	}
 
 	return nil
 }
 
-func (cb *ContextBuilder) loadChunks() error {
-	embJsonPath := filepath.Join(cb.eulixDir, "embeddings.json")
-	data, err := os.ReadFile(embJsonPath)
-	if err != nil {
-		return fmt.Errorf("failed to read embeddings.json: %w", err)
-	}
+// missingShown is how many chunks without a vector the warning names
+const missingShown = 5
 
-	var embData EmbeddingsData
-	if err := json.Unmarshal(data, &embData); err != nil {
-		return fmt.Errorf("failed to parse embeddings.json: %w", err)
+// loadChunks reads chunks.json, it returns each chunk's complexity for
+// scoreImportance
+func (cb *ContextBuilder) loadChunks() ([]int, error) {
+	store, err := chunkstore.Load(cb.eulixDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunks.json: %w", err)
 	}
 
-	cb.embData = &embData
-	cb.chunks = make([]Chunk, len(embData.Embeddings))
+	sorted := store.Sorted()
+	cb.chunks = make([]Chunk, len(sorted))
+	complexity := make([]int, len(sorted))
 
-	for i, embChunk := range embData.Embeddings {
-		symbols := extractSymbolsFromContent(embChunk.Content, embChunk.Metadata.Name)
-		tokens := len(embChunk.Content) / 4
+	for i, stored := range sorted {
+		symbols := extractSymbolsFromContent(stored.Content, stored.Metadata.Name)
+		tokens := len(stored.Content) / 4
 
 		cb.chunks[i] = Chunk{
-			ID:        embChunk.ID,
-			ChunkType: embChunk.ChunkType,
-			File:      embChunk.Metadata.FilePath,
-			StartLine: embChunk.Metadata.LineStart,
-			EndLine:   embChunk.Metadata.LineEnd,
-			Content:   embChunk.Content,
+			ID:        stored.ID,
+			ChunkType: stored.ChunkType,
+			File:      stored.Metadata.FilePath,
+			StartLine: stored.Metadata.LineStart,
+			EndLine:   stored.Metadata.LineEnd,
+			Content:   stored.Content,
 			Tokens:    tokens,
 			Symbols:   symbols,
-			Name:      embChunk.Metadata.Name,
-			Importance: calculateImportance(embChunk.ChunkType, embChunk.Metadata.Complexity),
+			Name:      stored.Metadata.Name,
 		}
+		complexity[i] = stored.Metadata.Complexity
+		cb.vectorMap[stored.ID] = i
 	}
 
-	return nil
-}
-
-// Load vector map from vectors.bin for fast ID-based lookups
-func (cb *ContextBuilder) loadVectorMap() error {
-	vectorsPath := filepath.Join(cb.eulixDir, "vectors.bin")
-	data, err := os.ReadFile(vectorsPath)
-	if err != nil {
-		return fmt.Errorf("vectors.bin not found: %w", err)
-	}
-
-	if len(data) < 12 { // Version(4)+Count(8)
-		return fmt.Errorf("invalid vectors file: too short (%d bytes)", len(data))
-	}
-
-	offset := 0
-
-	// Read version
-	version := binary.LittleEndian.Uint32(data[offset : offset+4])
-	offset += 4
-	if version != VectorVersion {
-		return fmt.Errorf("vectors.bin version mismatch: expected %d, got %d", VectorVersion, version)
-	}
-
-	// Read count
-	count := binary.LittleEndian.Uint64(data[offset : offset+8])
-	offset += 8
-
-	// Read dimension
-	dimension := binary.LittleEndian.Uint32(data[offset : offset+4])
-	offset += 4
-
-	// Read each ID and build map
-	for i := 0; i < int(count); i++ {
-		// Read ID length
-		if offset+4 > len(data) {
-			return fmt.Errorf("unexpected EOF reading ID length at index %d", i)
-		}
-		idLen := binary.LittleEndian.Uint32(data[offset : offset+4])
-		offset += 4
-
-		// Read ID
-		if offset+int(idLen) > len(data) {
-			return fmt.Errorf("unexpected EOF reading ID at index %d", i)
-		}
-		id := string(data[offset : offset+int(idLen)])
-		offset += int(idLen)
-
-		// Store ID -> Index mapping
-		cb.vectorMap[id] = i
-
-		// Skip vector data
-		offset += int(dimension) * 4
-	}
-
-	return nil
+	return complexity, nil
 }
 
 func (cb *ContextBuilder) loadCallGraph() {
@@ -276,24 +219,148 @@ func (cb *ContextBuilder) loadCallGraph() {
 
 // BuildContext is the key of the context window creation
 func (cb *ContextBuilder) BuildContext(query string) (*types.ContextWindow, error) {
-	systemPromptTokens := 150
+	budget := cb.tokenBudget(query)
+	selected, candidates := cb.retrieve(query, budget)
+	window := cb.assembleContext(cb.groupByFile(selected, budget))
+	window.Retrieval = retrievalStrength(candidates)
+	return window, nil
+}
+
+// What max_tokens holds besides the context, [retrieval] safety_buffer and
+// max_response change the last two
+const (
+	systemPromptTokens = 150
+	baseSafetyBuffer   = 200
+	baseMaxResponse    = 2000
+)
+
+// tokenBudget is how many tokens of context fit with query, the prompt
+// around it and the response
+func (cb *ContextBuilder) tokenBudget(query string) int {
 	queryTokens := len(query) / 4
-	safetyBuffer := 200
-	responseReserve := 2000
-	available := cb.config.LLM.MaxTokens - queryTokens - systemPromptTokens - safetyBuffer - responseReserve
-	tokenBudget := int(float64(available) * 0.85)
+	safetyBuffer := baseSafetyBuffer
+	if cb.config.Retrieval.SafetyBuffer > 0 {
+		safetyBuffer = cb.config.Retrieval.SafetyBuffer
+	}
+	available := cb.config.LLM.MaxTokens - queryTokens - systemPromptTokens - safetyBuffer - cb.params.maxResponse
+	return int(float64(available) * cb.budgetShare())
+}
+
+// BuildMergedContext retrieves for each sub-query of a decomposed question
+// and merges the results into one window. The first query is the original
+// question and sets the budget, which is split evenly so every part gets
+// some room.
+func (cb *ContextBuilder) BuildMergedContext(queries []string) (*types.ContextWindow, error) {
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no queries to retrieve for")
+	}
+
+	share := cb.tokenBudget(queries[0]) / len(queries)
 
-	candidates := cb.multiStrategySearch(query, 100)
+	var merged []Chunk
+	seen := make(map[string]bool)
+	retrieval := 0.0
+	for _, q := range queries {
+		selected, candidates := cb.retrieve(q, share)
+		retrieval += retrievalStrength(candidates)
+
+		for _, chunk := range selected {
+			key := fmt.Sprintf("%s:%d-%d", chunk.File, chunk.StartLine, chunk.EndLine)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, chunk)
+		}
+	}
+
+	window := cb.assembleContext(cb.groupByFile(merged, share*len(queries)))
+	window.Retrieval = retrieval / float64(len(queries))
+	return window, nil
+}
+
+// retrieve searches for query and picks the chunks that fit in budget, the
+// raw candidates are returned too for scoring retrieval
+func (cb *ContextBuilder) retrieve(query string, budget int) ([]Chunk, []ScoredChunk) {
+	candidates := cb.inScope(cb.multiStrategySearch(query, cb.candidates()))
 
 	var scored []ScoredChunk
 	if cb.hasCallGraph {
-		scored = cb.buildContextWithGraph(candidates, tokenBudget)
+		// The graph can reach outside the scope, filter again
+		scored = cb.inScope(cb.buildContextWithGraph(candidates, budget))
 	} else {
-		scored = cb.buildContextWithoutGraph(candidates, tokenBudget)
+		scored = cb.buildContextWithoutGraph(candidates, budget)
 	}
+	scored = cb.weighByImportance(scored)
 
-	selected := cb.selectChunks(scored, tokenBudget)
-	return cb.assembleContext(selected), nil
+	return cb.selectChunks(scored, budget), candidates
+}
+
+// SetScope keeps retrieval to files under paths, relative to the project
+// root. No paths searches everything again.
+func (cb *ContextBuilder) SetScope(paths []string) {
+	cb.scope = nil
+	for _, p := range paths {
+		p = strings.Trim(filepath.ToSlash(filepath.Clean(p)), "/")
+		if p != "" && p != "." {
+			cb.scope = append(cb.scope, p)
+		}
+	}
+}
+
+func (cb *ContextBuilder) inScope(chunks []ScoredChunk) []ScoredChunk {
+	if len(cb.scope) == 0 {
+		return chunks
+	}
+	kept := chunks[:0]
+	for _, c := range chunks {
+		for _, p := range cb.scope {
+			if c.File == p || strings.HasPrefix(c.File, p+"/") {
+				kept = append(kept, c)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// retrievalStrength scores the top candidates by how they were found:
+// index and exact symbol hits are solid, pure keyword hits much less so.
+// Fewer than three candidates pulls the score down.
+func retrievalStrength(candidates []ScoredChunk) float64 {
+	const top = 3
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	n := 0
+	for _, c := range candidates {
+		if n == top {
+			break
+		}
+
+		var strength float64
+		switch {
+		case strings.HasPrefix(c.MatchType, "kb") || strings.HasPrefix(c.MatchType, "exact"):
+			strength = 1.0
+		case c.MatchType == "semantic":
+			strength = math.Min(c.Score, 1.0) // cosine similarity
+		case c.MatchType == "keyword+semantic":
+			strength = 0.8
+		case c.MatchType == "partial":
+			strength = 0.6
+		case c.MatchType == "keyword":
+			strength = 0.5
+		default:
+			strength = 0.4
+		}
+
+		total += strength
+		n++
+	}
+
+	return total / float64(top)
 }
 
 func (cb *ContextBuilder) buildContextWithGraph(candidates []ScoredChunk, budget int) []ScoredChunk {
@@ -346,7 +413,10 @@ func (cb *ContextBuilder) buildContextWithGraph(candidates []ScoredChunk, budget
 	}
 
 	sort.Slice(result, func(i, j int) bool {
-		return result[i].Score > result[j].Score
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].ID < result[j].ID
 	})
 
 	return result
@@ -419,6 +489,7 @@ func (cb *ContextBuilder) assembleContext(chunks []Chunk) *types.ContextWindow {
 			EndLine:    chunk.EndLine,
 			Content:    chunk.Content,
 			Importance: chunk.Importance,
+			Compressed: chunk.Compressed,
 		}
 	}
 
@@ -452,6 +523,9 @@ func (cb *ContextBuilder) kbExactLookup(query string) []ScoredChunk {
 		// Search in KB indices first (fastest)
 		if locations, exists := cb.kbData.Indices.FunctionsByName[symbol]; exists {
 			for _, loc := range locations {
+				if picked := cb.picks[symbol]; picked != "" && loc != picked {
+					continue
+				}
 				if !matchedIDs[loc] {
 					matchedIDs[loc] = true
 					if chunk := cb.findChunkForLocation(loc); chunk != nil {
@@ -471,7 +545,7 @@ func (cb *ContextBuilder) kbExactLookup(query string) []ScoredChunk {
 		for filePath, fileStruct := range cb.kbData.Structure {
 			// Search functions
 			for _, fn := range fileStruct.Functions {
-				if strings.ToLower(fn.Name) == symbolLower {
+				if strings.ToLower(fn.Name) == symbolLower && !cb.pickedOther(symbol, filePath, fn.LineStart, fn.LineEnd) {
 					chunkID := fmt.Sprintf("%s:%d-%d", filePath, fn.LineStart, fn.LineEnd)
 					if !matchedIDs[chunkID] {
 						matchedIDs[chunkID] = true
@@ -492,7 +566,7 @@ func (cb *ContextBuilder) kbExactLookup(query string) []ScoredChunk {
 
 			// Search classes and methods
 			for _, class := range fileStruct.Classes {
-				if strings.ToLower(class.Name) == symbolLower {
+				if strings.ToLower(class.Name) == symbolLower && !cb.pickedOther(symbol, filePath, class.LineStart, class.LineEnd) {
 					chunkID := fmt.Sprintf("%s:%d-%d", filePath, class.LineStart, class.LineEnd)
 					if !matchedIDs[chunkID] {
 						matchedIDs[chunkID] = true
@@ -509,7 +583,7 @@ func (cb *ContextBuilder) kbExactLookup(query string) []ScoredChunk {
 
 				// Search methods within class
 				for _, method := range class.Methods {
-					if strings.ToLower(method.Name) == symbolLower {
+					if strings.ToLower(method.Name) == symbolLower && !cb.pickedOther(symbol, filePath, method.LineStart, method.LineEnd) {
 						chunkID := fmt.Sprintf("%s:%d-%d", filePath, method.LineStart, method.LineEnd)
 						if !matchedIDs[chunkID] {
 							matchedIDs[chunkID] = true
@@ -534,6 +608,30 @@ func (cb *ContextBuilder) kbExactLookup(query string) []ScoredChunk {
 					}
 				}
 			}
+
+			// Search package level vars and consts, the chunk holding the
+			// declaration if there is one
+			for _, global := range fileStruct.GlobalVars {
+				if strings.ToLower(global.Name) != symbolLower || cb.pickedOther(symbol, filePath, global.Line, global.Line) {
+					continue
+				}
+				location := fmt.Sprintf("%s:%d", filePath, global.Line)
+				if matchedIDs[location] {
+					continue
+				}
+				matchedIDs[location] = true
+				chunk := cb.buildChunkFromKBGlobal(global, filePath)
+				if found := cb.findChunkForLocation(location); found != nil {
+					chunk = *found
+				}
+				scored = append(scored, ScoredChunk{
+					Chunk:        chunk,
+					Score:        112.0,
+					Distance:     0,
+					MatchType:    "kb_global",
+					MatchDetails: fmt.Sprintf("Global: %s", global.Name),
+				})
+			}
 		}
 	}
 
@@ -644,6 +742,31 @@ func (cb *ContextBuilder) buildChunkFromKBClass(class KBClass, filePath string)
 	}
 }
 
+func (cb *ContextBuilder) buildChunkFromKBGlobal(global KBGlobalVar, filePath string) Chunk {
+	content := fmt.Sprintf("Variable: %s\nLine: %d\n", global.Name, global.Line)
+
+	if global.TypeAnnotation != "" {
+		content += fmt.Sprintf("Type: %s\n", global.TypeAnnotation)
+	}
+
+	if global.Value != "" {
+		content += fmt.Sprintf("Value: %s\n", global.Value)
+	}
+
+	return Chunk{
+		ID:         fmt.Sprintf("%s:%d-%d", filePath, global.Line, global.Line),
+		ChunkType:  "global",
+		File:       filePath,
+		StartLine:  global.Line,
+		EndLine:    global.Line,
+		Content:    content,
+		Tokens:     len(content) / 4,
+		Symbols:    []string{global.Name},
+		Name:       global.Name,
+		Importance: 0.8,
+	}
+}
+
 func (cb *ContextBuilder) expandFromKBFunction(fn KBFunction, filePath string, baseScore float64) []ScoredChunk {
 	expanded := make([]ScoredChunk, 0)
 
@@ -734,9 +857,10 @@ func (cb *ContextBuilder) multiStrategySearch(query string, topK int) []ScoredCh
 
 	// Strategy 4: Semantic search (if embeddings available)
 	if cb.hasEmbeddings {
+		cb.report(StageEmbedding, "")
 		queryEmbedding, err := cb.queryEmbedder.EmbedQueryBinary(query)
 		if err == nil {
-			semanticMatches := cb.vectorSearch(queryEmbedding, topK, 0.5)
+			semanticMatches := cb.vectorSearch(queryEmbedding, topK, cb.similarity())
 			for _, match := range semanticMatches {
 				if existing, exists := allCandidates[match.ID]; exists {
 					// Combine scores
@@ -769,7 +893,10 @@ func (cb *ContextBuilder) multiStrategySearch(query string, topK int) []ScoredCh
 		if result[i].MatchType != "exact" && result[j].MatchType == "exact" {
 			return false
 		}
-		return result[i].Score > result[j].Score
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].ID < result[j].ID
 	})
 
 	if len(result) > topK {
@@ -781,14 +908,18 @@ func (cb *ContextBuilder) multiStrategySearch(query string, topK int) []ScoredCh
 
 // Exact symbol search for precise function/class lookups
 func (cb *ContextBuilder) exactSymbolSearch(query string) []ScoredChunk {
-	potentialSymbols := extractPotentialSymbols(query)
+	potentialSymbols := withoutStopWords(extractPotentialSymbols(query))
 	scored := make([]ScoredChunk, 0)
 
 	for _, chunk := range cb.chunks {
 		nameLower := strings.ToLower(chunk.Name)
+		language := languageOf(chunk.File)
 
 		// Check for exact name match
 		for _, querySymbol := range potentialSymbols {
+			if isCodeKeyword(language, querySymbol) {
+				continue
+			}
 			querySymbolLower := strings.ToLower(querySymbol)
 			if nameLower == querySymbolLower {
 				scored = append(scored, ScoredChunk{
@@ -805,6 +936,9 @@ func (cb *ContextBuilder) exactSymbolSearch(query string) []ScoredChunk {
 		for _, symbol := range chunk.Symbols {
 			symbolLower := strings.ToLower(symbol)
 			for _, querySymbol := range potentialSymbols {
+				if isCodeKeyword(language, querySymbol) {
+					continue
+				}
 				querySymbolLower := strings.ToLower(querySymbol)
 				if symbolLower == querySymbolLower {
 					scored = append(scored, ScoredChunk{
@@ -824,13 +958,14 @@ func (cb *ContextBuilder) exactSymbolSearch(query string) []ScoredChunk {
 
 // Match based on partial identifier components
 func (cb *ContextBuilder) partialIdentifierMatch(query string) []ScoredChunk {
-	queryTokens := extractPotentialSymbols(query)
+	queryTokens := withoutStopWords(extractPotentialSymbols(query))
 	scored := make([]ScoredChunk, 0)
 	matchedChunks := make(map[string]bool)
 
 	for _, chunk := range cb.chunks {
 		chunkNameTokens := splitIdentifierToTokens(chunk.Name)
 		chunkNameLower := strings.ToLower(chunk.Name)
+		language := languageOf(chunk.File)
 
 		matchCount := 0
 		totalScore := 0.0
@@ -838,6 +973,9 @@ func (cb *ContextBuilder) partialIdentifierMatch(query string) []ScoredChunk {
 
 		// Check if any query tokens match chunk name components
 		for _, qToken := range queryTokens {
+			if isCodeKeyword(language, qToken) {
+				continue
+			}
 			qTokenLower := strings.ToLower(qToken)
 
 			// Direct token match
@@ -863,6 +1001,9 @@ func (cb *ContextBuilder) partialIdentifierMatch(query string) []ScoredChunk {
 			symbolLower := strings.ToLower(symbol)
 
 			for _, qToken := range queryTokens {
+				if isCodeKeyword(language, qToken) {
+					continue
+				}
 				qTokenLower := strings.ToLower(qToken)
 
 				for _, sToken := range symbolTokens {
@@ -902,18 +1043,26 @@ func (cb *ContextBuilder) partialIdentifierMatch(query string) []ScoredChunk {
 func (cb *ContextBuilder) keywordSearch(query string, topK int) []ScoredChunk {
 	queryLower := strings.ToLower(query)
 	keywords := extractQueryKeywords(queryLower)
-	potentialSymbols := extractPotentialSymbols(query)
+	potentialSymbols := withoutStopWords(extractPotentialSymbols(query))
+	asksAboutDocs := mentionsDocs(keywords)
+	asksAboutInfra := mentionsInfra(keywords)
+	asksAboutSchema := mentionsSchema(keywords)
 	scored := make([]ScoredChunk, 0)
 
 	for _, chunk := range cb.chunks {
 		score := 0.0
 		contentLower := strings.ToLower(chunk.Content)
 		nameLower := strings.ToLower(chunk.Name)
+		language := languageOf(chunk.File)
 
 		matchDetails := []string{}
 
-		// PRIORITY 1: Name matches
+		// PRIORITY 1: Name matches. A keyword of the chunk's language is
+		// never part of a name there, see stopwords.go.
 		for _, querySymbol := range potentialSymbols {
+			if isCodeKeyword(language, querySymbol) {
+				continue
+			}
 			querySymbolLower := strings.ToLower(querySymbol)
 			if nameLower == querySymbolLower {
 				score += 20.0
@@ -931,6 +1080,9 @@ func (cb *ContextBuilder) keywordSearch(query string, topK int) []ScoredChunk {
 		for _, symbol := range chunk.Symbols {
 			symbolLower := strings.ToLower(symbol)
 			for _, querySymbol := range potentialSymbols {
+				if isCodeKeyword(language, querySymbol) {
+					continue
+				}
 				querySymbolLower := strings.ToLower(querySymbol)
 				if symbolLower == querySymbolLower {
 					score += 15.0
@@ -944,6 +1096,9 @@ func (cb *ContextBuilder) keywordSearch(query string, topK int) []ScoredChunk {
 			}
 
 			for _, keyword := range keywords {
+				if isCodeKeyword(language, keyword) {
+					continue
+				}
 				if symbolLower == keyword {
 					score += 10.0
 				} else if strings.Contains(symbolLower, keyword) {
@@ -952,8 +1107,16 @@ func (cb *ContextBuilder) keywordSearch(query string, topK int) []ScoredChunk {
 			}
 		}
 
-		// PRIORITY 3: Content keyword matches
+		// PRIORITY 3: Content keyword matches, a keyword of the chunk's
+		// language only where it is used as one
 		for _, keyword := range keywords {
+			if isCodeKeyword(language, keyword) {
+				if containsWord(contentLower, keyword) {
+					score += 0.5
+					matchDetails = append(matchDetails, fmt.Sprintf("syntax=%s", keyword))
+				}
+				continue
+			}
 			if strings.Contains(contentLower, keyword) {
 				score += 2.0
 				matchDetails = append(matchDetails, fmt.Sprintf("keyword=%s", keyword))
@@ -963,7 +1126,7 @@ func (cb *ContextBuilder) keywordSearch(query string, topK int) []ScoredChunk {
 		// PRIORITY 4: File name relevance
 		fileLower := strings.ToLower(chunk.File)
 		for _, keyword := range keywords {
-			if strings.Contains(fileLower, keyword) {
+			if strings.Contains(fileLower, keyword) && !isCodeKeyword(language, keyword) {
 				score += 1.0
 			}
 		}
@@ -976,6 +1139,18 @@ func (cb *ContextBuilder) keywordSearch(query string, topK int) []ScoredChunk {
 			score += 0.8
 		case "method":
 			score += 0.6
+		case "doc":
+			if asksAboutDocs {
+				score += 3.0
+			}
+		case "infra":
+			if asksAboutInfra {
+				score += 3.0
+			}
+		case "schema":
+			if asksAboutSchema {
+				score += 3.0
+			}
 		}
 
 		if score > 0 {
@@ -1004,8 +1179,8 @@ func (cb *ContextBuilder) vectorSearch(queryEmb []float32, topK int, threshold f
 	scored := make([]ScoredChunk, 0)
 
 	for i, chunkEmb := range cb.embeddings {
-		if i >= len(cb.chunks) {
-			break
+		if chunkEmb == nil {
+			continue
 		}
 
 		similarity := cosineSimilarity(queryEmb, chunkEmb)
@@ -1041,8 +1216,23 @@ func (cb *ContextBuilder) selectChunks(scored []ScoredChunk, budget int) []Chunk
 	for _, sc := range scored {
 		chunkTokens := sc.Tokens + headerOverhead
 
+		// What doesn't fit is shortened, see compress.go. Without
+		// compression the rest is dropped.
 		if currentTokens+chunkTokens > budget {
-			break
+			compressed, ok := cb.compress(sc.Chunk, budget-currentTokens-headerOverhead)
+			if !ok {
+				if cb.compression() == CompressOff {
+					break
+				}
+				continue
+			}
+			selected = append(selected, compressed)
+			currentTokens += compressed.Tokens + headerOverhead
+			continue
+		}
+		// The best match goes in whatever its size, see max_chunk_tokens
+		if cb.params.maxChunkTokens > 0 && sc.Tokens > cb.params.maxChunkTokens && len(selected) > 0 {
+			continue
 		}
 
 		if len(selected) > 0 {
@@ -1063,6 +1253,12 @@ func (cb *ContextBuilder) selectChunks(scored []ScoredChunk, budget int) []Chunk
 	return selected
 }
 
+// Warnings describes anything that was missing or mismatched at load and
+// limits search, empty when everything lined up
+func (cb *ContextBuilder) Warnings() []string {
+	return cb.warnings
+}
+
 func (cb *ContextBuilder) Close() error {
 	return nil
 }
@@ -1091,43 +1287,7 @@ func extractSymbolsFromContent(content, name string) []string {
 	return symbols
 }
 
-func calculateImportance(chunkType string, complexity int) float64 {
-	baseScore := 0.5
-	switch chunkType {
-	case "function":
-		baseScore = 0.7
-	case "class":
-		baseScore = 0.8
-	case "method":
-		baseScore = 0.6
-	case "file":
-		baseScore = 0.4
-	}
-
-	if complexity > 5 {
-		baseScore += 0.1
-	}
-
-	if complexity > 10 {
-		baseScore += 0.1
-	}
-
-	if baseScore > 1.0 {
-		baseScore = 1.0
-	}
-
-	return baseScore
-}
-
 func extractQueryKeywords(queryLower string) []string {
-	stopWords := map[string]bool{
-		"how": true, "does": true, "the": true, "a": true, "an": true,
-		"is": true, "are": true, "what": true, "where": true, "when": true,
-		"can": true, "will": true, "should": true, "would": true, "could": true,
-		"this": true, "that": true, "these": true, "those": true, "of": true,
-		"in": true, "on": true, "at": true, "to": true, "for": true, "with": true,
-	}
-
 	words := strings.FieldsFunc(queryLower, func(r rune) bool {
 		return r == ' ' || r == ',' || r == '.' || r == '!' || r == '?' ||
 			r == ';' || r == ':' || r == '(' || r == ')' || r == '[' || r == ']'
@@ -1153,6 +1313,58 @@ func extractQueryKeywords(queryLower string) []string {
 }
 
 
+// docWords in a question point at the docs rather than the code, "what
+// does the design doc say about retries"
+var docWords = map[string]bool{
+	"doc": true, "docs": true, "documentation": true, "documented": true,
+	"readme": true, "design": true, "adr": true, "adrs": true, "rfc": true,
+	"spec": true, "guide": true, "notebook": true, "notes": true,
+}
+
+func mentionsDocs(keywords []string) bool {
+	for _, word := range keywords {
+		if docWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// infraWords point at deployment config, "what env vars does the worker need"
+var infraWords = map[string]bool{
+	"env": true, "environment": true, "vars": true, "variable": true, "variables": true,
+	"deploy": true, "deployment": true, "docker": true, "dockerfile": true,
+	"compose": true, "container": true, "containers": true, "image": true,
+	"port": true, "ports": true, "kubernetes": true, "k8s": true, "helm": true,
+	"terraform": true, "infra": true, "infrastructure": true, "pipeline": true,
+	"workflow": true, "service": true, "services": true,
+}
+
+func mentionsInfra(keywords []string) bool {
+	for _, word := range keywords {
+		if infraWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaWords point at database tables, "what columns does orders have"
+var schemaWords = map[string]bool{
+	"table": true, "tables": true, "column": true, "columns": true,
+	"schema": true, "migration": true, "migrations": true, "database": true,
+	"db": true, "sql": true, "foreign": true, "model": true, "models": true,
+}
+
+func mentionsSchema(keywords []string) bool {
+	for _, word := range keywords {
+		if schemaWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper Management Utilities
 
 // Split identifiers into tokens for matching
@@ -1243,7 +1455,8 @@ func contains(slice []string, item string) bool {
 }
 
 func canMerge(a, b Chunk) bool {
-	if a.File != b.File {
+	// A shortened chunk's content no longer matches its lines
+	if a.File != b.File || a.Compressed != "" || b.Compressed != "" {
 		return false
 	}
 
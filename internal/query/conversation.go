@@ -0,0 +1,141 @@
+package query
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxRecentEntities is how many symbols from earlier turns a follow-up can pick up
+	maxRecentEntities = 3
+	// maxDiscussed is how many symbols the session memory lists
+	maxDiscussed = 12
+)
+
+// Session is what a chat has established so far. The chat client owns it and
+// sends it along with each question, so the daemon stays stateless.
+type Session struct {
+	Recent    []string `json:"recent,omitempty"`    // entities of the last answer, for "it" and "that"
+	Discussed []string `json:"discussed,omitempty"` // symbols covered this session, oldest first
+	Notes     []string `json:"notes,omitempty"`     // added with /remember
+}
+
+// Remember adds a note the assistant should keep in mind for the session
+func (s *Session) Remember(note string) {
+	if note = strings.TrimSpace(note); note != "" {
+		s.Notes = append(s.Notes, note)
+	}
+}
+
+// Track records what an answer was about, its Entities
+func (s *Session) Track(entities []string) {
+	if len(entities) == 0 {
+		return
+	}
+	s.Recent = entities
+
+	for _, entity := range entities {
+		for i, seen := range s.Discussed {
+			if seen == entity {
+				s.Discussed = append(s.Discussed[:i], s.Discussed[i+1:]...)
+				break
+			}
+		}
+		s.Discussed = append(s.Discussed, entity)
+	}
+	if len(s.Discussed) > maxDiscussed {
+		s.Discussed = s.Discussed[len(s.Discussed)-maxDiscussed:]
+	}
+}
+
+// Memory renders the session as a short block for the top of a prompt, or ""
+// when nothing has been established yet
+func (s *Session) Memory() string {
+	if s == nil || len(s.Notes)+len(s.Discussed) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SESSION MEMORY (established earlier in this conversation, build on it instead of explaining it again):\n")
+	for _, note := range s.Notes {
+		sb.WriteString(fmt.Sprintf("- %s\n", note))
+	}
+	if len(s.Discussed) > 0 {
+		sb.WriteString(fmt.Sprintf("- Already discussed: %s\n", strings.Join(s.Discussed, ", ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// withMemory is the cache key of question asked with memory in the prompt
+func withMemory(question, memory string) string {
+	if memory == "" {
+		return question
+	}
+	sum := sha256.Sum256([]byte(memory))
+	return fmt.Sprintf("%s [memory %x]", question, sum[:8])
+}
+
+// A question leans on an earlier turn when it starts like a follow-up,
+// "what about its error handling?", "and the tests?", "it panics when...",
+// or is short and has a pronoun, "does it retry?". "this" and "that"
+// don't count on their own, in "what is this project" they name something
+// new.
+var (
+	followUpStart = regexp.MustCompile(`(?i)^\s*((what|how)\s+about|and|also|it|it's|its|they|their)\b`)
+	followUpWord  = regexp.MustCompile(`(?i)\b(it|its|it's|they|them|their)\b`)
+)
+
+// shortFollowUp is the most words a question may have to count as a
+// follow-up for a pronoun alone
+const shortFollowUp = 6
+
+// refersBack reports whether query leans on an earlier turn instead of
+// naming what it is about
+func refersBack(query string) bool {
+	return followUpStart.MatchString(query) ||
+		len(strings.Fields(query)) <= shortFollowUp && followUpWord.MatchString(query)
+}
+
+// resolveReferences fills in the symbols of a question that only refers to
+// earlier ones. recent is the entity list of the previous answer, newest
+// first. It returns the query to retrieve with, which names the symbols so
+// search can find them, or "" when nothing was resolved.
+func resolveReferences(query string, class *Classification, recent []string) string {
+	if len(recent) == 0 || len(class.Symbols) > 0 || !refersBack(query) {
+		return ""
+	}
+
+	if len(recent) > maxRecentEntities {
+		recent = recent[:maxRecentEntities]
+	}
+	class.Symbols = append([]string(nil), recent...)
+	for _, symbol := range recent {
+		class.Entities = append(class.Entities, Entity{Name: symbol})
+	}
+
+	return query + " " + strings.Join(recent, " ")
+}
+
+// answerEntities are the symbols an answer was about, what the next turn's
+// "it" most likely means
+func answerEntities(query string, class *Classification) []string {
+	if class == nil {
+		return nil
+	}
+	if len(class.Symbols) > 0 {
+		return class.Symbols
+	}
+	if entity := followUpEntity(query, class); entity != "" {
+		return []string{entity}
+	}
+	return nil
+}
+
+// AnswerInSession is Answer for chat: the session's recent entities resolve
+// "it" and "that" in follow-up questions, and its memory goes into the prompt
+func (r *Router) AnswerInSession(query string, session *Session) (*Answer, error) {
+	return r.answer(query, session)
+}
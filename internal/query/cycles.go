@@ -0,0 +1,250 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"eulix/internal/fault"
+)
+
+// Graphs FindCycles can look at
+const (
+	CycleGraphCalls = "calls" // functions, from kb_call_graph.json
+	CycleGraphDeps  = "deps"  // modules and files, the dependency_graph in kb.json
+)
+
+// GraphCycles are the cycles found in one graph
+type GraphCycles struct {
+	Graph  string  `json:"graph"`
+	Nodes  int     `json:"nodes"`
+	Cycles []Cycle `json:"cycles"`
+}
+
+// Cycle is a strongly connected component of more than one node, every
+// member reaches every other one. Loop is the shortest way round from the
+// first member, a place to start cutting.
+type Cycle struct {
+	Members []string `json:"members"`
+	Edges   int      `json:"edges"` // edges between members
+	Loop    []string `json:"loop"`
+}
+
+// kbDependencyGraph is the part of kb.json FindCycles reads
+type kbDependencyGraph struct {
+	DependencyGraph struct {
+		Nodes []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"nodes"`
+		Edges []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"edges"`
+	} `json:"dependency_graph"`
+}
+
+// FindCycles reports the cycles in each of graphs, biggest first. Recursion,
+// a node calling itself, isn't counted.
+func FindCycles(eulixDir string, graphs []string) ([]GraphCycles, error) {
+	var report []GraphCycles
+	for _, name := range graphs {
+		var edges map[string][]string
+		var err error
+		switch name {
+		case CycleGraphCalls:
+			edges, err = callEdges(eulixDir)
+		case CycleGraphDeps:
+			edges, err = dependencyEdges(eulixDir)
+		default:
+			return nil, fmt.Errorf("unknown graph %q, use calls or deps", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, GraphCycles{Graph: name, Nodes: len(edges), Cycles: findCycles(edges)})
+	}
+	return report, nil
+}
+
+// callEdges is the call graph as caller -> callees, calls out of the project
+// left out. called_by fills in calls the callee saw and the caller didn't.
+func callEdges(eulixDir string) (map[string][]string, error) {
+	graph, err := LoadCallGraph(eulixDir)
+	if err != nil {
+		return nil, err
+	}
+	edges := make(map[string][]string, len(graph.Functions))
+	for name, fn := range graph.Functions {
+		edges[name] = append(edges[name], fn.Calls...)
+		for _, caller := range fn.CalledBy {
+			edges[caller] = append(edges[caller], name)
+		}
+	}
+	for name, callees := range edges {
+		if _, ok := graph.Functions[name]; !ok {
+			delete(edges, name)
+			continue
+		}
+		known := callees[:0]
+		for _, callee := range callees {
+			if _, ok := graph.Functions[callee]; ok {
+				known = append(known, callee)
+			}
+		}
+		edges[name] = dedupe(known)
+	}
+	return edges, nil
+}
+
+// dependencyEdges is kb.json's dependency graph as importer -> imported,
+// nodes named by name where they have one
+func dependencyEdges(eulixDir string) (map[string][]string, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w, run 'eulix analyze' first: %w", fault.ErrKBMissing, err)
+	}
+	var kb kbDependencyGraph
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return nil, fmt.Errorf("failed to parse kb.json: %w", err)
+	}
+
+	names := make(map[string]string)
+	edges := make(map[string][]string)
+	for _, n := range kb.DependencyGraph.Nodes {
+		names[n.ID] = n.ID
+		if n.Name != "" {
+			names[n.ID] = n.Name
+		}
+		edges[names[n.ID]] = nil
+	}
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return id
+	}
+	for _, e := range kb.DependencyGraph.Edges {
+		from := name(e.From)
+		edges[from] = append(edges[from], name(e.To))
+	}
+	for from, to := range edges {
+		edges[from] = dedupe(to)
+	}
+	return edges, nil
+}
+
+// findCycles finds the strongly connected components with Tarjan's
+// algorithm and keeps the ones with more than one node
+func findCycles(edges map[string][]string) []Cycle {
+	nodes := make([]string, 0, len(edges))
+	for n := range edges {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	cycles := []Cycle{}
+
+	var visit func(n string)
+	visit = func(n string) {
+		index[n] = len(index)
+		low[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, m := range edges[n] {
+			if _, seen := index[m]; !seen {
+				visit(m)
+				low[n] = min(low[n], low[m])
+			} else if onStack[m] {
+				low[n] = min(low[n], index[m])
+			}
+		}
+
+		if low[n] != index[n] {
+			return
+		}
+		var members []string
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m] = false
+			members = append(members, m)
+			if m == n {
+				break
+			}
+		}
+		if len(members) > 1 {
+			cycles = append(cycles, newCycle(members, edges))
+		}
+	}
+	for _, n := range nodes {
+		if _, seen := index[n]; !seen {
+			visit(n)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		if len(cycles[i].Members) != len(cycles[j].Members) {
+			return len(cycles[i].Members) > len(cycles[j].Members)
+		}
+		return cycles[i].Members[0] < cycles[j].Members[0]
+	})
+	return cycles
+}
+
+func newCycle(members []string, edges map[string][]string) Cycle {
+	sort.Strings(members)
+	in := make(map[string]bool, len(members))
+	for _, m := range members {
+		in[m] = true
+	}
+	c := Cycle{Members: members}
+	for _, m := range members {
+		for _, to := range edges[m] {
+			if in[to] {
+				c.Edges++
+			}
+		}
+	}
+	c.Loop = shortestLoop(members[0], in, edges)
+	return c
+}
+
+// shortestLoop is a breadth first search inside the component from start
+// back to itself, start at both ends
+func shortestLoop(start string, in map[string]bool, edges map[string][]string) []string {
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, m := range edges[n] {
+			if !in[m] {
+				continue
+			}
+			if m == start {
+				loop := []string{start}
+				for at := n; at != start; at = prev[at] {
+					loop = append(loop, at)
+				}
+				loop = append(loop, start)
+				for i, j := 1, len(loop)-2; i < j; i, j = i+1, j-1 {
+					loop[i], loop[j] = loop[j], loop[i]
+				}
+				return loop
+			}
+			if _, seen := prev[m]; !seen {
+				prev[m] = n
+				queue = append(queue, m)
+			}
+		}
+	}
+	return nil
+}
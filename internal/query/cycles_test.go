@@ -0,0 +1,86 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindCyclesInFixtureGraph(t *testing.T) {
+	edges := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a", "d"},
+		"d": {"e"},
+		"e": {"d"},
+		"f": {"f"}, // recursion isn't a cycle
+		"g": nil,
+	}
+
+	got := findCycles(edges)
+	want := []Cycle{
+		{Members: []string{"a", "b", "c"}, Edges: 3, Loop: []string{"a", "b", "c", "a"}},
+		{Members: []string{"d", "e"}, Edges: 2, Loop: []string{"d", "e", "d"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findCycles = %+v, want %+v", got, want)
+	}
+
+	if got := findCycles(map[string][]string{"a": {"b"}, "b": {"c"}, "c": nil}); len(got) != 0 {
+		t.Errorf("acyclic graph has cycles %+v", got)
+	}
+}
+
+func TestFindCyclesShortestLoop(t *testing.T) {
+	// a reaches back to itself through b alone or the long way round
+	edges := map[string][]string{
+		"a": {"c", "b"},
+		"b": {"a"},
+		"c": {"d"},
+		"d": {"a"},
+	}
+	got := findCycles(edges)
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Loop, []string{"a", "b", "a"}) {
+		t.Errorf("findCycles = %+v, want one cycle looping a → b → a", got)
+	}
+}
+
+func TestFindCyclesFromKB(t *testing.T) {
+	eulixDir := t.TempDir()
+	// b calls a only on a's side, fmt.Println is outside the project
+	callGraph := `{"functions": {
+		"a": {"calls": ["b", "fmt.Println"], "called_by": ["b"]},
+		"b": {"calls": [], "called_by": ["a"]},
+		"c": {"calls": ["a"], "called_by": []}
+	}}`
+	kb := `{"dependency_graph": {
+		"nodes": [{"id": "m1", "name": "pkg/api"}, {"id": "m2", "name": "pkg/store"}, {"id": "m3"}],
+		"edges": [{"from": "m1", "to": "m2"}, {"from": "m1", "to": "m2"}, {"from": "m2", "to": "m1"}, {"from": "m2", "to": "m3"}]
+	}}`
+	for name, content := range map[string]string{"kb_call_graph.json": callGraph, "kb.json": kb} {
+		if err := os.WriteFile(filepath.Join(eulixDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := FindCycles(eulixDir, []string{CycleGraphCalls, CycleGraphDeps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GraphCycles{
+		{Graph: CycleGraphCalls, Nodes: 3, Cycles: []Cycle{
+			{Members: []string{"a", "b"}, Edges: 2, Loop: []string{"a", "b", "a"}},
+		}},
+		{Graph: CycleGraphDeps, Nodes: 3, Cycles: []Cycle{
+			{Members: []string{"pkg/api", "pkg/store"}, Edges: 2, Loop: []string{"pkg/api", "pkg/store", "pkg/api"}},
+		}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("FindCycles = %+v, want %+v", report, want)
+	}
+
+	if _, err := FindCycles(eulixDir, []string{"imports"}); err == nil {
+		t.Error("unknown graph was accepted")
+	}
+}
@@ -0,0 +1,143 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"eulix/internal/types"
+)
+
+// maxSubQueries caps how many retrievals one question can fan out into
+const maxSubQueries = 4
+
+var (
+	// "how does a request flow from the HTTP layer to the database"
+	flowPattern = regexp.MustCompile(`(?i)\bfrom\s+(?:the\s+)?(.+?)\s+(?:to|into|through|down\s+to)\s+(?:the\s+)?(.+?)[?.!]*$`)
+
+	// "where is X parsed and how is it stored", split before the second question word
+	conjoinedPattern = regexp.MustCompile(`(?i)(?:,\s*|\s+)(?:and|then|and\s+then)\s+(?:(?:how|what|where|why|which|who|when)\b)`)
+)
+
+// decompose splits a question that spans several parts of the code into
+// sub-queries, each retrieved on its own. It returns nil when the question is
+// simple enough for one retrieval. Lookups and comparisons are left alone,
+// their handlers already deal with several symbols, so are questions about
+// whole files, and so is anything the classifier says needs no context.
+func decompose(query string, class *Classification) []string {
+	if !class.NeedsContext {
+		return nil
+	}
+	switch class.Type {
+	case QueryTypeLocation, QueryTypeUsage, QueryTypeDependency, QueryTypeComparison, QueryTypeSchema, QueryTypeFile:
+		return nil
+	}
+
+	query = strings.TrimSpace(query)
+	var parts []string
+
+	switch {
+	case flowPattern.MatchString(query):
+		m := flowPattern.FindStringSubmatch(query)
+		parts = []string{m[1], m[2]}
+		// Whatever sits between the two ends is named by the symbols, if any
+		for _, symbol := range class.Symbols {
+			if !containsFold(m[1], symbol) && !containsFold(m[2], symbol) {
+				parts = append(parts, symbol)
+			}
+		}
+
+	case strings.Count(query, "?") > 1:
+		for _, q := range strings.Split(query, "?") {
+			parts = append(parts, q)
+		}
+
+	case conjoinedPattern.MatchString(query):
+		parts = splitConjoined(query)
+
+	case len(class.Symbols) > 1:
+		parts = append(parts, class.Symbols...)
+	}
+
+	var subQueries []string
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		part = strings.TrimSpace(strings.Trim(part, " ,;.?!"))
+		key := strings.ToLower(part)
+		if part == "" || seen[key] || strings.EqualFold(part, query) {
+			continue
+		}
+		seen[key] = true
+		subQueries = append(subQueries, part)
+		if len(subQueries) == maxSubQueries {
+			break
+		}
+	}
+
+	if len(subQueries) < 2 {
+		return nil
+	}
+	return subQueries
+}
+
+// splitConjoined cuts before each "and how/what/..." keeping the question word
+func splitConjoined(query string) []string {
+	var parts []string
+	rest := query
+	for {
+		loc := conjoinedPattern.FindStringIndex(rest)
+		if loc == nil {
+			break
+		}
+		// loc[1] is just past the question word, step back to its start
+		word := strings.LastIndexAny(rest[:loc[1]], " ,") + 1
+		parts = append(parts, rest[:loc[0]])
+		rest = rest[word:]
+	}
+	return append(parts, rest)
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// handleDecomposed retrieves once for the whole question and once per
+// sub-query, then asks for a single answer that covers every part
+func (r *Router) handleDecomposed(query string, class *Classification, subQueries []string) (string, error) {
+	context, err := r.mergedContext(query, subQueries)
+	if err != nil {
+		return "", fmt.Errorf("failed to build context: %w", err)
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(r.buildAntiHallucinationPrompt(query, class, context))
+	prompt.WriteString("\nThis question spans several parts of the code. The context was retrieved for each of these:\n")
+	for i, sub := range subQueries {
+		prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, sub))
+	}
+	prompt.WriteString("\nCover each part, then connect them into one answer to the question as a whole. ")
+	prompt.WriteString("Say where the context leaves a gap between two parts instead of guessing how they connect.\n")
+
+	response, err := r.ask(context, prompt.String())
+	if err != nil {
+		return "", fmt.Errorf("LLM query failed: %w", err)
+	}
+
+	return response, nil
+}
+
+// mergedContext is buildContext for a decomposed question
+func (r *Router) mergedContext(query string, subQueries []string) (*types.ContextWindow, error) {
+	queries := append([]string{query}, subQueries...)
+	window, err := r.retrieved(strings.Join(queries, "\n"), func() (*types.ContextWindow, error) {
+		r.report(StageRetrieving, fmt.Sprintf("for %d parts", len(subQueries)))
+		return r.contextBuilder.BuildMergedContext(queries)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.report(StageRetrieving, retrievedChunks(len(window.Chunks)))
+	r.signals.retrieval = window.Retrieval
+	r.signals.sources = window.Sources
+	return window, nil
+}
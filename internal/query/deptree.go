@@ -0,0 +1,246 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"eulix/internal/config"
+)
+
+// maxDependencyDepth caps --depth, past it the tree is the whole program
+const maxDependencyDepth = 10
+
+// Dependency directions
+const (
+	DirectionDependencies = "dependencies" // what it calls
+	DirectionDependents   = "dependents"   // what calls it
+	DirectionBoth         = "both"
+)
+
+// DependencyOptions are how far and which way to follow calls
+type DependencyOptions struct {
+	Depth        int
+	Direction    string
+	IncludeTypes bool
+}
+
+// DependencyDefaults fills in what [dependencies] leaves out and checks the
+// rest
+func DependencyDefaults(cfg config.DependenciesConfig) (DependencyOptions, error) {
+	opts := DependencyOptions{Depth: cfg.Depth, Direction: cfg.Direction, IncludeTypes: cfg.IncludeTypes}
+	if opts.Depth <= 0 {
+		opts.Depth = 3
+	}
+	if opts.Depth > maxDependencyDepth {
+		opts.Depth = maxDependencyDepth
+	}
+	switch opts.Direction {
+	case "":
+		opts.Direction = DirectionBoth
+	case DirectionDependencies, DirectionDependents, DirectionBoth:
+	default:
+		return opts, fmt.Errorf("unknown direction %q, use dependencies, dependents or both", opts.Direction)
+	}
+	return opts, nil
+}
+
+// DependencyTree is what a symbol calls and what calls it, as far as the
+// options go. A branch is nil when its direction wasn't asked for.
+type DependencyTree struct {
+	Root         *DependencyNode `json:"root"`
+	Dependencies *DependencyNode `json:"dependencies,omitempty"`
+	Dependents   *DependencyNode `json:"dependents,omitempty"`
+}
+
+// DependencyNode is a function in the tree. A function reached again is
+// listed with Repeat set and not expanded a second time.
+type DependencyNode struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type,omitempty"` // the type it is a method of, with IncludeTypes
+	Location string            `json:"location,omitempty"`
+	Repeat   bool              `json:"repeat,omitempty"`
+	Children []*DependencyNode `json:"children,omitempty"`
+}
+
+// LoadCallGraph reads kb_call_graph.json
+func LoadCallGraph(eulixDir string) (*CallGraph, error) {
+	graph, err := loadCallGraph(eulixDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kb_call_graph.json, run 'eulix analyze' first: %w", err)
+	}
+	return graph, nil
+}
+
+// Dependencies builds the tree for a function, or for a type when
+// IncludeTypes is set, from its methods
+func (g *CallGraph) Dependencies(entity string, opts DependencyOptions) (*DependencyTree, error) {
+	owners := make(map[string]string)
+	if opts.IncludeTypes {
+		for name, t := range g.Types {
+			for _, m := range t.Methods {
+				owners[m] = name
+			}
+		}
+	}
+
+	var starts []string
+	root := &DependencyNode{Name: entity}
+	if fn, ok := g.resolveFunction(entity); ok {
+		entity = fn.Key
+		root.Location = fn.Node.Location
+		root.Type = owners[entity]
+		if fn.Type != "" {
+			root.Type = fn.Type
+		}
+		starts = []string{entity}
+	} else if t, ok := g.Types[entity]; ok && opts.IncludeTypes {
+		root.Location = t.Location
+		starts = t.Methods
+	} else if ok {
+		return nil, fmt.Errorf("%s is a type, use include types to follow its methods", entity)
+	} else {
+		return nil, fmt.Errorf("%s is not in the call graph", entity)
+	}
+
+	tree := &DependencyTree{Root: root}
+	walk := func(next func(FunctionNode) []string) *DependencyNode {
+		branch := &DependencyNode{Name: root.Name, Type: root.Type, Location: root.Location}
+		seen := map[string]bool{entity: true}
+		var children []string
+		for _, s := range starts {
+			seen[s] = true
+			children = append(children, next(g.Functions[s])...)
+		}
+		branch.Children = g.expand(dedupe(children), 1, opts.Depth, seen, owners, next)
+		return branch
+	}
+	if opts.Direction != DirectionDependents {
+		tree.Dependencies = walk(func(f FunctionNode) []string { return f.Calls })
+	}
+	if opts.Direction != DirectionDependencies {
+		tree.Dependents = walk(func(f FunctionNode) []string { return f.CalledBy })
+	}
+	return tree, nil
+}
+
+func (g *CallGraph) expand(names []string, level, depth int, seen map[string]bool, owners map[string]string, next func(FunctionNode) []string) []*DependencyNode {
+	var nodes []*DependencyNode
+	for _, name := range names {
+		fn := g.Functions[name]
+		node := &DependencyNode{Name: name, Type: owners[name], Location: fn.Location}
+		if seen[name] {
+			node.Repeat = true
+		} else {
+			seen[name] = true
+			if level < depth {
+				node.Children = g.expand(dedupe(next(fn)), level+1, depth, seen, owners, next)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// dedupe drops repeats and sorts, so trees come out the same every run
+func dedupe(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, n := range names {
+		if n != "" && !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (n *DependencyNode) label() string {
+	if n.Type != "" && !strings.HasPrefix(n.Name, n.Type+".") {
+		return n.Type + "." + n.Name
+	}
+	return n.Name
+}
+
+// Tree renders the tree indented like the tree command, one section per
+// direction
+func (t *DependencyTree) Tree() string {
+	var sb strings.Builder
+	sb.WriteString(t.Root.label())
+	if t.Root.Location != "" {
+		sb.WriteString(" (" + t.Root.Location + ")")
+	}
+	sb.WriteString("\n")
+
+	section := func(title string, branch *DependencyNode) {
+		if branch == nil {
+			return
+		}
+		sb.WriteString("\n" + title + ":\n")
+		if len(branch.Children) == 0 {
+			sb.WriteString("  none\n")
+			return
+		}
+		writeBranch(&sb, branch.Children, "  ")
+	}
+	section("Dependencies (what it calls)", t.Dependencies)
+	section("Dependents (what calls it)", t.Dependents)
+	return sb.String()
+}
+
+func writeBranch(sb *strings.Builder, nodes []*DependencyNode, indent string) {
+	for i, n := range nodes {
+		branch, child := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, child = "└── ", "    "
+		}
+		sb.WriteString(indent + branch + n.label())
+		if n.Location != "" {
+			sb.WriteString(" (" + n.Location + ")")
+		}
+		if n.Repeat {
+			sb.WriteString(" (see above)")
+		}
+		sb.WriteString("\n")
+		writeBranch(sb, n.Children, indent+child)
+	}
+}
+
+// DOT renders the tree as a Graphviz digraph, edges point from caller to
+// callee in both directions
+func (t *DependencyTree) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph dependencies {\n  rankdir=LR;\n  node [shape=box];\n")
+	fmt.Fprintf(&sb, "  %q [style=bold];\n", t.Root.label())
+
+	edges := make(map[string]bool)
+	var lines []string
+	var walk func(parent *DependencyNode, nodes []*DependencyNode, down bool)
+	walk = func(parent *DependencyNode, nodes []*DependencyNode, down bool) {
+		for _, n := range nodes {
+			from, to := parent.label(), n.label()
+			if !down {
+				from, to = to, from
+			}
+			line := fmt.Sprintf("  %q -> %q;", from, to)
+			if !edges[line] {
+				edges[line] = true
+				lines = append(lines, line)
+			}
+			walk(n, n.Children, down)
+		}
+	}
+	if t.Dependencies != nil {
+		walk(t.Root, t.Dependencies.Children, true)
+	}
+	if t.Dependents != nil {
+		walk(t.Root, t.Dependents.Children, false)
+	}
+	sb.WriteString(strings.Join(lines, "\n"))
+	if len(lines) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
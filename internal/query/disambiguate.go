@@ -0,0 +1,93 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A symbol defined in several places, two ParseConfig in two packages,
+// would get context for all of them mixed together. The TUI asks which one
+// is meant before retrieving, with Disambiguate on and an AmbiguousError
+// back; everything else answers about all of them and says so.
+
+// AmbiguousError is returned before any context is built when a question
+// names a symbol defined in several places and the caller asked to choose.
+// Pick one with Router.Pick and ask again.
+type AmbiguousError struct {
+	Symbol    string   `json:"symbol"`
+	Locations []string `json:"locations"`
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%s is defined in %d places", e.Symbol, len(e.Locations))
+}
+
+// Disambiguate makes questions naming a symbol defined in several places
+// fail with an AmbiguousError, instead of being answered about all of them
+func (r *Router) Disambiguate(on bool) {
+	r.disambiguate = on
+}
+
+// Pick makes the next question about symbol mean its definition at
+// location, one of AmbiguousError.Locations. An empty location means all
+// of them.
+func (r *Router) Pick(symbol, location string) {
+	if r.picks == nil {
+		r.picks = make(map[string]string)
+	}
+	r.picks[symbol] = location
+}
+
+// symbolLocations is every definition of symbol in the index
+func (r *Router) symbolLocations(symbol string) []string {
+	match, _ := r.kbIndex.Lookup(symbol)
+	return match.Locations()
+}
+
+// disambiguated checks the symbols of a question that needs context. A
+// picked definition is passed on to the model; an ambiguous symbol is
+// either returned as an AmbiguousError or answered about in full, with a
+// warning listing where it is defined.
+func (r *Router) disambiguated(class *Classification) error {
+	if !class.NeedsContext {
+		return nil
+	}
+	for _, symbol := range class.Symbols {
+		locations := r.symbolLocations(symbol)
+		if len(locations) < 2 {
+			continue
+		}
+		location, picked := r.signals.picks[symbol]
+		switch {
+		case picked && location != "":
+			r.signals.memory += fmt.Sprintf("NOTE: %s here means the one defined at %s, not the others of that name.\n\n", symbol, location)
+		case !picked && r.disambiguate:
+			return &AmbiguousError{Symbol: symbol, Locations: locations}
+		default:
+			r.signals.memory += fmt.Sprintf("NOTE: %s is defined in %d places (%s). Say which one each part of the answer is about.\n\n",
+				symbol, len(locations), strings.Join(locations, ", "))
+			r.signals.warnings = append(r.signals.warnings,
+				fmt.Sprintf("%s is defined in %d places, the answer covers all of them: %s", symbol, len(locations), strings.Join(locations, ", ")))
+		}
+	}
+	return nil
+}
+
+// pickedOther reports whether the definition of symbol in file spanning
+// start to end is not the one the user picked
+func (cb *ContextBuilder) pickedOther(symbol, file string, start, end int) bool {
+	location := cb.picks[symbol]
+	if location == "" {
+		return false
+	}
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return false
+	}
+	line, err := strconv.Atoi(strings.SplitN(location[i+1:], "-", 2)[0])
+	if err != nil {
+		return false
+	}
+	return location[:i] != file || line < start || line > end
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"math"
+	"strings"
+)
+
+// When an answer says the context didn't have what it needed, the same
+// question can be asked again with wider retrieval: every level doubles the
+// candidates, lowers the similarity semantic matches need and gives the
+// context more of the token budget.
+
+// MaxExpansion is the widest retrieval Expand goes to
+const MaxExpansion = 3
+
+// The base values are the defaults [retrieval] and Tune change, expansion
+// steps from whatever they were tuned to
+const (
+	baseCandidates  = 100
+	baseSimilarity  = 0.5
+	minSimilarity   = 0.2
+	similarityStep  = 0.1
+	baseBudgetShare = 0.85
+	maxBudgetShare  = 0.95
+	budgetShareStep = 0.05
+)
+
+// missingContextPhrases are how answers say the context fell short, the
+// first is what the anti-hallucination prompt asks for
+var missingContextPhrases = []string{
+	"not available in the current context",
+	"not available in the provided context",
+	"not in the provided context",
+	"not in the current context",
+	"not included in the context",
+	"context does not contain",
+	"context doesn't contain",
+	"context does not include",
+	"context doesn't include",
+	"context does not show",
+	"context doesn't show",
+}
+
+// Expand makes the next question retrieve wider, level 1 to MaxExpansion.
+// The cached answer is skipped and replaced, it is the one that fell short.
+func (r *Router) Expand(level int) {
+	r.expansion = max(0, min(level, MaxExpansion))
+}
+
+// missingContext reports whether an answer says the context lacked what the
+// question needed
+func missingContext(text string) bool {
+	lower := strings.ToLower(text)
+	for _, phrase := range missingContextPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetExpansion widens retrieval by level, 0 is the default
+func (cb *ContextBuilder) SetExpansion(level int) {
+	cb.expansion = max(0, min(level, MaxExpansion))
+}
+
+// candidates is how many chunks a search keeps
+func (cb *ContextBuilder) candidates() int {
+	return cb.params.topK << cb.expansion
+}
+
+// similarity is the least cosine similarity a semantic match needs
+func (cb *ContextBuilder) similarity() float64 {
+	return math.Max(cb.params.similarity-similarityStep*float64(cb.expansion), math.Min(minSimilarity, cb.params.similarity))
+}
+
+// budgetShare is the part of the tokens left after the prompt and response
+// that goes to context
+func (cb *ContextBuilder) budgetShare() float64 {
+	return math.Min(cb.params.budgetShare+budgetShareStep*float64(cb.expansion), math.Max(maxBudgetShare, cb.params.budgetShare))
+}
@@ -0,0 +1,384 @@
+package query
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eulix/internal/types"
+)
+
+// A question about a whole file, "explain internal/cache/manager.go", skips
+// search: every chunk of the file goes in, in line order and merged where
+// they touch. A file too large for the budget keeps its most important
+// chunks whole, still in order, shortens the rest (see compress.go) and
+// the answer says what was left out. A line range, orders.go:120-180 or
+// orders.go#L120-L180 as pasted from a review, narrows that to the chunks
+// covering those lines.
+
+// filePathPattern matches a path or file name with a source extension, and
+// the line range after it
+var filePathPattern = regexp.MustCompile(`(?:^|[\s"'` + "`" + `(])((?:[\w.-]+/)*[\w-]+\.(?:go|py|pyi|js|jsx|mjs|ts|tsx|rs|c|h|cc|cpp|hpp|java|kt|rb|php|cs|swift|scala|sh|sql|proto))\b(?::(\d+)(?:-(\d+))?|#L(\d+)(?:-L?(\d+))?)?`)
+
+// FileRef is a file named in a question, with the lines asked about when
+// it gives a range. Start is 0 for the whole file.
+type FileRef struct {
+	Path  string
+	Start int
+	End   int
+}
+
+func (f FileRef) String() string {
+	switch {
+	case f.Start == 0:
+		return f.Path
+	case f.Start == f.End:
+		return fmt.Sprintf("%s:%d", f.Path, f.Start)
+	}
+	return fmt.Sprintf("%s:%d-%d", f.Path, f.Start, f.End)
+}
+
+// covers reports whether the chunk from start to end is in f
+func (f FileRef) covers(start, end int) bool {
+	return f.Start == 0 || (start <= f.End && end >= f.Start)
+}
+
+// ParseFileRef reads "path", "path:120", "path:120-180" or "path#L120-L180"
+func ParseFileRef(s string) (FileRef, error) {
+	refs := extractFiles(s)
+	if len(refs) != 1 || !onlyFiles(s) {
+		return FileRef{}, fmt.Errorf("%q is not a file or file:start-end", s)
+	}
+	ref := refs[0]
+	if ref.Start > ref.End {
+		return FileRef{}, fmt.Errorf("%s: range ends before it starts", s)
+	}
+	return ref, nil
+}
+
+// extractFiles returns the files named in query, in order
+func extractFiles(query string) []FileRef {
+	var files []FileRef
+	for _, m := range filePathPattern.FindAllStringSubmatch(query, -1) {
+		ref := FileRef{Path: strings.TrimPrefix(m[1], "./")}
+		start, end := m[2], m[3]
+		if start == "" {
+			start, end = m[4], m[5]
+		}
+		if start != "" {
+			ref.Start, _ = strconv.Atoi(start)
+			ref.End = ref.Start
+			if end != "" {
+				ref.End, _ = strconv.Atoi(end)
+			}
+		}
+		if !containsFileRef(files, ref) {
+			files = append(files, ref)
+		}
+	}
+	return files
+}
+
+func containsFileRef(files []FileRef, ref FileRef) bool {
+	for _, f := range files {
+		if f == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRange reports whether any of files gives lines
+func hasRange(files []FileRef) bool {
+	for _, f := range files {
+		if f.Start > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyFiles reports whether query is nothing but paths, like "main.go"
+func onlyFiles(query string) bool {
+	rest := filePathPattern.ReplaceAllString(query, " ")
+	return strings.Trim(rest, " \t\n.,;:?!\"'`()./") == ""
+}
+
+// Files lists every file that has chunks, sorted
+func (cb *ContextBuilder) Files() []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, c := range cb.chunks {
+		if !seen[c.File] {
+			seen[c.File] = true
+			files = append(files, c.File)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// ResolveFile matches a path from a question against the chunked files,
+// exactly or else as a suffix at a directory boundary, so "manager.go"
+// finds internal/cache/manager.go. A name in several directories returns
+// them all.
+func (cb *ContextBuilder) ResolveFile(path string) []string {
+	path = strings.Trim(filepath.ToSlash(filepath.Clean(path)), "/")
+	var matches []string
+	for _, file := range cb.Files() {
+		if file == path {
+			return []string{file}
+		}
+		if strings.HasSuffix(file, "/"+path) {
+			matches = append(matches, file)
+		}
+	}
+	return matches
+}
+
+// FileLines is the last line of path that has a chunk
+func (cb *ContextBuilder) FileLines(path string) int {
+	last := 0
+	for _, c := range cb.chunks {
+		if c.File == path && c.EndLine > last {
+			last = c.EndLine
+		}
+	}
+	return last
+}
+
+// fileChunks is every chunk in files, by file in the order given and then
+// by line. A whole file keeps a class chunk over the methods inside it, a
+// line range keeps the methods, the smallest chunks covering the lines.
+func (cb *ContextBuilder) fileChunks(files []FileRef) []Chunk {
+	order := make(map[string]int, len(files))
+	for i, f := range files {
+		order[f.Path] = i
+	}
+
+	var chunks []Chunk
+	for _, c := range cb.chunks {
+		if i, ok := order[c.File]; ok && files[i].covers(c.StartLine, c.EndLine) {
+			chunks = append(chunks, c)
+		}
+	}
+	sortFileChunks(chunks, order)
+
+	contains := func(a, b Chunk) bool {
+		return a.File == b.File && b.StartLine >= a.StartLine && b.EndLine <= a.EndLine
+	}
+	kept := chunks[:0]
+	for i, c := range chunks {
+		if files[order[c.File]].Start == 0 {
+			if n := len(kept); n > 0 && contains(kept[n-1], c) {
+				continue
+			}
+		} else if i+1 < len(chunks) && contains(c, chunks[i+1]) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+func sortFileChunks(chunks []Chunk, order map[string]int) {
+	sort.SliceStable(chunks, func(i, j int) bool {
+		a, b := chunks[i], chunks[j]
+		if a.File != b.File {
+			return order[a.File] < order[b.File]
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.EndLine > b.EndLine
+	})
+}
+
+// BuildFileContext is every chunk of files in order, cut to the budget by
+// importance. It also returns how many chunks didn't fit.
+func (cb *ContextBuilder) BuildFileContext(query string, files []FileRef) (*types.ContextWindow, int, error) {
+	chunks := cb.fileChunks(files)
+	if len(chunks) == 0 {
+		names := make([]string, len(files))
+		for i, f := range files {
+			names[i] = f.String()
+		}
+		return nil, 0, fmt.Errorf("no chunks for %s", strings.Join(names, ", "))
+	}
+	order := make(map[string]int, len(files))
+	for i, f := range files {
+		order[f.Path] = i
+	}
+
+	for i, c := range chunks {
+		chunks[i] = cb.scoped(c)
+	}
+
+	headerOverhead := 20
+	budget := cb.tokenBudget(query)
+	total := 0
+	for _, c := range chunks {
+		total += c.Tokens + headerOverhead
+	}
+
+	dropped := 0
+	if total > budget {
+		ranked := append([]Chunk(nil), chunks...)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return ranked[i].Importance > ranked[j].Importance
+		})
+		var kept []Chunk
+		used := 0
+		for _, c := range ranked {
+			if used+c.Tokens+headerOverhead > budget {
+				compressed, ok := cb.compress(c, budget-used-headerOverhead)
+				if !ok {
+					dropped++
+					continue
+				}
+				c = compressed
+			}
+			kept = append(kept, c)
+			used += c.Tokens + headerOverhead
+		}
+		chunks = kept
+		sortFileChunks(chunks, order)
+	}
+
+	var merged []Chunk
+	for _, c := range chunks {
+		if n := len(merged); n > 0 && canMerge(merged[n-1], c) {
+			merged[n-1] = mergeChunks(merged[n-1], c)
+			continue
+		}
+		merged = append(merged, c)
+	}
+
+	window := cb.assembleContext(merged)
+	window.Sources = nil
+	for _, f := range files {
+		if !containsString(window.Sources, f.Path) {
+			window.Sources = append(window.Sources, f.Path)
+		}
+	}
+	window.Retrieval = 1
+	return window, dropped, nil
+}
+
+// handleFile explains the files a question names from all of their chunks,
+// or the lines it gives. A file that isn't in the KB, a name that matches
+// several, or lines no chunk covers are answered without calling the model.
+func (r *Router) handleFile(query string, class *Classification) (string, error) {
+	if err := r.ensureContextBuilder(); err != nil {
+		return "", err
+	}
+	files, unresolved := r.resolveFiles(class)
+	if unresolved != "" {
+		return unresolved, nil
+	}
+
+	context, dropped, err := r.fileContext(query, files)
+	if err != nil {
+		return "", fmt.Errorf("failed to build context: %w", err)
+	}
+	r.report(StageRetrieving, retrievedChunks(len(context.Chunks)))
+	r.signals.retrieval = context.Retrieval
+	r.signals.sources = context.Sources
+	if warning := compressedWarning(context); warning != "" {
+		r.signals.warnings = append(r.signals.warnings, warning)
+	}
+	if dropped > 0 {
+		r.signals.warnings = append(r.signals.warnings,
+			fmt.Sprintf("%d chunks of %s didn't fit the context budget, the least important were left out", dropped, fileRefList(files)))
+	}
+
+	return r.ask(context, buildFilePrompt(query, files))
+}
+
+// resolveFiles finds the KB files class names, or says why it can't
+func (r *Router) resolveFiles(class *Classification) ([]FileRef, string) {
+	var files []FileRef
+	for _, ref := range class.Files {
+		matches := r.contextBuilder.ResolveFile(ref.Path)
+		switch {
+		case len(matches) == 0:
+			r.signals.retrieval = 0
+			return nil, fmt.Sprintf("%s is not in the knowledge base. Was it excluded by .euignore, or added since the last analyze?", ref.Path)
+		case len(matches) > 1:
+			r.signals.retrieval = 0.5
+			return nil, fmt.Sprintf("%s matches several files, which one?\n  %s", ref.Path, strings.Join(matches, "\n  "))
+		}
+		ref.Path = matches[0]
+
+		if ref.Start > 0 {
+			if last := r.contextBuilder.FileLines(ref.Path); ref.Start > last {
+				r.signals.retrieval = 0
+				return nil, fmt.Sprintf("%s ends at line %d in the knowledge base, run 'eulix analyze' if it has grown since", ref.Path, last)
+			}
+			if len(r.contextBuilder.fileChunks([]FileRef{ref})) == 0 {
+				r.signals.retrieval = 0
+				return nil, fmt.Sprintf("No chunk covers %s, those lines hold no function or type", ref)
+			}
+		}
+		if !containsFileRef(files, ref) {
+			files = append(files, ref)
+		}
+	}
+	return files, ""
+}
+
+// fileContext is BuildFileContext through retrieved, so a cached answer
+// can be checked against the file's current chunks
+func (r *Router) fileContext(query string, files []FileRef) (*types.ContextWindow, int, error) {
+	dropped := 0
+	window, err := r.retrieved("file:"+fileRefList(files), func() (*types.ContextWindow, error) {
+		r.report(StageRetrieving, "")
+		window, n, err := r.contextBuilder.BuildFileContext(query, files)
+		dropped = n
+		return window, err
+	})
+	return window, dropped, err
+}
+
+func fileRefList(files []FileRef) string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func buildFilePrompt(query string, files []FileRef) string {
+	if hasRange(files) {
+		return fmt.Sprintf(`You are explaining specific lines of source code, as if for a code review. The context holds the chunks covering them.
+
+LINES: %s
+
+QUESTION: %s
+
+Explain:
+1. What these lines do, step by step
+2. Why they are written this way, where the surrounding code shows it
+3. Anything a reviewer should check: edge cases, error handling, assumptions
+
+Keep to the lines asked about, use the rest of the context only to explain them.
+`, fileRefList(files), query)
+	}
+
+	return fmt.Sprintf(`You are explaining source files as a whole. The context holds all of their code, in order.
+
+FILES: %s
+
+QUESTION: %s
+
+Explain:
+1. What the file is for, in a sentence or two
+2. Its main types and functions, in the order they appear, and what each does
+3. How they work together, and what the file depends on or is used by where the code shows it
+
+Only describe what is in the context. Where lines are missing between chunks, say so rather than guessing what they hold.
+`, fileRefList(files), query)
+}
@@ -0,0 +1,85 @@
+package query
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"eulix/internal/chunkstore"
+	"eulix/internal/types"
+)
+
+// The project checksum changes with any file, but an answer only depends on
+// the code it was built from. Cached answers carry a fingerprint of the KB's
+// chunks of the files they cite and are only reused while those chunks are
+// the same, which keeps the cache honest across partial re-analyzes and
+// branch switches. Checking it takes the chunks, not a search, so a hit
+// never retrieves or embeds the question.
+
+// sourcesFingerprint hashes the KB's chunks of files by location and
+// content, "" without files or when the chunks can't be read. Order is left
+// out.
+func (r *Router) sourcesFingerprint(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	wanted := make(map[string]bool, len(files))
+	for _, f := range files {
+		wanted[f] = true
+	}
+
+	var keys []string
+	add := func(file string, start, end int, content string) {
+		if wanted[file] {
+			sum := sha256.Sum256([]byte(content))
+			keys = append(keys, fmt.Sprintf("%s:%d-%d:%x", file, start, end, sum[:8]))
+		}
+	}
+	// Chunks loaded for search already, else straight from chunks.json
+	if r.contextBuilder != nil {
+		for _, c := range r.contextBuilder.chunks {
+			add(c.File, c.StartLine, c.EndLine, c.Content)
+		}
+	} else {
+		store, err := chunkstore.Load(r.eulixDir)
+		if err != nil {
+			return ""
+		}
+		for _, c := range store.Chunks {
+			add(c.Metadata.FilePath, c.Metadata.LineStart, c.Metadata.LineEnd, c.Content)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// retrieved keeps the window for this answer, so handlers that ask for the
+// same context twice don't retrieve twice
+func (r *Router) retrieved(key string, retrieve func() (*types.ContextWindow, error)) (*types.ContextWindow, error) {
+	if r.signals.window != nil && r.signals.windowKey == key {
+		return r.signals.window, nil
+	}
+	// Handlers that need context get it here, whether or not dispatch
+	// loaded it up front
+	if err := r.ensureContextBuilder(); err != nil {
+		return nil, err
+	}
+	r.contextBuilder.Tune(r.signals.class)
+	r.contextBuilder.SetExpansion(r.signals.expansion)
+	r.contextBuilder.picks = r.signals.picks
+	r.contextBuilder.params.maxResponse = r.responseTokens()
+	window, err := retrieve()
+	if err != nil {
+		return nil, err
+	}
+	r.signals.window = window
+	r.signals.windowKey = key
+	return window, nil
+}
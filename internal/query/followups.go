@@ -0,0 +1,94 @@
+package query
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// maxFollowUps is how many suggestions an answer carries
+const maxFollowUps = 3
+
+// followUps suggests questions to ask next, built from what route saw while
+// answering: the symbol asked about, its neighbours in the call graph (or the
+// index when there is no graph) and the files the context came from. No model
+// call, so it is free to run after every answer.
+func (r *Router) followUps(query string) []string {
+	var out []string
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	add := func(format string, args ...interface{}) {
+		q := fmt.Sprintf(format, args...)
+		if len(out) >= maxFollowUps || seen[strings.ToLower(q)] {
+			return
+		}
+		seen[strings.ToLower(q)] = true
+		out = append(out, q)
+	}
+
+	class := r.signals.class
+	if class == nil {
+		return nil
+	}
+
+	if entity := followUpEntity(query, class); entity != "" {
+		callers, callees := r.neighbours(entity)
+
+		if len(callers) > 0 && class.Type != QueryTypeUsage {
+			add("Who calls %s?", entity)
+		}
+		if len(callees) > 0 {
+			add("What does %s do?", callees[0])
+		}
+		if len(callers) > 0 {
+			add("How does %s use %s?", callers[0], entity)
+		}
+		if class.Type != QueryTypeLocation {
+			add("Where is %s defined?", entity)
+		}
+		if class.Type != QueryTypeUnderstanding {
+			add("How does %s work?", entity)
+		}
+	}
+
+	for _, source := range r.signals.sources {
+		add("What does %s do?", filepath.Base(source))
+	}
+
+	return out
+}
+
+// followUpEntity is the symbol the query was about, only when it looks like
+// one so stop words don't turn into suggestions
+func followUpEntity(query string, class *Classification) string {
+	if len(class.Symbols) > 0 {
+		return class.Symbols[0]
+	}
+	if entity := extractEntityName(query); isLikelySymbol(entity) {
+		return entity
+	}
+	return ""
+}
+
+// neighbours returns who calls entity and what it calls, from the call graph
+// when it is loaded and from the index otherwise (callers only)
+func (r *Router) neighbours(entity string) (callers, callees []string) {
+	if r.callGraph.loaded() {
+		if node, ok := r.callGraph.Functions[entity]; ok {
+			return node.CalledBy, node.Calls
+		}
+	}
+
+	if r.kbIndex == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, caller := range r.kbIndex.FunctionsCalling[entity] {
+		name := strings.TrimPrefix(caller, "func_")
+		if name != entity && !seen[name] {
+			seen[name] = true
+			callers = append(callers, name)
+		}
+	}
+	return callers, nil
+}
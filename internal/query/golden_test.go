@@ -0,0 +1,529 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"eulix/internal/cache"
+	"eulix/internal/config"
+	"eulix/internal/llm"
+	"eulix/internal/testkit"
+	"eulix/internal/types"
+)
+
+// goldenQueries cover a lookup, a usage question, an explanation and a
+// question naming nothing in the fixture
+var goldenQueries = []string{
+	"where is ParseConfig",
+	"who calls CreateOrder",
+	"how does CreateOrder save an order",
+	"explain the Server type",
+	"what does the retry policy look like",
+}
+
+func TestClassifierGolden(t *testing.T) {
+	eulixDir := testkit.Fixture(t, "tiny")
+	classifier, err := QuerySheriff(filepath.Join(eulixDir, "kb_index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	for _, q := range goldenQueries {
+		c := classifier.Classify(q)
+		fmt.Fprintf(&sb, "%s\n  type=%s confidence=%.2f priority=%d needs_context=%t\n  symbols=%v keywords=%v\n",
+			q, c.Type, c.Confidence, c.Priority, c.NeedsContext, c.Symbols, c.Keywords)
+	}
+	testkit.Golden(t, "classifier", sb.String())
+}
+
+func TestMissingVectorsWarning(t *testing.T) {
+	eulixDir := testkit.Fixture(t, "tiny")
+	path := filepath.Join(eulixDir, "chunks.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store map[string]any
+	if err := json.Unmarshal(data, &store); err != nil {
+		t.Fatal(err)
+	}
+	chunks := store["chunks"].(map[string]any)
+	for i := 0; i < 8; i++ {
+		chunks[fmt.Sprintf("unembedded_%d", i)] = chunks["func_main"]
+	}
+	if data, err = json.Marshal(store); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := ContextWindowCreator(eulixDir, testkit.Config(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := strings.Join(cb.Warnings(), "\n")
+	if !strings.Contains(warnings, fmt.Sprintf("8 of %d chunks have no vector", len(cb.chunks))) {
+		t.Fatalf("warnings don't count the chunks without a vector: %q", warnings)
+	}
+	if n := strings.Count(warnings, "unembedded_"); n != missingShown || !strings.Contains(warnings, "and 3 more") {
+		t.Errorf("warning names %d chunks, want %d and the rest counted: %q", n, missingShown, warnings)
+	}
+}
+
+func TestContextGolden(t *testing.T) {
+	eulixDir := testkit.Fixture(t, "tiny")
+	cb, err := ContextWindowCreator(eulixDir, testkit.Config(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb.queryEmbedder = testkit.Mimic()
+	if len(cb.Warnings()) > 0 {
+		t.Fatalf("fixture didn't load cleanly: %v", cb.Warnings())
+	}
+
+	var sb strings.Builder
+	for _, q := range goldenQueries {
+		window, err := cb.BuildContext(q)
+		if err != nil {
+			t.Fatalf("%q: %v", q, err)
+		}
+		fmt.Fprintf(&sb, "%s\n%s", q, describeWindow(window))
+	}
+	testkit.Golden(t, "context", sb.String())
+}
+
+// newFixtureRouter is a router over the tiny fixture answering with fake,
+// with the context builder loaded and the fake embedder in place
+func newFixtureRouter(t *testing.T, cfg *config.Config, fake *testkit.FakeLLM) *Router {
+	t.Helper()
+	client, err := llm.MouthClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetProvider(fake.Provider)
+
+	r, err := QueryTrafficController(testkit.Fixture(t, "tiny"), cfg, client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	if err := r.ensureContextBuilder(); err != nil {
+		t.Fatal(err)
+	}
+	r.contextBuilder.queryEmbedder = testkit.Mimic()
+	return r
+}
+
+func TestPromptGolden(t *testing.T) {
+	cfg := testkit.Config()
+	fake := testkit.Parrot("CreateOrder validates the order, then saveOrder inserts it.")
+	r := newFixtureRouter(t, cfg, fake)
+
+	answer, err := r.Answer("how does CreateOrder save an order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(answer.Text, "saveOrder inserts it") {
+		t.Errorf("answer isn't the fake LLM's: %q", answer.Text)
+	}
+	prompts := fake.Prompts()
+	if len(prompts) != 1 {
+		t.Fatalf("sent %d prompts, want 1", len(prompts))
+	}
+	testkit.Golden(t, "prompt", prompts[0])
+}
+
+// describeWindow lists what a context window holds, one chunk a line
+func describeWindow(w *types.ContextWindow) string {
+	var sb strings.Builder
+	for _, c := range w.Chunks {
+		fmt.Fprintf(&sb, "  %s:%d-%d importance=%.3f\n", c.File, c.StartLine, c.EndLine, c.Importance)
+	}
+	fmt.Fprintf(&sb, "  retrieval=%.2f tokens=%d files=%d\n", w.Retrieval, w.TotalTokens, len(w.Sources))
+	return sb.String()
+}
+
+func TestFileContext(t *testing.T) {
+	classifier, err := QuerySheriff("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := classifier.Classify("explain internal/orders/orders.go")
+	if c.Type != QueryTypeFile || !reflect.DeepEqual(c.Files, []FileRef{{Path: "internal/orders/orders.go"}}) {
+		t.Fatalf("classified as %s with files %v", c.Type, c.Files)
+	}
+
+	eulixDir := testkit.Fixture(t, "tiny")
+	cb, err := ContextWindowCreator(eulixDir, testkit.Config(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cb.ResolveFile("orders.go"); !reflect.DeepEqual(got, []string{"internal/orders/orders.go"}) {
+		t.Fatalf("ResolveFile(orders.go) = %v", got)
+	}
+
+	window, dropped, err := cb.BuildFileContext("explain orders.go", []FileRef{{Path: "internal/orders/orders.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 0 {
+		t.Errorf("%d chunks dropped from a small file", dropped)
+	}
+	last := 0
+	for _, chunk := range window.Chunks {
+		if chunk.File != "internal/orders/orders.go" {
+			t.Errorf("chunk from %s", chunk.File)
+		}
+		if chunk.StartLine < last {
+			t.Errorf("chunks out of order: line %d after %d", chunk.StartLine, last)
+		}
+		last = chunk.EndLine
+	}
+	if last != 80 {
+		t.Errorf("file context ends at line %d, want 80", last)
+	}
+}
+
+func TestLineRangeContext(t *testing.T) {
+	ref, err := ParseFileRef("internal/orders/orders.go#L45-L50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (FileRef{Path: "internal/orders/orders.go", Start: 45, End: 50}); ref != want {
+		t.Fatalf("ParseFileRef = %+v, want %+v", ref, want)
+	}
+	if _, err := ParseFileRef("orders.go:50-45"); err == nil {
+		t.Error("backwards range parsed")
+	}
+
+	eulixDir := testkit.Fixture(t, "tiny")
+	cb, err := ContextWindowCreator(eulixDir, testkit.Config(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	window, _, err := cb.BuildFileContext("explain orders.go:45-50", []FileRef{ref})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(window.Chunks) != 1 || window.Chunks[0].StartLine != 40 || window.Chunks[0].EndLine != 58 {
+		t.Errorf("lines 45-50 got %s", describeWindow(window))
+	}
+}
+
+func TestFileContextCompressesToFit(t *testing.T) {
+	body := func(name string) string {
+		return "// " + name + " stores the order\nfunc " + name + "(order Order) error {\n" +
+			strings.Repeat("\t// check the next field\n\tif err := check(order); err != nil {\n\t\treturn err\n\t}\n\n", 8) + "\treturn nil\n}"
+	}
+	cfg := testkit.Config()
+	cb := &ContextBuilder{config: cfg}
+	cb.Tune(nil)
+	for i, name := range []string{"CreateOrder", "validateOrder", "saveOrder", "notifyOrder"} {
+		content := body(name)
+		cb.chunks = append(cb.chunks, Chunk{File: "orders.go", StartLine: i*50 + 1, EndLine: i*50 + 44, ChunkType: "function", Content: content,
+			Tokens: len(content) / 4, Name: name, Importance: 1 - float64(i)/10})
+	}
+	files := []FileRef{{Path: "orders.go"}}
+
+	// Room for about two thirds of the file
+	query := "explain orders.go"
+	total := 0
+	for _, c := range cb.chunks {
+		total += cb.scoped(c).Tokens + 20
+	}
+	cfg.LLM.MaxTokens -= int(float64(cb.tokenBudget(query)-total*2/3)/cb.budgetShare()) + 1
+
+	cfg.Retrieval.Compress = CompressOff
+	_, droppedOff, err := cb.BuildFileContext(query, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if droppedOff == 0 {
+		t.Fatal("nothing dropped without compression, the budget is too large for the test")
+	}
+
+	cfg.Retrieval.Compress = ""
+	window, dropped, err := cb.BuildFileContext(query, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 0 {
+		t.Errorf("%d chunks dropped with compression, %d without", dropped, droppedOff)
+	}
+	if compressedWarning(window) == "" {
+		t.Errorf("no chunk compressed: %s", describeWindow(window))
+	}
+	prompt := window.String()
+	if !strings.Contains(prompt, "signature and docs only) ---\n// function (orders.go)\n// notifyOrder stores the order\nfunc notifyOrder(order Order) error {\n    ...") {
+		t.Errorf("the least important chunk isn't cut to its signature:\n%s", prompt)
+	}
+}
+
+func TestGroupByFile(t *testing.T) {
+	cb := &ContextBuilder{config: testkit.Config()}
+	server := Chunk{File: "server.go", StartLine: 9, EndLine: 13, ChunkType: "class", Name: "Server",
+		Content: "// Server serves orders\ntype Server struct {\n\tcfg *Config\n\tdb  *DB\n}"}
+	start := Chunk{File: "server.go", StartLine: 27, EndLine: 30, ChunkType: "method", Name: "Start",
+		Content: "func (s *Server) Start() error {\n\treturn listen(s.cfg)\n}", Tokens: 12, Importance: 0.4}
+	handle := Chunk{File: "server.go", StartLine: 47, EndLine: 50, ChunkType: "method", Name: "handleOrder",
+		Content: "func (s *Server) handleOrder() {\n\tsave(s.db)\n}", Tokens: 11, Importance: 0.3}
+	main := Chunk{File: "main.go", StartLine: 5, EndLine: 8, ChunkType: "function", Name: "main",
+		Content: "func main() {\n\tNewServer().Start()\n}", Tokens: 9, Importance: 0.9}
+	cb.chunks = []Chunk{server, start, handle, main}
+
+	// Best match first, with main.go between the two methods
+	grouped := cb.groupByFile([]Chunk{handle, main, start}, 1000)
+	if len(grouped) != 2 || grouped[0].File != "server.go" || grouped[1].File != "main.go" {
+		t.Fatalf("want server.go then main.go, got %+v", grouped)
+	}
+	got := grouped[0]
+	want := "(lines 9-13, signature and docs only)\n// type (server.go)\n// Server serves orders\ntype Server struct {\n    ...\n\n" +
+		"... lines 14-26 not shown ...\n\n// method of type Server (server.go)\n" + start.Content +
+		"\n\n... lines 31-46 not shown ...\n\n// method of type Server (server.go)\n" + handle.Content
+	if got.Content != want {
+		t.Errorf("server.go content:\n%s\nwant:\n%s", got.Content, want)
+	}
+	if got.StartLine != 9 || got.EndLine != 50 || got.Importance != 0.4 {
+		t.Errorf("server.go spans %d-%d importance %.1f, want 9-50 importance 0.4", got.StartLine, got.EndLine, got.Importance)
+	}
+
+	// No room for the type's header
+	if grouped := cb.groupByFile([]Chunk{start}, 30); strings.Contains(grouped[0].Content, "type Server struct") {
+		t.Errorf("type header added past the budget:\n%s", grouped[0].Content)
+	}
+}
+
+func TestKeywordSearchLanguageKeywords(t *testing.T) {
+	cb := &ContextBuilder{config: testkit.Config(), chunks: []Chunk{
+		{ID: "theme", File: "ui/theme.go", ChunkType: "function", Name: "selectTheme", Symbols: []string{"selectTheme"},
+			Content: "func selectTheme(name string) Theme {\n\treturn themes[name]\n}"},
+		{ID: "worker", File: "jobs/worker.go", ChunkType: "method", Name: "run", Symbols: []string{"run"},
+			Content: "func (w *Worker) run() {\n\tfor {\n\t\tselect {\n\t\tcase job := <-w.jobs:\n\t\t\tjob.Do()\n\t\tcase <-w.done:\n\t\t\treturn\n\t\t}\n\t}\n}"},
+		{ID: "picker", File: "tools/picker.py", ChunkType: "function", Name: "select", Symbols: []string{"select"},
+			Content: "def select(options):\n    return options[0]"},
+	}}
+
+	rank := make(map[string]int)
+	for i, sc := range cb.keywordSearch("the select statement in the worker", 10) {
+		rank[sc.ID] = i + 1
+		switch {
+		case sc.ID == "theme" && strings.Contains(sc.MatchDetails, "select"):
+			t.Errorf("the Go keyword matched a Go name: %s", sc.MatchDetails)
+		case sc.ID == "picker" && !strings.Contains(sc.MatchDetails, "name=select"):
+			// select is a keyword in Go, not in Python
+			t.Errorf("the Python function select didn't match by name: %s", sc.MatchDetails)
+		}
+	}
+	if rank["worker"] == 0 || rank["worker"] > rank["theme"] {
+		t.Errorf("the worker's select ranks %d, selectTheme %d", rank["worker"], rank["theme"])
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+	cfg := testkit.Config()
+	cfg.LLM.Strategy = StrategyMapReduce
+	// Past the 4000 tokens an architecture answer keeps, room for a chunk
+	// or two a window
+	cfg.LLM.MaxTokens = 4550
+	fake := testkit.Parrot("CreateOrder validates the order, then saveOrder inserts it.")
+	r := newFixtureRouter(t, cfg, fake)
+
+	q := "what is the overall structure of order creation"
+	if class := r.Classify(q); !r.mapReduces(class) {
+		t.Fatalf("%s question isn't map-reduced", class.Type)
+	}
+	if _, err := r.Answer(q); err != nil {
+		t.Fatal(err)
+	}
+	prompts := fake.Prompts()
+	if len(prompts) < 3 {
+		t.Fatalf("sent %d prompts, want a summary per window and the answer", len(prompts))
+	}
+	for i, prompt := range prompts[:len(prompts)-1] {
+		if !strings.Contains(prompt, "one part of a large codebase") {
+			t.Errorf("prompt %d isn't a summary request:\n%s", i+1, prompt)
+		}
+	}
+	reduce := prompts[len(prompts)-1]
+	if !strings.Contains(reduce, "### Part 2: ") || !strings.Contains(reduce, "saveOrder inserts it") {
+		t.Errorf("the answer isn't asked over the summaries:\n%s", reduce)
+	}
+}
+
+func TestTranslatedQuestion(t *testing.T) {
+	for q, want := range map[string]string{
+		"how does CreateOrder save an order":         "",
+		"why does the worker die on shutdown":        "",
+		"¿Cómo valida CreateOrder el pedido?":        "Spanish",
+		"Wo wird ParseConfig aufgerufen?":            "German",
+		"CreateOrder फ़ंक्शन क्या करता है?":          "Hindi",
+		"what does `es la función` print in main.go": "",
+	} {
+		if got := detectLanguage(q); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", q, got, want)
+		}
+	}
+
+	fake := testkit.Parrot("How does CreateOrder validate the order?", "CreateOrder llama a validateOrder.")
+	r := newCachingRouter(t, fake)
+
+	answer, err := r.Answer("¿Cómo valida CreateOrder el pedido?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts := fake.Prompts()
+	if len(prompts) != 2 {
+		t.Fatalf("sent %d prompts, want the translation and the answer", len(prompts))
+	}
+	if !strings.Contains(prompts[0], "from Spanish into English") {
+		t.Errorf("the first prompt isn't a translation:\n%s", prompts[0])
+	}
+	if !strings.Contains(prompts[1], "How does CreateOrder validate the order?") || !strings.Contains(prompts[1], "Write the answer in Spanish") {
+		t.Errorf("the answer isn't asked for in English and answered in Spanish:\n%s", prompts[1])
+	}
+	if !strings.Contains(answer.Text, "llama a validateOrder") {
+		t.Errorf("answer %q", answer.Text)
+	}
+
+	// Asked again it comes from the cache, untranslated
+	again, err := r.Answer("¿Cómo valida CreateOrder el pedido?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Prompts()); n != 2 || again.Text != answer.Text {
+		t.Errorf("asked again: sent %d more prompts and answered %q, want the cached answer", n-2, again.Text)
+	}
+
+	// The translation is held to [llm.budget] like the answer
+	cfg := testkit.Config()
+	cfg.LLM.Budget.MaxPromptTokens = 100
+	fake = testkit.Parrot("How does CreateOrder validate the order?")
+	var over *BudgetError
+	if _, err := newFixtureRouter(t, cfg, fake).Answer("¿Cómo valida CreateOrder el pedido?"); !errors.As(err, &over) {
+		t.Errorf("over budget: err = %v, want a BudgetError", err)
+	}
+	if n := len(fake.Prompts()); n != 0 {
+		t.Errorf("sent %d prompts over budget", n)
+	}
+}
+
+func TestBudgetReduce(t *testing.T) {
+	q := "how does CreateOrder save an order"
+	full := testkit.Parrot("CreateOrder validates the order, then saveOrder inserts it.")
+	if _, err := newFixtureRouter(t, testkit.Config(), full).Answer(q); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testkit.Config()
+	cfg.LLM.Budget.OnExceed = "reduce"
+	cfg.LLM.Budget.MaxPromptTokens = 1100
+	fake := testkit.Parrot("CreateOrder validates the order, then saveOrder inserts it.")
+	answer, err := newFixtureRouter(t, cfg, fake).Answer(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reduced := false
+	for _, w := range answer.Warnings {
+		reduced = reduced || strings.HasPrefix(w, "context reduced from")
+	}
+	if !reduced {
+		t.Errorf("no reduced warning in %v", answer.Warnings)
+	}
+	sent, unbounded := fake.Prompts()[0], full.Prompts()[0]
+	if got, was := strings.Count(sent, "Relevance: "), strings.Count(unbounded, "Relevance: "); got >= was {
+		t.Errorf("sent %d chunks within the budget, %d without", got, was)
+	}
+	// The window goes into the prompt once, as reduced
+	if n := strings.Count(sent, "func CreateOrder"); n != 1 {
+		t.Errorf("CreateOrder is in the prompt %d times, want 1", n)
+	}
+}
+
+// newCachingRouter is newFixtureRouter with an SQL cache of its own
+func newCachingRouter(t *testing.T, fake *testkit.FakeLLM) *Router {
+	t.Helper()
+	cfg := testkit.Config()
+	cfg.Cache.SQL.Enabled = true
+	cfg.Cache.SQL.DSN = filepath.Join(t.TempDir(), "cache.db")
+	r := newFixtureRouter(t, cfg, fake)
+	answers, err := cache.CacheController(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { answers.Close() })
+	r.cache = answers
+	r.SetCurrentChecksum("fixture")
+	return r
+}
+
+func TestSessionMemoryCacheKey(t *testing.T) {
+	fake := testkit.Parrot("Without notes.", "With the note.")
+	r := newCachingRouter(t, fake)
+
+	q := "how does CreateOrder save an order"
+	if _, err := r.Answer(q); err != nil {
+		t.Fatal(err)
+	}
+	session := &Session{}
+	session.Remember("orders are never updated after saveOrder")
+	answer, err := r.AnswerInSession(q, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(answer.Text, "With the note") {
+		t.Errorf("answered from the cache without the session's notes: %q", answer.Text)
+	}
+
+	// The same notes again are answered from the cache
+	if _, err := r.AnswerInSession(q, session); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Prompts()); n != 2 {
+		t.Errorf("sent %d prompts, want 2", n)
+	}
+}
+
+func TestCacheHitSkipsRetrieval(t *testing.T) {
+	fake := testkit.Parrot("First answer.", "After the change.")
+	r := newCachingRouter(t, fake)
+
+	q := "how does CreateOrder save an order"
+	if _, err := r.Answer(q); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := r.Answer(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Text == "" || r.signals.window != nil {
+		t.Errorf("cache hit retrieved context again")
+	}
+	if n := len(fake.Prompts()); n != 1 {
+		t.Fatalf("sent %d prompts, want 1", n)
+	}
+	if usage, err := r.cache.Usage(); err != nil || usage.Asked != 2 || usage.Hits != 1 {
+		t.Errorf("usage %+v, %v, want 2 asked and 1 hit", usage, err)
+	}
+
+	// A chunk of a cited file changing in the KB takes the answer out
+	for i := range r.contextBuilder.chunks {
+		if r.contextBuilder.chunks[i].Name == "saveOrder" {
+			r.contextBuilder.chunks[i].Content += "\n// audited"
+		}
+	}
+	if _, err := r.Answer(q); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Prompts()); n != 2 {
+		t.Errorf("sent %d prompts after the KB changed, want 2", n)
+	}
+}
@@ -0,0 +1,259 @@
+package query
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"eulix/internal/config"
+)
+
+// A chunk's importance is a weighted mean of factors that each score 0-1:
+// its type, complexity, centrality in the call graph, how recently its file
+// changed and whether it is documented. [retrieval.importance] in eulix.toml
+// sets the weights. Search scores are nudged by it, so among similar matches
+// the important code goes in first.
+
+// defaultImportance are the weights when [retrieval.importance] sets none
+var defaultImportance = config.ImportanceWeights{
+	Type:       0.4,
+	Complexity: 0.2,
+	Centrality: 0.25,
+	Recency:    0.1,
+	Docs:       0.05,
+	Influence:  0.1,
+}
+
+const (
+	// complexitySaturation is the complexity that scores 1, most functions
+	// are well under it
+	complexitySaturation = 20
+
+	// recencyHalfLife is how long after the newest change in the project a
+	// file's recency halves
+	recencyHalfLife = 30 * 24 * time.Hour
+
+	// recencyCommits bounds how far back git log is walked for commit times,
+	// files not changed in that many commits fall back to modification time
+	recencyCommits = 2000
+)
+
+// typeImportance scores chunk types, unknown ones get 0.5
+var typeImportance = map[string]float64{
+	"class":    0.8,
+	"function": 0.7,
+	"method":   0.6,
+	"schema":   0.6,
+	"doc":      0.5,
+	"infra":    0.5,
+	"file":     0.4,
+}
+
+// docPrefixes start a comment in the languages the parser knows
+var docPrefixes = []string{"//", "/*", "#", "--", `"""`, "'''"}
+
+// importanceFactors are a chunk's factor scores
+type importanceFactors struct {
+	kind, complexity, centrality, recency, docs float64
+}
+
+// importanceWeights resolves the configured weights, all zero for defaults
+func importanceWeights(cfg config.ImportanceWeights) config.ImportanceWeights {
+	w := cfg
+	if w.Type == 0 && w.Complexity == 0 && w.Centrality == 0 && w.Recency == 0 && w.Docs == 0 {
+		w = defaultImportance
+		w.Influence = cfg.Influence
+	}
+	if w.Influence == 0 {
+		w.Influence = defaultImportance.Influence
+	}
+	return w
+}
+
+// importance is the weighted mean of f, negative weights count as 0
+func importance(f importanceFactors, w config.ImportanceWeights) float64 {
+	weights := []float64{w.Type, w.Complexity, w.Centrality, w.Recency, w.Docs}
+	scores := []float64{f.kind, f.complexity, f.centrality, f.recency, f.docs}
+	sum, total := 0.0, 0.0
+	for i, weight := range weights {
+		if weight > 0 {
+			sum += weight * scores[i]
+			total += weight
+		}
+	}
+	if total == 0 {
+		return 0.5
+	}
+	return sum / total
+}
+
+// scoreImportance sets Importance on every chunk. Centrality comes from
+// kb_index.json and recency from file times in the project, a factor it
+// can't get scores 0 for every chunk alike.
+func (cb *ContextBuilder) scoreImportance(complexity []int) {
+	weights := importanceWeights(cb.config.Retrieval.Importance)
+
+	var central map[string]float64
+	if weights.Centrality > 0 {
+		if index, err := loadKBIndex(cb.eulixDir); err == nil {
+			central = index.Centrality
+		}
+	}
+	var recency map[string]float64
+	if weights.Recency > 0 {
+		recency = cb.fileRecency()
+	}
+
+	for i := range cb.chunks {
+		chunk := &cb.chunks[i]
+		f := importanceFactors{kind: 0.5, recency: recency[chunk.File]}
+		if kind, ok := typeImportance[chunk.ChunkType]; ok {
+			f.kind = kind
+		}
+		f.complexity = math.Min(1, math.Log1p(float64(complexity[i]))/math.Log1p(complexitySaturation))
+		if chunk.ChunkType == "function" || chunk.ChunkType == "method" {
+			score, ok := central[chunk.Name]
+			if !ok {
+				// Methods may be named Type.Method in chunks and not in the graph
+				score = central[chunk.Name[strings.LastIndex(chunk.Name, ".")+1:]]
+			}
+			f.centrality = score
+		}
+		if chunk.ChunkType == "doc" || documented(chunk.Content) {
+			f.docs = 1
+		}
+		chunk.Importance = importance(f, weights)
+	}
+}
+
+// fileRecency scores each chunk file by when it last changed against the
+// newest one, so a project nobody touched for a year still ranks its files.
+// A file changed when it was last committed; a checkout or a touch resets
+// modification times, so those only count for files git doesn't know.
+func (cb *ContextBuilder) fileRecency() map[string]float64 {
+	root := filepath.Dir(cb.eulixDir)
+	files := make(map[string]bool)
+	for _, chunk := range cb.chunks {
+		files[chunk.File] = true
+	}
+	committed := commitTimes(root, files, recencyCommits)
+
+	modified := make(map[string]time.Time, len(files))
+	var newest time.Time
+	for file := range files {
+		t, ok := committed[file]
+		if !ok {
+			if info, err := os.Stat(filepath.Join(root, file)); err == nil {
+				t = info.ModTime()
+			}
+		}
+		if t.After(newest) {
+			newest = t
+		}
+		modified[file] = t
+	}
+
+	recency := make(map[string]float64, len(modified))
+	for file, t := range modified {
+		if t.IsZero() {
+			continue
+		}
+		age := newest.Sub(t)
+		recency[file] = math.Pow(0.5, float64(age)/float64(recencyHalfLife))
+	}
+	return recency
+}
+
+// commitTimes is when each of files was last committed under root, from one
+// walk of at most limit commits of git log that stops once all of them are
+// seen. Nil outside a git repository.
+func commitTimes(root string, files map[string]bool, limit int) map[string]time.Time {
+	cmd := exec.Command("git", "-C", root, "log", "--max-count="+strconv.Itoa(limit),
+		"--format=%x00%ct", "--name-only", "--no-renames", "--relative")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		return nil
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	times := make(map[string]time.Time)
+	var commit time.Time
+	scanner := bufio.NewScanner(out)
+	for len(times) < len(files) && scanner.Scan() {
+		line := scanner.Text()
+		if seconds, ok := strings.CutPrefix(line, "\x00"); ok {
+			if n, err := strconv.ParseInt(seconds, 10, 64); err == nil {
+				commit = time.Unix(n, 0)
+			}
+			continue
+		}
+		if _, seen := times[line]; files[line] && !seen {
+			times[line] = commit
+		}
+	}
+	return times
+}
+
+// documented reports whether a chunk is documented. eulix_embed chunks open
+// with "// File:" and friends and carry the docstring as "// Description:",
+// other chunks count when they open with a comment or have a Python
+// docstring right under the signature.
+func documented(content string) bool {
+	lines := strings.Split(content, "\n")
+	if strings.HasPrefix(lines[0], "// File: ") {
+		for _, line := range lines[1:] {
+			if !strings.HasPrefix(line, "// ") {
+				return false
+			}
+			if strings.HasPrefix(line, "// Description: ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	checked := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, prefix := range docPrefixes {
+			if strings.HasPrefix(line, prefix) && !strings.HasPrefix(line, "#include") && !strings.HasPrefix(line, "#!") {
+				return true
+			}
+		}
+		if checked++; checked == 2 {
+			return false
+		}
+	}
+	return false
+}
+
+// weighByImportance nudges search scores by importance and re-sorts, a
+// chunk of average importance keeps its score
+func (cb *ContextBuilder) weighByImportance(scored []ScoredChunk) []ScoredChunk {
+	influence := importanceWeights(cb.config.Retrieval.Importance).Influence
+	if influence < 0 {
+		return scored
+	}
+	for i := range scored {
+		scored[i].Score *= 1 + influence*(scored[i].Importance-0.5)
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRecencyUsesCommitTime(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(date string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=eulix", "-c", "user.email=eulix@example.com"}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(file string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, file), []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("", "init", "-q")
+	write("old.go")
+	git("", "add", "old.go")
+	git("2020-01-01T00:00:00Z", "commit", "-q", "-m", "old")
+	write("new.go")
+	git("", "add", "new.go")
+	git("2024-01-01T00:00:00Z", "commit", "-q", "-m", "new")
+	write("untracked.go")
+
+	// A fresh checkout gives every file the same modification time
+	now := time.Now()
+	for _, file := range []string{"old.go", "new.go", "untracked.go"} {
+		if err := os.Chtimes(filepath.Join(root, file), now, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cb := &ContextBuilder{
+		eulixDir: filepath.Join(root, ".eulix"),
+		chunks:   []Chunk{{File: "old.go"}, {File: "new.go"}, {File: "untracked.go"}},
+	}
+	recency := cb.fileRecency()
+	if recency["old.go"] >= recency["new.go"] {
+		t.Errorf("old.go scores %v, new.go %v: commit times weren't used", recency["old.go"], recency["new.go"])
+	}
+	if recency["untracked.go"] != 1 {
+		t.Errorf("untracked.go scores %v, want 1 from its modification time", recency["untracked.go"])
+	}
+
+	// Past the commit limit the walk stops and old.go is left to its mtime
+	times := commitTimes(root, map[string]bool{"old.go": true, "new.go": true}, 1)
+	if _, ok := times["old.go"]; ok || len(times) != 1 {
+		t.Errorf("commitTimes with a limit of 1 = %v, want only new.go", times)
+	}
+}
@@ -0,0 +1,98 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"eulix/internal/llm"
+)
+
+// OnText sets the callback that receives answers piece by piece as the model
+// generates them, nil turns streaming off. Like OnProgress it is called on the
+// goroutine answering the question.
+func (r *Router) OnText(fn func(string)) {
+	r.onText = fn
+}
+
+// Interrupt stops the answer in flight, safe to call from any goroutine. What
+// was generated so far comes back as a truncated answer that isn't cached.
+func (r *Router) Interrupt() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// interruptible makes the answer being started interruptible, and stops it
+// at max_seconds from [llm.budget]. The returned func ends it.
+func (r *Router) interruptible() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	if seconds := r.config.LLM.Budget.MaxSeconds; seconds > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+	}
+	r.signals.ctx = ctx
+	r.signals.partial = ""
+
+	r.mu.Lock()
+	r.cancel = cancel
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		r.cancel = nil
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+func (r *Router) generationContext() context.Context {
+	if r.signals.ctx == nil {
+		return context.Background()
+	}
+	return r.signals.ctx
+}
+
+// stream is the text callback for the current model call, agent tool calls
+// are not worth showing
+func (r *Router) stream() func(string) {
+	if r.signals.quiet {
+		return nil
+	}
+	return r.onText
+}
+
+// keepPartial remembers what an interrupted model call generated
+func (r *Router) keepPartial(text string, err error) (string, error) {
+	if errors.Is(err, llm.ErrInterrupted) {
+		r.signals.partial = text
+	}
+	return text, err
+}
+
+// truncatedAnswer is what was generated before an interrupt. It skips the
+// cache and the post-answer hook, the model never got to rate it.
+func (r *Router) truncatedAnswer(query string) *Answer {
+	text, _ := llm.SplitConfidence(r.signals.partial)
+	r.noteDeadline()
+	return &Answer{
+		Text:       text,
+		Confidence: scoreConfidence(r.signals.retrieval, r.signals.classifier, -1),
+		Entities:   answerEntities(query, r.signals.class),
+		Sources:    r.signals.sources,
+		Owners:     r.owners.All(r.signals.sources),
+		Truncated:  true,
+		Warnings:   r.signals.warnings,
+	}
+}
+
+// noteDeadline warns when it was max_seconds that stopped the answer rather
+// than Interrupt
+func (r *Router) noteDeadline() {
+	if errors.Is(r.generationContext().Err(), context.DeadlineExceeded) {
+		r.signals.warnings = append(r.signals.warnings,
+			fmt.Sprintf("stopped at the %ds limit in [llm.budget]", r.config.LLM.Budget.MaxSeconds))
+	}
+}
@@ -0,0 +1,167 @@
+package query
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Names in questions rarely match the index exactly: "start" for Start,
+// "httpserver.Start" with its package, "Server.Start" with its receiver.
+// Lookup tries the exact name first, then the qualified forms, then any
+// case, so an exact hit never loses to a looser one.
+
+// qualifiedNamePattern matches a name with a package or type before it,
+// "httpserver.Start", "Server::start" or "Server->start"
+var qualifiedNamePattern = regexp.MustCompile(`\b([A-Za-z_]\w*)(?:\.|::|->)([A-Za-z_]\w*)\b`)
+
+// SymbolMatch is what a name from a question resolved to in the index
+type SymbolMatch struct {
+	Name      string   // the name as indexed
+	Functions []string // locations of functions and methods by that name
+	Types     []string // locations of types by that name
+	Globals   []string // locations of package level vars and consts by that name
+}
+
+// Locations is every location of the match, functions first and globals last
+func (m SymbolMatch) Locations() []string {
+	var locations []string
+	for _, loc := range append(append(append([]string(nil), m.Functions...), m.Types...), m.Globals...) {
+		if !containsString(locations, loc) {
+			locations = append(locations, loc)
+		}
+	}
+	return locations
+}
+
+// Lookup resolves name the way a person would write it: exactly, qualified
+// with a package or receiver type, or in any case. A qualifier that names
+// neither a package nor a type is ignored, it may be an import alias.
+func (idx *KBIndex) Lookup(name string) (SymbolMatch, bool) {
+	name = strings.Trim(name, " \t?!.,;:'\"`()")
+	if match, ok := idx.lookupName(name); ok {
+		return match, true
+	}
+
+	m := qualifiedNamePattern.FindStringSubmatch(name)
+	if m == nil || m[0] != name {
+		return SymbolMatch{}, false
+	}
+	qualifier, base := m[1], m[2]
+	match, ok := idx.lookupName(base)
+	if !ok {
+		return SymbolMatch{}, false
+	}
+
+	// Type.Method keeps the definitions in the type's package
+	if typ, ok := idx.lookupName(qualifier); ok && len(typ.Types) > 0 {
+		dirs := make(map[string]bool)
+		for _, loc := range typ.Types {
+			file, _ := splitLocation(loc)
+			dirs[path.Dir(file)] = true
+		}
+		if narrowed, ok := match.filter(func(file string) bool { return dirs[path.Dir(file)] }); ok {
+			return narrowed, true
+		}
+	}
+
+	// package.Function keeps the definitions in that package, by directory
+	// or, for languages where files are modules, by file name
+	inPackage := func(file string) bool {
+		stem := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		return strings.EqualFold(path.Base(path.Dir(file)), qualifier) || strings.EqualFold(stem, qualifier)
+	}
+	if narrowed, ok := match.filter(inPackage); ok {
+		return narrowed, true
+	}
+	if idx.anyLocation(inPackage) {
+		// A package that doesn't define it
+		return SymbolMatch{}, false
+	}
+	return match, true
+}
+
+// anyLocation reports whether any indexed definition is in a file keep accepts
+func (idx *KBIndex) anyLocation(keep func(file string) bool) bool {
+	for _, names := range []map[string][]string{idx.FunctionsByName, idx.TypesByName, idx.GlobalsByName} {
+		for _, locations := range names {
+			for _, loc := range locations {
+				if file, _ := splitLocation(loc); keep(file) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// lookupName finds name exactly, or else in any case. Names differing only
+// in case, Start and start, all match.
+func (idx *KBIndex) lookupName(name string) (SymbolMatch, bool) {
+	functions, isFunc := idx.FunctionsByName[name]
+	types, isType := idx.TypesByName[name]
+	globals, isGlobal := idx.GlobalsByName[name]
+	if isFunc || isType || isGlobal {
+		return SymbolMatch{Name: name, Functions: functions, Types: types, Globals: globals}, true
+	}
+
+	if idx.folded == nil {
+		idx.folded = make(map[string][]string)
+		for _, names := range []map[string][]string{idx.FunctionsByName, idx.TypesByName, idx.GlobalsByName} {
+			for n := range names {
+				key := strings.ToLower(n)
+				if !containsString(idx.folded[key], n) {
+					idx.folded[key] = append(idx.folded[key], n)
+				}
+			}
+		}
+		for _, names := range idx.folded {
+			sort.Strings(names)
+		}
+	}
+
+	names := idx.folded[strings.ToLower(name)]
+	if len(names) == 0 {
+		return SymbolMatch{}, false
+	}
+	match := SymbolMatch{Name: names[0]}
+	for _, n := range names {
+		match.Functions = append(match.Functions, idx.FunctionsByName[n]...)
+		match.Types = append(match.Types, idx.TypesByName[n]...)
+		match.Globals = append(match.Globals, idx.GlobalsByName[n]...)
+	}
+	return match, true
+}
+
+// filter keeps the locations in files keep accepts, false when none are left
+func (m SymbolMatch) filter(keep func(file string) bool) (SymbolMatch, bool) {
+	narrowed := SymbolMatch{Name: m.Name}
+	for _, loc := range m.Functions {
+		if file, _ := splitLocation(loc); keep(file) {
+			narrowed.Functions = append(narrowed.Functions, loc)
+		}
+	}
+	for _, loc := range m.Types {
+		if file, _ := splitLocation(loc); keep(file) {
+			narrowed.Types = append(narrowed.Types, loc)
+		}
+	}
+	for _, loc := range m.Globals {
+		if file, _ := splitLocation(loc); keep(file) {
+			narrowed.Globals = append(narrowed.Globals, loc)
+		}
+	}
+	return narrowed, len(narrowed.Locations()) > 0
+}
+
+// qualifiedSymbol is symbol as the query writes it, with the package or
+// type in front when it has one
+func qualifiedSymbol(query, symbol string) string {
+	for _, m := range qualifiedNamePattern.FindAllStringSubmatch(query, -1) {
+		if m[2] == symbol {
+			return m[0]
+		}
+	}
+	return symbol
+}
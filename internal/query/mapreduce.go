@@ -0,0 +1,192 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"eulix/internal/config"
+	"eulix/internal/types"
+)
+
+// With [llm] strategy = "map_reduce" an architecture or understanding
+// question retrieves several windows' worth of context instead of one. Each
+// window, whole files where they fit, is summarized for the question by
+// summary_model, and the main model answers over the summaries. More of a
+// large project is seen, at the cost of a model call per window.
+
+// Values of [llm] strategy
+const (
+	StrategyDirect    = "direct"
+	StrategyMapReduce = "map_reduce"
+)
+
+const (
+	defaultMapGroups = 4
+	summaryTokens    = 600 // the most a summary may run to
+)
+
+// checkStrategy warns about an [llm] strategy that isn't one, it falls
+// back to direct
+func checkStrategy(cfg config.LLMConfig) []string {
+	switch cfg.Strategy {
+	case "", StrategyDirect, StrategyMapReduce:
+		return nil
+	}
+	return []string{fmt.Sprintf("[llm] strategy = %q is not a strategy, using %s. Use direct or map_reduce", cfg.Strategy, StrategyDirect)}
+}
+
+// mapReduces reports whether class is answered by map-reduce
+func (r *Router) mapReduces(class *Classification) bool {
+	if r.config.LLM.Strategy != StrategyMapReduce || !class.NeedsContext {
+		return false
+	}
+	return class.Type == QueryTypeArchitecture || class.Type == QueryTypeUnderstanding
+}
+
+func (r *Router) mapGroups() int {
+	if r.config.LLM.MapGroups > 0 {
+		return r.config.LLM.MapGroups
+	}
+	return defaultMapGroups
+}
+
+// BuildMapContext retrieves for query with groups times the usual budget,
+// for splitting into windows with splitWindow
+func (cb *ContextBuilder) BuildMapContext(query string, groups int) (*types.ContextWindow, error) {
+	budget := cb.tokenBudget(query) * groups
+	selected, candidates := cb.retrieve(query, budget)
+	window := cb.assembleContext(cb.groupByFile(selected, budget))
+	window.Retrieval = retrievalStrength(candidates)
+	return window, nil
+}
+
+// mapContext is BuildMapContext through retrieved, so cached answers are
+// checked against the same chunks
+func (r *Router) mapContext(query string) (*types.ContextWindow, error) {
+	window, err := r.retrieved("map:"+query, func() (*types.ContextWindow, error) {
+		r.report(StageRetrieving, fmt.Sprintf("for up to %d summaries", r.mapGroups()))
+		return r.contextBuilder.BuildMapContext(query, r.mapGroups())
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.report(StageRetrieving, retrievedChunks(len(window.Chunks)))
+	r.signals.retrieval = window.Retrieval
+	r.signals.sources = window.Sources
+	if warning := compressedWarning(window); warning != "" {
+		r.signals.warnings = append(r.signals.warnings, warning)
+	}
+	return window, nil
+}
+
+// splitWindow packs the chunks of window into windows of at most budget
+// tokens, keeping each file's chunks together where they fit. Files are
+// taken in the order of their best chunk.
+func splitWindow(window *types.ContextWindow, budget int) []*types.ContextWindow {
+	var files []string
+	byFile := make(map[string][]types.ContextChunk)
+	for _, chunk := range window.Chunks {
+		if _, ok := byFile[chunk.File]; !ok {
+			files = append(files, chunk.File)
+		}
+		byFile[chunk.File] = append(byFile[chunk.File], chunk)
+	}
+
+	var groups []*types.ContextWindow
+	current := &types.ContextWindow{Retrieval: window.Retrieval}
+	add := func(chunk types.ContextChunk) {
+		if len(current.Chunks) > 0 && current.TotalTokens+chunkTokens(chunk) > budget {
+			groups = append(groups, current)
+			current = &types.ContextWindow{Retrieval: window.Retrieval}
+		}
+		current.Chunks = append(current.Chunks, chunk)
+		current.TotalTokens += chunkTokens(chunk)
+	}
+	for _, file := range files {
+		size := 0
+		for _, chunk := range byFile[file] {
+			size += chunkTokens(chunk)
+		}
+		// A file that fits a window but not this one starts the next
+		if len(current.Chunks) > 0 && size <= budget && current.TotalTokens+size > budget {
+			groups = append(groups, current)
+			current = &types.ContextWindow{Retrieval: window.Retrieval}
+		}
+		for _, chunk := range byFile[file] {
+			add(chunk)
+		}
+	}
+	if len(current.Chunks) > 0 {
+		groups = append(groups, current)
+	}
+	for _, g := range groups {
+		g.Sources = chunkSources(g.Chunks)
+	}
+	return groups
+}
+
+// handleMapReduce summarizes each window of context for the question and
+// answers over the summaries. Context that fits one window is answered
+// from directly.
+func (r *Router) handleMapReduce(query string, class *Classification) (string, error) {
+	window, err := r.mapContext(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to build context: %w", err)
+	}
+	groups := splitWindow(window, r.contextBuilder.tokenBudget(query))
+	if len(groups) < 2 {
+		return r.ask(window, r.buildAntiHallucinationPrompt(query, class, window))
+	}
+
+	summaries := make([]string, len(groups))
+	for i, group := range groups {
+		r.report(StageCalling, fmt.Sprintf("summarizing %d of %d", i+1, len(groups)))
+		summary, err := r.summarize(query, group)
+		if err != nil {
+			return "", fmt.Errorf("summarizing part %d: %w", i+1, err)
+		}
+		summaries[i] = summary
+	}
+	return r.complete(buildReducePrompt(query, groups, summaries))
+}
+
+// summarize asks summary_model what window says about the question
+func (r *Router) summarize(query string, window *types.ContextWindow) (string, error) {
+	prompt := buildMapPrompt(query)
+	// Reducing a window to the budget lists its files as the sources
+	sources := r.signals.sources
+	defer func() { r.signals.sources = sources }()
+	if err := r.checkBudget(window, prompt); err != nil {
+		return "", err
+	}
+	r.llmClient.SetModel(r.config.LLM.SummaryModel)
+	r.llmClient.SetMaxTokens(summaryTokens)
+	defer r.llmClient.SetModel("")
+	return r.llmClient.QueryStream(r.generationContext(), window, prompt, nil)
+}
+
+func buildMapPrompt(query string) string {
+	return fmt.Sprintf(`You are reading one part of a large codebase to help answer a question about it. Another step combines your notes with notes on the other parts.
+
+QUESTION: %s
+
+Write notes on this part of the code as it bears on the question:
+- The types and functions involved, with their files, and what each does
+- How they call or depend on each other, and on anything outside this part
+- Anything that looks like it connects to code not shown here
+
+Only describe what is in the context. If nothing here bears on the question, answer "Nothing relevant". Keep it under 300 words.
+`, query)
+}
+
+func buildReducePrompt(query string, groups []*types.ContextWindow, summaries []string) string {
+	var sb strings.Builder
+	sb.WriteString("You are answering a question about a codebase too large to show at once. Each part of the relevant code was read separately, these are the notes on each part.\n\n")
+	for i, summary := range summaries {
+		fmt.Fprintf(&sb, "### Part %d: %s\n%s\n\n", i+1, strings.Join(groups[i].Sources, ", "), strings.TrimSpace(summary))
+	}
+	fmt.Fprintf(&sb, "QUESTION: %s\n\n", query)
+	sb.WriteString("Answer from the notes, connecting the parts into one picture and naming the files each point comes from. ")
+	sb.WriteString("The notes are summaries, not the code: where they don't say how two parts connect, say so rather than guessing.\n")
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"sort"
+	"strings"
+)
+
+// The call graph keys functions by name, so Manager.Get and Cache.Get can
+// share one node. A graph with receiver qualified keys, "Manager.Get",
+// keeps them apart and is used as is. Otherwise "Manager.Get" resolves to
+// the bare node when Manager has a Get method, and the answer names the
+// other types with one, whose callers the graph can't tell apart.
+
+// resolvedFunction is a node the call graph resolved a name to
+type resolvedFunction struct {
+	Key    string // key of the node in Functions
+	Node   FunctionNode
+	Type   string   // the receiver type, "" for a plain function
+	Shared []string // other types with a method of the same name
+}
+
+// Name is the function as a person would write it, Type.Method for methods
+func (f resolvedFunction) Name() string {
+	if f.Type != "" && !strings.Contains(f.Key, ".") {
+		return f.Type + "." + f.Key
+	}
+	return f.Key
+}
+
+// resolveFunction finds name in the call graph: exactly, as Type.Method or
+// package.Function, or in any case
+func (g *CallGraph) resolveFunction(name string) (resolvedFunction, bool) {
+	name = strings.Trim(name, " \t?!.,;:'\"`()")
+	if node, ok := g.Functions[name]; ok {
+		return resolvedFunction{Key: name, Node: node}, true
+	}
+
+	if m := qualifiedNamePattern.FindStringSubmatch(name); m != nil && m[0] == name {
+		qualifier, method := m[1], m[2]
+		if typeName, ok := g.findType(qualifier); ok {
+			if node, ok := g.Functions[typeName+"."+method]; ok {
+				return resolvedFunction{Key: typeName + "." + method, Node: node, Type: typeName}, true
+			}
+			for _, m := range g.Types[typeName].Methods {
+				if !strings.EqualFold(m, method) {
+					continue
+				}
+				if node, ok := g.Functions[typeName+"."+m]; ok {
+					return resolvedFunction{Key: typeName + "." + m, Node: node, Type: typeName}, true
+				}
+				if node, ok := g.Functions[m]; ok {
+					return resolvedFunction{Key: m, Node: node, Type: typeName, Shared: g.methodOwners(m, typeName)}, true
+				}
+			}
+			// The type has no such method
+			return resolvedFunction{}, false
+		}
+		// Not a type, take it for a package
+		name = method
+	}
+
+	if node, ok := g.Functions[name]; ok {
+		return resolvedFunction{Key: name, Node: node}, true
+	}
+	keys := make([]string, 0, len(g.Functions))
+	for key := range g.Functions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.EqualFold(key, name) {
+			return resolvedFunction{Key: key, Node: g.Functions[key]}, true
+		}
+	}
+	return resolvedFunction{}, false
+}
+
+// findType is the type named name, in any case
+func (g *CallGraph) findType(name string) (string, bool) {
+	if _, ok := g.Types[name]; ok {
+		return name, true
+	}
+	for typeName := range g.Types {
+		if strings.EqualFold(typeName, name) {
+			return typeName, true
+		}
+	}
+	return "", false
+}
+
+// methodOwners is every type other than except with a method called method
+// that has no node of its own, sorted
+func (g *CallGraph) methodOwners(method, except string) []string {
+	var owners []string
+	for typeName, t := range g.Types {
+		if _, own := g.Functions[typeName+"."+method]; typeName == except || own {
+			continue
+		}
+		for _, m := range t.Methods {
+			if m == method {
+				owners = append(owners, typeName)
+				break
+			}
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
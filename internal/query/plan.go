@@ -0,0 +1,260 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"eulix/internal/llm"
+)
+
+// maxAffectedFiles keeps a widely called symbol from listing half the repo
+const maxAffectedFiles = 40
+
+// RefactorPlan is a refactoring broken into ordered tasks, shaped so each
+// task can become a ticket
+type RefactorPlan struct {
+	Goal          string         `json:"goal"`
+	Summary       string         `json:"summary"`
+	Tasks         []PlanTask     `json:"tasks"`
+	AffectedFiles []AffectedFile `json:"affected_files"`
+	Risks         []string       `json:"risks,omitempty"`
+}
+
+type PlanTask struct {
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Files       []string `json:"files,omitempty"`
+	DependsOn   []int    `json:"depends_on,omitempty"`
+	Risk        string   `json:"risk"` // low, medium or high
+	RiskNotes   string   `json:"risk_notes,omitempty"`
+}
+
+// AffectedFile is a file the refactoring reaches and why, from the index
+// and call graph rather than the model
+type AffectedFile struct {
+	File    string   `json:"file"`
+	Reasons []string `json:"reasons"`
+}
+
+const planInstructions = `Plan the refactoring below as ordered tasks a team can pick up one at a time.
+Each task leaves the code building and tested, earlier tasks make later ones
+safe (add the new thing, move callers over, then remove the old one).
+
+Reply with JSON only, in exactly this shape:
+{
+  "summary": "two or three sentences on the approach",
+  "tasks": [
+    {
+      "id": 1,
+      "title": "imperative, under 70 characters",
+      "description": "what to change and how to verify it",
+      "files": ["path/from/the/lists/below"],
+      "depends_on": [],
+      "risk": "low|medium|high",
+      "risk_notes": "what could break, callers affected"
+    }
+  ],
+  "risks": ["risks for the plan as a whole"]
+}
+
+Only use files from the affected files and context below. Callers listed
+there must be updated by some task.`
+
+// PlanRefactor asks for a plan to reach goal, with the files it affects
+// worked out from where the symbols in goal are defined and called
+func (r *Router) PlanRefactor(goal string) (*RefactorPlan, error) {
+	if err := r.ensureContextBuilder(); err != nil {
+		return nil, err
+	}
+
+	r.signals = answerSignals{retrieval: -1, classifier: -1, quiet: true, waived: r.budgetWaived}
+	r.budgetWaived = false
+	defer r.interruptible()()
+
+	class := r.classifier.Classify(goal)
+	r.signals.class = class
+	window, err := r.buildContext(goal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build context: %w", err)
+	}
+
+	symbols := class.Symbols
+	if entity := followUpEntity(goal, class); len(symbols) == 0 && entity != "" {
+		symbols = []string{entity}
+	}
+	affected := r.affectedFiles(symbols, window.Sources)
+
+	prompt := fmt.Sprintf("%s\n\nGOAL: %s\n\nAFFECTED FILES:\n%s\nCONTEXT:\n%s",
+		planInstructions, goal, formatAffected(affected), window.String())
+	reply, err := r.complete(prompt)
+	if errors.Is(err, llm.ErrInterrupted) {
+		return nil, fmt.Errorf("planning was interrupted")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LLM query failed: %w", err)
+	}
+
+	plan, err := parsePlan(reply)
+	if err != nil {
+		return nil, err
+	}
+	plan.Goal = goal
+	plan.AffectedFiles = affected
+	return plan, nil
+}
+
+// affectedFiles lists where symbols are defined, where their callers are and
+// the other files retrieved for the goal, in that order
+func (r *Router) affectedFiles(symbols, sources []string) []AffectedFile {
+	var files []AffectedFile
+	index := make(map[string]int)
+	add := func(file, reason string) {
+		if file == "" {
+			return
+		}
+		i, ok := index[file]
+		if !ok {
+			if len(files) >= maxAffectedFiles {
+				return
+			}
+			i = len(files)
+			index[file] = i
+			files = append(files, AffectedFile{File: file})
+		}
+		if !containsString(files[i].Reasons, reason) {
+			files[i].Reasons = append(files[i].Reasons, reason)
+		}
+	}
+
+	for _, symbol := range symbols {
+		for _, loc := range append(r.kbIndex.FunctionsByName[symbol], r.kbIndex.TypesByName[symbol]...) {
+			file, _ := splitLocation(loc)
+			add(file, "defines "+symbol)
+		}
+	}
+	for _, symbol := range symbols {
+		callers, _ := r.neighbours(symbol)
+		sort.Strings(callers)
+		for _, caller := range callers {
+			for _, loc := range r.kbIndex.FunctionsByName[caller] {
+				file, _ := splitLocation(loc)
+				add(file, fmt.Sprintf("%s calls %s", caller, symbol))
+			}
+		}
+	}
+	for _, source := range sources {
+		add(source, "related code")
+	}
+	return files
+}
+
+func formatAffected(files []AffectedFile) string {
+	if len(files) == 0 {
+		return "  none found in the index\n"
+	}
+	var sb strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&sb, "  %s: %s\n", f.File, strings.Join(f.Reasons, "; "))
+	}
+	return sb.String()
+}
+
+// parsePlan reads the model's JSON, numbering tasks 1..n in order and
+// dropping dependencies on tasks that don't come earlier
+func parsePlan(reply string) (*RefactorPlan, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the LLM didn't reply with a plan")
+	}
+
+	var plan RefactorPlan
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("the LLM's plan isn't valid JSON: %w", err)
+	}
+	if len(plan.Tasks) == 0 {
+		return nil, fmt.Errorf("the LLM's plan has no tasks")
+	}
+
+	renumbered := make(map[int]int)
+	for i := range plan.Tasks {
+		renumbered[plan.Tasks[i].ID] = i + 1
+	}
+	for i := range plan.Tasks {
+		t := &plan.Tasks[i]
+		t.ID = i + 1
+
+		var deps []int
+		for _, dep := range t.DependsOn {
+			if id, ok := renumbered[dep]; ok && id < t.ID && !containsInt(deps, id) {
+				deps = append(deps, id)
+			}
+		}
+		t.DependsOn = deps
+
+		t.Risk = strings.ToLower(strings.TrimSpace(t.Risk))
+		if t.Risk != "low" && t.Risk != "high" {
+			t.Risk = "medium"
+		}
+	}
+	return &plan, nil
+}
+
+// Markdown renders the plan with a section per task
+func (p *RefactorPlan) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Refactoring plan: %s\n\n", p.Goal)
+	if p.Summary != "" {
+		fmt.Fprintf(&sb, "%s\n\n", p.Summary)
+	}
+
+	sb.WriteString("## Tasks\n")
+	for _, t := range p.Tasks {
+		fmt.Fprintf(&sb, "\n### %d. %s\n\n", t.ID, t.Title)
+		fmt.Fprintf(&sb, "- Risk: %s", t.Risk)
+		if t.RiskNotes != "" {
+			fmt.Fprintf(&sb, ", %s", t.RiskNotes)
+		}
+		sb.WriteString("\n")
+		if len(t.DependsOn) > 0 {
+			deps := make([]string, len(t.DependsOn))
+			for i, dep := range t.DependsOn {
+				deps[i] = fmt.Sprintf("%d", dep)
+			}
+			fmt.Fprintf(&sb, "- Depends on: %s\n", strings.Join(deps, ", "))
+		}
+		if len(t.Files) > 0 {
+			fmt.Fprintf(&sb, "- Files: `%s`\n", strings.Join(t.Files, "`, `"))
+		}
+		if t.Description != "" {
+			fmt.Fprintf(&sb, "\n%s\n", t.Description)
+		}
+	}
+
+	if len(p.AffectedFiles) > 0 {
+		sb.WriteString("\n## Affected files\n\n")
+		for _, f := range p.AffectedFiles {
+			fmt.Fprintf(&sb, "- `%s`: %s\n", f.File, strings.Join(f.Reasons, "; "))
+		}
+	}
+
+	if len(p.Risks) > 0 {
+		sb.WriteString("\n## Risks\n\n")
+		for _, risk := range p.Risks {
+			fmt.Fprintf(&sb, "- %s\n", risk)
+		}
+	}
+	return sb.String()
+}
+
+func containsInt(list []int, n int) bool {
+	for _, item := range list {
+		if item == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package query
+
+import "fmt"
+
+// Stage is a step of answering a question. Stages are reported while a
+// question is being answered so a UI can show more than a spinner.
+type Stage string
+
+const (
+	StageClassifying Stage = "classifying"
+	StageCache       Stage = "checking cache"
+	StageRetrieving  Stage = "retrieving"
+	StageEmbedding   Stage = "embedding query"
+	StageTool        Stage = "looking up"
+	StageCalling     Stage = "calling model"
+	StageRendering   Stage = "rendering"
+)
+
+// Progress is one stage event, Detail says what the stage is working on,
+// like how many chunks were retrieved
+type Progress struct {
+	Stage  Stage  `json:"stage"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (p Progress) String() string {
+	if p.Detail == "" {
+		return string(p.Stage)
+	}
+	return string(p.Stage) + " " + p.Detail
+}
+
+// OnProgress sets the callback for stage events, nil turns them off. It is
+// called on the goroutine answering the question and should return quickly.
+func (r *Router) OnProgress(fn func(Progress)) {
+	r.onProgress = fn
+	if r.contextBuilder != nil {
+		r.contextBuilder.progress = fn
+	}
+}
+
+func (r *Router) report(stage Stage, detail string) {
+	if r.onProgress != nil {
+		r.onProgress(Progress{Stage: stage, Detail: detail})
+	}
+}
+
+func (cb *ContextBuilder) report(stage Stage, detail string) {
+	if cb.progress != nil {
+		cb.progress(Progress{Stage: stage, Detail: detail})
+	}
+}
+
+func retrievedChunks(n int) string {
+	if n == 1 {
+		return "1 chunk"
+	}
+	return fmt.Sprintf("%d chunks", n)
+}
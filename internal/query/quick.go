@@ -0,0 +1,94 @@
+package query
+
+import (
+	"eulix/internal/config"
+	"eulix/internal/owners"
+)
+
+// quickConfidence is the least classifier confidence QuickAnswer trusts, the
+// pattern matches for where and who-calls questions score 0.95
+const quickConfidence = 0.9
+
+// QuickAnswer answers a where-is or who-calls question from kb_index.json
+// and kb_call_graph.json alone: no embeddings, cache, checksum or LLM. It
+// returns nil without an error when the question needs the full router,
+// because it isn't a plain lookup, names more than one symbol or the symbol
+// isn't in the index under exactly that name. Hooks have to run for every
+// question, with any configured it always returns nil. Quick answers don't
+// go into the query history.
+func QuickAnswer(eulixDir string, cfg *config.Config, query string) (*Answer, error) {
+	if len(cfg.Hooks) > 0 {
+		return nil, nil
+	}
+
+	kbIndex, err := loadKBIndex(eulixDir)
+	if err != nil {
+		return nil, err
+	}
+	classifier, err := QuerySheriff("")
+	if err != nil {
+		return nil, err
+	}
+	classifier.addSymbols(kbIndex.FunctionsByName, kbIndex.TypesByName)
+	classifier.addGlobals(kbIndex.GlobalsByName)
+
+	class := classifier.Classify(query)
+	if class.Confidence < quickConfidence || len(class.Symbols) > 1 {
+		return nil, nil
+	}
+	entity := extractEntityName(query)
+	if len(class.Symbols) == 1 {
+		entity = class.Symbols[0]
+	}
+	if entity == "" {
+		return nil, nil
+	}
+
+	r := &Router{eulixDir: eulixDir, config: cfg, kbIndex: kbIndex, classifier: classifier}
+	r.signals = answerSignals{retrieval: -1, classifier: class.Confidence, class: class}
+
+	var text string
+	switch class.Type {
+	case QueryTypeLocation:
+		if _, ok := kbIndex.Lookup(qualifiedSymbol(query, entity)); !ok {
+			return nil, nil
+		}
+		if text, err = r.handleLocation(query, class); err != nil {
+			return nil, err
+		}
+		r.callGraph, _ = loadCallGraph(eulixDir)
+
+	case QueryTypeUsage:
+		if r.callGraph, err = loadCallGraph(eulixDir); err != nil {
+			return nil, nil
+		}
+		_, function := r.callGraph.resolveFunction(qualifiedSymbol(query, entity))
+		_, typ := r.callGraph.Types[entity]
+		if !function && !typ {
+			return nil, nil
+		}
+		if text, err = r.handleUsage(query, class); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, nil
+	}
+	if r.callGraph == nil {
+		r.callGraph = &CallGraph{}
+	}
+
+	codeOwners, err := owners.Load(eulixDir)
+	if err != nil {
+		codeOwners = &owners.Map{}
+	}
+
+	return &Answer{
+		Text:       text,
+		Confidence: scoreConfidence(r.signals.retrieval, r.signals.classifier, -1),
+		FollowUps:  r.followUps(query),
+		Entities:   answerEntities(query, class),
+		Sources:    r.signals.sources,
+		Owners:     codeOwners.All(r.signals.sources),
+	}, nil
+}
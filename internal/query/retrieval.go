@@ -0,0 +1,110 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"eulix/internal/config"
+)
+
+// Retrieval is tuned per question: a location needs the definition and
+// little else, an architecture question is better served by many small
+// chunks across the project than a few whole files. [retrieval] in
+// eulix.toml sets the base, typeRetrieval goes on top, then
+// [retrieval.types.<type>].
+
+// typeRetrieval is eulix's own tuning for the query types that need it.
+// Lookups answer in a few lines, architecture and data flow answers run
+// long and get the room to finish.
+var typeRetrieval = map[QueryType]config.RetrievalParams{
+	QueryTypeLocation:     {TopK: 20, MaxResponse: 500},
+	QueryTypeUsage:        {TopK: 50, MaxResponse: 1000},
+	QueryTypeArchitecture: {TopK: 200, MaxChunkTokens: 400, MaxResponse: 4000},
+	QueryTypeDataFlow:     {TopK: 150, MaxResponse: 3000},
+}
+
+// priorityShare scales the budget share by the classifier's priority, 1 is
+// the most urgent. The default understanding question is 3 and keeps it all.
+var priorityShare = map[int]float64{4: 0.8, 5: 0.6}
+
+// retrievalParams are the knobs in effect for a question
+type retrievalParams struct {
+	topK           int
+	similarity     float64
+	budgetShare    float64
+	maxChunkTokens int
+	maxResponse    int
+}
+
+// queryTypeNames maps the [retrieval.types] keys to query types
+func queryTypeNames() map[string]QueryType {
+	names := make(map[string]QueryType)
+	for qt := QueryTypeLocation; qt <= QueryTypeFile; qt++ {
+		names[strings.ToLower(qt.String())] = qt
+	}
+	return names
+}
+
+// checkRetrievalTypes warns about [retrieval.types] keys that aren't a
+// query type, they would be ignored without a word otherwise
+func checkRetrievalTypes(cfg config.RetrievalConfig) []string {
+	known := queryTypeNames()
+	var warnings []string
+	for name := range cfg.Types {
+		if _, ok := known[strings.ToLower(name)]; !ok {
+			valid := make([]string, 0, len(known))
+			for n := range known {
+				valid = append(valid, n)
+			}
+			sort.Strings(valid)
+			warnings = append(warnings, fmt.Sprintf("[retrieval.types.%s] is not a query type, use one of %s", name, strings.Join(valid, ", ")))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// Tune sets the retrieval parameters for a classified question, nil
+// classification uses the base ones
+func (cb *ContextBuilder) Tune(class *Classification) {
+	cb.params = tunedParams(cb.config.Retrieval, class)
+}
+
+// tunedParams are the retrieval parameters for a classified question
+func tunedParams(cfg config.RetrievalConfig, class *Classification) retrievalParams {
+	p := retrievalParams{topK: baseCandidates, similarity: baseSimilarity, budgetShare: baseBudgetShare, maxResponse: baseMaxResponse}
+	p.apply(cfg.RetrievalParams)
+
+	if class != nil {
+		p.apply(typeRetrieval[class.Type])
+		for name, params := range cfg.Types {
+			if strings.EqualFold(name, class.Type.String()) {
+				p.apply(params)
+			}
+		}
+		if scale, ok := priorityShare[class.Priority]; ok && !cfg.IgnorePriority {
+			p.budgetShare *= scale
+		}
+	}
+	return p
+}
+
+// apply takes the set values of params, ignoring ones out of range
+func (p *retrievalParams) apply(params config.RetrievalParams) {
+	if params.TopK > 0 {
+		p.topK = params.TopK
+	}
+	if params.Similarity > 0 && params.Similarity <= 1 {
+		p.similarity = params.Similarity
+	}
+	if params.BudgetShare > 0 && params.BudgetShare <= 1 {
+		p.budgetShare = params.BudgetShare
+	}
+	if params.MaxChunkTokens > 0 {
+		p.maxChunkTokens = params.MaxChunkTokens
+	}
+	if params.MaxResponse > 0 {
+		p.maxResponse = params.MaxResponse
+	}
+}
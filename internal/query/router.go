@@ -2,15 +2,22 @@ package query
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"sort"
+	"time"
 
 	"eulix/internal/cache"
+	"eulix/internal/checksum"
 	"eulix/internal/config"
+	"eulix/internal/hooks"
 	"eulix/internal/llm"
+	"eulix/internal/owners"
+	"eulix/internal/schema"
 	"eulix/internal/types"
 )
 
@@ -21,14 +28,29 @@ func (r *Router) SetCurrentChecksum(checksum string) {
 }
 
 func QueryTrafficController(eulixDir string, cfg *config.Config, llmClient *llm.Client, cacheManager *cache.Manager) (*Router, error) {
+	if err := schema.Check(eulixDir); err != nil {
+		return nil, err
+	}
+
 	kbIndex, err := loadKBIndex(eulixDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load KB index: %w", err)
 	}
 
-	callGraph, err := loadCallGraph(eulixDir)
+	// The call graph is optional, without it usage and dependency questions
+	// are answered from context instead
+	callGraph, graphWarning := optionalCallGraph(eulixDir)
+
+	// So is the schema, most projects have no tables
+	dbSchema, err := loadDBSchema(eulixDir)
+	if err != nil {
+		dbSchema = &DBSchema{}
+	}
+
+	// Without ownership rules answers just don't say who owns the code
+	codeOwners, err := owners.Load(eulixDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load call graph: %w", err)
+		codeOwners = &owners.Map{}
 	}
 
 	kbIndexPath := filepath.Join(eulixDir, "kb_index.json")
@@ -36,6 +58,9 @@ func QueryTrafficController(eulixDir string, cfg *config.Config, llmClient *llm.
 	if err != nil {
 		return nil, fmt.Errorf("failed to create classifier: %w", err)
 	}
+	classifier.schema = dbSchema
+
+	llmClient.SetIdentifiers(kbIndex.symbolNames())
 
 	return &Router{
 		eulixDir:       eulixDir,
@@ -46,6 +71,10 @@ func QueryTrafficController(eulixDir string, cfg *config.Config, llmClient *llm.
 		contextBuilder: nil,
 		kbIndex:        kbIndex,
 		callGraph:      callGraph,
+		graphWarning:   graphWarning,
+		dbSchema:       dbSchema,
+		owners:         codeOwners,
+		hooks:          hooks.Bellboy(cfg.Hooks, cfg.LLM.Privacy.LocalOnly),
 	}, nil
 }
 
@@ -64,6 +93,22 @@ func loadKBIndex(eulixDir string) (*KBIndex, error) {
 	return &index, nil
 }
 
+// symbolNames lists every function, type and global in the index, for the
+// LLM client to anonymize when [llm.privacy] asks for it
+func (idx *KBIndex) symbolNames() []string {
+	names := make([]string, 0, len(idx.FunctionsByName)+len(idx.TypesByName)+len(idx.GlobalsByName))
+	for name := range idx.FunctionsByName {
+		names = append(names, name)
+	}
+	for name := range idx.TypesByName {
+		names = append(names, name)
+	}
+	for name := range idx.GlobalsByName {
+		names = append(names, name)
+	}
+	return names
+}
+
 func loadCallGraph(eulixDir string) (*CallGraph, error) {
 	graphPath := filepath.Join(eulixDir, "kb_call_graph.json")
 	data, err := os.ReadFile(graphPath)
@@ -73,12 +118,25 @@ func loadCallGraph(eulixDir string) (*CallGraph, error) {
 
 	var graph CallGraph
 	if err := json.Unmarshal(data, &graph); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("kb_call_graph.json is corrupt, run 'eulix analyze': %w", err)
 	}
 
 	return &graph, nil
 }
 
+// optionalCallGraph is the call graph, empty when the KB has none. One that
+// is there but can't be read is also empty, with a warning saying why.
+func optionalCallGraph(eulixDir string) (*CallGraph, string) {
+	graph, err := loadCallGraph(eulixDir)
+	switch {
+	case err == nil:
+		return graph, ""
+	case errors.Is(err, fs.ErrNotExist):
+		return &CallGraph{}, ""
+	}
+	return &CallGraph{}, fmt.Sprintf("Call graph disabled, usage and dependency questions are answered from context: %v", err)
+}
+
 func (r *Router) ensureContextBuilder() error {
 	if r.contextBuilder != nil {
 		return nil
@@ -89,25 +147,263 @@ func (r *Router) ensureContextBuilder() error {
 		return fmt.Errorf("failed to initialize context builder: %w", err)
 	}
 
+	contextBuilder.progress = r.onProgress
+	r.contextBuilder = contextBuilder
+	return nil
+}
+
+// Warm loads the context builder (chunks, embeddings, KB) up front instead of
+// on the first query, used by long running processes like the daemon
+func (r *Router) Warm() error {
+	return r.ensureContextBuilder()
+}
+
+// SetScope keeps retrieval for the following questions to files under
+// paths, none for the whole project
+func (r *Router) SetScope(paths []string) error {
+	if err := r.ensureContextBuilder(); err != nil {
+		return err
+	}
+	r.contextBuilder.SetScope(paths)
+	return nil
+}
+
+// RequireLocal fails when the configured model isn't on this machine, for
+// callers that were asked to keep code local
+func (r *Router) RequireLocal() error {
+	return r.llmClient.RequireLocal()
+}
+
+// Reload re-reads the KB index, call graph, classifier and (if it was loaded)
+// the context builder from disk, so a long running session picks up a new
+// analyze. Nothing is swapped unless everything loads.
+func (r *Router) Reload() error {
+	if err := schema.Check(r.eulixDir); err != nil {
+		return err
+	}
+
+	kbIndex, err := loadKBIndex(r.eulixDir)
+	if err != nil {
+		return fmt.Errorf("failed to load KB index: %w", err)
+	}
+
+	callGraph, graphWarning := optionalCallGraph(r.eulixDir)
+
+	dbSchema, err := loadDBSchema(r.eulixDir)
+	if err != nil {
+		dbSchema = &DBSchema{}
+	}
+
+	codeOwners, err := owners.Load(r.eulixDir)
+	if err != nil {
+		codeOwners = &owners.Map{}
+	}
+
+	classifier, err := QuerySheriff(filepath.Join(r.eulixDir, "kb_index.json"))
+	if err != nil {
+		return fmt.Errorf("failed to create classifier: %w", err)
+	}
+	classifier.schema = dbSchema
+
+	var contextBuilder *ContextBuilder
+	if r.contextBuilder != nil {
+		contextBuilder, err = ContextWindowCreator(r.eulixDir, r.config, r.llmClient)
+		if err != nil {
+			return fmt.Errorf("failed to initialize context builder: %w", err)
+		}
+		contextBuilder.progress = r.onProgress
+		r.contextBuilder.Close()
+	}
+
+	r.kbIndex = kbIndex
+	r.callGraph = callGraph
+	r.graphWarning = graphWarning
+	r.dbSchema = dbSchema
+	r.owners = codeOwners
+	r.classifier = classifier
 	r.contextBuilder = contextBuilder
+	r.llmClient.SetIdentifiers(kbIndex.symbolNames())
+
+	// Answers cached against the old KB no longer apply
+	if stored, err := checksum.HashHound(filepath.Dir(r.eulixDir)).Load(); err == nil {
+		r.currentChecksum = stored.Hash
+	}
+
 	return nil
 }
 
+// Warnings reports what limits search in the loaded KB, like chunks without
+// vectors. It loads the context builder if needed.
+func (r *Router) Warnings() []string {
+	var warnings []string
+	if r.graphWarning != "" {
+		warnings = append(warnings, r.graphWarning)
+	}
+	if err := r.ensureContextBuilder(); err != nil {
+		return append(warnings, err.Error())
+	}
+	return append(warnings, r.contextBuilder.Warnings()...)
+}
+
+// KBModTime is when the last analyze finished, used to notice a new KB
+func (r *Router) KBModTime() time.Time {
+	info, err := os.Stat(filepath.Join(r.eulixDir, "checksum.json"))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Search returns the context chunks a query would be answered from, without
+// calling the LLM
+func (r *Router) Search(query string) (*types.ContextWindow, error) {
+	if err := r.ensureContextBuilder(); err != nil {
+		return nil, err
+	}
+	r.contextBuilder.Tune(r.classifier.Classify(query))
+	r.contextBuilder.SetExpansion(0)
+	return r.contextBuilder.BuildContext(query)
+}
+
+// Query answers a question as text, with the confidence line appended
 func (r *Router) Query(query string) (string, error) {
-	// Check cache first
-	if r.cache != nil && r.currentChecksum != "" {
-		cached, found, err := r.cache.Get(query, r.currentChecksum)
-		if err == nil && found {
-			return cached, nil
+	answer, err := r.Answer(query)
+	if err != nil {
+		return "", err
+	}
+	return answer.String(), nil
+}
+
+func (r *Router) route(query string, recent []string) (string, error) {
+	// A question in another language is classified and searched in English,
+	// see translate.go. One asked before is answered from the cache without
+	// translating it again.
+	asked := query
+	r.signals.language = detectLanguage(query)
+	if r.translates() {
+		if response, ok := r.cachedTranslated(asked); ok {
+			return response, nil
 		}
+		translated, err := r.translated(query)
+		if err != nil {
+			return "", err
+		}
+		query = translated
 	}
 
-	// Classify query
+	// Classify query, also done on cache hits so the answer still gets a confidence
+	r.report(StageClassifying, "")
 	classification := r.classifier.Classify(query)
+	r.signals.classifier = classification.Confidence
+	r.signals.class = classification
+
+	// "what about its error handling?" means the symbols of the last answer,
+	// cache under the resolved question so another "it" doesn't get this answer
+	cacheKey := asked
+	if resolved := resolveReferences(query, classification, recent); resolved != "" {
+		r.signals.resolved = resolved
+		cacheKey = resolved
+	}
+	if r.signals.language != "" {
+		// The resolved question is English, its answer isn't
+		cacheKey = r.signals.language + ": " + cacheKey
+	}
+
+	// Before the cache, an answer about every ParseConfig isn't the one
+	// about the ParseConfig the user picks
+	if err := r.disambiguated(classification); err != nil {
+		return "", err
+	}
+
+	// Session memory, notes and picks go into the prompt and change the
+	// answer, it is only reused with the same ones
+	answerKey := withMemory(cacheKey, r.signals.memory)
+
+	// Check cache first, unless this is a retry with wider retrieval
+	if r.cache != nil && r.currentChecksum != "" && r.signals.expansion == 0 {
+		r.report(StageCache, "")
+		cached, found, err := r.cache.Lookup(answerKey, r.currentChecksum)
+		if err == nil && found {
+			if r.fresh(cached) {
+				return r.cacheHit(cacheKey, cached), nil
+			}
+			// Same project checksum but the KB's chunks of its sources changed
+			r.signals.previous = cached
+		} else if previous, found := r.cache.Previous(cacheKey, r.currentChecksum); found {
+			// Asked before the code changed, the answer gets compared with that one
+			r.signals.previous = previous
+		}
+
+		// A correction of the last answer is shown with the new one, and the
+		// model hears about it
+		if correction, found := r.cache.CorrectionFor(cacheKey, "", r.currentChecksum); found {
+			r.signals.correction = correction
+			r.signals.memory += fmt.Sprintf("USER CORRECTION (of an earlier answer to this question, take it as true):\n- %s\n\n", correction.Text)
+		}
+	}
 
 	var response string
 	var err error
 
+	// Questions spanning several parts of the code get a retrieval per part,
+	// decompose leaves lookups alone
+	if subQueries := decompose(query, classification); len(subQueries) > 1 {
+		if err := r.ensureContextBuilder(); err != nil {
+			return "", err
+		}
+		response, err = r.handleDecomposed(query, classification, subQueries)
+	} else if r.mapReduces(classification) {
+		if err := r.ensureContextBuilder(); err != nil {
+			return "", err
+		}
+		response, err = r.handleMapReduce(query, classification)
+	} else {
+		response, err = r.dispatch(query, classification)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	// Cache the response with current checksum
+	if r.cache != nil && r.currentChecksum != "" {
+		if err := r.cache.Set(answerKey, response, r.currentChecksum, r.signals.sources, r.sourcesFingerprint(r.signals.sources), classification.Type.String()); err != nil {
+			// Log error but don't fail the query
+			// TODO add failed logger
+		}
+	}
+
+	return response, nil
+}
+
+// fresh reports whether a cached answer still holds: the KB's chunks of its
+// sources are the ones it was given. Answers that used no context only
+// depend on the checksum.
+func (r *Router) fresh(cached *cache.CacheEntry) bool {
+	return cached.Fingerprint == "" || cached.Fingerprint == r.sourcesFingerprint(cached.Sources)
+}
+
+// cacheHit answers with cached, along with any correction of it
+func (r *Router) cacheHit(cacheKey string, cached *cache.CacheEntry) string {
+	r.cache.MarkHit(r.signals.logged)
+	r.signals.correction, _ = r.cache.CorrectionFor(cacheKey, cached.Response, r.currentChecksum)
+	return cached.Response
+}
+
+// dispatch sends a query to the handler for its type
+func (r *Router) dispatch(query string, classification *Classification) (string, error) {
+	var response string
+	var err error
+
+	// Lookups answer from the index and call graph, only load the chunks and
+	// embeddings for questions that need them. A lookup the index can't
+	// answer loads them itself, see answerFromContext.
+	if classification.NeedsContext {
+		if err := r.ensureContextBuilder(); err != nil {
+			return "", err
+		}
+	}
+
 	// Route to appropriate handler
 	switch classification.Type {
 	case QueryTypeLocation:
@@ -115,93 +411,46 @@ func (r *Router) Query(query string) (string, error) {
 	case QueryTypeUsage:
 		response, err = r.handleUsage(query, classification)
 	case QueryTypeUnderstanding:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleUnderstanding(query, classification)
 	case QueryTypeImplementation:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleImplementation(query, classification)
 	case QueryTypeArchitecture:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleArchitecture(query, classification)
 	case QueryTypeDebug:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleDebug(query, classification)
 	case QueryTypeComparison:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleComparison(query, classification)
 	case QueryTypeDependency:
 		response, err = r.handleDependency(query, classification)
+	case QueryTypeSchema:
+		response, err = r.handleSchema(query, classification)
 	case QueryTypeRefactoring:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleRefactoring(query, classification)
 	case QueryTypePerformance:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handlePerformance(query, classification)
 	case QueryTypeDataFlow:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleDataFlow(query, classification)
 	case QueryTypeSecurity:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleSecurity(query, classification)
 	case QueryTypeDocumentation:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleDocumentation(query, classification)
 	case QueryTypeExample:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleExample(query, classification)
 	case QueryTypeTesting:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleTesting(query, classification)
+	case QueryTypeFile:
+		response, err = r.handleFile(query, classification)
 	default:
-		if err := r.ensureContextBuilder(); err != nil {
-			return "", err
-		}
 		response, err = r.handleUnderstanding(query, classification)
 	}
 
-	if err != nil {
-		return "", err
-	}
-
-	// Cache the response with current checksum
-	if r.cache != nil && r.currentChecksum != "" {
-		if err := r.cache.Set(query, response, r.currentChecksum); err != nil {
-			// Log error but don't fail the query
-			// TODO add failed logger
-		}
-	}
-
-	return response, nil
+	return response, err
 }
 
 func (r *Router) handleLocation(query string, class *Classification) (string, error) {
 	var entity string
 	if len(class.Symbols) > 0 {
-		entity = class.Symbols[0]
+		entity = qualifiedSymbol(query, class.Symbols[0])
 	} else {
 		entity = extractEntityName(query)
 	}
@@ -212,23 +461,37 @@ func (r *Router) handleLocation(query string, class *Classification) (string, er
 
 	var results []string
 
-	if locations, ok := r.kbIndex.FunctionsByName[entity]; ok {
-		results = append(results, fmt.Sprintf("Function '%s' found at:", entity))
-		for _, loc := range locations {
+	match, _ := r.kbIndex.Lookup(entity)
+	if len(match.Functions) > 0 {
+		results = append(results, fmt.Sprintf("Function '%s' found at:", match.Name))
+		for _, loc := range match.Functions {
+			results = append(results, fmt.Sprintf("%s", loc))
+		}
+		r.addLocationSources(match.Functions)
+	}
+
+	if len(match.Types) > 0 {
+		results = append(results, fmt.Sprintf("Type '%s' found at:", match.Name))
+		for _, loc := range match.Types {
 			results = append(results, fmt.Sprintf("%s", loc))
 		}
+		r.addLocationSources(match.Types)
 	}
 
-	if locations, ok := r.kbIndex.TypesByName[entity]; ok {
-		results = append(results, fmt.Sprintf("Type '%s' found at:", entity))
-		for _, loc := range locations {
+	if len(match.Globals) > 0 {
+		results = append(results, fmt.Sprintf("Variable or constant '%s' found at:", match.Name))
+		for _, loc := range match.Globals {
 			results = append(results, fmt.Sprintf("%s", loc))
 		}
+		r.addLocationSources(match.Globals)
 	}
 
+	r.signals.retrieval = 1.0
 	if len(results) == 0 {
+		r.signals.retrieval = 0
 		matches := r.fuzzySearch(entity)
 		if len(matches) > 0 {
+			r.signals.retrieval = 0.5
 			results = append(results, fmt.Sprintf("No exact match for '%s'. Did you mean:", entity))
 			for _, match := range matches {
 				results = append(results, fmt.Sprintf("%s", match))
@@ -241,10 +504,23 @@ func (r *Router) handleLocation(query string, class *Classification) (string, er
 	return strings.Join(results, "\n"), nil
 }
 
+// addLocationSources records the files of file:line index entries as
+// sources, so location answers say who owns them
+func (r *Router) addLocationSources(locations []string) {
+	for _, loc := range locations {
+		if i := strings.LastIndex(loc, ":"); i > 0 {
+			loc = loc[:i]
+		}
+		if !containsString(r.signals.sources, loc) {
+			r.signals.sources = append(r.signals.sources, loc)
+		}
+	}
+}
+
 func (r *Router) handleUsage(query string, class *Classification) (string, error) {
 	var entity string
 	if len(class.Symbols) > 0 {
-		entity = class.Symbols[0]
+		entity = qualifiedSymbol(query, class.Symbols[0])
 	} else {
 		entity = extractEntityName(query)
 	}
@@ -255,9 +531,16 @@ func (r *Router) handleUsage(query string, class *Classification) (string, error
 
 	var results []string
 
-	if funcNode, ok := r.callGraph.Functions[entity]; ok {
-		results = append(results, fmt.Sprintf("Usage Analysis for '%s':", entity))
+	r.signals.retrieval = 1.0
+	if fn, ok := r.callGraph.resolveFunction(entity); ok {
+		funcNode := fn.Node
+		results = append(results, fmt.Sprintf("Usage Analysis for '%s':", fn.Name()))
 		results = append(results, fmt.Sprintf("Location: %s", funcNode.Location))
+		if len(fn.Shared) > 0 {
+			r.signals.retrieval = 0.7
+			results = append(results, fmt.Sprintf("Note: %s also has a %s method, the call graph doesn't tell their callers apart",
+				strings.Join(fn.Shared, ", "), fn.Key))
+		}
 		results = append(results, "")
 
 		if len(funcNode.Calls) > 0 {
@@ -293,7 +576,10 @@ func (r *Router) handleUsage(query string, class *Classification) (string, error
 			for _, caller := range callers {
 				results = append(results, fmt.Sprintf("  â† %s", caller))
 			}
+		} else if !r.callGraph.loaded() {
+			return r.answerFromContext(query, class)
 		} else {
+			r.signals.retrieval = 0
 			return fmt.Sprintf("No usage information found for '%s'", entity), nil
 		}
 	}
@@ -302,14 +588,14 @@ func (r *Router) handleUsage(query string, class *Classification) (string, error
 }
 
 func (r *Router) handleUnderstanding(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := r.buildAntiHallucinationPrompt(query, class, context)
 
-	response, err := r.llmClient.Query(context, prompt)
+	response, err := r.ask(context, prompt)
 	if err != nil {
 		return "", fmt.Errorf("LLM query failed: %w", err)
 	}
@@ -317,6 +603,28 @@ func (r *Router) handleUnderstanding(query string, class *Classification) (strin
 	return response, nil
 }
 
+// answerFromContext is the fallback for graph based handlers when the KB has
+// no call graph: search the chunks and let the LLM answer from them. A
+// lookup gets a prompt asking for the place, not an explanation.
+func (r *Router) answerFromContext(query string, class *Classification) (string, error) {
+	if err := r.ensureContextBuilder(); err != nil {
+		return "", err
+	}
+	if class.NeedsContext {
+		return r.handleUnderstanding(query, class)
+	}
+
+	context, err := r.buildContext(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to build context: %w", err)
+	}
+	response, err := r.ask(context, buildLookupPrompt(query, class))
+	if err != nil {
+		return "", fmt.Errorf("LLM query failed: %w", err)
+	}
+	return response, nil
+}
+
 func (r *Router) handleImplementation(query string, class *Classification) (string, error) {
 	var relevantFiles []string
 	for _, symbol := range class.Symbols {
@@ -328,15 +636,14 @@ func (r *Router) handleImplementation(query string, class *Classification) (stri
 		}
 	}
 
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`You have AST and semantic information, NOT source code.
 
-AST/SEMANTIC DATA:
-%s
+AST/SEMANTIC DATA: in the context above
 
 QUESTION: %s
 
@@ -361,9 +668,9 @@ SAY CLEARLY:
 - "Based on the signature, this function..."
 
 SYMBOLS: %v
-FILES: %v`, context, query, class.Symbols, relevantFiles)
+FILES: %v`, query, class.Symbols, relevantFiles)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleArchitecture(query string, class *Classification) (string, error) {
@@ -382,7 +689,11 @@ func (r *Router) handleArchitecture(query string, class *Classification) (string
 		}
 	}
 
-	context, err := r.contextBuilder.BuildContext(query)
+	if !r.callGraph.loaded() {
+		architectureInfo.WriteString("(call graph not available, use the AST data only)")
+	}
+
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
@@ -392,8 +703,7 @@ func (r *Router) handleArchitecture(query string, class *Classification) (string
 CALL GRAPH:
 %s
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 QUESTION: %s
 
@@ -409,21 +719,20 @@ YOU CANNOT DESCRIBE:
 - Specific algorithms used
 
 Focus on structural relationships visible in the graph and AST.`,
-		architectureInfo.String(), context, query)
+		architectureInfo.String(), query)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleDebug(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`Debug using AST/semantic information only.
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 PROBLEM: %s
 
@@ -440,9 +749,9 @@ YOU CANNOT CHECK:
 
 Be honest: "I can see X might return an error but cannot verify handling without code"
 
-SYMBOLS: %v`, context, query, class.Symbols)
+SYMBOLS: %v`, query, class.Symbols)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleComparison(query string, class *Classification) (string, error) {
@@ -450,15 +759,14 @@ func (r *Router) handleComparison(query string, class *Classification) (string,
 		return "Comparison requires at least two entities. Please specify which functions/types to compare.", nil
 	}
 
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`Compare using AST/type information.
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 COMPARE: %v
 
@@ -472,12 +780,16 @@ COMPARE BY:
 
 State clearly: "Signature-wise they differ in..." or "Cannot compare logic without source code"
 
-Use actual symbols from the AST data.`, context, class.Symbols, query)
+Use actual symbols from the AST data.`, class.Symbols, query)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleDependency(query string, class *Classification) (string, error) {
+	if class.Module != "" {
+		return r.handleModuleUses(class.Module)
+	}
+
 	var entity string
 	if len(class.Symbols) > 0 {
 		entity = class.Symbols[0]
@@ -489,51 +801,36 @@ func (r *Router) handleDependency(query string, class *Classification) (string,
 		return "Could not identify entity for dependency analysis", nil
 	}
 
-	var results []string
-	results = append(results, fmt.Sprintf("— Dependency Analysis for '%s':", entity))
-
-	if funcNode, ok := r.callGraph.Functions[entity]; ok {
-		// Direct dependencies
-		if len(funcNode.Calls) > 0 {
-			results = append(results, "\nDirect Dependencies (functions it calls):")
-			for _, dep := range funcNode.Calls {
-				results = append(results, fmt.Sprintf("  â†’ %s", dep))
-			}
-		}
-
-		// Dependents (reverse dependencies)
-		if len(funcNode.CalledBy) > 0 {
-			results = append(results, "\nDependent Functions (functions that call it):")
-			for _, caller := range funcNode.CalledBy {
-				results = append(results, fmt.Sprintf("  â† %s", caller))
-			}
-		}
+	opts, err := DependencyDefaults(r.config.Dependencies)
+	if err != nil {
+		return "", fmt.Errorf("[dependencies]: %w", err)
+	}
 
-		// Transitive dependencies (2 levels deep)
-		transitive := r.findTransitiveDependencies(entity, 2)
-		if len(transitive) > 0 {
-			results = append(results, "\nTransitive Dependencies:")
-			for _, dep := range transitive {
-				results = append(results, fmt.Sprintf("  â‡’ %s", dep))
-			}
+	r.signals.retrieval = 1.0
+	tree, err := r.callGraph.Dependencies(entity, opts)
+	if err == nil {
+		r.addLocationSources([]string{tree.Root.Location})
+		if r.config.Dependencies.Format == "dot" {
+			return tree.DOT(), nil
 		}
-	} else {
-		results = append(results, "\nNo dependency information found")
+		return fmt.Sprintf("— Dependency Analysis for '%s', %d levels:\n\n%s", entity, opts.Depth, tree.Tree()), nil
 	}
-
-	return strings.Join(results, "\n"), nil
+	if !r.callGraph.loaded() {
+		return r.answerFromContext(query, class)
+	}
+	r.signals.retrieval = 0
+	return fmt.Sprintf("— Dependency Analysis for '%s':\n\nNo dependency information found", entity), nil
 }
 
 func (r *Router) handleRefactoring(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`Suggest refactoring from AST structure.
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 QUESTION: %s
 
@@ -551,22 +848,23 @@ YOU CANNOT DETECT:
 
 Focus on structural issues visible in AST/call graph.
 
-SYMBOLS: %v`, context, query, class.Symbols)
+AFFECTED FILES (defining or calling the symbols):
+%s
+SYMBOLS: %v`, query, formatAffected(r.affectedFiles(class.Symbols, nil)), class.Symbols)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 
 func (r *Router) handlePerformance(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`Performance analysis from AST data.
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 QUESTION: %s
 
@@ -583,13 +881,13 @@ YOU CANNOT DETERMINE:
 
 Be explicit: "The call graph suggests..." or "Without seeing loops, I cannot assess..."
 
-SYMBOLS: %v`, context, query, class.Symbols)
+SYMBOLS: %v`, query, class.Symbols)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleDataFlow(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
@@ -604,14 +902,16 @@ func (r *Router) handleDataFlow(query string, class *Classification) (string, er
 		}
 		callGraphInfo = builder.String()
 	}
+	if !r.callGraph.loaded() {
+		callGraphInfo = "(call graph not available, use the AST data only)"
+	}
 
 	prompt := fmt.Sprintf(`Trace data flow using call graph and types.
 
 CALL GRAPH:
 %s
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 QUESTION: %s
 
@@ -630,21 +930,20 @@ YOU CANNOT SEE:
 
 Focus on type flow through the call chain.
 
-SYMBOLS: %v`, callGraphInfo, context, query, class.Symbols)
+SYMBOLS: %v`, callGraphInfo, query, class.Symbols)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleSecurity(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`Security analysis from AST/types.
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 QUESTION: %s
 
@@ -664,21 +963,20 @@ YOU CANNOT CHECK:
 
 Focus on API surface and type safety.
 
-SYMBOLS: %v`, context, query, class.Symbols)
+SYMBOLS: %v`, query, class.Symbols)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleDocumentation(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`Document from AST/signatures.
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 QUESTION: %s
 
@@ -695,21 +993,20 @@ Called by: HandleRequest"
 
 Cannot document: actual behavior, edge cases, implementation details
 
-SYMBOLS: %v`, context, query, class.Symbols)
+SYMBOLS: %v`, query, class.Symbols)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleExample(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
 
 	prompt := fmt.Sprintf(`Create examples from function signatures.
 
-AST DATA:
-%s
+AST DATA: in the context above
 
 QUESTION: %s
 
@@ -725,13 +1022,13 @@ if err != nil { ... }
 
 Be clear: "This example shows correct types but I cannot verify the actual behavior"
 
-SYMBOLS: %v`, context, query, class.Symbols)
+SYMBOLS: %v`, query, class.Symbols)
 
-	return r.llmClient.Query(context, prompt)
+	return r.ask(context, prompt)
 }
 
 func (r *Router) handleTesting(query string, class *Classification) (string, error) {
-	context, err := r.contextBuilder.BuildContext(query)
+	context, err := r.buildContext(query)
 	if err != nil {
 		return "", fmt.Errorf("failed to build context: %w", err)
 	}
@@ -751,7 +1048,7 @@ SYMBOLS: %v
 
 Question: %s`, query, class.Symbols, query)
 
-	response, err := r.llmClient.Query(context, prompt)
+	response, err := r.ask(context, prompt)
 	if err != nil {
 		return "", fmt.Errorf("LLM query failed: %w", err)
 	}
@@ -786,36 +1083,6 @@ func (r *Router) buildAntiHallucinationPrompt(query string, class *Classificatio
 	return promptBuilder.String()
 }
 
-// Helper functions
-func (r *Router) findTransitiveDependencies(funcName string, depth int) []string {
-	if depth <= 0 {
-		return []string{}
-	}
-
-	visited := make(map[string]bool)
-	var result []string
-
-	var traverse func(name string, currentDepth int)
-	traverse = func(name string, currentDepth int) {
-		if currentDepth > depth || visited[name] {
-			return
-		}
-		visited[name] = true
-
-		if funcNode, ok := r.callGraph.Functions[name]; ok {
-			for _, callee := range funcNode.Calls {
-				if !visited[callee] && callee != funcName {
-					result = append(result, callee)
-					traverse(callee, currentDepth+1)
-				}
-			}
-		}
-	}
-
-	traverse(funcName, 0)
-	return result
-}
-
 func (r *Router) Close() error {
 	if r.contextBuilder != nil {
 		return r.contextBuilder.Close()
@@ -905,6 +1172,14 @@ func (r *Router) fuzzySearch(entity string) []string {
 		}
 	}
 
+	// Search in package level vars and consts
+	for name := range r.kbIndex.GlobalsByName {
+		score := fuzzyScore(entityLower, strings.ToLower(name))
+		if score > 0 {
+			matches = append(matches, match{name, score, "variable"})
+		}
+	}
+
 	// Sort by score descending
 	sort.Slice(matches, func(i, j int) bool {
 		return matches[i].score > matches[j].score
@@ -0,0 +1,47 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Classify is the classification a question gets when it is answered, for
+// callers that plan work before asking
+func (r *Router) Classify(query string) *Classification {
+	return r.classifier.Classify(query)
+}
+
+// Schedule orders questions for a batch: lookups that need no context
+// first, they don't wait on the LLM, then the rest by priority, most urgent
+// first. Ties keep their order. It returns indexes into classes.
+func Schedule(classes []*Classification) []int {
+	order := make([]int, len(classes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := classes[order[i]], classes[order[j]]
+		if a.NeedsContext != b.NeedsContext {
+			return !a.NeedsContext
+		}
+		return a.Priority < b.Priority
+	})
+	return order
+}
+
+// buildLookupPrompt is the prompt for a lookup the index couldn't answer,
+// it asks for where the symbol is rather than an explanation of it
+func buildLookupPrompt(query string, class *Classification) string {
+	var sb strings.Builder
+	sb.WriteString("INSTRUCTIONS:\n")
+	sb.WriteString("1. This is a lookup. Answer with the file paths and line indicators from the context below, then at most two sentences\n")
+	sb.WriteString("2. Use ONLY the context, if it isn't there say: 'This information is not available in the current context'\n")
+	sb.WriteString("3. Do NOT explain how the code works unless asked\n\n")
+	if len(class.Symbols) > 0 {
+		sb.WriteString(fmt.Sprintf("SYMBOLS MENTIONED: %v\n", class.Symbols))
+	}
+	sb.WriteString(fmt.Sprintf("\nQUERY TYPE: %s\n", class.Type.String()))
+	sb.WriteString(fmt.Sprintf("USER QUESTION: %s\n", query))
+	return sb.String()
+}
@@ -0,0 +1,363 @@
+package query
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"eulix/internal/chunkstore"
+	"eulix/internal/config"
+)
+
+// newRoutingRouter is a router over a small index and call graph, with no
+// LLM and an empty .eulix, so anything that reaches for chunks or the model
+// shows up
+func newRoutingRouter(t *testing.T) *Router {
+	t.Helper()
+	index := &KBIndex{
+		FunctionsByName: map[string][]string{"ParseConfig": {"internal/config/parse.go:12"}},
+		TypesByName:     map[string][]string{"Server": {"internal/server/server.go:5"}},
+	}
+	classifier, err := QuerySheriff("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier.addSymbols(index.FunctionsByName, index.TypesByName)
+	return &Router{
+		eulixDir:   t.TempDir(),
+		config:     &config.Config{},
+		classifier: classifier,
+		kbIndex:    index,
+		callGraph: &CallGraph{Functions: map[string]FunctionNode{
+			"ParseConfig": {Name: "ParseConfig", Location: "internal/config/parse.go:12", CalledBy: []string{"main"}},
+		}},
+		signals: answerSignals{retrieval: -1, classifier: -1},
+	}
+}
+
+func TestRouteSkipsContextForLookups(t *testing.T) {
+	for _, q := range []string{"where is ParseConfig", "who calls ParseConfig"} {
+		r := newRoutingRouter(t)
+		class := r.Classify(q)
+		if class.NeedsContext {
+			t.Fatalf("%q: classified as needing context", q)
+		}
+		response, err := r.route(q, nil)
+		if err != nil {
+			t.Fatalf("%q: %v", q, err)
+		}
+		if r.contextBuilder != nil {
+			t.Errorf("%q: context builder loaded for a lookup", q)
+		}
+		if !strings.Contains(response, "internal/config/parse.go:12") {
+			t.Errorf("%q: answer doesn't give the location:\n%s", q, response)
+		}
+	}
+}
+
+func TestDispatchLoadsContextWhenNeeded(t *testing.T) {
+	r := newRoutingRouter(t)
+	class := &Classification{Type: QueryTypeLocation, Symbols: []string{"ParseConfig"}, NeedsContext: true}
+	if _, err := r.dispatch("where is ParseConfig", class); err != nil {
+		t.Fatal(err)
+	}
+	if r.contextBuilder == nil {
+		t.Error("context builder not loaded for a question that needs context")
+	}
+}
+
+func TestDecomposeLeavesLookupsAlone(t *testing.T) {
+	q := "how does a request flow from the HTTP layer to the database"
+	class := &Classification{Type: QueryTypeDataFlow, NeedsContext: true}
+	if parts := decompose(q, class); len(parts) < 2 {
+		t.Fatalf("expected sub-queries, got %v", parts)
+	}
+	class.NeedsContext = false
+	if parts := decompose(q, class); parts != nil {
+		t.Errorf("decomposed a question that needs no context: %v", parts)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	classes := []*Classification{
+		{Type: QueryTypeUnderstanding, NeedsContext: true, Priority: 3},
+		{Type: QueryTypeDebug, NeedsContext: true, Priority: 1},
+		{Type: QueryTypeUsage, NeedsContext: false, Priority: 4},
+		{Type: QueryTypeArchitecture, NeedsContext: true, Priority: 3},
+		{Type: QueryTypeLocation, NeedsContext: false, Priority: 5},
+	}
+	want := []int{2, 4, 1, 0, 3}
+	if got := Schedule(classes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Schedule = %v, want %v", got, want)
+	}
+}
+
+func TestLookupPrompt(t *testing.T) {
+	class := &Classification{Type: QueryTypeLocation, Symbols: []string{"ParseConfig"}}
+	prompt := buildLookupPrompt("where is ParseConfig", class)
+	for _, want := range []string{"This is a lookup", "ParseConfig", "QUERY TYPE: Location"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("lookup prompt is missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestAmbiguousSymbol(t *testing.T) {
+	r := newRoutingRouter(t)
+	r.kbIndex.FunctionsByName["ParseConfig"] = append(r.kbIndex.FunctionsByName["ParseConfig"], "cmd/tool/flags.go:30")
+	class := &Classification{Type: QueryTypeUnderstanding, Symbols: []string{"ParseConfig"}, NeedsContext: true}
+
+	r.Disambiguate(true)
+	var ambiguous *AmbiguousError
+	if err := r.disambiguated(class); !errors.As(err, &ambiguous) || len(ambiguous.Locations) != 2 {
+		t.Fatalf("got %v, want the two definitions of ParseConfig", err)
+	}
+
+	r.signals.picks = map[string]string{"ParseConfig": "cmd/tool/flags.go:30"}
+	if err := r.disambiguated(class); err != nil {
+		t.Fatalf("picked definition still ambiguous: %v", err)
+	}
+	if !strings.Contains(r.signals.memory, "cmd/tool/flags.go:30") {
+		t.Errorf("the model isn't told which one was picked:\n%s", r.signals.memory)
+	}
+
+	r.Disambiguate(false)
+	r.signals = answerSignals{}
+	if err := r.disambiguated(class); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.signals.warnings) != 1 || !strings.Contains(r.signals.warnings[0], "2 places") {
+		t.Errorf("answering about all of them doesn't say so: %v", r.signals.warnings)
+	}
+
+	cb := &ContextBuilder{picks: map[string]string{"ParseConfig": "cmd/tool/flags.go:30"}}
+	if cb.pickedOther("ParseConfig", "cmd/tool/flags.go", 28, 40) || !cb.pickedOther("ParseConfig", "internal/config/parse.go", 12, 40) {
+		t.Error("pickedOther doesn't keep to the picked definition")
+	}
+}
+
+func TestLookupNormalizesNames(t *testing.T) {
+	idx := &KBIndex{
+		FunctionsByName: map[string][]string{
+			"Start":       {"internal/server/server.go:27", "internal/worker/pool.go:14"},
+			"ParseConfig": {"internal/config/parse.go:12"},
+		},
+		TypesByName: map[string][]string{"Server": {"internal/server/server.go:9"}},
+	}
+	for name, want := range map[string][]string{
+		"start":              {"internal/server/server.go:27", "internal/worker/pool.go:14"},
+		"parseconfig?":       {"internal/config/parse.go:12"},
+		"server.Start":       {"internal/server/server.go:27"},
+		"Server.Start":       {"internal/server/server.go:27"},
+		"worker.start":       {"internal/worker/pool.go:14"},
+		"httpserver.Start":   {"internal/server/server.go:27", "internal/worker/pool.go:14"},
+		"config.ParseConfig": {"internal/config/parse.go:12"},
+	} {
+		match, ok := idx.Lookup(name)
+		if !ok || !reflect.DeepEqual(match.Locations(), want) {
+			t.Errorf("Lookup(%q) = %v, %t, want %v", name, match.Locations(), ok, want)
+		}
+	}
+	if match, ok := idx.Lookup("worker.ParseConfig"); ok {
+		t.Errorf("found ParseConfig in a package that doesn't define it: %v", match.Locations())
+	}
+}
+
+func TestUsageOfMethodOnType(t *testing.T) {
+	r := newRoutingRouter(t)
+	r.callGraph.Functions["Get"] = FunctionNode{Name: "Get", Location: "internal/cache/manager.go:40", CalledBy: []string{"Lookup"}}
+	r.callGraph.Functions["Pool.Get"] = FunctionNode{Name: "Get", Location: "internal/worker/pool.go:12", CalledBy: []string{"run"}}
+	r.callGraph.Types = map[string]TypeNode{
+		"Manager": {Name: "Manager", Methods: []string{"Get"}},
+		"Store":   {Name: "Store", Methods: []string{"Get"}},
+		"Pool":    {Name: "Pool", Methods: []string{"Get"}},
+	}
+
+	response, err := r.handleUsage("who calls Manager.Get", &Classification{Type: QueryTypeUsage, Symbols: []string{"Get"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"'Manager.Get'", "internal/cache/manager.go:40", "Lookup", "Store also has a Get method"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("Manager.Get usage is missing %q:\n%s", want, response)
+		}
+	}
+
+	fn, ok := r.callGraph.resolveFunction("pool.get")
+	if !ok || fn.Node.Location != "internal/worker/pool.go:12" || len(fn.Shared) != 0 {
+		t.Errorf("pool.get resolved to %+v, %t, want the qualified node", fn, ok)
+	}
+	if _, ok := r.callGraph.resolveFunction("Manager.Put"); ok {
+		t.Error("resolved a method the type doesn't have")
+	}
+}
+
+func TestLocationOfGlobal(t *testing.T) {
+	r := newRoutingRouter(t)
+	r.kbIndex.GlobalsByName = map[string][]string{"DefaultTimeout": {"internal/server/server.go:7"}}
+	r.classifier.addGlobals(r.kbIndex.GlobalsByName)
+
+	q := "where is DefaultTimeout defined"
+	response, err := r.route(q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response, "'DefaultTimeout'") || !strings.Contains(response, "internal/server/server.go:7") {
+		t.Errorf("%q doesn't give the location:\n%s", q, response)
+	}
+	if r.contextBuilder != nil {
+		t.Errorf("%q: context builder loaded for a lookup", q)
+	}
+}
+
+func TestRouteModuleUsesFromImports(t *testing.T) {
+	r := newRoutingRouter(t)
+	kb := `{"structure": {
+		"internal/store/redis.go": {"imports": [{"module": "github.com/redis/go-redis/v9", "items": [], "type": "external"}]},
+		"internal/server/server.go": {"imports": [{"module": "net/http", "items": [], "type": "external"}]}
+	}, "external_dependencies": [{"name": "github.com/redis/go-redis/v9", "version": "v9.5.1"}]}`
+	if err := os.WriteFile(filepath.Join(r.eulixDir, "kb.json"), []byte(kb), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := &chunkstore.Store{Chunks: map[string]*chunkstore.Chunk{
+		"c1": {ChunkType: "function", Content: "func Open() *redis.Client {\n\treturn redis.NewClient(&redis.Options{})\n}",
+			Metadata: chunkstore.Metadata{FilePath: "internal/store/redis.go", LineStart: 10, LineEnd: 12, Name: "Open"}},
+	}}
+	if err := store.Save(r.eulixDir); err != nil {
+		t.Fatal(err)
+	}
+
+	q := "where do we use github.com/redis/go-redis"
+	if class := r.Classify(q); class.Type != QueryTypeDependency || class.Module != "github.com/redis/go-redis" {
+		t.Fatalf("classified as %s, module %q", class.Type, class.Module)
+	}
+	response, err := r.route(q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"v9.5.1", "internal/store/redis.go", "11  return redis.NewClient(&redis.Options{})  (Open)"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("answer is missing %q:\n%s", want, response)
+		}
+	}
+	if strings.Contains(response, "server.go") {
+		t.Errorf("answer lists a file that doesn't import the module:\n%s", response)
+	}
+}
+
+func TestResponseTokensPerQueryType(t *testing.T) {
+	r := newRoutingRouter(t)
+	r.config.Retrieval.Types = map[string]config.RetrievalParams{"usage": {MaxResponse: 1500}}
+
+	for qt, want := range map[QueryType]int{
+		QueryTypeLocation:      500,
+		QueryTypeArchitecture:  4000,
+		QueryTypeUsage:         1500,
+		QueryTypeUnderstanding: baseMaxResponse,
+	} {
+		r.signals.class = &Classification{Type: qt}
+		if got := r.responseTokens(); got != want {
+			t.Errorf("%s: %d response tokens, want %d", qt, got, want)
+		}
+	}
+
+	r.SetMaxResponse(800)
+	if got := r.responseTokens(); got != 800 {
+		t.Errorf("with SetMaxResponse(800): %d response tokens", got)
+	}
+}
+
+func TestSchemaQuestions(t *testing.T) {
+	schema := &DBSchema{Tables: []DBTable{{
+		Name:    "users",
+		Columns: []DBColumn{{Name: "id", PrimaryKey: true}, {Name: "email"}},
+	}}}
+	cases := []struct {
+		query  string
+		schema bool
+	}{
+		{"which tables reference users.id", true},
+		{"what columns does the users table have", true},
+		{"show the foreign keys in the database", true},
+		{"is users.email nullable", true},
+		// Code questions that mention tables
+		{"how does the scheduler pick a column to sort by", false},
+		{"add a migration step to the Server startup", false},
+		{"why does the table renderer crash", false},
+		{"explain the routing table in Server", false},
+	}
+	for _, tc := range cases {
+		r := newRoutingRouter(t)
+		r.classifier.schema = schema
+		if got := r.Classify(tc.query).Type == QueryTypeSchema; got != tc.schema {
+			t.Errorf("%q: schema question = %v, want %v", tc.query, got, tc.schema)
+		}
+	}
+
+	// Without a kb_schema.json nothing is a schema question
+	r := newRoutingRouter(t)
+	if class := r.Classify("which tables reference users.id"); class.Type == QueryTypeSchema {
+		t.Errorf("classified as a schema question with no schema loaded")
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+	recent := []string{"ParseConfig"}
+	cases := []struct {
+		query    string
+		resolves bool
+	}{
+		{"what about its error handling?", true},
+		{"and the tests?", true},
+		{"does it retry?", true},
+		{"it panics when the file is empty, why?", true},
+		{"how about the callers", true},
+		// Questions of their own
+		{"what is this project about", false},
+		{"is there a retry limit", false},
+		{"how does the server decide that a request timed out and what does it return to the client", false},
+		{"where is the same timeout configured for the worker pool", false},
+		{"who calls Server", false},
+	}
+	r := newRoutingRouter(t)
+	for _, tc := range cases {
+		class := r.Classify(tc.query)
+		got := resolveReferences(tc.query, class, recent) != ""
+		if got != tc.resolves {
+			t.Errorf("%q: resolved = %v, want %v", tc.query, got, tc.resolves)
+		}
+	}
+}
+
+func TestOptionalCallGraph(t *testing.T) {
+	dir := t.TempDir()
+	graph, warning := optionalCallGraph(dir)
+	if graph.loaded() || warning != "" {
+		t.Errorf("without kb_call_graph.json: loaded %v, warning %q", graph.loaded(), warning)
+	}
+
+	path := filepath.Join(dir, "kb_call_graph.json")
+	if err := os.WriteFile(path, []byte(`{"functions": {"main": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	graph, warning = optionalCallGraph(dir)
+	if graph.loaded() || !strings.Contains(warning, "kb_call_graph.json is corrupt") {
+		t.Errorf("corrupt kb_call_graph.json: loaded %v, warning %q", graph.loaded(), warning)
+	}
+	r := newRoutingRouter(t)
+	r.graphWarning = warning
+	if warnings := r.Warnings(); len(warnings) == 0 || warnings[0] != warning {
+		t.Errorf("Warnings() = %q, want the call graph's first", warnings)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"functions": {"main": {"name": "main"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	graph, warning = optionalCallGraph(dir)
+	if !graph.loaded() || warning != "" {
+		t.Errorf("valid kb_call_graph.json: loaded %v, warning %q", graph.loaded(), warning)
+	}
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Questions mix two kinds of small words. "the", "how" and "does" carry
+// nothing and are dropped. "select", "match" and "defer" are keywords of
+// the language the code is in: they can't name a function or type there,
+// and the user means the construct, so in a chunk of that language they only
+// count as syntax, weakly, and never as part of a name. In other languages
+// they are ordinary words, select can be a Python function.
+
+// stopWords are the English words dropped from questions before search
+var stopWords = map[string]bool{
+	"how": true, "does": true, "did": true, "the": true, "a": true, "an": true,
+	"is": true, "are": true, "was": true, "were": true, "been": true,
+	"what": true, "where": true, "when": true, "which": true, "who": true, "why": true,
+	"can": true, "will": true, "should": true, "would": true, "could": true,
+	"this": true, "that": true, "these": true, "those": true, "there": true, "here": true,
+	"has": true, "have": true, "its": true, "our": true, "your": true,
+	"and": true, "from": true, "into": true, "about": true,
+	"of": true, "in": true, "on": true, "at": true, "to": true, "for": true, "with": true,
+}
+
+// languageKeywords are the reserved words of each language, by the names
+// languageOf returns. Words that are also stop words are left out.
+var languageKeywords = map[string]map[string]bool{
+	"go":         wordSet("break case chan const continue default defer else fallthrough func go goto if import interface map package range return select struct switch type var"),
+	"python":     wordSet("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False"),
+	"javascript": wordSet("async await break case catch class const continue debugger default delete do else export extends finally for function if import in instanceof let new of return super switch this throw try typeof var void while with yield"),
+	"typescript": wordSet("abstract async await break case catch class const continue declare default delete do else enum export extends finally for function if implements import in instanceof interface let namespace new of private protected public readonly return super switch this throw try type typeof var void while with yield"),
+	"rust":       wordSet("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self static struct super trait type unsafe use where while"),
+	"java":       wordSet("abstract assert break case catch class const continue default do else enum extends final finally for if implements import instanceof interface native new package private protected public return static super switch synchronized this throw throws transient try void volatile while"),
+	"c":          wordSet("auto break case char const continue default do double else enum extern float for goto if inline int long register return short signed sizeof static struct switch typedef union unsigned void volatile while class namespace template typename virtual public private protected"),
+	"sql":        wordSet("select insert update delete from where join inner outer left right on group order by having limit offset create alter drop table index view into values set and or not null primary foreign key references union distinct"),
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[strings.ToLower(w)] = true
+	}
+	return set
+}
+
+// languageOf is the language of file by its extension, "" when it has none
+// in languageKeywords
+func languageOf(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".go":
+		return "go"
+	case ".py", ".pyi":
+		return "python"
+	case ".js", ".jsx", ".mjs", ".cjs":
+		return "javascript"
+	case ".ts", ".tsx":
+		return "typescript"
+	case ".rs":
+		return "rust"
+	case ".java", ".kt":
+		return "java"
+	case ".c", ".h", ".cc", ".cpp", ".cxx", ".hpp":
+		return "c"
+	case ".sql":
+		return "sql"
+	}
+	return ""
+}
+
+// isCodeKeyword reports whether word, as written, is reserved in language.
+// Select isn't, it can name a Go method.
+func isCodeKeyword(language, word string) bool {
+	return languageKeywords[language][word]
+}
+
+// withoutStopWords drops the stop words from words, in any case
+func withoutStopWords(words []string) []string {
+	kept := words[:0:0]
+	for _, w := range words {
+		if !stopWords[strings.ToLower(w)] {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
+
+// containsWord reports whether word appears in text on its own, not as part
+// of a longer name
+func containsWord(text, word string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(word)
+		if (i == 0 || !isWordByte(text[i-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,203 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema questions are answered straight from kb_schema.json, like
+// dependency questions are from the call graph. A column named as
+// table.column lists what references it, a table name lists its columns.
+
+var (
+	// "users.id"
+	columnRefPattern = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z_][A-Za-z0-9_]*)\b`)
+
+	// "which tables reference orders", "what points to users"
+	referencePattern = regexp.MustCompile(`(?i)\b(referenc\w*|refers?\s+to|points?\s+(to|at)|foreign\s+keys?\s+to|depends?\s+on)\b`)
+)
+
+func loadDBSchema(eulixDir string) (*DBSchema, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb_schema.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var s DBSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *DBSchema) loaded() bool {
+	return s != nil && len(s.Tables) > 0
+}
+
+// table finds a table by name, "order" finds orders too
+func (s *DBSchema) table(name string) *DBTable {
+	name = strings.ToLower(name)
+	for _, candidate := range []string{name, name + "s", name + "es", strings.TrimSuffix(name, "s")} {
+		for i := range s.Tables {
+			if s.Tables[i].Name == candidate {
+				return &s.Tables[i]
+			}
+		}
+	}
+	return nil
+}
+
+// column finds a column of t by name, in any case
+func (t *DBTable) column(name string) *DBColumn {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// referencing lists the tables with a foreign key to table, to column only
+// when column isn't empty
+func (s *DBSchema) referencing(table, column string) []string {
+	var refs []string
+	for _, t := range s.Tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.RefTable != table {
+				continue
+			}
+			if column != "" && !containsString(fk.RefColumns, column) {
+				continue
+			}
+			refs = append(refs, fmt.Sprintf("%s.%s -> %s.%s (%s)",
+				t.Name, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "), tableSource(t)))
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+func (r *Router) handleSchema(query string, class *Classification) (string, error) {
+	if !r.dbSchema.loaded() {
+		return r.answerFromContext(query, class)
+	}
+
+	r.signals.retrieval = 1.0
+	var results []string
+	var sources []string
+
+	// table.column: what references it
+	for _, m := range columnRefPattern.FindAllStringSubmatch(query, -1) {
+		t := r.dbSchema.table(m[1])
+		if t == nil {
+			continue
+		}
+		column := strings.ToLower(m[2])
+		sources = append(sources, t.Source)
+		results = append(results, fmt.Sprintf("— Tables referencing '%s.%s':", t.Name, column))
+		if refs := r.dbSchema.referencing(t.Name, column); len(refs) > 0 {
+			for _, ref := range refs {
+				results = append(results, "  "+ref)
+			}
+		} else {
+			results = append(results, "  none")
+		}
+		results = append(results, "")
+	}
+	if len(results) > 0 {
+		r.signals.sources = sources
+		return strings.TrimSpace(strings.Join(results, "\n")), nil
+	}
+
+	// Table names: their columns, or what references them
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(query, func(c rune) bool {
+		return !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9')
+	}) {
+		t := r.dbSchema.table(word)
+		if t == nil || seen[t.Name] {
+			continue
+		}
+		seen[t.Name] = true
+		sources = append(sources, t.Source)
+
+		if referencePattern.MatchString(query) {
+			results = append(results, fmt.Sprintf("— Tables referencing '%s':", t.Name))
+			refs := r.dbSchema.referencing(t.Name, "")
+			for _, ref := range refs {
+				results = append(results, "  "+ref)
+			}
+			if len(refs) == 0 {
+				results = append(results, "  none")
+			}
+		} else {
+			results = append(results, describeTable(t, r.dbSchema.referencing(t.Name, ""))...)
+		}
+		results = append(results, "")
+	}
+	if len(results) > 0 {
+		r.signals.sources = sources
+		return strings.TrimSpace(strings.Join(results, "\n")), nil
+	}
+
+	// No table named, list them all
+	results = append(results, fmt.Sprintf("— %d tables:", len(r.dbSchema.Tables)))
+	for _, t := range r.dbSchema.Tables {
+		results = append(results, fmt.Sprintf("  %-24s %d columns  %s", t.Name, len(t.Columns), tableSource(t)))
+	}
+	return strings.Join(results, "\n"), nil
+}
+
+func describeTable(t *DBTable, referencedBy []string) []string {
+	lines := []string{fmt.Sprintf("— Table '%s' (%s, %s):", t.Name, t.Origin, tableSource(*t))}
+
+	lines = append(lines, "\nColumns:")
+	for _, c := range t.Columns {
+		var flags []string
+		if c.PrimaryKey {
+			flags = append(flags, "primary key")
+		}
+		if !c.Nullable {
+			flags = append(flags, "not null")
+		}
+		line := fmt.Sprintf("  %-24s %s", c.Name, c.DataType)
+		if len(flags) > 0 {
+			line += " (" + strings.Join(flags, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+
+	if len(t.ForeignKeys) > 0 {
+		lines = append(lines, "\nForeign keys:")
+		for _, fk := range t.ForeignKeys {
+			lines = append(lines, fmt.Sprintf("  %s -> %s.%s",
+				strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", ")))
+		}
+	}
+
+	if len(referencedBy) > 0 {
+		lines = append(lines, "\nReferenced by:")
+		for _, ref := range referencedBy {
+			lines = append(lines, "  "+ref)
+		}
+	}
+	return lines
+}
+
+func tableSource(t DBTable) string {
+	return fmt.Sprintf("%s:%d", t.Source, t.Line)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
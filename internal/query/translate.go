@@ -0,0 +1,233 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"eulix/internal/config"
+	"eulix/internal/llm"
+)
+
+// The classifier's patterns and the keyword search are English. A question
+// in another language is translated to English first, by the model, and
+// answered in the language it was asked in. Symbols are taken from the
+// question as written, so a translation that "fixes" CrearPedido doesn't
+// lose it.
+
+// Values of [llm] translate
+const (
+	TranslateAuto = "auto" // translate questions that aren't English (default)
+	TranslateOff  = "off"
+)
+
+// translateTokens is the most a translated question may run to
+const translateTokens = 300
+
+// checkTranslate warns about an [llm] translate that isn't one, it falls
+// back to auto
+func checkTranslate(cfg config.LLMConfig) []string {
+	switch cfg.Translate {
+	case "", TranslateAuto, TranslateOff:
+		return nil
+	}
+	return []string{fmt.Sprintf("[llm] translate = %q is not a mode, using %s. Use auto or off", cfg.Translate, TranslateAuto)}
+}
+
+// scriptLanguages name a question by its script. Japanese comes before
+// Chinese, it is written with Han characters too.
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+}{
+	{unicode.Devanagari, "Hindi"},
+	{unicode.Bengali, "Bengali"},
+	{unicode.Tamil, "Tamil"},
+	{unicode.Cyrillic, "Russian"},
+	{unicode.Greek, "Greek"},
+	{unicode.Hebrew, "Hebrew"},
+	{unicode.Arabic, "Arabic"},
+	{unicode.Thai, "Thai"},
+	{unicode.Hangul, "Korean"},
+	{unicode.Hiragana, "Japanese"},
+	{unicode.Katakana, "Japanese"},
+	{unicode.Han, "Chinese"},
+}
+
+// questionWords are common words of languages written in Latin script that
+// English questions don't use. Words that are also English, like "was" or
+// "die", are left out.
+var questionWords = map[string]map[string]bool{
+	"Spanish":    wordSet("dónde donde cómo qué cuál cuáles quién quiénes por qué porque está están es son el los las del una unos para función funciones llama llaman usa usan hace hacen se archivo clase método"),
+	"German":     wordSet("wo wie warum welche welcher welches wer wird werden ist sind der das den dem des und eine einen einer nicht funktion funktionen aufgerufen verwendet datei klasse methode gibt"),
+	"French":     wordSet("où comment pourquoi quelle quel quelles qui est sont le les des une un dans fonction fonctions appelle appelée utilise fichier classe méthode fait"),
+	"Portuguese": wordSet("onde como por que qual quais quem está são os uma um das dos função funções chama chamada usa arquivo classe método faz"),
+}
+
+// detectLanguage names the language query is written in, "" for English or
+// when it can't tell. Symbols and `quoted code` don't count.
+func detectLanguage(query string) string {
+	prose := stripQuotedCode(query)
+
+	counts := make(map[string]int)
+	for _, r := range prose {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				counts[s.language]++
+				break
+			}
+		}
+	}
+	for _, s := range scriptLanguages {
+		if counts[s.language] >= 2 {
+			return s.language
+		}
+	}
+
+	var words []string
+	for _, word := range strings.FieldsFunc(prose, func(r rune) bool { return !unicode.IsLetter(r) && r != '_' && !unicode.IsDigit(r) }) {
+		if !looksLikeSymbol(word) {
+			words = append(words, strings.ToLower(word))
+		}
+	}
+	english := 0
+	for _, w := range words {
+		if stopWords[w] {
+			english++
+		}
+	}
+	best, bestCount := "", 0
+	for language, markers := range questionWords {
+		n := 0
+		for _, w := range words {
+			if markers[w] {
+				n++
+			}
+		}
+		if n > bestCount || n == bestCount && language < best {
+			best, bestCount = language, n
+		}
+	}
+	if bestCount >= 2 && bestCount > english {
+		return best
+	}
+	return ""
+}
+
+// looksLikeSymbol reports whether word is written like code: snake_case,
+// camelCase or with digits
+func looksLikeSymbol(word string) bool {
+	for i, r := range word {
+		if r == '_' || unicode.IsDigit(r) || i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripQuotedCode drops what is between backticks
+func stripQuotedCode(query string) string {
+	parts := strings.Split(query, "`")
+	var prose []string
+	for i := 0; i < len(parts); i += 2 {
+		prose = append(prose, parts[i])
+	}
+	return strings.Join(prose, " ")
+}
+
+// translates reports whether the current question is translated before it
+// is classified
+func (r *Router) translates() bool {
+	return r.signals.language != "" && r.config.LLM.Translate != TranslateOff
+}
+
+// cachedTranslated is the cached answer to asked, a question in another
+// language, looked up as written before it costs a translation. Follow-ups
+// that name nothing are cached under what they resolved to and don't hit.
+func (r *Router) cachedTranslated(asked string) (string, bool) {
+	if r.cache == nil || r.currentChecksum == "" || r.signals.expansion > 0 {
+		return "", false
+	}
+	cacheKey := r.signals.language + ": " + asked
+	cached, found, err := r.cache.Lookup(withMemory(cacheKey, r.signals.memory), r.currentChecksum)
+	if err != nil || !found || !r.fresh(cached) {
+		return "", false
+	}
+	class := r.classifier.Classify(asked)
+	r.signals.classifier = class.Confidence
+	r.signals.class = class
+	return r.cacheHit(cacheKey, cached), true
+}
+
+// translated is query, in r.signals.language, in English for classification
+// and retrieval. The translation is a model call like any other, within
+// [llm.budget] and --local-only. When it fails otherwise the question is
+// searched as written, with a warning.
+func (r *Router) translated(query string) (string, error) {
+	language := r.signals.language
+	prompt := buildTranslatePrompt(query, language)
+	if err := r.checkBudget(nil, prompt); err != nil {
+		return "", err
+	}
+
+	r.report(StageClassifying, "translating from "+language)
+	r.llmClient.SetModel(r.config.LLM.TranslateModel)
+	r.llmClient.SetMaxTokens(translateTokens)
+	defer r.llmClient.SetModel("")
+	response, err := r.llmClient.CompleteStream(r.generationContext(), prompt, nil)
+	if errors.Is(err, llm.ErrLocalOnly) || errors.Is(err, llm.ErrInterrupted) {
+		return "", err
+	}
+	english := cleanTranslation(response)
+	if err != nil || english == "" {
+		r.signals.warnings = append(r.signals.warnings, fmt.Sprintf("The question couldn't be translated from %s, it was searched as written", language))
+		return query, nil
+	}
+
+	// Symbols come from the question as written, a name the translation
+	// changed is searched for too
+	var missing []string
+	for _, symbol := range r.classifier.validateSymbols(r.classifier.extractSymbols(query)) {
+		if !strings.Contains(english, symbol) {
+			missing = append(missing, symbol)
+		}
+	}
+	if len(missing) > 0 {
+		english += " (" + strings.Join(missing, ", ") + ")"
+	}
+	return english, nil
+}
+
+func buildTranslatePrompt(query, language string) string {
+	return fmt.Sprintf(`Translate this question about a codebase from %s into English.
+Keep function, type and variable names, file paths, code and anything in quotes or backticks exactly as written.
+Reply with the English question only, no explanation.
+
+%s
+`, language, query)
+}
+
+// cleanTranslation is the first line of the model's reply without quotes
+// around it
+func cleanTranslation(response string) string {
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return strings.Trim(line, "\"'")
+		}
+	}
+	return ""
+}
+
+// answerLanguage is the instruction to answer in the question's language,
+// "" for English
+func (r *Router) answerLanguage() string {
+	language := r.signals.language
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nThe question was asked in %s. Write the answer in %s, keeping code, names and file paths as they are. Write the CONFIDENCE line in English.\n", language, language)
+}
@@ -0,0 +1,297 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"eulix/internal/chunkstore"
+)
+
+// "Where do we use github.com/redis/go-redis" is answered from the imports
+// analyze recorded for each file, not from search. The call sites are the
+// lines of the importing files' chunks that go through the package name or
+// an imported item, which is as close as the KB gets: calls only record the
+// name after the last dot.
+
+// modulePathPattern matches an import path with a host, like
+// github.com/redis/go-redis or golang.org/x/sync, or an npm @scope/package
+var modulePathPattern = regexp.MustCompile(`(?:^|[\s"'` + "`" + `(])([a-z0-9][\w-]*(?:\.[\w-]+)+/[\w.@/-]*[\w-]|@[\w.-]+/[\w.-]*[\w-])`)
+
+// importedNamePattern matches the module in "who imports numpy" and "where
+// is requests imported"
+var importedNamePattern = regexp.MustCompile(`(?i)\bimport(?:s|ed|ing)?\s+(?:of\s+)?([@\w][\w./@-]*[\w-])|\b(?:is|are)\s+([@\w][\w./@-]*[\w-])\s+imported\b`)
+
+// notModules are words importedNamePattern catches that don't name a module
+var notModules = map[string]bool{"the": true, "this": true, "that": true, "it": true, "a": true, "an": true, "any": true, "from": true, "in": true}
+
+// extractModule is the module a question names, "" when it names none
+func extractModule(query string) string {
+	if m := modulePathPattern.FindStringSubmatch(query); m != nil {
+		return m[1]
+	}
+	if m := importedNamePattern.FindStringSubmatch(query); m != nil {
+		name := m[1] + m[2]
+		if !notModules[strings.ToLower(name)] {
+			return name
+		}
+	}
+	return ""
+}
+
+// ModuleUses is every file importing a module, and where each one uses it
+type ModuleUses struct {
+	Module  string          `json:"module"`
+	Version string          `json:"version,omitempty"` // from the project's manifest, when listed there
+	Files   []ImportingFile `json:"files"`
+}
+
+// ImportingFile is a file importing the module
+type ImportingFile struct {
+	File    string     `json:"file"`
+	Imports []string   `json:"imports"` // as written, the module or its subpackages
+	Items   []string   `json:"items,omitempty"`
+	Calls   []CallSite `json:"calls"`
+}
+
+// CallSite is a line of an importing file going through the module
+type CallSite struct {
+	Function string `json:"function"` // the chunk the line is in
+	Line     int    `json:"line"`
+	Code     string `json:"code"`
+}
+
+// Calls is the number of call sites across all files
+func (u *ModuleUses) Calls() int {
+	n := 0
+	for _, f := range u.Files {
+		n += len(f.Calls)
+	}
+	return n
+}
+
+// kbImports is the part of kb.json FindUses reads
+type kbImports struct {
+	Structure map[string]struct {
+		Imports []struct {
+			Module string   `json:"module"`
+			Items  []string `json:"items"`
+		} `json:"imports"`
+	} `json:"structure"`
+	ExternalDependencies []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"external_dependencies"`
+}
+
+// FindUses lists the files importing module, by its full path, a prefix of
+// it or one of its path elements, so "go-redis" finds
+// github.com/redis/go-redis/v9. Call sites come from chunks.json and are
+// left out when it is missing.
+func FindUses(eulixDir, module string) (*ModuleUses, error) {
+	module = strings.Trim(strings.TrimSpace(module), "\"'`")
+	if module == "" {
+		return nil, fmt.Errorf("no module given")
+	}
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return nil, err
+	}
+	var kb kbImports
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return nil, fmt.Errorf("failed to parse kb.json: %w", err)
+	}
+
+	uses := &ModuleUses{Module: module, Files: []ImportingFile{}}
+	for _, dep := range kb.ExternalDependencies {
+		if matchesModule(dep.Name, module) {
+			uses.Version = dep.Version
+			break
+		}
+	}
+
+	qualifiers := make(map[string][]string)
+	for file, fs := range kb.Structure {
+		var importing ImportingFile
+		for _, imp := range fs.Imports {
+			name, alias := splitImportAlias(imp.Module)
+			if !matchesModule(name, module) {
+				continue
+			}
+			importing.Imports = appendMissing(importing.Imports, name)
+			importing.Items = appendMissing(importing.Items, imp.Items...)
+			if alias != "" {
+				qualifiers[file] = appendMissing(qualifiers[file], alias)
+			} else if len(imp.Items) == 0 {
+				qualifiers[file] = appendMissing(qualifiers[file], packageNames(name)...)
+			}
+		}
+		if len(importing.Imports) > 0 {
+			importing.File = file
+			uses.Files = append(uses.Files, importing)
+		}
+	}
+	sort.Slice(uses.Files, func(i, j int) bool { return uses.Files[i].File < uses.Files[j].File })
+
+	if store, err := chunkstore.Load(eulixDir); err == nil {
+		for i := range uses.Files {
+			f := &uses.Files[i]
+			f.Calls = callSites(store, f.File, qualifiers[f.File], f.Items)
+		}
+	}
+	return uses, nil
+}
+
+// matchesModule reports whether the import path imported is want, below it,
+// or, for a bare name like "redis", has it as a path element
+func matchesModule(imported, want string) bool {
+	imported, want = strings.ToLower(imported), strings.ToLower(strings.TrimSuffix(want, "/"))
+	if imported == want || strings.HasPrefix(imported, want+"/") || strings.HasPrefix(imported, want+".") {
+		return true
+	}
+	if strings.ContainsAny(want, "/.") {
+		return false
+	}
+	for _, element := range strings.FieldsFunc(imported, func(r rune) bool { return r == '/' || r == '.' }) {
+		if element == want {
+			return true
+		}
+	}
+	return false
+}
+
+// splitImportAlias splits Python's "numpy as np" into the module and alias
+func splitImportAlias(module string) (string, string) {
+	if i := strings.Index(module, " as "); i > 0 {
+		return strings.TrimSpace(module[:i]), strings.TrimSpace(module[i+4:])
+	}
+	return strings.TrimSpace(module), ""
+}
+
+// versionSuffix is the /v9 at the end of a Go module path
+var versionSuffix = regexp.MustCompile(`/v\d+$`)
+
+// packageNames are the names code likely uses for an import path: "os.path"
+// as it is, "github.com/redis/go-redis/v9" as redis or go-redis
+func packageNames(module string) []string {
+	if !strings.Contains(module, "/") {
+		return []string{module}
+	}
+	base := path.Base(versionSuffix.ReplaceAllString(module, ""))
+	names := []string{base}
+	if trimmed := strings.TrimSuffix(strings.TrimPrefix(base, "go-"), "-go"); trimmed != base {
+		names = append(names, trimmed)
+	}
+	if cleaned := strings.NewReplacer("-", "", ".", "").Replace(base); cleaned != base {
+		names = append(names, cleaned)
+	}
+	return names
+}
+
+// callSites is every line of file's chunks going through one of
+// qualifiers, like redis.NewClient, or calling one of items, by line
+func callSites(store *chunkstore.Store, file string, qualifiers, items []string) []CallSite {
+	var alternatives []string
+	for _, q := range qualifiers {
+		if q != "" && q != "_" && q != "." {
+			alternatives = append(alternatives, regexp.QuoteMeta(q)+`\.\w`)
+		}
+	}
+	for _, item := range items {
+		if item != "*" && item != "_" && item != "." {
+			alternatives = append(alternatives, regexp.QuoteMeta(item)+`\s*[.(]`)
+		}
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	pattern := regexp.MustCompile(`(?:^|[^\w.])(?:` + strings.Join(alternatives, "|") + `)`)
+
+	seen := make(map[int]bool)
+	var calls []CallSite
+	for _, chunk := range store.Sorted() {
+		if chunk.Metadata.FilePath != file {
+			continue
+		}
+		for i, line := range strings.Split(chunk.Content, "\n") {
+			number := chunk.Metadata.LineStart + i
+			code := strings.TrimSpace(line)
+			if seen[number] || isImportLine(code) || !pattern.MatchString(code) {
+				continue
+			}
+			seen[number] = true
+			calls = append(calls, CallSite{Function: chunk.Metadata.Name, Line: number, Code: code})
+		}
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Line < calls[j].Line })
+	return calls
+}
+
+func isImportLine(code string) bool {
+	for _, prefix := range []string{"import ", "from ", "#include", "require(", "use ", "//", "#"} {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		if !containsString(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// Summary is the report eulix uses and the router print, at most
+// callsPerFile call sites for each file
+func (u *ModuleUses) Summary(callsPerFile int) string {
+	if len(u.Files) == 0 {
+		return fmt.Sprintf("No file imports %s", u.Module)
+	}
+
+	var lines []string
+	title := fmt.Sprintf("%s is imported by %d files, %d call sites", u.Module, len(u.Files), u.Calls())
+	if u.Version != "" {
+		title = fmt.Sprintf("%s %s is imported by %d files, %d call sites", u.Module, u.Version, len(u.Files), u.Calls())
+	}
+	lines = append(lines, title)
+	for _, f := range u.Files {
+		lines = append(lines, "", fmt.Sprintf("%s (%s)", f.File, strings.Join(f.Imports, ", ")))
+		if len(f.Items) > 0 {
+			lines = append(lines, fmt.Sprintf("  imports %s", strings.Join(f.Items, ", ")))
+		}
+		for i, call := range f.Calls {
+			if i == callsPerFile {
+				lines = append(lines, fmt.Sprintf("  and %d more", len(f.Calls)-callsPerFile))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("  %d  %s  (%s)", call.Line, call.Code, call.Function))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleModuleUses lists the files importing module and their call sites
+func (r *Router) handleModuleUses(module string) (string, error) {
+	uses, err := FindUses(r.eulixDir, module)
+	if err != nil {
+		return "", fmt.Errorf("failed to read imports: %w", err)
+	}
+	if len(uses.Files) == 0 {
+		r.signals.retrieval = 0
+		return fmt.Sprintf("No file imports %s. Imports are recorded by 'eulix analyze', run it if the import is new.", module), nil
+	}
+	r.signals.retrieval = 1.0
+	for _, f := range uses.Files {
+		r.addLocationSources([]string{f.File})
+	}
+	return uses.Summary(5), nil
+}
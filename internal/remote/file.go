@@ -0,0 +1,49 @@
+package remote
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fileStore is a directory, e.g. a network share or a CI artifact mount
+type fileStore struct {
+	dir string
+}
+
+func (f *fileStore) String() string {
+	return "file://" + f.dir
+}
+
+func (f *fileStore) Put(name string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(f.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes the file private, the store is meant to be shared
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(f.dir, name))
+}
+
+func (f *fileStore) Get(name string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(f.dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
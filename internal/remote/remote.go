@@ -0,0 +1,99 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Prebuilt indexes are kept in object storage as a snapshot archive plus its
+// sha256, so a CI job can analyze once and every developer pulls the result.
+// Backends talk plain HTTP: s3:// signs requests with SigV4 from the usual
+// AWS_* variables, gs:// sends an OAuth token, file:// is a shared directory.
+
+// Store reads and writes objects under one prefix
+type Store interface {
+	// Put uploads size bytes from r as name
+	Put(name string, r io.Reader, size int64) error
+	// Get opens name, returning ErrNotFound when it doesn't exist
+	Get(name string) (io.ReadCloser, error)
+	// String is the location, for messages
+	String() string
+}
+
+// ErrNotFound is returned by Get for a missing object
+var ErrNotFound = errors.New("object not found")
+
+// Courier opens the store behind a URL like s3://bucket/path,
+// gs://bucket/path or file:///mnt/shared/index
+func Courier(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index location %q: %w", rawURL, err)
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s has no bucket", rawURL)
+		}
+		return newS3(u.Host, prefix), nil
+	case "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s has no bucket", rawURL)
+		}
+		return newGCS(u.Host, prefix), nil
+	case "file":
+		return &fileStore{dir: u.Host + u.Path}, nil
+	case "":
+		return nil, fmt.Errorf("index location %q needs a scheme: s3://, gs:// or file://", rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported index location %q, use s3://, gs:// or file://", rawURL)
+	}
+}
+
+// transferClient is shared by the HTTP backends, a KB for a large repo can
+// take a while over a slow link
+var transferClient = &http.Client{Timeout: 30 * time.Minute}
+
+// objectKey joins the store prefix and an object name
+func objectKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// escapePath percent-encodes everything but unreserved characters and '/',
+// the form both S3 signing and GCS object URLs expect
+func escapePath(path string) string {
+	var sb strings.Builder
+	for _, b := range []byte(path) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '.', b == '_', b == '~', b == '/':
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// checkResponse turns a failed response into an error with the start of
+// the body, which is where S3 and GCS explain themselves
+func checkResponse(resp *http.Response, what string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s: %s: %s", what, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"eulix/internal/chunkstore"
+)
+
+type migration struct {
+	from, to    int
+	description string
+	apply       func(eulixDir string) error
+}
+
+var migrations = []migration{
+	{
+		from:        0,
+		to:          1,
+		description: "add EULX header to legacy embeddings.bin and mark artifacts with schema_version",
+		apply:       migrateV0ToV1,
+	},
+	{
+		from:        1,
+		to:          2,
+		description: "split embeddings.json into chunks.json and ID keyed vectors.bin",
+		apply:       migrateV1ToV2,
+	},
+}
+
+// Migrate upgrades the artifacts in eulixDir in place to Version, reporting
+// each step to out. Each step stamps the artifacts with the version it
+// reached. It returns the version it started from.
+func Migrate(eulixDir string, out io.Writer) (int, error) {
+	start, err := Detect(eulixDir)
+	if err != nil {
+		return 0, err
+	}
+	if start > Version {
+		return start, fmt.Errorf("knowledge base schema v%d is newer than this build (v%d)", start, Version)
+	}
+
+	current := start
+	for _, m := range migrations {
+		if m.from != current {
+			continue
+		}
+
+		fmt.Fprintf(out, "v%d -> v%d: %s\n", m.from, m.to, m.description)
+		if err := m.apply(eulixDir); err != nil {
+			return start, fmt.Errorf("migration v%d -> v%d failed: %w", m.from, m.to, err)
+		}
+		// Only the step's own version, a later step that fails must leave
+		// the artifacts marked as what they are
+		if err := Stamp(eulixDir, m.to); err != nil {
+			return start, fmt.Errorf("migration v%d -> v%d failed: %w", m.from, m.to, err)
+		}
+		current = m.to
+	}
+
+	if current != Version {
+		return start, fmt.Errorf("no migration path from v%d to v%d, run 'eulix analyze'", current, Version)
+	}
+	return start, nil
+}
+
+// Must match what query.ContextBuilder reads
+const (
+	binaryMagic   = "EULX"
+	binaryVersion = uint32(2)
+)
+
+func migrateV0ToV1(eulixDir string) error {
+	return upgradeEmbeddingsBin(filepath.Join(eulixDir, "embeddings.bin"))
+}
+
+func migrateV1ToV2(eulixDir string) error {
+	if chunkstore.HasLegacy(eulixDir) {
+		return chunkstore.Split(eulixDir)
+	}
+	return nil
+}
+
+// Older releases wrote embeddings.bin as count(4) + dim(4) + floats, without
+// the magic and version fields
+func upgradeEmbeddingsBin(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(data) >= 4 && string(data[0:4]) == binaryMagic {
+		return nil
+	}
+
+	if len(data) < 8 {
+		return fmt.Errorf("embeddings.bin too short to migrate (%d bytes)", len(data))
+	}
+
+	count := binary.LittleEndian.Uint32(data[0:4])
+	dim := binary.LittleEndian.Uint32(data[4:8])
+	if expected := 8 + uint64(count)*uint64(dim)*4; uint64(len(data)) != expected {
+		return fmt.Errorf("embeddings.bin is not a legacy file (expected %d bytes, got %d), run 'eulix analyze'", expected, len(data))
+	}
+
+	header := make([]byte, 8)
+	copy(header[0:4], binaryMagic)
+	binary.LittleEndian.PutUint32(header[4:8], binaryVersion)
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(header, data...), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,30 @@
+package schema
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFailedMigrationKeepsVersion(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"kb.json":         `{"structure": {}}`,
+		"embeddings.json": `{"embeddings": "not a list"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Migrate(dir, io.Discard); err == nil {
+		t.Fatal("splitting a broken embeddings.json succeeded")
+	}
+	if v, err := Detect(dir); err != nil || v != 1 {
+		t.Errorf("after v1 -> v2 failed, Detect = %d, %v, want 1", v, err)
+	}
+	if err := Check(dir); err == nil {
+		t.Error("a half migrated KB passes the schema check")
+	}
+}
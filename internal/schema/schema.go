@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"eulix/internal/fault"
+)
+
+// Version is the .eulix layout this build reads and writes. Bump it together
+// with a new entry in migrations whenever an artifact format changes.
+const Version = 2
+
+// JSON artifacts carry a top level "schema_version" key. vectors.bin has its
+// own header version. embeddings.json only exists in v1 and older.
+var jsonArtifacts = []string{
+	"kb.json",
+	"kb_index.json",
+	"kb_call_graph.json",
+	"kb_summary.json",
+	"chunks.json",
+	"embeddings.json",
+}
+
+// Stamp writes schema_version as the first key, so reading it back only
+// needs the start of the file
+const prefixSize = 256
+
+var versionPattern = regexp.MustCompile(`^\s*\{\s*"schema_version"\s*:\s*(\d+)`)
+
+// ReadVersion returns the schema_version of a JSON artifact, 0 when unmarked
+func ReadVersion(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, prefixSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, err
+	}
+
+	m := versionPattern.FindSubmatch(buf[:n])
+	if m == nil {
+		return 0, nil
+	}
+	return strconv.Atoi(string(m[1]))
+}
+
+// Detect returns the lowest schema version across the artifacts present
+func Detect(eulixDir string) (int, error) {
+	version := -1
+	for _, name := range jsonArtifacts {
+		v, err := ReadVersion(filepath.Join(eulixDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if version == -1 || v < version {
+			version = v
+		}
+	}
+
+	if version == -1 {
+		return 0, fmt.Errorf("no knowledge base found in %s", eulixDir)
+	}
+	return version, nil
+}
+
+// Check fails when the artifacts were written by an older or newer eulix
+func Check(eulixDir string) error {
+	version, err := Detect(eulixDir)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version < Version:
+		return fmt.Errorf("%w, schema v%d is older than v%d, run 'eulix migrate' (or 'eulix analyze')", fault.ErrKBStale, version, Version)
+	case version > Version:
+		return fmt.Errorf("%w, schema v%d was written by a newer eulix (this build supports v%d), upgrade eulix", fault.ErrKBStale, version, Version)
+	}
+	return nil
+}
+
+// Stamp marks every JSON artifact present with schema version, Version for
+// what this build writes
+func Stamp(eulixDir string, version int) error {
+	for _, name := range jsonArtifacts {
+		path := filepath.Join(eulixDir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := stampFile(path, version); err != nil {
+			return fmt.Errorf("failed to stamp %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func stampFile(path string, version int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Drop an existing marker so restamping is idempotent
+	if loc := versionPattern.FindIndex(data); loc != nil {
+		rest := bytes.TrimLeft(data[loc[1]:], " \t\r\n")
+		rest = bytes.TrimPrefix(rest, []byte(","))
+		data = append([]byte("{"), rest...)
+	}
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return fmt.Errorf("not a JSON object")
+	}
+
+	body := bytes.TrimLeft(trimmed[1:], " \t\r\n")
+	marker := fmt.Sprintf(`{"schema_version": %d`, version)
+
+	var out bytes.Buffer
+	out.Grow(len(data) + len(marker) + 2)
+	out.WriteString(marker)
+	if len(body) > 0 && body[0] != '}' {
+		out.WriteString(",\n  ")
+	}
+	out.Write(body)
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
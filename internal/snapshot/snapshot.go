@@ -0,0 +1,346 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"eulix/internal/schema"
+)
+
+// A snapshot is a gzipped tar of the analyze output in .eulix, kept in
+// .eulix/snapshots. The answer cache, config and lock stay out: the cache is
+// keyed on checksum.json so it stays valid across a restore on its own.
+
+// Dir is where snapshots are kept, relative to .eulix
+const Dir = "snapshots"
+
+// manifestName is the first entry of every snapshot archive
+const manifestName = "snapshot.json"
+
+// Artifacts are the files a snapshot carries, whichever of them exist
+var Artifacts = []string{
+	"kb.json",
+	"kb_index.json",
+	"kb_call_graph.json",
+	"kb_summary.json",
+	"chunks.json",
+	"vectors.bin",
+	"context.json",
+	"checksum.json",
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Info describes a snapshot, it is stored in the archive as snapshot.json
+type Info struct {
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version"`
+	Files         []string  `json:"files"`
+	Path          string    `json:"-"`
+	Size          int64     `json:"-"`
+}
+
+// Path is where the snapshot called name lives
+func Path(eulixDir, name string) string {
+	return filepath.Join(eulixDir, Dir, name+".tar.gz")
+}
+
+// DefaultName is a timestamp, used when create gets no name
+func DefaultName() string {
+	return time.Now().Format("20060102-150405")
+}
+
+// Create archives the artifacts in eulixDir as a snapshot called name.
+// An existing snapshot with that name is only replaced when overwrite is set.
+func Create(eulixDir, name string, overwrite bool) (*Info, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid snapshot name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+
+	path := Path(eulixDir, name)
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return nil, fmt.Errorf("snapshot %q already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	return Write(eulixDir, name, path)
+}
+
+// Write archives the artifacts in eulixDir to path, which can be anywhere.
+// The archive is written next to path and renamed, a failed write leaves no
+// half archive behind under the real name.
+func Write(eulixDir, name, path string) (*Info, error) {
+	info := &Info{Name: name, CreatedAt: time.Now(), Path: path}
+	for _, file := range Artifacts {
+		if _, err := os.Stat(filepath.Join(eulixDir, file)); err == nil {
+			info.Files = append(info.Files, file)
+		}
+	}
+	if len(info.Files) == 0 {
+		return nil, fmt.Errorf("no knowledge base found in %s, run 'eulix analyze' first", eulixDir)
+	}
+	version, err := schema.Detect(eulixDir)
+	if err != nil {
+		return nil, err
+	}
+	info.SchemaVersion = version
+
+	tmp := path + ".tmp"
+	if err := writeArchive(tmp, eulixDir, info); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	if stat, err := os.Stat(path); err == nil {
+		info.Size = stat.Size()
+	}
+	return info, nil
+}
+
+func writeArchive(path, eulixDir string, info *Info) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: info.CreatedAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	for _, file := range info.Files {
+		if err := addFile(tw, filepath.Join(eulixDir, file), file); err != nil {
+			return fmt.Errorf("failed to add %s: %w", file, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func addFile(tw *tar.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// List returns the snapshots in eulixDir, newest first. Archives that can't
+// be read are skipped.
+func List(eulixDir string) ([]Info, error) {
+	matches, err := filepath.Glob(filepath.Join(eulixDir, Dir, "*.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Info
+	for _, path := range matches {
+		info, err := Read(path)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, *info)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Read loads the manifest of the snapshot archive at path
+func Read(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a snapshot: %w", path, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, fmt.Errorf("%s is not a snapshot", path)
+	}
+
+	var info Info
+	if err := json.NewDecoder(tr).Decode(&info); err != nil {
+		return nil, fmt.Errorf("%s has a broken manifest: %w", path, err)
+	}
+
+	info.Path = path
+	if stat, err := f.Stat(); err == nil {
+		info.Size = stat.Size()
+	}
+	return &info, nil
+}
+
+// Resolve turns a snapshot name, or the path of an archive copied from
+// another machine, into the archive path
+func Resolve(eulixDir, nameOrPath string) (string, error) {
+	if namePattern.MatchString(nameOrPath) && !strings.HasSuffix(nameOrPath, ".tar.gz") {
+		path := Path(eulixDir, nameOrPath)
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("no snapshot named %q, see 'eulix snapshot list'", nameOrPath)
+		}
+		return path, nil
+	}
+	if _, err := os.Stat(nameOrPath); err != nil {
+		return "", fmt.Errorf("snapshot %s not found", nameOrPath)
+	}
+	return nameOrPath, nil
+}
+
+// Restore replaces the artifacts in eulixDir with those in the archive.
+// Everything is extracted before anything is replaced, and artifacts the
+// snapshot doesn't have are removed so nothing from the newer analyze is left
+// mixed in.
+func Restore(eulixDir, path string) (*Info, error) {
+	info, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.SchemaVersion > schema.Version {
+		return nil, fmt.Errorf("snapshot %q has schema v%d, this eulix supports v%d, upgrade eulix", info.Name, info.SchemaVersion, schema.Version)
+	}
+
+	staging, err := os.MkdirTemp(eulixDir, ".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	extracted, err := extract(path, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range Artifacts {
+		target := filepath.Join(eulixDir, file)
+		if !extracted[file] {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %w", file, err)
+			}
+			continue
+		}
+		if err := os.Rename(filepath.Join(staging, file), target); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", file, err)
+		}
+	}
+
+	return info, nil
+}
+
+// extract unpacks the artifacts of a snapshot into dir. Only names from
+// Artifacts are accepted, an archive from elsewhere can't write outside .eulix.
+func extract(path, dir string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	allowed := make(map[string]bool, len(Artifacts))
+	for _, file := range Artifacts {
+		allowed[file] = true
+	}
+
+	extracted := make(map[string]bool)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if hdr.Name == manifestName {
+			continue
+		}
+		if !allowed[hdr.Name] || hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("snapshot has unexpected entry %q", hdr.Name)
+		}
+
+		out, err := os.Create(filepath.Join(dir, hdr.Name))
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(out, tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+		extracted[hdr.Name] = true
+	}
+
+	if len(extracted) == 0 {
+		return nil, fmt.Errorf("snapshot has no knowledge base files")
+	}
+	return extracted, nil
+}
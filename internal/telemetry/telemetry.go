@@ -0,0 +1,325 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"eulix/internal/fault"
+	"eulix/internal/version"
+)
+
+// Telemetry is off until someone runs `eulix telemetry on`. What is kept is
+// a handful of counters: which commands ran, how long they took in coarse
+// buckets and what kind of error they failed with. No arguments, paths,
+// queries, error messages or code, the report printed by `eulix telemetry
+// status` is exactly what gets sent. Counters are sent at most once a day.
+
+// DefaultEndpoint receives reports, EULIX_TELEMETRY_URL overrides it
+const DefaultEndpoint = "https://telemetry.eulix.dev/v1/usage"
+
+// sendInterval is how long counters pile up before they are sent, and how
+// long to wait after a failed send
+const sendInterval = 24 * time.Hour
+
+// Settings is the user's choice, kept in ~/.eulix/telemetry.json
+type Settings struct {
+	Enabled   bool      `json:"enabled"`
+	ID        string    `json:"id,omitempty"` // random, not derived from the machine or user
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Report is what is sent
+type Report struct {
+	ID       string                    `json:"id"`
+	Version  string                    `json:"version"`
+	OS       string                    `json:"os"`
+	Arch     string                    `json:"arch"`
+	Since    time.Time                 `json:"since"`
+	Commands map[string]int            `json:"commands"`
+	Errors   map[string]int            `json:"errors"`
+	Latency  map[string]map[string]int `json:"latency"` // command -> bucket -> count
+}
+
+// pending is the report being collected in ~/.eulix/telemetry-pending.json
+type pending struct {
+	Report
+	LastAttempt time.Time `json:"last_attempt"` // when sending last failed
+}
+
+// Dir holds the settings and the pending report
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".eulix"), nil
+}
+
+// Disabled reports whether the environment forbids telemetry, whatever the
+// settings say: DO_NOT_TRACK=1 or EULIX_TELEMETRY=off
+func Disabled() bool {
+	if v := os.Getenv("DO_NOT_TRACK"); v != "" && v != "0" && v != "false" {
+		return true
+	}
+	switch os.Getenv("EULIX_TELEMETRY") {
+	case "0", "off", "false":
+		return true
+	}
+	return false
+}
+
+// Endpoint is where reports go
+func Endpoint() string {
+	if v := os.Getenv("EULIX_TELEMETRY_URL"); v != "" {
+		return v
+	}
+	return DefaultEndpoint
+}
+
+// Load reads the settings, missing settings mean off
+func Load() (*Settings, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	var s Settings
+	if err := readJSON(filepath.Join(dir, "telemetry.json"), &s); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetEnabled records the user's choice. Turning telemetry off deletes the
+// counters that weren't sent yet and forgets the ID.
+func SetEnabled(enabled bool) (*Settings, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	s, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Enabled = enabled
+	s.DecidedAt = time.Now().UTC()
+	if enabled && s.ID == "" {
+		s.ID = newID()
+	}
+	if !enabled {
+		s.ID = ""
+		if err := os.Remove(filepath.Join(dir, "telemetry-pending.json")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return s, writeJSON(filepath.Join(dir, "telemetry.json"), s)
+}
+
+// Pending returns the counters collected since the last report as they
+// would be sent
+func Pending(s *Settings) (*Report, error) {
+	p, err := loadPending()
+	if err != nil {
+		return nil, err
+	}
+	p.stamp(s.ID)
+	return &p.Report, nil
+}
+
+func loadPending() (*pending, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	var p pending
+	if err := readJSON(filepath.Join(dir, "telemetry-pending.json"), &p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// run is the command being timed, set by Start
+var run struct {
+	command string
+	started time.Time
+}
+
+// Start times a command. command is its path like "eulix cache list".
+func Start(command string) {
+	run.command = command
+	run.started = time.Now()
+}
+
+// Finish counts the command started with Start and sends the counters when
+// they are due. category is "" for success, see Category. It never fails
+// the command, telemetry problems are ignored.
+func Finish(category string) {
+	if run.command == "" || Disabled() {
+		return
+	}
+	command := run.command
+	run.command = ""
+
+	s, err := Load()
+	if err != nil || !s.Enabled || s.ID == "" {
+		return
+	}
+	p, err := loadPending()
+	if err != nil {
+		return
+	}
+	p.add(command, category, time.Since(run.started))
+
+	if p.due(time.Now()) {
+		p.stamp(s.ID)
+		if err := send(&p.Report); err == nil {
+			p = &pending{}
+		} else {
+			p.LastAttempt = time.Now().UTC()
+		}
+	}
+
+	if dir, err := Dir(); err == nil {
+		writeJSON(filepath.Join(dir, "telemetry-pending.json"), p)
+	}
+}
+
+func (r *Report) stamp(id string) {
+	r.ID = id
+	r.Version = version.Version
+	r.OS = runtime.GOOS
+	r.Arch = runtime.GOARCH
+}
+
+func (r *Report) add(command, category string, took time.Duration) {
+	if r.Since.IsZero() {
+		r.Since = time.Now().UTC()
+	}
+	if r.Commands == nil {
+		r.Commands = make(map[string]int)
+	}
+	if r.Errors == nil {
+		r.Errors = make(map[string]int)
+	}
+	if r.Latency == nil {
+		r.Latency = make(map[string]map[string]int)
+	}
+
+	r.Commands[command]++
+	if category != "" {
+		r.Errors[category]++
+	}
+	if r.Latency[command] == nil {
+		r.Latency[command] = make(map[string]int)
+	}
+	r.Latency[command][Bucket(took)]++
+}
+
+func (p *pending) due(now time.Time) bool {
+	return now.Sub(p.Since) >= sendInterval && now.Sub(p.LastAttempt) >= sendInterval
+}
+
+// Bucket rounds a duration to a coarse range
+func Bucket(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return "0-1s"
+	case d < 5*time.Second:
+		return "1-5s"
+	case d < 30*time.Second:
+		return "5-30s"
+	case d < 2*time.Minute:
+		return "30s-2m"
+	default:
+		return "2m+"
+	}
+}
+
+// Category names the kind of an error without anything from its message,
+// the fault code when it carries one
+func Category(err error) string {
+	if err == nil {
+		return ""
+	}
+	if code := fault.Code(err); code != "" {
+		return code
+	}
+
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_found"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "parse"
+	default:
+		return "other"
+	}
+}
+
+func send(r *Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	// Short timeout, an unreachable endpoint mustn't hold up the command
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Post(Endpoint(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,32 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"eulix/internal/fault"
+)
+
+func TestCategory(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("%w: model is required", fault.ErrConfig), fault.CodeConfig},
+		{fmt.Errorf("%w: run eulix analyze", fault.ErrKBMissing), fault.CodeKBMissing},
+		{fmt.Errorf("%w: %w", fault.ErrProviderUnavailable, context.DeadlineExceeded), fault.CodeProviderUnavailable},
+		{fmt.Errorf("%w: %w", fault.ErrBinaryMissing, fs.ErrNotExist), fault.CodeBinaryMissing},
+		{fmt.Errorf("request: %w", context.DeadlineExceeded), "timeout"},
+		{fmt.Errorf("open: %w", fs.ErrNotExist), "not_found"},
+		{errors.New("something else"), "other"},
+	}
+	for _, tt := range tests {
+		if got := Category(tt.err); got != tt.want {
+			t.Errorf("Category(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
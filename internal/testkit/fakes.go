@@ -0,0 +1,118 @@
+package testkit
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FakeLLM stands in for a provider, see llm.Client.SetProvider. It answers
+// with its replies in turn, the last one over again once they run out, and
+// keeps every prompt it was sent.
+type FakeLLM struct {
+	mu      sync.Mutex
+	replies []string
+	prompts []string
+}
+
+// Parrot is a FakeLLM answering with replies, "OK" without any
+func Parrot(replies ...string) *FakeLLM {
+	if len(replies) == 0 {
+		replies = []string{"OK"}
+	}
+	return &FakeLLM{replies: replies}
+}
+
+// Provider is the llm.Provider, streaming the reply word by word
+func (f *FakeLLM) Provider(ctx context.Context, prompt string, onText func(string)) (string, error) {
+	f.mu.Lock()
+	reply := f.replies[min(len(f.prompts), len(f.replies)-1)]
+	f.prompts = append(f.prompts, prompt)
+	f.mu.Unlock()
+
+	if onText != nil {
+		for _, word := range strings.SplitAfter(reply, " ") {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			onText(word)
+		}
+	}
+	return reply, nil
+}
+
+// Prompts are the prompts sent so far, in order
+func (f *FakeLLM) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
+
+// FakeEmbedder stands in for eulix_embed. It hashes words into buckets, so
+// texts sharing words come out similar and the same text always gives the
+// same vector.
+type FakeEmbedder struct{}
+
+// Mimic is a FakeEmbedder with vectors of Dimension
+func Mimic() *FakeEmbedder {
+	return &FakeEmbedder{}
+}
+
+// EmbedQueryBinary embeds text, lowercased and split into words and
+// identifier parts, into a unit vector
+func (e *FakeEmbedder) EmbedQueryBinary(text string) ([]float32, error) {
+	vec := make([]float32, Dimension)
+	for _, word := range words(text) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		vec[h.Sum32()%Dimension]++
+	}
+
+	norm := 0.0
+	for _, v := range vec {
+		norm += float64(v * v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec, nil
+}
+
+// Embed is EmbedQueryBinary, to match embeddings.Embedder
+func (e *FakeEmbedder) Embed(text string) ([]float32, error) {
+	return e.EmbedQueryBinary(text)
+}
+
+// words splits on anything but letters and digits and at lower to upper
+// case changes, so ParseConfig counts as parse and config
+func words(text string) []string {
+	var out []string
+	var word []rune
+	flush := func() {
+		if len(word) > 1 {
+			out = append(out, strings.ToLower(string(word)))
+		}
+		word = word[:0]
+	}
+	var prev rune
+	for _, r := range text {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	flush()
+	return out
+}
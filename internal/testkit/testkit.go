@@ -0,0 +1,104 @@
+// Package testkit is what tests need to run eulix offline: fixture knowledge
+// bases from testdata/, a fake LLM and embedder, and golden files. Only
+// tests import it.
+//
+// A fixture is a .eulix directory as analyze writes it, minus vectors.bin,
+// which Fixture embeds with the fake embedder so semantic search works too.
+// Golden files live in the testdata/ of the package under test, run
+//
+//	go test ./internal/query -update
+//
+// to rewrite them after an intended change and review the diff.
+package testkit
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"eulix/internal/chunkstore"
+	"eulix/internal/config"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Dimension is the fake embedder's vector size, and the fixtures'
+const Dimension = 64
+
+// Fixture copies the fixture KB name from testkit's testdata into a temp
+// dir and embeds its chunks. It returns the .eulix path, the project root
+// is its parent.
+func Fixture(t testing.TB, name string) string {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(0)
+	src := filepath.Join(filepath.Dir(file), "testdata", name)
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatalf("fixture %s: %v", name, err)
+	}
+
+	eulixDir := filepath.Join(t.TempDir(), ".eulix")
+	if err := os.MkdirAll(eulixDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(eulixDir, e.Name()), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := chunkstore.Load(eulixDir)
+	if err != nil {
+		t.Fatalf("fixture %s: %v", name, err)
+	}
+	embedder := Mimic()
+	vectors := &chunkstore.Vectors{Dimension: Dimension, ByID: make(map[string][]float32, len(store.Chunks))}
+	for id, chunk := range store.Chunks {
+		vectors.ByID[id], _ = embedder.EmbedQueryBinary(chunk.Content)
+	}
+	if err := vectors.Save(eulixDir); err != nil {
+		t.Fatal(err)
+	}
+	return eulixDir
+}
+
+// Config is a config for fixtures: the fake embedder's dimension, a hosted
+// model name that never gets called and no cache or hooks
+func Config() *config.Config {
+	cfg := &config.Config{}
+	cfg.LLM.Model = "fake"
+	cfg.LLM.MaxTokens = 8000
+	cfg.Embeddings.Model = "fake"
+	cfg.Embeddings.Dimension = Dimension
+	return cfg
+}
+
+// Golden compares got with testdata/name.golden in the package under test,
+// writing it instead with -update
+func Golden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run with -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file, run with -update if that's intended\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
@@ -0,0 +1,395 @@
+package tour
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eulix/internal/fault"
+	"eulix/internal/llm"
+	"eulix/internal/query"
+)
+
+// A tour is a reading order for someone new to the code: where it starts,
+// the modules the rest leans on, then the types everything passes around.
+// Stops are picked from the KB alone, the LLM only writes the summaries.
+
+// Sections in the order they are visited
+const (
+	SectionEntryPoints = "Entry points"
+	SectionModules     = "Key modules"
+	SectionTypes       = "Core data structures"
+)
+
+const (
+	maxEntryStops  = 6
+	maxModuleStops = 6
+	maxTypeStops   = 6
+	// maxExcerptLines is how much of a stop's code goes in a prompt or on screen
+	maxExcerptLines = 40
+	// describeBatch is how many stops go in one prompt
+	describeBatch = 6
+)
+
+// Stop is one place on the tour
+type Stop struct {
+	Section  string   `json:"section"`
+	Title    string   `json:"title"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	LineEnd  int      `json:"line_end,omitempty"`
+	Why      string   `json:"why"`                // why it is on the tour
+	Pointers []string `json:"pointers,omitempty"` // file:line worth opening next
+	Summary  string   `json:"summary,omitempty"`
+}
+
+// Location is file:line
+func (s Stop) Location() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+type Tour struct {
+	Project string `json:"project"`
+	Stops   []Stop `json:"stops"`
+}
+
+var (
+	describeLinePattern = regexp.MustCompile(`^\s*[-*]?\s*(\d+)\s*\|\s*(.+)$`)
+	identPattern        = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+// Build picks the stops from kb.json in eulixDir
+func Build(project, eulixDir string) (*Tour, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w, run 'eulix analyze' first: %w", fault.ErrKBMissing, err)
+	}
+	var kb query.KnowledgeBase
+	if err := json.Unmarshal(data, &kb); err != nil {
+		return nil, fmt.Errorf("failed to parse kb.json: %w", err)
+	}
+
+	t := &Tour{Project: project}
+	t.Stops = append(t.Stops, entryStops(&kb)...)
+	t.Stops = append(t.Stops, moduleStops(&kb)...)
+	t.Stops = append(t.Stops, typeStops(&kb)...)
+	return t, nil
+}
+
+// entryStops are main functions and commands first, routes grouped by file
+// since a handler on its own says little
+func entryStops(kb *query.KnowledgeBase) []Stop {
+	rank := map[string]int{"main": 0, "cli_command": 1, "api_endpoint": 2}
+	entries := append([]query.EntryPoint(nil), kb.EntryPoints...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if rank[entries[i].EntryType] != rank[entries[j].EntryType] {
+			return rank[entries[i].EntryType] < rank[entries[j].EntryType]
+		}
+		return entries[i].File < entries[j].File
+	})
+
+	var stops []Stop
+	routes := make(map[string]int) // file -> index in stops
+	for _, ep := range entries {
+		if ep.EntryType == "api_endpoint" {
+			pointer := fmt.Sprintf("%s:%d %s", ep.File, ep.Line, ep.Path)
+			if i, ok := routes[ep.File]; ok {
+				stops[i].Pointers = append(stops[i].Pointers, pointer)
+				continue
+			}
+			if len(stops) >= maxEntryStops {
+				continue
+			}
+			routes[ep.File] = len(stops)
+			stops = append(stops, Stop{
+				Section:  SectionEntryPoints,
+				Title:    "HTTP routes in " + path.Base(ep.File),
+				File:     ep.File,
+				Line:     ep.Line,
+				Why:      "requests come in here",
+				Pointers: []string{pointer},
+			})
+			continue
+		}
+
+		if len(stops) >= maxEntryStops {
+			continue
+		}
+		why := "the program starts here"
+		if ep.EntryType == "cli_command" {
+			why = "a command users run"
+		}
+		stop := Stop{Section: SectionEntryPoints, Title: ep.Function, File: ep.File, Line: ep.Line, Why: why}
+		if fn := findFunction(kb.Structure[ep.File], ep.Function); fn != nil {
+			stop.LineEnd = fn.LineEnd
+		}
+		stops = append(stops, stop)
+	}
+	return stops
+}
+
+// moduleStops are the directories most called from other directories, the
+// ones the rest of the code is built on
+func moduleStops(kb *query.KnowledgeBase) []Stop {
+	type located struct {
+		fn   query.KBFunction
+		file string
+	}
+	type module struct {
+		dir       string
+		fanIn     int
+		functions int
+		top       []located // most called first, pointers come from here
+	}
+	modules := make(map[string]*module)
+
+	for file, structure := range kb.Structure {
+		dir := path.Dir(file)
+		m := modules[dir]
+		if m == nil {
+			m = &module{dir: dir}
+			modules[dir] = m
+		}
+		for _, fn := range allFunctions(structure) {
+			m.functions++
+			external := 0
+			for _, caller := range fn.CalledBy {
+				if path.Dir(caller.File) != dir {
+					external++
+				}
+			}
+			m.fanIn += external
+			m.top = append(m.top, located{fn, file})
+		}
+	}
+
+	// Without callers in the KB, size is all there is to go on
+	var ranked []*module
+	for _, m := range modules {
+		if m.functions > 0 {
+			ranked = append(ranked, m)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].fanIn != ranked[j].fanIn {
+			return ranked[i].fanIn > ranked[j].fanIn
+		}
+		if ranked[i].functions != ranked[j].functions {
+			return ranked[i].functions > ranked[j].functions
+		}
+		return ranked[i].dir < ranked[j].dir
+	})
+
+	var stops []Stop
+	for _, m := range ranked[:min(len(ranked), maxModuleStops)] {
+		sort.Slice(m.top, func(i, j int) bool {
+			a, b := m.top[i], m.top[j]
+			if len(a.fn.CalledBy) != len(b.fn.CalledBy) {
+				return len(a.fn.CalledBy) > len(b.fn.CalledBy)
+			}
+			if a.file != b.file {
+				return a.file < b.file
+			}
+			return a.fn.LineStart < b.fn.LineStart
+		})
+		stop := Stop{
+			Section: SectionModules,
+			Title:   m.dir + "/",
+			File:    m.dir,
+			Why:     fmt.Sprintf("%d functions", m.functions),
+		}
+		if m.fanIn > 0 {
+			stop.Why = fmt.Sprintf("%d calls from other modules into %d functions", m.fanIn, m.functions)
+		}
+		for _, l := range m.top[:min(len(m.top), 3)] {
+			stop.Pointers = append(stop.Pointers, fmt.Sprintf("%s:%d %s", l.file, l.fn.LineStart, l.fn.Name))
+		}
+		stops = append(stops, stop)
+	}
+	return stops
+}
+
+// typeStops are the types named in the most signatures, with their methods
+// as a tiebreak
+func typeStops(kb *query.KnowledgeBase) []Stop {
+	type candidate struct {
+		class query.KBClass
+		file  string
+		uses  int
+	}
+
+	// Signatures naming each identifier, counted once per signature
+	inSignatures := make(map[string]int)
+	for _, structure := range kb.Structure {
+		for _, fn := range allFunctions(structure) {
+			seen := make(map[string]bool)
+			for _, word := range identPattern.FindAllString(fn.Signature, -1) {
+				if !seen[word] {
+					seen[word] = true
+					inSignatures[word]++
+				}
+			}
+		}
+	}
+
+	var candidates []candidate
+	for file, structure := range kb.Structure {
+		for _, class := range structure.Classes {
+			uses := inSignatures[class.Name]
+			if uses+len(class.Methods) > 0 {
+				candidates = append(candidates, candidate{class: class, file: file, uses: uses})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.uses != b.uses {
+			return a.uses > b.uses
+		}
+		if len(a.class.Methods) != len(b.class.Methods) {
+			return len(a.class.Methods) > len(b.class.Methods)
+		}
+		if a.class.Name != b.class.Name {
+			return a.class.Name < b.class.Name
+		}
+		return a.file < b.file
+	})
+
+	var stops []Stop
+	for _, c := range candidates[:min(len(candidates), maxTypeStops)] {
+		stops = append(stops, Stop{
+			Section: SectionTypes,
+			Title:   c.class.Name,
+			File:    c.file,
+			Line:    c.class.LineStart,
+			LineEnd: c.class.LineEnd,
+			Why:     fmt.Sprintf("in %d signatures, %d methods", c.uses, len(c.class.Methods)),
+		})
+	}
+	return stops
+}
+
+// Describe has the LLM summarize every stop from its code, onBatch is told
+// how many are done after each prompt
+func (t *Tour) Describe(client *llm.Client, projectDir string, onBatch func(done, total int)) error {
+	for start := 0; start < len(t.Stops); start += describeBatch {
+		end := min(start+describeBatch, len(t.Stops))
+		response, err := client.Complete(t.describePrompt(start, end, projectDir))
+		if err != nil {
+			return fmt.Errorf("LLM summary failed: %w", err)
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(response))
+		for scanner.Scan() {
+			m := describeLinePattern.FindStringSubmatch(scanner.Text())
+			if m == nil {
+				continue
+			}
+			n, err := strconv.Atoi(m[1])
+			if err == nil && n > start && n <= end {
+				t.Stops[n-1].Summary = strings.TrimSpace(m[2])
+			}
+		}
+		if onBatch != nil {
+			onBatch(end, len(t.Stops))
+		}
+	}
+	return nil
+}
+
+func (t *Tour) describePrompt(start, end int, projectDir string) string {
+	var sb strings.Builder
+	sb.WriteString(`You are writing an onboarding tour of a codebase for a new developer.
+For each stop below write two or three sentences: what it is responsible for and what to notice when reading it. Use only the code shown.
+
+Reply with one line per stop in exactly this format:
+number|summary
+`)
+	for i := start; i < end; i++ {
+		s := t.Stops[i]
+		fmt.Fprintf(&sb, "\n%d. %s: %s (%s, %s)\n", i+1, s.Section, s.Title, s.Location(), s.Why)
+		if excerpt := Excerpt(projectDir, s); excerpt != "" {
+			fmt.Fprintf(&sb, "```\n%s\n```\n", excerpt)
+		}
+	}
+	return sb.String()
+}
+
+// Excerpt is the code at a stop. A module has no single place to start, its
+// pointers stand in for it.
+func Excerpt(projectDir string, s Stop) string {
+	if s.Section == SectionModules {
+		return strings.Join(s.Pointers, "\n")
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectDir, s.File))
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(data), "\n")
+	start := s.Line - 1
+	if start < 0 || start >= len(lines) {
+		return ""
+	}
+	end := s.LineEnd
+	if end <= start || end > len(lines) {
+		end = len(lines)
+	}
+	if end-start > maxExcerptLines {
+		return strings.Join(lines[start:start+maxExcerptLines], "\n") + "\n..."
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// Markdown renders the tour with a section per part and a heading per stop
+func (t *Tour) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# A tour of %s\n", t.Project)
+
+	section := ""
+	for i, s := range t.Stops {
+		if s.Section != section {
+			section = s.Section
+			fmt.Fprintf(&sb, "\n## %s\n", section)
+		}
+		location := s.Location()
+		if s.Section == SectionModules {
+			location = s.File + "/"
+		}
+		fmt.Fprintf(&sb, "\n### %d. %s\n\n`%s`, %s\n", i+1, s.Title, location, s.Why)
+		if s.Summary != "" {
+			fmt.Fprintf(&sb, "\n%s\n", s.Summary)
+		}
+		if len(s.Pointers) > 0 {
+			sb.WriteString("\n")
+			for _, p := range s.Pointers {
+				fmt.Fprintf(&sb, "- `%s`\n", p)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func allFunctions(structure query.FileStructure) []query.KBFunction {
+	functions := append([]query.KBFunction(nil), structure.Functions...)
+	for _, class := range structure.Classes {
+		functions = append(functions, class.Methods...)
+	}
+	return functions
+}
+
+func findFunction(structure query.FileStructure, name string) *query.KBFunction {
+	for _, fn := range allFunctions(structure) {
+		if fn.Name == name {
+			return &fn
+		}
+	}
+	return nil
+}
@@ -1,14 +1,21 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
+	"eulix/internal/analyzer"
 	"eulix/internal/cache"
+	"eulix/internal/checksum"
 	"eulix/internal/config"
+	"eulix/internal/daemon"
 	"eulix/internal/query"
 
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -38,21 +45,83 @@ type Model struct {
 	viewport     viewport.Model
 	spinner      spinner.Model
 	router       *query.Router
+	remote       *daemon.Client
 	config       *config.Config
 	cacheManager *cache.Manager
 	width        int
 	height       int
 	err          error
 	processing   bool
+	kbModTime    time.Time
+	detector     *checksum.Detector
+	staleFiles   int
+	warned       bool // load warnings were shown for the current KB
+	followUps    []string // suggestions under the last answer, picked with 1-3
+	session      *query.Session // what this chat has established, sent with every question
+	history      []string       // past questions, most recent first
+	recall       int            // position in history while pressing up, -1 when not recalling
+	fuzzy        string         // history match Tab accepts when no question starts with the input
+	keys         KeyMap
+	mouse        bool // mouse captured for wheel scrolling and click-to-select
+	selected     int  // message picked with the mouse, -1 when none
+	answer       *query.Answer // last answer, shown in the context panel
+	asked        time.Time
+	elapsed      time.Duration // how long the last answer took
+	search       string        // retrieval capabilities, as of the last answer
+	showContext  bool
+	showStats    bool
+	panelWidth   int
+	stage        query.Progress // what the running question is doing
+	live         int            // message the answer streams into, -1 when none
+	overBudget   string         // question held back by [llm.budget], /send sends it
+	picking      string         // question held back to ask which definition it means
+	ambiguous    *query.AmbiguousError // the definitions picking chooses from, by number
+	picks        map[string]string     // definitions picked for picking so far
+	palette      *palette       // symbol search, nil when closed
+	lastQuestion string         // what the last answer was for, the Expand key asks it again
+	expansion    int            // how much wider the last answer retrieved, see query.Router.Expand
 }
 
 type queryResultMsg struct {
-	result string
-	err    error
+	question  string
+	result    string
+	followUps []string
+	entities  []string
+	answer    *query.Answer
+	search    string
+	err      error
+	stale    int
+	warnings []string
+}
+
+// queryEvent is a stage or a piece of answer text from the question being
+// answered
+type queryEvent struct {
+	progress query.Progress
+	text     string
+}
+
+type queryEventMsg struct {
+	event  queryEvent
+	events chan queryEvent
 }
 
 type switchToCacheViewerMsg struct{}
 
+// kbWatchMsg fires periodically to check whether analyze produced a new KB
+type kbWatchMsg struct{}
+
+type reloadResultMsg struct {
+	err  error
+	auto bool
+}
+
+const kbWatchInterval = 5 * time.Second
+
+type reanalyzeResultMsg struct {
+	err error
+}
+
 // Color scheme
 var (
 	primaryColor   = lipgloss.Color("#00D9FF")
@@ -75,16 +144,27 @@ func MainModel(router *query.Router, cfg *config.Config, cacheManager *cache.Man
 	ti.Width = 80
 	ti.PromptStyle = lipgloss.NewStyle().Foreground(primaryColor).Bold(true)
 	ti.TextStyle = lipgloss.NewStyle().Foreground(textColor)
+	ti.ShowSuggestions = true
+	ti.CompletionStyle = lipgloss.NewStyle().Foreground(mutedColor)
 
 	s := spinner.New()
 	s.Spinner = spinner.Points
 	s.Style = lipgloss.NewStyle().Foreground(primaryColor)
 
+	keys := Locksmith(cfg.TUI.Keys)
+
 	vp := viewport.New(80, 20)
-	// Disable mouse in viewport to allow text selection
-	vp.MouseWheelEnabled = false
+	// The wheel only scrolls when the mouse is captured, otherwise the
+	// terminal keeps it for text selection
+	vp.MouseWheelEnabled = cfg.TUI.Mouse
+	vp.KeyMap = keys.viewportKeys()
+
+	var kbModTime time.Time
+	if router != nil {
+		kbModTime = router.KBModTime()
+	}
 
-	return Model{
+	m := Model{
 		state:        StateIdle,
 		input:        ti,
 		viewport:     vp,
@@ -92,17 +172,61 @@ func MainModel(router *query.Router, cfg *config.Config, cacheManager *cache.Man
 		router:       router,
 		config:       cfg,
 		cacheManager: cacheManager,
+		kbModTime:    kbModTime,
+		detector:     checksum.HashHound("."),
+		session:      &query.Session{},
+		recall:       -1,
+		keys:         keys,
+		mouse:        cfg.TUI.Mouse,
+		selected:     -1,
+		live:         -1,
+		showContext:  true,
+		showStats:    true,
+		panelWidth:   defaultPanelWidth,
 		messages: []Message{
-			{Role: "system", Content: "Welcome to Eulix AI Code Assistant\n\nI can help you understand and navigate your codebase.\n\nTry asking:\n  - What does this function do?\n  - Explain the authentication flow\n  - Show me error handling patterns\n\nType /help to see available commands"},
+			{Role: "system", Content: welcomeText},
 		},
 	}
+	if router != nil {
+		router.Disambiguate(true)
+	}
+	m.loadHistory()
+	return m
+}
+
+// WithDaemon routes queries through a running daemon instead of the local router
+func (m Model) WithDaemon(client *daemon.Client) Model {
+	client.Disambiguate(true)
+	m.remote = client
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
+	cmds := []tea.Cmd{
 		textinput.Blink,
-		tea.DisableMouse, // Disable mouse capture to allow text selection
-	)
+		mouseMode(m.mouse),
+		watchKB(),
+	}
+	if len(m.history) == 0 {
+		cmds = append(cmds, loadOnboarding(".eulix"))
+	}
+	return tea.Batch(cmds...)
+}
+
+func watchKB() tea.Cmd {
+	return tea.Tick(kbWatchInterval, func(time.Time) tea.Msg {
+		return kbWatchMsg{}
+	})
+}
+
+// reloadKB re-reads the knowledge base, through the daemon when there is one
+func (m Model) reloadKB(auto bool) tea.Cmd {
+	return func() tea.Msg {
+		if m.remote != nil {
+			return reloadResultMsg{err: m.remote.Reload(), auto: auto}
+		}
+		return reloadResultMsg{err: m.router.Reload(), auto: auto}
+	}
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -110,10 +234,75 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "esc":
+		if m.palette != nil {
+			return m.updatePalette(msg)
+		}
+
+		switch {
+		case m.processing && m.state == StateProcessing && key.Matches(msg, m.keys.Interrupt):
+			m.stage = query.Progress{Stage: "stopping"}
+			return m, m.interrupt()
+
+		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 
+		case key.Matches(msg, m.keys.History):
+			return m, func() tea.Msg {
+				return switchToCacheViewerMsg{}
+			}
+
+		case key.Matches(msg, m.keys.Expand):
+			if m.processing || m.lastQuestion == "" {
+				return m, nil
+			}
+			return m.expand()
+
+		case key.Matches(msg, m.keys.Palette):
+			m.palette = openPalette(".eulix")
+			return m, nil
+
+		case key.Matches(msg, m.keys.Copy):
+			m.messages = append(m.messages, Message{
+				Role:    "system",
+				Content: m.copySelection(),
+			})
+			m.viewport.SetContent(m.renderMessages())
+			m.viewport.GotoBottom()
+			return m, nil
+
+		case key.Matches(msg, m.keys.ContextPanel):
+			m.showContext = !m.showContext
+			m.layout()
+			return m, nil
+
+		case key.Matches(msg, m.keys.StatsPanel):
+			m.showStats = !m.showStats
+			m.layout()
+			return m, nil
+
+		case key.Matches(msg, m.keys.PanelGrow):
+			m.resizePanels(panelStep)
+			return m, nil
+
+		case key.Matches(msg, m.keys.PanelShrink):
+			m.resizePanels(-panelStep)
+			return m, nil
+
+		case key.Matches(msg, m.keys.Reanalyze):
+			if m.processing || m.staleFiles == 0 {
+				return m, nil
+			}
+			m.processing = true
+			m.messages = append(m.messages, Message{
+				Role:    "system",
+				Content: fmt.Sprintf("Re-analyzing %d changed files...", m.staleFiles),
+			})
+			m.viewport.SetContent(m.renderMessages())
+			m.viewport.GotoBottom()
+			return m, tea.Batch(m.spinner.Tick, m.reanalyze())
+		}
+
+		switch msg.String() {
 		case "enter":
 			if m.processing {
 				return m, nil
@@ -128,57 +317,219 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m.handleCommand(query)
 			}
 
-			m.messages = append(m.messages, Message{
-				Role:    "user",
-				Content: query,
-			})
+			// A new question, not the one definitions were being picked for
+			m.picks = nil
+			return m.submit(query)
 
-			m.input.SetValue("")
-			m.processing = true
-			m.state = StateProcessing
+		case "up", "down":
+			// Shell style recall, only from an empty input so suggestions can still be cycled
+			if !m.processing && len(m.history) > 0 && (m.input.Value() == "" || m.recall >= 0) {
+				m.recallHistory(msg.String() == "up")
+				return m, nil
+			}
 
-			return m, tea.Batch(
-				m.spinner.Tick,
-				m.processQuery(query),
-			)
+		case "tab":
+			if m.fuzzy != "" && len(m.input.MatchedSuggestions()) == 0 {
+				m.input.SetValue(m.fuzzy)
+				m.input.CursorEnd()
+				m.fuzzy = ""
+				return m, nil
+			}
+
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			// Quick-pick a definition or a follow-up, only on an empty input
+			// so typing still works
+			n := int(msg.String()[0] - '1')
+			if !m.processing && m.input.Value() == "" && m.ambiguous != nil && n < len(m.ambiguous.Locations) {
+				return m.pick(m.ambiguous.Locations[n])
+			}
+			if !m.processing && m.input.Value() == "" && n < len(m.followUps) {
+				return m.submit(m.followUps[n])
+			}
 		}
 
+	case queryEventMsg:
+		if !m.processing {
+			return m, waitForEvent(msg.events)
+		}
+		if msg.event.text == "" {
+			m.stage = msg.event.progress
+			return m, waitForEvent(msg.events)
+		}
+		// Stream the answer into a message of its own, replaced by the
+		// full answer when it arrives
+		if m.live < 0 {
+			m.messages = append(m.messages, Message{Role: "assistant"})
+			m.live = len(m.messages) - 1
+		}
+		m.messages[m.live].Content += msg.event.text
+		m.viewport.SetContent(m.renderMessages())
+		m.viewport.GotoBottom()
+		return m, waitForEvent(msg.events)
+
 	case queryResultMsg:
 		m.processing = false
+		m.stage = query.Progress{}
+		m.followUps = msg.followUps
+		m.session.Track(msg.entities)
+		m.elapsed = time.Since(m.asked)
+		if msg.search != "" {
+			m.search = msg.search
+		}
 
-		if msg.err != nil {
+		live := m.live
+		m.live = -1
+		m.overBudget = ""
+		m.picking, m.ambiguous = "", nil
+		var budget *query.BudgetError
+		var ambiguous *query.AmbiguousError
+		if errors.As(msg.err, &ambiguous) {
+			m.picking, m.ambiguous = msg.question, ambiguous
+			content := fmt.Sprintf("%v, which one do you mean?\n", ambiguous)
+			for i, loc := range ambiguous.Locations {
+				content += fmt.Sprintf("\n  %d) %s", i+1, loc)
+			}
+			content += "\n\nPress the number, or type /send to ask about all of them."
+			m.messages = append(m.messages, Message{Role: "system", Content: content})
+			m.state = StateIdle
+		} else if errors.As(msg.err, &budget) {
+			m.overBudget = msg.question
+			m.messages = append(m.messages, Message{
+				Role:    "system",
+				Content: fmt.Sprintf("[!] Not sent: this %v.\n\nType /send to send it anyway, or ask something narrower.", budget),
+			})
+			m.state = StateIdle
+		} else if msg.err != nil {
+			m.picks = nil
+			if live >= 0 {
+				m.messages = append(m.messages[:live], m.messages[live+1:]...)
+			}
 			m.messages = append(m.messages, Message{
 				Role:    "error",
 				Content: fmt.Sprintf("Error: %v", msg.err),
 			})
 			m.state = StateError
 		} else {
+			m.picks = nil
+			m.lastQuestion = msg.question
+			content := msg.result
+			if msg.answer != nil && msg.answer.MissingContext && m.expansion < query.MaxExpansion {
+				content += fmt.Sprintf("\n\n[!] The context may have missed what this needs. Press %s to ask again with more context.", helpKey(m.keys.Expand))
+			}
+			if msg.stale > 0 {
+				content += fmt.Sprintf("\n\n[!] KB is %d files stale, answer may not match the code. Press %s to reanalyze.", msg.stale, helpKey(m.keys.Reanalyze))
+			}
+			if len(msg.followUps) > 0 {
+				content += "\n\nFollow-ups (press the number):"
+				for i, q := range msg.followUps {
+					content += fmt.Sprintf("\n  %d) %s", i+1, q)
+				}
+			}
+			if live >= 0 {
+				m.messages[live].Content = content
+			} else {
+				m.messages = append(m.messages, Message{
+					Role:    "assistant",
+					Content: content,
+				})
+			}
+			m.answer = msg.answer
+			m.state = StateDisplaying
+		}
+		m.staleFiles = msg.stale
+
+		if len(msg.warnings) > 0 && !m.warned {
 			m.messages = append(m.messages, Message{
-				Role:    "assistant",
-				Content: msg.result,
+				Role:    "system",
+				Content: "[!] " + strings.Join(msg.warnings, "\n[!] "),
 			})
-			m.state = StateDisplaying
 		}
+		m.warned = true
 
 		m.viewport.SetContent(m.renderMessages())
 		m.viewport.GotoBottom()
 
 		return m, nil
 
+	case onboardingMsg:
+		// Only while the welcome is all there is, a question asked before
+		// the KB was read keeps its follow-ups
+		if len(m.messages) == 1 && !m.processing {
+			m.messages[0].Content = msg.text
+			m.followUps = msg.questions
+			m.viewport.SetContent(m.renderMessages())
+		}
+		return m, nil
+
+	case kbWatchMsg:
+		// The daemon watches for itself, only a local router needs this
+		if m.remote == nil && !m.processing && m.router.KBModTime().After(m.kbModTime) {
+			m.processing = true
+			return m, tea.Batch(m.spinner.Tick, m.reloadKB(true), watchKB())
+		}
+		return m, watchKB()
+
+	case reanalyzeResultMsg:
+		if msg.err != nil {
+			m.processing = false
+			m.messages = append(m.messages, Message{
+				Role:    "error",
+				Content: fmt.Sprintf("Re-analyze failed: %v", msg.err),
+			})
+			m.viewport.SetContent(m.renderMessages())
+			m.viewport.GotoBottom()
+			return m, nil
+		}
+		m.staleFiles = 0
+		return m, m.reloadKB(false)
+
+	case reloadResultMsg:
+		m.processing = false
+		if m.router != nil {
+			m.kbModTime = m.router.KBModTime()
+		}
+
+		if msg.err == nil {
+			// The new KB may line up differently, show its warnings again
+			m.warned = false
+		}
+
+		if msg.err != nil {
+			m.messages = append(m.messages, Message{
+				Role:    "error",
+				Content: fmt.Sprintf("Reload failed: %v", msg.err),
+			})
+		} else if msg.auto {
+			m.messages = append(m.messages, Message{
+				Role:    "system",
+				Content: "Knowledge base changed on disk and was reloaded.",
+			})
+		} else {
+			m.messages = append(m.messages, Message{
+				Role:    "system",
+				Content: "Knowledge base reloaded.",
+			})
+		}
+		m.viewport.SetContent(m.renderMessages())
+		m.viewport.GotoBottom()
+		return m, nil
+
 	case spinner.TickMsg:
 		if m.processing {
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
 		}
 
+	case tea.MouseMsg:
+		if m.mouse && msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress && msg.X < m.conversationWidth() {
+			m.selectAt(msg.Y)
+			return m, nil
+		}
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.viewport.Width = msg.Width - 4
-		m.viewport.Height = msg.Height - 10
-		m.input.Width = msg.Width - 8
-
-		m.viewport.SetContent(m.renderMessages())
+		m.layout()
 
 	case switchToCacheViewerMsg:
 		if m.cacheManager == nil {
@@ -191,7 +542,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		entries, err := m.cacheManager.ListAll()
+		groups, err := m.cacheManager.History()
 		if err != nil {
 			m.messages = append(m.messages, Message{
 				Role:    "error",
@@ -202,7 +553,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		if len(entries) == 0 {
+		if len(groups) == 0 {
 			m.messages = append(m.messages, Message{
 				Role:    "system",
 				Content: "No cache entries found. Your question history is empty.",
@@ -212,7 +563,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		cacheModel := HistoryView(entries, m.cacheManager)
+		cacheModel := HistoryView(groups, m.cacheManager).WithKeys(m.keys)
 		cacheModel.width = m.width
 		cacheModel.height = m.height
 
@@ -221,6 +572,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cacheModel.viewport.Width = m.width - 4
 		cacheModel.viewport.Height = m.height - 6
 
+		if m.mouse {
+			// The history view has no mouse handling
+			return cacheModel, tea.Batch(cacheModel.Init(), tea.DisableMouse)
+		}
 		return cacheModel, cacheModel.Init()
 	}
 
@@ -228,6 +583,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.input, cmd = m.input.Update(msg)
 	}
 
+	if _, ok := msg.(tea.KeyMsg); ok {
+		// Typing ends recall, and the fuzzy hint follows the input
+		m.recall = -1
+		m.fuzzy = ""
+		if len(m.input.MatchedSuggestions()) == 0 {
+			m.fuzzy = fuzzyMatch(m.input.Value(), m.history)
+		}
+	}
+
 	m.viewport, _ = m.viewport.Update(msg)
 
 	return m, cmd
@@ -243,7 +607,13 @@ func (m Model) handleCommand(command string) (tea.Model, tea.Cmd) {
 	case "/help":
 		m.messages = append(m.messages, Message{
 			Role:    "system",
-			Content: "AVAILABLE COMMANDS\n\n  /help     Show this help message\n  /history  View cached queries and responses\n  /clear    Clear conversation history\n  /stats    Show system statistics\n  /quit     Exit the application\n\nKEYBOARD SHORTCUTS\n\n  Enter     Send message\n  Esc       Exit application\n  Ctrl+C    Force exit",
+			Content: "AVAILABLE COMMANDS\n\n  /help     Show this help message\n  /history  View cached queries and responses\n  /clear    Clear conversation history\n  /stats    Show system statistics\n  /reload   Reload the knowledge base after analyze\n  /frequent List the questions you ask most\n  /remember Keep a note in mind for this session, no note shows the memory\n  /mouse    Toggle mouse capture: wheel scrolling and click-to-select\n  /send     Send a question held back by the [llm.budget] limits, or ask about every definition of an ambiguous name\n  /more     Ask the last question again with more context\n  /quit     Exit the application\n\nKEYBOARD SHORTCUTS\n\n" +
+				"  Enter     Send message\n  Up/Down   Recall earlier questions (empty input)\n  Tab       Complete from earlier questions\n  1-3       Ask a suggested follow-up (empty input)\n" +
+				fmt.Sprintf("  %-9s Reanalyze when the KB is stale\n  %-9s Open query history\n  %-9s Search symbols, Enter inserts one, Tab shows its source\n  %-9s Ask the last question again with more context\n  %-9s Copy the selected message, else the last answer\n  %-9s Stop an answer, keeping what was generated\n  %-9s Scroll the conversation\n  %-9s Show or hide the context panel\n  %-9s Show or hide the stats panel\n  %-9s Resize the panels\n  %-9s Exit application\n\nKeys can be changed in [tui.keys] in eulix.toml",
+					helpKey(m.keys.Reanalyze), helpKey(m.keys.History), helpKey(m.keys.Palette), helpKey(m.keys.Expand), helpKey(m.keys.Copy), helpKey(m.keys.Interrupt),
+					helpKey(m.keys.PageUp)+"/"+helpKey(m.keys.PageDown),
+					helpKey(m.keys.ContextPanel), helpKey(m.keys.StatsPanel),
+					helpKey(m.keys.PanelGrow)+"/"+helpKey(m.keys.PanelShrink), helpKey(m.keys.Quit)),
 		})
 		m.viewport.SetContent(m.renderMessages())
 		m.viewport.GotoBottom()
@@ -259,6 +629,11 @@ func (m Model) handleCommand(command string) (tea.Model, tea.Cmd) {
 		m.messages = []Message{
 			{Role: "system", Content: "Conversation cleared. How can I help you?"},
 		}
+		m.followUps = nil
+		m.selected = -1
+		m.live = -1
+		m.answer = nil
+		m.session = &query.Session{}
 		m.viewport.SetContent(m.renderMessages())
 		m.viewport.GotoTop()
 		m.input.SetValue("")
@@ -275,6 +650,96 @@ func (m Model) handleCommand(command string) (tea.Model, tea.Cmd) {
 		m.input.SetValue("")
 		return m, nil
 
+	case "/reload":
+		m.input.SetValue("")
+		if m.processing {
+			return m, nil
+		}
+		m.processing = true
+		return m, tea.Batch(m.spinner.Tick, m.reloadKB(false))
+
+	case "/frequent":
+		m.messages = append(m.messages, Message{
+			Role:    "system",
+			Content: m.frequentQueries(),
+		})
+		m.viewport.SetContent(m.renderMessages())
+		m.viewport.GotoBottom()
+		m.input.SetValue("")
+		return m, nil
+
+	case "/remember":
+		note := strings.TrimSpace(strings.TrimPrefix(command, parts[0]))
+		content := "Noted, I'll keep that in mind for this session."
+		if note == "" {
+			content = strings.TrimSpace(m.session.Memory())
+			if content == "" {
+				content = "Nothing remembered yet. Use /remember <note>, symbols you ask about are tracked automatically."
+			}
+		} else {
+			m.session.Remember(note)
+		}
+		m.messages = append(m.messages, Message{
+			Role:    "system",
+			Content: content,
+		})
+		m.viewport.SetContent(m.renderMessages())
+		m.viewport.GotoBottom()
+		m.input.SetValue("")
+		return m, nil
+
+	case "/mouse":
+		m.setMouse(!m.mouse)
+		content := "Mouse off. Select text with the terminal as usual."
+		if m.mouse {
+			content = fmt.Sprintf("Mouse on. The wheel scrolls, click a message to select it and %s copies it. Most terminals still select text with Shift held.", helpKey(m.keys.Copy))
+		}
+		m.messages = append(m.messages, Message{
+			Role:    "system",
+			Content: content,
+		})
+		m.viewport.SetContent(m.renderMessages())
+		m.viewport.GotoBottom()
+		m.input.SetValue("")
+		return m, mouseMode(m.mouse)
+
+	case "/send":
+		if m.ambiguous != nil {
+			return m.pick("")
+		}
+		if m.overBudget == "" {
+			m.messages = append(m.messages, Message{
+				Role:    "system",
+				Content: "No question is waiting on the budget.",
+			})
+			m.viewport.SetContent(m.renderMessages())
+			m.viewport.GotoBottom()
+			m.input.SetValue("")
+			return m, nil
+		}
+		if m.remote != nil {
+			m.remote.WaiveBudget()
+		} else {
+			m.router.WaiveBudget()
+		}
+		return m.submit(m.overBudget)
+
+	case "/more":
+		m.input.SetValue("")
+		if m.processing {
+			return m, nil
+		}
+		if m.lastQuestion == "" {
+			m.messages = append(m.messages, Message{
+				Role:    "system",
+				Content: "Nothing to retry yet, ask a question first.",
+			})
+			m.viewport.SetContent(m.renderMessages())
+			m.viewport.GotoBottom()
+			return m, nil
+		}
+		return m.expand()
+
 	case "/quit":
 		return m, tea.Quit
 
@@ -304,12 +769,22 @@ func (m Model) getSystemStats() string {
 		cacheStatus = "Enabled"
 	}
 
-	return fmt.Sprintf("SYSTEM STATISTICS\n\n  Total Messages    %d\n  Your Questions    %d\n  AI Responses      %d\n  Current State     %s\n  Cache Status      %s",
+	search := "Unknown"
+	if m.remote != nil {
+		if status, err := m.remote.Status(); err == nil {
+			search = status.Capabilities.String()
+		}
+	} else if m.router != nil {
+		search = m.router.Capabilities().String()
+	}
+
+	return fmt.Sprintf("SYSTEM STATISTICS\n\n  Total Messages    %d\n  Your Questions    %d\n  AI Responses      %d\n  Current State     %s\n  Cache Status      %s\n  Search            %s",
 		conversationLength,
 		userMessages,
 		userMessages,
 		m.getStateName(),
-		cacheStatus)
+		cacheStatus,
+		search)
 }
 
 func (m Model) getStateName() string {
@@ -353,10 +828,17 @@ func (m Model) View() string {
 		BorderStyle(lipgloss.NormalBorder()).
 		BorderForeground(borderColor).
 		Padding(1, 2).
-		Width(m.width - 2).
+		Width(m.conversationWidth() - 2).
 		Height(m.viewport.Height)
 
-	b.WriteString(viewportStyle.Render(m.viewport.View()))
+	conversation := viewportStyle.Render(m.viewport.View())
+	if m.palette != nil {
+		conversation = m.renderPalette(m.conversationWidth(), lipgloss.Height(conversation)-2)
+	}
+	if m.sideWidth() > 0 {
+		conversation = lipgloss.JoinHorizontal(lipgloss.Top, conversation, m.renderPanels(lipgloss.Height(conversation)))
+	}
+	b.WriteString(conversation)
 	b.WriteString("\n")
 
 	// Processing indicator
@@ -365,7 +847,12 @@ func (m Model) View() string {
 			Foreground(primaryColor).
 			Bold(true).
 			Padding(0, 2)
-		b.WriteString(processingStyle.Render(fmt.Sprintf("%s Processing your request...", m.spinner.View())))
+		status := "Processing your request..."
+		if m.stage.Stage != "" {
+			stage := m.stage.String()
+			status = fmt.Sprintf("%s%s... (%.1fs) | %s: stop", strings.ToUpper(stage[:1]), stage[1:], time.Since(m.asked).Seconds(), helpKey(m.keys.Interrupt))
+		}
+		b.WriteString(processingStyle.Render(fmt.Sprintf("%s %s", m.spinner.View(), status)))
 		b.WriteString("\n")
 	}
 
@@ -389,21 +876,185 @@ func (m Model) View() string {
 		Foreground(mutedColor).
 		Padding(0, 2)
 
-	helpText := "Enter: send | Esc: quit | /help: commands | Mouse selection enabled"
+	mouseHelp := "Mouse selection enabled"
+	if m.mouse {
+		mouseHelp = fmt.Sprintf("Click: select message, %s: copy", helpKey(m.keys.Copy))
+	}
+	helpText := fmt.Sprintf("Enter: send | %s: quit | /help: commands | %s", helpKey(m.keys.Quit), mouseHelp)
+	if m.fuzzy != "" {
+		helpText = fmt.Sprintf("Tab: %s | %s", truncate(m.fuzzy, 50), helpText)
+	}
+	if m.staleFiles > 0 {
+		helpText = fmt.Sprintf("%s: reanalyze (%d files stale) | %s", helpKey(m.keys.Reanalyze), m.staleFiles, helpText)
+	}
 	b.WriteString(helpStyle.Render(helpText))
 
 	return b.String()
 }
 
-func (m Model) processQuery(query string) tea.Cmd {
+// processQuery answers in the background, sending stages and answer text to
+// events and closing it when done
+func (m Model) processQuery(question string, events chan queryEvent) tea.Cmd {
+	return func() tea.Msg {
+		defer close(events)
+		report := func(p query.Progress) {
+			events <- queryEvent{progress: p}
+		}
+		stream := func(text string) {
+			events <- queryEvent{text: text}
+		}
+
+		// Cheap mtime check so long sessions notice edits made since analyze
+		stale, _ := m.detector.StaleFiles()
+
+		if m.remote != nil {
+			msg := answerResult(m.remote.AnswerStream(question, m.session, report, stream))
+			msg.question = question
+			msg.stale = stale
+			if status, err := m.remote.Status(); err == nil {
+				msg.search = status.Capabilities.String()
+				if !m.warned {
+					msg.warnings = status.Warnings
+				}
+			}
+			return msg
+		}
+		m.router.OnProgress(report)
+		m.router.OnText(stream)
+		defer m.router.OnProgress(nil)
+		defer m.router.OnText(nil)
+		msg := answerResult(m.router.AnswerInSession(question, m.session))
+		msg.question = question
+		msg.stale = stale
+		msg.search = m.router.Capabilities().String()
+		if !m.warned {
+			msg.warnings = m.router.Warnings()
+		}
+		return msg
+	}
+}
+
+func waitForEvent(events chan queryEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
+			return nil
+		}
+		return queryEventMsg{event: event, events: events}
+	}
+}
+
+// interrupt stops the answer in flight, the partial answer still arrives as
+// a queryResultMsg
+func (m Model) interrupt() tea.Cmd {
+	if m.remote != nil {
+		return func() tea.Msg {
+			m.remote.Interrupt()
+			return nil
+		}
+	}
+	m.router.Interrupt()
+	return nil
+}
+
+func answerResult(answer *query.Answer, err error) queryResultMsg {
+	if err != nil {
+		return queryResultMsg{err: err}
+	}
+	return queryResultMsg{result: answer.String(), followUps: answer.FollowUps, entities: answer.Entities, answer: answer}
+}
+
+// pick asks the question held back by an AmbiguousError again, about the
+// definition at location or about all of them for "". Earlier picks for the
+// same question are sent again, it may name more than one such symbol.
+func (m Model) pick(location string) (tea.Model, tea.Cmd) {
+	if m.picks == nil {
+		m.picks = make(map[string]string)
+	}
+	m.picks[m.ambiguous.Symbol] = location
+	question := m.picking
+	m.picking, m.ambiguous = "", nil
+	for symbol, loc := range m.picks {
+		if m.remote != nil {
+			m.remote.Pick(symbol, loc)
+		} else {
+			m.router.Pick(symbol, loc)
+		}
+	}
+	return m.submit(question)
+}
+
+// submit sends a question as if it was typed and Enter was pressed
+func (m Model) submit(question string) (tea.Model, tea.Cmd) {
+	m.expansion = 0
+	return m.send(question)
+}
+
+// expand asks the last question again, retrieving wider each time up to
+// query.MaxExpansion. The cached answer is the one being retried, it is
+// skipped and replaced.
+func (m Model) expand() (tea.Model, tea.Cmd) {
+	if m.expansion >= query.MaxExpansion {
+		m.messages = append(m.messages, Message{
+			Role:    "system",
+			Content: "Already retrieving as wide as it goes. Try naming the function or file the answer needs.",
+		})
+		m.viewport.SetContent(m.renderMessages())
+		m.viewport.GotoBottom()
+		return m, nil
+	}
+	m.expansion++
+	if m.remote != nil {
+		m.remote.Expand(m.expansion)
+	} else {
+		m.router.Expand(m.expansion)
+	}
+	m.messages = append(m.messages, Message{
+		Role:    "system",
+		Content: fmt.Sprintf("Asking again with more context (level %d of %d)...", m.expansion, query.MaxExpansion),
+	})
+	return m.send(m.lastQuestion)
+}
+
+func (m Model) send(question string) (tea.Model, tea.Cmd) {
+	m.messages = append(m.messages, Message{
+		Role:    "user",
+		Content: question,
+	})
+
+	m.input.SetValue("")
+	m.processing = true
+	m.state = StateProcessing
+	m.asked = time.Now()
+	m.followUps = nil
+	m.fuzzy = ""
+	m.remember(question)
+
+	events := make(chan queryEvent, 64)
+	return m, tea.Batch(
+		m.spinner.Tick,
+		m.processQuery(question, events),
+		waitForEvent(events),
+	)
+}
+
+// reanalyze runs the analyze pipeline in-process, output is dropped since
+// the TUI owns the screen
+func (m Model) reanalyze() tea.Cmd {
 	return func() tea.Msg {
-		result, err := m.router.Query(query)
-		return queryResultMsg{result: result, err: err}
+		_, err := analyzer.Run(".", m.config, analyzer.Options{})
+		return reanalyzeResultMsg{err: err}
 	}
 }
 
 func (m Model) renderMessages() string {
-	var b strings.Builder
+	return strings.Join(m.messageBlocks(), "")
+}
+
+// messageBlocks renders each message separately so a click can be mapped
+// back to the message under it
+func (m Model) messageBlocks() []string {
+	blocks := make([]string, 0, len(m.messages))
 
 	userStyle := lipgloss.NewStyle().
 		Foreground(primaryColor).
@@ -427,7 +1078,10 @@ func (m Model) renderMessages() string {
 		wrapWidth = 40
 	}
 
-	for _, msg := range m.messages {
+	selectedStyle := lipgloss.NewStyle().
+		Reverse(true)
+
+	for i, msg := range m.messages {
 		var prefix string
 		var style lipgloss.Style
 
@@ -447,6 +1101,9 @@ func (m Model) renderMessages() string {
 		}
 
 		header := style.Render(prefix)
+		if i == m.selected {
+			header = selectedStyle.Render(prefix) + systemStyle.Render(" selected")
+		}
 
 		// Format content based on role
 		var content string
@@ -457,11 +1114,10 @@ func (m Model) renderMessages() string {
 		}
 
 		fullMessage := fmt.Sprintf("%s\n%s", header, content)
-		b.WriteString(messagePadding.Render(fullMessage))
-		b.WriteString("\n")
+		blocks = append(blocks, messagePadding.Render(fullMessage)+"\n")
 	}
 
-	return b.String()
+	return blocks
 }
 
 // formatMarkdownResponse formats LLM responses with markdown-like styling
@@ -729,3 +1385,28 @@ func wrapText(text string, width int) string {
 
 	return result.String()
 }
+
+// copySelection copies the message picked with the mouse, or the last answer
+func (m Model) copySelection() string {
+	if m.selected >= 0 && m.selected < len(m.messages) {
+		return copyToClipboard(m.messages[m.selected].Content)
+	}
+	return m.copyLastAnswer()
+}
+
+// copyLastAnswer puts the latest assistant message on the clipboard
+func (m Model) copyLastAnswer() string {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == "assistant" {
+			return copyToClipboard(m.messages[i].Content)
+		}
+	}
+	return "Nothing to copy yet."
+}
+
+func copyToClipboard(text string) string {
+	if err := clipboard.WriteAll(text); err != nil {
+		return fmt.Sprintf("Copy failed: %v", err)
+	}
+	return "Copied to clipboard."
+}
@@ -6,9 +6,11 @@ import (
 	"time"
 
 	"eulix/internal/cache"
+	"eulix/internal/config"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -17,56 +19,51 @@ import (
 type CacheViewerModel struct {
 	list         list.Model
 	viewport     viewport.Model
-	entries      []cache.CacheEntry
+	groups       []cache.HistoryGroup
+	expanded     map[string]bool // groups showing their older answers, by key
 	cacheManager *cache.Manager
-	selected     int
+	selected     cacheItem // the entry open in the detail view
 	width        int
 	height       int
 	showDetail   bool
 	quitting     bool
+	keys         KeyMap
+	notice       string // result of the last copy or rating, shown in the help line
+	note         textinput.Model
+	editing      bool            // notes of the open entry are being edited
+	correcting   bool            // the note being edited is a correction
+	marked       map[string]bool // entries marked for a bulk delete or export, by hash
+	expiredOnly  bool            // list only questions whose latest answer expired
+	confirming   bool            // deleting the marked entries waits for y
+	pinned       map[string]bool // hashes pinned to eulix notes
+	corrections  map[string]cache.Correction
 }
 
+// cacheItem is a question, shown with its latest answer, or one of its
+// older answers when the group is expanded
 type cacheItem struct {
-	entry cache.CacheEntry
-	index int
-}
-type keyMap struct {
-	Up     key.Binding
-	Down   key.Binding
-	Enter  key.Binding
-	Delete key.Binding
-	Back   key.Binding
-	Quit   key.Binding
-}
-
-var keys = keyMap{
-	Up: key.NewBinding(
-		key.WithKeys("up", "k"),
-		key.WithHelp("↑/k", "up"),
-	),
-	Down: key.NewBinding(
-		key.WithKeys("down", "j"),
-		key.WithHelp("↓/j", "down"),
-	),
-	Enter: key.NewBinding(
-		key.WithKeys("enter"),
-		key.WithHelp("enter", "view details"),
-	),
-	Delete: key.NewBinding(
-		key.WithKeys("d", "delete"),
-		key.WithHelp("d", "delete entry"),
-	),
-	Back: key.NewBinding(
-		key.WithKeys("esc", "b"),
-		key.WithHelp("esc", "back"),
-	),
-	Quit: key.NewBinding(
-		key.WithKeys("q", "ctrl+c"),
-		key.WithHelp("q", "quit"),
-	),
+	entry     cache.CacheEntry
+	group     int  // index in groups
+	asked     int  // times the question was asked
+	older     int  // older answers in the group
+	child     bool // an older answer listed under its question
+	previous  bool // kept from before the code changed, read only
+	expanded  bool
+	marked    bool
+	pinned    bool
+	corrected bool
 }
 
 func (i cacheItem) Title() string {
+	if i.child {
+		label := "earlier answer"
+		if i.previous {
+			label = "answer from before the code changed"
+		}
+		title := fmt.Sprintf("    ↳ %s%s, %s", i.markPrefix(), label, i.entry.CreatedAt.Format("2006-01-02 15:04"))
+		return title + i.suffix()
+	}
+
 	query := i.entry.Query
 	if len(query) > 60 {
 		query = query[:57] + "..."
@@ -77,39 +74,158 @@ func (i cacheItem) Title() string {
 		status = "⏱"
 	}
 
-	return fmt.Sprintf("%s [%d] %s", status, i.index+1, query)
+	title := fmt.Sprintf("%s%s [%d] %s", i.markPrefix(), status, i.group+1, query)
+	if i.asked > 1 {
+		title += fmt.Sprintf(" (×%d)", i.asked)
+	}
+	return title + i.suffix()
+}
+
+// suffix shows the rating and whether the entry is pinned
+func (i cacheItem) suffix() string {
+	var s string
+	if i.entry.Rating > 0 {
+		s += " " + stars(i.entry.Rating)
+	}
+	if i.pinned {
+		s += " 📌"
+	}
+	if i.corrected {
+		s += " ✎ corrected"
+	}
+	return s
+}
+
+func (i cacheItem) markPrefix() string {
+	if i.marked {
+		return "● "
+	}
+	return ""
+}
+
+// stars draws a 1-5 rating
+func stars(rating int) string {
+	return strings.Repeat("★", rating) + strings.Repeat("☆", cache.MaxRating-rating)
 }
 
 func (i cacheItem) Description() string {
-	return fmt.Sprintf("Created: %s • Expires: %s",
+	desc := fmt.Sprintf("Created: %s • Expires: %s",
 		i.entry.CreatedAt.Format("2006-01-02 15:04"),
 		i.entry.ExpiresAt.Format("2006-01-02 15:04"))
+	if i.child {
+		return "      " + desc
+	}
+	switch {
+	case i.older == 1 && !i.expanded:
+		desc += " • 1 older answer"
+	case i.older > 1 && !i.expanded:
+		desc += fmt.Sprintf(" • %d older answers", i.older)
+	}
+	return desc
 }
 
 func (i cacheItem) FilterValue() string {
 	return i.entry.Query
 }
 
-func HistoryView(entries []cache.CacheEntry, manager *cache.Manager) CacheViewerModel {
-	items := make([]list.Item, len(entries))
-	for i, entry := range entries {
-		items[i] = cacheItem{entry: entry, index: i}
-	}
-
-	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
+// HistoryView lists groups one question per row, each with its latest
+// answer
+func HistoryView(groups []cache.HistoryGroup, manager *cache.Manager) CacheViewerModel {
+	l := list.New(nil, list.NewDefaultDelegate(), 0, 0)
 	l.Title = "Cache History"
 	l.SetShowStatusBar(true)
 	l.SetFilteringEnabled(true)
 
 	vp := viewport.New(0, 0)
 
-	return CacheViewerModel{
+	note := textinput.New()
+	note.Placeholder = "What was wrong or right about this answer"
+	note.CharLimit = 500
+
+	m := CacheViewerModel{
 		list:         l,
 		viewport:     vp,
-		entries:      entries,
+		note:         note,
 		cacheManager: manager,
 		showDetail:   false,
 	}
+	m.groups = groups
+	m.expanded = make(map[string]bool)
+	m.marked = make(map[string]bool)
+	m.pinned = make(map[string]bool)
+	m.corrections = make(map[string]cache.Correction)
+	if manager != nil {
+		m.pinned = manager.Pinned()
+		m.corrections = manager.Corrections()
+	}
+	m.refresh()
+	return m.WithKeys(Locksmith(config.KeysConfig{}))
+}
+
+// refresh rebuilds the list from groups, keeping the cursor where it was
+func (m *CacheViewerModel) refresh() {
+	var items []list.Item
+	for gi, g := range m.groups {
+		latest := g.Latest()
+		if m.expiredOnly && !time.Now().After(latest.ExpiresAt) {
+			continue
+		}
+		older := len(g.Entries) - 1 + len(g.Older)
+		open := m.expanded[g.Key] && older > 0
+		items = append(items, cacheItem{entry: latest, group: gi, asked: g.Asked, older: older, expanded: open,
+			marked: m.marked[latest.QueryHash], pinned: m.pinned[latest.QueryHash],
+			corrected: m.corrected(latest.QueryHash)})
+		if !open {
+			continue
+		}
+		for _, entry := range g.Entries[1:] {
+			items = append(items, cacheItem{entry: entry, group: gi, child: true,
+				marked: m.marked[entry.QueryHash], pinned: m.pinned[entry.QueryHash],
+				corrected: m.corrected(entry.QueryHash)})
+		}
+		for _, entry := range g.Older {
+			items = append(items, cacheItem{entry: entry, group: gi, child: true, previous: true})
+		}
+	}
+
+	cursor := m.list.Index()
+	m.list.SetItems(items)
+	if cursor >= len(items) {
+		cursor = len(items) - 1
+	}
+	if cursor >= 0 {
+		m.list.Select(cursor)
+	}
+}
+
+// toggle expands or collapses the group of item
+func (m *CacheViewerModel) toggle(item cacheItem) {
+	g := m.groups[item.group]
+	if len(g.Entries)+len(g.Older) < 2 {
+		m.notice = "No older answers to this question"
+		return
+	}
+	m.expanded[g.Key] = !m.expanded[g.Key]
+	m.notice = ""
+	m.refresh()
+	if item.child {
+		// The row under the cursor is gone, go back to its question
+		for i, it := range m.list.Items() {
+			if ci := it.(cacheItem); !ci.child && ci.group == item.group {
+				m.list.Select(i)
+				break
+			}
+		}
+	}
+}
+
+// WithKeys applies a key map from [tui.keys]
+func (m CacheViewerModel) WithKeys(keys KeyMap) CacheViewerModel {
+	m.keys = keys
+	m.list.KeyMap.CursorUp = keys.Up
+	m.list.KeyMap.CursorDown = keys.Down
+	m.viewport.KeyMap = keys.viewportKeys()
+	return m
 }
 
 func (m CacheViewerModel) Init() tea.Cmd {
@@ -128,27 +244,103 @@ func (m CacheViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.viewport.Height = msg.Height - 6
 
 	case tea.KeyMsg:
+		if m.editing {
+			return m.updateNote(msg)
+		}
 		if m.showDetail {
-			switch msg.String() {
-			case "esc", "b", "q":
+			if rating, ok := ratingKey(msg); ok {
+				m.rate(m.selected, rating)
+				m.viewport.SetContent(m.renderDetail())
+				return m, nil
+			}
+			switch {
+			case key.Matches(msg, m.keys.Back):
 				m.showDetail = false
+				m.notice = ""
+				return m, nil
+			case key.Matches(msg, m.keys.Quit):
+				m.quitting = true
+				return m, tea.Quit
+			case key.Matches(msg, m.keys.Delete):
+				m.delete(m.selected)
+				return m, nil
+			case key.Matches(msg, m.keys.Copy):
+				m.notice = copyToClipboard(m.selected.entry.Response)
+				return m, nil
+			case key.Matches(msg, m.keys.Pin):
+				m.pin(m.selected)
+				m.viewport.SetContent(m.renderDetail())
 				return m, nil
-			case "d", "delete":
-				return m, m.deleteCurrentEntry()
+			case key.Matches(msg, m.keys.Note):
+				if m.selected.previous {
+					m.notice = "Answers from before the code changed are read only"
+					return m, nil
+				}
+				return m, m.editNote(false, m.selected.entry.Notes)
+			case key.Matches(msg, m.keys.Correct):
+				if m.selected.previous {
+					m.notice = "Answers from before the code changed are read only"
+					return m, nil
+				}
+				return m, m.editNote(true, m.corrections[m.selected.entry.QueryHash].Text)
 			}
-		} else {
-			switch msg.String() {
-			case "q", "ctrl+c":
+		} else if m.confirming {
+			m.confirming = false
+			if msg.String() == "y" {
+				m.deleteMarked()
+			} else {
+				m.markedNotice()
+			}
+			return m, nil
+		} else if m.list.FilterState() != list.Filtering && !key.Matches(msg, m.list.KeyMap.ClearFilter) {
+			// While filtering every key is text for the filter
+			if rating, ok := ratingKey(msg); ok {
+				if item, ok := m.list.SelectedItem().(cacheItem); ok {
+					m.rate(item, rating)
+				}
+				return m, nil
+			}
+			item, ok := m.list.SelectedItem().(cacheItem)
+			switch {
+			case key.Matches(msg, m.keys.Quit):
 				m.quitting = true
 				return m, tea.Quit
-			case "enter":
-				m.selected = m.list.Index()
+			case key.Matches(msg, m.keys.Open) && ok:
+				m.selected = item
 				m.showDetail = true
 				m.viewport.SetContent(m.renderDetail())
 				m.viewport.GotoTop()
 				return m, nil
-			case "d", "delete":
-				return m, m.deleteCurrentEntry()
+			case key.Matches(msg, m.keys.Group) && ok:
+				m.toggle(item)
+				return m, nil
+			case key.Matches(msg, m.keys.Delete) && len(m.marked) > 0:
+				m.confirming = true
+				m.notice = fmt.Sprintf("Delete %d marked entries? y/n", len(m.marked))
+				return m, nil
+			case key.Matches(msg, m.keys.Delete) && ok:
+				m.delete(item)
+				return m, nil
+			case key.Matches(msg, m.keys.Mark) && ok:
+				m.mark(item)
+				return m, nil
+			case key.Matches(msg, m.keys.Pin) && ok:
+				m.pin(item)
+				return m, nil
+			case key.Matches(msg, m.keys.MarkAll):
+				m.markAll()
+				return m, nil
+			case key.Matches(msg, m.keys.Export) && (ok || len(m.marked) > 0):
+				m.export(m.markedEntries(item))
+				return m, nil
+			case key.Matches(msg, m.keys.Expired):
+				m.expiredOnly = !m.expiredOnly
+				m.list.Title = "Cache History"
+				if m.expiredOnly {
+					m.list.Title = "Cache History (expired only)"
+				}
+				m.refresh()
+				return m, nil
 			}
 		}
 	}
@@ -182,7 +374,13 @@ func (m CacheViewerModel) renderListView() string {
 	b.WriteString("\n")
 
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Padding(1, 0)
-	b.WriteString(helpStyle.Render("enter: view • d: delete • q: quit"))
+	help := fmt.Sprintf("%s: view • %s: older answers • 1-5: rate, 0 clears • %s: pin • %s: mark • %s: mark all • %s: delete • %s: export • %s: expired only • %s: quit",
+		m.keys.Open.Help().Key, m.keys.Group.Help().Key, m.keys.Pin.Help().Key, m.keys.Mark.Help().Key, m.keys.MarkAll.Help().Key,
+		m.keys.Delete.Help().Key, m.keys.Export.Help().Key, m.keys.Expired.Help().Key, m.keys.Quit.Help().Key)
+	if m.notice != "" {
+		help = m.notice + " • " + help
+	}
+	b.WriteString(helpStyle.Render(help))
 
 	return b.String()
 }
@@ -210,17 +408,27 @@ func (m CacheViewerModel) renderDetailView() string {
 	b.WriteString("\n\n")
 	b.WriteString(contentStyle.Render(m.viewport.View()))
 	b.WriteString("\n")
-	b.WriteString(helpStyle.Render("esc: back • d: delete • q: quit"))
+	help := fmt.Sprintf("%s: back • 1-5: rate • %s: notes • %s: correct • %s: pin • %s: copy • %s: delete • %s: quit",
+		m.keys.Back.Help().Key, m.keys.Note.Help().Key, m.keys.Correct.Help().Key, m.keys.Pin.Help().Key,
+		m.keys.Copy.Help().Key, m.keys.Delete.Help().Key, m.keys.Quit.Help().Key)
+	if m.editing {
+		b.WriteString(m.note.View())
+		b.WriteString("\n")
+		help = "enter: save notes • esc: cancel"
+		if m.correcting {
+			help = "enter: save the correction, empty removes it • esc: cancel"
+		}
+	}
+	if m.notice != "" {
+		help = m.notice + " • " + help
+	}
+	b.WriteString(helpStyle.Render(help))
 
 	return b.String()
 }
 
 func (m CacheViewerModel) renderDetail() string {
-	if m.selected >= len(m.entries) {
-		return "Invalid selection"
-	}
-
-	entry := m.entries[m.selected]
+	entry := m.selected.entry
 
 	var b strings.Builder
 
@@ -244,6 +452,25 @@ func (m CacheViewerModel) renderDetail() string {
 	b.WriteString(valueStyle.Render(wrapTextCache(entry.Query, m.width-8)))
 	b.WriteString("\n\n")
 
+	if g := m.groups[m.selected.group]; g.Asked > 1 || m.selected.child {
+		var asked []string
+		if g.Asked > 1 {
+			asked = append(asked, fmt.Sprintf("Asked %d times", g.Asked))
+		}
+		switch {
+		case m.selected.previous:
+			asked = append(asked, "an answer from before the code changed")
+		case m.selected.child:
+			asked = append(asked, "an earlier answer")
+		default:
+			if older := len(g.Entries) - 1 + len(g.Older); older > 0 {
+				asked = append(asked, fmt.Sprintf("%d older answers, %s in the list", older, m.keys.Group.Help().Key))
+			}
+		}
+		b.WriteString(valueStyle.Render(strings.Join(asked, ", ")))
+		b.WriteString("\n\n")
+	}
+
 	// Response
 	b.WriteString(labelStyle.Render("Response:"))
 	b.WriteString("\n")
@@ -254,6 +481,20 @@ func (m CacheViewerModel) renderDetail() string {
 	b.WriteString(valueStyle.Render(wrapTextCache(responsePreview, m.width-8)))
 	b.WriteString("\n\n")
 
+	if c, ok := m.corrections[entry.QueryHash]; ok {
+		b.WriteString(expiredStyle.Render(fmt.Sprintf("Correction (%s):", c.CreatedAt.Format("2006-01-02"))))
+		b.WriteString("\n")
+		b.WriteString(valueStyle.Render(wrapTextCache(c.Text, m.width-8)))
+		b.WriteString("\n\n")
+	}
+
+	if entry.Notes != "" {
+		b.WriteString(labelStyle.Render("Notes:"))
+		b.WriteString("\n")
+		b.WriteString(valueStyle.Render(wrapTextCache(entry.Notes, m.width-8)))
+		b.WriteString("\n\n")
+	}
+
 	// Metadata
 	b.WriteString(labelStyle.Render("Metadata:"))
 	b.WriteString("\n")
@@ -265,37 +506,192 @@ func (m CacheViewerModel) renderDetail() string {
 		b.WriteString(fmt.Sprintf("  Time left: %s\n", formatDuration(timeLeft)))
 	}
 
+	if entry.Rating > 0 {
+		b.WriteString(fmt.Sprintf("  Rating:   %s (%d/%d)\n", stars(entry.Rating), entry.Rating, cache.MaxRating))
+	}
+	if m.pinned[entry.QueryHash] {
+		b.WriteString("  Pinned:   yes, kept in eulix notes\n")
+	}
 	b.WriteString(fmt.Sprintf("  Hash:     %s\n", entry.QueryHash))
 	b.WriteString(fmt.Sprintf("  Checksum: %s\n", entry.ChecksumHash[:16]+"..."))
 
 	return b.String()
 }
 
-func (m CacheViewerModel) deleteCurrentEntry() tea.Cmd {
-	return func() tea.Msg {
-		if m.selected >= len(m.entries) {
-			return nil
+// ratingKey reads 1-5 as a rating and 0 as clearing it
+func ratingKey(msg tea.KeyMsg) (int, bool) {
+	k := msg.String()
+	if len(k) != 1 || k[0] < '0' || k[0] > '0'+cache.MaxRating {
+		return 0, false
+	}
+	return int(k[0] - '0'), true
+}
+
+// rate saves a rating for item, keeping its notes
+func (m *CacheViewerModel) rate(item cacheItem, rating int) {
+	if item.previous {
+		m.notice = "Answers from before the code changed are read only"
+		return
+	}
+	entry := item.entry
+	if err := m.cacheManager.Rate(entry.QueryHash, rating, entry.Notes); err != nil {
+		m.notice = fmt.Sprintf("Rating failed: %v", err)
+		return
+	}
+	entry.Rating = rating
+	m.update(item, entry)
+
+	switch {
+	case rating == 0:
+		m.notice = "Rating cleared"
+	case rating <= cache.LowRating:
+		m.notice = fmt.Sprintf("Rated %d/%d, it won't be answered from the cache again", rating, cache.MaxRating)
+	default:
+		m.notice = fmt.Sprintf("Rated %d/%d", rating, cache.MaxRating)
+	}
+}
+
+// pin pins item to eulix notes, or unpins it
+func (m *CacheViewerModel) pin(item cacheItem) {
+	if item.previous {
+		m.notice = "Answers from before the code changed are read only"
+		return
+	}
+	hash := item.entry.QueryHash
+	if m.pinned[hash] {
+		if err := m.cacheManager.Unpin(hash); err != nil {
+			m.notice = fmt.Sprintf("Unpin failed: %v", err)
+			return
+		}
+		delete(m.pinned, hash)
+		m.notice = "Unpinned"
+	} else {
+		if _, err := m.cacheManager.Pin(hash); err != nil {
+			m.notice = fmt.Sprintf("Pin failed: %v", err)
+			return
 		}
+		m.pinned[hash] = true
+		m.notice = "Pinned, see eulix notes"
+	}
+	m.refresh()
+}
 
-		entry := m.entries[m.selected]
-		if err := m.cacheManager.Delete(entry.QueryHash); err != nil {
-			return nil
+// update replaces the entry of item in its group, and in the detail view
+// when it is the open one
+func (m *CacheViewerModel) update(item cacheItem, entry cache.CacheEntry) {
+	g := &m.groups[item.group]
+	for i := range g.Entries {
+		if g.Entries[i].QueryHash == entry.QueryHash {
+			g.Entries[i] = entry
 		}
+	}
+	if m.selected.entry.QueryHash == entry.QueryHash {
+		m.selected.entry = entry
+	}
+	m.refresh()
+}
+
+// editNote opens the input under the detail view on value, for the notes
+// of the open entry or its correction
+func (m *CacheViewerModel) editNote(correcting bool, value string) tea.Cmd {
+	m.editing = true
+	m.correcting = correcting
+	m.note.Placeholder = "What was wrong or right about this answer"
+	if correcting {
+		m.note.Placeholder = "What is actually true, shown with this answer until the next analyze"
+	}
+	m.note.SetValue(value)
+	m.note.CursorEnd()
+	m.note.Width = m.width - 8
+	return m.note.Focus()
+}
 
-		// Remove from local list
-		m.entries = append(m.entries[:m.selected], m.entries[m.selected+1:]...)
+// corrected reports whether the entry with hash has a correction
+func (m *CacheViewerModel) corrected(hash string) bool {
+	_, ok := m.corrections[hash]
+	return ok
+}
+
+// saveCorrection saves the input as the correction of the open entry
+func (m *CacheViewerModel) saveCorrection() {
+	hash := m.selected.entry.QueryHash
+	text := strings.TrimSpace(m.note.Value())
+	if err := m.cacheManager.Correct(hash, text); err != nil {
+		m.notice = fmt.Sprintf("Saving the correction failed: %v", err)
+		return
+	}
+	if text == "" {
+		delete(m.corrections, hash)
+		m.notice = "Correction removed"
+	} else {
+		m.corrections[hash] = cache.Correction{QueryHash: hash, Query: m.selected.entry.Query, Text: text, CreatedAt: time.Now()}
+		m.notice = "Correction saved, it is shown with this answer until the next analyze"
+	}
+	m.refresh()
+	m.viewport.SetContent(m.renderDetail())
+}
 
-		// Update list items
-		items := make([]list.Item, len(m.entries))
-		for i, e := range m.entries {
-			items[i] = cacheItem{entry: e, index: i}
+// updateNote handles keys while the notes or the correction of the open
+// entry are edited
+func (m CacheViewerModel) updateNote(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.editing = false
+		m.note.Blur()
+		if m.correcting {
+			m.saveCorrection()
+			return m, nil
+		}
+		entry := m.selected.entry
+		notes := strings.TrimSpace(m.note.Value())
+		if err := m.cacheManager.Rate(entry.QueryHash, entry.Rating, notes); err != nil {
+			m.notice = fmt.Sprintf("Saving notes failed: %v", err)
+			return m, nil
 		}
-		m.list.SetItems(items)
+		entry.Notes = notes
+		m.update(m.selected, entry)
+		m.notice = "Notes saved"
+		m.viewport.SetContent(m.renderDetail())
+		return m, nil
+	case tea.KeyEsc:
+		m.editing = false
+		m.note.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.note, cmd = m.note.Update(msg)
+	return m, cmd
+}
 
-		m.showDetail = false
+// delete removes the entry of item from the cache and its group, the group
+// goes when it was the last answer
+func (m *CacheViewerModel) delete(item cacheItem) {
+	if item.previous {
+		m.notice = "Answers from before the code changed are read only"
+		return
+	}
+	if err := m.cacheManager.Delete(item.entry.QueryHash); err != nil {
+		m.notice = fmt.Sprintf("Delete failed: %v", err)
+		return
+	}
 
-		return nil
+	g := &m.groups[item.group]
+	for i := range g.Entries {
+		if g.Entries[i].QueryHash == item.entry.QueryHash {
+			g.Entries = append(g.Entries[:i], g.Entries[i+1:]...)
+			break
+		}
+	}
+	if len(g.Entries) == 0 {
+		delete(m.expanded, g.Key)
+		m.groups = append(m.groups[:item.group], m.groups[item.group+1:]...)
 	}
+
+	delete(m.marked, item.entry.QueryHash)
+	m.showDetail = false
+	m.notice = "Entry deleted"
+	m.refresh()
 }
 
 func wrapTextCache(text string, width int) string {
@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eulix/internal/cache"
+)
+
+// Rows marked with space are deleted or exported together. Delete asks
+// first when rows are marked; with none marked, delete and export act on
+// the row under the cursor like before. Answers from before the code
+// changed are read only and can't be marked.
+
+// mark marks or unmarks the entry of item
+func (m *CacheViewerModel) mark(item cacheItem) {
+	if item.previous {
+		m.notice = "Answers from before the code changed are read only"
+		return
+	}
+	hash := item.entry.QueryHash
+	if m.marked[hash] {
+		delete(m.marked, hash)
+	} else {
+		m.marked[hash] = true
+	}
+	m.markedNotice()
+	m.refresh()
+}
+
+// markAll marks every row the list shows, or clears the marks when they
+// are all marked already
+func (m *CacheViewerModel) markAll() {
+	var hashes []string
+	for _, it := range m.list.VisibleItems() {
+		if item := it.(cacheItem); !item.previous {
+			hashes = append(hashes, item.entry.QueryHash)
+		}
+	}
+
+	all := len(hashes) > 0
+	for _, hash := range hashes {
+		all = all && m.marked[hash]
+	}
+	for _, hash := range hashes {
+		if all {
+			delete(m.marked, hash)
+		} else {
+			m.marked[hash] = true
+		}
+	}
+	m.markedNotice()
+	m.refresh()
+}
+
+func (m *CacheViewerModel) markedNotice() {
+	if len(m.marked) == 0 {
+		m.notice = ""
+		return
+	}
+	m.notice = fmt.Sprintf("%d marked", len(m.marked))
+}
+
+// markedEntries is every marked entry, most recent question first, or the
+// entry of item when nothing is marked
+func (m *CacheViewerModel) markedEntries(item cacheItem) []cache.CacheEntry {
+	if len(m.marked) == 0 {
+		return []cache.CacheEntry{item.entry}
+	}
+	var entries []cache.CacheEntry
+	for _, g := range m.groups {
+		for _, entry := range g.Entries {
+			if m.marked[entry.QueryHash] {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// deleteMarked deletes every marked entry, the ones that fail stay marked
+func (m *CacheViewerModel) deleteMarked() {
+	deleted := 0
+	var lastErr error
+	for gi := range m.groups {
+		g := &m.groups[gi]
+		kept := g.Entries[:0]
+		for _, entry := range g.Entries {
+			if !m.marked[entry.QueryHash] {
+				kept = append(kept, entry)
+				continue
+			}
+			if err := m.cacheManager.Delete(entry.QueryHash); err != nil {
+				lastErr = err
+				kept = append(kept, entry)
+				continue
+			}
+			delete(m.marked, entry.QueryHash)
+			deleted++
+		}
+		g.Entries = kept
+	}
+
+	groups := m.groups[:0]
+	for _, g := range m.groups {
+		if len(g.Entries) > 0 {
+			groups = append(groups, g)
+		} else {
+			delete(m.expanded, g.Key)
+		}
+	}
+	m.groups = groups
+
+	m.notice = fmt.Sprintf("Deleted %d entries", deleted)
+	if lastErr != nil {
+		m.notice += fmt.Sprintf(", %d failed: %v", len(m.marked), lastErr)
+	}
+	m.refresh()
+}
+
+// export writes entries to .eulix/exports as Markdown
+func (m *CacheViewerModel) export(entries []cache.CacheEntry) {
+	path, err := exportHistory(".eulix", entries, m.groups)
+	if err != nil {
+		m.notice = fmt.Sprintf("Export failed: %v", err)
+		return
+	}
+	m.notice = fmt.Sprintf("Exported %d entries to %s", len(entries), path)
+}
+
+// exportHistory writes entries as Markdown, each with how often its
+// question was asked according to groups, and returns the file's path
+func exportHistory(eulixDir string, entries []cache.CacheEntry, groups []cache.HistoryGroup) (string, error) {
+	asked := make(map[string]int)
+	for _, g := range groups {
+		for _, entry := range g.Entries {
+			asked[entry.QueryHash] = g.Asked
+		}
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# eulix history, %s\n", now.Format("2006-01-02 15:04"))
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "\n## %s\n\n", strings.TrimSpace(entry.Query))
+		meta := []string{"Answered " + entry.CreatedAt.Format("2006-01-02 15:04")}
+		if n := asked[entry.QueryHash]; n > 1 {
+			meta = append(meta, fmt.Sprintf("asked %d times", n))
+		}
+		if time.Now().After(entry.ExpiresAt) {
+			meta = append(meta, "expired")
+		}
+		if entry.Rating > 0 {
+			meta = append(meta, fmt.Sprintf("rated %d/%d", entry.Rating, cache.MaxRating))
+		}
+		fmt.Fprintf(&sb, "_%s_\n\n", strings.Join(meta, ", "))
+		sb.WriteString(strings.TrimSpace(entry.Response))
+		sb.WriteString("\n")
+		if entry.Notes != "" {
+			fmt.Fprintf(&sb, "\n> Notes: %s\n", entry.Notes)
+		}
+	}
+
+	dir := filepath.Join(eulixDir, "exports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("history-%s.md", now.Format("20060102-150405")))
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"strings"
+
+	"eulix/internal/config"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// KeyMap holds the bindings for every TUI action. Chat has a text input, so
+// its defaults stay off printable keys.
+type KeyMap struct {
+	Quit       key.Binding
+	Back       key.Binding
+	Up         key.Binding
+	Down       key.Binding
+	ScrollUp   key.Binding
+	ScrollDown key.Binding
+	PageUp     key.Binding
+	PageDown   key.Binding
+	Open       key.Binding
+	Delete     key.Binding
+	History    key.Binding
+	Copy       key.Binding
+	Reanalyze  key.Binding
+	Palette    key.Binding
+	Expand     key.Binding
+	Note       key.Binding
+	Group      key.Binding
+	Mark       key.Binding
+	MarkAll    key.Binding
+	Export     key.Binding
+	Expired    key.Binding
+	Pin        key.Binding
+	Correct    key.Binding
+
+	Interrupt    key.Binding
+	ContextPanel key.Binding
+	StatsPanel   key.Binding
+	PanelGrow    key.Binding
+	PanelShrink  key.Binding
+}
+
+// Locksmith builds the key map from [tui.keys], actions the config leaves out
+// get their defaults
+func Locksmith(cfg config.KeysConfig) KeyMap {
+	bind := func(keys, defaults []string, help string) key.Binding {
+		if keys == nil {
+			keys = defaults
+		}
+		if len(keys) == 0 {
+			return key.NewBinding(key.WithDisabled())
+		}
+		// Bubbletea names the space bar " ", config and help say space
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = k
+			if k == "space" {
+				names[i] = " "
+			}
+		}
+		return key.NewBinding(key.WithKeys(names...), key.WithHelp(keys[0], help))
+	}
+
+	return KeyMap{
+		Quit:       bind(cfg.Quit, []string{"ctrl+c", "esc"}, "quit"),
+		Back:       bind(cfg.Back, []string{"esc", "b"}, "back"),
+		Up:         bind(cfg.Up, []string{"up", "k"}, "up"),
+		Down:       bind(cfg.Down, []string{"down", "j"}, "down"),
+		ScrollUp:   bind(cfg.ScrollUp, []string{"ctrl+up"}, "scroll up"),
+		ScrollDown: bind(cfg.ScrollDown, []string{"ctrl+down"}, "scroll down"),
+		PageUp:     bind(cfg.PageUp, []string{"pgup"}, "page up"),
+		PageDown:   bind(cfg.PageDown, []string{"pgdown"}, "page down"),
+		Open:       bind(cfg.Open, []string{"enter"}, "view details"),
+		Delete:     bind(cfg.Delete, []string{"d", "delete"}, "delete entry"),
+		History:    bind(cfg.History, []string{"ctrl+o"}, "history"),
+		Copy:       bind(cfg.Copy, []string{"ctrl+y"}, "copy"),
+		Reanalyze:  bind(cfg.Reanalyze, []string{"ctrl+r"}, "reanalyze"),
+		Palette:    bind(cfg.Palette, []string{"ctrl+p"}, "symbols"),
+		Expand:     bind(cfg.Expand, []string{"ctrl+x"}, "more context"),
+		Note:       bind(cfg.Note, []string{"n"}, "edit notes"),
+		Group:      bind(cfg.Group, []string{"tab"}, "older answers"),
+		Mark:       bind(cfg.Mark, []string{"space"}, "mark"),
+		MarkAll:    bind(cfg.MarkAll, []string{"a"}, "mark all"),
+		Export:     bind(cfg.Export, []string{"x"}, "export"),
+		Expired:    bind(cfg.Expired, []string{"e"}, "expired only"),
+		Pin:        bind(cfg.Pin, []string{"p"}, "pin"),
+		Correct:    bind(cfg.Correct, []string{"c"}, "correct"),
+
+		Interrupt:    bind(cfg.Interrupt, []string{"esc"}, "stop answer"),
+		ContextPanel: bind(cfg.ContextPanel, []string{"f2"}, "context panel"),
+		StatsPanel:   bind(cfg.StatsPanel, []string{"f3"}, "stats panel"),
+		PanelGrow:    bind(cfg.PanelGrow, []string{"shift+left"}, "widen panels"),
+		PanelShrink:  bind(cfg.PanelShrink, []string{"shift+right"}, "narrow panels"),
+	}
+}
+
+// viewportKeys limits a viewport to the scroll bindings, its defaults use
+// letters that would also land in the chat input
+func (k KeyMap) viewportKeys() viewport.KeyMap {
+	return viewport.KeyMap{
+		Up:           k.ScrollUp,
+		Down:         k.ScrollDown,
+		PageUp:       k.PageUp,
+		PageDown:     k.PageDown,
+		HalfPageUp:   key.NewBinding(key.WithDisabled()),
+		HalfPageDown: key.NewBinding(key.WithDisabled()),
+		Left:         key.NewBinding(key.WithDisabled()),
+		Right:        key.NewBinding(key.WithDisabled()),
+	}
+}
+
+// helpKey is the key shown for a binding in help text, capitalised like the
+// rest of the chat help
+func helpKey(b key.Binding) string {
+	k := b.Help().Key
+	if k == "" {
+		return "unbound"
+	}
+	parts := strings.Split(k, "+")
+	for i, p := range parts {
+		if len(p) > 1 {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "+")
+}
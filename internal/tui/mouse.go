@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// viewportTop is the screen row of the first conversation line: the header,
+// then the viewport border and padding
+const viewportTop = 3
+
+func mouseMode(on bool) tea.Cmd {
+	if on {
+		return tea.EnableMouseCellMotion
+	}
+	return tea.DisableMouse
+}
+
+// setMouse switches mouse capture, the terminal's own text selection only
+// works while it is off
+func (m *Model) setMouse(on bool) {
+	m.mouse = on
+	m.viewport.MouseWheelEnabled = on
+	if !on {
+		m.selected = -1
+		m.viewport.SetContent(m.renderMessages())
+	}
+}
+
+// selectAt selects the message at screen row y, clicking it again clears the
+// selection. A message whose start is scrolled out of view is scrolled back
+// to its header.
+func (m *Model) selectAt(y int) {
+	row := y - viewportTop
+	if row < 0 || row >= m.viewport.Height {
+		return
+	}
+	line := m.viewport.YOffset + row
+
+	start := 0
+	for i, block := range m.messageBlocks() {
+		end := start + strings.Count(block, "\n")
+		if line < end {
+			if m.selected == i {
+				m.selected = -1
+			} else {
+				m.selected = i
+			}
+			m.viewport.SetContent(m.renderMessages())
+			if start < m.viewport.YOffset {
+				m.viewport.SetYOffset(start)
+			}
+			return
+		}
+		start = end
+	}
+}
@@ -0,0 +1,218 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"eulix/internal/query"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A first chat, one with no history yet, opens with what the KB says about
+// the project and three questions about it instead of generic examples.
+// They are picked with 1-3 like follow-ups.
+
+const welcomeText = "Welcome to Eulix AI Code Assistant\n\nI can help you understand and navigate your codebase.\n\nTry asking:\n  - What does this function do?\n  - Explain the authentication flow\n  - Show me error handling patterns\n\nType /help to see available commands"
+
+// onboardingMsg carries the first-run welcome, read from kb.json off the
+// UI goroutine since a large KB takes a moment to parse
+type onboardingMsg struct {
+	text      string
+	questions []string
+}
+
+func loadOnboarding(eulixDir string) tea.Cmd {
+	return func() tea.Msg {
+		data, err := os.ReadFile(filepath.Join(eulixDir, "kb.json"))
+		if err != nil {
+			return nil
+		}
+		var kb query.KnowledgeBase
+		if err := json.Unmarshal(data, &kb); err != nil {
+			return nil
+		}
+		text, questions := onboarding(&kb)
+		if len(questions) == 0 {
+			return nil
+		}
+		return onboardingMsg{text: text, questions: questions}
+	}
+}
+
+// onboarding describes the project and suggests a question about where it
+// starts, about its most called function and about its largest type
+func onboarding(kb *query.KnowledgeBase) (string, []string) {
+	var sb strings.Builder
+	sb.WriteString("Welcome to Eulix AI Code Assistant\n\n")
+
+	name := kb.Metadata.ProjectName
+	if name == "" {
+		name = "This project"
+	}
+	if language, files := mainLanguage(kb); language != "" {
+		fmt.Fprintf(&sb, "%s is mostly %s (%d of %d files)", name, language, files, len(kb.Structure))
+	} else {
+		fmt.Fprintf(&sb, "%s has %d analyzed files", name, len(kb.Structure))
+	}
+
+	var questions []string
+	if ep, ok := mainEntryPoint(kb); ok {
+		fmt.Fprintf(&sb, ", starting from %s", describeEntryPoint(ep))
+		if more := len(kb.EntryPoints) - 1; more > 0 {
+			fmt.Fprintf(&sb, " and %d more entry points", more)
+		}
+		questions = append(questions, entryQuestion(ep))
+	}
+	sb.WriteString(".\n\n")
+
+	if fn, ok := mostCalled(kb); ok {
+		questions = append(questions, fmt.Sprintf("What does %s do, and why do %d places call it?", fn.Name, len(fn.CalledBy)))
+	}
+	if class, ok := largestClass(kb); ok {
+		questions = append(questions, fmt.Sprintf("Explain the %s type and how it is used", class.Name))
+	}
+	if len(questions) == 0 {
+		return "", nil
+	}
+
+	sb.WriteString("Pick a question to start with (press the number):")
+	for i, q := range questions {
+		fmt.Fprintf(&sb, "\n  %d) %s", i+1, q)
+	}
+	sb.WriteString("\n\nOr ask your own. Type /help to see available commands")
+	return sb.String(), questions
+}
+
+// mainLanguage is the language most files are in, ties go to the name
+// first in order so the welcome doesn't change between runs
+func mainLanguage(kb *query.KnowledgeBase) (string, int) {
+	counts := make(map[string]int)
+	for _, fs := range kb.Structure {
+		if fs.Language != "" {
+			counts[fs.Language]++
+		}
+	}
+	best, most := "", 0
+	for language, n := range counts {
+		if n > most || (n == most && language < best) {
+			best, most = language, n
+		}
+	}
+	return languageName(best), most
+}
+
+func languageName(language string) string {
+	switch strings.ToLower(language) {
+	case "go":
+		return "Go"
+	case "javascript":
+		return "JavaScript"
+	case "typescript":
+		return "TypeScript"
+	case "c":
+		return "C"
+	case "cpp":
+		return "C++"
+	}
+	if language == "" {
+		return ""
+	}
+	return strings.ToUpper(language[:1]) + language[1:]
+}
+
+// mainEntryPoint is a main function before a command before a route, like
+// the tour orders them
+func mainEntryPoint(kb *query.KnowledgeBase) (query.EntryPoint, bool) {
+	if len(kb.EntryPoints) == 0 {
+		return query.EntryPoint{}, false
+	}
+	rank := map[string]int{"main": 0, "cli_command": 1, "api_endpoint": 2}
+	entries := append([]query.EntryPoint(nil), kb.EntryPoints...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, iok := rank[entries[i].EntryType]
+		rj, jok := rank[entries[j].EntryType]
+		if iok != jok {
+			return iok
+		}
+		if ri != rj {
+			return ri < rj
+		}
+		return entries[i].File < entries[j].File
+	})
+	return entries[0], true
+}
+
+func describeEntryPoint(ep query.EntryPoint) string {
+	switch ep.EntryType {
+	case "api_endpoint":
+		return fmt.Sprintf("the %s route in %s", ep.Path, path.Base(ep.File))
+	case "cli_command":
+		if ep.Path != "" {
+			return fmt.Sprintf("the %s command in %s", ep.Path, ep.File)
+		}
+	}
+	return fmt.Sprintf("%s in %s", ep.Function, ep.File)
+}
+
+func entryQuestion(ep query.EntryPoint) string {
+	switch ep.EntryType {
+	case "api_endpoint":
+		method := "a"
+		if len(ep.Methods) > 0 {
+			method = "a " + strings.ToUpper(ep.Methods[0])
+		}
+		return fmt.Sprintf("How is %s request to %s handled, from %s onwards?", method, ep.Path, ep.Function)
+	case "cli_command":
+		if ep.Path != "" {
+			return fmt.Sprintf("What happens when the %s command runs?", ep.Path)
+		}
+	}
+	return fmt.Sprintf("What happens at startup, from %s in %s?", ep.Function, ep.File)
+}
+
+// mostCalled is the function with the most callers, methods included
+func mostCalled(kb *query.KnowledgeBase) (query.KBFunction, bool) {
+	var best query.KBFunction
+	for _, file := range sortedFiles(kb) {
+		fs := kb.Structure[file]
+		functions := append([]query.KBFunction(nil), fs.Functions...)
+		for _, class := range fs.Classes {
+			functions = append(functions, class.Methods...)
+		}
+		for _, fn := range functions {
+			if len(fn.CalledBy) > len(best.CalledBy) {
+				best = fn
+			}
+		}
+	}
+	return best, len(best.CalledBy) > 1
+}
+
+// largestClass is the type with the most methods
+func largestClass(kb *query.KnowledgeBase) (query.KBClass, bool) {
+	var best query.KBClass
+	found := false
+	for _, file := range sortedFiles(kb) {
+		for _, class := range kb.Structure[file].Classes {
+			if !found || len(class.Methods) > len(best.Methods) {
+				best, found = class, true
+			}
+		}
+	}
+	return best, found
+}
+
+func sortedFiles(kb *query.KnowledgeBase) []string {
+	files := make([]string, 0, len(kb.Structure))
+	for file := range kb.Structure {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,336 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"eulix/internal/query"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The palette searches every indexed symbol as you type, straight from
+// kb_index.json with no LLM. Enter puts the symbol in the question, Tab
+// shows its source first.
+
+// maxPaletteMatches caps the ranked list, nobody scrolls past it
+const maxPaletteMatches = 200
+
+// symbol is one place a function or type is defined
+type symbol struct {
+	name string
+	kind string // "func" or "type"
+	file string
+	line int
+}
+
+func (s symbol) location() string {
+	if s.line > 0 {
+		return fmt.Sprintf("%s:%d", s.file, s.line)
+	}
+	return s.file
+}
+
+// palette is the symbol search open over the chat
+type palette struct {
+	input   textinput.Model
+	symbols []symbol
+	matches []symbol
+	cursor  int
+	source  *viewport.Model // the selected symbol's file, nil in the list
+	err     error
+}
+
+// openPalette loads the symbols fresh, the KB may have been reanalyzed
+// since the last time
+func openPalette(eulixDir string) *palette {
+	ti := textinput.New()
+	ti.Placeholder = "Search functions and types"
+	ti.Prompt = "@ "
+	ti.PromptStyle = lipgloss.NewStyle().Foreground(primaryColor).Bold(true)
+	ti.TextStyle = lipgloss.NewStyle().Foreground(textColor)
+	ti.Focus()
+
+	p := &palette{input: ti}
+	p.symbols, p.err = loadSymbols(eulixDir)
+	p.filter()
+	return p
+}
+
+// loadSymbols reads every definition in kb_index.json, sorted by name
+func loadSymbols(eulixDir string) ([]symbol, error) {
+	data, err := os.ReadFile(filepath.Join(eulixDir, "kb_index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kb_index.json, run 'eulix analyze' first: %w", err)
+	}
+	var index query.KBIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse kb_index.json: %w", err)
+	}
+
+	var symbols []symbol
+	add := func(byName map[string][]string, kind string) {
+		for name, locations := range byName {
+			for _, loc := range locations {
+				s := symbol{name: name, kind: kind, file: loc}
+				if i := strings.LastIndex(loc, ":"); i > 0 {
+					if line, err := strconv.Atoi(loc[i+1:]); err == nil {
+						s.file, s.line = loc[:i], line
+					}
+				}
+				symbols = append(symbols, s)
+			}
+		}
+	}
+	add(index.FunctionsByName, "func")
+	add(index.TypesByName, "type")
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].name != symbols[j].name {
+			return symbols[i].name < symbols[j].name
+		}
+		return symbols[i].location() < symbols[j].location()
+	})
+	return symbols, nil
+}
+
+// filter ranks the symbols against the search, an empty one lists them all
+func (p *palette) filter() {
+	search := strings.TrimSpace(p.input.Value())
+	p.cursor = 0
+	if search == "" {
+		p.matches = p.symbols[:min(len(p.symbols), maxPaletteMatches)]
+		return
+	}
+
+	type scored struct {
+		symbol
+		score int
+	}
+	var ranked []scored
+	for _, s := range p.symbols {
+		if score, ok := fuzzyScore(search, s.name); ok {
+			ranked = append(ranked, scored{s, score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	p.matches = p.matches[:0:0]
+	for i := 0; i < len(ranked) && i < maxPaletteMatches; i++ {
+		p.matches = append(p.matches, ranked[i].symbol)
+	}
+}
+
+// fuzzyScore matches search as a case-insensitive subsequence of name.
+// Letters that start a word in name, follow the last match or begin it
+// count extra, a shorter name wins ties.
+func fuzzyScore(search, name string) (int, bool) {
+	want := []rune(strings.ToLower(search))
+	runes := []rune(name)
+	score, i, last := 0, 0, -2
+	for j, r := range runes {
+		if i == len(want) {
+			break
+		}
+		if unicode.ToLower(r) != want[i] {
+			continue
+		}
+		score++
+		switch {
+		case j == 0:
+			score += 8
+		case j == last+1:
+			score += 5
+		case runes[j-1] == '_' || runes[j-1] == '.' || unicode.IsLower(runes[j-1]) && unicode.IsUpper(r):
+			score += 4
+		}
+		last = j
+		i++
+	}
+	if i < len(want) {
+		return 0, false
+	}
+	if strings.EqualFold(search, name) {
+		score += 20
+	}
+	return score*10 - len(runes), true
+}
+
+func (p *palette) selected() (symbol, bool) {
+	if p.cursor < len(p.matches) {
+		return p.matches[p.cursor], true
+	}
+	return symbol{}, false
+}
+
+// showSource opens the selected symbol's file scrolled to its line
+func (p *palette) showSource(keys KeyMap, width, height int) {
+	s, ok := p.selected()
+	if !ok {
+		return
+	}
+	vp := viewport.New(width, height)
+	vp.KeyMap = keys.viewportKeys()
+
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		vp.SetContent(fmt.Sprintf("Can't read %s: %v", s.file, err))
+		p.source = &vp
+		return
+	}
+
+	numberStyle := lipgloss.NewStyle().Foreground(mutedColor)
+	currentStyle := lipgloss.NewStyle().Foreground(codeColor).Bold(true)
+	var sb strings.Builder
+	for i, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		if i+1 == s.line {
+			sb.WriteString(currentStyle.Render(fmt.Sprintf("%5d ", i+1)) + line + "\n")
+			continue
+		}
+		sb.WriteString(numberStyle.Render(fmt.Sprintf("%5d ", i+1)) + line + "\n")
+	}
+	vp.SetContent(sb.String())
+	vp.SetYOffset(max(s.line-4, 0))
+	p.source = &vp
+}
+
+// updatePalette handles a key while the palette is open. Esc steps back
+// out of the source then closes, Enter inserts the symbol and closes.
+func (m Model) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.palette
+	switch {
+	case msg.String() == "esc" || key.Matches(msg, m.keys.Palette):
+		if p.source != nil && msg.String() == "esc" {
+			p.source = nil
+			return m, nil
+		}
+		m.palette = nil
+		return m, nil
+
+	case msg.String() == "enter":
+		if s, ok := p.selected(); ok {
+			m.insertSymbol(s.name)
+		}
+		m.palette = nil
+		return m, nil
+
+	case msg.String() == "tab":
+		if p.source == nil {
+			p.showSource(m.keys, m.viewport.Width, m.viewport.Height-2)
+		}
+		return m, nil
+	}
+
+	if p.source != nil {
+		switch msg.String() {
+		case "up":
+			p.source.LineUp(1)
+		case "down":
+			p.source.LineDown(1)
+		default:
+			*p.source, _ = p.source.Update(msg)
+		}
+		return m, nil
+	}
+
+	page := max(m.viewport.Height-4, 1)
+	switch msg.String() {
+	case "up":
+		p.cursor = max(p.cursor-1, 0)
+		return m, nil
+	case "down":
+		p.cursor = min(p.cursor+1, max(len(p.matches)-1, 0))
+		return m, nil
+	case "pgup":
+		p.cursor = max(p.cursor-page, 0)
+		return m, nil
+	case "pgdown":
+		p.cursor = min(p.cursor+page, max(len(p.matches)-1, 0))
+		return m, nil
+	}
+
+	before := p.input.Value()
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != before {
+		p.filter()
+	}
+	return m, cmd
+}
+
+// insertSymbol puts name into the question at the cursor, spaced from the
+// words around it
+func (m *Model) insertSymbol(name string) {
+	value := []rune(m.input.Value())
+	pos := min(m.input.Position(), len(value))
+	before, after := string(value[:pos]), string(value[pos:])
+	if before != "" && !strings.HasSuffix(before, " ") {
+		name = " " + name
+	}
+	if after != "" && !strings.HasPrefix(after, " ") {
+		name += " "
+	}
+	m.input.SetValue(before + name + after)
+	m.input.SetCursor(pos + len([]rune(name)))
+}
+
+// renderPalette draws the palette in place of the conversation
+func (m Model) renderPalette(width, height int) string {
+	p := m.palette
+	box := lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(highlightColor).
+		Padding(0, 1).
+		Width(width - 2).
+		Height(height)
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+
+	if p.source != nil {
+		s, _ := p.selected()
+		title := lipgloss.NewStyle().Bold(true).Foreground(primaryColor).Render(s.name) + muted.Render("  "+s.location())
+		help := muted.Render(fmt.Sprintf("Enter: insert • %s/%s: scroll • Esc: back to results", helpKey(m.keys.PageUp), helpKey(m.keys.PageDown)))
+		return box.Render(title + "\n" + p.source.View() + "\n" + help)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(p.input.View() + "\n")
+	switch {
+	case p.err != nil:
+		sb.WriteString(lipgloss.NewStyle().Foreground(errorColor).Render(p.err.Error()) + "\n")
+	case len(p.matches) == 0:
+		sb.WriteString(muted.Render("No symbol matches") + "\n")
+	}
+
+	rows := max(height-3, 1)
+	start := 0
+	if p.cursor >= rows {
+		start = p.cursor - rows + 1
+	}
+	nameWidth := max(min(width/2, 40), 10)
+	selectedStyle := lipgloss.NewStyle().Reverse(true)
+	line := lipgloss.NewStyle().MaxWidth(width - 4)
+	for i := start; i < len(p.matches) && i < start+rows; i++ {
+		s := p.matches[i]
+		row := fmt.Sprintf("%-4s %-*s", s.kind, nameWidth, truncate(s.name, nameWidth))
+		if i == p.cursor {
+			row = selectedStyle.Render(row)
+		}
+		sb.WriteString(line.Render(row+" "+muted.Render(s.location())) + "\n")
+	}
+
+	count := fmt.Sprintf("%d of %d symbols", len(p.matches), len(p.symbols))
+	sb.WriteString(muted.Render(count + " • Enter: insert • Tab: source • Esc: close"))
+	return box.Render(sb.String())
+}
@@ -0,0 +1,251 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Chat splits into the conversation and a column of side panels: the context
+// behind the last answer and session stats. Panels keep that detail out of the
+// message stream; each can be collapsed, and they fold away on narrow screens.
+
+const (
+	defaultPanelWidth = 36
+	minPanelWidth     = 24
+	panelStep         = 4
+	// minConversationWidth is the narrowest conversation the panels may leave,
+	// below it they are hidden
+	minConversationWidth = 60
+)
+
+// sideWidth is the width the side panels take, 0 when none are shown
+func (m Model) sideWidth() int {
+	if !m.showContext && !m.showStats {
+		return 0
+	}
+	if m.width-m.panelWidth < minConversationWidth {
+		return 0
+	}
+	return m.panelWidth
+}
+
+func (m Model) conversationWidth() int {
+	return m.width - m.sideWidth()
+}
+
+// layout sizes the conversation after a resize or a panel change
+func (m *Model) layout() {
+	m.viewport.Width = m.conversationWidth() - 4
+	m.viewport.Height = m.height - 10
+	m.input.Width = m.width - 8
+	m.viewport.SetContent(m.renderMessages())
+}
+
+// resizePanels widens or narrows the side panels, keeping half the screen for
+// the conversation
+func (m *Model) resizePanels(delta int) {
+	width := m.panelWidth + delta
+	if width < minPanelWidth {
+		width = minPanelWidth
+	}
+	if max := m.width / 2; width > max && max >= minPanelWidth {
+		width = max
+	}
+	m.panelWidth = width
+	m.layout()
+}
+
+// renderPanels renders the side column at the given height
+func (m Model) renderPanels(height int) string {
+	var panels []func() string
+	if m.showContext {
+		panels = append(panels, m.contextPanel)
+	}
+	if m.showStats {
+		panels = append(panels, m.statsPanel)
+	}
+
+	var rendered []string
+	for i, panel := range panels {
+		h := height / len(panels)
+		if i == len(panels)-1 {
+			h = height - h*(len(panels)-1)
+		}
+		rendered = append(rendered, m.renderPanel(panel(), h))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rendered...)
+}
+
+// renderPanel boxes content, clipping it to the box rather than wrapping so
+// a long source list can't push the layout around
+func (m Model) renderPanel(content string, height int) string {
+	width := m.sideWidth() - 4
+	rows := height - 2
+	if rows < 1 {
+		rows = 1
+	}
+
+	lines := strings.Split(content, "\n")
+	if len(lines) > rows {
+		lines = append(lines[:rows-1], "...")
+	}
+	for i, line := range lines {
+		if lipgloss.Width(line) > width {
+			lines[i] = truncate(line, width)
+		}
+	}
+
+	return lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(borderColor).
+		Padding(0, 1).
+		Width(m.sideWidth() - 2).
+		Height(rows).
+		Render(strings.Join(lines, "\n"))
+}
+
+func panelTitle(title string) string {
+	return lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true).
+		Render(title)
+}
+
+// contextPanel shows what the last answer was built from
+func (m Model) contextPanel() string {
+	var sb strings.Builder
+	sb.WriteString(panelTitle("CONTEXT"))
+
+	a := m.answer
+	if a == nil {
+		sb.WriteString("\n\nAsk a question to see the sources behind the answer.")
+		return sb.String()
+	}
+
+	part := func(v float64) string {
+		if v < 0 {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.0f%%", v*100)
+	}
+	sb.WriteString("\n\nConfidence " + a.Confidence.String())
+	sb.WriteString("\n  retrieval  " + part(a.Confidence.Retrieval))
+	sb.WriteString("\n  classifier " + part(a.Confidence.Classifier))
+	sb.WriteString("\n  model      " + part(a.Confidence.Model))
+
+	width := m.sideWidth() - 6
+	if len(a.Sources) > 0 {
+		sb.WriteString(fmt.Sprintf("\n\nSources (%d)", len(a.Sources)))
+		for _, source := range a.Sources {
+			sb.WriteString("\n  " + shortenPath(source, width))
+		}
+	}
+
+	if len(a.Owners) > 0 {
+		sb.WriteString("\n\nOwners")
+		for _, owner := range a.Owners {
+			sb.WriteString("\n  " + owner)
+		}
+	}
+
+	if len(a.Entities) > 0 {
+		sb.WriteString("\n\nSymbols")
+		for _, entity := range a.Entities {
+			sb.WriteString("\n  " + entity)
+		}
+	}
+
+	if len(a.Steps) > 0 {
+		sb.WriteString("\n\nAgent steps")
+		for _, step := range a.Steps {
+			mark := "x"
+			if step.Found {
+				mark = "+"
+			}
+			sb.WriteString(fmt.Sprintf("\n  %s %s(%s)", mark, step.Tool, step.Arg))
+		}
+	}
+
+	if a.Diff != nil {
+		changed := len(a.Diff.ChangedSources) + len(a.Diff.AddedSources) + len(a.Diff.RemovedSources)
+		sb.WriteString(fmt.Sprintf("\n\nChanged since %s, %d sources differ", a.Diff.PreviousAt.Format("2 Jan 15:04"), changed))
+	}
+
+	return sb.String()
+}
+
+// statsPanel is the session at a glance, /stats has the full listing
+func (m Model) statsPanel() string {
+	questions := 0
+	for _, msg := range m.messages {
+		if msg.Role == "user" {
+			questions++
+		}
+	}
+
+	lastAnswer := "-"
+	if m.elapsed > 0 {
+		lastAnswer = fmt.Sprintf("%.1fs", m.elapsed.Seconds())
+	}
+
+	cacheStatus := "off"
+	if m.cacheManager != nil {
+		cacheStatus = "on"
+	}
+
+	kb := "-"
+	if !m.kbModTime.IsZero() {
+		kb = m.kbModTime.Format("2 Jan 15:04")
+	}
+
+	search := m.search
+	if search == "" {
+		search = "-"
+	}
+
+	mouse := "off"
+	if m.mouse {
+		mouse = "on"
+	}
+
+	notes := 0
+	if m.session != nil {
+		notes = len(m.session.Notes)
+	}
+
+	rows := []struct{ label, value string }{
+		{"Questions", fmt.Sprint(questions)},
+		{"Last answer", lastAnswer},
+		{"Cache", cacheStatus},
+		{"KB analyzed", kb},
+		{"Stale files", fmt.Sprint(m.staleFiles)},
+		{"Notes", fmt.Sprint(notes)},
+		{"Mouse", mouse},
+		{"Search", search},
+	}
+
+	var sb strings.Builder
+	sb.WriteString(panelTitle("STATS"))
+	sb.WriteString("\n")
+	for _, row := range rows {
+		sb.WriteString(fmt.Sprintf("\n%-12s %s", row.label, row.value))
+	}
+	return sb.String()
+}
+
+// shortenPath keeps the end of a path, the file name matters more than the
+// directories above it
+func shortenPath(path string, max int) string {
+	if max < 4 || len([]rune(path)) <= max {
+		return path
+	}
+	base := filepath.Base(path)
+	if len([]rune(base))+3 >= max {
+		return truncate(base, max)
+	}
+	runes := []rune(path)
+	return "..." + string(runes[len(runes)-(max-3):])
+}
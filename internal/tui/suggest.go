@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// historySize is how many past questions chat loads for recall and autocomplete
+const historySize = 200
+
+// loadHistory reads past questions from the query log, most recent first
+func (m *Model) loadHistory() {
+	if m.cacheManager == nil {
+		return
+	}
+	history, err := m.cacheManager.RecentQueries(historySize)
+	if err != nil {
+		return
+	}
+	m.history = history
+	m.input.SetSuggestions(m.history)
+}
+
+// remember puts a question at the front of the history
+func (m *Model) remember(query string) {
+	history := []string{query}
+	for _, q := range m.history {
+		if q != query && len(history) < historySize {
+			history = append(history, q)
+		}
+	}
+	m.history = history
+	m.input.SetSuggestions(m.history)
+	m.recall = -1
+}
+
+// recallHistory steps through past questions like a shell, up is older
+func (m *Model) recallHistory(older bool) {
+	m.fuzzy = ""
+	if older && m.recall < len(m.history)-1 {
+		m.recall++
+	} else if !older {
+		m.recall--
+	}
+
+	if m.recall < 0 {
+		m.recall = -1
+		m.input.SetValue("")
+		return
+	}
+	m.input.SetValue(m.history[m.recall])
+	m.input.CursorEnd()
+}
+
+// fuzzyMatch finds a past question for input when no question starts with
+// it: first one containing every word of input, else one containing its
+// letters in order. History is most recent first, so recent wins ties.
+func fuzzyMatch(input string, history []string) string {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if len(input) < 2 || strings.HasPrefix(input, "/") {
+		return ""
+	}
+
+	words := strings.Fields(input)
+	for _, q := range history {
+		lower := strings.ToLower(q)
+		all := true
+		for _, w := range words {
+			if !strings.Contains(lower, w) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return q
+		}
+	}
+
+	for _, q := range history {
+		if isSubsequence(input, strings.ToLower(q)) {
+			return q
+		}
+	}
+	return ""
+}
+
+func isSubsequence(needle, haystack string) bool {
+	want := []rune(needle)
+	i := 0
+	for _, r := range haystack {
+		if i < len(want) && want[i] == r {
+			i++
+		}
+	}
+	return i == len(want)
+}
+
+// frequentQueries renders the /frequent listing
+func (m Model) frequentQueries() string {
+	if m.cacheManager == nil {
+		return "Query history needs the SQL cache. Enable [cache.sql] in eulix.toml."
+	}
+
+	counts, err := m.cacheManager.FrequentQueries(10)
+	if err != nil {
+		return fmt.Sprintf("Failed to read query history: %v", err)
+	}
+	if len(counts) == 0 {
+		return "No questions asked yet."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("MOST ASKED\n")
+	for i, qc := range counts {
+		sb.WriteString(fmt.Sprintf("\n  %2d. %-4s %s", i+1, fmt.Sprintf("%dx", qc.Count), qc.Query))
+	}
+	sb.WriteString("\n\nStart typing one and press Tab to complete it.")
+	return sb.String()
+}
+
+func truncate(s string, max int) string {
+	if len([]rune(s)) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-3]) + "..."
+}
@@ -0,0 +1,162 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"eulix/internal/config"
+	"eulix/internal/tour"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TourModel walks through a tour one stop at a time, showing the code at
+// each stop under its summary
+type TourModel struct {
+	tour       *tour.Tour
+	projectDir string
+	current    int
+	viewport   viewport.Model
+	width      int
+	height     int
+	keys       KeyMap
+	next       key.Binding
+	previous   key.Binding
+	quitting   bool
+}
+
+func TourGuide(t *tour.Tour, projectDir string) TourModel {
+	return TourModel{
+		tour:       t,
+		projectDir: projectDir,
+		viewport:   viewport.New(0, 0),
+	}.WithKeys(Locksmith(config.KeysConfig{}))
+}
+
+// WithKeys applies a key map from [tui.keys]. Down and Open go to the next
+// stop, Up to the previous one, the arrow keys sideways do too.
+func (m TourModel) WithKeys(keys KeyMap) TourModel {
+	m.keys = keys
+	m.next = key.NewBinding(key.WithKeys(append(append([]string{"right", "l"}, keys.Down.Keys()...), keys.Open.Keys()...)...))
+	m.previous = key.NewBinding(key.WithKeys(append([]string{"left", "h"}, keys.Up.Keys()...)...))
+	m.viewport.KeyMap = keys.viewportKeys()
+	return m
+}
+
+func (m TourModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m TourModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.viewport.Width = msg.Width - 4
+		m.viewport.Height = msg.Height - 7
+		m.show(m.current)
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			m.quitting = true
+			return m, tea.Quit
+		case key.Matches(msg, m.next):
+			if m.current == len(m.tour.Stops)-1 {
+				m.quitting = true
+				return m, tea.Quit
+			}
+			m.show(m.current + 1)
+			return m, nil
+		case key.Matches(msg, m.previous):
+			m.show(max(m.current-1, 0))
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// show moves to stop i and renders it into the viewport
+func (m *TourModel) show(i int) {
+	m.current = i
+	m.viewport.SetContent(m.renderStop(m.tour.Stops[i]))
+	m.viewport.GotoTop()
+}
+
+func (m TourModel) renderStop(s tour.Stop) string {
+	var sb strings.Builder
+	location := s.Location()
+	if s.Section == tour.SectionModules {
+		location = s.File + "/"
+	}
+
+	sb.WriteString(lipgloss.NewStyle().Bold(true).Render(s.Title))
+	sb.WriteString("\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(location+", "+s.Why))
+
+	width := max(m.viewport.Width-2, 20)
+	if s.Summary != "" {
+		sb.WriteString("\n\n" + lipgloss.NewStyle().Width(width).Render(s.Summary))
+	}
+
+	if len(s.Pointers) > 0 {
+		sb.WriteString("\n\nOpen next:")
+		for _, p := range s.Pointers {
+			sb.WriteString("\n  " + p)
+		}
+	}
+
+	if s.Section != tour.SectionModules {
+		if excerpt := tour.Excerpt(m.projectDir, s); excerpt != "" {
+			sb.WriteString("\n\n")
+			for i, line := range strings.Split(excerpt, "\n") {
+				number := fmt.Sprintf("%4d ", s.Line+i)
+				if line == "..." {
+					number = "     "
+				}
+				sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(number))
+				sb.WriteString(line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (m TourModel) View() string {
+	if m.quitting || len(m.tour.Stops) == 0 {
+		return ""
+	}
+
+	s := m.tour.Stops[m.current]
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Padding(0, 1).
+		Width(m.width - 2)
+
+	contentStyle := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(0, 1).
+		Width(m.width - 2)
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf("A tour of %s · %s · stop %d of %d",
+		m.tour.Project, s.Section, m.current+1, len(m.tour.Stops))))
+	b.WriteString("\n")
+	b.WriteString(contentStyle.Render(m.viewport.View()))
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render(fmt.Sprintf("→/%s: next • ←/%s: previous • %s/%s: scroll • %s: quit",
+		m.keys.Down.Help().Key, m.keys.Up.Help().Key, m.keys.ScrollUp.Help().Key, m.keys.ScrollDown.Help().Key, m.keys.Quit.Help().Key)))
+	return b.String()
+}
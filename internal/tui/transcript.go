@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SaveTranscript writes the conversation to .eulix/transcripts as Markdown so
+// quitting, Ctrl+C or a SIGTERM doesn't lose it. Nothing is written when no
+// question was asked, the returned path is "" then.
+func (m Model) SaveTranscript(eulixDir string) (string, error) {
+	asked := false
+	for _, msg := range m.messages {
+		if msg.Role == "user" {
+			asked = true
+			break
+		}
+	}
+	if !asked {
+		return "", nil
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# eulix chat, %s\n", now.Format("2006-01-02 15:04"))
+	for _, msg := range m.messages {
+		switch msg.Role {
+		case "user":
+			sb.WriteString("\n## You\n\n")
+		case "assistant":
+			sb.WriteString("\n## eulix\n\n")
+		case "error":
+			sb.WriteString("\n## Error\n\n")
+		default:
+			// Help text, stats and notices aren't part of the conversation
+			continue
+		}
+		sb.WriteString(strings.TrimSpace(msg.Content))
+		sb.WriteString("\n")
+	}
+
+	dir := filepath.Join(eulixDir, "transcripts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("chat-%s.md", now.Format("20060102-150405")))
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ContextChunk represents a piece of code with metadata
 type ContextChunk struct {
 	File       string
@@ -7,6 +12,7 @@ type ContextChunk struct {
 	EndLine    int
 	Content    string
 	Importance float64
+	Compressed string // how Content was shortened to fit the budget, "" when whole
 }
 
 // ContextWindow represents the full context for a query
@@ -14,4 +20,24 @@ type ContextWindow struct {
 	Chunks      []ContextChunk
 	TotalTokens int
 	Sources     []string
+	Retrieval   float64 // 0-1, how well the best matches fit the query
+}
+
+// String renders the chunks so the window can be dropped straight into a prompt
+func (w *ContextWindow) String() string {
+	if w == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, chunk := range w.Chunks {
+		if chunk.Compressed != "" {
+			sb.WriteString(fmt.Sprintf("--- %s (lines %d-%d, %s) ---\n", chunk.File, chunk.StartLine, chunk.EndLine, chunk.Compressed))
+		} else {
+			sb.WriteString(fmt.Sprintf("--- %s (lines %d-%d) ---\n", chunk.File, chunk.StartLine, chunk.EndLine))
+		}
+		sb.WriteString(chunk.Content)
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
 }
@@ -0,0 +1,5 @@
+package version
+
+// Version is the eulix release, set by release builds with
+// -ldflags "-X eulix/internal/version.Version=x.y.z"
+var Version = "0.5.3"
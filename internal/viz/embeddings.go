@@ -0,0 +1,317 @@
+package viz
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+
+	"eulix/internal/chunkstore"
+)
+
+// Points are the chunk vectors projected onto their two principal
+// components. PCA keeps the directions the vectors spread most along, so
+// chunks close in 2D are close in the full space, though not every pair
+// close in the full space ends up close in 2D.
+
+// Point is one chunk on the plot
+type Point struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Type  string  `json:"type"`
+	File  string  `json:"file"`
+	Line  int     `json:"line"`
+	Group string  `json:"group"` // directory, cut to two levels, for the colour
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+}
+
+// powerIterations caps the iterations for a component, most settle well
+// before
+const powerIterations = 100
+
+// Points loads the chunks with a vector from eulixDir and projects them
+func Points(eulixDir string) ([]Point, error) {
+	store, err := chunkstore.Load(eulixDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunks.json, run 'eulix analyze' first: %w", err)
+	}
+	vectors, err := chunkstore.LoadVectors(eulixDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vectors.bin, run 'eulix analyze' first: %w", err)
+	}
+
+	var points []Point
+	var data [][]float64
+	for _, chunk := range store.Sorted() {
+		vec := vectors.ByID[chunk.ID]
+		if len(vec) == 0 || len(vec) != vectors.Dimension {
+			continue
+		}
+		row := make([]float64, len(vec))
+		for d, v := range vec {
+			row[d] = float64(v)
+		}
+		data = append(data, row)
+		points = append(points, Point{
+			ID:    chunk.ID,
+			Name:  chunk.Metadata.Name,
+			Type:  chunk.ChunkType,
+			File:  chunk.Metadata.FilePath,
+			Line:  chunk.Metadata.LineStart,
+			Group: group(chunk.Metadata.FilePath),
+		})
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("only %d chunks have vectors, too few to plot", len(points))
+	}
+
+	xs, ys := project(data)
+	for i := range points {
+		points[i].X, points[i].Y = xs[i], ys[i]
+	}
+	return points, nil
+}
+
+func group(file string) string {
+	dir := path.Dir(file)
+	if dir == "." {
+		return "(root)"
+	}
+	for n, i := 0, 0; i < len(dir); i++ {
+		if dir[i] == '/' {
+			if n++; n == 2 {
+				return dir[:i]
+			}
+		}
+	}
+	return dir
+}
+
+// project centres data and returns each row's coordinates along the top two
+// principal components, found by power iteration on XᵀX without building it
+func project(data [][]float64) ([]float64, []float64) {
+	dims := len(data[0])
+	mean := make([]float64, dims)
+	for _, row := range data {
+		for d, v := range row {
+			mean[d] += v
+		}
+	}
+	for d := range mean {
+		mean[d] /= float64(len(data))
+	}
+	for _, row := range data {
+		for d := range row {
+			row[d] -= mean[d]
+		}
+	}
+
+	first := component(data, nil)
+	second := component(data, first)
+	xs := make([]float64, len(data))
+	ys := make([]float64, len(data))
+	for i, row := range data {
+		xs[i] = dot(row, first)
+		ys[i] = dot(row, second)
+	}
+	return xs, ys
+}
+
+// component is the direction of most variance, orthogonal to not when set
+func component(data [][]float64, not []float64) []float64 {
+	dims := len(data[0])
+	v := make([]float64, dims)
+	for d := range v {
+		// Any start works unless it is orthogonal to the answer, a fixed
+		// uneven one keeps runs repeatable
+		v[d] = 1 + float64(d%7)/7
+	}
+
+	scores := make([]float64, len(data))
+	for it := 0; it < powerIterations; it++ {
+		if not != nil {
+			orthogonalize(v, not)
+		}
+		normalize(v)
+		for i, row := range data {
+			scores[i] = dot(row, v)
+		}
+		next := make([]float64, dims)
+		for i, row := range data {
+			for d, x := range row {
+				next[d] += x * scores[i]
+			}
+		}
+		if not != nil {
+			orthogonalize(next, not)
+		}
+		normalize(next)
+		settled := math.Abs(math.Abs(dot(next, v))-1) < 1e-10
+		v = next
+		if settled {
+			break
+		}
+	}
+	if not != nil {
+		orthogonalize(v, not)
+	}
+	normalize(v)
+	return v
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func orthogonalize(v, against []float64) {
+	p := dot(v, against)
+	for d := range v {
+		v[d] -= p * against[d]
+	}
+}
+
+func normalize(v []float64) {
+	norm := math.Sqrt(dot(v, v))
+	if norm == 0 {
+		return
+	}
+	for d := range v {
+		v[d] /= norm
+	}
+}
+
+// WriteCSV writes a row per point with a header
+func WriteCSV(w io.Writer, points []Point) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "name", "type", "file", "line", "group", "x", "y"})
+	for _, p := range points {
+		out.Write([]string{
+			p.ID, p.Name, p.Type, p.File, strconv.Itoa(p.Line), p.Group,
+			strconv.FormatFloat(p.X, 'f', 5, 64), strconv.FormatFloat(p.Y, 'f', 5, 64),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteHTML writes a standalone page with the points as a scatter plot,
+// coloured by directory, with the chunk under the pointer named
+func WriteHTML(w io.Writer, project string, points []Point) error {
+	groups := make(map[string]int)
+	for _, p := range points {
+		groups[p.Group]++
+	}
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if groups[names[i]] != groups[names[j]] {
+			return groups[names[i]] > groups[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	data, err := json.Marshal(points)
+	if err != nil {
+		return err
+	}
+	legend, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	return plotTemplate.Execute(w, map[string]any{
+		"Project": project,
+		"Count":   len(points),
+		"Points":  template.JS(data),
+		"Groups":  template.JS(legend),
+	})
+}
+
+var plotTemplate = template.Must(template.New("plot").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Embeddings of {{.Project}}</title>
+<style>
+  body { margin: 0; font: 13px sans-serif; display: flex; height: 100vh; }
+  #plot { flex: 1; }
+  #side { width: 260px; padding: 12px; overflow-y: auto; border-left: 1px solid #ddd; }
+  #tip { position: fixed; pointer-events: none; background: #222; color: #fff; padding: 4px 8px; border-radius: 4px; display: none; }
+  .group { cursor: pointer; margin: 2px 0; }
+  .group.off { opacity: 0.3; }
+  .swatch { display: inline-block; width: 10px; height: 10px; margin-right: 6px; border-radius: 50%; }
+</style>
+</head>
+<body>
+<canvas id="plot"></canvas>
+<div id="side">
+  <b>{{.Project}}</b><br>{{.Count}} chunks, first two principal components.<br>Click a directory to hide it.
+  <div id="legend"></div>
+</div>
+<div id="tip"></div>
+<script>
+const points = {{.Points}};
+const groups = {{.Groups}};
+const hidden = new Set();
+const colour = g => "hsl(" + (groups.indexOf(g) * 137.5 % 360) + ",65%,50%)";
+const canvas = document.getElementById("plot"), ctx = canvas.getContext("2d"), tip = document.getElementById("tip");
+let scale;
+
+function draw() {
+  canvas.width = canvas.clientWidth; canvas.height = canvas.clientHeight;
+  // reduce, spreading a large project's points into Math.min overflows the stack
+  const bound = (key, pick) => points.reduce((m, p) => pick(m, p[key]), points[0][key]);
+  const minX = bound("x", Math.min), maxX = bound("x", Math.max), minY = bound("y", Math.min), maxY = bound("y", Math.max);
+  const pad = 20;
+  scale = p => [pad + (p.x - minX) / ((maxX - minX) || 1) * (canvas.width - 2 * pad),
+                pad + (maxY - p.y) / ((maxY - minY) || 1) * (canvas.height - 2 * pad)];
+  ctx.clearRect(0, 0, canvas.width, canvas.height);
+  for (const p of points) {
+    if (hidden.has(p.group)) continue;
+    const [x, y] = scale(p);
+    ctx.fillStyle = colour(p.group);
+    ctx.beginPath(); ctx.arc(x, y, 3, 0, 2 * Math.PI); ctx.fill();
+  }
+}
+
+canvas.addEventListener("mousemove", e => {
+  let best = null, bestDist = 64;
+  for (const p of points) {
+    if (hidden.has(p.group)) continue;
+    const [x, y] = scale(p);
+    const d = (x - e.offsetX) ** 2 + (y - e.offsetY) ** 2;
+    if (d < bestDist) { best = p; bestDist = d; }
+  }
+  if (!best) { tip.style.display = "none"; return; }
+  tip.textContent = (best.name || best.id) + " (" + best.type + ") " + best.file + ":" + best.line;
+  tip.style.left = (e.clientX + 12) + "px"; tip.style.top = (e.clientY + 12) + "px";
+  tip.style.display = "block";
+});
+
+const legend = document.getElementById("legend");
+for (const g of groups) {
+  const div = document.createElement("div");
+  div.className = "group";
+  div.innerHTML = '<span class="swatch"></span>';
+  div.firstChild.style.background = colour(g);
+  div.appendChild(document.createTextNode(g));
+  div.onclick = () => { hidden.has(g) ? hidden.delete(g) : hidden.add(g); div.classList.toggle("off"); draw(); };
+  legend.appendChild(div);
+}
+window.addEventListener("resize", draw);
+draw();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,259 @@
+// Package eulix is the embeddable API for Eulix. It wraps the same knowledge
+// base, router and cache the CLI uses so other Go programs can index a
+// project, ask questions and run context search without shelling out.
+package eulix
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"eulix/internal/analyzer"
+	"eulix/internal/cache"
+	"eulix/internal/checksum"
+	"eulix/internal/config"
+	"eulix/internal/llm"
+	"eulix/internal/query"
+)
+
+// Options configures a Client. Zero values fall back to the project's
+// eulix.toml, and to the built in defaults when that is missing.
+type Options struct {
+	// Root is the project directory, defaults to "."
+	Root string
+
+	// ConfigPath defaults to <Root>/eulix.toml
+	ConfigPath string
+
+	// LLM overrides the [llm] section when set
+	LLM *LLMOptions
+
+	// EnableCache turns on the answer cache configured in eulix.toml
+	EnableCache bool
+}
+
+// LLMOptions mirrors the [llm] section of eulix.toml
+type LLMOptions struct {
+	Provider    string // "ollama" or "anthropic"
+	Model       string
+	APIKey      string
+	BaseURL     string
+	MaxTokens   int
+	Temperature float64
+}
+
+// IndexOptions controls Index
+type IndexOptions struct {
+	// Progress receives parser and embedder output, discarded when nil
+	Progress io.Writer
+}
+
+// SearchOptions controls Search
+type SearchOptions struct {
+	// Limit caps the number of results, 0 means no limit
+	Limit int
+}
+
+// Result is one chunk of code returned by Search
+type Result struct {
+	File      string
+	StartLine int
+	EndLine   int
+	Content   string
+	Score     float64
+}
+
+// Client is safe for concurrent use, queries are serialized internally
+type Client struct {
+	root     string
+	eulixDir string
+	cfg      *config.Config
+
+	mu     sync.Mutex
+	router *query.Router
+	cache  *cache.Manager
+}
+
+// Open prepares a client for the project at opts.Root. The knowledge base is
+// loaded lazily on the first Query or Search.
+func Open(opts Options) (*Client, error) {
+	root := opts.Root
+	if root == "" {
+		root = "."
+	}
+
+	configPath := opts.ConfigPath
+	if configPath == "" {
+		configPath = filepath.Join(root, "eulix.toml")
+	}
+
+	cfg, err := config.LoadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if opts.LLM != nil {
+		applyLLMOptions(cfg, opts.LLM)
+	}
+
+	if !opts.EnableCache {
+		cfg.Cache.Redis.Enabled = false
+		cfg.Cache.SQL.Enabled = false
+	}
+
+	// Paths in eulix.toml are relative to the project, not the caller's
+	// working directory
+	cfg.InRepo(root)
+
+	return &Client{
+		root:     root,
+		eulixDir: filepath.Join(root, ".eulix"),
+		cfg:      cfg,
+	}, nil
+}
+
+func applyLLMOptions(cfg *config.Config, o *LLMOptions) {
+	if o.Provider != "" {
+		cfg.LLM.Provider = o.Provider
+		cfg.LLM.Local = o.Provider == "ollama"
+	}
+	if o.Model != "" {
+		cfg.LLM.Model = o.Model
+	}
+	if o.APIKey != "" {
+		cfg.LLM.APIKey = o.APIKey
+	}
+	if o.BaseURL != "" {
+		cfg.LLM.BaseURL = o.BaseURL
+	}
+	if o.MaxTokens > 0 {
+		cfg.LLM.MaxTokens = o.MaxTokens
+	}
+	if o.Temperature > 0 {
+		cfg.LLM.Temperature = o.Temperature
+	}
+}
+
+// Index runs the parser and embedder over the project. It needs the
+// eulix_parser and eulix_embed binaries on PATH. Any loaded router is dropped
+// so the next query sees the new knowledge base.
+func (c *Client) Index(opts IndexOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.eulixDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", c.eulixDir, err)
+	}
+
+	if _, err := analyzer.Run(c.root, c.cfg, analyzer.Options{Stdout: opts.Progress, Stderr: opts.Progress}); err != nil {
+		return err
+	}
+
+	c.closeLocked()
+	return nil
+}
+
+// Query answers a natural language question about the project
+func (c *Client) Query(question string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.ensureRouter(); err != nil {
+		return "", err
+	}
+	return c.router.Query(question)
+}
+
+// Search returns the code chunks most relevant to a query without calling
+// the LLM
+func (c *Client) Search(q string, opts SearchOptions) ([]Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.ensureRouter(); err != nil {
+		return nil, err
+	}
+
+	window, err := c.router.Search(q)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(window.Chunks))
+	for _, chunk := range window.Chunks {
+		results = append(results, Result{
+			File:      chunk.File,
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+			Content:   chunk.Content,
+			Score:     chunk.Importance,
+		})
+		if opts.Limit > 0 && len(results) >= opts.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Close releases the router and cache
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeLocked()
+	return nil
+}
+
+func (c *Client) closeLocked() {
+	if c.router != nil {
+		c.router.Close()
+		c.router = nil
+	}
+	if c.cache != nil {
+		c.cache.Close()
+		c.cache = nil
+	}
+}
+
+func (c *Client) ensureRouter() error {
+	if c.router != nil {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(c.eulixDir, "kb.json")); err != nil {
+		return fmt.Errorf("knowledge base not found in %s, call Index first", c.eulixDir)
+	}
+
+	var currentHash string
+	if current, err := checksum.HashHound(c.root).Load(); err == nil {
+		currentHash = current.Hash
+	}
+
+	var cacheManager *cache.Manager
+	if c.cfg.Cache.Redis.Enabled || c.cfg.Cache.SQL.Enabled {
+		cm, err := cache.CacheController(c.cfg)
+		if err == nil {
+			cacheManager = cm
+		}
+	}
+
+	llmClient, err := llm.MouthClient(c.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize LLM: %w", err)
+	}
+
+	router, err := query.QueryTrafficController(c.eulixDir, c.cfg, llmClient, cacheManager)
+	if err != nil {
+		if cacheManager != nil {
+			cacheManager.Close()
+		}
+		return fmt.Errorf("failed to initialize query router: %w", err)
+	}
+	router.SetCurrentChecksum(currentHash)
+
+	c.router = router
+	c.cache = cacheManager
+	return nil
+}
@@ -0,0 +1,24 @@
+package eulix
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenResolvesPathsAgainstRoot(t *testing.T) {
+	root := t.TempDir()
+	c, err := Open(Options{Root: root, EnableCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, dir := range map[string]string{
+		"cache DSN":     c.cfg.Cache.SQL.DSN,
+		"audit dir":     c.cfg.LLM.Privacy.AuditDir,
+		"cassettes dir": c.cfg.Cassettes.Dir,
+	} {
+		if rel, err := filepath.Rel(root, dir); err != nil || !filepath.IsLocal(rel) {
+			t.Errorf("%s = %q, want it under %s", name, dir, root)
+		}
+	}
+}